@@ -0,0 +1,280 @@
+// Package client is the Go client of the Mailer and admin APIs of kannon,
+// with the sends, templates, stats and suppressions of a domain, retries
+// of unavailable servers and the verification of webhook deliveries
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"kannon.gyozatech.dev/generated/pb"
+)
+
+// Config configures the connections of a Client to the APIs
+type Config struct {
+	// MailerAddr is the address of the Mailer API, like kannon.example.com:50052
+	MailerAddr string
+	// Domain and APIKey authenticate the calls of the Mailer API
+	Domain string
+	APIKey string
+	// AdminAddr is the address of the admin API, empty when the admin calls are not used
+	AdminAddr string
+	// Token is the bearer token of the admin API, empty when it's not authenticated
+	Token string
+	// TLS is the config of the connections, nil connects without TLS
+	TLS *tls.Config
+	// MaxAttempts are the attempts of a call while the server is unavailable, 3 when 0
+	MaxAttempts int
+	// RetryBaseDelay is the delay of the first retry, doubled by the next ones, 200ms when 0
+	RetryBaseDelay time.Duration
+}
+
+// Client calls the Mailer API as a domain and the admin API, it is safe for concurrent use
+type Client struct {
+	mailer pb.MailerClient
+	admin  pb.ApiClient
+	conns  []*grpc.ClientConn
+
+	maxAttempts    int
+	retryBaseDelay time.Duration
+}
+
+// ErrNoAdmin is returned by the admin calls of a Client without AdminAddr
+var ErrNoAdmin = errors.New("client without admin address")
+
+// New builds the Client of config, the connections are established on the first call
+func New(config Config) (*Client, error) {
+	if config.MailerAddr == "" {
+		return nil, errors.New("missing mailer address")
+	}
+	if config.Domain == "" || config.APIKey == "" {
+		return nil, errors.New("missing domain or api key")
+	}
+	c := &Client{
+		maxAttempts:    config.MaxAttempts,
+		retryBaseDelay: config.RetryBaseDelay,
+	}
+	if c.maxAttempts == 0 {
+		c.maxAttempts = 3
+	}
+	if c.retryBaseDelay == 0 {
+		c.retryBaseDelay = 200 * time.Millisecond
+	}
+
+	transport := grpc.WithInsecure()
+	if config.TLS != nil {
+		transport = grpc.WithTransportCredentials(credentials.NewTLS(config.TLS))
+	}
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte(config.Domain+":"+config.APIKey))
+	mailerConn, err := grpc.Dial(config.MailerAddr, transport,
+		grpc.WithPerRPCCredentials(authorization{value: basic, secure: config.TLS != nil}),
+		grpc.WithUnaryInterceptor(c.retry),
+	)
+	if err != nil {
+		return nil, err
+	}
+	c.conns = append(c.conns, mailerConn)
+	c.mailer = pb.NewMailerClient(mailerConn)
+
+	if config.AdminAddr != "" {
+		opts := []grpc.DialOption{transport, grpc.WithUnaryInterceptor(c.retry)}
+		if config.Token != "" {
+			opts = append(opts, grpc.WithPerRPCCredentials(authorization{value: "Bearer " + config.Token, secure: config.TLS != nil}))
+		}
+		adminConn, err := grpc.Dial(config.AdminAddr, opts...)
+		if err != nil {
+			_ = c.Close()
+			return nil, err
+		}
+		c.conns = append(c.conns, adminConn)
+		c.admin = pb.NewApiClient(adminConn)
+	}
+	return c, nil
+}
+
+// Close closes the connections of c
+func (c *Client) Close() error {
+	var res error
+	for _, conn := range c.conns {
+		if err := conn.Close(); err != nil && res == nil {
+			res = err
+		}
+	}
+	return res
+}
+
+// NewIdempotencyKey returns a random idempotency key, sends with the same key
+// create a single message, so a send can be retried without sending it twice
+func NewIdempotencyKey() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// SendHTML sends an html email, a random idempotency key is set when
+// the request has none so that the retries send it once
+func (c *Client) SendHTML(ctx context.Context, in *pb.SendHTMLRequest) (*pb.SendResponse, error) {
+	if in.IdempotencyKey == "" && !in.DryRun {
+		in = proto.Clone(in).(*pb.SendHTMLRequest)
+		in.IdempotencyKey = NewIdempotencyKey()
+	}
+	return c.mailer.SendHTML(ctx, in)
+}
+
+// SendTemplate sends an email of a template, a random idempotency key is set
+// when the request has none so that the retries send it once
+func (c *Client) SendTemplate(ctx context.Context, in *pb.SendTemplateRequest) (*pb.SendResponse, error) {
+	if in.IdempotencyKey == "" && !in.DryRun {
+		in = proto.Clone(in).(*pb.SendTemplateRequest)
+		in.IdempotencyKey = NewIdempotencyKey()
+	}
+	return c.mailer.SendTemplate(ctx, in)
+}
+
+// CancelMessage cancels the emails of a message not dispatched yet
+func (c *Client) CancelMessage(ctx context.Context, messageID string) (*pb.CancelMessageResponse, error) {
+	return c.mailer.CancelMessage(ctx, &pb.CancelMessageRequest{MessageId: messageID})
+}
+
+// PreviewTemplate renders a template without sending it
+func (c *Client) PreviewTemplate(ctx context.Context, in *pb.PreviewTemplateRequest) (*pb.PreviewResponse, error) {
+	return c.mailer.PreviewTemplate(ctx, in)
+}
+
+// GetStats returns the stats of the domain, or of a message when MessageId is set
+func (c *Client) GetStats(ctx context.Context, in *pb.GetStatsRequest) (*pb.Stats, error) {
+	return c.mailer.GetStats(ctx, in)
+}
+
+// GetMessageStatus returns the status and the events of the recipients of a message
+func (c *Client) GetMessageStatus(ctx context.Context, messageID string) (*pb.MessageStatus, error) {
+	return c.mailer.GetMessageStatus(ctx, &pb.GetMessageStatusRequest{MessageId: messageID})
+}
+
+// CreateTemplate creates a named template, sent with its template id by SendTemplate
+func (c *Client) CreateTemplate(ctx context.Context, in *pb.CreateTemplateRequest) (*pb.Template, error) {
+	if c.admin == nil {
+		return nil, ErrNoAdmin
+	}
+	return c.admin.CreateTemplate(ctx, in)
+}
+
+// GetTemplate returns a version of a template, the active version when Version is 0
+func (c *Client) GetTemplate(ctx context.Context, in *pb.GetTemplateRequest) (*pb.Template, error) {
+	if c.admin == nil {
+		return nil, ErrNoAdmin
+	}
+	return c.admin.GetTemplate(ctx, in)
+}
+
+// GetTemplates returns the active versions of the named templates of domain
+func (c *Client) GetTemplates(ctx context.Context, domain string) ([]*pb.Template, error) {
+	if c.admin == nil {
+		return nil, ErrNoAdmin
+	}
+	res, err := c.admin.GetTemplates(ctx, &pb.GetTemplatesRequest{Domain: domain})
+	if err != nil {
+		return nil, err
+	}
+	return res.Templates, nil
+}
+
+// UpdateTemplate creates a new active version of an existing template
+func (c *Client) UpdateTemplate(ctx context.Context, in *pb.UpdateTemplateRequest) (*pb.Template, error) {
+	if c.admin == nil {
+		return nil, ErrNoAdmin
+	}
+	return c.admin.UpdateTemplate(ctx, in)
+}
+
+// DeleteTemplate deletes a template of domain, the emails not sent yet are still sent
+func (c *Client) DeleteTemplate(ctx context.Context, domain string, templateID string) error {
+	if c.admin == nil {
+		return ErrNoAdmin
+	}
+	_, err := c.admin.DeleteTemplate(ctx, &pb.DeleteTemplateRequest{Domain: domain, TemplateId: templateID})
+	return err
+}
+
+// GetSuppressions returns the suppressed recipients of domain
+func (c *Client) GetSuppressions(ctx context.Context, domain string) ([]*pb.Suppression, error) {
+	if c.admin == nil {
+		return nil, ErrNoAdmin
+	}
+	res, err := c.admin.GetSuppressions(ctx, &pb.GetSuppressionsRequest{Domain: domain})
+	if err != nil {
+		return nil, err
+	}
+	return res.Suppressions, nil
+}
+
+// AddSuppression blocks the sends of domain to email
+func (c *Client) AddSuppression(ctx context.Context, domain string, email string) (*pb.Suppression, error) {
+	if c.admin == nil {
+		return nil, ErrNoAdmin
+	}
+	return c.admin.AddSuppression(ctx, &pb.AddSuppressionRequest{Domain: domain, Email: email})
+}
+
+// RemoveSuppression unblocks the sends of domain to email
+func (c *Client) RemoveSuppression(ctx context.Context, domain string, email string) error {
+	if c.admin == nil {
+		return ErrNoAdmin
+	}
+	_, err := c.admin.RemoveSuppression(ctx, &pb.RemoveSuppressionRequest{Domain: domain, Email: email})
+	return err
+}
+
+// Mailer returns the client of every call of the Mailer API, like StreamEvents
+func (c *Client) Mailer() pb.MailerClient {
+	return c.mailer
+}
+
+// Admin returns the client of every call of the admin API, nil without AdminAddr
+func (c *Client) Admin() pb.ApiClient {
+	return c.admin
+}
+
+// retry invokes the unary calls again with exponential backoff while the server
+// is unavailable, the other errors are returned to the caller
+func (c *Client) retry(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	delay := c.retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if status.Code(err) != codes.Unavailable || attempt >= c.maxAttempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// authorization sets the authorization header of the calls
+type authorization struct {
+	value  string
+	secure bool
+}
+
+func (a authorization) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": a.value}, nil
+}
+
+func (a authorization) RequireTransportSecurity() bool {
+	return a.secure
+}
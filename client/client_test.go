@@ -0,0 +1,137 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"kannon.gyozatech.dev/generated/pb"
+	"kannon.gyozatech.dev/internal/events"
+	"kannon.gyozatech.dev/internal/webhooks"
+)
+
+const testBody = `{"type":"delivered","message_id":"msg_1@kannon.io","email":"test@test.com","timestamp":"2021-08-12T10:00:00Z"}`
+
+func signature(secret string, timestamp int64, body string) string {
+	return fmt.Sprintf("t=%v,v1=%v", timestamp, webhooks.Sign(secret, timestamp, []byte(body)))
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	now := time.Unix(1628762400, 0)
+	e, err := verifyWebhook("secret", signature("secret", now.Unix(), testBody), []byte(testBody), now)
+	assert.Nil(t, err)
+	assert.Equal(t, Delivered, e.Type)
+	assert.Equal(t, "msg_1@kannon.io", e.MessageID)
+	assert.Equal(t, "test@test.com", e.Email)
+
+	_, err = verifyWebhook("other", signature("secret", now.Unix(), testBody), []byte(testBody), now)
+	assert.Equal(t, ErrInvalidSignature, err)
+	_, err = verifyWebhook("secret", signature("secret", now.Unix(), testBody), []byte(testBody+" "), now)
+	assert.Equal(t, ErrInvalidSignature, err)
+	_, err = verifyWebhook("secret", signature("secret", now.Add(-time.Hour).Unix(), testBody), []byte(testBody), now)
+	assert.Equal(t, ErrInvalidSignature, err)
+	_, err = verifyWebhook("secret", "", []byte(testBody), now)
+	assert.Equal(t, ErrInvalidSignature, err)
+}
+
+func TestWebhookHandler(t *testing.T) {
+	var received []Event
+	h := WebhookHandler("secret", func(ctx context.Context, e Event) error {
+		received = append(received, e)
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(testBody))
+	req.Header.Set(SignatureHeader, signature("secret", time.Now().Unix(), testBody))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Len(t, received, 1)
+
+	req = httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(testBody))
+	req.Header.Set(SignatureHeader, signature("other", time.Now().Unix(), testBody))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Len(t, received, 1)
+}
+
+func TestEventTypes(t *testing.T) {
+	types := []EventType{Accepted, Delivered, Bounced, Opened, Clicked, Unsubscribed, Complained}
+	assert.Len(t, types, len(events.Types))
+	for i, typ := range events.Types {
+		assert.Equal(t, string(typ), string(types[i]))
+	}
+}
+
+func TestRetry(t *testing.T) {
+	c := &Client{maxAttempts: 3, retryBaseDelay: time.Millisecond}
+	calls := 0
+	invoker := func(code codes.Code) grpc.UnaryInvoker {
+		return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			calls++
+			if code == codes.OK {
+				return nil
+			}
+			return status.Error(code, "failed")
+		}
+	}
+
+	err := c.retry(context.Background(), "/kannon.Mailer/GetStats", nil, nil, nil, invoker(codes.Unavailable))
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = c.retry(context.Background(), "/kannon.Mailer/GetStats", nil, nil, nil, invoker(codes.InvalidArgument))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, 1, calls)
+
+	calls = 0
+	assert.Nil(t, c.retry(context.Background(), "/kannon.Mailer/GetStats", nil, nil, nil, invoker(codes.OK)))
+	assert.Equal(t, 1, calls)
+}
+
+type mailerMock struct {
+	pb.MailerClient
+	sent []*pb.SendHTMLRequest
+}
+
+func (m *mailerMock) SendHTML(ctx context.Context, in *pb.SendHTMLRequest, opts ...grpc.CallOption) (*pb.SendResponse, error) {
+	m.sent = append(m.sent, in)
+	return &pb.SendResponse{}, nil
+}
+
+func TestSendIdempotencyKey(t *testing.T) {
+	m := &mailerMock{}
+	c := &Client{mailer: m}
+
+	in := &pb.SendHTMLRequest{Subject: "test"}
+	_, err := c.SendHTML(context.Background(), in)
+	assert.Nil(t, err)
+	assert.Len(t, m.sent[0].IdempotencyKey, 32)
+	assert.Equal(t, "", in.IdempotencyKey)
+
+	_, err = c.SendHTML(context.Background(), &pb.SendHTMLRequest{IdempotencyKey: "order-1"})
+	assert.Nil(t, err)
+	assert.Equal(t, "order-1", m.sent[1].IdempotencyKey)
+
+	_, err = c.SendHTML(context.Background(), &pb.SendHTMLRequest{DryRun: true})
+	assert.Nil(t, err)
+	assert.Equal(t, "", m.sent[2].IdempotencyKey)
+}
+
+func TestAdminWithoutAddress(t *testing.T) {
+	c, err := New(Config{MailerAddr: "localhost:50052", Domain: "kannon.io", APIKey: "key"})
+	assert.Nil(t, err)
+	defer c.Close()
+	_, err = c.GetSuppressions(context.Background(), "kannon.io")
+	assert.Equal(t, ErrNoAdmin, err)
+}
@@ -0,0 +1,132 @@
+package client
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventType is the type of the event of a webhook delivery
+type EventType string
+
+const (
+	Accepted     EventType = "accepted"
+	Delivered    EventType = "delivered"
+	Bounced      EventType = "bounced"
+	Opened       EventType = "opened"
+	Clicked      EventType = "clicked"
+	Unsubscribed EventType = "unsubscribed"
+	Complained   EventType = "complained"
+)
+
+// Event is the event of the email sent to a recipient of a message, the body of a webhook delivery
+type Event struct {
+	Type      EventType              `json:"type"`
+	MessageID string                 `json:"message_id"`
+	Email     string                 `json:"email"`
+	Timestamp time.Time              `json:"timestamp"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// SignatureHeader is the header of the timestamp and the signature of a webhook delivery
+const SignatureHeader = "X-Kannon-Signature"
+
+// WebhookTolerance is the max difference between the timestamp of a delivery and the
+// time it's verified, older deliveries are rejected so that they can't be replayed
+const WebhookTolerance = 5 * time.Minute
+
+// ErrInvalidSignature is returned for the deliveries not signed with the secret of the webhook
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// VerifyWebhook checks the signature header of a webhook delivery with the secret of
+// the webhook and returns the event of body, ErrInvalidSignature when it doesn't match
+func VerifyWebhook(secret string, signature string, body []byte) (Event, error) {
+	return verifyWebhook(secret, signature, body, time.Now())
+}
+
+func verifyWebhook(secret string, signature string, body []byte, now time.Time) (Event, error) {
+	var timestamp int64
+	var signatures []string
+	for _, part := range strings.Split(signature, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			timestamp, _ = strconv.ParseInt(kv[1], 10, 64)
+		case "v1":
+			signatures = append(signatures, kv[1])
+		}
+	}
+	if timestamp == 0 || len(signatures) == 0 {
+		return Event{}, ErrInvalidSignature
+	}
+	if d := now.Sub(time.Unix(timestamp, 0)); d > WebhookTolerance || d < -WebhookTolerance {
+		return Event{}, ErrInvalidSignature
+	}
+
+	// the signature is the hex HMAC-SHA256 of "<timestamp>.<body>"
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+	valid := false
+	for _, s := range signatures {
+		if sig, err := hex.DecodeString(s); err == nil && hmac.Equal(sig, expected) {
+			valid = true
+		}
+	}
+	if !valid {
+		return Event{}, ErrInvalidSignature
+	}
+
+	var e Event
+	if err := json.Unmarshal(body, &e); err != nil {
+		return Event{}, err
+	}
+	return e, nil
+}
+
+// maxWebhookBody is the max size of the body of a delivery read by WebhookHandler
+const maxWebhookBody = 1 << 20
+
+// WebhookHandler returns an http handler of the deliveries of a webhook, fn is called
+// with the verified events. Invalid deliveries are answered with 401 and the errors
+// of fn with 500, so the delivery is retried
+func WebhookHandler(secret string, fn func(ctx context.Context, e Event) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		e, err := VerifyWebhook(secret, r.Header.Get(SignatureHeader), body)
+		if errors.Is(err, ErrInvalidSignature) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := fn(r.Context(), e); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"kannon.gyozatech.dev/internal/daemons/bouncer"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/shutdown"
+)
+
+var log = logging.Logger("bouncer")
+
+func main() {
+	if err := bouncer.Run(shutdown.Context()); err != nil {
+		log.Fatal(err.Error())
+	}
+}
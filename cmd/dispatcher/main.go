@@ -0,0 +1,15 @@
+package main
+
+import (
+	"kannon.gyozatech.dev/internal/daemons/dispatcher"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/shutdown"
+)
+
+var log = logging.Logger("dispatcher")
+
+func main() {
+	if err := dispatcher.Run(shutdown.Context()); err != nil {
+		log.Fatal(err.Error())
+	}
+}
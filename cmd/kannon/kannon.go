@@ -0,0 +1,192 @@
+// kannon runs the daemons of kannon as goroutines of a single process,
+// for small installs and end to end tests:
+//
+//	kannon run --all
+//	kannon run api dispatcher sender
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/joho/godotenv"
+	"kannon.gyozatech.dev/internal/configfile"
+	"kannon.gyozatech.dev/internal/daemons/api"
+	"kannon.gyozatech.dev/internal/daemons/bouncer"
+	"kannon.gyozatech.dev/internal/daemons/dispatcher"
+	"kannon.gyozatech.dev/internal/daemons/sender"
+	"kannon.gyozatech.dev/internal/daemons/stats"
+	"kannon.gyozatech.dev/internal/daemons/submission"
+	"kannon.gyozatech.dev/internal/errorreport"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/queue"
+	"kannon.gyozatech.dev/internal/shutdown"
+	"kannon.gyozatech.dev/internal/tracing"
+)
+
+var log = logging.Logger("kannon")
+
+// sharedConfig are the settings shared by the daemons of the process,
+// read from the top level of the config file and the environment
+type sharedConfig struct {
+	queue.Config
+	// MetricsPort is the port of the metrics and health endpoints of every daemon, 0 disables them
+	MetricsPort uint16 `default:"9090"`
+	// DebugAddr is the address of the pprof and runtime stats server, like localhost:6060, empty disables it
+	DebugAddr string
+	// Log configures the log lines, like APP_LOG_FORMAT=json and APP_LOG_LEVELS=queue=debug
+	Log logging.Config
+	// Sentry reports the errors and panics of every daemon, like APP_SENTRY_DSN
+	Sentry errorreport.Config
+	// Tracing is the collector of the spans of every daemon, like APP_TRACING_ENDPOINT
+	Tracing tracing.Config
+}
+
+// daemon is a daemon run by kannon
+type daemon struct {
+	name string
+	run  func(ctx context.Context, config sharedConfig) error
+}
+
+// daemons are the daemons run by --all, in start order
+var daemons = []daemon{
+	{"api", func(ctx context.Context, _ sharedConfig) error { return api.Run(ctx) }},
+	{"dispatcher", func(ctx context.Context, _ sharedConfig) error { return dispatcher.Run(ctx) }},
+	{"sender", func(ctx context.Context, config sharedConfig) error { return sender.Run(ctx, senderArgs(config)) }},
+	{"stats", func(ctx context.Context, _ sharedConfig) error { return stats.Run(ctx) }},
+	{"bouncer", func(ctx context.Context, _ sharedConfig) error { return bouncer.Run(ctx) }},
+	{"submission", func(ctx context.Context, _ sharedConfig) error { return submission.Run(ctx) }},
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "run" {
+		usage()
+	}
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	fs.Usage = usage
+	all := fs.Bool("all", false, "Run every daemon")
+	fs.Parse(os.Args[2:])
+
+	var selected []daemon
+	for _, d := range daemons {
+		if *all || contains(fs.Args(), d.name) {
+			selected = append(selected, d)
+		}
+	}
+	for _, name := range fs.Args() {
+		if !isDaemon(name) {
+			fmt.Fprintf(os.Stderr, "unknown daemon %v\n", name)
+			usage()
+		}
+	}
+	if len(selected) == 0 {
+		usage()
+	}
+
+	if err := run(shutdown.Context(), selected); err != nil {
+		log.Fatal(err.Error())
+	}
+}
+
+func usage() {
+	names := make([]string, len(daemons))
+	for i, d := range daemons {
+		names[i] = d.name
+	}
+	fmt.Fprintf(os.Stderr, "usage: kannon run --all | kannon run <daemon>...\n\ndaemons: %v\n", strings.Join(names, ", "))
+	os.Exit(2)
+}
+
+// run runs the daemons until ctx is canceled, a daemon failing stops
+// the others and its error is returned
+func run(ctx context.Context, selected []daemon) error {
+	_ = godotenv.Load()
+
+	var config sharedConfig
+	if err := configfile.Load("kannon", &config); err != nil {
+		return err
+	}
+	if err := logging.Setup(config.Log); err != nil {
+		return fmt.Errorf("invalid log config: %w", err)
+	}
+	// the daemons share the reporter and the exporter of the process
+	stopReports, err := errorreport.Start(config.Sentry, "kannon")
+	if err != nil {
+		return fmt.Errorf("invalid sentry config: %w", err)
+	}
+	defer stopReports()
+	defer errorreport.Recover()
+	stopTracing, err := tracing.Start(config.Tracing, "kannon")
+	if err != nil {
+		return fmt.Errorf("invalid tracing config: %w", err)
+	}
+	defer stopTracing()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, len(selected))
+	var wg sync.WaitGroup
+	for _, d := range selected {
+		wg.Add(1)
+		go func(d daemon) {
+			defer wg.Done()
+			defer errorreport.Recover()
+			if err := d.run(ctx, config); err != nil {
+				errs <- fmt.Errorf("%v: %w", d.name, err)
+				cancel()
+			}
+		}(d)
+	}
+	log.Infof("🚀 running %v daemons", len(selected))
+	wg.Wait()
+	close(errs)
+	if err := <-errs; err != nil {
+		return err
+	}
+	log.Infof("kannon stopped")
+	return nil
+}
+
+// senderArgs are the flags of the sender with the settings of config, the sender is
+// configured by flags and shares the broker, the log and the servers of the process
+func senderArgs(config sharedConfig) []string {
+	args := []string{
+		"-broker=" + config.Broker,
+		"-nasts-url=" + config.NatsConn,
+		"-nats-tls-cert=" + config.NatsTLS.CertFile,
+		"-nats-tls-key=" + config.NatsTLS.KeyFile,
+		"-nats-tls-ca=" + config.NatsTLS.CAFile,
+		"-nats-tls-server-name=" + config.NatsTLS.ServerName,
+		fmt.Sprintf("-metrics-port=%v", config.MetricsPort),
+		"-debug-addr=" + config.DebugAddr,
+		"-log-format=" + config.Log.Format,
+		"-log-level=" + config.Log.Level,
+		"-log-levels=" + config.Log.Levels,
+	}
+	if len(config.KafkaBrokers) > 0 {
+		args = append(args, "-kafka-brokers="+strings.Join(config.KafkaBrokers, ","))
+	}
+	return args
+}
+
+func isDaemon(name string) bool {
+	for _, d := range daemons {
+		if d.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,597 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"kannon.gyozatech.dev/generated/pb"
+)
+
+var commands = []command{
+	{"domains list", "list the domains", listDomains},
+	{"domains create", "<domain>: register a domain and print its API key and DNS records", createDomain},
+	{"domains dns", "<domain>: print the DNS records of a domain and their published values", domainDNSRecords},
+	{"domains verify", "<domain>: check the DNS records of a domain", verifyDomain},
+	{"domains provision", "<domain>: create the DNS records of a domain with the DNS provider", provisionDomain},
+	{"domains pause", "[-reason r] <domain>: stop the dispatch of the emails of a domain", pauseDomain},
+	{"domains resume", "<domain>: dispatch again the emails of a paused domain", resumeDomain},
+	{"dkim keys", "<domain>: list the DKIM keys of a domain", dkimKeys},
+	{"dkim rotate", "[-algorithm rsa|ed25519] [-selector name] <domain>: create a pending DKIM key", rotateDKIMKey},
+	{"dkim promote", "[-grace-days 7] <domain> <selector>: make a pending DKIM key the active key", promoteDKIMKey},
+	{"templates list", "<domain>: list the named templates of a domain", listTemplates},
+	{"templates get", "[-version n] <domain> <template-id>: print the active version of a template, or the version of -version", getTemplate},
+	{"templates create", "[-text file] [-fields name,link] <domain> <name> <html file>: create a named template", createTemplate},
+	{"templates upload", "[-text file] [-fields name,link] <domain> <template-id> <html file>: create a new version of a template", uploadTemplate},
+	{"templates rollback", "<domain> <template-id> <version>: make a version of a template the active version", rollbackTemplate},
+	{"templates delete", "<domain> <template-id>: delete a template, its emails not sent yet are still sent", deleteTemplate},
+	{"send", "-to emails [-from email] [-subject s] [-dry-run] <html file>: send a test email with the API key", send},
+	{"events tail", "[-types opened,bounced] stream the events of the domain of the API key", tailEvents},
+	{"queue list", "[-domain d] [-status scheduled] [-limit 100]: list the emails of the sending queue", listQueue},
+	{"queue dead-letters", "[-domain d] [-all] [-limit 100]: list the emails that failed processing", listDeadLetters},
+	{"queue requeue", "<id>: send a dead letter to its subject again", requeueDeadLetter},
+	{"sending status", "print whether every dispatch and send is halted", sendingStatus},
+	{"sending halt", "[-reason r]: halt every dispatch and send of every domain", haltSending},
+	{"sending resume", "resume the dispatches and sends", resumeSending},
+	{"audit list", "[-domain d] [-actor sub] [-method SetDomainQuota] [-limit 100]: list the mutations of the admin API", listAuditLogs},
+}
+
+// errUsage is returned for commands called with wrong args
+var errUsage = errors.New("wrong arguments")
+
+// parseArgs parses the flags of a command and checks that it has n args
+func parseArgs(name string, args []string, n int, flags func(fs *flag.FlagSet)) ([]string, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	if flags != nil {
+		flags(fs)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != n {
+		return nil, errUsage
+	}
+	return fs.Args(), nil
+}
+
+func listDomains(c *client, args []string) error {
+	if _, err := parseArgs("domains list", args, 0, nil); err != nil {
+		return err
+	}
+	ctx, cancel := c.adminContext()
+	defer cancel()
+	res, err := c.admin.GetDomains(ctx, &emptypb.Empty{})
+	if err != nil {
+		return err
+	}
+	return printJSON(res)
+}
+
+func createDomain(c *client, args []string) error {
+	args, err := parseArgs("domains create", args, 1, nil)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.adminContext()
+	defer cancel()
+	domain, err := c.admin.CreateDomain(ctx, &pb.CreateDomainRequest{Domain: args[0]})
+	if err != nil {
+		return err
+	}
+	if err := printJSON(domain); err != nil {
+		return err
+	}
+	records, err := c.admin.GetDomainDNSRecords(ctx, &pb.GetDomainDNSRecordsRequest{Domain: args[0]})
+	if err != nil {
+		return err
+	}
+	return printJSON(records)
+}
+
+func domainDNSRecords(c *client, args []string) error {
+	args, err := parseArgs("domains dns", args, 1, nil)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.adminContext()
+	defer cancel()
+	res, err := c.admin.GetDomainDNSRecords(ctx, &pb.GetDomainDNSRecordsRequest{Domain: args[0]})
+	if err != nil {
+		return err
+	}
+	return printJSON(res)
+}
+
+func verifyDomain(c *client, args []string) error {
+	args, err := parseArgs("domains verify", args, 1, nil)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.adminContext()
+	defer cancel()
+	res, err := c.admin.VerifyDomain(ctx, &pb.VerifyDomainRequest{Domain: args[0]})
+	if err != nil {
+		return err
+	}
+	return printJSON(res)
+}
+
+func provisionDomain(c *client, args []string) error {
+	args, err := parseArgs("domains provision", args, 1, nil)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.adminContext()
+	defer cancel()
+	res, err := c.admin.ProvisionDomainDNS(ctx, &pb.ProvisionDomainDNSRequest{Domain: args[0]})
+	if err != nil {
+		return err
+	}
+	return printJSON(res)
+}
+
+func pauseDomain(c *client, args []string) error {
+	var reason string
+	args, err := parseArgs("domains pause", args, 1, func(fs *flag.FlagSet) {
+		fs.StringVar(&reason, "reason", "", "Reason of the pause, returned to the rejected sends")
+	})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.adminContext()
+	defer cancel()
+	res, err := c.admin.PauseDomain(ctx, &pb.PauseDomainRequest{Domain: args[0], Reason: reason})
+	if err != nil {
+		return err
+	}
+	return printJSON(res)
+}
+
+func resumeDomain(c *client, args []string) error {
+	args, err := parseArgs("domains resume", args, 1, nil)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.adminContext()
+	defer cancel()
+	res, err := c.admin.ResumeDomain(ctx, &pb.ResumeDomainRequest{Domain: args[0]})
+	if err != nil {
+		return err
+	}
+	return printJSON(res)
+}
+
+func dkimKeys(c *client, args []string) error {
+	args, err := parseArgs("dkim keys", args, 1, nil)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.adminContext()
+	defer cancel()
+	res, err := c.admin.GetDomainDKIMKeys(ctx, &pb.GetDomainDKIMKeysRequest{Domain: args[0]})
+	if err != nil {
+		return err
+	}
+	return printJSON(res)
+}
+
+func rotateDKIMKey(c *client, args []string) error {
+	var algorithm, selector string
+	args, err := parseArgs("dkim rotate", args, 1, func(fs *flag.FlagSet) {
+		fs.StringVar(&algorithm, "algorithm", "rsa", "Algorithm of the new key: rsa or ed25519")
+		fs.StringVar(&selector, "selector", "", "Selector of the new key, generated when empty")
+	})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.adminContext()
+	defer cancel()
+	key, err := c.admin.RotateDomainDKIMKey(ctx, &pb.RotateDomainDKIMKeyRequest{
+		Domain:    args[0],
+		Algorithm: algorithm,
+		Selector:  selector,
+	})
+	if err != nil {
+		return err
+	}
+	return printJSON(key)
+}
+
+func promoteDKIMKey(c *client, args []string) error {
+	var graceDays uint
+	args, err := parseArgs("dkim promote", args, 2, func(fs *flag.FlagSet) {
+		fs.UintVar(&graceDays, "grace-days", 0, "Days the previous key stays valid, 0 is 7 days")
+	})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.adminContext()
+	defer cancel()
+	domain, err := c.admin.PromoteDomainDKIMKey(ctx, &pb.PromoteDomainDKIMKeyRequest{
+		Domain:    args[0],
+		Selector:  args[1],
+		GraceDays: uint32(graceDays),
+	})
+	if err != nil {
+		return err
+	}
+	return printJSON(domain)
+}
+
+func listTemplates(c *client, args []string) error {
+	args, err := parseArgs("templates list", args, 1, nil)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.adminContext()
+	defer cancel()
+	res, err := c.admin.GetTemplates(ctx, &pb.GetTemplatesRequest{Domain: args[0]})
+	if err != nil {
+		return err
+	}
+	return printJSON(res)
+}
+
+func getTemplate(c *client, args []string) error {
+	var version uint
+	args, err := parseArgs("templates get", args, 2, func(fs *flag.FlagSet) {
+		fs.UintVar(&version, "version", 0, "Version of the template, the active version when 0")
+	})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.adminContext()
+	defer cancel()
+	template, err := c.admin.GetTemplate(ctx, &pb.GetTemplateRequest{
+		Domain:     args[0],
+		TemplateId: args[1],
+		Version:    uint32(version),
+	})
+	if err != nil {
+		return err
+	}
+	return printJSON(template)
+}
+
+// templateFlags are the flags of the commands uploading a template
+type templateFlags struct {
+	textFile string
+	fields   string
+}
+
+func (f *templateFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.textFile, "text", "", "File of the plain-text version, generated from the html when empty")
+	fs.StringVar(&f.fields, "fields", "", "Comma separated fields the placeholders can use, any field when empty")
+}
+
+// read reads the html file and the text file of f
+func (f *templateFlags) read(htmlFile string) (string, string, error) {
+	html, err := readFile(htmlFile)
+	if err != nil {
+		return "", "", err
+	}
+	var text string
+	if f.textFile != "" {
+		if text, err = readFile(f.textFile); err != nil {
+			return "", "", err
+		}
+	}
+	return html, text, nil
+}
+
+func (f *templateFlags) fieldList() []string {
+	if f.fields == "" {
+		return nil
+	}
+	return strings.Split(f.fields, ",")
+}
+
+func createTemplate(c *client, args []string) error {
+	var flags templateFlags
+	args, err := parseArgs("templates create", args, 3, flags.register)
+	if err != nil {
+		return err
+	}
+	html, text, err := flags.read(args[2])
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.adminContext()
+	defer cancel()
+	template, err := c.admin.CreateTemplate(ctx, &pb.CreateTemplateRequest{
+		Domain: args[0],
+		Name:   args[1],
+		Html:   html,
+		Text:   text,
+		Fields: flags.fieldList(),
+	})
+	if err != nil {
+		return err
+	}
+	return printJSON(template)
+}
+
+func uploadTemplate(c *client, args []string) error {
+	var flags templateFlags
+	args, err := parseArgs("templates upload", args, 3, flags.register)
+	if err != nil {
+		return err
+	}
+	html, text, err := flags.read(args[2])
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.adminContext()
+	defer cancel()
+	template, err := c.admin.UpdateTemplate(ctx, &pb.UpdateTemplateRequest{
+		Domain:     args[0],
+		TemplateId: args[1],
+		Html:       html,
+		Text:       text,
+		Fields:     flags.fieldList(),
+	})
+	if err != nil {
+		return err
+	}
+	return printJSON(template)
+}
+
+func rollbackTemplate(c *client, args []string) error {
+	args, err := parseArgs("templates rollback", args, 3, nil)
+	if err != nil {
+		return err
+	}
+	version, err := strconv.ParseUint(args[2], 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid template version: %v", args[2])
+	}
+	ctx, cancel := c.adminContext()
+	defer cancel()
+	template, err := c.admin.RollbackTemplate(ctx, &pb.RollbackTemplateRequest{
+		Domain:     args[0],
+		TemplateId: args[1],
+		Version:    uint32(version),
+	})
+	if err != nil {
+		return err
+	}
+	return printJSON(template)
+}
+
+func deleteTemplate(c *client, args []string) error {
+	args, err := parseArgs("templates delete", args, 2, nil)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.adminContext()
+	defer cancel()
+	_, err = c.admin.DeleteTemplate(ctx, &pb.DeleteTemplateRequest{Domain: args[0], TemplateId: args[1]})
+	return err
+}
+
+func send(c *client, args []string) error {
+	var to, from, alias, subject string
+	var dryRun bool
+	args, err := parseArgs("send", args, 1, func(fs *flag.FlagSet) {
+		fs.StringVar(&to, "to", "", "Comma separated recipients")
+		fs.StringVar(&from, "from", "", "Sender email, the default sender of the domain when empty")
+		fs.StringVar(&alias, "from-name", "", "Sender name")
+		fs.StringVar(&subject, "subject", "Kannon test email", "Subject")
+		fs.BoolVar(&dryRun, "dry-run", false, "Print the emails as they would be sent without sending them")
+	})
+	if err != nil {
+		return err
+	}
+	if to == "" {
+		return errors.New("missing -to")
+	}
+	html, err := readFile(args[0])
+	if err != nil {
+		return err
+	}
+	ctx, cancel, err := c.mailerContext(false)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	res, err := c.mailer.SendHTML(ctx, &pb.SendHTMLRequest{
+		Sender:  &pb.Sender{Email: from, Alias: alias},
+		To:      strings.Split(to, ","),
+		Subject: subject,
+		Html:    html,
+		DryRun:  dryRun,
+	})
+	if err != nil {
+		return err
+	}
+	return printJSON(res)
+}
+
+func tailEvents(c *client, args []string) error {
+	var types string
+	if _, err := parseArgs("events tail", args, 0, func(fs *flag.FlagSet) {
+		fs.StringVar(&types, "types", "", "Comma separated types of the events, every type when empty")
+	}); err != nil {
+		return err
+	}
+	ctx, cancel, err := c.mailerContext(true)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	req := &pb.StreamEventsRequest{}
+	if types != "" {
+		req.Types = strings.Split(types, ",")
+	}
+	stream, err := c.mailer.StreamEvents(ctx, req)
+	if err != nil {
+		return err
+	}
+	for {
+		event, err := stream.Recv()
+		if errors.Is(err, io.EOF) || ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// one event per line
+		data, err := protojson.Marshal(event)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	}
+}
+
+func listQueue(c *client, args []string) error {
+	var domain, status string
+	var limit uint
+	if _, err := parseArgs("queue list", args, 0, func(fs *flag.FlagSet) {
+		fs.StringVar(&domain, "domain", "", "Domain of the emails, every domain when empty")
+		fs.StringVar(&status, "status", "scheduled", "Status of the emails: scheduled, sending, sent, error, suppressed, quota_exceeded or canceled")
+		fs.UintVar(&limit, "limit", 100, "Max number of emails")
+	}); err != nil {
+		return err
+	}
+	ctx, cancel := c.adminContext()
+	defer cancel()
+	res, err := c.admin.SearchMessages(ctx, &pb.SearchMessagesRequest{
+		Domain: domain,
+		Status: status,
+		Limit:  uint32(limit),
+	})
+	if err != nil {
+		return err
+	}
+	return printJSON(res)
+}
+
+func listDeadLetters(c *client, args []string) error {
+	var domain string
+	var all bool
+	var limit uint
+	if _, err := parseArgs("queue dead-letters", args, 0, func(fs *flag.FlagSet) {
+		fs.StringVar(&domain, "domain", "", "Domain of the dead letters, every domain when empty")
+		fs.BoolVar(&all, "all", false, "Include the requeued dead letters")
+		fs.UintVar(&limit, "limit", 100, "Max number of dead letters")
+	}); err != nil {
+		return err
+	}
+	ctx, cancel := c.adminContext()
+	defer cancel()
+	res, err := c.admin.GetDeadLetters(ctx, &pb.GetDeadLettersRequest{
+		Domain:          domain,
+		IncludeRequeued: all,
+		Limit:           uint32(limit),
+	})
+	if err != nil {
+		return err
+	}
+	return printJSON(res)
+}
+
+func requeueDeadLetter(c *client, args []string) error {
+	args, err := parseArgs("queue requeue", args, 1, nil)
+	if err != nil {
+		return err
+	}
+	id, err := strconv.ParseInt(args[0], 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid dead letter id: %v", args[0])
+	}
+	ctx, cancel := c.adminContext()
+	defer cancel()
+	res, err := c.admin.RequeueDeadLetter(ctx, &pb.RequeueDeadLetterRequest{Id: int32(id)})
+	if err != nil {
+		return err
+	}
+	return printJSON(res)
+}
+
+func sendingStatus(c *client, args []string) error {
+	if _, err := parseArgs("sending status", args, 0, nil); err != nil {
+		return err
+	}
+	ctx, cancel := c.adminContext()
+	defer cancel()
+	res, err := c.admin.GetSendingStatus(ctx, &emptypb.Empty{})
+	if err != nil {
+		return err
+	}
+	return printJSON(res)
+}
+
+func haltSending(c *client, args []string) error {
+	var reason string
+	if _, err := parseArgs("sending halt", args, 0, func(fs *flag.FlagSet) {
+		fs.StringVar(&reason, "reason", "", "Reason of the halt, logged by the daemons and kept in the deferred emails")
+	}); err != nil {
+		return err
+	}
+	ctx, cancel := c.adminContext()
+	defer cancel()
+	res, err := c.admin.HaltSending(ctx, &pb.HaltSendingRequest{Reason: reason})
+	if err != nil {
+		return err
+	}
+	return printJSON(res)
+}
+
+func resumeSending(c *client, args []string) error {
+	if _, err := parseArgs("sending resume", args, 0, nil); err != nil {
+		return err
+	}
+	ctx, cancel := c.adminContext()
+	defer cancel()
+	res, err := c.admin.ResumeSending(ctx, &emptypb.Empty{})
+	if err != nil {
+		return err
+	}
+	return printJSON(res)
+}
+
+func listAuditLogs(c *client, args []string) error {
+	var domain, actor, method string
+	var limit uint
+	if _, err := parseArgs("audit list", args, 0, func(fs *flag.FlagSet) {
+		fs.StringVar(&domain, "domain", "", "Domain of the mutations, every domain when empty")
+		fs.StringVar(&actor, "actor", "", "Subject of the token of the caller, every caller when empty")
+		fs.StringVar(&method, "method", "", "RPC of the mutations, every RPC when empty")
+		fs.UintVar(&limit, "limit", 100, "Max number of logs")
+	}); err != nil {
+		return err
+	}
+	ctx, cancel := c.adminContext()
+	defer cancel()
+	res, err := c.admin.GetAuditLogs(ctx, &pb.GetAuditLogsRequest{
+		Domain: domain,
+		Actor:  actor,
+		Method: method,
+		Limit:  uint32(limit),
+	})
+	if err != nil {
+		return err
+	}
+	return printJSON(res)
+}
+
+// readFile returns the content of a file, stdin for -
+func readFile(name string) (string, error) {
+	if name == "-" {
+		data, err := ioutil.ReadAll(os.Stdin)
+		return string(data), err
+	}
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return "", fmt.Errorf("cannot read %v: %w", name, err)
+	}
+	return string(data), nil
+}
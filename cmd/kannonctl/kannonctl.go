@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"kannon.gyozatech.dev/generated/pb"
+	"kannon.gyozatech.dev/internal/tlsconfig"
+)
+
+// command is a command of kannonctl, like domains create
+type command struct {
+	name  string
+	usage string
+	run   func(c *client, args []string) error
+}
+
+// client calls the admin API with the token of the operator
+// and the mailer API with the API key of a domain
+type client struct {
+	admin   pb.ApiClient
+	mailer  pb.MailerClient
+	token   string
+	apiKey  string
+	timeout time.Duration
+}
+
+func main() {
+	apiAddr := flag.String("api-addr", "localhost:50051", "Address of the admin API")
+	mailerAddr := flag.String("mailer-addr", "localhost:50052", "Address of the mailer API")
+	token := flag.String("token", os.Getenv("KANNON_TOKEN"), "Bearer token of the admin API, KANNON_TOKEN by default")
+	apiKey := flag.String("api-key", os.Getenv("KANNON_API_KEY"), "API key of the mailer API as <domain>:<key>, KANNON_API_KEY by default")
+	useTLS := flag.Bool("tls", false, "Connect with TLS, also enabled by the other -tls flags")
+	tlsCert := flag.String("tls-cert", "", "Client certificate of the mTLS connections")
+	tlsKey := flag.String("tls-key", "", "Key of the client certificate")
+	tlsCA := flag.String("tls-ca", "", "CAs of the server, the system CAs when empty")
+	tlsServerName := flag.String("tls-server-name", "", "Name in the certificate of the server, the host of the address when empty")
+	timeout := flag.Duration("timeout", 30*time.Second, "Max time of a call")
+
+	flag.Usage = usage
+	flag.Parse()
+
+	cmd, args, ok := findCommand(flag.Args())
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	tlsConfig := tlsconfig.Config{
+		CertFile:   *tlsCert,
+		KeyFile:    *tlsKey,
+		CAFile:     *tlsCA,
+		ServerName: *tlsServerName,
+	}
+	if err := tlsConfig.Validate(); err != nil {
+		fatal(fmt.Errorf("invalid tls config: %w", err))
+	}
+	opts := []grpc.DialOption{grpc.WithInsecure()}
+	if *useTLS || tlsConfig.Enabled() {
+		config, err := tlsConfig.ClientConfig()
+		if err != nil {
+			fatal(err)
+		}
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(config))}
+	}
+
+	// connections are established on the first call
+	apiConn, err := grpc.Dial(*apiAddr, opts...)
+	if err != nil {
+		fatal(err)
+	}
+	defer apiConn.Close()
+	mailerConn, err := grpc.Dial(*mailerAddr, opts...)
+	if err != nil {
+		fatal(err)
+	}
+	defer mailerConn.Close()
+
+	c := &client{
+		admin:   pb.NewApiClient(apiConn),
+		mailer:  pb.NewMailerClient(mailerConn),
+		token:   *token,
+		apiKey:  *apiKey,
+		timeout: *timeout,
+	}
+	err = cmd.run(c, args)
+	if errors.Is(err, errUsage) {
+		fatal(fmt.Errorf("usage: kannonctl %v %v", cmd.name, cmd.usage))
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+// findCommand returns the command named by the first one or two args and its args
+func findCommand(args []string) (command, []string, bool) {
+	if len(args) >= 2 {
+		if cmd, ok := commandByName(args[0] + " " + args[1]); ok {
+			return cmd, args[2:], true
+		}
+	}
+	if len(args) >= 1 {
+		if cmd, ok := commandByName(args[0]); ok {
+			return cmd, args[1:], true
+		}
+	}
+	return command{}, nil, false
+}
+
+func commandByName(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: kannonctl [flags] <command> [args]\n\nCommands:\n")
+	names := make([]string, 0, len(commands))
+	usages := make(map[string]string, len(commands))
+	for _, cmd := range commands {
+		names = append(names, cmd.name)
+		usages[cmd.name] = cmd.usage
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-22v %v\n", name, usages[name])
+	}
+	fmt.Fprintf(os.Stderr, "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "kannonctl: %v\n", err)
+	os.Exit(1)
+}
+
+// adminContext returns the context of a call of the admin API
+func (c *client) adminContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	if c.token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+c.token)
+	}
+	return ctx, cancel
+}
+
+// mailerContext returns the context of a call of the mailer API, streams
+// have no timeout and are canceled on interrupt
+func (c *client) mailerContext(stream bool) (context.Context, context.CancelFunc, error) {
+	if !strings.Contains(c.apiKey, ":") {
+		return nil, nil, fmt.Errorf("missing api key, set -api-key or KANNON_API_KEY to <domain>:<key>")
+	}
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if stream {
+		ctx, cancel = signalContext()
+	} else {
+		ctx, cancel = context.WithTimeout(context.Background(), c.timeout)
+	}
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(c.apiKey))
+	return metadata.AppendToOutgoingContext(ctx, "authorization", auth), cancel, nil
+}
+
+// signalContext returns a context canceled on interrupt
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		select {
+		case <-sig:
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(sig)
+	}()
+	return ctx, cancel
+}
+
+// printJSON prints m as indented JSON
+func printJSON(m proto.Message) error {
+	data, err := protojson.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(m)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
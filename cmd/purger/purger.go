@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"github.com/joho/godotenv"
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/archive"
+	"kannon.gyozatech.dev/internal/configfile"
+	"kannon.gyozatech.dev/internal/dbconfig"
+	"kannon.gyozatech.dev/internal/domains"
+	"kannon.gyozatech.dev/internal/errorreport"
+	"kannon.gyozatech.dev/internal/export"
+	"kannon.gyozatech.dev/internal/health"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/metrics"
+	"kannon.gyozatech.dev/internal/retention"
+	"kannon.gyozatech.dev/internal/s3"
+	"kannon.gyozatech.dev/internal/shutdown"
+)
+
+var log = logging.Logger("purger")
+
+type appConfig struct {
+	// MetricsPort is the port of the metrics and health endpoints, 0 disables them
+	MetricsPort uint16 `default:"9090"`
+	// DebugAddr is the address of the pprof and runtime stats server, like localhost:6060, empty disables it
+	DebugAddr string
+	// Log configures the log lines, like APP_LOG_FORMAT=json and APP_LOG_LEVELS=queue=debug
+	Log logging.Config
+	// DB configures the connection pool to the database, like APP_DB_MAXOPENCONNS
+	DB dbconfig.Config
+	// Sentry reports the errors and panics, like APP_SENTRY_DSN
+	Sentry errorreport.Config
+	// RetentionDays is the retention of domains without a custom one
+	RetentionDays uint `default:"90"`
+	// Interval between purges
+	Interval time.Duration `default:"1h"`
+	// BatchSize is the number of messages deleted by a single query
+	BatchSize uint `default:"1000"`
+	// Archive is the bucket of the sent emails deleted with their messages, like APP_ARCHIVE_BUCKET
+	Archive archive.Config
+	// Export is the bucket of the daily exports of the events of every domain, like APP_EXPORT_BUCKET
+	Export export.Config
+}
+
+func main() {
+	_ = godotenv.Load()
+
+	var config appConfig
+	err := configfile.Load("purger", &config)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	if err := logging.Setup(config.Log); err != nil {
+		log.Fatalf("invalid log config: %v", err)
+	}
+	stopReports, err := errorreport.Start(config.Sentry, "kannon-purger")
+	if err != nil {
+		log.Fatalf("invalid sentry config: %v", err)
+	}
+	defer stopReports()
+	defer errorreport.Recover()
+
+	db, err := sqlc.Conn()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+	config.DB.Apply(db)
+
+	dm, err := domains.NewDomainManager(db)
+	if err != nil {
+		panic(err)
+	}
+	rm, err := retention.NewRetentionManager(db)
+	if err != nil {
+		panic(err)
+	}
+
+	var ar archive.Archive
+	if config.Archive.Enabled() {
+		if ar, err = archive.NewArchive(config.Archive); err != nil {
+			log.Fatalf("invalid archive config: %v", err)
+		}
+	}
+
+	var em export.Manager
+	var eb s3.Bucket
+	if config.Export.Enabled() {
+		if _, err := export.ParseFormat(string(config.Export.Format)); err != nil {
+			log.Fatalf("invalid export config: %v", err)
+		}
+		if eb, err = s3.NewBucket(config.Export.Config); err != nil {
+			log.Fatalf("invalid export config: %v", err)
+		}
+		if em, err = export.NewExportManager(db); err != nil {
+			panic(err)
+		}
+	}
+
+	metrics.Serve(config.MetricsPort, health.DB(db))
+	metrics.ServeDebug(config.DebugAddr)
+
+	// the log levels and the database pool change without a restart
+	configfile.Watch(func() {
+		var next appConfig
+		if err := configfile.Load("purger", &next); err != nil {
+			log.Errorf("cannot reload config: %v", err)
+			return
+		}
+		if err := logging.Setup(next.Log); err != nil {
+			log.Errorf("cannot reload log config: %v", err)
+			return
+		}
+		next.DB.Apply(db)
+		log.Infof("config reloaded")
+	})
+
+	ctx := shutdown.Context()
+	for ctx.Err() == nil {
+		if eb != nil {
+			exportEvents(ctx, dm, em, eb, config.Export.Format)
+		}
+		purge(ctx, dm, rm, ar, config)
+		purgeDeletedDomains(ctx, dm, rm, ar, config)
+		retireDKIMKeys(dm)
+		select {
+		case <-ctx.Done():
+		case <-time.After(config.Interval):
+		}
+	}
+	log.Infof("purger stopped")
+}
+
+// exportEvents uploads the events of every UTC day of every domain not exported yet,
+// before they are purged, and exports again the days with late events
+func exportEvents(ctx context.Context, dm domains.DomainManager, em export.Manager, eb s3.Bucket, format export.Format) {
+	ds, err := dm.GetAllDomains()
+	if err != nil {
+		log.Errorf("cannot get domains: %v", err)
+		return
+	}
+	for _, d := range ds {
+		exported, err := export.ExportDays(ctx, em, eb, format, d.Domain, time.Now())
+		for key, n := range exported {
+			log.Infof("[📦 exported] %v events of %v to %v", n, d.Domain, key)
+		}
+		if err != nil {
+			log.Errorf("cannot export events of %v: %v", d.Domain, err)
+		}
+	}
+}
+
+// purge deletes the data of the domains older than their retention,
+// with their archived emails when ar is not nil
+func purge(ctx context.Context, dm domains.DomainManager, rm retention.Manager, ar archive.Archive, config appConfig) {
+	ds, err := dm.GetAllDomains()
+	if err != nil {
+		log.Errorf("cannot get domains: %v", err)
+		return
+	}
+	var total retention.Purged
+	for _, d := range ds {
+		before := retention.Cutoff(time.Now(), d.RetentionDays, config.RetentionDays)
+		purged, err := rm.Purge(d.Domain, before, config.BatchSize)
+		if err != nil {
+			log.Errorf("cannot purge %v: %v", d.Domain, err)
+		}
+		log.Infof("[🧹 purged] %v before %v: %+v", d.Domain, before.Format(time.RFC3339), purged)
+		if ar != nil {
+			purgeArchive(ctx, ar, d.Domain, before)
+		}
+		total.Add(purged)
+	}
+	log.Infof("[🧹 purged] total: %+v", total)
+}
+
+// purgeDeletedDomains deletes the domains at the end of their deletion
+// grace period with all their data
+func purgeDeletedDomains(ctx context.Context, dm domains.DomainManager, rm retention.Manager, ar archive.Archive, config appConfig) {
+	ds, err := dm.GetDomainsToPurge()
+	if err != nil {
+		log.Errorf("cannot get deleted domains: %v", err)
+		return
+	}
+	for _, d := range ds {
+		purged, err := rm.PurgeDomain(d.Domain, config.BatchSize)
+		if err != nil {
+			log.Errorf("cannot purge deleted domain %v: %v", d.Domain, err)
+			continue
+		}
+		log.Infof("[🧹 deleted] %v: %+v", d.Domain, purged)
+		if ar != nil {
+			purgeArchive(ctx, ar, d.Domain, time.Now())
+		}
+	}
+}
+
+// purgeArchive deletes the emails of domain archived before a time
+func purgeArchive(ctx context.Context, ar archive.Archive, domain string, before time.Time) {
+	deleted, err := ar.Purge(ctx, domain, before)
+	if err != nil {
+		log.Errorf("cannot purge archive of %v: %v", domain, err)
+		return
+	}
+	log.Infof("[🧹 purged] %v archived emails of %v", deleted, domain)
+}
+
+// retireDKIMKeys retires the DKIM keys at the end of their grace period
+func retireDKIMKeys(dm domains.DomainManager) {
+	keys, err := dm.RetireDKIMKeys()
+	if err != nil {
+		log.Errorf("cannot retire dkim keys: %v", err)
+		return
+	}
+	for _, k := range keys {
+		log.Infof("[🔑 retired] %v selector %v, its DNS record can be removed", k.Domain, k.Selector)
+	}
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"os"
+
+	"kannon.gyozatech.dev/internal/daemons/sender"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/shutdown"
+)
+
+var log = logging.Logger("sender")
+
+func main() {
+	if err := sender.Run(shutdown.Context(), os.Args[1:]); err != nil {
+		log.Fatal(err.Error())
+	}
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"kannon.gyozatech.dev/internal/daemons/stats"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/shutdown"
+)
+
+var log = logging.Logger("stats")
+
+func main() {
+	if err := stats.Run(shutdown.Context()); err != nil {
+		log.Fatal(err.Error())
+	}
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"kannon.gyozatech.dev/internal/daemons/submission"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/shutdown"
+)
+
+var log = logging.Logger("submission")
+
+func main() {
+	if err := submission.Run(shutdown.Context()); err != nil {
+		log.Fatal(err.Error())
+	}
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"kannon.gyozatech.dev/internal/daemons/tracker"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/shutdown"
+)
+
+var log = logging.Logger("tracker")
+
+func main() {
+	if err := tracker.Run(shutdown.Context()); err != nil {
+		log.Fatal(err.Error())
+	}
+}
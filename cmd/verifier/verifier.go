@@ -0,0 +1,128 @@
+package main
+
+import (
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"github.com/joho/godotenv"
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/configfile"
+	"kannon.gyozatech.dev/internal/dbconfig"
+	"kannon.gyozatech.dev/internal/domains"
+	"kannon.gyozatech.dev/internal/errorreport"
+	"kannon.gyozatech.dev/internal/health"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/metrics"
+	"kannon.gyozatech.dev/internal/shutdown"
+	"kannon.gyozatech.dev/internal/verification"
+)
+
+var log = logging.Logger("verifier")
+
+type appConfig struct {
+	// MetricsPort is the port of the metrics and health endpoints, 0 disables them
+	MetricsPort uint16 `default:"9090"`
+	// DebugAddr is the address of the pprof and runtime stats server, like localhost:6060, empty disables it
+	DebugAddr string
+	// Log configures the log lines, like APP_LOG_FORMAT=json and APP_LOG_LEVELS=queue=debug
+	Log logging.Config
+	// DB configures the connection pool to the database, like APP_DB_MAXOPENCONNS
+	DB dbconfig.Config
+	// Sentry reports the errors and panics, like APP_SENTRY_DSN
+	Sentry errorreport.Config
+	// Verification are the records checked, like APP_VERIFICATION_SPFINCLUDE
+	Verification verification.Config
+	// Interval between checks of the domains
+	Interval time.Duration `default:"1h"`
+}
+
+func main() {
+	_ = godotenv.Load()
+
+	var config appConfig
+	err := configfile.Load("verifier", &config)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	if err := logging.Setup(config.Log); err != nil {
+		log.Fatalf("invalid log config: %v", err)
+	}
+	stopReports, err := errorreport.Start(config.Sentry, "kannon-verifier")
+	if err != nil {
+		log.Fatalf("invalid sentry config: %v", err)
+	}
+	defer stopReports()
+	defer errorreport.Recover()
+
+	if err := config.Verification.Validate(); err != nil {
+		log.Fatal(err.Error())
+	}
+
+	db, err := sqlc.Conn()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+	config.DB.Apply(db)
+
+	dm, err := domains.NewDomainManager(db)
+	if err != nil {
+		panic(err)
+	}
+	vm, err := verification.NewVerificationManager(db, config.Verification)
+	if err != nil {
+		panic(err)
+	}
+
+	metrics.Serve(config.MetricsPort, health.DB(db))
+	metrics.ServeDebug(config.DebugAddr)
+
+	// the log levels and the database pool change without a restart
+	configfile.Watch(func() {
+		var next appConfig
+		if err := configfile.Load("verifier", &next); err != nil {
+			log.Errorf("cannot reload config: %v", err)
+			return
+		}
+		if err := logging.Setup(next.Log); err != nil {
+			log.Errorf("cannot reload log config: %v", err)
+			return
+		}
+		next.DB.Apply(db)
+		log.Infof("config reloaded")
+	})
+
+	ctx := shutdown.Context()
+	for ctx.Err() == nil {
+		verify(dm, vm)
+		select {
+		case <-ctx.Done():
+		case <-time.After(config.Interval):
+		}
+	}
+	log.Infof("verifier stopped")
+}
+
+func verify(dm domains.DomainManager, vm verification.Manager) {
+	ds, err := dm.GetAllDomains()
+	if err != nil {
+		log.Errorf("cannot get domains: %v", err)
+		return
+	}
+	for _, d := range ds {
+		verified, records, err := vm.VerifyDomain(d)
+		if err != nil {
+			log.Errorf("cannot verify %v: %v", d.Domain, err)
+			continue
+		}
+		for _, r := range records {
+			if r.Status == sqlc.DomainRecordStatusFailed {
+				log.Warnf("[🔎 failed] %v %v record: %v", d.Domain, r.Record, r.Error)
+			}
+		}
+		if verified.Verified != d.Verified {
+			log.Infof("[🔎 verified] %v: %v", d.Domain, verified.Verified)
+		}
+	}
+}
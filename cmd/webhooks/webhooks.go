@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"github.com/joho/godotenv"
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/configfile"
+	"kannon.gyozatech.dev/internal/dbconfig"
+	"kannon.gyozatech.dev/internal/errorreport"
+	"kannon.gyozatech.dev/internal/events"
+	"kannon.gyozatech.dev/internal/health"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/metrics"
+	"kannon.gyozatech.dev/internal/queue"
+	"kannon.gyozatech.dev/internal/shutdown"
+	"kannon.gyozatech.dev/internal/webhooks"
+)
+
+var log = logging.Logger("webhooks")
+
+type appConfig struct {
+	queue.Config
+	// MetricsPort is the port of the metrics and health endpoints, 0 disables them
+	MetricsPort uint16 `default:"9090"`
+	// DebugAddr is the address of the pprof and runtime stats server, like localhost:6060, empty disables it
+	DebugAddr string
+	// Log configures the log lines, like APP_LOG_FORMAT=json and APP_LOG_LEVELS=queue=debug
+	Log logging.Config
+	// DB configures the connection pool to the database, like APP_DB_MAXOPENCONNS
+	DB dbconfig.Config
+	// Sentry reports the errors and panics, like APP_SENTRY_DSN
+	Sentry errorreport.Config
+	// Timeout of webhook requests
+	Timeout time.Duration `default:"10s"`
+	// MaxDeliveries is the number of deliveries sent every second
+	MaxDeliveries uint `default:"100"`
+}
+
+func main() {
+	_ = godotenv.Load()
+
+	var config appConfig
+	err := configfile.Load("webhooks", &config)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	if err := logging.Setup(config.Log); err != nil {
+		log.Fatalf("invalid log config: %v", err)
+	}
+	stopReports, err := errorreport.Start(config.Sentry, "kannon-webhooks")
+	if err != nil {
+		log.Fatalf("invalid sentry config: %v", err)
+	}
+	defer stopReports()
+	defer errorreport.Recover()
+
+	db, err := sqlc.Conn()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+	config.DB.Apply(db)
+
+	wm, err := webhooks.NewWebhookManager(db)
+	if err != nil {
+		panic(err)
+	}
+
+	b, err := queue.Open(config.Config, nil)
+	if err != nil {
+		log.Fatalf("Cannot connect to %v: %v\n", config.Broker, err)
+	}
+	defer b.Close()
+
+	metrics.Serve(config.MetricsPort, health.DB(db), queue.HealthCheck(b))
+	metrics.ServeDebug(config.DebugAddr)
+
+	// the log levels and the database pool change without a restart
+	configfile.Watch(func() {
+		var next appConfig
+		if err := configfile.Load("webhooks", &next); err != nil {
+			log.Errorf("cannot reload config: %v", err)
+			return
+		}
+		if err := logging.Setup(next.Log); err != nil {
+			log.Errorf("cannot reload log config: %v", err)
+			return
+		}
+		next.DB.Apply(db)
+		log.Infof("config reloaded")
+	})
+
+	ctx := shutdown.Context()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer errorreport.Recover()
+		handleEvents(ctx, b, wm)
+		wg.Done()
+	}()
+	go func() {
+		defer errorreport.Recover()
+		deliveryLoop(ctx, wm, &http.Client{Timeout: config.Timeout}, config.MaxDeliveries)
+		wg.Done()
+	}()
+	wg.Wait()
+	log.Infof("webhooks stopped")
+}
+
+// handleEvents enqueues webhook deliveries of the events
+// published on emails subjects
+func handleEvents(ctx context.Context, b queue.Consumer, wm webhooks.Manager) {
+	b.Consume(ctx, "webhooks", func(msg queue.Message) {
+		event, ok, err := events.Parse(msg.Subject(), msg.Data())
+		if err != nil {
+			log.Errorf("cannot parse event on %v: %v", msg.Subject(), err)
+		} else if ok {
+			if err := wm.Enqueue(event); err != nil {
+				log.Errorf("cannot enqueue webhook event: %v", err)
+			}
+		}
+		if err := msg.Ack(); err != nil {
+			log.Errorf("Cannot hack msg to nats: %v\n", err)
+		}
+	})
+}
+
+func deliveryLoop(ctx context.Context, wm webhooks.Manager, client *http.Client, max uint) {
+	for ctx.Err() == nil {
+		deliveries, err := wm.PrepareDeliveries(max, 5*client.Timeout)
+		if err != nil {
+			log.Fatalf("cannot prepare webhook deliveries: %v", err)
+		}
+		var wg sync.WaitGroup
+		for _, d := range deliveries {
+			wg.Add(1)
+			go func(d sqlc.PrepareWebhookDeliveriesRow) {
+				defer errorreport.Recover()
+				deliver(wm, client, d)
+				wg.Done()
+			}(d)
+		}
+		wg.Wait()
+		select {
+		case <-ctx.Done():
+		case <-time.After(1 * time.Second):
+		}
+	}
+}
+
+func deliver(wm webhooks.Manager, client *http.Client, d sqlc.PrepareWebhookDeliveriesRow) {
+	code, err := webhooks.Deliver(client, d.Url, d.Secret, d.Event, d.Payload)
+	if err != nil {
+		log.Warnf("[🪝 failed] %v %v: %v", d.Event, d.Url, err)
+		if err := wm.SetFailed(d.ID, d.Attempts, code, err); err != nil {
+			log.Errorf("cannot set webhook delivery failed: %v", err)
+		}
+		return
+	}
+	log.Infof("[🪝 delivered] %v %v", d.Event, d.Url)
+	if err := wm.SetDelivered(d.ID, code); err != nil {
+		log.Errorf("cannot set webhook delivery delivered: %v", err)
+	}
+}
@@ -0,0 +1,7 @@
+-- migrate:up
+
+ALTER TABLE templates ADD COLUMN text varchar NOT NULL DEFAULT '';
+
+-- migrate:down
+
+ALTER TABLE templates DROP COLUMN text;
@@ -0,0 +1,14 @@
+-- migrate:up
+
+CREATE TABLE attachments (
+    id SERIAL PRIMARY KEY,
+    message_id integer NOT NULL,
+    filename varchar(255) NOT NULL,
+    content bytea NOT NULL,
+    FOREIGN KEY (message_id) REFERENCES messages(id)
+);
+CREATE INDEX ON attachments (message_id);
+
+-- migrate:down
+
+DROP TABLE attachments;
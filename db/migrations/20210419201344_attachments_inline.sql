@@ -0,0 +1,7 @@
+-- migrate:up
+
+ALTER TABLE attachments ADD COLUMN inline boolean NOT NULL DEFAULT false;
+
+-- migrate:down
+
+ALTER TABLE attachments DROP COLUMN inline;
@@ -0,0 +1,9 @@
+-- migrate:up
+
+ALTER TABLE messages ADD COLUMN cc varchar(320)[] NOT NULL DEFAULT '{}';
+ALTER TABLE messages ADD COLUMN bcc varchar(320)[] NOT NULL DEFAULT '{}';
+
+-- migrate:down
+
+ALTER TABLE messages DROP COLUMN bcc;
+ALTER TABLE messages DROP COLUMN cc;
@@ -0,0 +1,7 @@
+-- migrate:up
+
+ALTER TABLE messages ADD COLUMN headers jsonb NOT NULL DEFAULT '{}';
+
+-- migrate:down
+
+ALTER TABLE messages DROP COLUMN headers;
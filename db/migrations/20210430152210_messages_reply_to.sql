@@ -0,0 +1,7 @@
+-- migrate:up
+
+ALTER TABLE messages ADD COLUMN reply_to varchar(320) NOT NULL DEFAULT '';
+
+-- migrate:down
+
+ALTER TABLE messages DROP COLUMN reply_to;
@@ -0,0 +1,15 @@
+-- migrate:up
+
+ALTER TABLE templates ADD COLUMN version integer NOT NULL DEFAULT 1;
+ALTER TABLE templates ADD COLUMN active boolean NOT NULL DEFAULT true;
+CREATE UNIQUE INDEX ON templates (template_id, version);
+
+ALTER TABLE messages ADD COLUMN template_version integer NOT NULL DEFAULT 1;
+
+-- migrate:down
+
+ALTER TABLE messages DROP COLUMN template_version;
+
+DROP INDEX templates_template_id_version_idx;
+ALTER TABLE templates DROP COLUMN active;
+ALTER TABLE templates DROP COLUMN version;
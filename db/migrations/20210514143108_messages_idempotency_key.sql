@@ -0,0 +1,9 @@
+-- migrate:up
+
+ALTER TABLE messages ADD COLUMN idempotency_key varchar(255);
+CREATE UNIQUE INDEX ON messages (domain, idempotency_key) WHERE idempotency_key IS NOT NULL;
+
+-- migrate:down
+
+DROP INDEX messages_domain_idempotency_key_idx;
+ALTER TABLE messages DROP COLUMN idempotency_key;
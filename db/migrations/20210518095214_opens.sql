@@ -0,0 +1,15 @@
+-- migrate:up
+
+CREATE TABLE opens (
+    id SERIAL PRIMARY KEY,
+    message_id varchar(50) NOT NULL,
+    email varchar(320) NOT NULL,
+    ip varchar(45) NOT NULL DEFAULT '',
+    user_agent varchar NOT NULL DEFAULT '',
+    timestamp timestamp with time zone NOT NULL
+);
+CREATE INDEX ON opens (message_id);
+
+-- migrate:down
+
+DROP TABLE opens;
@@ -0,0 +1,17 @@
+-- migrate:up
+
+CREATE TYPE suppression_reason AS ENUM ('unsubscribed');
+
+CREATE TABLE suppressions (
+    id SERIAL PRIMARY KEY,
+    domain varchar(254) NOT NULL,
+    email varchar(320) NOT NULL,
+    reason suppression_reason NOT NULL,
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    UNIQUE (domain, email)
+);
+
+-- migrate:down
+
+DROP TABLE suppressions;
+DROP TYPE suppression_reason;
@@ -0,0 +1,11 @@
+-- migrate:up
+
+ALTER TYPE suppression_reason ADD VALUE 'bounced';
+ALTER TYPE suppression_reason ADD VALUE 'complained';
+ALTER TYPE suppression_reason ADD VALUE 'manual';
+
+ALTER TYPE sending_pool_status ADD VALUE 'suppressed';
+
+-- migrate:down
+
+-- enum values cannot be removed
@@ -0,0 +1,9 @@
+-- migrate:up
+
+CREATE TYPE bounce_type AS ENUM ('none', 'soft', 'hard');
+ALTER TABLE sending_pool_emails ADD COLUMN bounce_type bounce_type NOT NULL DEFAULT 'none';
+
+-- migrate:down
+
+ALTER TABLE sending_pool_emails DROP COLUMN bounce_type;
+DROP TYPE bounce_type;
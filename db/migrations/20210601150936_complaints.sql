@@ -0,0 +1,14 @@
+-- migrate:up
+
+CREATE TABLE complaints (
+    id SERIAL PRIMARY KEY,
+    message_id varchar(50) NOT NULL,
+    email varchar(320) NOT NULL,
+    feedback_type varchar(50) NOT NULL DEFAULT '',
+    timestamp timestamp with time zone NOT NULL
+);
+CREATE INDEX ON complaints (message_id);
+
+-- migrate:down
+
+DROP TABLE complaints;
@@ -0,0 +1,34 @@
+-- migrate:up
+
+CREATE TABLE webhooks (
+    id SERIAL PRIMARY KEY,
+    domain varchar(254) NOT NULL,
+    url varchar NOT NULL,
+    secret varchar(64) NOT NULL,
+    events varchar(20)[] NOT NULL,
+    created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+CREATE INDEX ON webhooks (domain);
+
+CREATE TYPE webhook_delivery_status AS ENUM ('pending', 'delivered', 'failed');
+
+CREATE TABLE webhook_deliveries (
+    id SERIAL PRIMARY KEY,
+    webhook_id integer NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event varchar(20) NOT NULL,
+    payload jsonb NOT NULL,
+    status webhook_delivery_status NOT NULL DEFAULT 'pending',
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_time timestamp with time zone NOT NULL DEFAULT now(),
+    response_code integer NOT NULL DEFAULT 0,
+    error_msg varchar NOT NULL DEFAULT '',
+    created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+CREATE INDEX ON webhook_deliveries (webhook_id);
+CREATE INDEX ON webhook_deliveries (next_attempt_time, status);
+
+-- migrate:down
+
+DROP TABLE webhook_deliveries;
+DROP TYPE webhook_delivery_status;
+DROP TABLE webhooks;
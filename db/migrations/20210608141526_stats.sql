@@ -0,0 +1,16 @@
+-- migrate:up
+
+CREATE TABLE stats (
+    id SERIAL PRIMARY KEY,
+    domain varchar(254) NOT NULL,
+    message_id varchar(50) NOT NULL,
+    type varchar(20) NOT NULL,
+    hour timestamp with time zone NOT NULL,
+    count integer NOT NULL DEFAULT 0,
+    UNIQUE (domain, message_id, type, hour)
+);
+CREATE INDEX ON stats (domain, hour);
+
+-- migrate:down
+
+DROP TABLE stats;
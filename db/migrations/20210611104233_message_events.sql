@@ -0,0 +1,15 @@
+-- migrate:up
+
+CREATE TABLE message_events (
+    id SERIAL PRIMARY KEY,
+    message_id varchar(50) NOT NULL,
+    email varchar(320) NOT NULL,
+    type varchar(20) NOT NULL,
+    data jsonb NOT NULL DEFAULT '{}',
+    timestamp timestamp with time zone NOT NULL
+);
+CREATE INDEX ON message_events (message_id);
+
+-- migrate:down
+
+DROP TABLE message_events;
@@ -0,0 +1,15 @@
+-- migrate:up
+
+ALTER TABLE messages ADD COLUMN created_at timestamp with time zone NOT NULL DEFAULT now();
+CREATE INDEX ON messages (domain, created_at);
+CREATE INDEX ON messages (template_id);
+CREATE INDEX ON sending_pool_emails (message_id);
+CREATE INDEX ON sending_pool_emails (email);
+
+-- migrate:down
+
+DROP INDEX sending_pool_emails_email_idx;
+DROP INDEX sending_pool_emails_message_id_idx;
+DROP INDEX messages_template_id_idx;
+DROP INDEX messages_domain_created_at_idx;
+ALTER TABLE messages DROP COLUMN created_at;
@@ -0,0 +1,9 @@
+-- migrate:up
+
+ALTER TABLE domains ADD COLUMN retention_days integer NOT NULL DEFAULT 0;
+CREATE INDEX ON message_events (timestamp);
+
+-- migrate:down
+
+DROP INDEX message_events_timestamp_idx;
+ALTER TABLE domains DROP COLUMN retention_days;
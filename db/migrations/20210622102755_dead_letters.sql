@@ -0,0 +1,18 @@
+-- migrate:up
+
+CREATE TABLE dead_letters (
+    id SERIAL PRIMARY KEY,
+    domain varchar(254) NOT NULL DEFAULT '',
+    subject varchar(100) NOT NULL DEFAULT '',
+    message_id varchar(50) NOT NULL DEFAULT '',
+    email varchar(320) NOT NULL DEFAULT '',
+    payload bytea NOT NULL DEFAULT '',
+    reason varchar NOT NULL,
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    requeued_at timestamp with time zone
+);
+CREATE INDEX ON dead_letters (domain);
+
+-- migrate:down
+
+DROP TABLE dead_letters;
@@ -0,0 +1,9 @@
+-- migrate:up
+
+ALTER TABLE sending_pool_emails ADD COLUMN priority smallint NOT NULL DEFAULT 0;
+CREATE INDEX ON sending_pool_emails (priority DESC, scheduled_time) WHERE status = 'scheduled';
+
+-- migrate:down
+
+DROP INDEX sending_pool_emails_priority_scheduled_time_idx;
+ALTER TABLE sending_pool_emails DROP COLUMN priority;
@@ -0,0 +1,13 @@
+-- migrate:up
+
+ALTER TABLE domains ADD COLUMN rate_per_second integer NOT NULL DEFAULT 0;
+ALTER TABLE domains ADD COLUMN rate_per_hour integer NOT NULL DEFAULT 0;
+ALTER TABLE sending_pool_emails ADD COLUMN dispatched_at timestamp with time zone;
+CREATE INDEX ON sending_pool_emails (dispatched_at);
+
+-- migrate:down
+
+DROP INDEX sending_pool_emails_dispatched_at_idx;
+ALTER TABLE sending_pool_emails DROP COLUMN dispatched_at;
+ALTER TABLE domains DROP COLUMN rate_per_hour;
+ALTER TABLE domains DROP COLUMN rate_per_second;
@@ -0,0 +1,7 @@
+-- migrate:up
+
+ALTER TABLE domains ADD COLUMN ip_pool character varying(50) NOT NULL DEFAULT '';
+
+-- migrate:down
+
+ALTER TABLE domains DROP COLUMN ip_pool;
@@ -0,0 +1,11 @@
+-- migrate:up
+
+ALTER TABLE domains ADD COLUMN dkim_ed25519_private_key character varying NOT NULL DEFAULT '';
+ALTER TABLE domains ADD COLUMN dkim_ed25519_public_key character varying NOT NULL DEFAULT '';
+ALTER TABLE domains ADD COLUMN dkim_signing character varying(10) NOT NULL DEFAULT 'rsa';
+
+-- migrate:down
+
+ALTER TABLE domains DROP COLUMN dkim_signing;
+ALTER TABLE domains DROP COLUMN dkim_ed25519_public_key;
+ALTER TABLE domains DROP COLUMN dkim_ed25519_private_key;
@@ -0,0 +1,27 @@
+-- migrate:up
+
+ALTER TABLE domains ADD COLUMN dkim_selector character varying(63) NOT NULL DEFAULT 'kannon';
+ALTER TABLE domains ADD COLUMN dkim_ed25519_selector character varying(63) NOT NULL DEFAULT 'kannon-ed25519';
+
+CREATE TYPE dkim_key_status AS ENUM ('pending', 'retiring', 'retired');
+
+CREATE TABLE dkim_keys (
+    id SERIAL PRIMARY KEY,
+    domain varchar(254) NOT NULL,
+    selector varchar(63) NOT NULL,
+    algorithm varchar(10) NOT NULL,
+    private_key varchar NOT NULL,
+    public_key varchar NOT NULL,
+    status dkim_key_status NOT NULL DEFAULT 'pending',
+    retire_at timestamp with time zone,
+    created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX ON dkim_keys (domain, selector);
+CREATE INDEX ON dkim_keys (status, retire_at);
+
+-- migrate:down
+
+DROP TABLE dkim_keys;
+DROP TYPE dkim_key_status;
+ALTER TABLE domains DROP COLUMN dkim_ed25519_selector;
+ALTER TABLE domains DROP COLUMN dkim_selector;
@@ -0,0 +1,7 @@
+-- migrate:up
+
+ALTER TABLE domains ADD COLUMN dkim_headers character varying(100)[] NOT NULL DEFAULT '{}';
+
+-- migrate:down
+
+ALTER TABLE domains DROP COLUMN dkim_headers;
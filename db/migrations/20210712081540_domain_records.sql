@@ -0,0 +1,21 @@
+-- migrate:up
+
+ALTER TABLE domains ADD COLUMN verified boolean NOT NULL DEFAULT false;
+
+CREATE TYPE domain_record_status AS ENUM ('verified', 'failed');
+
+CREATE TABLE domain_records (
+    id SERIAL PRIMARY KEY,
+    domain varchar(254) NOT NULL,
+    record varchar(20) NOT NULL,
+    status domain_record_status NOT NULL,
+    error varchar NOT NULL DEFAULT '',
+    checked_at timestamp with time zone NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX ON domain_records (domain, record);
+
+-- migrate:down
+
+DROP TABLE domain_records;
+DROP TYPE domain_record_status;
+ALTER TABLE domains DROP COLUMN verified;
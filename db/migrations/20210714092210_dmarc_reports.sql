@@ -0,0 +1,30 @@
+-- migrate:up
+
+CREATE TABLE dmarc_reports (
+    id SERIAL PRIMARY KEY,
+    org_name varchar(254) NOT NULL,
+    report_id varchar(254) NOT NULL,
+    domain varchar(254) NOT NULL,
+    begin_at timestamp with time zone NOT NULL,
+    end_at timestamp with time zone NOT NULL,
+    created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX ON dmarc_reports (org_name, report_id);
+
+CREATE TABLE dmarc_records (
+    id SERIAL PRIMARY KEY,
+    report_id integer NOT NULL REFERENCES dmarc_reports(id) ON DELETE CASCADE,
+    header_from varchar(254) NOT NULL,
+    source_ip varchar(45) NOT NULL,
+    count bigint NOT NULL,
+    disposition varchar(20) NOT NULL,
+    dkim_aligned boolean NOT NULL,
+    spf_aligned boolean NOT NULL,
+    begin_at timestamp with time zone NOT NULL
+);
+CREATE INDEX ON dmarc_records (header_from, begin_at);
+
+-- migrate:down
+
+DROP TABLE dmarc_records;
+DROP TABLE dmarc_reports;
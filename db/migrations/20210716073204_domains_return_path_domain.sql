@@ -0,0 +1,7 @@
+-- migrate:up
+
+ALTER TABLE domains ADD COLUMN return_path_domain character varying(254) NOT NULL DEFAULT '';
+
+-- migrate:down
+
+ALTER TABLE domains DROP COLUMN return_path_domain;
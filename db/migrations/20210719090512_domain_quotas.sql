@@ -0,0 +1,21 @@
+-- migrate:up
+
+ALTER TABLE domains ADD COLUMN daily_quota integer NOT NULL DEFAULT 0;
+ALTER TABLE domains ADD COLUMN monthly_quota integer NOT NULL DEFAULT 0;
+
+ALTER TYPE sending_pool_status ADD VALUE 'quota_exceeded';
+
+CREATE TABLE domain_usage (
+    id SERIAL PRIMARY KEY,
+    domain varchar(254) NOT NULL,
+    day date NOT NULL,
+    sent integer NOT NULL DEFAULT 0
+);
+CREATE UNIQUE INDEX ON domain_usage (domain, day);
+
+-- migrate:down
+
+DROP TABLE domain_usage;
+ALTER TABLE domains DROP COLUMN monthly_quota;
+ALTER TABLE domains DROP COLUMN daily_quota;
+-- enum values cannot be removed
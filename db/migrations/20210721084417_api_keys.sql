@@ -0,0 +1,27 @@
+-- migrate:up
+
+CREATE TABLE api_keys (
+    id SERIAL PRIMARY KEY,
+    domain varchar(254) NOT NULL,
+    name varchar(100) NOT NULL DEFAULT '',
+    prefix varchar(8) NOT NULL,
+    key_hash varchar(64) NOT NULL,
+    scopes varchar(20)[] NOT NULL,
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    last_used_at timestamp with time zone,
+    revoked_at timestamp with time zone
+);
+CREATE UNIQUE INDEX ON api_keys (domain, key_hash);
+
+-- the key of every domain becomes its default admin key
+INSERT INTO api_keys (domain, name, prefix, key_hash, scopes)
+    SELECT domain, 'default', left(key, 8), encode(sha256(key::bytea), 'hex'), '{admin}'
+    FROM domains;
+
+ALTER TABLE domains DROP COLUMN key;
+
+-- migrate:down
+
+-- keys are stored hashed, domains get a new key
+ALTER TABLE domains ADD COLUMN key character varying(50) NOT NULL DEFAULT md5(random()::text);
+DROP TABLE api_keys;
@@ -0,0 +1,19 @@
+-- migrate:up
+
+-- keys replaced by a rotation stay valid until expires_at
+ALTER TABLE api_keys ADD COLUMN expires_at timestamp with time zone;
+
+CREATE TABLE api_key_calls (
+    id SERIAL PRIMARY KEY,
+    api_key_id integer NOT NULL,
+    domain varchar(254) NOT NULL,
+    method varchar(100) NOT NULL,
+    allowed boolean NOT NULL,
+    called_at timestamp with time zone NOT NULL DEFAULT now()
+);
+CREATE INDEX ON api_key_calls (domain, called_at);
+
+-- migrate:down
+
+DROP TABLE api_key_calls;
+ALTER TABLE api_keys DROP COLUMN expires_at;
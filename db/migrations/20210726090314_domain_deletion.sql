@@ -0,0 +1,13 @@
+-- migrate:up
+
+-- deleted domains are purged at purge_at, end of their grace period
+ALTER TABLE domains ADD COLUMN deleted_at timestamp with time zone;
+ALTER TABLE domains ADD COLUMN purge_at timestamp with time zone;
+
+ALTER TYPE sending_pool_status ADD VALUE 'canceled';
+
+-- migrate:down
+
+ALTER TABLE domains DROP COLUMN purge_at;
+ALTER TABLE domains DROP COLUMN deleted_at;
+-- enum values cannot be removed
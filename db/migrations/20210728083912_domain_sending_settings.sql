@@ -0,0 +1,21 @@
+-- migrate:up
+
+-- defaults of the emails of a domain, the sender and the headers
+-- of the messages override them
+ALTER TABLE domains ADD COLUMN default_from_email character varying(254) DEFAULT '' NOT NULL;
+ALTER TABLE domains ADD COLUMN default_from_name character varying(254) DEFAULT '' NOT NULL;
+ALTER TABLE domains ADD COLUMN default_headers jsonb DEFAULT '{}' NOT NULL;
+ALTER TABLE domains ADD COLUMN open_tracking boolean DEFAULT true NOT NULL;
+ALTER TABLE domains ADD COLUMN click_tracking boolean DEFAULT true NOT NULL;
+ALTER TABLE domains ADD COLUMN footer_html character varying DEFAULT '' NOT NULL;
+ALTER TABLE domains ADD COLUMN footer_text character varying DEFAULT '' NOT NULL;
+
+-- migrate:down
+
+ALTER TABLE domains DROP COLUMN footer_text;
+ALTER TABLE domains DROP COLUMN footer_html;
+ALTER TABLE domains DROP COLUMN click_tracking;
+ALTER TABLE domains DROP COLUMN open_tracking;
+ALTER TABLE domains DROP COLUMN default_headers;
+ALTER TABLE domains DROP COLUMN default_from_name;
+ALTER TABLE domains DROP COLUMN default_from_email;
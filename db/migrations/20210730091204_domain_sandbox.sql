@@ -0,0 +1,11 @@
+-- migrate:up
+
+-- emails of sandbox domains are not delivered, the sender simulates
+-- their delivery bouncing sandbox_bounce_percent of the recipients
+ALTER TABLE domains ADD COLUMN sandbox boolean DEFAULT false NOT NULL;
+ALTER TABLE domains ADD COLUMN sandbox_bounce_percent integer DEFAULT 0 NOT NULL;
+
+-- migrate:down
+
+ALTER TABLE domains DROP COLUMN sandbox_bounce_percent;
+ALTER TABLE domains DROP COLUMN sandbox;
@@ -0,0 +1,10 @@
+-- migrate:up
+
+-- emails of paused domains stay scheduled until the domain is resumed
+ALTER TABLE domains ADD COLUMN paused_at timestamp with time zone;
+ALTER TABLE domains ADD COLUMN paused_reason character varying(254) DEFAULT '' NOT NULL;
+
+-- migrate:down
+
+ALTER TABLE domains DROP COLUMN paused_reason;
+ALTER TABLE domains DROP COLUMN paused_at;
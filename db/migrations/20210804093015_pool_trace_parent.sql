@@ -0,0 +1,9 @@
+-- migrate:up
+
+-- traceparent header of the send request of the email, the parent of
+-- the spans of its dispatch, delivery and delivery events
+ALTER TABLE sending_pool_emails ADD COLUMN trace_parent character varying(55) DEFAULT '' NOT NULL;
+
+-- migrate:down
+
+ALTER TABLE sending_pool_emails DROP COLUMN trace_parent;
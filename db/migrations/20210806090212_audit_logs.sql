@@ -0,0 +1,17 @@
+-- migrate:up
+
+CREATE TABLE audit_logs (
+    id SERIAL PRIMARY KEY,
+    actor varchar NOT NULL,
+    method varchar(100) NOT NULL,
+    domain varchar(254) NOT NULL DEFAULT '',
+    request jsonb NOT NULL DEFAULT '{}',
+    before_value jsonb NOT NULL DEFAULT 'null',
+    after_value jsonb NOT NULL DEFAULT 'null',
+    created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+CREATE INDEX ON audit_logs (domain, created_at);
+
+-- migrate:down
+
+DROP TABLE audit_logs;
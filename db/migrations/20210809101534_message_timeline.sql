@@ -0,0 +1,17 @@
+-- migrate:up
+
+CREATE TABLE message_timeline (
+    id SERIAL PRIMARY KEY,
+    message_id varchar(50) NOT NULL,
+    email varchar(320) NOT NULL,
+    stage varchar(20) NOT NULL,
+    smtp_code integer NOT NULL DEFAULT 0,
+    smtp_response varchar NOT NULL DEFAULT '',
+    data jsonb NOT NULL DEFAULT '{}',
+    timestamp timestamp with time zone NOT NULL
+);
+CREATE INDEX ON message_timeline (message_id);
+
+-- migrate:down
+
+DROP TABLE message_timeline;
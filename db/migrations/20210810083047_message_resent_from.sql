@@ -0,0 +1,8 @@
+-- migrate:up
+
+-- message_id of the message sent again by a resend, empty for the other sends
+ALTER TABLE messages ADD COLUMN resent_from character varying(50) DEFAULT '' NOT NULL;
+
+-- migrate:down
+
+ALTER TABLE messages DROP COLUMN resent_from;
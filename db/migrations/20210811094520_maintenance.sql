@@ -0,0 +1,14 @@
+-- migrate:up
+
+-- maintenance is the switch halting every dispatch and send,
+-- a single row created by the first halt
+CREATE TABLE maintenance (
+    id boolean PRIMARY KEY DEFAULT true CHECK (id),
+    halted boolean NOT NULL DEFAULT false,
+    reason varchar NOT NULL DEFAULT '',
+    changed_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+-- migrate:down
+
+DROP TABLE maintenance;
@@ -0,0 +1,17 @@
+-- migrate:up
+
+CREATE TABLE smtp_responses (
+    id SERIAL PRIMARY KEY,
+    domain varchar(254) NOT NULL,
+    provider varchar(254) NOT NULL,
+    code integer NOT NULL,
+    enhanced_code varchar(16) NOT NULL DEFAULT '',
+    hour timestamp with time zone NOT NULL,
+    count integer NOT NULL DEFAULT 0,
+    UNIQUE (domain, provider, code, enhanced_code, hour)
+);
+CREATE INDEX ON smtp_responses (domain, hour);
+
+-- migrate:down
+
+DROP TABLE smtp_responses;
@@ -0,0 +1,12 @@
+-- migrate:up
+
+ALTER TABLE templates ADD COLUMN name varchar(100) NOT NULL DEFAULT '';
+ALTER TABLE templates ADD COLUMN deleted_at timestamp with time zone;
+-- templates with more versions were uploaded, not created by SendHTML
+UPDATE templates SET name = template_id
+    WHERE template_id IN (SELECT template_id FROM templates WHERE version > 1);
+
+-- migrate:down
+
+ALTER TABLE templates DROP COLUMN deleted_at;
+ALTER TABLE templates DROP COLUMN name;
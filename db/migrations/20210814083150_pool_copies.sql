@@ -0,0 +1,9 @@
+-- migrate:up
+
+-- the cc and bcc recipients of a message are sent with the email of its first
+-- recipient, until they are tried once
+ALTER TABLE sending_pool_emails ADD COLUMN copies boolean NOT NULL DEFAULT false;
+
+-- migrate:down
+
+ALTER TABLE sending_pool_emails DROP COLUMN copies;
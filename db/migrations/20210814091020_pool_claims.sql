@@ -0,0 +1,10 @@
+-- migrate:up
+
+-- emails claimed by a dispatcher and not published yet
+ALTER TABLE sending_pool_emails ADD COLUMN claimed_at timestamp with time zone;
+CREATE INDEX sending_pool_emails_claimed_at_idx ON sending_pool_emails (claimed_at) WHERE status = 'sending';
+
+-- migrate:down
+
+DROP INDEX sending_pool_emails_claimed_at_idx;
+ALTER TABLE sending_pool_emails DROP COLUMN claimed_at;
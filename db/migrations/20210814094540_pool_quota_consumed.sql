@@ -0,0 +1,9 @@
+-- migrate:up
+
+-- emails counted in the quota of their domain, retries and deferrals are not counted again
+ALTER TABLE sending_pool_emails ADD COLUMN quota_consumed boolean NOT NULL DEFAULT false;
+UPDATE sending_pool_emails SET quota_consumed = true WHERE dispatched_at IS NOT NULL;
+
+-- migrate:down
+
+ALTER TABLE sending_pool_emails DROP COLUMN quota_consumed;
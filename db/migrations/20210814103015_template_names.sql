@@ -0,0 +1,20 @@
+-- migrate:up
+
+-- templates of SendHTML have a generated id, a single version and at most the
+-- message of their SendHTML, the other templates were created in the database
+-- for SendTemplate and are named after their id
+UPDATE templates AS t SET name = t.template_id
+    WHERE t.name = '' AND NOT (
+        t.template_id LIKE 'template\_c%@' || t.domain
+        AND NOT EXISTS (
+            SELECT 1 FROM templates AS v WHERE v.template_id = t.template_id AND v.version > 1
+        )
+        AND (SELECT COUNT(*) FROM messages AS m WHERE m.template_id = t.template_id) <= 1
+    );
+-- every version has the name of the template, the first is unique
+CREATE UNIQUE INDEX templates_domain_name_idx ON templates (domain, name)
+    WHERE name <> '' AND deleted_at IS NULL AND version = 1;
+
+-- migrate:down
+
+DROP INDEX templates_domain_name_idx;
@@ -0,0 +1,14 @@
+-- migrate:up
+
+-- deliveries of the IPs in warm-up to every provider by day, counted by every sender
+CREATE TABLE warmup_usage (
+    ip varchar(45) NOT NULL,
+    provider varchar(64) NOT NULL,
+    day date NOT NULL,
+    count integer NOT NULL DEFAULT 0,
+    PRIMARY KEY (ip, provider, day)
+);
+
+-- migrate:down
+
+DROP TABLE warmup_usage;
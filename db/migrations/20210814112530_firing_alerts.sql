@@ -0,0 +1,16 @@
+-- migrate:up
+
+-- alerts firing by rule and domain, shared by every stats service
+CREATE TABLE firing_alerts (
+    rule varchar(32) NOT NULL,
+    domain varchar(254) NOT NULL DEFAULT '',
+    value double precision NOT NULL,
+    threshold double precision NOT NULL,
+    message varchar NOT NULL,
+    fired_at timestamptz NOT NULL,
+    PRIMARY KEY (rule, domain)
+);
+
+-- migrate:down
+
+DROP TABLE firing_alerts;
@@ -0,0 +1,13 @@
+-- migrate:up
+
+-- events counted in the stats rollups, a redelivered event is counted once
+CREATE TABLE counted_events (
+    id varchar(64) PRIMARY KEY,
+    message_id varchar(50) NOT NULL,
+    counted_at timestamptz NOT NULL DEFAULT NOW()
+);
+CREATE INDEX ON counted_events (message_id);
+
+-- migrate:down
+
+DROP TABLE counted_events;
@@ -0,0 +1,13 @@
+-- migrate:up
+
+-- cursor of the daily exports of the events of a domain: the days before
+-- exported_before are exported with the events up to last_event_id
+CREATE TABLE event_exports (
+    domain varchar(254) PRIMARY KEY,
+    exported_before timestamptz NOT NULL,
+    last_event_id int NOT NULL
+);
+
+-- migrate:down
+
+DROP TABLE event_exports;
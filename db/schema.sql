@@ -9,6 +9,38 @@ SET xmloption = content;
 SET client_min_messages = warning;
 SET row_security = off;
 
+--
+-- Name: bounce_type; Type: TYPE; Schema: public; Owner: -
+--
+
+CREATE TYPE public.bounce_type AS ENUM (
+    'none',
+    'soft',
+    'hard'
+);
+
+
+--
+-- Name: dkim_key_status; Type: TYPE; Schema: public; Owner: -
+--
+
+CREATE TYPE public.dkim_key_status AS ENUM (
+    'pending',
+    'retiring',
+    'retired'
+);
+
+
+--
+-- Name: domain_record_status; Type: TYPE; Schema: public; Owner: -
+--
+
+CREATE TYPE public.domain_record_status AS ENUM (
+    'verified',
+    'failed'
+);
+
+
 --
 -- Name: sending_pool_status; Type: TYPE; Schema: public; Owner: -
 --
@@ -18,7 +50,33 @@ CREATE TYPE public.sending_pool_status AS ENUM (
     'sending',
     'sent',
     'scheduled',
-    'error'
+    'error',
+    'suppressed',
+    'quota_exceeded',
+    'canceled'
+);
+
+
+--
+-- Name: suppression_reason; Type: TYPE; Schema: public; Owner: -
+--
+
+CREATE TYPE public.suppression_reason AS ENUM (
+    'unsubscribed',
+    'bounced',
+    'complained',
+    'manual'
+);
+
+
+--
+-- Name: webhook_delivery_status; Type: TYPE; Schema: public; Owner: -
+--
+
+CREATE TYPE public.webhook_delivery_status AS ENUM (
+    'pending',
+    'delivered',
+    'failed'
 );
 
 
@@ -27,24 +85,62 @@ SET default_tablespace = '';
 SET default_table_access_method = heap;
 
 --
--- Name: domains; Type: TABLE; Schema: public; Owner: -
+-- Name: api_key_calls; Type: TABLE; Schema: public; Owner: -
 --
 
-CREATE TABLE public.domains (
+CREATE TABLE public.api_key_calls (
     id integer NOT NULL,
+    api_key_id integer NOT NULL,
     domain character varying(254) NOT NULL,
+    method character varying(100) NOT NULL,
+    allowed boolean NOT NULL,
+    called_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: api_key_calls_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.api_key_calls_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: api_key_calls_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.api_key_calls_id_seq OWNED BY public.api_key_calls.id;
+
+
+--
+-- Name: api_keys; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.api_keys (
+    id integer NOT NULL,
+    domain character varying(254) NOT NULL,
+    name character varying(100) DEFAULT ''::character varying NOT NULL,
+    prefix character varying(8) NOT NULL,
+    key_hash character varying(64) NOT NULL,
+    scopes character varying(20)[] NOT NULL,
     created_at timestamp with time zone DEFAULT now() NOT NULL,
-    key character varying(50) NOT NULL,
-    dkim_private_key character varying NOT NULL,
-    dkim_public_key character varying NOT NULL
+    last_used_at timestamp with time zone,
+    revoked_at timestamp with time zone,
+    expires_at timestamp with time zone
 );
 
 
 --
--- Name: domains_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+-- Name: api_keys_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-CREATE SEQUENCE public.domains_id_seq
+CREATE SEQUENCE public.api_keys_id_seq
     AS integer
     START WITH 1
     INCREMENT BY 1
@@ -54,32 +150,30 @@ CREATE SEQUENCE public.domains_id_seq
 
 
 --
--- Name: domains_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+-- Name: api_keys_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
 --
 
-ALTER SEQUENCE public.domains_id_seq OWNED BY public.domains.id;
+ALTER SEQUENCE public.api_keys_id_seq OWNED BY public.api_keys.id;
 
 
 --
--- Name: messages; Type: TABLE; Schema: public; Owner: -
+-- Name: attachments; Type: TABLE; Schema: public; Owner: -
 --
 
-CREATE TABLE public.messages (
+CREATE TABLE public.attachments (
     id integer NOT NULL,
-    message_id character varying(50) NOT NULL,
-    subject character varying NOT NULL,
-    sender_email character varying(320) NOT NULL,
-    sender_alias character varying(100) NOT NULL,
-    template_id character varying(50) NOT NULL,
-    domain character varying(254) NOT NULL
+    message_id integer NOT NULL,
+    filename character varying(255) NOT NULL,
+    content bytea NOT NULL,
+    inline boolean DEFAULT false NOT NULL
 );
 
 
 --
--- Name: messages_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+-- Name: attachments_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-CREATE SEQUENCE public.messages_id_seq
+CREATE SEQUENCE public.attachments_id_seq
     AS integer
     START WITH 1
     INCREMENT BY 1
@@ -89,43 +183,66 @@ CREATE SEQUENCE public.messages_id_seq
 
 
 --
--- Name: messages_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+-- Name: attachments_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
 --
 
-ALTER SEQUENCE public.messages_id_seq OWNED BY public.messages.id;
+ALTER SEQUENCE public.attachments_id_seq OWNED BY public.attachments.id;
 
 
 --
--- Name: schema_migrations; Type: TABLE; Schema: public; Owner: -
+-- Name: audit_logs; Type: TABLE; Schema: public; Owner: -
 --
 
-CREATE TABLE public.schema_migrations (
-    version character varying(255) NOT NULL
+CREATE TABLE public.audit_logs (
+    id integer NOT NULL,
+    actor character varying NOT NULL,
+    method character varying(100) NOT NULL,
+    domain character varying(254) DEFAULT ''::character varying NOT NULL,
+    request jsonb DEFAULT '{}'::jsonb NOT NULL,
+    before_value jsonb DEFAULT 'null'::jsonb NOT NULL,
+    after_value jsonb DEFAULT 'null'::jsonb NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
 );
 
 
 --
--- Name: sending_pool_emails; Type: TABLE; Schema: public; Owner: -
+-- Name: audit_logs_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-CREATE TABLE public.sending_pool_emails (
+CREATE SEQUENCE public.audit_logs_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: audit_logs_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.audit_logs_id_seq OWNED BY public.audit_logs.id;
+
+
+--
+-- Name: complaints; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.complaints (
     id integer NOT NULL,
-    status public.sending_pool_status DEFAULT 'initializing'::public.sending_pool_status NOT NULL,
-    scheduled_time timestamp with time zone DEFAULT now() NOT NULL,
-    original_scheduled_time timestamp with time zone NOT NULL,
-    trial smallint DEFAULT 0 NOT NULL,
+    message_id character varying(50) NOT NULL,
     email character varying(320) NOT NULL,
-    message_id integer NOT NULL,
-    error_msg character varying DEFAULT ''::character varying NOT NULL,
-    error_code integer DEFAULT 0 NOT NULL
+    feedback_type character varying(50) DEFAULT ''::character varying NOT NULL,
+    "timestamp" timestamp with time zone NOT NULL
 );
 
 
 --
--- Name: sending_pool_emails_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+-- Name: complaints_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-CREATE SEQUENCE public.sending_pool_emails_id_seq
+CREATE SEQUENCE public.complaints_id_seq
     AS integer
     START WITH 1
     INCREMENT BY 1
@@ -135,17 +252,45 @@ CREATE SEQUENCE public.sending_pool_emails_id_seq
 
 
 --
--- Name: sending_pool_emails_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+-- Name: complaints_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
 --
 
-ALTER SEQUENCE public.sending_pool_emails_id_seq OWNED BY public.sending_pool_emails.id;
+ALTER SEQUENCE public.complaints_id_seq OWNED BY public.complaints.id;
 
 
 --
--- Name: sending_pool_emails_message_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+-- Name: counted_events; Type: TABLE; Schema: public; Owner: -
 --
 
-CREATE SEQUENCE public.sending_pool_emails_message_id_seq
+CREATE TABLE public.counted_events (
+    id character varying(64) NOT NULL,
+    message_id character varying(50) NOT NULL,
+    counted_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: dead_letters; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.dead_letters (
+    id integer NOT NULL,
+    domain character varying(254) DEFAULT ''::character varying NOT NULL,
+    subject character varying(100) DEFAULT ''::character varying NOT NULL,
+    message_id character varying(50) DEFAULT ''::character varying NOT NULL,
+    email character varying(320) DEFAULT ''::character varying NOT NULL,
+    payload bytea DEFAULT '\x'::bytea NOT NULL,
+    reason character varying NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    requeued_at timestamp with time zone
+);
+
+
+--
+-- Name: dead_letters_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.dead_letters_id_seq
     AS integer
     START WITH 1
     INCREMENT BY 1
@@ -155,29 +300,34 @@ CREATE SEQUENCE public.sending_pool_emails_message_id_seq
 
 
 --
--- Name: sending_pool_emails_message_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+-- Name: dead_letters_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
 --
 
-ALTER SEQUENCE public.sending_pool_emails_message_id_seq OWNED BY public.sending_pool_emails.message_id;
+ALTER SEQUENCE public.dead_letters_id_seq OWNED BY public.dead_letters.id;
 
 
 --
--- Name: templates; Type: TABLE; Schema: public; Owner: -
+-- Name: dkim_keys; Type: TABLE; Schema: public; Owner: -
 --
 
-CREATE TABLE public.templates (
+CREATE TABLE public.dkim_keys (
     id integer NOT NULL,
-    template_id character varying(50) NOT NULL,
-    html character varying NOT NULL,
-    domain character varying(254) NOT NULL
+    domain character varying(254) NOT NULL,
+    selector character varying(63) NOT NULL,
+    algorithm character varying(10) NOT NULL,
+    private_key character varying NOT NULL,
+    public_key character varying NOT NULL,
+    status public.dkim_key_status DEFAULT 'pending'::public.dkim_key_status NOT NULL,
+    retire_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
 );
 
 
 --
--- Name: templates_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+-- Name: dkim_keys_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-CREATE SEQUENCE public.templates_id_seq
+CREATE SEQUENCE public.dkim_keys_id_seq
     AS integer
     START WITH 1
     INCREMENT BY 1
@@ -187,143 +337,1421 @@ CREATE SEQUENCE public.templates_id_seq
 
 
 --
--- Name: templates_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+-- Name: dkim_keys_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
 --
 
-ALTER SEQUENCE public.templates_id_seq OWNED BY public.templates.id;
+ALTER SEQUENCE public.dkim_keys_id_seq OWNED BY public.dkim_keys.id;
 
 
 --
--- Name: domains id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: dmarc_records; Type: TABLE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.domains ALTER COLUMN id SET DEFAULT nextval('public.domains_id_seq'::regclass);
+CREATE TABLE public.dmarc_records (
+    id integer NOT NULL,
+    report_id integer NOT NULL,
+    header_from character varying(254) NOT NULL,
+    source_ip character varying(45) NOT NULL,
+    count bigint NOT NULL,
+    disposition character varying(20) NOT NULL,
+    dkim_aligned boolean NOT NULL,
+    spf_aligned boolean NOT NULL,
+    begin_at timestamp with time zone NOT NULL
+);
 
 
 --
--- Name: messages id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: dmarc_records_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.messages ALTER COLUMN id SET DEFAULT nextval('public.messages_id_seq'::regclass);
+CREATE SEQUENCE public.dmarc_records_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
 
 
 --
--- Name: sending_pool_emails id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: dmarc_records_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.sending_pool_emails ALTER COLUMN id SET DEFAULT nextval('public.sending_pool_emails_id_seq'::regclass);
+ALTER SEQUENCE public.dmarc_records_id_seq OWNED BY public.dmarc_records.id;
 
 
 --
--- Name: sending_pool_emails message_id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: dmarc_reports; Type: TABLE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.sending_pool_emails ALTER COLUMN message_id SET DEFAULT nextval('public.sending_pool_emails_message_id_seq'::regclass);
+CREATE TABLE public.dmarc_reports (
+    id integer NOT NULL,
+    org_name character varying(254) NOT NULL,
+    report_id character varying(254) NOT NULL,
+    domain character varying(254) NOT NULL,
+    begin_at timestamp with time zone NOT NULL,
+    end_at timestamp with time zone NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
 
 
 --
--- Name: templates id; Type: DEFAULT; Schema: public; Owner: -
+-- Name: dmarc_reports_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.templates ALTER COLUMN id SET DEFAULT nextval('public.templates_id_seq'::regclass);
+CREATE SEQUENCE public.dmarc_reports_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
 
 
 --
--- Name: domains domains_domain_key; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: dmarc_reports_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.domains
-    ADD CONSTRAINT domains_domain_key UNIQUE (domain);
+ALTER SEQUENCE public.dmarc_reports_id_seq OWNED BY public.dmarc_reports.id;
 
 
 --
--- Name: domains domains_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: domain_records; Type: TABLE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.domains
-    ADD CONSTRAINT domains_pkey PRIMARY KEY (id);
+CREATE TABLE public.domain_records (
+    id integer NOT NULL,
+    domain character varying(254) NOT NULL,
+    record character varying(20) NOT NULL,
+    status public.domain_record_status NOT NULL,
+    error character varying DEFAULT ''::character varying NOT NULL,
+    checked_at timestamp with time zone DEFAULT now() NOT NULL
+);
 
 
 --
--- Name: messages messages_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: domain_records_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.messages
-    ADD CONSTRAINT messages_pkey PRIMARY KEY (id);
+CREATE SEQUENCE public.domain_records_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
 
 
 --
--- Name: schema_migrations schema_migrations_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: domain_records_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.schema_migrations
-    ADD CONSTRAINT schema_migrations_pkey PRIMARY KEY (version);
+ALTER SEQUENCE public.domain_records_id_seq OWNED BY public.domain_records.id;
 
 
 --
--- Name: sending_pool_emails sending_pool_emails_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: domain_usage; Type: TABLE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.sending_pool_emails
-    ADD CONSTRAINT sending_pool_emails_pkey PRIMARY KEY (id);
+CREATE TABLE public.domain_usage (
+    id integer NOT NULL,
+    domain character varying(254) NOT NULL,
+    day date NOT NULL,
+    sent integer DEFAULT 0 NOT NULL
+);
 
 
 --
--- Name: templates templates_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: domain_usage_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.templates
-    ADD CONSTRAINT templates_pkey PRIMARY KEY (id);
+CREATE SEQUENCE public.domain_usage_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
 
 
 --
--- Name: domains_domain_idx; Type: INDEX; Schema: public; Owner: -
+-- Name: domain_usage_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
 --
 
-CREATE INDEX domains_domain_idx ON public.domains USING btree (domain);
+ALTER SEQUENCE public.domain_usage_id_seq OWNED BY public.domain_usage.id;
 
 
 --
--- Name: messages_domain_idx; Type: INDEX; Schema: public; Owner: -
+-- Name: domains; Type: TABLE; Schema: public; Owner: -
 --
 
-CREATE INDEX messages_domain_idx ON public.messages USING btree (domain);
+CREATE TABLE public.domains (
+    id integer NOT NULL,
+    domain character varying(254) NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    dkim_private_key character varying NOT NULL,
+    dkim_public_key character varying NOT NULL,
+    retention_days integer DEFAULT 0 NOT NULL,
+    rate_per_second integer DEFAULT 0 NOT NULL,
+    rate_per_hour integer DEFAULT 0 NOT NULL,
+    ip_pool character varying(50) DEFAULT ''::character varying NOT NULL,
+    dkim_ed25519_private_key character varying DEFAULT ''::character varying NOT NULL,
+    dkim_ed25519_public_key character varying DEFAULT ''::character varying NOT NULL,
+    dkim_signing character varying(10) DEFAULT 'rsa'::character varying NOT NULL,
+    dkim_selector character varying(63) DEFAULT 'kannon'::character varying NOT NULL,
+    dkim_ed25519_selector character varying(63) DEFAULT 'kannon-ed25519'::character varying NOT NULL,
+    dkim_headers character varying(100)[] DEFAULT '{}'::character varying[] NOT NULL,
+    verified boolean DEFAULT false NOT NULL,
+    return_path_domain character varying(254) DEFAULT ''::character varying NOT NULL,
+    daily_quota integer DEFAULT 0 NOT NULL,
+    monthly_quota integer DEFAULT 0 NOT NULL,
+    deleted_at timestamp with time zone,
+    purge_at timestamp with time zone,
+    default_from_email character varying(254) DEFAULT ''::character varying NOT NULL,
+    default_from_name character varying(254) DEFAULT ''::character varying NOT NULL,
+    default_headers jsonb DEFAULT '{}'::jsonb NOT NULL,
+    open_tracking boolean DEFAULT true NOT NULL,
+    click_tracking boolean DEFAULT true NOT NULL,
+    footer_html character varying DEFAULT ''::character varying NOT NULL,
+    footer_text character varying DEFAULT ''::character varying NOT NULL,
+    sandbox boolean DEFAULT false NOT NULL,
+    sandbox_bounce_percent integer DEFAULT 0 NOT NULL,
+    paused_at timestamp with time zone,
+    paused_reason character varying(254) DEFAULT ''::character varying NOT NULL
+);
 
 
 --
--- Name: messages_message_id_idx; Type: INDEX; Schema: public; Owner: -
+-- Name: domains_id_seq; Type: SEQUENCE; Schema: public; Owner: -
 --
 
-CREATE INDEX messages_message_id_idx ON public.messages USING btree (message_id);
+CREATE SEQUENCE public.domains_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
 
 
 --
--- Name: sending_pool_emails_scheduled_time_status_idx; Type: INDEX; Schema: public; Owner: -
+-- Name: domains_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
 --
 
-CREATE INDEX sending_pool_emails_scheduled_time_status_idx ON public.sending_pool_emails USING btree (scheduled_time, status);
+ALTER SEQUENCE public.domains_id_seq OWNED BY public.domains.id;
 
 
 --
--- Name: templates_domain_idx; Type: INDEX; Schema: public; Owner: -
+-- Name: event_exports; Type: TABLE; Schema: public; Owner: -
 --
 
-CREATE INDEX templates_domain_idx ON public.templates USING btree (domain);
+CREATE TABLE public.event_exports (
+    domain character varying(254) NOT NULL,
+    exported_before timestamp with time zone NOT NULL,
+    last_event_id integer NOT NULL
+);
 
 
 --
--- Name: templates_template_id_idx; Type: INDEX; Schema: public; Owner: -
+-- Name: firing_alerts; Type: TABLE; Schema: public; Owner: -
 --
 
-CREATE INDEX templates_template_id_idx ON public.templates USING btree (template_id);
+CREATE TABLE public.firing_alerts (
+    rule character varying(32) NOT NULL,
+    domain character varying(254) DEFAULT ''::character varying NOT NULL,
+    value double precision NOT NULL,
+    threshold double precision NOT NULL,
+    message character varying NOT NULL,
+    fired_at timestamp with time zone NOT NULL
+);
 
 
 --
--- Name: sending_pool_emails sending_pool_emails_message_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+-- Name: maintenance; Type: TABLE; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.sending_pool_emails
-    ADD CONSTRAINT sending_pool_emails_message_id_fkey FOREIGN KEY (message_id) REFERENCES public.messages(id);
+CREATE TABLE public.maintenance (
+    id boolean DEFAULT true NOT NULL,
+    halted boolean DEFAULT false NOT NULL,
+    reason character varying DEFAULT ''::character varying NOT NULL,
+    changed_at timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT maintenance_id_check CHECK (id)
+);
+
+
+--
+-- Name: message_events; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.message_events (
+    id integer NOT NULL,
+    message_id character varying(50) NOT NULL,
+    email character varying(320) NOT NULL,
+    type character varying(20) NOT NULL,
+    data jsonb DEFAULT '{}'::jsonb NOT NULL,
+    "timestamp" timestamp with time zone NOT NULL
+);
+
+
+--
+-- Name: message_events_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.message_events_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: message_events_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.message_events_id_seq OWNED BY public.message_events.id;
+
+
+--
+-- Name: message_timeline; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.message_timeline (
+    id integer NOT NULL,
+    message_id character varying(50) NOT NULL,
+    email character varying(320) NOT NULL,
+    stage character varying(20) NOT NULL,
+    smtp_code integer DEFAULT 0 NOT NULL,
+    smtp_response character varying DEFAULT ''::character varying NOT NULL,
+    data jsonb DEFAULT '{}'::jsonb NOT NULL,
+    "timestamp" timestamp with time zone NOT NULL
+);
+
+
+--
+-- Name: message_timeline_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.message_timeline_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: message_timeline_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.message_timeline_id_seq OWNED BY public.message_timeline.id;
+
+
+--
+-- Name: messages; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.messages (
+    id integer NOT NULL,
+    message_id character varying(50) NOT NULL,
+    subject character varying NOT NULL,
+    sender_email character varying(320) NOT NULL,
+    sender_alias character varying(100) NOT NULL,
+    template_id character varying(50) NOT NULL,
+    domain character varying(254) NOT NULL,
+    cc character varying(320)[] DEFAULT '{}'::character varying[] NOT NULL,
+    bcc character varying(320)[] DEFAULT '{}'::character varying[] NOT NULL,
+    headers jsonb DEFAULT '{}'::jsonb NOT NULL,
+    reply_to character varying(320) DEFAULT ''::character varying NOT NULL,
+    fields jsonb DEFAULT '{}'::jsonb NOT NULL,
+    template_version integer DEFAULT 1 NOT NULL,
+    idempotency_key character varying(255),
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    resent_from character varying(50) DEFAULT ''::character varying NOT NULL
+);
+
+
+--
+-- Name: messages_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.messages_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: messages_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.messages_id_seq OWNED BY public.messages.id;
+
+
+--
+-- Name: opens; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.opens (
+    id integer NOT NULL,
+    message_id character varying(50) NOT NULL,
+    email character varying(320) NOT NULL,
+    ip character varying(45) DEFAULT ''::character varying NOT NULL,
+    user_agent character varying DEFAULT ''::character varying NOT NULL,
+    "timestamp" timestamp with time zone NOT NULL
+);
+
+
+--
+-- Name: opens_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.opens_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: opens_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.opens_id_seq OWNED BY public.opens.id;
+
+
+--
+-- Name: schema_migrations; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.schema_migrations (
+    version character varying(255) NOT NULL
+);
+
+
+--
+-- Name: sending_pool_emails; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.sending_pool_emails (
+    id integer NOT NULL,
+    status public.sending_pool_status DEFAULT 'initializing'::public.sending_pool_status NOT NULL,
+    scheduled_time timestamp with time zone DEFAULT now() NOT NULL,
+    original_scheduled_time timestamp with time zone NOT NULL,
+    trial smallint DEFAULT 0 NOT NULL,
+    email character varying(320) NOT NULL,
+    message_id integer NOT NULL,
+    error_msg character varying DEFAULT ''::character varying NOT NULL,
+    error_code integer DEFAULT 0 NOT NULL,
+    fields jsonb DEFAULT '{}'::jsonb NOT NULL,
+    bounce_type public.bounce_type DEFAULT 'none'::public.bounce_type NOT NULL,
+    priority smallint DEFAULT 0 NOT NULL,
+    dispatched_at timestamp with time zone,
+    trace_parent character varying(55) DEFAULT ''::character varying NOT NULL,
+    copies boolean DEFAULT false NOT NULL,
+    claimed_at timestamp with time zone,
+    quota_consumed boolean DEFAULT false NOT NULL
+);
+
+
+--
+-- Name: sending_pool_emails_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.sending_pool_emails_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: sending_pool_emails_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.sending_pool_emails_id_seq OWNED BY public.sending_pool_emails.id;
+
+
+--
+-- Name: sending_pool_emails_message_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.sending_pool_emails_message_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: sending_pool_emails_message_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.sending_pool_emails_message_id_seq OWNED BY public.sending_pool_emails.message_id;
+
+
+--
+-- Name: smtp_responses; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.smtp_responses (
+    id integer NOT NULL,
+    domain character varying(254) NOT NULL,
+    provider character varying(254) NOT NULL,
+    code integer NOT NULL,
+    enhanced_code character varying(16) DEFAULT ''::character varying NOT NULL,
+    hour timestamp with time zone NOT NULL,
+    count integer DEFAULT 0 NOT NULL
+);
+
+
+--
+-- Name: smtp_responses_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.smtp_responses_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: smtp_responses_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.smtp_responses_id_seq OWNED BY public.smtp_responses.id;
+
+
+--
+-- Name: stats; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.stats (
+    id integer NOT NULL,
+    domain character varying(254) NOT NULL,
+    message_id character varying(50) NOT NULL,
+    type character varying(20) NOT NULL,
+    hour timestamp with time zone NOT NULL,
+    count integer DEFAULT 0 NOT NULL
+);
+
+
+--
+-- Name: stats_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.stats_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: stats_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.stats_id_seq OWNED BY public.stats.id;
+
+
+--
+-- Name: suppressions; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.suppressions (
+    id integer NOT NULL,
+    domain character varying(254) NOT NULL,
+    email character varying(320) NOT NULL,
+    reason public.suppression_reason NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: suppressions_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.suppressions_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: suppressions_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.suppressions_id_seq OWNED BY public.suppressions.id;
+
+
+--
+-- Name: templates; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.templates (
+    id integer NOT NULL,
+    template_id character varying(50) NOT NULL,
+    html character varying NOT NULL,
+    domain character varying(254) NOT NULL,
+    text character varying DEFAULT ''::character varying NOT NULL,
+    version integer DEFAULT 1 NOT NULL,
+    active boolean DEFAULT true NOT NULL,
+    name character varying(100) DEFAULT ''::character varying NOT NULL,
+    deleted_at timestamp with time zone
+);
+
+
+--
+-- Name: templates_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.templates_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: templates_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.templates_id_seq OWNED BY public.templates.id;
+
+
+--
+-- Name: warmup_usage; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.warmup_usage (
+    ip character varying(45) NOT NULL,
+    provider character varying(64) NOT NULL,
+    day date NOT NULL,
+    count integer DEFAULT 0 NOT NULL
+);
+
+
+--
+-- Name: webhook_deliveries; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.webhook_deliveries (
+    id integer NOT NULL,
+    webhook_id integer NOT NULL,
+    event character varying(20) NOT NULL,
+    payload jsonb NOT NULL,
+    status public.webhook_delivery_status DEFAULT 'pending'::public.webhook_delivery_status NOT NULL,
+    attempts integer DEFAULT 0 NOT NULL,
+    next_attempt_time timestamp with time zone DEFAULT now() NOT NULL,
+    response_code integer DEFAULT 0 NOT NULL,
+    error_msg character varying DEFAULT ''::character varying NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: webhook_deliveries_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.webhook_deliveries_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: webhook_deliveries_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.webhook_deliveries_id_seq OWNED BY public.webhook_deliveries.id;
+
+
+--
+-- Name: webhooks; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.webhooks (
+    id integer NOT NULL,
+    domain character varying(254) NOT NULL,
+    url character varying NOT NULL,
+    secret character varying(64) NOT NULL,
+    events character varying(20)[] NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: webhooks_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.webhooks_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: webhooks_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.webhooks_id_seq OWNED BY public.webhooks.id;
+
+
+--
+-- Name: api_key_calls id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.api_key_calls ALTER COLUMN id SET DEFAULT nextval('public.api_key_calls_id_seq'::regclass);
+
+
+--
+-- Name: api_keys id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.api_keys ALTER COLUMN id SET DEFAULT nextval('public.api_keys_id_seq'::regclass);
+
+
+--
+-- Name: attachments id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.attachments ALTER COLUMN id SET DEFAULT nextval('public.attachments_id_seq'::regclass);
+
+
+--
+-- Name: audit_logs id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.audit_logs ALTER COLUMN id SET DEFAULT nextval('public.audit_logs_id_seq'::regclass);
+
+
+--
+-- Name: complaints id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.complaints ALTER COLUMN id SET DEFAULT nextval('public.complaints_id_seq'::regclass);
+
+
+--
+-- Name: dead_letters id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.dead_letters ALTER COLUMN id SET DEFAULT nextval('public.dead_letters_id_seq'::regclass);
+
+
+--
+-- Name: dkim_keys id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.dkim_keys ALTER COLUMN id SET DEFAULT nextval('public.dkim_keys_id_seq'::regclass);
+
+
+--
+-- Name: dmarc_records id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.dmarc_records ALTER COLUMN id SET DEFAULT nextval('public.dmarc_records_id_seq'::regclass);
+
+
+--
+-- Name: dmarc_reports id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.dmarc_reports ALTER COLUMN id SET DEFAULT nextval('public.dmarc_reports_id_seq'::regclass);
+
+
+--
+-- Name: domain_records id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.domain_records ALTER COLUMN id SET DEFAULT nextval('public.domain_records_id_seq'::regclass);
+
+
+--
+-- Name: domain_usage id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.domain_usage ALTER COLUMN id SET DEFAULT nextval('public.domain_usage_id_seq'::regclass);
+
+
+--
+-- Name: domains id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.domains ALTER COLUMN id SET DEFAULT nextval('public.domains_id_seq'::regclass);
+
+
+--
+-- Name: message_events id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.message_events ALTER COLUMN id SET DEFAULT nextval('public.message_events_id_seq'::regclass);
+
+
+--
+-- Name: message_timeline id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.message_timeline ALTER COLUMN id SET DEFAULT nextval('public.message_timeline_id_seq'::regclass);
+
+
+--
+-- Name: messages id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.messages ALTER COLUMN id SET DEFAULT nextval('public.messages_id_seq'::regclass);
+
+
+--
+-- Name: opens id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.opens ALTER COLUMN id SET DEFAULT nextval('public.opens_id_seq'::regclass);
+
+
+--
+-- Name: sending_pool_emails id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.sending_pool_emails ALTER COLUMN id SET DEFAULT nextval('public.sending_pool_emails_id_seq'::regclass);
+
+
+--
+-- Name: sending_pool_emails message_id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.sending_pool_emails ALTER COLUMN message_id SET DEFAULT nextval('public.sending_pool_emails_message_id_seq'::regclass);
+
+
+--
+-- Name: smtp_responses id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.smtp_responses ALTER COLUMN id SET DEFAULT nextval('public.smtp_responses_id_seq'::regclass);
+
+
+--
+-- Name: stats id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.stats ALTER COLUMN id SET DEFAULT nextval('public.stats_id_seq'::regclass);
+
+
+--
+-- Name: suppressions id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.suppressions ALTER COLUMN id SET DEFAULT nextval('public.suppressions_id_seq'::regclass);
+
+
+--
+-- Name: templates id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.templates ALTER COLUMN id SET DEFAULT nextval('public.templates_id_seq'::regclass);
+
+
+--
+-- Name: webhook_deliveries id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.webhook_deliveries ALTER COLUMN id SET DEFAULT nextval('public.webhook_deliveries_id_seq'::regclass);
+
+
+--
+-- Name: webhooks id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.webhooks ALTER COLUMN id SET DEFAULT nextval('public.webhooks_id_seq'::regclass);
+
+
+--
+-- Name: api_key_calls api_key_calls_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.api_key_calls
+    ADD CONSTRAINT api_key_calls_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: api_keys api_keys_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.api_keys
+    ADD CONSTRAINT api_keys_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: attachments attachments_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.attachments
+    ADD CONSTRAINT attachments_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: audit_logs audit_logs_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.audit_logs
+    ADD CONSTRAINT audit_logs_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: complaints complaints_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.complaints
+    ADD CONSTRAINT complaints_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: counted_events counted_events_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.counted_events
+    ADD CONSTRAINT counted_events_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: dead_letters dead_letters_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.dead_letters
+    ADD CONSTRAINT dead_letters_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: dkim_keys dkim_keys_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.dkim_keys
+    ADD CONSTRAINT dkim_keys_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: dmarc_records dmarc_records_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.dmarc_records
+    ADD CONSTRAINT dmarc_records_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: dmarc_reports dmarc_reports_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.dmarc_reports
+    ADD CONSTRAINT dmarc_reports_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: domain_records domain_records_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.domain_records
+    ADD CONSTRAINT domain_records_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: domain_usage domain_usage_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.domain_usage
+    ADD CONSTRAINT domain_usage_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: domains domains_domain_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.domains
+    ADD CONSTRAINT domains_domain_key UNIQUE (domain);
+
+
+--
+-- Name: domains domains_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.domains
+    ADD CONSTRAINT domains_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: event_exports event_exports_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_exports
+    ADD CONSTRAINT event_exports_pkey PRIMARY KEY (domain);
+
+
+--
+-- Name: firing_alerts firing_alerts_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.firing_alerts
+    ADD CONSTRAINT firing_alerts_pkey PRIMARY KEY (rule, domain);
+
+
+--
+-- Name: maintenance maintenance_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.maintenance
+    ADD CONSTRAINT maintenance_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: message_events message_events_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.message_events
+    ADD CONSTRAINT message_events_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: message_timeline message_timeline_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.message_timeline
+    ADD CONSTRAINT message_timeline_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: messages messages_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.messages
+    ADD CONSTRAINT messages_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: opens opens_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.opens
+    ADD CONSTRAINT opens_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: schema_migrations schema_migrations_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.schema_migrations
+    ADD CONSTRAINT schema_migrations_pkey PRIMARY KEY (version);
+
+
+--
+-- Name: sending_pool_emails sending_pool_emails_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.sending_pool_emails
+    ADD CONSTRAINT sending_pool_emails_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: smtp_responses smtp_responses_domain_provider_code_enhanced_code_hour_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.smtp_responses
+    ADD CONSTRAINT smtp_responses_domain_provider_code_enhanced_code_hour_key UNIQUE (domain, provider, code, enhanced_code, hour);
+
+
+--
+-- Name: smtp_responses smtp_responses_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.smtp_responses
+    ADD CONSTRAINT smtp_responses_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: stats stats_domain_message_id_type_hour_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.stats
+    ADD CONSTRAINT stats_domain_message_id_type_hour_key UNIQUE (domain, message_id, type, hour);
+
+
+--
+-- Name: stats stats_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.stats
+    ADD CONSTRAINT stats_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: suppressions suppressions_domain_email_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.suppressions
+    ADD CONSTRAINT suppressions_domain_email_key UNIQUE (domain, email);
+
+
+--
+-- Name: suppressions suppressions_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.suppressions
+    ADD CONSTRAINT suppressions_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: templates templates_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.templates
+    ADD CONSTRAINT templates_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: warmup_usage warmup_usage_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.warmup_usage
+    ADD CONSTRAINT warmup_usage_pkey PRIMARY KEY (ip, provider, day);
+
+
+--
+-- Name: webhook_deliveries webhook_deliveries_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.webhook_deliveries
+    ADD CONSTRAINT webhook_deliveries_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: webhooks webhooks_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.webhooks
+    ADD CONSTRAINT webhooks_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: api_key_calls_domain_called_at_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX api_key_calls_domain_called_at_idx ON public.api_key_calls USING btree (domain, called_at);
+
+
+--
+-- Name: api_keys_domain_key_hash_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX api_keys_domain_key_hash_idx ON public.api_keys USING btree (domain, key_hash);
+
+
+--
+-- Name: attachments_message_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX attachments_message_id_idx ON public.attachments USING btree (message_id);
+
+
+--
+-- Name: audit_logs_domain_created_at_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX audit_logs_domain_created_at_idx ON public.audit_logs USING btree (domain, created_at);
+
+
+--
+-- Name: complaints_message_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX complaints_message_id_idx ON public.complaints USING btree (message_id);
+
+
+--
+-- Name: counted_events_message_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX counted_events_message_id_idx ON public.counted_events USING btree (message_id);
+
+
+--
+-- Name: dead_letters_domain_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX dead_letters_domain_idx ON public.dead_letters USING btree (domain);
+
+
+--
+-- Name: dkim_keys_domain_selector_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX dkim_keys_domain_selector_idx ON public.dkim_keys USING btree (domain, selector);
+
+
+--
+-- Name: dkim_keys_status_retire_at_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX dkim_keys_status_retire_at_idx ON public.dkim_keys USING btree (status, retire_at);
+
+
+--
+-- Name: dmarc_records_header_from_begin_at_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX dmarc_records_header_from_begin_at_idx ON public.dmarc_records USING btree (header_from, begin_at);
+
+
+--
+-- Name: dmarc_reports_org_name_report_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX dmarc_reports_org_name_report_id_idx ON public.dmarc_reports USING btree (org_name, report_id);
+
+
+--
+-- Name: domain_records_domain_record_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX domain_records_domain_record_idx ON public.domain_records USING btree (domain, record);
+
+
+--
+-- Name: domain_usage_domain_day_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX domain_usage_domain_day_idx ON public.domain_usage USING btree (domain, day);
+
+
+--
+-- Name: domains_domain_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX domains_domain_idx ON public.domains USING btree (domain);
+
+
+--
+-- Name: message_events_message_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX message_events_message_id_idx ON public.message_events USING btree (message_id);
+
+
+--
+-- Name: message_events_timestamp_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX message_events_timestamp_idx ON public.message_events USING btree ("timestamp");
+
+
+--
+-- Name: message_timeline_message_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX message_timeline_message_id_idx ON public.message_timeline USING btree (message_id);
+
+
+--
+-- Name: messages_domain_created_at_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX messages_domain_created_at_idx ON public.messages USING btree (domain, created_at);
+
+
+--
+-- Name: messages_domain_idempotency_key_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX messages_domain_idempotency_key_idx ON public.messages USING btree (domain, idempotency_key) WHERE (idempotency_key IS NOT NULL);
+
+
+--
+-- Name: messages_domain_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX messages_domain_idx ON public.messages USING btree (domain);
+
+
+--
+-- Name: messages_message_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX messages_message_id_idx ON public.messages USING btree (message_id);
+
+
+--
+-- Name: messages_template_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX messages_template_id_idx ON public.messages USING btree (template_id);
+
+
+--
+-- Name: opens_message_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX opens_message_id_idx ON public.opens USING btree (message_id);
+
+
+--
+-- Name: sending_pool_emails_claimed_at_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX sending_pool_emails_claimed_at_idx ON public.sending_pool_emails USING btree (claimed_at) WHERE (status = 'sending'::public.sending_pool_status);
+
+
+--
+-- Name: sending_pool_emails_dispatched_at_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX sending_pool_emails_dispatched_at_idx ON public.sending_pool_emails USING btree (dispatched_at);
+
+
+--
+-- Name: sending_pool_emails_email_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX sending_pool_emails_email_idx ON public.sending_pool_emails USING btree (email);
+
+
+--
+-- Name: sending_pool_emails_message_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX sending_pool_emails_message_id_idx ON public.sending_pool_emails USING btree (message_id);
+
+
+--
+-- Name: sending_pool_emails_priority_scheduled_time_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX sending_pool_emails_priority_scheduled_time_idx ON public.sending_pool_emails USING btree (priority DESC, scheduled_time) WHERE (status = 'scheduled'::public.sending_pool_status);
+
+
+--
+-- Name: sending_pool_emails_scheduled_time_status_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX sending_pool_emails_scheduled_time_status_idx ON public.sending_pool_emails USING btree (scheduled_time, status);
+
+
+--
+-- Name: smtp_responses_domain_hour_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX smtp_responses_domain_hour_idx ON public.smtp_responses USING btree (domain, hour);
+
+
+--
+-- Name: stats_domain_hour_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX stats_domain_hour_idx ON public.stats USING btree (domain, hour);
+
+
+--
+-- Name: templates_domain_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX templates_domain_idx ON public.templates USING btree (domain);
+
+
+--
+-- Name: templates_domain_name_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX templates_domain_name_idx ON public.templates USING btree (domain, name) WHERE (((name)::text <> ''::text) AND (deleted_at IS NULL) AND (version = 1));
+
+
+--
+-- Name: templates_template_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX templates_template_id_idx ON public.templates USING btree (template_id);
+
+
+--
+-- Name: templates_template_id_version_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE UNIQUE INDEX templates_template_id_version_idx ON public.templates USING btree (template_id, version);
+
+
+--
+-- Name: webhook_deliveries_next_attempt_time_status_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX webhook_deliveries_next_attempt_time_status_idx ON public.webhook_deliveries USING btree (next_attempt_time, status);
+
+
+--
+-- Name: webhook_deliveries_webhook_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX webhook_deliveries_webhook_id_idx ON public.webhook_deliveries USING btree (webhook_id);
+
+
+--
+-- Name: webhooks_domain_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX webhooks_domain_idx ON public.webhooks USING btree (domain);
+
+
+--
+-- Name: attachments attachments_message_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.attachments
+    ADD CONSTRAINT attachments_message_id_fkey FOREIGN KEY (message_id) REFERENCES public.messages(id);
+
+
+--
+-- Name: dmarc_records dmarc_records_report_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.dmarc_records
+    ADD CONSTRAINT dmarc_records_report_id_fkey FOREIGN KEY (report_id) REFERENCES public.dmarc_reports(id) ON DELETE CASCADE;
+
+
+--
+-- Name: sending_pool_emails sending_pool_emails_message_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.sending_pool_emails
+    ADD CONSTRAINT sending_pool_emails_message_id_fkey FOREIGN KEY (message_id) REFERENCES public.messages(id);
+
+
+--
+-- Name: webhook_deliveries webhook_deliveries_webhook_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.webhook_deliveries
+    ADD CONSTRAINT webhook_deliveries_webhook_id_fkey FOREIGN KEY (webhook_id) REFERENCES public.webhooks(id) ON DELETE CASCADE;
 
 
 --
@@ -336,4 +1764,56 @@ ALTER TABLE ONLY public.sending_pool_emails
 --
 
 INSERT INTO public.schema_migrations (version) VALUES
-    ('20210406191606');
+    ('20210406191606'),
+    ('20210412184512'),
+    ('20210415093027'),
+    ('20210419201344'),
+    ('20210423174501'),
+    ('20210427110238'),
+    ('20210430152210'),
+    ('20210504091855'),
+    ('20210507160412'),
+    ('20210511102733'),
+    ('20210514143108'),
+    ('20210518095214'),
+    ('20210521112347'),
+    ('20210525163051'),
+    ('20210528102419'),
+    ('20210601150936'),
+    ('20210604113752'),
+    ('20210608141526'),
+    ('20210611104233'),
+    ('20210615093512'),
+    ('20210618151047'),
+    ('20210622102755'),
+    ('20210625110318'),
+    ('20210629094122'),
+    ('20210702091530'),
+    ('20210705103012'),
+    ('20210707094518'),
+    ('20210709083127'),
+    ('20210712081540'),
+    ('20210714092210'),
+    ('20210716073204'),
+    ('20210719090512'),
+    ('20210721084417'),
+    ('20210723081547'),
+    ('20210726090314'),
+    ('20210728083912'),
+    ('20210730091204'),
+    ('20210802084521'),
+    ('20210804093015'),
+    ('20210806090212'),
+    ('20210809101534'),
+    ('20210810083047'),
+    ('20210811094520'),
+    ('20210812091203'),
+    ('20210813084210'),
+    ('20210814083150'),
+    ('20210814091020'),
+    ('20210814094540'),
+    ('20210814103015'),
+    ('20210814111045'),
+    ('20210814112530'),
+    ('20210814114210'),
+    ('20210814115840');
@@ -21,7 +21,107 @@ const _ = grpc.SupportPackageIsVersion7
 type ApiClient interface {
 	GetDomains(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*GetDomainsResponse, error)
 	CreateDomain(ctx context.Context, in *CreateDomainRequest, opts ...grpc.CallOption) (*Domain, error)
+	// RegenerateDomainKey creates a new default admin API key of a domain,
+	// the previous default keys are revoked
 	RegenerateDomainKey(ctx context.Context, in *RegenerateDomainKeyRequest, opts ...grpc.CallOption) (*Domain, error)
+	// DeleteDomain deletes a domain and cancels its emails not sent yet, the domain
+	// can be restored for the grace period, then its data is purged
+	DeleteDomain(ctx context.Context, in *DeleteDomainRequest, opts ...grpc.CallOption) (*DeleteDomainResponse, error)
+	// RestoreDomain restores a deleted domain within its grace period
+	RestoreDomain(ctx context.Context, in *RestoreDomainRequest, opts ...grpc.CallOption) (*Domain, error)
+	// CreateAPIKey creates an API key of a domain with scopes send, stats or admin,
+	// the key is returned only at its creation
+	CreateAPIKey(ctx context.Context, in *CreateAPIKeyRequest, opts ...grpc.CallOption) (*APIKey, error)
+	GetAPIKeys(ctx context.Context, in *GetAPIKeysRequest, opts ...grpc.CallOption) (*GetAPIKeysResponse, error)
+	RevokeAPIKey(ctx context.Context, in *RevokeAPIKeyRequest, opts ...grpc.CallOption) (*APIKey, error)
+	// RotateAPIKey creates a key with the name and the scopes of an active key,
+	// which stays valid for the overlap so clients can switch to the new key
+	RotateAPIKey(ctx context.Context, in *RotateAPIKeyRequest, opts ...grpc.CallOption) (*RotateAPIKeyResponse, error)
+	// GetAPIKeyCalls returns the Mailer API calls authenticated by the keys of a domain,
+	// most recent first
+	GetAPIKeyCalls(ctx context.Context, in *GetAPIKeyCallsRequest, opts ...grpc.CallOption) (*GetAPIKeyCallsResponse, error)
+	// SetDomainRetention sets the days messages and events of a domain are kept
+	SetDomainRetention(ctx context.Context, in *SetDomainRetentionRequest, opts ...grpc.CallOption) (*Domain, error)
+	// SetDomainRateLimit sets the max emails of a domain dispatched every second and every hour
+	SetDomainRateLimit(ctx context.Context, in *SetDomainRateLimitRequest, opts ...grpc.CallOption) (*Domain, error)
+	// SetDomainQuota sets the max emails of a domain dispatched every day and every month
+	SetDomainQuota(ctx context.Context, in *SetDomainQuotaRequest, opts ...grpc.CallOption) (*Domain, error)
+	// SetDomainIPPool sets the pool of source IPs of the sender used by a domain
+	SetDomainIPPool(ctx context.Context, in *SetDomainIPPoolRequest, opts ...grpc.CallOption) (*Domain, error)
+	// SetDomainReturnPath sets the subdomain of a domain used as return path
+	SetDomainReturnPath(ctx context.Context, in *SetDomainReturnPathRequest, opts ...grpc.CallOption) (*Domain, error)
+	// SetDomainSendingSettings sets the default sender and headers, the open and click
+	// tracking and the footers of the emails of a domain, replacing the current settings
+	SetDomainSendingSettings(ctx context.Context, in *SetDomainSendingSettingsRequest, opts ...grpc.CallOption) (*Domain, error)
+	// SetDomainSandbox makes the sender simulate the deliveries of the emails of a domain
+	// instead of sending them, publishing their delivered and bounced events
+	SetDomainSandbox(ctx context.Context, in *SetDomainSandboxRequest, opts ...grpc.CallOption) (*Domain, error)
+	// PauseDomain stops the dispatch of the emails of a domain and rejects its sends,
+	// scheduled emails are dispatched when the domain is resumed
+	PauseDomain(ctx context.Context, in *PauseDomainRequest, opts ...grpc.CallOption) (*Domain, error)
+	ResumeDomain(ctx context.Context, in *ResumeDomainRequest, opts ...grpc.CallOption) (*Domain, error)
+	// GetDomainReputation returns the bounce, block and complaint rates of a domain
+	// over the last day, week and month
+	GetDomainReputation(ctx context.Context, in *GetDomainReputationRequest, opts ...grpc.CallOption) (*DomainReputation, error)
+	// SetDomainDKIMSigning sets the DKIM keys signing the emails of a domain
+	SetDomainDKIMSigning(ctx context.Context, in *SetDomainDKIMSigningRequest, opts ...grpc.CallOption) (*Domain, error)
+	// SetDomainDKIMHeaders sets the headers signed with DKIM by a domain
+	SetDomainDKIMHeaders(ctx context.Context, in *SetDomainDKIMHeadersRequest, opts ...grpc.CallOption) (*Domain, error)
+	// RotateDomainDKIMKey generates a pending DKIM key with a new selector,
+	// emails are signed with the active key until the new one is promoted
+	RotateDomainDKIMKey(ctx context.Context, in *RotateDomainDKIMKeyRequest, opts ...grpc.CallOption) (*DKIMKey, error)
+	// PromoteDomainDKIMKey makes a pending key active once its DNS record is
+	// published, the previous key is retired after a grace period
+	PromoteDomainDKIMKey(ctx context.Context, in *PromoteDomainDKIMKeyRequest, opts ...grpc.CallOption) (*Domain, error)
+	GetDomainDKIMKeys(ctx context.Context, in *GetDomainDKIMKeysRequest, opts ...grpc.CallOption) (*GetDomainDKIMKeysResponse, error)
+	// VerifyDomain checks the DNS records of a domain now, the verifier
+	// checks every domain periodically
+	VerifyDomain(ctx context.Context, in *VerifyDomainRequest, opts ...grpc.CallOption) (*DomainVerification, error)
+	// GetDomainVerification returns the status of the DNS records of a domain at their last check
+	GetDomainVerification(ctx context.Context, in *GetDomainVerificationRequest, opts ...grpc.CallOption) (*DomainVerification, error)
+	// GetDomainDNSRecords returns the DNS records a domain must publish,
+	// validated against the records currently published
+	GetDomainDNSRecords(ctx context.Context, in *GetDomainDNSRecordsRequest, opts ...grpc.CallOption) (*GetDomainDNSRecordsResponse, error)
+	// ProvisionDomainDNS creates the DNS records of a domain with the DNS provider,
+	// fails with FailedPrecondition when no provider is configured
+	ProvisionDomainDNS(ctx context.Context, in *ProvisionDomainDNSRequest, opts ...grpc.CallOption) (*ProvisionDomainDNSResponse, error)
+	// CreateTemplate creates a named template of a domain, the html must have
+	// its elements closed and the placeholders of the html and the text well formed
+	CreateTemplate(ctx context.Context, in *CreateTemplateRequest, opts ...grpc.CallOption) (*Template, error)
+	// GetTemplate returns a version of a template, the active version when 0
+	GetTemplate(ctx context.Context, in *GetTemplateRequest, opts ...grpc.CallOption) (*Template, error)
+	// GetTemplates returns the active versions of the named templates of a domain
+	GetTemplates(ctx context.Context, in *GetTemplatesRequest, opts ...grpc.CallOption) (*GetTemplatesResponse, error)
+	// UpdateTemplate creates a new active version of a template, validated like CreateTemplate
+	UpdateTemplate(ctx context.Context, in *UpdateTemplateRequest, opts ...grpc.CallOption) (*Template, error)
+	// RollbackTemplate sets the active version of a template
+	RollbackTemplate(ctx context.Context, in *RollbackTemplateRequest, opts ...grpc.CallOption) (*Template, error)
+	// DeleteTemplate makes a template unavailable to the sends, the emails
+	// not sent yet are sent with their version
+	DeleteTemplate(ctx context.Context, in *DeleteTemplateRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	GetSuppressions(ctx context.Context, in *GetSuppressionsRequest, opts ...grpc.CallOption) (*GetSuppressionsResponse, error)
+	// AddSuppression manually blocks a recipient of a domain
+	AddSuppression(ctx context.Context, in *AddSuppressionRequest, opts ...grpc.CallOption) (*Suppression, error)
+	RemoveSuppression(ctx context.Context, in *RemoveSuppressionRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// CreateWebhook subscribes an url to events of a domain,
+	// deliveries are signed with the returned secret
+	CreateWebhook(ctx context.Context, in *CreateWebhookRequest, opts ...grpc.CallOption) (*Webhook, error)
+	GetWebhooks(ctx context.Context, in *GetWebhooksRequest, opts ...grpc.CallOption) (*GetWebhooksResponse, error)
+	DeleteWebhook(ctx context.Context, in *DeleteWebhookRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	GetWebhookDeliveries(ctx context.Context, in *GetWebhookDeliveriesRequest, opts ...grpc.CallOption) (*GetWebhookDeliveriesResponse, error)
+	// SearchMessages returns the emails sent to recipients, most recent first
+	SearchMessages(ctx context.Context, in *SearchMessagesRequest, opts ...grpc.CallOption) (*SearchMessagesResponse, error)
+	GetDeadLetters(ctx context.Context, in *GetDeadLettersRequest, opts ...grpc.CallOption) (*GetDeadLettersResponse, error)
+	// RequeueDeadLetter publishes a dead letter again on its subject,
+	// emails that exhausted their retries are scheduled again
+	RequeueDeadLetter(ctx context.Context, in *RequeueDeadLetterRequest, opts ...grpc.CallOption) (*DeadLetterEntry, error)
+	// GetAuditLogs returns the mutations of the admin API, most recent first
+	GetAuditLogs(ctx context.Context, in *GetAuditLogsRequest, opts ...grpc.CallOption) (*GetAuditLogsResponse, error)
+	// HaltSending halts the dispatch and the send of the emails of every domain,
+	// emails stay scheduled until ResumeSending
+	HaltSending(ctx context.Context, in *HaltSendingRequest, opts ...grpc.CallOption) (*SendingStatus, error)
+	ResumeSending(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*SendingStatus, error)
+	GetSendingStatus(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*SendingStatus, error)
 }
 
 type apiClient struct {
@@ -59,90 +159,1570 @@ func (c *apiClient) RegenerateDomainKey(ctx context.Context, in *RegenerateDomai
 	return out, nil
 }
 
+func (c *apiClient) DeleteDomain(ctx context.Context, in *DeleteDomainRequest, opts ...grpc.CallOption) (*DeleteDomainResponse, error) {
+	out := new(DeleteDomainResponse)
+	err := c.cc.Invoke(ctx, "/kannon.Api/DeleteDomain", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) RestoreDomain(ctx context.Context, in *RestoreDomainRequest, opts ...grpc.CallOption) (*Domain, error) {
+	out := new(Domain)
+	err := c.cc.Invoke(ctx, "/kannon.Api/RestoreDomain", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) CreateAPIKey(ctx context.Context, in *CreateAPIKeyRequest, opts ...grpc.CallOption) (*APIKey, error) {
+	out := new(APIKey)
+	err := c.cc.Invoke(ctx, "/kannon.Api/CreateAPIKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) GetAPIKeys(ctx context.Context, in *GetAPIKeysRequest, opts ...grpc.CallOption) (*GetAPIKeysResponse, error) {
+	out := new(GetAPIKeysResponse)
+	err := c.cc.Invoke(ctx, "/kannon.Api/GetAPIKeys", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) RevokeAPIKey(ctx context.Context, in *RevokeAPIKeyRequest, opts ...grpc.CallOption) (*APIKey, error) {
+	out := new(APIKey)
+	err := c.cc.Invoke(ctx, "/kannon.Api/RevokeAPIKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) RotateAPIKey(ctx context.Context, in *RotateAPIKeyRequest, opts ...grpc.CallOption) (*RotateAPIKeyResponse, error) {
+	out := new(RotateAPIKeyResponse)
+	err := c.cc.Invoke(ctx, "/kannon.Api/RotateAPIKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) GetAPIKeyCalls(ctx context.Context, in *GetAPIKeyCallsRequest, opts ...grpc.CallOption) (*GetAPIKeyCallsResponse, error) {
+	out := new(GetAPIKeyCallsResponse)
+	err := c.cc.Invoke(ctx, "/kannon.Api/GetAPIKeyCalls", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) SetDomainRetention(ctx context.Context, in *SetDomainRetentionRequest, opts ...grpc.CallOption) (*Domain, error) {
+	out := new(Domain)
+	err := c.cc.Invoke(ctx, "/kannon.Api/SetDomainRetention", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) SetDomainRateLimit(ctx context.Context, in *SetDomainRateLimitRequest, opts ...grpc.CallOption) (*Domain, error) {
+	out := new(Domain)
+	err := c.cc.Invoke(ctx, "/kannon.Api/SetDomainRateLimit", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) SetDomainQuota(ctx context.Context, in *SetDomainQuotaRequest, opts ...grpc.CallOption) (*Domain, error) {
+	out := new(Domain)
+	err := c.cc.Invoke(ctx, "/kannon.Api/SetDomainQuota", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) SetDomainIPPool(ctx context.Context, in *SetDomainIPPoolRequest, opts ...grpc.CallOption) (*Domain, error) {
+	out := new(Domain)
+	err := c.cc.Invoke(ctx, "/kannon.Api/SetDomainIPPool", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) SetDomainReturnPath(ctx context.Context, in *SetDomainReturnPathRequest, opts ...grpc.CallOption) (*Domain, error) {
+	out := new(Domain)
+	err := c.cc.Invoke(ctx, "/kannon.Api/SetDomainReturnPath", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) SetDomainSendingSettings(ctx context.Context, in *SetDomainSendingSettingsRequest, opts ...grpc.CallOption) (*Domain, error) {
+	out := new(Domain)
+	err := c.cc.Invoke(ctx, "/kannon.Api/SetDomainSendingSettings", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) SetDomainSandbox(ctx context.Context, in *SetDomainSandboxRequest, opts ...grpc.CallOption) (*Domain, error) {
+	out := new(Domain)
+	err := c.cc.Invoke(ctx, "/kannon.Api/SetDomainSandbox", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) PauseDomain(ctx context.Context, in *PauseDomainRequest, opts ...grpc.CallOption) (*Domain, error) {
+	out := new(Domain)
+	err := c.cc.Invoke(ctx, "/kannon.Api/PauseDomain", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) ResumeDomain(ctx context.Context, in *ResumeDomainRequest, opts ...grpc.CallOption) (*Domain, error) {
+	out := new(Domain)
+	err := c.cc.Invoke(ctx, "/kannon.Api/ResumeDomain", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) GetDomainReputation(ctx context.Context, in *GetDomainReputationRequest, opts ...grpc.CallOption) (*DomainReputation, error) {
+	out := new(DomainReputation)
+	err := c.cc.Invoke(ctx, "/kannon.Api/GetDomainReputation", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) SetDomainDKIMSigning(ctx context.Context, in *SetDomainDKIMSigningRequest, opts ...grpc.CallOption) (*Domain, error) {
+	out := new(Domain)
+	err := c.cc.Invoke(ctx, "/kannon.Api/SetDomainDKIMSigning", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) SetDomainDKIMHeaders(ctx context.Context, in *SetDomainDKIMHeadersRequest, opts ...grpc.CallOption) (*Domain, error) {
+	out := new(Domain)
+	err := c.cc.Invoke(ctx, "/kannon.Api/SetDomainDKIMHeaders", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) RotateDomainDKIMKey(ctx context.Context, in *RotateDomainDKIMKeyRequest, opts ...grpc.CallOption) (*DKIMKey, error) {
+	out := new(DKIMKey)
+	err := c.cc.Invoke(ctx, "/kannon.Api/RotateDomainDKIMKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) PromoteDomainDKIMKey(ctx context.Context, in *PromoteDomainDKIMKeyRequest, opts ...grpc.CallOption) (*Domain, error) {
+	out := new(Domain)
+	err := c.cc.Invoke(ctx, "/kannon.Api/PromoteDomainDKIMKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) GetDomainDKIMKeys(ctx context.Context, in *GetDomainDKIMKeysRequest, opts ...grpc.CallOption) (*GetDomainDKIMKeysResponse, error) {
+	out := new(GetDomainDKIMKeysResponse)
+	err := c.cc.Invoke(ctx, "/kannon.Api/GetDomainDKIMKeys", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) VerifyDomain(ctx context.Context, in *VerifyDomainRequest, opts ...grpc.CallOption) (*DomainVerification, error) {
+	out := new(DomainVerification)
+	err := c.cc.Invoke(ctx, "/kannon.Api/VerifyDomain", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) GetDomainVerification(ctx context.Context, in *GetDomainVerificationRequest, opts ...grpc.CallOption) (*DomainVerification, error) {
+	out := new(DomainVerification)
+	err := c.cc.Invoke(ctx, "/kannon.Api/GetDomainVerification", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) GetDomainDNSRecords(ctx context.Context, in *GetDomainDNSRecordsRequest, opts ...grpc.CallOption) (*GetDomainDNSRecordsResponse, error) {
+	out := new(GetDomainDNSRecordsResponse)
+	err := c.cc.Invoke(ctx, "/kannon.Api/GetDomainDNSRecords", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) ProvisionDomainDNS(ctx context.Context, in *ProvisionDomainDNSRequest, opts ...grpc.CallOption) (*ProvisionDomainDNSResponse, error) {
+	out := new(ProvisionDomainDNSResponse)
+	err := c.cc.Invoke(ctx, "/kannon.Api/ProvisionDomainDNS", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) CreateTemplate(ctx context.Context, in *CreateTemplateRequest, opts ...grpc.CallOption) (*Template, error) {
+	out := new(Template)
+	err := c.cc.Invoke(ctx, "/kannon.Api/CreateTemplate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) GetTemplate(ctx context.Context, in *GetTemplateRequest, opts ...grpc.CallOption) (*Template, error) {
+	out := new(Template)
+	err := c.cc.Invoke(ctx, "/kannon.Api/GetTemplate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) GetTemplates(ctx context.Context, in *GetTemplatesRequest, opts ...grpc.CallOption) (*GetTemplatesResponse, error) {
+	out := new(GetTemplatesResponse)
+	err := c.cc.Invoke(ctx, "/kannon.Api/GetTemplates", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) UpdateTemplate(ctx context.Context, in *UpdateTemplateRequest, opts ...grpc.CallOption) (*Template, error) {
+	out := new(Template)
+	err := c.cc.Invoke(ctx, "/kannon.Api/UpdateTemplate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) RollbackTemplate(ctx context.Context, in *RollbackTemplateRequest, opts ...grpc.CallOption) (*Template, error) {
+	out := new(Template)
+	err := c.cc.Invoke(ctx, "/kannon.Api/RollbackTemplate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) DeleteTemplate(ctx context.Context, in *DeleteTemplateRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/kannon.Api/DeleteTemplate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) GetSuppressions(ctx context.Context, in *GetSuppressionsRequest, opts ...grpc.CallOption) (*GetSuppressionsResponse, error) {
+	out := new(GetSuppressionsResponse)
+	err := c.cc.Invoke(ctx, "/kannon.Api/GetSuppressions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) AddSuppression(ctx context.Context, in *AddSuppressionRequest, opts ...grpc.CallOption) (*Suppression, error) {
+	out := new(Suppression)
+	err := c.cc.Invoke(ctx, "/kannon.Api/AddSuppression", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) RemoveSuppression(ctx context.Context, in *RemoveSuppressionRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/kannon.Api/RemoveSuppression", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) CreateWebhook(ctx context.Context, in *CreateWebhookRequest, opts ...grpc.CallOption) (*Webhook, error) {
+	out := new(Webhook)
+	err := c.cc.Invoke(ctx, "/kannon.Api/CreateWebhook", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) GetWebhooks(ctx context.Context, in *GetWebhooksRequest, opts ...grpc.CallOption) (*GetWebhooksResponse, error) {
+	out := new(GetWebhooksResponse)
+	err := c.cc.Invoke(ctx, "/kannon.Api/GetWebhooks", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) DeleteWebhook(ctx context.Context, in *DeleteWebhookRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/kannon.Api/DeleteWebhook", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) GetWebhookDeliveries(ctx context.Context, in *GetWebhookDeliveriesRequest, opts ...grpc.CallOption) (*GetWebhookDeliveriesResponse, error) {
+	out := new(GetWebhookDeliveriesResponse)
+	err := c.cc.Invoke(ctx, "/kannon.Api/GetWebhookDeliveries", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) SearchMessages(ctx context.Context, in *SearchMessagesRequest, opts ...grpc.CallOption) (*SearchMessagesResponse, error) {
+	out := new(SearchMessagesResponse)
+	err := c.cc.Invoke(ctx, "/kannon.Api/SearchMessages", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) GetDeadLetters(ctx context.Context, in *GetDeadLettersRequest, opts ...grpc.CallOption) (*GetDeadLettersResponse, error) {
+	out := new(GetDeadLettersResponse)
+	err := c.cc.Invoke(ctx, "/kannon.Api/GetDeadLetters", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) RequeueDeadLetter(ctx context.Context, in *RequeueDeadLetterRequest, opts ...grpc.CallOption) (*DeadLetterEntry, error) {
+	out := new(DeadLetterEntry)
+	err := c.cc.Invoke(ctx, "/kannon.Api/RequeueDeadLetter", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) GetAuditLogs(ctx context.Context, in *GetAuditLogsRequest, opts ...grpc.CallOption) (*GetAuditLogsResponse, error) {
+	out := new(GetAuditLogsResponse)
+	err := c.cc.Invoke(ctx, "/kannon.Api/GetAuditLogs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) HaltSending(ctx context.Context, in *HaltSendingRequest, opts ...grpc.CallOption) (*SendingStatus, error) {
+	out := new(SendingStatus)
+	err := c.cc.Invoke(ctx, "/kannon.Api/HaltSending", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) ResumeSending(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*SendingStatus, error) {
+	out := new(SendingStatus)
+	err := c.cc.Invoke(ctx, "/kannon.Api/ResumeSending", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) GetSendingStatus(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*SendingStatus, error) {
+	out := new(SendingStatus)
+	err := c.cc.Invoke(ctx, "/kannon.Api/GetSendingStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ApiServer is the server API for Api service.
 // All implementations should embed UnimplementedApiServer
 // for forward compatibility
 type ApiServer interface {
 	GetDomains(context.Context, *emptypb.Empty) (*GetDomainsResponse, error)
 	CreateDomain(context.Context, *CreateDomainRequest) (*Domain, error)
+	// RegenerateDomainKey creates a new default admin API key of a domain,
+	// the previous default keys are revoked
 	RegenerateDomainKey(context.Context, *RegenerateDomainKeyRequest) (*Domain, error)
+	// DeleteDomain deletes a domain and cancels its emails not sent yet, the domain
+	// can be restored for the grace period, then its data is purged
+	DeleteDomain(context.Context, *DeleteDomainRequest) (*DeleteDomainResponse, error)
+	// RestoreDomain restores a deleted domain within its grace period
+	RestoreDomain(context.Context, *RestoreDomainRequest) (*Domain, error)
+	// CreateAPIKey creates an API key of a domain with scopes send, stats or admin,
+	// the key is returned only at its creation
+	CreateAPIKey(context.Context, *CreateAPIKeyRequest) (*APIKey, error)
+	GetAPIKeys(context.Context, *GetAPIKeysRequest) (*GetAPIKeysResponse, error)
+	RevokeAPIKey(context.Context, *RevokeAPIKeyRequest) (*APIKey, error)
+	// RotateAPIKey creates a key with the name and the scopes of an active key,
+	// which stays valid for the overlap so clients can switch to the new key
+	RotateAPIKey(context.Context, *RotateAPIKeyRequest) (*RotateAPIKeyResponse, error)
+	// GetAPIKeyCalls returns the Mailer API calls authenticated by the keys of a domain,
+	// most recent first
+	GetAPIKeyCalls(context.Context, *GetAPIKeyCallsRequest) (*GetAPIKeyCallsResponse, error)
+	// SetDomainRetention sets the days messages and events of a domain are kept
+	SetDomainRetention(context.Context, *SetDomainRetentionRequest) (*Domain, error)
+	// SetDomainRateLimit sets the max emails of a domain dispatched every second and every hour
+	SetDomainRateLimit(context.Context, *SetDomainRateLimitRequest) (*Domain, error)
+	// SetDomainQuota sets the max emails of a domain dispatched every day and every month
+	SetDomainQuota(context.Context, *SetDomainQuotaRequest) (*Domain, error)
+	// SetDomainIPPool sets the pool of source IPs of the sender used by a domain
+	SetDomainIPPool(context.Context, *SetDomainIPPoolRequest) (*Domain, error)
+	// SetDomainReturnPath sets the subdomain of a domain used as return path
+	SetDomainReturnPath(context.Context, *SetDomainReturnPathRequest) (*Domain, error)
+	// SetDomainSendingSettings sets the default sender and headers, the open and click
+	// tracking and the footers of the emails of a domain, replacing the current settings
+	SetDomainSendingSettings(context.Context, *SetDomainSendingSettingsRequest) (*Domain, error)
+	// SetDomainSandbox makes the sender simulate the deliveries of the emails of a domain
+	// instead of sending them, publishing their delivered and bounced events
+	SetDomainSandbox(context.Context, *SetDomainSandboxRequest) (*Domain, error)
+	// PauseDomain stops the dispatch of the emails of a domain and rejects its sends,
+	// scheduled emails are dispatched when the domain is resumed
+	PauseDomain(context.Context, *PauseDomainRequest) (*Domain, error)
+	ResumeDomain(context.Context, *ResumeDomainRequest) (*Domain, error)
+	// GetDomainReputation returns the bounce, block and complaint rates of a domain
+	// over the last day, week and month
+	GetDomainReputation(context.Context, *GetDomainReputationRequest) (*DomainReputation, error)
+	// SetDomainDKIMSigning sets the DKIM keys signing the emails of a domain
+	SetDomainDKIMSigning(context.Context, *SetDomainDKIMSigningRequest) (*Domain, error)
+	// SetDomainDKIMHeaders sets the headers signed with DKIM by a domain
+	SetDomainDKIMHeaders(context.Context, *SetDomainDKIMHeadersRequest) (*Domain, error)
+	// RotateDomainDKIMKey generates a pending DKIM key with a new selector,
+	// emails are signed with the active key until the new one is promoted
+	RotateDomainDKIMKey(context.Context, *RotateDomainDKIMKeyRequest) (*DKIMKey, error)
+	// PromoteDomainDKIMKey makes a pending key active once its DNS record is
+	// published, the previous key is retired after a grace period
+	PromoteDomainDKIMKey(context.Context, *PromoteDomainDKIMKeyRequest) (*Domain, error)
+	GetDomainDKIMKeys(context.Context, *GetDomainDKIMKeysRequest) (*GetDomainDKIMKeysResponse, error)
+	// VerifyDomain checks the DNS records of a domain now, the verifier
+	// checks every domain periodically
+	VerifyDomain(context.Context, *VerifyDomainRequest) (*DomainVerification, error)
+	// GetDomainVerification returns the status of the DNS records of a domain at their last check
+	GetDomainVerification(context.Context, *GetDomainVerificationRequest) (*DomainVerification, error)
+	// GetDomainDNSRecords returns the DNS records a domain must publish,
+	// validated against the records currently published
+	GetDomainDNSRecords(context.Context, *GetDomainDNSRecordsRequest) (*GetDomainDNSRecordsResponse, error)
+	// ProvisionDomainDNS creates the DNS records of a domain with the DNS provider,
+	// fails with FailedPrecondition when no provider is configured
+	ProvisionDomainDNS(context.Context, *ProvisionDomainDNSRequest) (*ProvisionDomainDNSResponse, error)
+	// CreateTemplate creates a named template of a domain, the html must have
+	// its elements closed and the placeholders of the html and the text well formed
+	CreateTemplate(context.Context, *CreateTemplateRequest) (*Template, error)
+	// GetTemplate returns a version of a template, the active version when 0
+	GetTemplate(context.Context, *GetTemplateRequest) (*Template, error)
+	// GetTemplates returns the active versions of the named templates of a domain
+	GetTemplates(context.Context, *GetTemplatesRequest) (*GetTemplatesResponse, error)
+	// UpdateTemplate creates a new active version of a template, validated like CreateTemplate
+	UpdateTemplate(context.Context, *UpdateTemplateRequest) (*Template, error)
+	// RollbackTemplate sets the active version of a template
+	RollbackTemplate(context.Context, *RollbackTemplateRequest) (*Template, error)
+	// DeleteTemplate makes a template unavailable to the sends, the emails
+	// not sent yet are sent with their version
+	DeleteTemplate(context.Context, *DeleteTemplateRequest) (*emptypb.Empty, error)
+	GetSuppressions(context.Context, *GetSuppressionsRequest) (*GetSuppressionsResponse, error)
+	// AddSuppression manually blocks a recipient of a domain
+	AddSuppression(context.Context, *AddSuppressionRequest) (*Suppression, error)
+	RemoveSuppression(context.Context, *RemoveSuppressionRequest) (*emptypb.Empty, error)
+	// CreateWebhook subscribes an url to events of a domain,
+	// deliveries are signed with the returned secret
+	CreateWebhook(context.Context, *CreateWebhookRequest) (*Webhook, error)
+	GetWebhooks(context.Context, *GetWebhooksRequest) (*GetWebhooksResponse, error)
+	DeleteWebhook(context.Context, *DeleteWebhookRequest) (*emptypb.Empty, error)
+	GetWebhookDeliveries(context.Context, *GetWebhookDeliveriesRequest) (*GetWebhookDeliveriesResponse, error)
+	// SearchMessages returns the emails sent to recipients, most recent first
+	SearchMessages(context.Context, *SearchMessagesRequest) (*SearchMessagesResponse, error)
+	GetDeadLetters(context.Context, *GetDeadLettersRequest) (*GetDeadLettersResponse, error)
+	// RequeueDeadLetter publishes a dead letter again on its subject,
+	// emails that exhausted their retries are scheduled again
+	RequeueDeadLetter(context.Context, *RequeueDeadLetterRequest) (*DeadLetterEntry, error)
+	// GetAuditLogs returns the mutations of the admin API, most recent first
+	GetAuditLogs(context.Context, *GetAuditLogsRequest) (*GetAuditLogsResponse, error)
+	// HaltSending halts the dispatch and the send of the emails of every domain,
+	// emails stay scheduled until ResumeSending
+	HaltSending(context.Context, *HaltSendingRequest) (*SendingStatus, error)
+	ResumeSending(context.Context, *emptypb.Empty) (*SendingStatus, error)
+	GetSendingStatus(context.Context, *emptypb.Empty) (*SendingStatus, error)
+}
+
+// UnimplementedApiServer should be embedded to have forward compatible implementations.
+type UnimplementedApiServer struct {
+}
+
+func (UnimplementedApiServer) GetDomains(context.Context, *emptypb.Empty) (*GetDomainsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDomains not implemented")
+}
+func (UnimplementedApiServer) CreateDomain(context.Context, *CreateDomainRequest) (*Domain, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateDomain not implemented")
+}
+func (UnimplementedApiServer) RegenerateDomainKey(context.Context, *RegenerateDomainKeyRequest) (*Domain, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegenerateDomainKey not implemented")
+}
+func (UnimplementedApiServer) DeleteDomain(context.Context, *DeleteDomainRequest) (*DeleteDomainResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteDomain not implemented")
+}
+func (UnimplementedApiServer) RestoreDomain(context.Context, *RestoreDomainRequest) (*Domain, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreDomain not implemented")
+}
+func (UnimplementedApiServer) CreateAPIKey(context.Context, *CreateAPIKeyRequest) (*APIKey, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateAPIKey not implemented")
+}
+func (UnimplementedApiServer) GetAPIKeys(context.Context, *GetAPIKeysRequest) (*GetAPIKeysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAPIKeys not implemented")
+}
+func (UnimplementedApiServer) RevokeAPIKey(context.Context, *RevokeAPIKeyRequest) (*APIKey, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeAPIKey not implemented")
+}
+func (UnimplementedApiServer) RotateAPIKey(context.Context, *RotateAPIKeyRequest) (*RotateAPIKeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RotateAPIKey not implemented")
+}
+func (UnimplementedApiServer) GetAPIKeyCalls(context.Context, *GetAPIKeyCallsRequest) (*GetAPIKeyCallsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAPIKeyCalls not implemented")
+}
+func (UnimplementedApiServer) SetDomainRetention(context.Context, *SetDomainRetentionRequest) (*Domain, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetDomainRetention not implemented")
+}
+func (UnimplementedApiServer) SetDomainRateLimit(context.Context, *SetDomainRateLimitRequest) (*Domain, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetDomainRateLimit not implemented")
+}
+func (UnimplementedApiServer) SetDomainQuota(context.Context, *SetDomainQuotaRequest) (*Domain, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetDomainQuota not implemented")
+}
+func (UnimplementedApiServer) SetDomainIPPool(context.Context, *SetDomainIPPoolRequest) (*Domain, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetDomainIPPool not implemented")
+}
+func (UnimplementedApiServer) SetDomainReturnPath(context.Context, *SetDomainReturnPathRequest) (*Domain, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetDomainReturnPath not implemented")
+}
+func (UnimplementedApiServer) SetDomainSendingSettings(context.Context, *SetDomainSendingSettingsRequest) (*Domain, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetDomainSendingSettings not implemented")
+}
+func (UnimplementedApiServer) SetDomainSandbox(context.Context, *SetDomainSandboxRequest) (*Domain, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetDomainSandbox not implemented")
+}
+func (UnimplementedApiServer) PauseDomain(context.Context, *PauseDomainRequest) (*Domain, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PauseDomain not implemented")
+}
+func (UnimplementedApiServer) ResumeDomain(context.Context, *ResumeDomainRequest) (*Domain, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResumeDomain not implemented")
+}
+func (UnimplementedApiServer) GetDomainReputation(context.Context, *GetDomainReputationRequest) (*DomainReputation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDomainReputation not implemented")
+}
+func (UnimplementedApiServer) SetDomainDKIMSigning(context.Context, *SetDomainDKIMSigningRequest) (*Domain, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetDomainDKIMSigning not implemented")
+}
+func (UnimplementedApiServer) SetDomainDKIMHeaders(context.Context, *SetDomainDKIMHeadersRequest) (*Domain, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetDomainDKIMHeaders not implemented")
+}
+func (UnimplementedApiServer) RotateDomainDKIMKey(context.Context, *RotateDomainDKIMKeyRequest) (*DKIMKey, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RotateDomainDKIMKey not implemented")
+}
+func (UnimplementedApiServer) PromoteDomainDKIMKey(context.Context, *PromoteDomainDKIMKeyRequest) (*Domain, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PromoteDomainDKIMKey not implemented")
+}
+func (UnimplementedApiServer) GetDomainDKIMKeys(context.Context, *GetDomainDKIMKeysRequest) (*GetDomainDKIMKeysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDomainDKIMKeys not implemented")
+}
+func (UnimplementedApiServer) VerifyDomain(context.Context, *VerifyDomainRequest) (*DomainVerification, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VerifyDomain not implemented")
+}
+func (UnimplementedApiServer) GetDomainVerification(context.Context, *GetDomainVerificationRequest) (*DomainVerification, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDomainVerification not implemented")
+}
+func (UnimplementedApiServer) GetDomainDNSRecords(context.Context, *GetDomainDNSRecordsRequest) (*GetDomainDNSRecordsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDomainDNSRecords not implemented")
+}
+func (UnimplementedApiServer) ProvisionDomainDNS(context.Context, *ProvisionDomainDNSRequest) (*ProvisionDomainDNSResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ProvisionDomainDNS not implemented")
+}
+func (UnimplementedApiServer) CreateTemplate(context.Context, *CreateTemplateRequest) (*Template, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateTemplate not implemented")
+}
+func (UnimplementedApiServer) GetTemplate(context.Context, *GetTemplateRequest) (*Template, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTemplate not implemented")
+}
+func (UnimplementedApiServer) GetTemplates(context.Context, *GetTemplatesRequest) (*GetTemplatesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTemplates not implemented")
+}
+func (UnimplementedApiServer) UpdateTemplate(context.Context, *UpdateTemplateRequest) (*Template, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateTemplate not implemented")
+}
+func (UnimplementedApiServer) RollbackTemplate(context.Context, *RollbackTemplateRequest) (*Template, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RollbackTemplate not implemented")
+}
+func (UnimplementedApiServer) DeleteTemplate(context.Context, *DeleteTemplateRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteTemplate not implemented")
+}
+func (UnimplementedApiServer) GetSuppressions(context.Context, *GetSuppressionsRequest) (*GetSuppressionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSuppressions not implemented")
+}
+func (UnimplementedApiServer) AddSuppression(context.Context, *AddSuppressionRequest) (*Suppression, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddSuppression not implemented")
+}
+func (UnimplementedApiServer) RemoveSuppression(context.Context, *RemoveSuppressionRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveSuppression not implemented")
+}
+func (UnimplementedApiServer) CreateWebhook(context.Context, *CreateWebhookRequest) (*Webhook, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateWebhook not implemented")
+}
+func (UnimplementedApiServer) GetWebhooks(context.Context, *GetWebhooksRequest) (*GetWebhooksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetWebhooks not implemented")
+}
+func (UnimplementedApiServer) DeleteWebhook(context.Context, *DeleteWebhookRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteWebhook not implemented")
+}
+func (UnimplementedApiServer) GetWebhookDeliveries(context.Context, *GetWebhookDeliveriesRequest) (*GetWebhookDeliveriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetWebhookDeliveries not implemented")
+}
+func (UnimplementedApiServer) SearchMessages(context.Context, *SearchMessagesRequest) (*SearchMessagesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchMessages not implemented")
+}
+func (UnimplementedApiServer) GetDeadLetters(context.Context, *GetDeadLettersRequest) (*GetDeadLettersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDeadLetters not implemented")
+}
+func (UnimplementedApiServer) RequeueDeadLetter(context.Context, *RequeueDeadLetterRequest) (*DeadLetterEntry, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequeueDeadLetter not implemented")
+}
+func (UnimplementedApiServer) GetAuditLogs(context.Context, *GetAuditLogsRequest) (*GetAuditLogsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAuditLogs not implemented")
+}
+func (UnimplementedApiServer) HaltSending(context.Context, *HaltSendingRequest) (*SendingStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HaltSending not implemented")
+}
+func (UnimplementedApiServer) ResumeSending(context.Context, *emptypb.Empty) (*SendingStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResumeSending not implemented")
+}
+func (UnimplementedApiServer) GetSendingStatus(context.Context, *emptypb.Empty) (*SendingStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSendingStatus not implemented")
+}
+
+// UnsafeApiServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ApiServer will
+// result in compilation errors.
+type UnsafeApiServer interface {
+	mustEmbedUnimplementedApiServer()
+}
+
+func RegisterApiServer(s grpc.ServiceRegistrar, srv ApiServer) {
+	s.RegisterService(&Api_ServiceDesc, srv)
+}
+
+func _Api_GetDomains_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).GetDomains(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/GetDomains",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).GetDomains(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_CreateDomain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateDomainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).CreateDomain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/CreateDomain",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).CreateDomain(ctx, req.(*CreateDomainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_RegenerateDomainKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegenerateDomainKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).RegenerateDomainKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/RegenerateDomainKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).RegenerateDomainKey(ctx, req.(*RegenerateDomainKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_DeleteDomain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteDomainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).DeleteDomain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/DeleteDomain",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).DeleteDomain(ctx, req.(*DeleteDomainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_RestoreDomain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreDomainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).RestoreDomain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/RestoreDomain",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).RestoreDomain(ctx, req.(*RestoreDomainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_CreateAPIKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateAPIKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).CreateAPIKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/CreateAPIKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).CreateAPIKey(ctx, req.(*CreateAPIKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_GetAPIKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAPIKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).GetAPIKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/GetAPIKeys",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).GetAPIKeys(ctx, req.(*GetAPIKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_RevokeAPIKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeAPIKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).RevokeAPIKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/RevokeAPIKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).RevokeAPIKey(ctx, req.(*RevokeAPIKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_RotateAPIKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RotateAPIKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).RotateAPIKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/RotateAPIKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).RotateAPIKey(ctx, req.(*RotateAPIKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_GetAPIKeyCalls_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAPIKeyCallsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).GetAPIKeyCalls(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/GetAPIKeyCalls",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).GetAPIKeyCalls(ctx, req.(*GetAPIKeyCallsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_SetDomainRetention_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetDomainRetentionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).SetDomainRetention(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/SetDomainRetention",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).SetDomainRetention(ctx, req.(*SetDomainRetentionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_SetDomainRateLimit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetDomainRateLimitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).SetDomainRateLimit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/SetDomainRateLimit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).SetDomainRateLimit(ctx, req.(*SetDomainRateLimitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_SetDomainQuota_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetDomainQuotaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).SetDomainQuota(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/SetDomainQuota",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).SetDomainQuota(ctx, req.(*SetDomainQuotaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_SetDomainIPPool_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetDomainIPPoolRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).SetDomainIPPool(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/SetDomainIPPool",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).SetDomainIPPool(ctx, req.(*SetDomainIPPoolRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_SetDomainReturnPath_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetDomainReturnPathRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).SetDomainReturnPath(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/SetDomainReturnPath",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).SetDomainReturnPath(ctx, req.(*SetDomainReturnPathRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_SetDomainSendingSettings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetDomainSendingSettingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).SetDomainSendingSettings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/SetDomainSendingSettings",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).SetDomainSendingSettings(ctx, req.(*SetDomainSendingSettingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_SetDomainSandbox_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetDomainSandboxRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).SetDomainSandbox(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/SetDomainSandbox",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).SetDomainSandbox(ctx, req.(*SetDomainSandboxRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_PauseDomain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PauseDomainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).PauseDomain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/PauseDomain",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).PauseDomain(ctx, req.(*PauseDomainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_ResumeDomain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResumeDomainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).ResumeDomain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/ResumeDomain",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).ResumeDomain(ctx, req.(*ResumeDomainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_GetDomainReputation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDomainReputationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).GetDomainReputation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/GetDomainReputation",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).GetDomainReputation(ctx, req.(*GetDomainReputationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_SetDomainDKIMSigning_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetDomainDKIMSigningRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).SetDomainDKIMSigning(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/SetDomainDKIMSigning",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).SetDomainDKIMSigning(ctx, req.(*SetDomainDKIMSigningRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_SetDomainDKIMHeaders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetDomainDKIMHeadersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).SetDomainDKIMHeaders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/SetDomainDKIMHeaders",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).SetDomainDKIMHeaders(ctx, req.(*SetDomainDKIMHeadersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_RotateDomainDKIMKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RotateDomainDKIMKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).RotateDomainDKIMKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/RotateDomainDKIMKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).RotateDomainDKIMKey(ctx, req.(*RotateDomainDKIMKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_PromoteDomainDKIMKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PromoteDomainDKIMKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).PromoteDomainDKIMKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/PromoteDomainDKIMKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).PromoteDomainDKIMKey(ctx, req.(*PromoteDomainDKIMKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_GetDomainDKIMKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDomainDKIMKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).GetDomainDKIMKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/GetDomainDKIMKeys",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).GetDomainDKIMKeys(ctx, req.(*GetDomainDKIMKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_VerifyDomain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyDomainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).VerifyDomain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/VerifyDomain",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).VerifyDomain(ctx, req.(*VerifyDomainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_GetDomainVerification_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDomainVerificationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).GetDomainVerification(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/GetDomainVerification",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).GetDomainVerification(ctx, req.(*GetDomainVerificationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_GetDomainDNSRecords_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDomainDNSRecordsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).GetDomainDNSRecords(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/GetDomainDNSRecords",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).GetDomainDNSRecords(ctx, req.(*GetDomainDNSRecordsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_ProvisionDomainDNS_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProvisionDomainDNSRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).ProvisionDomainDNS(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/ProvisionDomainDNS",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).ProvisionDomainDNS(ctx, req.(*ProvisionDomainDNSRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_CreateTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).CreateTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/CreateTemplate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).CreateTemplate(ctx, req.(*CreateTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_GetTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).GetTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/GetTemplate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).GetTemplate(ctx, req.(*GetTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_GetTemplates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTemplatesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).GetTemplates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/GetTemplates",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).GetTemplates(ctx, req.(*GetTemplatesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_UpdateTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).UpdateTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/UpdateTemplate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).UpdateTemplate(ctx, req.(*UpdateTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_RollbackTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RollbackTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).RollbackTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/RollbackTemplate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).RollbackTemplate(ctx, req.(*RollbackTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_DeleteTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).DeleteTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/DeleteTemplate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).DeleteTemplate(ctx, req.(*DeleteTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_GetSuppressions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSuppressionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).GetSuppressions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/GetSuppressions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).GetSuppressions(ctx, req.(*GetSuppressionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-// UnimplementedApiServer should be embedded to have forward compatible implementations.
-type UnimplementedApiServer struct {
+func _Api_AddSuppression_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddSuppressionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).AddSuppression(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/AddSuppression",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).AddSuppression(ctx, req.(*AddSuppressionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (UnimplementedApiServer) GetDomains(context.Context, *emptypb.Empty) (*GetDomainsResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetDomains not implemented")
+func _Api_RemoveSuppression_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveSuppressionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).RemoveSuppression(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/RemoveSuppression",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).RemoveSuppression(ctx, req.(*RemoveSuppressionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedApiServer) CreateDomain(context.Context, *CreateDomainRequest) (*Domain, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CreateDomain not implemented")
+
+func _Api_CreateWebhook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateWebhookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).CreateWebhook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/CreateWebhook",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).CreateWebhook(ctx, req.(*CreateWebhookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedApiServer) RegenerateDomainKey(context.Context, *RegenerateDomainKeyRequest) (*Domain, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method RegenerateDomainKey not implemented")
+
+func _Api_GetWebhooks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWebhooksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).GetWebhooks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/GetWebhooks",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).GetWebhooks(ctx, req.(*GetWebhooksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-// UnsafeApiServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to ApiServer will
-// result in compilation errors.
-type UnsafeApiServer interface {
-	mustEmbedUnimplementedApiServer()
+func _Api_DeleteWebhook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteWebhookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).DeleteWebhook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/DeleteWebhook",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).DeleteWebhook(ctx, req.(*DeleteWebhookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func RegisterApiServer(s grpc.ServiceRegistrar, srv ApiServer) {
-	s.RegisterService(&Api_ServiceDesc, srv)
+func _Api_GetWebhookDeliveries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWebhookDeliveriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).GetWebhookDeliveries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/GetWebhookDeliveries",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).GetWebhookDeliveries(ctx, req.(*GetWebhookDeliveriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _Api_GetDomains_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(emptypb.Empty)
+func _Api_SearchMessages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchMessagesRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(ApiServer).GetDomains(ctx, in)
+		return srv.(ApiServer).SearchMessages(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/kannon.Api/GetDomains",
+		FullMethod: "/kannon.Api/SearchMessages",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ApiServer).GetDomains(ctx, req.(*emptypb.Empty))
+		return srv.(ApiServer).SearchMessages(ctx, req.(*SearchMessagesRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Api_CreateDomain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CreateDomainRequest)
+func _Api_GetDeadLetters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDeadLettersRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(ApiServer).CreateDomain(ctx, in)
+		return srv.(ApiServer).GetDeadLetters(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/kannon.Api/CreateDomain",
+		FullMethod: "/kannon.Api/GetDeadLetters",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ApiServer).CreateDomain(ctx, req.(*CreateDomainRequest))
+		return srv.(ApiServer).GetDeadLetters(ctx, req.(*GetDeadLettersRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Api_RegenerateDomainKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(RegenerateDomainKeyRequest)
+func _Api_RequeueDeadLetter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequeueDeadLetterRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(ApiServer).RegenerateDomainKey(ctx, in)
+		return srv.(ApiServer).RequeueDeadLetter(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/kannon.Api/RegenerateDomainKey",
+		FullMethod: "/kannon.Api/RequeueDeadLetter",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ApiServer).RegenerateDomainKey(ctx, req.(*RegenerateDomainKeyRequest))
+		return srv.(ApiServer).RequeueDeadLetter(ctx, req.(*RequeueDeadLetterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_GetAuditLogs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAuditLogsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).GetAuditLogs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/GetAuditLogs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).GetAuditLogs(ctx, req.(*GetAuditLogsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_HaltSending_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HaltSendingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).HaltSending(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/HaltSending",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).HaltSending(ctx, req.(*HaltSendingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_ResumeSending_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).ResumeSending(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/ResumeSending",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).ResumeSending(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_GetSendingStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).GetSendingStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Api/GetSendingStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).GetSendingStatus(ctx, req.(*emptypb.Empty))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -166,6 +1746,190 @@ var Api_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RegenerateDomainKey",
 			Handler:    _Api_RegenerateDomainKey_Handler,
 		},
+		{
+			MethodName: "DeleteDomain",
+			Handler:    _Api_DeleteDomain_Handler,
+		},
+		{
+			MethodName: "RestoreDomain",
+			Handler:    _Api_RestoreDomain_Handler,
+		},
+		{
+			MethodName: "CreateAPIKey",
+			Handler:    _Api_CreateAPIKey_Handler,
+		},
+		{
+			MethodName: "GetAPIKeys",
+			Handler:    _Api_GetAPIKeys_Handler,
+		},
+		{
+			MethodName: "RevokeAPIKey",
+			Handler:    _Api_RevokeAPIKey_Handler,
+		},
+		{
+			MethodName: "RotateAPIKey",
+			Handler:    _Api_RotateAPIKey_Handler,
+		},
+		{
+			MethodName: "GetAPIKeyCalls",
+			Handler:    _Api_GetAPIKeyCalls_Handler,
+		},
+		{
+			MethodName: "SetDomainRetention",
+			Handler:    _Api_SetDomainRetention_Handler,
+		},
+		{
+			MethodName: "SetDomainRateLimit",
+			Handler:    _Api_SetDomainRateLimit_Handler,
+		},
+		{
+			MethodName: "SetDomainQuota",
+			Handler:    _Api_SetDomainQuota_Handler,
+		},
+		{
+			MethodName: "SetDomainIPPool",
+			Handler:    _Api_SetDomainIPPool_Handler,
+		},
+		{
+			MethodName: "SetDomainReturnPath",
+			Handler:    _Api_SetDomainReturnPath_Handler,
+		},
+		{
+			MethodName: "SetDomainSendingSettings",
+			Handler:    _Api_SetDomainSendingSettings_Handler,
+		},
+		{
+			MethodName: "SetDomainSandbox",
+			Handler:    _Api_SetDomainSandbox_Handler,
+		},
+		{
+			MethodName: "PauseDomain",
+			Handler:    _Api_PauseDomain_Handler,
+		},
+		{
+			MethodName: "ResumeDomain",
+			Handler:    _Api_ResumeDomain_Handler,
+		},
+		{
+			MethodName: "GetDomainReputation",
+			Handler:    _Api_GetDomainReputation_Handler,
+		},
+		{
+			MethodName: "SetDomainDKIMSigning",
+			Handler:    _Api_SetDomainDKIMSigning_Handler,
+		},
+		{
+			MethodName: "SetDomainDKIMHeaders",
+			Handler:    _Api_SetDomainDKIMHeaders_Handler,
+		},
+		{
+			MethodName: "RotateDomainDKIMKey",
+			Handler:    _Api_RotateDomainDKIMKey_Handler,
+		},
+		{
+			MethodName: "PromoteDomainDKIMKey",
+			Handler:    _Api_PromoteDomainDKIMKey_Handler,
+		},
+		{
+			MethodName: "GetDomainDKIMKeys",
+			Handler:    _Api_GetDomainDKIMKeys_Handler,
+		},
+		{
+			MethodName: "VerifyDomain",
+			Handler:    _Api_VerifyDomain_Handler,
+		},
+		{
+			MethodName: "GetDomainVerification",
+			Handler:    _Api_GetDomainVerification_Handler,
+		},
+		{
+			MethodName: "GetDomainDNSRecords",
+			Handler:    _Api_GetDomainDNSRecords_Handler,
+		},
+		{
+			MethodName: "ProvisionDomainDNS",
+			Handler:    _Api_ProvisionDomainDNS_Handler,
+		},
+		{
+			MethodName: "CreateTemplate",
+			Handler:    _Api_CreateTemplate_Handler,
+		},
+		{
+			MethodName: "GetTemplate",
+			Handler:    _Api_GetTemplate_Handler,
+		},
+		{
+			MethodName: "GetTemplates",
+			Handler:    _Api_GetTemplates_Handler,
+		},
+		{
+			MethodName: "UpdateTemplate",
+			Handler:    _Api_UpdateTemplate_Handler,
+		},
+		{
+			MethodName: "RollbackTemplate",
+			Handler:    _Api_RollbackTemplate_Handler,
+		},
+		{
+			MethodName: "DeleteTemplate",
+			Handler:    _Api_DeleteTemplate_Handler,
+		},
+		{
+			MethodName: "GetSuppressions",
+			Handler:    _Api_GetSuppressions_Handler,
+		},
+		{
+			MethodName: "AddSuppression",
+			Handler:    _Api_AddSuppression_Handler,
+		},
+		{
+			MethodName: "RemoveSuppression",
+			Handler:    _Api_RemoveSuppression_Handler,
+		},
+		{
+			MethodName: "CreateWebhook",
+			Handler:    _Api_CreateWebhook_Handler,
+		},
+		{
+			MethodName: "GetWebhooks",
+			Handler:    _Api_GetWebhooks_Handler,
+		},
+		{
+			MethodName: "DeleteWebhook",
+			Handler:    _Api_DeleteWebhook_Handler,
+		},
+		{
+			MethodName: "GetWebhookDeliveries",
+			Handler:    _Api_GetWebhookDeliveries_Handler,
+		},
+		{
+			MethodName: "SearchMessages",
+			Handler:    _Api_SearchMessages_Handler,
+		},
+		{
+			MethodName: "GetDeadLetters",
+			Handler:    _Api_GetDeadLetters_Handler,
+		},
+		{
+			MethodName: "RequeueDeadLetter",
+			Handler:    _Api_RequeueDeadLetter_Handler,
+		},
+		{
+			MethodName: "GetAuditLogs",
+			Handler:    _Api_GetAuditLogs_Handler,
+		},
+		{
+			MethodName: "HaltSending",
+			Handler:    _Api_HaltSending_Handler,
+		},
+		{
+			MethodName: "ResumeSending",
+			Handler:    _Api_ResumeSending_Handler,
+		},
+		{
+			MethodName: "GetSendingStatus",
+			Handler:    _Api_GetSendingStatus_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "api.proto",
@@ -20,6 +20,29 @@ const _ = grpc.SupportPackageIsVersion7
 type MailerClient interface {
 	SendHTML(ctx context.Context, in *SendHTMLRequest, opts ...grpc.CallOption) (*SendResponse, error)
 	SendTemplate(ctx context.Context, in *SendTemplateRequest, opts ...grpc.CallOption) (*SendResponse, error)
+	// ResendMessage sends a message again as a new message linked to the original
+	ResendMessage(ctx context.Context, in *ResendMessageRequest, opts ...grpc.CallOption) (*SendResponse, error)
+	// CancelMessage cancels the emails of a message not dispatched yet
+	CancelMessage(ctx context.Context, in *CancelMessageRequest, opts ...grpc.CallOption) (*CancelMessageResponse, error)
+	// PreviewTemplate renders a template for a recipient without sending it
+	PreviewTemplate(ctx context.Context, in *PreviewTemplateRequest, opts ...grpc.CallOption) (*PreviewResponse, error)
+	// GetStats returns the event counts of the domain, or of one of its messages
+	GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*Stats, error)
+	// GetSMTPResponses returns the responses of the MXs to the deliveries of the
+	// domain by receiving provider, SMTP code and enhanced status code
+	GetSMTPResponses(ctx context.Context, in *GetSMTPResponsesRequest, opts ...grpc.CallOption) (*SMTPResponses, error)
+	// GetDMARCStats returns the DMARC results of the emails of the domain
+	// in the aggregate reports received, by source ip
+	GetDMARCStats(ctx context.Context, in *GetDMARCStatsRequest, opts ...grpc.CallOption) (*DMARCStats, error)
+	// GetQuota returns the daily and monthly quota of the domain and its usage
+	GetQuota(ctx context.Context, in *GetQuotaRequest, opts ...grpc.CallOption) (*Quota, error)
+	// GetMessageStatus returns the status and event history of the recipients of a message
+	GetMessageStatus(ctx context.Context, in *GetMessageStatusRequest, opts ...grpc.CallOption) (*MessageStatus, error)
+	// GetMessageEML returns the signed email sent to a recipient of a message,
+	// when the emails are archived
+	GetMessageEML(ctx context.Context, in *GetMessageEMLRequest, opts ...grpc.CallOption) (*MessageEML, error)
+	// StreamEvents sends the events of the domain as they happen
+	StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (Mailer_StreamEventsClient, error)
 }
 
 type mailerClient struct {
@@ -48,12 +71,148 @@ func (c *mailerClient) SendTemplate(ctx context.Context, in *SendTemplateRequest
 	return out, nil
 }
 
+func (c *mailerClient) ResendMessage(ctx context.Context, in *ResendMessageRequest, opts ...grpc.CallOption) (*SendResponse, error) {
+	out := new(SendResponse)
+	err := c.cc.Invoke(ctx, "/kannon.Mailer/ResendMessage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mailerClient) CancelMessage(ctx context.Context, in *CancelMessageRequest, opts ...grpc.CallOption) (*CancelMessageResponse, error) {
+	out := new(CancelMessageResponse)
+	err := c.cc.Invoke(ctx, "/kannon.Mailer/CancelMessage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mailerClient) PreviewTemplate(ctx context.Context, in *PreviewTemplateRequest, opts ...grpc.CallOption) (*PreviewResponse, error) {
+	out := new(PreviewResponse)
+	err := c.cc.Invoke(ctx, "/kannon.Mailer/PreviewTemplate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mailerClient) GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*Stats, error) {
+	out := new(Stats)
+	err := c.cc.Invoke(ctx, "/kannon.Mailer/GetStats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mailerClient) GetSMTPResponses(ctx context.Context, in *GetSMTPResponsesRequest, opts ...grpc.CallOption) (*SMTPResponses, error) {
+	out := new(SMTPResponses)
+	err := c.cc.Invoke(ctx, "/kannon.Mailer/GetSMTPResponses", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mailerClient) GetDMARCStats(ctx context.Context, in *GetDMARCStatsRequest, opts ...grpc.CallOption) (*DMARCStats, error) {
+	out := new(DMARCStats)
+	err := c.cc.Invoke(ctx, "/kannon.Mailer/GetDMARCStats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mailerClient) GetQuota(ctx context.Context, in *GetQuotaRequest, opts ...grpc.CallOption) (*Quota, error) {
+	out := new(Quota)
+	err := c.cc.Invoke(ctx, "/kannon.Mailer/GetQuota", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mailerClient) GetMessageStatus(ctx context.Context, in *GetMessageStatusRequest, opts ...grpc.CallOption) (*MessageStatus, error) {
+	out := new(MessageStatus)
+	err := c.cc.Invoke(ctx, "/kannon.Mailer/GetMessageStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mailerClient) GetMessageEML(ctx context.Context, in *GetMessageEMLRequest, opts ...grpc.CallOption) (*MessageEML, error) {
+	out := new(MessageEML)
+	err := c.cc.Invoke(ctx, "/kannon.Mailer/GetMessageEML", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mailerClient) StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (Mailer_StreamEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Mailer_ServiceDesc.Streams[0], "/kannon.Mailer/StreamEvents", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &mailerStreamEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Mailer_StreamEventsClient interface {
+	Recv() (*MessageEvent, error)
+	grpc.ClientStream
+}
+
+type mailerStreamEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *mailerStreamEventsClient) Recv() (*MessageEvent, error) {
+	m := new(MessageEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // MailerServer is the server API for Mailer service.
 // All implementations should embed UnimplementedMailerServer
 // for forward compatibility
 type MailerServer interface {
 	SendHTML(context.Context, *SendHTMLRequest) (*SendResponse, error)
 	SendTemplate(context.Context, *SendTemplateRequest) (*SendResponse, error)
+	// ResendMessage sends a message again as a new message linked to the original
+	ResendMessage(context.Context, *ResendMessageRequest) (*SendResponse, error)
+	// CancelMessage cancels the emails of a message not dispatched yet
+	CancelMessage(context.Context, *CancelMessageRequest) (*CancelMessageResponse, error)
+	// PreviewTemplate renders a template for a recipient without sending it
+	PreviewTemplate(context.Context, *PreviewTemplateRequest) (*PreviewResponse, error)
+	// GetStats returns the event counts of the domain, or of one of its messages
+	GetStats(context.Context, *GetStatsRequest) (*Stats, error)
+	// GetSMTPResponses returns the responses of the MXs to the deliveries of the
+	// domain by receiving provider, SMTP code and enhanced status code
+	GetSMTPResponses(context.Context, *GetSMTPResponsesRequest) (*SMTPResponses, error)
+	// GetDMARCStats returns the DMARC results of the emails of the domain
+	// in the aggregate reports received, by source ip
+	GetDMARCStats(context.Context, *GetDMARCStatsRequest) (*DMARCStats, error)
+	// GetQuota returns the daily and monthly quota of the domain and its usage
+	GetQuota(context.Context, *GetQuotaRequest) (*Quota, error)
+	// GetMessageStatus returns the status and event history of the recipients of a message
+	GetMessageStatus(context.Context, *GetMessageStatusRequest) (*MessageStatus, error)
+	// GetMessageEML returns the signed email sent to a recipient of a message,
+	// when the emails are archived
+	GetMessageEML(context.Context, *GetMessageEMLRequest) (*MessageEML, error)
+	// StreamEvents sends the events of the domain as they happen
+	StreamEvents(*StreamEventsRequest, Mailer_StreamEventsServer) error
 }
 
 // UnimplementedMailerServer should be embedded to have forward compatible implementations.
@@ -66,6 +225,36 @@ func (UnimplementedMailerServer) SendHTML(context.Context, *SendHTMLRequest) (*S
 func (UnimplementedMailerServer) SendTemplate(context.Context, *SendTemplateRequest) (*SendResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SendTemplate not implemented")
 }
+func (UnimplementedMailerServer) ResendMessage(context.Context, *ResendMessageRequest) (*SendResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResendMessage not implemented")
+}
+func (UnimplementedMailerServer) CancelMessage(context.Context, *CancelMessageRequest) (*CancelMessageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelMessage not implemented")
+}
+func (UnimplementedMailerServer) PreviewTemplate(context.Context, *PreviewTemplateRequest) (*PreviewResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PreviewTemplate not implemented")
+}
+func (UnimplementedMailerServer) GetStats(context.Context, *GetStatsRequest) (*Stats, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStats not implemented")
+}
+func (UnimplementedMailerServer) GetSMTPResponses(context.Context, *GetSMTPResponsesRequest) (*SMTPResponses, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSMTPResponses not implemented")
+}
+func (UnimplementedMailerServer) GetDMARCStats(context.Context, *GetDMARCStatsRequest) (*DMARCStats, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDMARCStats not implemented")
+}
+func (UnimplementedMailerServer) GetQuota(context.Context, *GetQuotaRequest) (*Quota, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetQuota not implemented")
+}
+func (UnimplementedMailerServer) GetMessageStatus(context.Context, *GetMessageStatusRequest) (*MessageStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMessageStatus not implemented")
+}
+func (UnimplementedMailerServer) GetMessageEML(context.Context, *GetMessageEMLRequest) (*MessageEML, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMessageEML not implemented")
+}
+func (UnimplementedMailerServer) StreamEvents(*StreamEventsRequest, Mailer_StreamEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamEvents not implemented")
+}
 
 // UnsafeMailerServer may be embedded to opt out of forward compatibility for this service.
 // Use of this interface is not recommended, as added methods to MailerServer will
@@ -114,6 +303,189 @@ func _Mailer_SendTemplate_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Mailer_ResendMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResendMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailerServer).ResendMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Mailer/ResendMessage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailerServer).ResendMessage(ctx, req.(*ResendMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mailer_CancelMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailerServer).CancelMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Mailer/CancelMessage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailerServer).CancelMessage(ctx, req.(*CancelMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mailer_PreviewTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PreviewTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailerServer).PreviewTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Mailer/PreviewTemplate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailerServer).PreviewTemplate(ctx, req.(*PreviewTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mailer_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailerServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Mailer/GetStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailerServer).GetStats(ctx, req.(*GetStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mailer_GetSMTPResponses_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSMTPResponsesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailerServer).GetSMTPResponses(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Mailer/GetSMTPResponses",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailerServer).GetSMTPResponses(ctx, req.(*GetSMTPResponsesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mailer_GetDMARCStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDMARCStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailerServer).GetDMARCStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Mailer/GetDMARCStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailerServer).GetDMARCStats(ctx, req.(*GetDMARCStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mailer_GetQuota_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetQuotaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailerServer).GetQuota(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Mailer/GetQuota",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailerServer).GetQuota(ctx, req.(*GetQuotaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mailer_GetMessageStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMessageStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailerServer).GetMessageStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Mailer/GetMessageStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailerServer).GetMessageStatus(ctx, req.(*GetMessageStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mailer_GetMessageEML_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMessageEMLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MailerServer).GetMessageEML(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kannon.Mailer/GetMessageEML",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MailerServer).GetMessageEML(ctx, req.(*GetMessageEMLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mailer_StreamEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MailerServer).StreamEvents(m, &mailerStreamEventsServer{stream})
+}
+
+type Mailer_StreamEventsServer interface {
+	Send(*MessageEvent) error
+	grpc.ServerStream
+}
+
+type mailerStreamEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *mailerStreamEventsServer) Send(m *MessageEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // Mailer_ServiceDesc is the grpc.ServiceDesc for Mailer service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -129,7 +501,49 @@ var Mailer_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SendTemplate",
 			Handler:    _Mailer_SendTemplate_Handler,
 		},
+		{
+			MethodName: "ResendMessage",
+			Handler:    _Mailer_ResendMessage_Handler,
+		},
+		{
+			MethodName: "CancelMessage",
+			Handler:    _Mailer_CancelMessage_Handler,
+		},
+		{
+			MethodName: "PreviewTemplate",
+			Handler:    _Mailer_PreviewTemplate_Handler,
+		},
+		{
+			MethodName: "GetStats",
+			Handler:    _Mailer_GetStats_Handler,
+		},
+		{
+			MethodName: "GetSMTPResponses",
+			Handler:    _Mailer_GetSMTPResponses_Handler,
+		},
+		{
+			MethodName: "GetDMARCStats",
+			Handler:    _Mailer_GetDMARCStats_Handler,
+		},
+		{
+			MethodName: "GetQuota",
+			Handler:    _Mailer_GetQuota_Handler,
+		},
+		{
+			MethodName: "GetMessageStatus",
+			Handler:    _Mailer_GetMessageStatus_Handler,
+		},
+		{
+			MethodName: "GetMessageEML",
+			Handler:    _Mailer_GetMessageEML_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamEvents",
+			Handler:       _Mailer_StreamEvents_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "mailer.proto",
 }
@@ -22,72 +22,593 @@ func New(db DBTX) *Queries {
 func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	q := Queries{db: db}
 	var err error
+	if q.addWarmupUsageStmt, err = db.PrepareContext(ctx, addWarmupUsage); err != nil {
+		return nil, fmt.Errorf("error preparing query AddWarmupUsage: %w", err)
+	}
+	if q.cancelDomainPoolStmt, err = db.PrepareContext(ctx, cancelDomainPool); err != nil {
+		return nil, fmt.Errorf("error preparing query CancelDomainPool: %w", err)
+	}
+	if q.cancelMessagePoolStmt, err = db.PrepareContext(ctx, cancelMessagePool); err != nil {
+		return nil, fmt.Errorf("error preparing query CancelMessagePool: %w", err)
+	}
+	if q.claimDeadLetterStmt, err = db.PrepareContext(ctx, claimDeadLetter); err != nil {
+		return nil, fmt.Errorf("error preparing query ClaimDeadLetter: %w", err)
+	}
+	if q.consumeDomainQuotaStmt, err = db.PrepareContext(ctx, consumeDomainQuota); err != nil {
+		return nil, fmt.Errorf("error preparing query ConsumeDomainQuota: %w", err)
+	}
+	if q.countEventStmt, err = db.PrepareContext(ctx, countEvent); err != nil {
+		return nil, fmt.Errorf("error preparing query CountEvent: %w", err)
+	}
+	if q.countPendingPoolEmailsStmt, err = db.PrepareContext(ctx, countPendingPoolEmails); err != nil {
+		return nil, fmt.Errorf("error preparing query CountPendingPoolEmails: %w", err)
+	}
+	if q.countSendingPoolEmailsInFlightStmt, err = db.PrepareContext(ctx, countSendingPoolEmailsInFlight); err != nil {
+		return nil, fmt.Errorf("error preparing query CountSendingPoolEmailsInFlight: %w", err)
+	}
+	if q.createAPIKeyStmt, err = db.PrepareContext(ctx, createAPIKey); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateAPIKey: %w", err)
+	}
+	if q.createAPIKeyCallStmt, err = db.PrepareContext(ctx, createAPIKeyCall); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateAPIKeyCall: %w", err)
+	}
+	if q.createAttachmentStmt, err = db.PrepareContext(ctx, createAttachment); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateAttachment: %w", err)
+	}
+	if q.createAuditLogStmt, err = db.PrepareContext(ctx, createAuditLog); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateAuditLog: %w", err)
+	}
+	if q.createComplaintStmt, err = db.PrepareContext(ctx, createComplaint); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateComplaint: %w", err)
+	}
+	if q.createDKIMKeyStmt, err = db.PrepareContext(ctx, createDKIMKey); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateDKIMKey: %w", err)
+	}
+	if q.createDMARCRecordStmt, err = db.PrepareContext(ctx, createDMARCRecord); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateDMARCRecord: %w", err)
+	}
+	if q.createDMARCReportStmt, err = db.PrepareContext(ctx, createDMARCReport); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateDMARCReport: %w", err)
+	}
+	if q.createDeadLetterStmt, err = db.PrepareContext(ctx, createDeadLetter); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateDeadLetter: %w", err)
+	}
 	if q.createDomainStmt, err = db.PrepareContext(ctx, createDomain); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateDomain: %w", err)
 	}
+	if q.createDomainUsageStmt, err = db.PrepareContext(ctx, createDomainUsage); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateDomainUsage: %w", err)
+	}
 	if q.createMessageStmt, err = db.PrepareContext(ctx, createMessage); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateMessage: %w", err)
 	}
+	if q.createMessageEventStmt, err = db.PrepareContext(ctx, createMessageEvent); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateMessageEvent: %w", err)
+	}
+	if q.createOpenStmt, err = db.PrepareContext(ctx, createOpen); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateOpen: %w", err)
+	}
 	if q.createPoolStmt, err = db.PrepareContext(ctx, createPool); err != nil {
 		return nil, fmt.Errorf("error preparing query CreatePool: %w", err)
 	}
+	if q.createPoolTimelineEntriesStmt, err = db.PrepareContext(ctx, createPoolTimelineEntries); err != nil {
+		return nil, fmt.Errorf("error preparing query CreatePoolTimelineEntries: %w", err)
+	}
+	if q.createSuppressionStmt, err = db.PrepareContext(ctx, createSuppression); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateSuppression: %w", err)
+	}
 	if q.createTemplateStmt, err = db.PrepareContext(ctx, createTemplate); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateTemplate: %w", err)
 	}
+	if q.createTemplateVersionStmt, err = db.PrepareContext(ctx, createTemplateVersion); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateTemplateVersion: %w", err)
+	}
+	if q.createTimelineEntryStmt, err = db.PrepareContext(ctx, createTimelineEntry); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateTimelineEntry: %w", err)
+	}
+	if q.createWebhookStmt, err = db.PrepareContext(ctx, createWebhook); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateWebhook: %w", err)
+	}
+	if q.createWebhookDeliveriesStmt, err = db.PrepareContext(ctx, createWebhookDeliveries); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateWebhookDeliveries: %w", err)
+	}
+	if q.deferSendingPoolEmailStmt, err = db.PrepareContext(ctx, deferSendingPoolEmail); err != nil {
+		return nil, fmt.Errorf("error preparing query DeferSendingPoolEmail: %w", err)
+	}
+	if q.deleteDomainStmt, err = db.PrepareContext(ctx, deleteDomain); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteDomain: %w", err)
+	}
+	if q.deleteSuppressionStmt, err = db.PrepareContext(ctx, deleteSuppression); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteSuppression: %w", err)
+	}
+	if q.deleteTemplateStmt, err = db.PrepareContext(ctx, deleteTemplate); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteTemplate: %w", err)
+	}
+	if q.deleteWebhookStmt, err = db.PrepareContext(ctx, deleteWebhook); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteWebhook: %w", err)
+	}
+	if q.expireAPIKeyStmt, err = db.PrepareContext(ctx, expireAPIKey); err != nil {
+		return nil, fmt.Errorf("error preparing query ExpireAPIKey: %w", err)
+	}
+	if q.exportMessageEventsStmt, err = db.PrepareContext(ctx, exportMessageEvents); err != nil {
+		return nil, fmt.Errorf("error preparing query ExportMessageEvents: %w", err)
+	}
+	if q.findAPIKeyStmt, err = db.PrepareContext(ctx, findAPIKey); err != nil {
+		return nil, fmt.Errorf("error preparing query FindAPIKey: %w", err)
+	}
+	if q.findDeletedDomainStmt, err = db.PrepareContext(ctx, findDeletedDomain); err != nil {
+		return nil, fmt.Errorf("error preparing query FindDeletedDomain: %w", err)
+	}
 	if q.findDomainStmt, err = db.PrepareContext(ctx, findDomain); err != nil {
 		return nil, fmt.Errorf("error preparing query FindDomain: %w", err)
 	}
-	if q.findDomainWithKeyStmt, err = db.PrepareContext(ctx, findDomainWithKey); err != nil {
-		return nil, fmt.Errorf("error preparing query FindDomainWithKey: %w", err)
+	if q.findMessageWithIdempotencyKeyStmt, err = db.PrepareContext(ctx, findMessageWithIdempotencyKey); err != nil {
+		return nil, fmt.Errorf("error preparing query FindMessageWithIdempotencyKey: %w", err)
+	}
+	if q.findPendingDKIMKeyStmt, err = db.PrepareContext(ctx, findPendingDKIMKey); err != nil {
+		return nil, fmt.Errorf("error preparing query FindPendingDKIMKey: %w", err)
+	}
+	if q.findSendingPoolEmailStmt, err = db.PrepareContext(ctx, findSendingPoolEmail); err != nil {
+		return nil, fmt.Errorf("error preparing query FindSendingPoolEmail: %w", err)
 	}
 	if q.findTemplateStmt, err = db.PrepareContext(ctx, findTemplate); err != nil {
 		return nil, fmt.Errorf("error preparing query FindTemplate: %w", err)
 	}
+	if q.findTemplateByNameStmt, err = db.PrepareContext(ctx, findTemplateByName); err != nil {
+		return nil, fmt.Errorf("error preparing query FindTemplateByName: %w", err)
+	}
+	if q.findTemplateVersionStmt, err = db.PrepareContext(ctx, findTemplateVersion); err != nil {
+		return nil, fmt.Errorf("error preparing query FindTemplateVersion: %w", err)
+	}
+	if q.fireAlertStmt, err = db.PrepareContext(ctx, fireAlert); err != nil {
+		return nil, fmt.Errorf("error preparing query FireAlert: %w", err)
+	}
+	if q.getAPIKeyStmt, err = db.PrepareContext(ctx, getAPIKey); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAPIKey: %w", err)
+	}
+	if q.getAPIKeyCallsStmt, err = db.PrepareContext(ctx, getAPIKeyCalls); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAPIKeyCalls: %w", err)
+	}
+	if q.getAPIKeysStmt, err = db.PrepareContext(ctx, getAPIKeys); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAPIKeys: %w", err)
+	}
 	if q.getAllDomainsStmt, err = db.PrepareContext(ctx, getAllDomains); err != nil {
 		return nil, fmt.Errorf("error preparing query GetAllDomains: %w", err)
 	}
+	if q.getAuditLogsStmt, err = db.PrepareContext(ctx, getAuditLogs); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAuditLogs: %w", err)
+	}
+	if q.getDKIMKeysStmt, err = db.PrepareContext(ctx, getDKIMKeys); err != nil {
+		return nil, fmt.Errorf("error preparing query GetDKIMKeys: %w", err)
+	}
+	if q.getDMARCStatsStmt, err = db.PrepareContext(ctx, getDMARCStats); err != nil {
+		return nil, fmt.Errorf("error preparing query GetDMARCStats: %w", err)
+	}
+	if q.getDeadLettersStmt, err = db.PrepareContext(ctx, getDeadLetters); err != nil {
+		return nil, fmt.Errorf("error preparing query GetDeadLetters: %w", err)
+	}
+	if q.getDomainRecordsStmt, err = db.PrepareContext(ctx, getDomainRecords); err != nil {
+		return nil, fmt.Errorf("error preparing query GetDomainRecords: %w", err)
+	}
+	if q.getDomainUsageStmt, err = db.PrepareContext(ctx, getDomainUsage); err != nil {
+		return nil, fmt.Errorf("error preparing query GetDomainUsage: %w", err)
+	}
 	if q.getDomainsStmt, err = db.PrepareContext(ctx, getDomains); err != nil {
 		return nil, fmt.Errorf("error preparing query GetDomains: %w", err)
 	}
+	if q.getDomainsStatsStmt, err = db.PrepareContext(ctx, getDomainsStats); err != nil {
+		return nil, fmt.Errorf("error preparing query GetDomainsStats: %w", err)
+	}
+	if q.getDomainsToPurgeStmt, err = db.PrepareContext(ctx, getDomainsToPurge); err != nil {
+		return nil, fmt.Errorf("error preparing query GetDomainsToPurge: %w", err)
+	}
+	if q.getEventDaysToExportStmt, err = db.PrepareContext(ctx, getEventDaysToExport); err != nil {
+		return nil, fmt.Errorf("error preparing query GetEventDaysToExport: %w", err)
+	}
+	if q.getEventExportStmt, err = db.PrepareContext(ctx, getEventExport); err != nil {
+		return nil, fmt.Errorf("error preparing query GetEventExport: %w", err)
+	}
+	if q.getFiringAlertsStmt, err = db.PrepareContext(ctx, getFiringAlerts); err != nil {
+		return nil, fmt.Errorf("error preparing query GetFiringAlerts: %w", err)
+	}
+	if q.getLastMessageEventIDStmt, err = db.PrepareContext(ctx, getLastMessageEventID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetLastMessageEventID: %w", err)
+	}
+	if q.getMaintenanceStmt, err = db.PrepareContext(ctx, getMaintenance); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMaintenance: %w", err)
+	}
+	if q.getMessageStmt, err = db.PrepareContext(ctx, getMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMessage: %w", err)
+	}
+	if q.getMessageAttachmentsStmt, err = db.PrepareContext(ctx, getMessageAttachments); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMessageAttachments: %w", err)
+	}
+	if q.getMessageEventsStmt, err = db.PrepareContext(ctx, getMessageEvents); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMessageEvents: %w", err)
+	}
+	if q.getMessageRecipientsStmt, err = db.PrepareContext(ctx, getMessageRecipients); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMessageRecipients: %w", err)
+	}
+	if q.getMessageStatsStmt, err = db.PrepareContext(ctx, getMessageStats); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMessageStats: %w", err)
+	}
+	if q.getMessageTimelineStmt, err = db.PrepareContext(ctx, getMessageTimeline); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMessageTimeline: %w", err)
+	}
+	if q.getOldestPendingPoolEmailStmt, err = db.PrepareContext(ctx, getOldestPendingPoolEmail); err != nil {
+		return nil, fmt.Errorf("error preparing query GetOldestPendingPoolEmail: %w", err)
+	}
+	if q.getSMTPResponsesStmt, err = db.PrepareContext(ctx, getSMTPResponses); err != nil {
+		return nil, fmt.Errorf("error preparing query GetSMTPResponses: %w", err)
+	}
 	if q.getSendingDataStmt, err = db.PrepareContext(ctx, getSendingData); err != nil {
 		return nil, fmt.Errorf("error preparing query GetSendingData: %w", err)
 	}
+	if q.getStatsStmt, err = db.PrepareContext(ctx, getStats); err != nil {
+		return nil, fmt.Errorf("error preparing query GetStats: %w", err)
+	}
+	if q.getSuppressionsStmt, err = db.PrepareContext(ctx, getSuppressions); err != nil {
+		return nil, fmt.Errorf("error preparing query GetSuppressions: %w", err)
+	}
+	if q.getTemplatesStmt, err = db.PrepareContext(ctx, getTemplates); err != nil {
+		return nil, fmt.Errorf("error preparing query GetTemplates: %w", err)
+	}
+	if q.getWebhookDeliveriesStmt, err = db.PrepareContext(ctx, getWebhookDeliveries); err != nil {
+		return nil, fmt.Errorf("error preparing query GetWebhookDeliveries: %w", err)
+	}
+	if q.getWebhooksStmt, err = db.PrepareContext(ctx, getWebhooks); err != nil {
+		return nil, fmt.Errorf("error preparing query GetWebhooks: %w", err)
+	}
+	if q.incrementSMTPResponsesStmt, err = db.PrepareContext(ctx, incrementSMTPResponses); err != nil {
+		return nil, fmt.Errorf("error preparing query IncrementSMTPResponses: %w", err)
+	}
+	if q.incrementStatsStmt, err = db.PrepareContext(ctx, incrementStats); err != nil {
+		return nil, fmt.Errorf("error preparing query IncrementStats: %w", err)
+	}
+	if q.isRecipientSuppressedStmt, err = db.PrepareContext(ctx, isRecipientSuppressed); err != nil {
+		return nil, fmt.Errorf("error preparing query IsRecipientSuppressed: %w", err)
+	}
+	if q.pauseDomainStmt, err = db.PrepareContext(ctx, pauseDomain); err != nil {
+		return nil, fmt.Errorf("error preparing query PauseDomain: %w", err)
+	}
 	if q.prepareForSendStmt, err = db.PrepareContext(ctx, prepareForSend); err != nil {
 		return nil, fmt.Errorf("error preparing query PrepareForSend: %w", err)
 	}
+	if q.prepareWebhookDeliveriesStmt, err = db.PrepareContext(ctx, prepareWebhookDeliveries); err != nil {
+		return nil, fmt.Errorf("error preparing query PrepareWebhookDeliveries: %w", err)
+	}
+	if q.promoteDKIMKeyStmt, err = db.PrepareContext(ctx, promoteDKIMKey); err != nil {
+		return nil, fmt.Errorf("error preparing query PromoteDKIMKey: %w", err)
+	}
+	if q.purgeAPIKeyCallsStmt, err = db.PrepareContext(ctx, purgeAPIKeyCalls); err != nil {
+		return nil, fmt.Errorf("error preparing query PurgeAPIKeyCalls: %w", err)
+	}
+	if q.purgeDomainStmt, err = db.PrepareContext(ctx, purgeDomain); err != nil {
+		return nil, fmt.Errorf("error preparing query PurgeDomain: %w", err)
+	}
+	if q.purgeMessagesStmt, err = db.PrepareContext(ctx, purgeMessages); err != nil {
+		return nil, fmt.Errorf("error preparing query PurgeMessages: %w", err)
+	}
+	if q.purgeWebhookDeliveriesStmt, err = db.PrepareContext(ctx, purgeWebhookDeliveries); err != nil {
+		return nil, fmt.Errorf("error preparing query PurgeWebhookDeliveries: %w", err)
+	}
+	if q.reclaimSendingPoolEmailsStmt, err = db.PrepareContext(ctx, reclaimSendingPoolEmails); err != nil {
+		return nil, fmt.Errorf("error preparing query ReclaimSendingPoolEmails: %w", err)
+	}
+	if q.releaseDeadLetterStmt, err = db.PrepareContext(ctx, releaseDeadLetter); err != nil {
+		return nil, fmt.Errorf("error preparing query ReleaseDeadLetter: %w", err)
+	}
+	if q.releaseSendingPoolEmailStmt, err = db.PrepareContext(ctx, releaseSendingPoolEmail); err != nil {
+		return nil, fmt.Errorf("error preparing query ReleaseSendingPoolEmail: %w", err)
+	}
+	if q.requeueSendingPoolEmailStmt, err = db.PrepareContext(ctx, requeueSendingPoolEmail); err != nil {
+		return nil, fmt.Errorf("error preparing query RequeueSendingPoolEmail: %w", err)
+	}
+	if q.resolveAlertStmt, err = db.PrepareContext(ctx, resolveAlert); err != nil {
+		return nil, fmt.Errorf("error preparing query ResolveAlert: %w", err)
+	}
+	if q.restoreDomainStmt, err = db.PrepareContext(ctx, restoreDomain); err != nil {
+		return nil, fmt.Errorf("error preparing query RestoreDomain: %w", err)
+	}
+	if q.resumeDomainStmt, err = db.PrepareContext(ctx, resumeDomain); err != nil {
+		return nil, fmt.Errorf("error preparing query ResumeDomain: %w", err)
+	}
+	if q.retireDKIMKeysStmt, err = db.PrepareContext(ctx, retireDKIMKeys); err != nil {
+		return nil, fmt.Errorf("error preparing query RetireDKIMKeys: %w", err)
+	}
+	if q.revokeAPIKeyStmt, err = db.PrepareContext(ctx, revokeAPIKey); err != nil {
+		return nil, fmt.Errorf("error preparing query RevokeAPIKey: %w", err)
+	}
+	if q.revokeAPIKeysByNameStmt, err = db.PrepareContext(ctx, revokeAPIKeysByName); err != nil {
+		return nil, fmt.Errorf("error preparing query RevokeAPIKeysByName: %w", err)
+	}
+	if q.searchMessagesStmt, err = db.PrepareContext(ctx, searchMessages); err != nil {
+		return nil, fmt.Errorf("error preparing query SearchMessages: %w", err)
+	}
+	if q.setActiveTemplateVersionStmt, err = db.PrepareContext(ctx, setActiveTemplateVersion); err != nil {
+		return nil, fmt.Errorf("error preparing query SetActiveTemplateVersion: %w", err)
+	}
+	if q.setDomainDKIMHeadersStmt, err = db.PrepareContext(ctx, setDomainDKIMHeaders); err != nil {
+		return nil, fmt.Errorf("error preparing query SetDomainDKIMHeaders: %w", err)
+	}
+	if q.setDomainDKIMSigningStmt, err = db.PrepareContext(ctx, setDomainDKIMSigning); err != nil {
+		return nil, fmt.Errorf("error preparing query SetDomainDKIMSigning: %w", err)
+	}
+	if q.setDomainIPPoolStmt, err = db.PrepareContext(ctx, setDomainIPPool); err != nil {
+		return nil, fmt.Errorf("error preparing query SetDomainIPPool: %w", err)
+	}
+	if q.setDomainQuotaStmt, err = db.PrepareContext(ctx, setDomainQuota); err != nil {
+		return nil, fmt.Errorf("error preparing query SetDomainQuota: %w", err)
+	}
+	if q.setDomainRateLimitStmt, err = db.PrepareContext(ctx, setDomainRateLimit); err != nil {
+		return nil, fmt.Errorf("error preparing query SetDomainRateLimit: %w", err)
+	}
+	if q.setDomainRecordStmt, err = db.PrepareContext(ctx, setDomainRecord); err != nil {
+		return nil, fmt.Errorf("error preparing query SetDomainRecord: %w", err)
+	}
+	if q.setDomainRetentionStmt, err = db.PrepareContext(ctx, setDomainRetention); err != nil {
+		return nil, fmt.Errorf("error preparing query SetDomainRetention: %w", err)
+	}
+	if q.setDomainReturnPathDomainStmt, err = db.PrepareContext(ctx, setDomainReturnPathDomain); err != nil {
+		return nil, fmt.Errorf("error preparing query SetDomainReturnPathDomain: %w", err)
+	}
+	if q.setDomainSandboxStmt, err = db.PrepareContext(ctx, setDomainSandbox); err != nil {
+		return nil, fmt.Errorf("error preparing query SetDomainSandbox: %w", err)
+	}
+	if q.setDomainSendingSettingsStmt, err = db.PrepareContext(ctx, setDomainSendingSettings); err != nil {
+		return nil, fmt.Errorf("error preparing query SetDomainSendingSettings: %w", err)
+	}
+	if q.setDomainVerifiedStmt, err = db.PrepareContext(ctx, setDomainVerified); err != nil {
+		return nil, fmt.Errorf("error preparing query SetDomainVerified: %w", err)
+	}
+	if q.setEventExportStmt, err = db.PrepareContext(ctx, setEventExport); err != nil {
+		return nil, fmt.Errorf("error preparing query SetEventExport: %w", err)
+	}
+	if q.setMaintenanceStmt, err = db.PrepareContext(ctx, setMaintenance); err != nil {
+		return nil, fmt.Errorf("error preparing query SetMaintenance: %w", err)
+	}
+	if q.setSendingPoolEmailBouncedStmt, err = db.PrepareContext(ctx, setSendingPoolEmailBounced); err != nil {
+		return nil, fmt.Errorf("error preparing query SetSendingPoolEmailBounced: %w", err)
+	}
+	if q.setSendingPoolEmailDeliveredStmt, err = db.PrepareContext(ctx, setSendingPoolEmailDelivered); err != nil {
+		return nil, fmt.Errorf("error preparing query SetSendingPoolEmailDelivered: %w", err)
+	}
+	if q.setSendingPoolEmailPublishedStmt, err = db.PrepareContext(ctx, setSendingPoolEmailPublished); err != nil {
+		return nil, fmt.Errorf("error preparing query SetSendingPoolEmailPublished: %w", err)
+	}
+	if q.setSendingPoolEmailQuotaConsumedStmt, err = db.PrepareContext(ctx, setSendingPoolEmailQuotaConsumed); err != nil {
+		return nil, fmt.Errorf("error preparing query SetSendingPoolEmailQuotaConsumed: %w", err)
+	}
+	if q.setSendingPoolEmailStatusStmt, err = db.PrepareContext(ctx, setSendingPoolEmailStatus); err != nil {
+		return nil, fmt.Errorf("error preparing query SetSendingPoolEmailStatus: %w", err)
+	}
+	if q.setWebhookDeliveryResultStmt, err = db.PrepareContext(ctx, setWebhookDeliveryResult); err != nil {
+		return nil, fmt.Errorf("error preparing query SetWebhookDeliveryResult: %w", err)
+	}
+	if q.suppressMessageRecipientStmt, err = db.PrepareContext(ctx, suppressMessageRecipient); err != nil {
+		return nil, fmt.Errorf("error preparing query SuppressMessageRecipient: %w", err)
+	}
+	if q.touchAPIKeyStmt, err = db.PrepareContext(ctx, touchAPIKey); err != nil {
+		return nil, fmt.Errorf("error preparing query TouchAPIKey: %w", err)
+	}
 	return &q, nil
 }
 
 func (q *Queries) Close() error {
 	var err error
+	if q.addWarmupUsageStmt != nil {
+		if cerr := q.addWarmupUsageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addWarmupUsageStmt: %w", cerr)
+		}
+	}
+	if q.cancelDomainPoolStmt != nil {
+		if cerr := q.cancelDomainPoolStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing cancelDomainPoolStmt: %w", cerr)
+		}
+	}
+	if q.cancelMessagePoolStmt != nil {
+		if cerr := q.cancelMessagePoolStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing cancelMessagePoolStmt: %w", cerr)
+		}
+	}
+	if q.claimDeadLetterStmt != nil {
+		if cerr := q.claimDeadLetterStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing claimDeadLetterStmt: %w", cerr)
+		}
+	}
+	if q.consumeDomainQuotaStmt != nil {
+		if cerr := q.consumeDomainQuotaStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing consumeDomainQuotaStmt: %w", cerr)
+		}
+	}
+	if q.countEventStmt != nil {
+		if cerr := q.countEventStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countEventStmt: %w", cerr)
+		}
+	}
+	if q.countPendingPoolEmailsStmt != nil {
+		if cerr := q.countPendingPoolEmailsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countPendingPoolEmailsStmt: %w", cerr)
+		}
+	}
+	if q.countSendingPoolEmailsInFlightStmt != nil {
+		if cerr := q.countSendingPoolEmailsInFlightStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countSendingPoolEmailsInFlightStmt: %w", cerr)
+		}
+	}
+	if q.createAPIKeyStmt != nil {
+		if cerr := q.createAPIKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createAPIKeyStmt: %w", cerr)
+		}
+	}
+	if q.createAPIKeyCallStmt != nil {
+		if cerr := q.createAPIKeyCallStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createAPIKeyCallStmt: %w", cerr)
+		}
+	}
+	if q.createAttachmentStmt != nil {
+		if cerr := q.createAttachmentStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createAttachmentStmt: %w", cerr)
+		}
+	}
+	if q.createAuditLogStmt != nil {
+		if cerr := q.createAuditLogStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createAuditLogStmt: %w", cerr)
+		}
+	}
+	if q.createComplaintStmt != nil {
+		if cerr := q.createComplaintStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createComplaintStmt: %w", cerr)
+		}
+	}
+	if q.createDKIMKeyStmt != nil {
+		if cerr := q.createDKIMKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createDKIMKeyStmt: %w", cerr)
+		}
+	}
+	if q.createDMARCRecordStmt != nil {
+		if cerr := q.createDMARCRecordStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createDMARCRecordStmt: %w", cerr)
+		}
+	}
+	if q.createDMARCReportStmt != nil {
+		if cerr := q.createDMARCReportStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createDMARCReportStmt: %w", cerr)
+		}
+	}
+	if q.createDeadLetterStmt != nil {
+		if cerr := q.createDeadLetterStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createDeadLetterStmt: %w", cerr)
+		}
+	}
 	if q.createDomainStmt != nil {
 		if cerr := q.createDomainStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createDomainStmt: %w", cerr)
 		}
 	}
+	if q.createDomainUsageStmt != nil {
+		if cerr := q.createDomainUsageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createDomainUsageStmt: %w", cerr)
+		}
+	}
 	if q.createMessageStmt != nil {
 		if cerr := q.createMessageStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createMessageStmt: %w", cerr)
 		}
 	}
+	if q.createMessageEventStmt != nil {
+		if cerr := q.createMessageEventStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createMessageEventStmt: %w", cerr)
+		}
+	}
+	if q.createOpenStmt != nil {
+		if cerr := q.createOpenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createOpenStmt: %w", cerr)
+		}
+	}
 	if q.createPoolStmt != nil {
 		if cerr := q.createPoolStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createPoolStmt: %w", cerr)
 		}
 	}
+	if q.createPoolTimelineEntriesStmt != nil {
+		if cerr := q.createPoolTimelineEntriesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createPoolTimelineEntriesStmt: %w", cerr)
+		}
+	}
+	if q.createSuppressionStmt != nil {
+		if cerr := q.createSuppressionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createSuppressionStmt: %w", cerr)
+		}
+	}
 	if q.createTemplateStmt != nil {
 		if cerr := q.createTemplateStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createTemplateStmt: %w", cerr)
 		}
 	}
+	if q.createTemplateVersionStmt != nil {
+		if cerr := q.createTemplateVersionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createTemplateVersionStmt: %w", cerr)
+		}
+	}
+	if q.createTimelineEntryStmt != nil {
+		if cerr := q.createTimelineEntryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createTimelineEntryStmt: %w", cerr)
+		}
+	}
+	if q.createWebhookStmt != nil {
+		if cerr := q.createWebhookStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createWebhookStmt: %w", cerr)
+		}
+	}
+	if q.createWebhookDeliveriesStmt != nil {
+		if cerr := q.createWebhookDeliveriesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createWebhookDeliveriesStmt: %w", cerr)
+		}
+	}
+	if q.deferSendingPoolEmailStmt != nil {
+		if cerr := q.deferSendingPoolEmailStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deferSendingPoolEmailStmt: %w", cerr)
+		}
+	}
+	if q.deleteDomainStmt != nil {
+		if cerr := q.deleteDomainStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteDomainStmt: %w", cerr)
+		}
+	}
+	if q.deleteSuppressionStmt != nil {
+		if cerr := q.deleteSuppressionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteSuppressionStmt: %w", cerr)
+		}
+	}
+	if q.deleteTemplateStmt != nil {
+		if cerr := q.deleteTemplateStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteTemplateStmt: %w", cerr)
+		}
+	}
+	if q.deleteWebhookStmt != nil {
+		if cerr := q.deleteWebhookStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteWebhookStmt: %w", cerr)
+		}
+	}
+	if q.expireAPIKeyStmt != nil {
+		if cerr := q.expireAPIKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing expireAPIKeyStmt: %w", cerr)
+		}
+	}
+	if q.exportMessageEventsStmt != nil {
+		if cerr := q.exportMessageEventsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing exportMessageEventsStmt: %w", cerr)
+		}
+	}
+	if q.findAPIKeyStmt != nil {
+		if cerr := q.findAPIKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing findAPIKeyStmt: %w", cerr)
+		}
+	}
+	if q.findDeletedDomainStmt != nil {
+		if cerr := q.findDeletedDomainStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing findDeletedDomainStmt: %w", cerr)
+		}
+	}
 	if q.findDomainStmt != nil {
 		if cerr := q.findDomainStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing findDomainStmt: %w", cerr)
 		}
 	}
-	if q.findDomainWithKeyStmt != nil {
-		if cerr := q.findDomainWithKeyStmt.Close(); cerr != nil {
-			err = fmt.Errorf("error closing findDomainWithKeyStmt: %w", cerr)
+	if q.findMessageWithIdempotencyKeyStmt != nil {
+		if cerr := q.findMessageWithIdempotencyKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing findMessageWithIdempotencyKeyStmt: %w", cerr)
+		}
+	}
+	if q.findPendingDKIMKeyStmt != nil {
+		if cerr := q.findPendingDKIMKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing findPendingDKIMKeyStmt: %w", cerr)
+		}
+	}
+	if q.findSendingPoolEmailStmt != nil {
+		if cerr := q.findSendingPoolEmailStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing findSendingPoolEmailStmt: %w", cerr)
 		}
 	}
 	if q.findTemplateStmt != nil {
@@ -95,26 +616,401 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing findTemplateStmt: %w", cerr)
 		}
 	}
+	if q.findTemplateByNameStmt != nil {
+		if cerr := q.findTemplateByNameStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing findTemplateByNameStmt: %w", cerr)
+		}
+	}
+	if q.findTemplateVersionStmt != nil {
+		if cerr := q.findTemplateVersionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing findTemplateVersionStmt: %w", cerr)
+		}
+	}
+	if q.fireAlertStmt != nil {
+		if cerr := q.fireAlertStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing fireAlertStmt: %w", cerr)
+		}
+	}
+	if q.getAPIKeyStmt != nil {
+		if cerr := q.getAPIKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAPIKeyStmt: %w", cerr)
+		}
+	}
+	if q.getAPIKeyCallsStmt != nil {
+		if cerr := q.getAPIKeyCallsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAPIKeyCallsStmt: %w", cerr)
+		}
+	}
+	if q.getAPIKeysStmt != nil {
+		if cerr := q.getAPIKeysStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAPIKeysStmt: %w", cerr)
+		}
+	}
 	if q.getAllDomainsStmt != nil {
 		if cerr := q.getAllDomainsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getAllDomainsStmt: %w", cerr)
 		}
 	}
+	if q.getAuditLogsStmt != nil {
+		if cerr := q.getAuditLogsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAuditLogsStmt: %w", cerr)
+		}
+	}
+	if q.getDKIMKeysStmt != nil {
+		if cerr := q.getDKIMKeysStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getDKIMKeysStmt: %w", cerr)
+		}
+	}
+	if q.getDMARCStatsStmt != nil {
+		if cerr := q.getDMARCStatsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getDMARCStatsStmt: %w", cerr)
+		}
+	}
+	if q.getDeadLettersStmt != nil {
+		if cerr := q.getDeadLettersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getDeadLettersStmt: %w", cerr)
+		}
+	}
+	if q.getDomainRecordsStmt != nil {
+		if cerr := q.getDomainRecordsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getDomainRecordsStmt: %w", cerr)
+		}
+	}
+	if q.getDomainUsageStmt != nil {
+		if cerr := q.getDomainUsageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getDomainUsageStmt: %w", cerr)
+		}
+	}
 	if q.getDomainsStmt != nil {
 		if cerr := q.getDomainsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getDomainsStmt: %w", cerr)
 		}
 	}
+	if q.getDomainsStatsStmt != nil {
+		if cerr := q.getDomainsStatsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getDomainsStatsStmt: %w", cerr)
+		}
+	}
+	if q.getDomainsToPurgeStmt != nil {
+		if cerr := q.getDomainsToPurgeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getDomainsToPurgeStmt: %w", cerr)
+		}
+	}
+	if q.getEventDaysToExportStmt != nil {
+		if cerr := q.getEventDaysToExportStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getEventDaysToExportStmt: %w", cerr)
+		}
+	}
+	if q.getEventExportStmt != nil {
+		if cerr := q.getEventExportStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getEventExportStmt: %w", cerr)
+		}
+	}
+	if q.getFiringAlertsStmt != nil {
+		if cerr := q.getFiringAlertsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getFiringAlertsStmt: %w", cerr)
+		}
+	}
+	if q.getLastMessageEventIDStmt != nil {
+		if cerr := q.getLastMessageEventIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getLastMessageEventIDStmt: %w", cerr)
+		}
+	}
+	if q.getMaintenanceStmt != nil {
+		if cerr := q.getMaintenanceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMaintenanceStmt: %w", cerr)
+		}
+	}
+	if q.getMessageStmt != nil {
+		if cerr := q.getMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMessageStmt: %w", cerr)
+		}
+	}
+	if q.getMessageAttachmentsStmt != nil {
+		if cerr := q.getMessageAttachmentsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMessageAttachmentsStmt: %w", cerr)
+		}
+	}
+	if q.getMessageEventsStmt != nil {
+		if cerr := q.getMessageEventsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMessageEventsStmt: %w", cerr)
+		}
+	}
+	if q.getMessageRecipientsStmt != nil {
+		if cerr := q.getMessageRecipientsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMessageRecipientsStmt: %w", cerr)
+		}
+	}
+	if q.getMessageStatsStmt != nil {
+		if cerr := q.getMessageStatsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMessageStatsStmt: %w", cerr)
+		}
+	}
+	if q.getMessageTimelineStmt != nil {
+		if cerr := q.getMessageTimelineStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMessageTimelineStmt: %w", cerr)
+		}
+	}
+	if q.getOldestPendingPoolEmailStmt != nil {
+		if cerr := q.getOldestPendingPoolEmailStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getOldestPendingPoolEmailStmt: %w", cerr)
+		}
+	}
+	if q.getSMTPResponsesStmt != nil {
+		if cerr := q.getSMTPResponsesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getSMTPResponsesStmt: %w", cerr)
+		}
+	}
 	if q.getSendingDataStmt != nil {
 		if cerr := q.getSendingDataStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getSendingDataStmt: %w", cerr)
 		}
 	}
+	if q.getStatsStmt != nil {
+		if cerr := q.getStatsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getStatsStmt: %w", cerr)
+		}
+	}
+	if q.getSuppressionsStmt != nil {
+		if cerr := q.getSuppressionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getSuppressionsStmt: %w", cerr)
+		}
+	}
+	if q.getTemplatesStmt != nil {
+		if cerr := q.getTemplatesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getTemplatesStmt: %w", cerr)
+		}
+	}
+	if q.getWebhookDeliveriesStmt != nil {
+		if cerr := q.getWebhookDeliveriesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getWebhookDeliveriesStmt: %w", cerr)
+		}
+	}
+	if q.getWebhooksStmt != nil {
+		if cerr := q.getWebhooksStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getWebhooksStmt: %w", cerr)
+		}
+	}
+	if q.incrementSMTPResponsesStmt != nil {
+		if cerr := q.incrementSMTPResponsesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing incrementSMTPResponsesStmt: %w", cerr)
+		}
+	}
+	if q.incrementStatsStmt != nil {
+		if cerr := q.incrementStatsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing incrementStatsStmt: %w", cerr)
+		}
+	}
+	if q.isRecipientSuppressedStmt != nil {
+		if cerr := q.isRecipientSuppressedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing isRecipientSuppressedStmt: %w", cerr)
+		}
+	}
+	if q.pauseDomainStmt != nil {
+		if cerr := q.pauseDomainStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing pauseDomainStmt: %w", cerr)
+		}
+	}
 	if q.prepareForSendStmt != nil {
 		if cerr := q.prepareForSendStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing prepareForSendStmt: %w", cerr)
 		}
 	}
+	if q.prepareWebhookDeliveriesStmt != nil {
+		if cerr := q.prepareWebhookDeliveriesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing prepareWebhookDeliveriesStmt: %w", cerr)
+		}
+	}
+	if q.promoteDKIMKeyStmt != nil {
+		if cerr := q.promoteDKIMKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing promoteDKIMKeyStmt: %w", cerr)
+		}
+	}
+	if q.purgeAPIKeyCallsStmt != nil {
+		if cerr := q.purgeAPIKeyCallsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing purgeAPIKeyCallsStmt: %w", cerr)
+		}
+	}
+	if q.purgeDomainStmt != nil {
+		if cerr := q.purgeDomainStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing purgeDomainStmt: %w", cerr)
+		}
+	}
+	if q.purgeMessagesStmt != nil {
+		if cerr := q.purgeMessagesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing purgeMessagesStmt: %w", cerr)
+		}
+	}
+	if q.purgeWebhookDeliveriesStmt != nil {
+		if cerr := q.purgeWebhookDeliveriesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing purgeWebhookDeliveriesStmt: %w", cerr)
+		}
+	}
+	if q.reclaimSendingPoolEmailsStmt != nil {
+		if cerr := q.reclaimSendingPoolEmailsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing reclaimSendingPoolEmailsStmt: %w", cerr)
+		}
+	}
+	if q.releaseDeadLetterStmt != nil {
+		if cerr := q.releaseDeadLetterStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing releaseDeadLetterStmt: %w", cerr)
+		}
+	}
+	if q.releaseSendingPoolEmailStmt != nil {
+		if cerr := q.releaseSendingPoolEmailStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing releaseSendingPoolEmailStmt: %w", cerr)
+		}
+	}
+	if q.requeueSendingPoolEmailStmt != nil {
+		if cerr := q.requeueSendingPoolEmailStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing requeueSendingPoolEmailStmt: %w", cerr)
+		}
+	}
+	if q.resolveAlertStmt != nil {
+		if cerr := q.resolveAlertStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing resolveAlertStmt: %w", cerr)
+		}
+	}
+	if q.restoreDomainStmt != nil {
+		if cerr := q.restoreDomainStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing restoreDomainStmt: %w", cerr)
+		}
+	}
+	if q.resumeDomainStmt != nil {
+		if cerr := q.resumeDomainStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing resumeDomainStmt: %w", cerr)
+		}
+	}
+	if q.retireDKIMKeysStmt != nil {
+		if cerr := q.retireDKIMKeysStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing retireDKIMKeysStmt: %w", cerr)
+		}
+	}
+	if q.revokeAPIKeyStmt != nil {
+		if cerr := q.revokeAPIKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing revokeAPIKeyStmt: %w", cerr)
+		}
+	}
+	if q.revokeAPIKeysByNameStmt != nil {
+		if cerr := q.revokeAPIKeysByNameStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing revokeAPIKeysByNameStmt: %w", cerr)
+		}
+	}
+	if q.searchMessagesStmt != nil {
+		if cerr := q.searchMessagesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing searchMessagesStmt: %w", cerr)
+		}
+	}
+	if q.setActiveTemplateVersionStmt != nil {
+		if cerr := q.setActiveTemplateVersionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setActiveTemplateVersionStmt: %w", cerr)
+		}
+	}
+	if q.setDomainDKIMHeadersStmt != nil {
+		if cerr := q.setDomainDKIMHeadersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setDomainDKIMHeadersStmt: %w", cerr)
+		}
+	}
+	if q.setDomainDKIMSigningStmt != nil {
+		if cerr := q.setDomainDKIMSigningStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setDomainDKIMSigningStmt: %w", cerr)
+		}
+	}
+	if q.setDomainIPPoolStmt != nil {
+		if cerr := q.setDomainIPPoolStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setDomainIPPoolStmt: %w", cerr)
+		}
+	}
+	if q.setDomainQuotaStmt != nil {
+		if cerr := q.setDomainQuotaStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setDomainQuotaStmt: %w", cerr)
+		}
+	}
+	if q.setDomainRateLimitStmt != nil {
+		if cerr := q.setDomainRateLimitStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setDomainRateLimitStmt: %w", cerr)
+		}
+	}
+	if q.setDomainRecordStmt != nil {
+		if cerr := q.setDomainRecordStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setDomainRecordStmt: %w", cerr)
+		}
+	}
+	if q.setDomainRetentionStmt != nil {
+		if cerr := q.setDomainRetentionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setDomainRetentionStmt: %w", cerr)
+		}
+	}
+	if q.setDomainReturnPathDomainStmt != nil {
+		if cerr := q.setDomainReturnPathDomainStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setDomainReturnPathDomainStmt: %w", cerr)
+		}
+	}
+	if q.setDomainSandboxStmt != nil {
+		if cerr := q.setDomainSandboxStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setDomainSandboxStmt: %w", cerr)
+		}
+	}
+	if q.setDomainSendingSettingsStmt != nil {
+		if cerr := q.setDomainSendingSettingsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setDomainSendingSettingsStmt: %w", cerr)
+		}
+	}
+	if q.setDomainVerifiedStmt != nil {
+		if cerr := q.setDomainVerifiedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setDomainVerifiedStmt: %w", cerr)
+		}
+	}
+	if q.setEventExportStmt != nil {
+		if cerr := q.setEventExportStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setEventExportStmt: %w", cerr)
+		}
+	}
+	if q.setMaintenanceStmt != nil {
+		if cerr := q.setMaintenanceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setMaintenanceStmt: %w", cerr)
+		}
+	}
+	if q.setSendingPoolEmailBouncedStmt != nil {
+		if cerr := q.setSendingPoolEmailBouncedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setSendingPoolEmailBouncedStmt: %w", cerr)
+		}
+	}
+	if q.setSendingPoolEmailDeliveredStmt != nil {
+		if cerr := q.setSendingPoolEmailDeliveredStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setSendingPoolEmailDeliveredStmt: %w", cerr)
+		}
+	}
+	if q.setSendingPoolEmailPublishedStmt != nil {
+		if cerr := q.setSendingPoolEmailPublishedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setSendingPoolEmailPublishedStmt: %w", cerr)
+		}
+	}
+	if q.setSendingPoolEmailQuotaConsumedStmt != nil {
+		if cerr := q.setSendingPoolEmailQuotaConsumedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setSendingPoolEmailQuotaConsumedStmt: %w", cerr)
+		}
+	}
+	if q.setSendingPoolEmailStatusStmt != nil {
+		if cerr := q.setSendingPoolEmailStatusStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setSendingPoolEmailStatusStmt: %w", cerr)
+		}
+	}
+	if q.setWebhookDeliveryResultStmt != nil {
+		if cerr := q.setWebhookDeliveryResultStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setWebhookDeliveryResultStmt: %w", cerr)
+		}
+	}
+	if q.suppressMessageRecipientStmt != nil {
+		if cerr := q.suppressMessageRecipientStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing suppressMessageRecipientStmt: %w", cerr)
+		}
+	}
+	if q.touchAPIKeyStmt != nil {
+		if cerr := q.touchAPIKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing touchAPIKeyStmt: %w", cerr)
+		}
+	}
 	return err
 }
 
@@ -152,35 +1048,259 @@ func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, ar
 }
 
 type Queries struct {
-	db                    DBTX
-	tx                    *sql.Tx
-	createDomainStmt      *sql.Stmt
-	createMessageStmt     *sql.Stmt
-	createPoolStmt        *sql.Stmt
-	createTemplateStmt    *sql.Stmt
-	findDomainStmt        *sql.Stmt
-	findDomainWithKeyStmt *sql.Stmt
-	findTemplateStmt      *sql.Stmt
-	getAllDomainsStmt     *sql.Stmt
-	getDomainsStmt        *sql.Stmt
-	getSendingDataStmt    *sql.Stmt
-	prepareForSendStmt    *sql.Stmt
+	db                                   DBTX
+	tx                                   *sql.Tx
+	addWarmupUsageStmt                   *sql.Stmt
+	cancelDomainPoolStmt                 *sql.Stmt
+	cancelMessagePoolStmt                *sql.Stmt
+	claimDeadLetterStmt                  *sql.Stmt
+	consumeDomainQuotaStmt               *sql.Stmt
+	countEventStmt                       *sql.Stmt
+	countPendingPoolEmailsStmt           *sql.Stmt
+	countSendingPoolEmailsInFlightStmt   *sql.Stmt
+	createAPIKeyStmt                     *sql.Stmt
+	createAPIKeyCallStmt                 *sql.Stmt
+	createAttachmentStmt                 *sql.Stmt
+	createAuditLogStmt                   *sql.Stmt
+	createComplaintStmt                  *sql.Stmt
+	createDKIMKeyStmt                    *sql.Stmt
+	createDMARCRecordStmt                *sql.Stmt
+	createDMARCReportStmt                *sql.Stmt
+	createDeadLetterStmt                 *sql.Stmt
+	createDomainStmt                     *sql.Stmt
+	createDomainUsageStmt                *sql.Stmt
+	createMessageStmt                    *sql.Stmt
+	createMessageEventStmt               *sql.Stmt
+	createOpenStmt                       *sql.Stmt
+	createPoolStmt                       *sql.Stmt
+	createPoolTimelineEntriesStmt        *sql.Stmt
+	createSuppressionStmt                *sql.Stmt
+	createTemplateStmt                   *sql.Stmt
+	createTemplateVersionStmt            *sql.Stmt
+	createTimelineEntryStmt              *sql.Stmt
+	createWebhookStmt                    *sql.Stmt
+	createWebhookDeliveriesStmt          *sql.Stmt
+	deferSendingPoolEmailStmt            *sql.Stmt
+	deleteDomainStmt                     *sql.Stmt
+	deleteSuppressionStmt                *sql.Stmt
+	deleteTemplateStmt                   *sql.Stmt
+	deleteWebhookStmt                    *sql.Stmt
+	expireAPIKeyStmt                     *sql.Stmt
+	exportMessageEventsStmt              *sql.Stmt
+	findAPIKeyStmt                       *sql.Stmt
+	findDeletedDomainStmt                *sql.Stmt
+	findDomainStmt                       *sql.Stmt
+	findMessageWithIdempotencyKeyStmt    *sql.Stmt
+	findPendingDKIMKeyStmt               *sql.Stmt
+	findSendingPoolEmailStmt             *sql.Stmt
+	findTemplateStmt                     *sql.Stmt
+	findTemplateByNameStmt               *sql.Stmt
+	findTemplateVersionStmt              *sql.Stmt
+	fireAlertStmt                        *sql.Stmt
+	getAPIKeyStmt                        *sql.Stmt
+	getAPIKeyCallsStmt                   *sql.Stmt
+	getAPIKeysStmt                       *sql.Stmt
+	getAllDomainsStmt                    *sql.Stmt
+	getAuditLogsStmt                     *sql.Stmt
+	getDKIMKeysStmt                      *sql.Stmt
+	getDMARCStatsStmt                    *sql.Stmt
+	getDeadLettersStmt                   *sql.Stmt
+	getDomainRecordsStmt                 *sql.Stmt
+	getDomainUsageStmt                   *sql.Stmt
+	getDomainsStmt                       *sql.Stmt
+	getDomainsStatsStmt                  *sql.Stmt
+	getDomainsToPurgeStmt                *sql.Stmt
+	getEventDaysToExportStmt             *sql.Stmt
+	getEventExportStmt                   *sql.Stmt
+	getFiringAlertsStmt                  *sql.Stmt
+	getLastMessageEventIDStmt            *sql.Stmt
+	getMaintenanceStmt                   *sql.Stmt
+	getMessageStmt                       *sql.Stmt
+	getMessageAttachmentsStmt            *sql.Stmt
+	getMessageEventsStmt                 *sql.Stmt
+	getMessageRecipientsStmt             *sql.Stmt
+	getMessageStatsStmt                  *sql.Stmt
+	getMessageTimelineStmt               *sql.Stmt
+	getOldestPendingPoolEmailStmt        *sql.Stmt
+	getSMTPResponsesStmt                 *sql.Stmt
+	getSendingDataStmt                   *sql.Stmt
+	getStatsStmt                         *sql.Stmt
+	getSuppressionsStmt                  *sql.Stmt
+	getTemplatesStmt                     *sql.Stmt
+	getWebhookDeliveriesStmt             *sql.Stmt
+	getWebhooksStmt                      *sql.Stmt
+	incrementSMTPResponsesStmt           *sql.Stmt
+	incrementStatsStmt                   *sql.Stmt
+	isRecipientSuppressedStmt            *sql.Stmt
+	pauseDomainStmt                      *sql.Stmt
+	prepareForSendStmt                   *sql.Stmt
+	prepareWebhookDeliveriesStmt         *sql.Stmt
+	promoteDKIMKeyStmt                   *sql.Stmt
+	purgeAPIKeyCallsStmt                 *sql.Stmt
+	purgeDomainStmt                      *sql.Stmt
+	purgeMessagesStmt                    *sql.Stmt
+	purgeWebhookDeliveriesStmt           *sql.Stmt
+	reclaimSendingPoolEmailsStmt         *sql.Stmt
+	releaseDeadLetterStmt                *sql.Stmt
+	releaseSendingPoolEmailStmt          *sql.Stmt
+	requeueSendingPoolEmailStmt          *sql.Stmt
+	resolveAlertStmt                     *sql.Stmt
+	restoreDomainStmt                    *sql.Stmt
+	resumeDomainStmt                     *sql.Stmt
+	retireDKIMKeysStmt                   *sql.Stmt
+	revokeAPIKeyStmt                     *sql.Stmt
+	revokeAPIKeysByNameStmt              *sql.Stmt
+	searchMessagesStmt                   *sql.Stmt
+	setActiveTemplateVersionStmt         *sql.Stmt
+	setDomainDKIMHeadersStmt             *sql.Stmt
+	setDomainDKIMSigningStmt             *sql.Stmt
+	setDomainIPPoolStmt                  *sql.Stmt
+	setDomainQuotaStmt                   *sql.Stmt
+	setDomainRateLimitStmt               *sql.Stmt
+	setDomainRecordStmt                  *sql.Stmt
+	setDomainRetentionStmt               *sql.Stmt
+	setDomainReturnPathDomainStmt        *sql.Stmt
+	setDomainSandboxStmt                 *sql.Stmt
+	setDomainSendingSettingsStmt         *sql.Stmt
+	setDomainVerifiedStmt                *sql.Stmt
+	setEventExportStmt                   *sql.Stmt
+	setMaintenanceStmt                   *sql.Stmt
+	setSendingPoolEmailBouncedStmt       *sql.Stmt
+	setSendingPoolEmailDeliveredStmt     *sql.Stmt
+	setSendingPoolEmailPublishedStmt     *sql.Stmt
+	setSendingPoolEmailQuotaConsumedStmt *sql.Stmt
+	setSendingPoolEmailStatusStmt        *sql.Stmt
+	setWebhookDeliveryResultStmt         *sql.Stmt
+	suppressMessageRecipientStmt         *sql.Stmt
+	touchAPIKeyStmt                      *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
-		db:                    tx,
-		tx:                    tx,
-		createDomainStmt:      q.createDomainStmt,
-		createMessageStmt:     q.createMessageStmt,
-		createPoolStmt:        q.createPoolStmt,
-		createTemplateStmt:    q.createTemplateStmt,
-		findDomainStmt:        q.findDomainStmt,
-		findDomainWithKeyStmt: q.findDomainWithKeyStmt,
-		findTemplateStmt:      q.findTemplateStmt,
-		getAllDomainsStmt:     q.getAllDomainsStmt,
-		getDomainsStmt:        q.getDomainsStmt,
-		getSendingDataStmt:    q.getSendingDataStmt,
-		prepareForSendStmt:    q.prepareForSendStmt,
+		db:                                   tx,
+		tx:                                   tx,
+		addWarmupUsageStmt:                   q.addWarmupUsageStmt,
+		cancelDomainPoolStmt:                 q.cancelDomainPoolStmt,
+		cancelMessagePoolStmt:                q.cancelMessagePoolStmt,
+		claimDeadLetterStmt:                  q.claimDeadLetterStmt,
+		consumeDomainQuotaStmt:               q.consumeDomainQuotaStmt,
+		countEventStmt:                       q.countEventStmt,
+		countPendingPoolEmailsStmt:           q.countPendingPoolEmailsStmt,
+		countSendingPoolEmailsInFlightStmt:   q.countSendingPoolEmailsInFlightStmt,
+		createAPIKeyStmt:                     q.createAPIKeyStmt,
+		createAPIKeyCallStmt:                 q.createAPIKeyCallStmt,
+		createAttachmentStmt:                 q.createAttachmentStmt,
+		createAuditLogStmt:                   q.createAuditLogStmt,
+		createComplaintStmt:                  q.createComplaintStmt,
+		createDKIMKeyStmt:                    q.createDKIMKeyStmt,
+		createDMARCRecordStmt:                q.createDMARCRecordStmt,
+		createDMARCReportStmt:                q.createDMARCReportStmt,
+		createDeadLetterStmt:                 q.createDeadLetterStmt,
+		createDomainStmt:                     q.createDomainStmt,
+		createDomainUsageStmt:                q.createDomainUsageStmt,
+		createMessageStmt:                    q.createMessageStmt,
+		createMessageEventStmt:               q.createMessageEventStmt,
+		createOpenStmt:                       q.createOpenStmt,
+		createPoolStmt:                       q.createPoolStmt,
+		createPoolTimelineEntriesStmt:        q.createPoolTimelineEntriesStmt,
+		createSuppressionStmt:                q.createSuppressionStmt,
+		createTemplateStmt:                   q.createTemplateStmt,
+		createTemplateVersionStmt:            q.createTemplateVersionStmt,
+		createTimelineEntryStmt:              q.createTimelineEntryStmt,
+		createWebhookStmt:                    q.createWebhookStmt,
+		createWebhookDeliveriesStmt:          q.createWebhookDeliveriesStmt,
+		deferSendingPoolEmailStmt:            q.deferSendingPoolEmailStmt,
+		deleteDomainStmt:                     q.deleteDomainStmt,
+		deleteSuppressionStmt:                q.deleteSuppressionStmt,
+		deleteTemplateStmt:                   q.deleteTemplateStmt,
+		deleteWebhookStmt:                    q.deleteWebhookStmt,
+		expireAPIKeyStmt:                     q.expireAPIKeyStmt,
+		exportMessageEventsStmt:              q.exportMessageEventsStmt,
+		findAPIKeyStmt:                       q.findAPIKeyStmt,
+		findDeletedDomainStmt:                q.findDeletedDomainStmt,
+		findDomainStmt:                       q.findDomainStmt,
+		findMessageWithIdempotencyKeyStmt:    q.findMessageWithIdempotencyKeyStmt,
+		findPendingDKIMKeyStmt:               q.findPendingDKIMKeyStmt,
+		findSendingPoolEmailStmt:             q.findSendingPoolEmailStmt,
+		findTemplateStmt:                     q.findTemplateStmt,
+		findTemplateByNameStmt:               q.findTemplateByNameStmt,
+		findTemplateVersionStmt:              q.findTemplateVersionStmt,
+		fireAlertStmt:                        q.fireAlertStmt,
+		getAPIKeyStmt:                        q.getAPIKeyStmt,
+		getAPIKeyCallsStmt:                   q.getAPIKeyCallsStmt,
+		getAPIKeysStmt:                       q.getAPIKeysStmt,
+		getAllDomainsStmt:                    q.getAllDomainsStmt,
+		getAuditLogsStmt:                     q.getAuditLogsStmt,
+		getDKIMKeysStmt:                      q.getDKIMKeysStmt,
+		getDMARCStatsStmt:                    q.getDMARCStatsStmt,
+		getDeadLettersStmt:                   q.getDeadLettersStmt,
+		getDomainRecordsStmt:                 q.getDomainRecordsStmt,
+		getDomainUsageStmt:                   q.getDomainUsageStmt,
+		getDomainsStmt:                       q.getDomainsStmt,
+		getDomainsStatsStmt:                  q.getDomainsStatsStmt,
+		getDomainsToPurgeStmt:                q.getDomainsToPurgeStmt,
+		getEventDaysToExportStmt:             q.getEventDaysToExportStmt,
+		getEventExportStmt:                   q.getEventExportStmt,
+		getFiringAlertsStmt:                  q.getFiringAlertsStmt,
+		getLastMessageEventIDStmt:            q.getLastMessageEventIDStmt,
+		getMaintenanceStmt:                   q.getMaintenanceStmt,
+		getMessageStmt:                       q.getMessageStmt,
+		getMessageAttachmentsStmt:            q.getMessageAttachmentsStmt,
+		getMessageEventsStmt:                 q.getMessageEventsStmt,
+		getMessageRecipientsStmt:             q.getMessageRecipientsStmt,
+		getMessageStatsStmt:                  q.getMessageStatsStmt,
+		getMessageTimelineStmt:               q.getMessageTimelineStmt,
+		getOldestPendingPoolEmailStmt:        q.getOldestPendingPoolEmailStmt,
+		getSMTPResponsesStmt:                 q.getSMTPResponsesStmt,
+		getSendingDataStmt:                   q.getSendingDataStmt,
+		getStatsStmt:                         q.getStatsStmt,
+		getSuppressionsStmt:                  q.getSuppressionsStmt,
+		getTemplatesStmt:                     q.getTemplatesStmt,
+		getWebhookDeliveriesStmt:             q.getWebhookDeliveriesStmt,
+		getWebhooksStmt:                      q.getWebhooksStmt,
+		incrementSMTPResponsesStmt:           q.incrementSMTPResponsesStmt,
+		incrementStatsStmt:                   q.incrementStatsStmt,
+		isRecipientSuppressedStmt:            q.isRecipientSuppressedStmt,
+		pauseDomainStmt:                      q.pauseDomainStmt,
+		prepareForSendStmt:                   q.prepareForSendStmt,
+		prepareWebhookDeliveriesStmt:         q.prepareWebhookDeliveriesStmt,
+		promoteDKIMKeyStmt:                   q.promoteDKIMKeyStmt,
+		purgeAPIKeyCallsStmt:                 q.purgeAPIKeyCallsStmt,
+		purgeDomainStmt:                      q.purgeDomainStmt,
+		purgeMessagesStmt:                    q.purgeMessagesStmt,
+		purgeWebhookDeliveriesStmt:           q.purgeWebhookDeliveriesStmt,
+		reclaimSendingPoolEmailsStmt:         q.reclaimSendingPoolEmailsStmt,
+		releaseDeadLetterStmt:                q.releaseDeadLetterStmt,
+		releaseSendingPoolEmailStmt:          q.releaseSendingPoolEmailStmt,
+		requeueSendingPoolEmailStmt:          q.requeueSendingPoolEmailStmt,
+		resolveAlertStmt:                     q.resolveAlertStmt,
+		restoreDomainStmt:                    q.restoreDomainStmt,
+		resumeDomainStmt:                     q.resumeDomainStmt,
+		retireDKIMKeysStmt:                   q.retireDKIMKeysStmt,
+		revokeAPIKeyStmt:                     q.revokeAPIKeyStmt,
+		revokeAPIKeysByNameStmt:              q.revokeAPIKeysByNameStmt,
+		searchMessagesStmt:                   q.searchMessagesStmt,
+		setActiveTemplateVersionStmt:         q.setActiveTemplateVersionStmt,
+		setDomainDKIMHeadersStmt:             q.setDomainDKIMHeadersStmt,
+		setDomainDKIMSigningStmt:             q.setDomainDKIMSigningStmt,
+		setDomainIPPoolStmt:                  q.setDomainIPPoolStmt,
+		setDomainQuotaStmt:                   q.setDomainQuotaStmt,
+		setDomainRateLimitStmt:               q.setDomainRateLimitStmt,
+		setDomainRecordStmt:                  q.setDomainRecordStmt,
+		setDomainRetentionStmt:               q.setDomainRetentionStmt,
+		setDomainReturnPathDomainStmt:        q.setDomainReturnPathDomainStmt,
+		setDomainSandboxStmt:                 q.setDomainSandboxStmt,
+		setDomainSendingSettingsStmt:         q.setDomainSendingSettingsStmt,
+		setDomainVerifiedStmt:                q.setDomainVerifiedStmt,
+		setEventExportStmt:                   q.setEventExportStmt,
+		setMaintenanceStmt:                   q.setMaintenanceStmt,
+		setSendingPoolEmailBouncedStmt:       q.setSendingPoolEmailBouncedStmt,
+		setSendingPoolEmailDeliveredStmt:     q.setSendingPoolEmailDeliveredStmt,
+		setSendingPoolEmailPublishedStmt:     q.setSendingPoolEmailPublishedStmt,
+		setSendingPoolEmailQuotaConsumedStmt: q.setSendingPoolEmailQuotaConsumedStmt,
+		setSendingPoolEmailStatusStmt:        q.setSendingPoolEmailStatusStmt,
+		setWebhookDeliveryResultStmt:         q.setWebhookDeliveryResultStmt,
+		suppressMessageRecipientStmt:         q.suppressMessageRecipientStmt,
+		touchAPIKeyStmt:                      q.touchAPIKeyStmt,
 	}
 }
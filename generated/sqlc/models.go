@@ -3,18 +3,82 @@
 package sqlc
 
 import (
+	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 )
 
+type BounceType string
+
+const (
+	BounceTypeNone BounceType = "none"
+	BounceTypeSoft BounceType = "soft"
+	BounceTypeHard BounceType = "hard"
+)
+
+func (e *BounceType) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = BounceType(s)
+	case string:
+		*e = BounceType(s)
+	default:
+		return fmt.Errorf("unsupported scan type for BounceType: %T", src)
+	}
+	return nil
+}
+
+type DkimKeyStatus string
+
+const (
+	DkimKeyStatusPending  DkimKeyStatus = "pending"
+	DkimKeyStatusRetiring DkimKeyStatus = "retiring"
+	DkimKeyStatusRetired  DkimKeyStatus = "retired"
+)
+
+func (e *DkimKeyStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = DkimKeyStatus(s)
+	case string:
+		*e = DkimKeyStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for DkimKeyStatus: %T", src)
+	}
+	return nil
+}
+
+type DomainRecordStatus string
+
+const (
+	DomainRecordStatusVerified DomainRecordStatus = "verified"
+	DomainRecordStatusFailed   DomainRecordStatus = "failed"
+)
+
+func (e *DomainRecordStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = DomainRecordStatus(s)
+	case string:
+		*e = DomainRecordStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for DomainRecordStatus: %T", src)
+	}
+	return nil
+}
+
 type SendingPoolStatus string
 
 const (
-	SendingPoolStatusInitializing SendingPoolStatus = "initializing"
-	SendingPoolStatusSending      SendingPoolStatus = "sending"
-	SendingPoolStatusSent         SendingPoolStatus = "sent"
-	SendingPoolStatusScheduled    SendingPoolStatus = "scheduled"
-	SendingPoolStatusError        SendingPoolStatus = "error"
+	SendingPoolStatusInitializing  SendingPoolStatus = "initializing"
+	SendingPoolStatusSending       SendingPoolStatus = "sending"
+	SendingPoolStatusSent          SendingPoolStatus = "sent"
+	SendingPoolStatusScheduled     SendingPoolStatus = "scheduled"
+	SendingPoolStatusError         SendingPoolStatus = "error"
+	SendingPoolStatusSuppressed    SendingPoolStatus = "suppressed"
+	SendingPoolStatusQuotaExceeded SendingPoolStatus = "quota_exceeded"
+	SendingPoolStatusCanceled      SendingPoolStatus = "canceled"
 )
 
 func (e *SendingPoolStatus) Scan(src interface{}) error {
@@ -29,23 +93,267 @@ func (e *SendingPoolStatus) Scan(src interface{}) error {
 	return nil
 }
 
+type SuppressionReason string
+
+const (
+	SuppressionReasonUnsubscribed SuppressionReason = "unsubscribed"
+	SuppressionReasonBounced      SuppressionReason = "bounced"
+	SuppressionReasonComplained   SuppressionReason = "complained"
+	SuppressionReasonManual       SuppressionReason = "manual"
+)
+
+func (e *SuppressionReason) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = SuppressionReason(s)
+	case string:
+		*e = SuppressionReason(s)
+	default:
+		return fmt.Errorf("unsupported scan type for SuppressionReason: %T", src)
+	}
+	return nil
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusDelivered WebhookDeliveryStatus = "delivered"
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatus = "failed"
+)
+
+func (e *WebhookDeliveryStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = WebhookDeliveryStatus(s)
+	case string:
+		*e = WebhookDeliveryStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for WebhookDeliveryStatus: %T", src)
+	}
+	return nil
+}
+
+type ApiKey struct {
+	ID         int32
+	Domain     string
+	Name       string
+	Prefix     string
+	KeyHash    string
+	Scopes     []string
+	CreatedAt  time.Time
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+	ExpiresAt  sql.NullTime
+}
+
+type ApiKeyCall struct {
+	ID       int32
+	ApiKeyID int32
+	Domain   string
+	Method   string
+	Allowed  bool
+	CalledAt time.Time
+}
+
+type Attachment struct {
+	ID        int32
+	MessageID int32
+	Filename  string
+	Content   []byte
+	Inline    bool
+}
+
+type AuditLog struct {
+	ID          int32
+	Actor       string
+	Method      string
+	Domain      string
+	Request     json.RawMessage
+	BeforeValue json.RawMessage
+	AfterValue  json.RawMessage
+	CreatedAt   time.Time
+}
+
+type Complaint struct {
+	ID           int32
+	MessageID    string
+	Email        string
+	FeedbackType string
+	Timestamp    time.Time
+}
+
+type CountedEvent struct {
+	ID        string
+	MessageID string
+	CountedAt time.Time
+}
+
+type DeadLetter struct {
+	ID         int32
+	Domain     string
+	Subject    string
+	MessageID  string
+	Email      string
+	Payload    []byte
+	Reason     string
+	CreatedAt  time.Time
+	RequeuedAt sql.NullTime
+}
+
+type DkimKey struct {
+	ID         int32
+	Domain     string
+	Selector   string
+	Algorithm  string
+	PrivateKey string
+	PublicKey  string
+	Status     DkimKeyStatus
+	RetireAt   sql.NullTime
+	CreatedAt  time.Time
+}
+
+type DmarcRecord struct {
+	ID          int32
+	ReportID    int32
+	HeaderFrom  string
+	SourceIp    string
+	Count       int64
+	Disposition string
+	DkimAligned bool
+	SpfAligned  bool
+	BeginAt     time.Time
+}
+
+type DmarcReport struct {
+	ID        int32
+	OrgName   string
+	ReportID  string
+	Domain    string
+	BeginAt   time.Time
+	EndAt     time.Time
+	CreatedAt time.Time
+}
+
 type Domain struct {
-	ID             int32
+	ID                    int32
+	Domain                string
+	CreatedAt             time.Time
+	DkimPrivateKey        string
+	DkimPublicKey         string
+	RetentionDays         int32
+	RatePerSecond         int32
+	RatePerHour           int32
+	IpPool                string
+	DkimEd25519PrivateKey string
+	DkimEd25519PublicKey  string
+	DkimSigning           string
+	DkimSelector          string
+	DkimEd25519Selector   string
+	DkimHeaders           []string
+	Verified              bool
+	ReturnPathDomain      string
+	DailyQuota            int32
+	MonthlyQuota          int32
+	DeletedAt             sql.NullTime
+	PurgeAt               sql.NullTime
+	DefaultFromEmail      string
+	DefaultFromName       string
+	DefaultHeaders        json.RawMessage
+	OpenTracking          bool
+	ClickTracking         bool
+	FooterHtml            string
+	FooterText            string
+	Sandbox               bool
+	SandboxBouncePercent  int32
+	PausedAt              sql.NullTime
+	PausedReason          string
+}
+
+type DomainRecord struct {
+	ID        int32
+	Domain    string
+	Record    string
+	Status    DomainRecordStatus
+	Error     string
+	CheckedAt time.Time
+}
+
+type DomainUsage struct {
+	ID     int32
+	Domain string
+	Day    time.Time
+	Sent   int32
+}
+
+type EventExport struct {
 	Domain         string
-	CreatedAt      time.Time
-	Key            string
-	DkimPrivateKey string
-	DkimPublicKey  string
+	ExportedBefore time.Time
+	LastEventID    int32
+}
+
+type FiringAlert struct {
+	Rule      string
+	Domain    string
+	Value     float64
+	Threshold float64
+	Message   string
+	FiredAt   time.Time
+}
+
+type Maintenance struct {
+	ID        bool
+	Halted    bool
+	Reason    string
+	ChangedAt time.Time
 }
 
 type Message struct {
-	ID          int32
-	MessageID   string
-	Subject     string
-	SenderEmail string
-	SenderAlias string
-	TemplateID  string
-	Domain      string
+	ID              int32
+	MessageID       string
+	Subject         string
+	SenderEmail     string
+	SenderAlias     string
+	TemplateID      string
+	Domain          string
+	Cc              []string
+	Bcc             []string
+	Headers         json.RawMessage
+	ReplyTo         string
+	Fields          json.RawMessage
+	TemplateVersion int32
+	IdempotencyKey  sql.NullString
+	CreatedAt       time.Time
+	ResentFrom      string
+}
+
+type MessageEvent struct {
+	ID        int32
+	MessageID string
+	Email     string
+	Type      string
+	Data      json.RawMessage
+	Timestamp time.Time
+}
+
+type MessageTimeline struct {
+	ID           int32
+	MessageID    string
+	Email        string
+	Stage        string
+	SmtpCode     int32
+	SmtpResponse string
+	Data         json.RawMessage
+	Timestamp    time.Time
+}
+
+type Open struct {
+	ID        int32
+	MessageID string
+	Email     string
+	Ip        string
+	UserAgent string
+	Timestamp time.Time
 }
 
 type SchemaMigration struct {
@@ -62,6 +370,41 @@ type SendingPoolEmail struct {
 	MessageID             int32
 	ErrorMsg              string
 	ErrorCode             int32
+	Fields                json.RawMessage
+	BounceType            BounceType
+	Priority              int16
+	DispatchedAt          sql.NullTime
+	TraceParent           string
+	Copies                bool
+	ClaimedAt             sql.NullTime
+	QuotaConsumed         bool
+}
+
+type SmtpResponse struct {
+	ID           int32
+	Domain       string
+	Provider     string
+	Code         int32
+	EnhancedCode string
+	Hour         time.Time
+	Count        int32
+}
+
+type Stat struct {
+	ID        int32
+	Domain    string
+	MessageID string
+	Type      string
+	Hour      time.Time
+	Count     int32
+}
+
+type Suppression struct {
+	ID        int32
+	Domain    string
+	Email     string
+	Reason    SuppressionReason
+	CreatedAt time.Time
 }
 
 type Template struct {
@@ -69,4 +412,38 @@ type Template struct {
 	TemplateID string
 	Html       string
 	Domain     string
+	Text       string
+	Version    int32
+	Active     bool
+	Name       string
+	DeletedAt  sql.NullTime
+}
+
+type WarmupUsage struct {
+	Ip       string
+	Provider string
+	Day      time.Time
+	Count    int32
+}
+
+type Webhook struct {
+	ID        int32
+	Domain    string
+	Url       string
+	Secret    string
+	Events    []string
+	CreatedAt time.Time
+}
+
+type WebhookDelivery struct {
+	ID              int32
+	WebhookID       int32
+	Event           string
+	Payload         json.RawMessage
+	Status          WebhookDeliveryStatus
+	Attempts        int32
+	NextAttemptTime time.Time
+	ResponseCode    int32
+	ErrorMsg        string
+	CreatedAt       time.Time
 }
@@ -5,123 +5,104 @@ package sqlc
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/lib/pq"
 )
 
-const createDomain = `-- name: CreateDomain :one
-INSERT INTO domains 
-    (domain, key, dkim_private_key, dkim_public_key)
-    VALUES ($1, $2, $3, $4) 
-    RETURNING id, domain, created_at, key, dkim_private_key, dkim_public_key
+const addWarmupUsage = `-- name: AddWarmupUsage :execrows
+INSERT INTO warmup_usage AS w (ip, provider, day, count)
+    SELECT $1::varchar, $2::varchar, $3::date, $4::integer
+    WHERE $4::integer <= $5::integer
+    ON CONFLICT (ip, provider, day) DO UPDATE
+        SET count = w.count + EXCLUDED.count
+        WHERE w.count + EXCLUDED.count <= $5::integer
 `
 
-type CreateDomainParams struct {
-	Domain         string
-	Key            string
-	DkimPrivateKey string
-	DkimPublicKey  string
+type AddWarmupUsageParams struct {
+	Ip       string
+	Provider string
+	Day      time.Time
+	Count    int32
+	Max      int32
 }
 
-func (q *Queries) CreateDomain(ctx context.Context, arg CreateDomainParams) (Domain, error) {
-	row := q.queryRow(ctx, q.createDomainStmt, createDomain,
-		arg.Domain,
-		arg.Key,
-		arg.DkimPrivateKey,
-		arg.DkimPublicKey,
-	)
-	var i Domain
-	err := row.Scan(
-		&i.ID,
-		&i.Domain,
-		&i.CreatedAt,
-		&i.Key,
-		&i.DkimPrivateKey,
-		&i.DkimPublicKey,
+// deliveries over the max of the day are not counted
+func (q *Queries) AddWarmupUsage(ctx context.Context, arg AddWarmupUsageParams) (int64, error) {
+	result, err := q.exec(ctx, q.addWarmupUsageStmt, addWarmupUsage,
+		arg.Ip,
+		arg.Provider,
+		arg.Day,
+		arg.Count,
+		arg.Max,
 	)
-	return i, err
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
-const createMessage = `-- name: CreateMessage :one
-INSERT INTO messages
-    (message_id, subject, sender_email, sender_alias, template_id, domain) VALUES
-    ($1, $2, $3, $4, $5, $6) RETURNING id, message_id, subject, sender_email, sender_alias, template_id, domain
+const cancelDomainPool = `-- name: CancelDomainPool :execrows
+UPDATE sending_pool_emails AS sp
+    SET status = 'canceled'
+    FROM messages AS m
+    WHERE m.id = sp.message_id
+    AND m.domain = $1
+    AND (sp.status IN ('initializing', 'scheduled')
+        OR ($2::boolean AND sp.status = 'sending'))
 `
 
-type CreateMessageParams struct {
-	MessageID   string
-	Subject     string
-	SenderEmail string
-	SenderAlias string
-	TemplateID  string
-	Domain      string
+type CancelDomainPoolParams struct {
+	Domain         string
+	IncludeSending bool
 }
 
-func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (Message, error) {
-	row := q.queryRow(ctx, q.createMessageStmt, createMessage,
-		arg.MessageID,
-		arg.Subject,
-		arg.SenderEmail,
-		arg.SenderAlias,
-		arg.TemplateID,
-		arg.Domain,
-	)
-	var i Message
-	err := row.Scan(
-		&i.ID,
-		&i.MessageID,
-		&i.Subject,
-		&i.SenderEmail,
-		&i.SenderAlias,
-		&i.TemplateID,
-		&i.Domain,
-	)
-	return i, err
+// emails being sent are canceled only with include_sending
+func (q *Queries) CancelDomainPool(ctx context.Context, arg CancelDomainPoolParams) (int64, error) {
+	result, err := q.exec(ctx, q.cancelDomainPoolStmt, cancelDomainPool, arg.Domain, arg.IncludeSending)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
-const createPool = `-- name: CreatePool :many
-INSERT INTO sending_pool_emails
-    (email, status, scheduled_time, original_scheduled_time, message_id)
-(
-    SELECT
-        email,
-        'scheduled',
-        $1,
-        $1,
-        $2
-    FROM
-        UNNEST($3::varchar[]) as email
-)
-RETURNING id, status, scheduled_time, original_scheduled_time, trial, email, message_id, error_msg, error_code
+const cancelMessagePool = `-- name: CancelMessagePool :many
+UPDATE sending_pool_emails AS sp
+    SET status = 'canceled'
+    FROM messages AS m
+    WHERE m.id = sp.message_id
+    AND m.domain = $1
+    AND m.message_id = $2
+    AND (CARDINALITY($3::varchar[]) = 0 OR sp.email = ANY($3::varchar[]))
+    AND sp.status IN ('initializing', 'scheduled')
+    RETURNING sp.id, sp.email
 `
 
-type CreatePoolParams struct {
-	ScheduledTime time.Time
-	MessageID     int32
-	Emails        []string
+type CancelMessagePoolParams struct {
+	Domain    string
+	MessageID string
+	Emails    []string
 }
 
-func (q *Queries) CreatePool(ctx context.Context, arg CreatePoolParams) ([]SendingPoolEmail, error) {
-	rows, err := q.query(ctx, q.createPoolStmt, createPool, arg.ScheduledTime, arg.MessageID, pq.Array(arg.Emails))
+type CancelMessagePoolRow struct {
+	ID    int32
+	Email string
+}
+
+// emails claimed by a dispatcher are locked until they are sending,
+// then they are not canceled anymore. No emails cancels every recipient
+func (q *Queries) CancelMessagePool(ctx context.Context, arg CancelMessagePoolParams) ([]CancelMessagePoolRow, error) {
+	rows, err := q.query(ctx, q.cancelMessagePoolStmt, cancelMessagePool, arg.Domain, arg.MessageID, pq.Array(arg.Emails))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []SendingPoolEmail
+	var items []CancelMessagePoolRow
 	for rows.Next() {
-		var i SendingPoolEmail
-		if err := rows.Scan(
-			&i.ID,
-			&i.Status,
-			&i.ScheduledTime,
-			&i.OriginalScheduledTime,
-			&i.Trial,
-			&i.Email,
-			&i.MessageID,
-			&i.ErrorMsg,
-			&i.ErrorCode,
-		); err != nil {
+		var i CancelMessagePoolRow
+		if err := rows.Scan(&i.ID, &i.Email); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -135,160 +116,369 @@ func (q *Queries) CreatePool(ctx context.Context, arg CreatePoolParams) ([]Sendi
 	return items, nil
 }
 
-const createTemplate = `-- name: CreateTemplate :one
-INSERT INTO templates
-    (template_id, html, domain)
-    VALUES ($1, $2, $3)
-    RETURNING id, template_id, html, domain
+const claimDeadLetter = `-- name: ClaimDeadLetter :one
+UPDATE dead_letters
+    SET requeued_at = NOW()
+    WHERE id = $1 AND requeued_at IS NULL
+    RETURNING id, domain, subject, message_id, email, payload, reason, created_at, requeued_at
 `
 
-type CreateTemplateParams struct {
-	TemplateID string
-	Html       string
-	Domain     string
-}
-
-func (q *Queries) CreateTemplate(ctx context.Context, arg CreateTemplateParams) (Template, error) {
-	row := q.queryRow(ctx, q.createTemplateStmt, createTemplate, arg.TemplateID, arg.Html, arg.Domain)
-	var i Template
+// no rows when the letter is already requeued, a letter is claimed once
+func (q *Queries) ClaimDeadLetter(ctx context.Context, id int32) (DeadLetter, error) {
+	row := q.queryRow(ctx, q.claimDeadLetterStmt, claimDeadLetter, id)
+	var i DeadLetter
 	err := row.Scan(
 		&i.ID,
-		&i.TemplateID,
-		&i.Html,
 		&i.Domain,
+		&i.Subject,
+		&i.MessageID,
+		&i.Email,
+		&i.Payload,
+		&i.Reason,
+		&i.CreatedAt,
+		&i.RequeuedAt,
 	)
 	return i, err
 }
 
-const findDomain = `-- name: FindDomain :one
-SELECT
-    id, domain, created_at, key, dkim_private_key, dkim_public_key
-FROM domains
-    WHERE domain = $1
+const consumeDomainQuota = `-- name: ConsumeDomainQuota :execrows
+WITH email AS (
+    SELECT m.domain, 1 + CASE WHEN sp.copies THEN CARDINALITY(m.cc) + CARDINALITY(m.bcc) ELSE 0 END AS n
+    FROM sending_pool_emails AS sp
+        JOIN messages AS m ON m.id = sp.message_id
+        WHERE sp.id = $1
+)
+UPDATE domain_usage AS u
+    SET sent = u.sent + e.n
+    FROM email AS e, domains AS d
+    WHERE d.domain = e.domain AND u.domain = e.domain AND u.day = CURRENT_DATE
+    AND (d.daily_quota = 0 OR u.sent + e.n <= d.daily_quota)
+    AND (d.monthly_quota = 0 OR u.sent + e.n + (
+        SELECT COALESCE(SUM(p.sent), 0) FROM domain_usage AS p
+        WHERE p.domain = e.domain AND p.day >= date_trunc('month', CURRENT_DATE) AND p.day < CURRENT_DATE
+    ) <= d.monthly_quota)
 `
 
-func (q *Queries) FindDomain(ctx context.Context, domain string) (Domain, error) {
-	row := q.queryRow(ctx, q.findDomainStmt, findDomain, domain)
-	var i Domain
+// counts a pool email in the usage of today of its domain, with its copies for the email
+// carrying the cc and bcc. No rows are affected when the daily or monthly quota is exceeded.
+// The check and the increment are a single update of the row of today, concurrent
+// consumes of a domain wait for each other and check the updated count
+func (q *Queries) ConsumeDomainQuota(ctx context.Context, id int32) (int64, error) {
+	result, err := q.exec(ctx, q.consumeDomainQuotaStmt, consumeDomainQuota, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const countEvent = `-- name: CountEvent :execrows
+INSERT INTO counted_events (id, message_id) VALUES ($1, $2)
+    ON CONFLICT (id) DO NOTHING
+`
+
+type CountEventParams struct {
+	ID        string
+	MessageID string
+}
+
+// 0 rows when the event was already counted
+func (q *Queries) CountEvent(ctx context.Context, arg CountEventParams) (int64, error) {
+	result, err := q.exec(ctx, q.countEventStmt, countEvent, arg.ID, arg.MessageID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const countPendingPoolEmails = `-- name: CountPendingPoolEmails :one
+SELECT COUNT(*) FROM sending_pool_emails
+    WHERE scheduled_time <= NOW() and status = 'scheduled'
+`
+
+func (q *Queries) CountPendingPoolEmails(ctx context.Context) (int64, error) {
+	row := q.queryRow(ctx, q.countPendingPoolEmailsStmt, countPendingPoolEmails)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countSendingPoolEmailsInFlight = `-- name: CountSendingPoolEmailsInFlight :one
+SELECT COUNT(*) FROM sending_pool_emails
+    WHERE status = 'sending'
+`
+
+func (q *Queries) CountSendingPoolEmailsInFlight(ctx context.Context) (int64, error) {
+	row := q.queryRow(ctx, q.countSendingPoolEmailsInFlightStmt, countSendingPoolEmailsInFlight)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createAPIKey = `-- name: CreateAPIKey :one
+INSERT INTO api_keys (domain, name, prefix, key_hash, scopes)
+    VALUES ($1, $2, $3, $4, $5)
+    RETURNING id, domain, name, prefix, key_hash, scopes, created_at, last_used_at, revoked_at, expires_at
+`
+
+type CreateAPIKeyParams struct {
+	Domain  string
+	Name    string
+	Prefix  string
+	KeyHash string
+	Scopes  []string
+}
+
+func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error) {
+	row := q.queryRow(ctx, q.createAPIKeyStmt, createAPIKey,
+		arg.Domain,
+		arg.Name,
+		arg.Prefix,
+		arg.KeyHash,
+		pq.Array(arg.Scopes),
+	)
+	var i ApiKey
 	err := row.Scan(
 		&i.ID,
 		&i.Domain,
+		&i.Name,
+		&i.Prefix,
+		&i.KeyHash,
+		pq.Array(&i.Scopes),
 		&i.CreatedAt,
-		&i.Key,
-		&i.DkimPrivateKey,
-		&i.DkimPublicKey,
+		&i.LastUsedAt,
+		&i.RevokedAt,
+		&i.ExpiresAt,
 	)
 	return i, err
 }
 
-const findDomainWithKey = `-- name: FindDomainWithKey :one
-SELECT
-    id, domain, created_at, key, dkim_private_key, dkim_public_key
-FROM domains
-    WHERE domain = $1
-    AND key = $2
+const createAPIKeyCall = `-- name: CreateAPIKeyCall :exec
+INSERT INTO api_key_calls (api_key_id, domain, method, allowed)
+    VALUES ($1, $2, $3, $4)
 `
 
-type FindDomainWithKeyParams struct {
-	Domain string
-	Key    string
+type CreateAPIKeyCallParams struct {
+	ApiKeyID int32
+	Domain   string
+	Method   string
+	Allowed  bool
 }
 
-func (q *Queries) FindDomainWithKey(ctx context.Context, arg FindDomainWithKeyParams) (Domain, error) {
-	row := q.queryRow(ctx, q.findDomainWithKeyStmt, findDomainWithKey, arg.Domain, arg.Key)
-	var i Domain
+func (q *Queries) CreateAPIKeyCall(ctx context.Context, arg CreateAPIKeyCallParams) error {
+	_, err := q.exec(ctx, q.createAPIKeyCallStmt, createAPIKeyCall,
+		arg.ApiKeyID,
+		arg.Domain,
+		arg.Method,
+		arg.Allowed,
+	)
+	return err
+}
+
+const createAttachment = `-- name: CreateAttachment :one
+INSERT INTO attachments
+    (message_id, filename, content, inline)
+    VALUES ($1, $2, $3, $4)
+    RETURNING id, message_id, filename, content, inline
+`
+
+type CreateAttachmentParams struct {
+	MessageID int32
+	Filename  string
+	Content   []byte
+	Inline    bool
+}
+
+func (q *Queries) CreateAttachment(ctx context.Context, arg CreateAttachmentParams) (Attachment, error) {
+	row := q.queryRow(ctx, q.createAttachmentStmt, createAttachment,
+		arg.MessageID,
+		arg.Filename,
+		arg.Content,
+		arg.Inline,
+	)
+	var i Attachment
+	err := row.Scan(
+		&i.ID,
+		&i.MessageID,
+		&i.Filename,
+		&i.Content,
+		&i.Inline,
+	)
+	return i, err
+}
+
+const createAuditLog = `-- name: CreateAuditLog :one
+INSERT INTO audit_logs (actor, method, domain, request, before_value, after_value)
+    VALUES ($1, $2, $3, $4, $5, $6)
+    RETURNING id, actor, method, domain, request, before_value, after_value, created_at
+`
+
+type CreateAuditLogParams struct {
+	Actor       string
+	Method      string
+	Domain      string
+	Request     json.RawMessage
+	BeforeValue json.RawMessage
+	AfterValue  json.RawMessage
+}
+
+func (q *Queries) CreateAuditLog(ctx context.Context, arg CreateAuditLogParams) (AuditLog, error) {
+	row := q.queryRow(ctx, q.createAuditLogStmt, createAuditLog,
+		arg.Actor,
+		arg.Method,
+		arg.Domain,
+		arg.Request,
+		arg.BeforeValue,
+		arg.AfterValue,
+	)
+	var i AuditLog
 	err := row.Scan(
 		&i.ID,
+		&i.Actor,
+		&i.Method,
 		&i.Domain,
+		&i.Request,
+		&i.BeforeValue,
+		&i.AfterValue,
 		&i.CreatedAt,
-		&i.Key,
-		&i.DkimPrivateKey,
-		&i.DkimPublicKey,
 	)
 	return i, err
 }
 
-const findTemplate = `-- name: FindTemplate :one
-SELECT
-    id, template_id, html, domain
-FROM templates
-    WHERE template_id = $1
-    AND domain = $2
+const createComplaint = `-- name: CreateComplaint :exec
+INSERT INTO complaints (message_id, email, feedback_type, timestamp) VALUES
+    ($1, $2, $3, $4)
 `
 
-type FindTemplateParams struct {
-	TemplateID string
+type CreateComplaintParams struct {
+	MessageID    string
+	Email        string
+	FeedbackType string
+	Timestamp    time.Time
+}
+
+func (q *Queries) CreateComplaint(ctx context.Context, arg CreateComplaintParams) error {
+	_, err := q.exec(ctx, q.createComplaintStmt, createComplaint,
+		arg.MessageID,
+		arg.Email,
+		arg.FeedbackType,
+		arg.Timestamp,
+	)
+	return err
+}
+
+const createDKIMKey = `-- name: CreateDKIMKey :one
+WITH replaced AS (
+    DELETE FROM dkim_keys
+    WHERE domain = $1
+        AND algorithm = $3
+        AND status = 'pending'
+)
+INSERT INTO dkim_keys (domain, selector, algorithm, private_key, public_key)
+    VALUES ($1, $2, $3, $4, $5)
+    RETURNING id, domain, selector, algorithm, private_key, public_key, status, retire_at, created_at
+`
+
+type CreateDKIMKeyParams struct {
 	Domain     string
+	Selector   string
+	Algorithm  string
+	PrivateKey string
+	PublicKey  string
 }
 
-func (q *Queries) FindTemplate(ctx context.Context, arg FindTemplateParams) (Template, error) {
-	row := q.queryRow(ctx, q.findTemplateStmt, findTemplate, arg.TemplateID, arg.Domain)
-	var i Template
+// the new pending key replaces the pending key of the same algorithm
+func (q *Queries) CreateDKIMKey(ctx context.Context, arg CreateDKIMKeyParams) (DkimKey, error) {
+	row := q.queryRow(ctx, q.createDKIMKeyStmt, createDKIMKey,
+		arg.Domain,
+		arg.Selector,
+		arg.Algorithm,
+		arg.PrivateKey,
+		arg.PublicKey,
+	)
+	var i DkimKey
 	err := row.Scan(
 		&i.ID,
-		&i.TemplateID,
-		&i.Html,
 		&i.Domain,
+		&i.Selector,
+		&i.Algorithm,
+		&i.PrivateKey,
+		&i.PublicKey,
+		&i.Status,
+		&i.RetireAt,
+		&i.CreatedAt,
 	)
 	return i, err
 }
 
-const getAllDomains = `-- name: GetAllDomains :many
-SELECT
-    id, domain, created_at, key, dkim_private_key, dkim_public_key
-FROM domains
+const createDMARCRecord = `-- name: CreateDMARCRecord :exec
+INSERT INTO dmarc_records (report_id, header_from, source_ip, count, disposition, dkim_aligned, spf_aligned, begin_at)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 `
 
-func (q *Queries) GetAllDomains(ctx context.Context) ([]Domain, error) {
-	rows, err := q.query(ctx, q.getAllDomainsStmt, getAllDomains)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []Domain
-	for rows.Next() {
-		var i Domain
-		if err := rows.Scan(
-			&i.ID,
-			&i.Domain,
-			&i.CreatedAt,
-			&i.Key,
-			&i.DkimPrivateKey,
-			&i.DkimPublicKey,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+type CreateDMARCRecordParams struct {
+	ReportID    int32
+	HeaderFrom  string
+	SourceIp    string
+	Count       int64
+	Disposition string
+	DkimAligned bool
+	SpfAligned  bool
+	BeginAt     time.Time
 }
 
-const getDomains = `-- name: GetDomains :many
-SELECT id, domain, created_at, key, dkim_private_key, dkim_public_key FROM domains
+func (q *Queries) CreateDMARCRecord(ctx context.Context, arg CreateDMARCRecordParams) error {
+	_, err := q.exec(ctx, q.createDMARCRecordStmt, createDMARCRecord,
+		arg.ReportID,
+		arg.HeaderFrom,
+		arg.SourceIp,
+		arg.Count,
+		arg.Disposition,
+		arg.DkimAligned,
+		arg.SpfAligned,
+		arg.BeginAt,
+	)
+	return err
+}
+
+const createDMARCReport = `-- name: CreateDMARCReport :many
+INSERT INTO dmarc_reports (org_name, report_id, domain, begin_at, end_at)
+    VALUES ($1, $2, $3, $4, $5)
+    ON CONFLICT (org_name, report_id) DO NOTHING
+    RETURNING id, org_name, report_id, domain, begin_at, end_at, created_at
 `
 
-func (q *Queries) GetDomains(ctx context.Context) ([]Domain, error) {
-	rows, err := q.query(ctx, q.getDomainsStmt, getDomains)
+type CreateDMARCReportParams struct {
+	OrgName  string
+	ReportID string
+	Domain   string
+	BeginAt  time.Time
+	EndAt    time.Time
+}
+
+// no rows are returned for reports already received
+func (q *Queries) CreateDMARCReport(ctx context.Context, arg CreateDMARCReportParams) ([]DmarcReport, error) {
+	rows, err := q.query(ctx, q.createDMARCReportStmt, createDMARCReport,
+		arg.OrgName,
+		arg.ReportID,
+		arg.Domain,
+		arg.BeginAt,
+		arg.EndAt,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Domain
+	var items []DmarcReport
 	for rows.Next() {
-		var i Domain
+		var i DmarcReport
 		if err := rows.Scan(
 			&i.ID,
+			&i.OrgName,
+			&i.ReportID,
 			&i.Domain,
+			&i.BeginAt,
+			&i.EndAt,
 			&i.CreatedAt,
-			&i.Key,
-			&i.DkimPrivateKey,
-			&i.DkimPublicKey,
 		); err != nil {
 			return nil, err
 		}
@@ -303,64 +493,274 @@ func (q *Queries) GetDomains(ctx context.Context) ([]Domain, error) {
 	return items, nil
 }
 
-const getSendingData = `-- name: GetSendingData :one
-SELECT
-    t.html,
-    m.domain,
-    d.dkim_private_key,
-    d.dkim_public_key,
-    m.subject,
-    m.message_id,
-    m.sender_email,
-    m.sender_alias
-FROM messages as m
-    JOIN templates as t ON t.template_id = m.template_id
-    JOIN domains as d ON d.domain = m.domain
-    WHERE m.id=$1
+const createDeadLetter = `-- name: CreateDeadLetter :one
+INSERT INTO dead_letters (domain, subject, message_id, email, payload, reason, created_at) VALUES
+    ($1, $2, $3, $4, $5, $6, $7)
+    RETURNING id, domain, subject, message_id, email, payload, reason, created_at, requeued_at
 `
 
-type GetSendingDataRow struct {
-	Html           string
-	Domain         string
-	DkimPrivateKey string
-	DkimPublicKey  string
-	Subject        string
-	MessageID      string
-	SenderEmail    string
-	SenderAlias    string
+type CreateDeadLetterParams struct {
+	Domain    string
+	Subject   string
+	MessageID string
+	Email     string
+	Payload   []byte
+	Reason    string
+	CreatedAt time.Time
 }
 
-func (q *Queries) GetSendingData(ctx context.Context, messageID int32) (GetSendingDataRow, error) {
-	row := q.queryRow(ctx, q.getSendingDataStmt, getSendingData, messageID)
-	var i GetSendingDataRow
+func (q *Queries) CreateDeadLetter(ctx context.Context, arg CreateDeadLetterParams) (DeadLetter, error) {
+	row := q.queryRow(ctx, q.createDeadLetterStmt, createDeadLetter,
+		arg.Domain,
+		arg.Subject,
+		arg.MessageID,
+		arg.Email,
+		arg.Payload,
+		arg.Reason,
+		arg.CreatedAt,
+	)
+	var i DeadLetter
 	err := row.Scan(
-		&i.Html,
+		&i.ID,
 		&i.Domain,
+		&i.Subject,
+		&i.MessageID,
+		&i.Email,
+		&i.Payload,
+		&i.Reason,
+		&i.CreatedAt,
+		&i.RequeuedAt,
+	)
+	return i, err
+}
+
+const createDomain = `-- name: CreateDomain :one
+INSERT INTO domains 
+    (domain, dkim_private_key, dkim_public_key, dkim_ed25519_private_key, dkim_ed25519_public_key)
+    VALUES ($1, $2, $3, $4, $5) 
+    RETURNING id, domain, created_at, dkim_private_key, dkim_public_key, retention_days, rate_per_second, rate_per_hour, ip_pool, dkim_ed25519_private_key, dkim_ed25519_public_key, dkim_signing, dkim_selector, dkim_ed25519_selector, dkim_headers, verified, return_path_domain, daily_quota, monthly_quota, deleted_at, purge_at, default_from_email, default_from_name, default_headers, open_tracking, click_tracking, footer_html, footer_text, sandbox, sandbox_bounce_percent, paused_at, paused_reason
+`
+
+type CreateDomainParams struct {
+	Domain                string
+	DkimPrivateKey        string
+	DkimPublicKey         string
+	DkimEd25519PrivateKey string
+	DkimEd25519PublicKey  string
+}
+
+func (q *Queries) CreateDomain(ctx context.Context, arg CreateDomainParams) (Domain, error) {
+	row := q.queryRow(ctx, q.createDomainStmt, createDomain,
+		arg.Domain,
+		arg.DkimPrivateKey,
+		arg.DkimPublicKey,
+		arg.DkimEd25519PrivateKey,
+		arg.DkimEd25519PublicKey,
+	)
+	var i Domain
+	err := row.Scan(
+		&i.ID,
+		&i.Domain,
+		&i.CreatedAt,
 		&i.DkimPrivateKey,
 		&i.DkimPublicKey,
-		&i.Subject,
+		&i.RetentionDays,
+		&i.RatePerSecond,
+		&i.RatePerHour,
+		&i.IpPool,
+		&i.DkimEd25519PrivateKey,
+		&i.DkimEd25519PublicKey,
+		&i.DkimSigning,
+		&i.DkimSelector,
+		&i.DkimEd25519Selector,
+		pq.Array(&i.DkimHeaders),
+		&i.Verified,
+		&i.ReturnPathDomain,
+		&i.DailyQuota,
+		&i.MonthlyQuota,
+		&i.DeletedAt,
+		&i.PurgeAt,
+		&i.DefaultFromEmail,
+		&i.DefaultFromName,
+		&i.DefaultHeaders,
+		&i.OpenTracking,
+		&i.ClickTracking,
+		&i.FooterHtml,
+		&i.FooterText,
+		&i.Sandbox,
+		&i.SandboxBouncePercent,
+		&i.PausedAt,
+		&i.PausedReason,
+	)
+	return i, err
+}
+
+const createDomainUsage = `-- name: CreateDomainUsage :exec
+INSERT INTO domain_usage (domain, day, sent)
+SELECT m.domain, CURRENT_DATE, 0
+FROM sending_pool_emails AS sp
+    JOIN messages AS m ON m.id = sp.message_id
+    WHERE sp.id = $1
+ON CONFLICT (domain, day) DO NOTHING
+`
+
+// creates the usage of today of the domain of a pool email, consumed by ConsumeDomainQuota
+func (q *Queries) CreateDomainUsage(ctx context.Context, id int32) error {
+	_, err := q.exec(ctx, q.createDomainUsageStmt, createDomainUsage, id)
+	return err
+}
+
+const createMessage = `-- name: CreateMessage :one
+INSERT INTO messages
+    (message_id, subject, sender_email, sender_alias, template_id, template_version, domain, cc, bcc, headers, reply_to, fields, idempotency_key, resent_from) VALUES
+    ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id, message_id, subject, sender_email, sender_alias, template_id, domain, cc, bcc, headers, reply_to, fields, template_version, idempotency_key, created_at, resent_from
+`
+
+type CreateMessageParams struct {
+	MessageID       string
+	Subject         string
+	SenderEmail     string
+	SenderAlias     string
+	TemplateID      string
+	TemplateVersion int32
+	Domain          string
+	Cc              []string
+	Bcc             []string
+	Headers         json.RawMessage
+	ReplyTo         string
+	Fields          json.RawMessage
+	IdempotencyKey  sql.NullString
+	ResentFrom      string
+}
+
+func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (Message, error) {
+	row := q.queryRow(ctx, q.createMessageStmt, createMessage,
+		arg.MessageID,
+		arg.Subject,
+		arg.SenderEmail,
+		arg.SenderAlias,
+		arg.TemplateID,
+		arg.TemplateVersion,
+		arg.Domain,
+		pq.Array(arg.Cc),
+		pq.Array(arg.Bcc),
+		arg.Headers,
+		arg.ReplyTo,
+		arg.Fields,
+		arg.IdempotencyKey,
+		arg.ResentFrom,
+	)
+	var i Message
+	err := row.Scan(
+		&i.ID,
 		&i.MessageID,
+		&i.Subject,
 		&i.SenderEmail,
 		&i.SenderAlias,
+		&i.TemplateID,
+		&i.Domain,
+		pq.Array(&i.Cc),
+		pq.Array(&i.Bcc),
+		&i.Headers,
+		&i.ReplyTo,
+		&i.Fields,
+		&i.TemplateVersion,
+		&i.IdempotencyKey,
+		&i.CreatedAt,
+		&i.ResentFrom,
 	)
 	return i, err
 }
 
-const prepareForSend = `-- name: PrepareForSend :many
-UPDATE sending_pool_emails AS sp
-    SET status = 'scheduled'
-    FROM (
-            SELECT id FROM sending_pool_emails
-            WHERE scheduled_time <= NOW() and status = 'scheduled'
-            ORDER BY RANDOM()
-            LIMIT $1
-        ) AS t
-    WHERE sp.id = t.id
-    RETURNING sp.id, sp.status, sp.scheduled_time, sp.original_scheduled_time, sp.trial, sp.email, sp.message_id, sp.error_msg, sp.error_code
+const createMessageEvent = `-- name: CreateMessageEvent :exec
+INSERT INTO message_events (message_id, email, type, data, timestamp) VALUES
+    ($1, $2, $3, $4, $5)
 `
 
-func (q *Queries) PrepareForSend(ctx context.Context, limit int32) ([]SendingPoolEmail, error) {
-	rows, err := q.query(ctx, q.prepareForSendStmt, prepareForSend, limit)
+type CreateMessageEventParams struct {
+	MessageID string
+	Email     string
+	Type      string
+	Data      json.RawMessage
+	Timestamp time.Time
+}
+
+func (q *Queries) CreateMessageEvent(ctx context.Context, arg CreateMessageEventParams) error {
+	_, err := q.exec(ctx, q.createMessageEventStmt, createMessageEvent,
+		arg.MessageID,
+		arg.Email,
+		arg.Type,
+		arg.Data,
+		arg.Timestamp,
+	)
+	return err
+}
+
+const createOpen = `-- name: CreateOpen :exec
+INSERT INTO opens (message_id, email, ip, user_agent, timestamp) VALUES
+    ($1, $2, $3, $4, $5)
+`
+
+type CreateOpenParams struct {
+	MessageID string
+	Email     string
+	Ip        string
+	UserAgent string
+	Timestamp time.Time
+}
+
+func (q *Queries) CreateOpen(ctx context.Context, arg CreateOpenParams) error {
+	_, err := q.exec(ctx, q.createOpenStmt, createOpen,
+		arg.MessageID,
+		arg.Email,
+		arg.Ip,
+		arg.UserAgent,
+		arg.Timestamp,
+	)
+	return err
+}
+
+const createPool = `-- name: CreatePool :many
+INSERT INTO sending_pool_emails
+    (email, fields, status, scheduled_time, original_scheduled_time, message_id, priority, trace_parent, copies)
+(
+    SELECT
+        e.email,
+        f.fields::jsonb,
+        'scheduled',
+        $1,
+        $1,
+        $2,
+        $3,
+        $4,
+        e.i = 1 AND $5::boolean
+    FROM
+        UNNEST($6::varchar[]) WITH ORDINALITY as e(email, i)
+        JOIN UNNEST($7::varchar[]) WITH ORDINALITY as f(fields, i) USING (i)
+)
+RETURNING id, status, scheduled_time, original_scheduled_time, trial, email, message_id, error_msg, error_code, fields, bounce_type, priority, dispatched_at, trace_parent, copies, claimed_at, quota_consumed
+`
+
+type CreatePoolParams struct {
+	ScheduledTime time.Time
+	MessageID     int32
+	Priority      int16
+	TraceParent   string
+	Copies        bool
+	Emails        []string
+	Fields        []string
+}
+
+func (q *Queries) CreatePool(ctx context.Context, arg CreatePoolParams) ([]SendingPoolEmail, error) {
+	rows, err := q.query(ctx, q.createPoolStmt, createPool,
+		arg.ScheduledTime,
+		arg.MessageID,
+		arg.Priority,
+		arg.TraceParent,
+		arg.Copies,
+		pq.Array(arg.Emails),
+		pq.Array(arg.Fields),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -378,6 +778,14 @@ func (q *Queries) PrepareForSend(ctx context.Context, limit int32) ([]SendingPoo
 			&i.MessageID,
 			&i.ErrorMsg,
 			&i.ErrorCode,
+			&i.Fields,
+			&i.BounceType,
+			&i.Priority,
+			&i.DispatchedAt,
+			&i.TraceParent,
+			&i.Copies,
+			&i.ClaimedAt,
+			&i.QuotaConsumed,
 		); err != nil {
 			return nil, err
 		}
@@ -391,3 +799,3920 @@ func (q *Queries) PrepareForSend(ctx context.Context, limit int32) ([]SendingPoo
 	}
 	return items, nil
 }
+
+const createPoolTimelineEntries = `-- name: CreatePoolTimelineEntries :exec
+INSERT INTO message_timeline (message_id, email, stage, data, timestamp)
+    SELECT m.message_id, sp.email, $1, $2, $3 FROM sending_pool_emails AS sp
+        JOIN messages AS m ON m.id = sp.message_id
+        WHERE sp.id = ANY($4::int[])
+`
+
+type CreatePoolTimelineEntriesParams struct {
+	Stage     string
+	Data      json.RawMessage
+	Timestamp time.Time
+	Ids       []int32
+}
+
+func (q *Queries) CreatePoolTimelineEntries(ctx context.Context, arg CreatePoolTimelineEntriesParams) error {
+	_, err := q.exec(ctx, q.createPoolTimelineEntriesStmt, createPoolTimelineEntries,
+		arg.Stage,
+		arg.Data,
+		arg.Timestamp,
+		pq.Array(arg.Ids),
+	)
+	return err
+}
+
+const createSuppression = `-- name: CreateSuppression :one
+INSERT INTO suppressions (domain, email, reason) VALUES
+    ($1, lower($2::varchar), $3)
+    ON CONFLICT (domain, email) DO UPDATE SET reason = EXCLUDED.reason
+    RETURNING id, domain, email, reason, created_at
+`
+
+type CreateSuppressionParams struct {
+	Domain string
+	Email  string
+	Reason SuppressionReason
+}
+
+func (q *Queries) CreateSuppression(ctx context.Context, arg CreateSuppressionParams) (Suppression, error) {
+	row := q.queryRow(ctx, q.createSuppressionStmt, createSuppression, arg.Domain, arg.Email, arg.Reason)
+	var i Suppression
+	err := row.Scan(
+		&i.ID,
+		&i.Domain,
+		&i.Email,
+		&i.Reason,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createTemplate = `-- name: CreateTemplate :one
+INSERT INTO templates
+    (template_id, html, text, domain, name)
+    VALUES ($1, $2, $3, $4, $5)
+    RETURNING id, template_id, html, domain, text, version, active, name, deleted_at
+`
+
+type CreateTemplateParams struct {
+	TemplateID string
+	Html       string
+	Text       string
+	Domain     string
+	Name       string
+}
+
+func (q *Queries) CreateTemplate(ctx context.Context, arg CreateTemplateParams) (Template, error) {
+	row := q.queryRow(ctx, q.createTemplateStmt, createTemplate,
+		arg.TemplateID,
+		arg.Html,
+		arg.Text,
+		arg.Domain,
+		arg.Name,
+	)
+	var i Template
+	err := row.Scan(
+		&i.ID,
+		&i.TemplateID,
+		&i.Html,
+		&i.Domain,
+		&i.Text,
+		&i.Version,
+		&i.Active,
+		&i.Name,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const createTemplateVersion = `-- name: CreateTemplateVersion :one
+INSERT INTO templates
+    (template_id, html, text, domain, version, active, name)
+    SELECT $1, $2, $3, $4, COALESCE(MAX(version), 0) + 1, false, COALESCE(MAX(name), '')
+    FROM templates
+    WHERE template_id = $1
+    AND domain = $4
+    RETURNING id, template_id, html, domain, text, version, active, name, deleted_at
+`
+
+type CreateTemplateVersionParams struct {
+	TemplateID string
+	Html       string
+	Text       string
+	Domain     string
+}
+
+func (q *Queries) CreateTemplateVersion(ctx context.Context, arg CreateTemplateVersionParams) (Template, error) {
+	row := q.queryRow(ctx, q.createTemplateVersionStmt, createTemplateVersion,
+		arg.TemplateID,
+		arg.Html,
+		arg.Text,
+		arg.Domain,
+	)
+	var i Template
+	err := row.Scan(
+		&i.ID,
+		&i.TemplateID,
+		&i.Html,
+		&i.Domain,
+		&i.Text,
+		&i.Version,
+		&i.Active,
+		&i.Name,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const createTimelineEntry = `-- name: CreateTimelineEntry :exec
+INSERT INTO message_timeline (message_id, email, stage, smtp_code, smtp_response, data, timestamp) VALUES
+    ($1, $2, $3, $4, $5, $6, $7)
+`
+
+type CreateTimelineEntryParams struct {
+	MessageID    string
+	Email        string
+	Stage        string
+	SmtpCode     int32
+	SmtpResponse string
+	Data         json.RawMessage
+	Timestamp    time.Time
+}
+
+func (q *Queries) CreateTimelineEntry(ctx context.Context, arg CreateTimelineEntryParams) error {
+	_, err := q.exec(ctx, q.createTimelineEntryStmt, createTimelineEntry,
+		arg.MessageID,
+		arg.Email,
+		arg.Stage,
+		arg.SmtpCode,
+		arg.SmtpResponse,
+		arg.Data,
+		arg.Timestamp,
+	)
+	return err
+}
+
+const createWebhook = `-- name: CreateWebhook :one
+INSERT INTO webhooks (domain, url, secret, events) VALUES
+    ($1, $2, $3, $4)
+    RETURNING id, domain, url, secret, events, created_at
+`
+
+type CreateWebhookParams struct {
+	Domain string
+	Url    string
+	Secret string
+	Events []string
+}
+
+func (q *Queries) CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error) {
+	row := q.queryRow(ctx, q.createWebhookStmt, createWebhook,
+		arg.Domain,
+		arg.Url,
+		arg.Secret,
+		pq.Array(arg.Events),
+	)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.Domain,
+		&i.Url,
+		&i.Secret,
+		pq.Array(&i.Events),
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createWebhookDeliveries = `-- name: CreateWebhookDeliveries :execrows
+INSERT INTO webhook_deliveries (webhook_id, event, payload)
+    SELECT w.id, $1::varchar, $2::jsonb
+    FROM webhooks AS w
+    WHERE w.domain = $3 AND $1::varchar = ANY(w.events)
+`
+
+type CreateWebhookDeliveriesParams struct {
+	Event   string
+	Payload json.RawMessage
+	Domain  string
+}
+
+func (q *Queries) CreateWebhookDeliveries(ctx context.Context, arg CreateWebhookDeliveriesParams) (int64, error) {
+	result, err := q.exec(ctx, q.createWebhookDeliveriesStmt, createWebhookDeliveries, arg.Event, arg.Payload, arg.Domain)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deferSendingPoolEmail = `-- name: DeferSendingPoolEmail :exec
+UPDATE sending_pool_emails AS sp
+    SET
+        status = 'scheduled',
+        scheduled_time = $1
+    FROM messages AS m
+    WHERE m.id = sp.message_id AND m.message_id = $2 AND sp.email = $3
+`
+
+type DeferSendingPoolEmailParams struct {
+	ScheduledTime time.Time
+	MessageID     string
+	Email         string
+}
+
+func (q *Queries) DeferSendingPoolEmail(ctx context.Context, arg DeferSendingPoolEmailParams) error {
+	_, err := q.exec(ctx, q.deferSendingPoolEmailStmt, deferSendingPoolEmail, arg.ScheduledTime, arg.MessageID, arg.Email)
+	return err
+}
+
+const deleteDomain = `-- name: DeleteDomain :one
+UPDATE domains
+    SET deleted_at = NOW(), purge_at = $1
+    WHERE domain = $2
+    AND deleted_at IS NULL
+    RETURNING id, domain, created_at, dkim_private_key, dkim_public_key, retention_days, rate_per_second, rate_per_hour, ip_pool, dkim_ed25519_private_key, dkim_ed25519_public_key, dkim_signing, dkim_selector, dkim_ed25519_selector, dkim_headers, verified, return_path_domain, daily_quota, monthly_quota, deleted_at, purge_at, default_from_email, default_from_name, default_headers, open_tracking, click_tracking, footer_html, footer_text, sandbox, sandbox_bounce_percent, paused_at, paused_reason
+`
+
+type DeleteDomainParams struct {
+	PurgeAt sql.NullTime
+	Domain  string
+}
+
+func (q *Queries) DeleteDomain(ctx context.Context, arg DeleteDomainParams) (Domain, error) {
+	row := q.queryRow(ctx, q.deleteDomainStmt, deleteDomain, arg.PurgeAt, arg.Domain)
+	var i Domain
+	err := row.Scan(
+		&i.ID,
+		&i.Domain,
+		&i.CreatedAt,
+		&i.DkimPrivateKey,
+		&i.DkimPublicKey,
+		&i.RetentionDays,
+		&i.RatePerSecond,
+		&i.RatePerHour,
+		&i.IpPool,
+		&i.DkimEd25519PrivateKey,
+		&i.DkimEd25519PublicKey,
+		&i.DkimSigning,
+		&i.DkimSelector,
+		&i.DkimEd25519Selector,
+		pq.Array(&i.DkimHeaders),
+		&i.Verified,
+		&i.ReturnPathDomain,
+		&i.DailyQuota,
+		&i.MonthlyQuota,
+		&i.DeletedAt,
+		&i.PurgeAt,
+		&i.DefaultFromEmail,
+		&i.DefaultFromName,
+		&i.DefaultHeaders,
+		&i.OpenTracking,
+		&i.ClickTracking,
+		&i.FooterHtml,
+		&i.FooterText,
+		&i.Sandbox,
+		&i.SandboxBouncePercent,
+		&i.PausedAt,
+		&i.PausedReason,
+	)
+	return i, err
+}
+
+const deleteSuppression = `-- name: DeleteSuppression :execrows
+DELETE FROM suppressions
+    WHERE domain = $1 AND email = lower($2::varchar)
+`
+
+type DeleteSuppressionParams struct {
+	Domain string
+	Email  string
+}
+
+func (q *Queries) DeleteSuppression(ctx context.Context, arg DeleteSuppressionParams) (int64, error) {
+	result, err := q.exec(ctx, q.deleteSuppressionStmt, deleteSuppression, arg.Domain, arg.Email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteTemplate = `-- name: DeleteTemplate :execrows
+UPDATE templates
+    SET deleted_at = NOW()
+    WHERE template_id = $1
+    AND domain = $2
+    AND deleted_at IS NULL
+`
+
+type DeleteTemplateParams struct {
+	TemplateID string
+	Domain     string
+}
+
+// the versions stay, the emails of the template not sent yet render their own version
+func (q *Queries) DeleteTemplate(ctx context.Context, arg DeleteTemplateParams) (int64, error) {
+	result, err := q.exec(ctx, q.deleteTemplateStmt, deleteTemplate, arg.TemplateID, arg.Domain)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteWebhook = `-- name: DeleteWebhook :execrows
+DELETE FROM webhooks
+    WHERE domain = $1 AND id = $2
+`
+
+type DeleteWebhookParams struct {
+	Domain string
+	ID     int32
+}
+
+func (q *Queries) DeleteWebhook(ctx context.Context, arg DeleteWebhookParams) (int64, error) {
+	result, err := q.exec(ctx, q.deleteWebhookStmt, deleteWebhook, arg.Domain, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const expireAPIKey = `-- name: ExpireAPIKey :one
+UPDATE api_keys
+    SET expires_at = $1
+    WHERE domain = $2
+    AND id = $3
+    AND revoked_at IS NULL
+    AND (expires_at IS NULL OR expires_at > $1)
+    RETURNING id, domain, name, prefix, key_hash, scopes, created_at, last_used_at, revoked_at, expires_at
+`
+
+type ExpireAPIKeyParams struct {
+	ExpiresAt sql.NullTime
+	Domain    string
+	ID        int32
+}
+
+// keys already expiring before expires_at keep their expiration
+func (q *Queries) ExpireAPIKey(ctx context.Context, arg ExpireAPIKeyParams) (ApiKey, error) {
+	row := q.queryRow(ctx, q.expireAPIKeyStmt, expireAPIKey, arg.ExpiresAt, arg.Domain, arg.ID)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.Domain,
+		&i.Name,
+		&i.Prefix,
+		&i.KeyHash,
+		pq.Array(&i.Scopes),
+		&i.CreatedAt,
+		&i.LastUsedAt,
+		&i.RevokedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const exportMessageEvents = `-- name: ExportMessageEvents :many
+SELECT me.id, me.message_id, me.email, me.type, me.data, me.timestamp FROM message_events AS me
+    JOIN messages AS m ON m.message_id = me.message_id
+    WHERE m.domain = $1
+    AND me.timestamp >= $2 AND me.timestamp < $3
+    AND (CARDINALITY($4::varchar[]) = 0 OR me.type = ANY($4::varchar[]))
+    AND me.id > $5
+    ORDER BY me.id
+    LIMIT $6
+`
+
+type ExportMessageEventsParams struct {
+	Domain    string
+	StartTime time.Time
+	EndTime   time.Time
+	Types     []string
+	After     int32
+	Max       int32
+}
+
+func (q *Queries) ExportMessageEvents(ctx context.Context, arg ExportMessageEventsParams) ([]MessageEvent, error) {
+	rows, err := q.query(ctx, q.exportMessageEventsStmt, exportMessageEvents,
+		arg.Domain,
+		arg.StartTime,
+		arg.EndTime,
+		pq.Array(arg.Types),
+		arg.After,
+		arg.Max,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MessageEvent
+	for rows.Next() {
+		var i MessageEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.MessageID,
+			&i.Email,
+			&i.Type,
+			&i.Data,
+			&i.Timestamp,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const findAPIKey = `-- name: FindAPIKey :one
+SELECT id, domain, name, prefix, key_hash, scopes, created_at, last_used_at, revoked_at, expires_at FROM api_keys
+    WHERE domain = $1
+    AND key_hash = $2
+    AND revoked_at IS NULL
+    AND (expires_at IS NULL OR expires_at > NOW())
+`
+
+type FindAPIKeyParams struct {
+	Domain  string
+	KeyHash string
+}
+
+func (q *Queries) FindAPIKey(ctx context.Context, arg FindAPIKeyParams) (ApiKey, error) {
+	row := q.queryRow(ctx, q.findAPIKeyStmt, findAPIKey, arg.Domain, arg.KeyHash)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.Domain,
+		&i.Name,
+		&i.Prefix,
+		&i.KeyHash,
+		pq.Array(&i.Scopes),
+		&i.CreatedAt,
+		&i.LastUsedAt,
+		&i.RevokedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const findDeletedDomain = `-- name: FindDeletedDomain :one
+SELECT id, domain, created_at, dkim_private_key, dkim_public_key, retention_days, rate_per_second, rate_per_hour, ip_pool, dkim_ed25519_private_key, dkim_ed25519_public_key, dkim_signing, dkim_selector, dkim_ed25519_selector, dkim_headers, verified, return_path_domain, daily_quota, monthly_quota, deleted_at, purge_at, default_from_email, default_from_name, default_headers, open_tracking, click_tracking, footer_html, footer_text, sandbox, sandbox_bounce_percent, paused_at, paused_reason FROM domains
+    WHERE domain = $1
+    AND deleted_at IS NOT NULL
+`
+
+func (q *Queries) FindDeletedDomain(ctx context.Context, domain string) (Domain, error) {
+	row := q.queryRow(ctx, q.findDeletedDomainStmt, findDeletedDomain, domain)
+	var i Domain
+	err := row.Scan(
+		&i.ID,
+		&i.Domain,
+		&i.CreatedAt,
+		&i.DkimPrivateKey,
+		&i.DkimPublicKey,
+		&i.RetentionDays,
+		&i.RatePerSecond,
+		&i.RatePerHour,
+		&i.IpPool,
+		&i.DkimEd25519PrivateKey,
+		&i.DkimEd25519PublicKey,
+		&i.DkimSigning,
+		&i.DkimSelector,
+		&i.DkimEd25519Selector,
+		pq.Array(&i.DkimHeaders),
+		&i.Verified,
+		&i.ReturnPathDomain,
+		&i.DailyQuota,
+		&i.MonthlyQuota,
+		&i.DeletedAt,
+		&i.PurgeAt,
+		&i.DefaultFromEmail,
+		&i.DefaultFromName,
+		&i.DefaultHeaders,
+		&i.OpenTracking,
+		&i.ClickTracking,
+		&i.FooterHtml,
+		&i.FooterText,
+		&i.Sandbox,
+		&i.SandboxBouncePercent,
+		&i.PausedAt,
+		&i.PausedReason,
+	)
+	return i, err
+}
+
+const findDomain = `-- name: FindDomain :one
+SELECT
+    id, domain, created_at, dkim_private_key, dkim_public_key, retention_days, rate_per_second, rate_per_hour, ip_pool, dkim_ed25519_private_key, dkim_ed25519_public_key, dkim_signing, dkim_selector, dkim_ed25519_selector, dkim_headers, verified, return_path_domain, daily_quota, monthly_quota, deleted_at, purge_at, default_from_email, default_from_name, default_headers, open_tracking, click_tracking, footer_html, footer_text, sandbox, sandbox_bounce_percent, paused_at, paused_reason
+FROM domains
+    WHERE domain = $1
+    AND deleted_at IS NULL
+`
+
+func (q *Queries) FindDomain(ctx context.Context, domain string) (Domain, error) {
+	row := q.queryRow(ctx, q.findDomainStmt, findDomain, domain)
+	var i Domain
+	err := row.Scan(
+		&i.ID,
+		&i.Domain,
+		&i.CreatedAt,
+		&i.DkimPrivateKey,
+		&i.DkimPublicKey,
+		&i.RetentionDays,
+		&i.RatePerSecond,
+		&i.RatePerHour,
+		&i.IpPool,
+		&i.DkimEd25519PrivateKey,
+		&i.DkimEd25519PublicKey,
+		&i.DkimSigning,
+		&i.DkimSelector,
+		&i.DkimEd25519Selector,
+		pq.Array(&i.DkimHeaders),
+		&i.Verified,
+		&i.ReturnPathDomain,
+		&i.DailyQuota,
+		&i.MonthlyQuota,
+		&i.DeletedAt,
+		&i.PurgeAt,
+		&i.DefaultFromEmail,
+		&i.DefaultFromName,
+		&i.DefaultHeaders,
+		&i.OpenTracking,
+		&i.ClickTracking,
+		&i.FooterHtml,
+		&i.FooterText,
+		&i.Sandbox,
+		&i.SandboxBouncePercent,
+		&i.PausedAt,
+		&i.PausedReason,
+	)
+	return i, err
+}
+
+const findMessageWithIdempotencyKey = `-- name: FindMessageWithIdempotencyKey :one
+SELECT id, message_id, subject, sender_email, sender_alias, template_id, domain, cc, bcc, headers, reply_to, fields, template_version, idempotency_key, created_at, resent_from FROM messages
+    WHERE domain = $1 AND idempotency_key = $2
+`
+
+type FindMessageWithIdempotencyKeyParams struct {
+	Domain         string
+	IdempotencyKey sql.NullString
+}
+
+func (q *Queries) FindMessageWithIdempotencyKey(ctx context.Context, arg FindMessageWithIdempotencyKeyParams) (Message, error) {
+	row := q.queryRow(ctx, q.findMessageWithIdempotencyKeyStmt, findMessageWithIdempotencyKey, arg.Domain, arg.IdempotencyKey)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.MessageID,
+		&i.Subject,
+		&i.SenderEmail,
+		&i.SenderAlias,
+		&i.TemplateID,
+		&i.Domain,
+		pq.Array(&i.Cc),
+		pq.Array(&i.Bcc),
+		&i.Headers,
+		&i.ReplyTo,
+		&i.Fields,
+		&i.TemplateVersion,
+		&i.IdempotencyKey,
+		&i.CreatedAt,
+		&i.ResentFrom,
+	)
+	return i, err
+}
+
+const findPendingDKIMKey = `-- name: FindPendingDKIMKey :one
+SELECT
+    id, domain, selector, algorithm, private_key, public_key, status, retire_at, created_at
+FROM dkim_keys
+    WHERE domain = $1
+    AND selector = $2
+    AND status = 'pending'
+`
+
+type FindPendingDKIMKeyParams struct {
+	Domain   string
+	Selector string
+}
+
+func (q *Queries) FindPendingDKIMKey(ctx context.Context, arg FindPendingDKIMKeyParams) (DkimKey, error) {
+	row := q.queryRow(ctx, q.findPendingDKIMKeyStmt, findPendingDKIMKey, arg.Domain, arg.Selector)
+	var i DkimKey
+	err := row.Scan(
+		&i.ID,
+		&i.Domain,
+		&i.Selector,
+		&i.Algorithm,
+		&i.PrivateKey,
+		&i.PublicKey,
+		&i.Status,
+		&i.RetireAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const findSendingPoolEmail = `-- name: FindSendingPoolEmail :one
+SELECT sp.id, sp.status, sp.scheduled_time, sp.original_scheduled_time, sp.trial, sp.email, sp.message_id, sp.error_msg, sp.error_code, sp.fields, sp.bounce_type, sp.priority, sp.dispatched_at, sp.trace_parent, sp.copies, sp.claimed_at, sp.quota_consumed FROM sending_pool_emails AS sp
+    JOIN messages AS m ON m.id = sp.message_id
+    WHERE m.message_id = $1 AND sp.email = $2
+`
+
+type FindSendingPoolEmailParams struct {
+	MessageID string
+	Email     string
+}
+
+func (q *Queries) FindSendingPoolEmail(ctx context.Context, arg FindSendingPoolEmailParams) (SendingPoolEmail, error) {
+	row := q.queryRow(ctx, q.findSendingPoolEmailStmt, findSendingPoolEmail, arg.MessageID, arg.Email)
+	var i SendingPoolEmail
+	err := row.Scan(
+		&i.ID,
+		&i.Status,
+		&i.ScheduledTime,
+		&i.OriginalScheduledTime,
+		&i.Trial,
+		&i.Email,
+		&i.MessageID,
+		&i.ErrorMsg,
+		&i.ErrorCode,
+		&i.Fields,
+		&i.BounceType,
+		&i.Priority,
+		&i.DispatchedAt,
+		&i.TraceParent,
+		&i.Copies,
+		&i.ClaimedAt,
+		&i.QuotaConsumed,
+	)
+	return i, err
+}
+
+const findTemplate = `-- name: FindTemplate :one
+SELECT
+    id, template_id, html, domain, text, version, active, name, deleted_at
+FROM templates
+    WHERE template_id = $1
+    AND domain = $2
+    AND active
+    AND deleted_at IS NULL
+`
+
+type FindTemplateParams struct {
+	TemplateID string
+	Domain     string
+}
+
+func (q *Queries) FindTemplate(ctx context.Context, arg FindTemplateParams) (Template, error) {
+	row := q.queryRow(ctx, q.findTemplateStmt, findTemplate, arg.TemplateID, arg.Domain)
+	var i Template
+	err := row.Scan(
+		&i.ID,
+		&i.TemplateID,
+		&i.Html,
+		&i.Domain,
+		&i.Text,
+		&i.Version,
+		&i.Active,
+		&i.Name,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const findTemplateByName = `-- name: FindTemplateByName :one
+SELECT id, template_id, html, domain, text, version, active, name, deleted_at FROM templates
+    WHERE domain = $1
+    AND name = $2
+    AND active
+    AND deleted_at IS NULL
+`
+
+type FindTemplateByNameParams struct {
+	Domain string
+	Name   string
+}
+
+func (q *Queries) FindTemplateByName(ctx context.Context, arg FindTemplateByNameParams) (Template, error) {
+	row := q.queryRow(ctx, q.findTemplateByNameStmt, findTemplateByName, arg.Domain, arg.Name)
+	var i Template
+	err := row.Scan(
+		&i.ID,
+		&i.TemplateID,
+		&i.Html,
+		&i.Domain,
+		&i.Text,
+		&i.Version,
+		&i.Active,
+		&i.Name,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const findTemplateVersion = `-- name: FindTemplateVersion :one
+SELECT
+    id, template_id, html, domain, text, version, active, name, deleted_at
+FROM templates
+    WHERE template_id = $1
+    AND domain = $2
+    AND version = $3
+    AND deleted_at IS NULL
+`
+
+type FindTemplateVersionParams struct {
+	TemplateID string
+	Domain     string
+	Version    int32
+}
+
+func (q *Queries) FindTemplateVersion(ctx context.Context, arg FindTemplateVersionParams) (Template, error) {
+	row := q.queryRow(ctx, q.findTemplateVersionStmt, findTemplateVersion, arg.TemplateID, arg.Domain, arg.Version)
+	var i Template
+	err := row.Scan(
+		&i.ID,
+		&i.TemplateID,
+		&i.Html,
+		&i.Domain,
+		&i.Text,
+		&i.Version,
+		&i.Active,
+		&i.Name,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const fireAlert = `-- name: FireAlert :execrows
+INSERT INTO firing_alerts (rule, domain, value, threshold, message, fired_at)
+    VALUES ($1, $2, $3, $4, $5, $6)
+    ON CONFLICT (rule, domain) DO NOTHING
+`
+
+type FireAlertParams struct {
+	Rule      string
+	Domain    string
+	Value     float64
+	Threshold float64
+	Message   string
+	FiredAt   time.Time
+}
+
+// alerts already firing are not inserted again
+func (q *Queries) FireAlert(ctx context.Context, arg FireAlertParams) (int64, error) {
+	result, err := q.exec(ctx, q.fireAlertStmt, fireAlert,
+		arg.Rule,
+		arg.Domain,
+		arg.Value,
+		arg.Threshold,
+		arg.Message,
+		arg.FiredAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getAPIKey = `-- name: GetAPIKey :one
+SELECT id, domain, name, prefix, key_hash, scopes, created_at, last_used_at, revoked_at, expires_at FROM api_keys
+    WHERE domain = $1
+    AND id = $2
+`
+
+type GetAPIKeyParams struct {
+	Domain string
+	ID     int32
+}
+
+func (q *Queries) GetAPIKey(ctx context.Context, arg GetAPIKeyParams) (ApiKey, error) {
+	row := q.queryRow(ctx, q.getAPIKeyStmt, getAPIKey, arg.Domain, arg.ID)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.Domain,
+		&i.Name,
+		&i.Prefix,
+		&i.KeyHash,
+		pq.Array(&i.Scopes),
+		&i.CreatedAt,
+		&i.LastUsedAt,
+		&i.RevokedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const getAPIKeyCalls = `-- name: GetAPIKeyCalls :many
+SELECT id, api_key_id, domain, method, allowed, called_at FROM api_key_calls
+    WHERE domain = $1
+    AND ($2::integer = 0 OR api_key_id = $2::integer)
+    AND called_at >= $3 AND called_at < $4
+    ORDER BY id DESC
+    LIMIT $5
+`
+
+type GetAPIKeyCallsParams struct {
+	Domain   string
+	ApiKeyID int32
+	Start    time.Time
+	Stop     time.Time
+	Max      int32
+}
+
+func (q *Queries) GetAPIKeyCalls(ctx context.Context, arg GetAPIKeyCallsParams) ([]ApiKeyCall, error) {
+	rows, err := q.query(ctx, q.getAPIKeyCallsStmt, getAPIKeyCalls,
+		arg.Domain,
+		arg.ApiKeyID,
+		arg.Start,
+		arg.Stop,
+		arg.Max,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ApiKeyCall
+	for rows.Next() {
+		var i ApiKeyCall
+		if err := rows.Scan(
+			&i.ID,
+			&i.ApiKeyID,
+			&i.Domain,
+			&i.Method,
+			&i.Allowed,
+			&i.CalledAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAPIKeys = `-- name: GetAPIKeys :many
+SELECT id, domain, name, prefix, key_hash, scopes, created_at, last_used_at, revoked_at, expires_at FROM api_keys
+    WHERE domain = $1
+    ORDER BY created_at DESC
+`
+
+func (q *Queries) GetAPIKeys(ctx context.Context, domain string) ([]ApiKey, error) {
+	rows, err := q.query(ctx, q.getAPIKeysStmt, getAPIKeys, domain)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ApiKey
+	for rows.Next() {
+		var i ApiKey
+		if err := rows.Scan(
+			&i.ID,
+			&i.Domain,
+			&i.Name,
+			&i.Prefix,
+			&i.KeyHash,
+			pq.Array(&i.Scopes),
+			&i.CreatedAt,
+			&i.LastUsedAt,
+			&i.RevokedAt,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllDomains = `-- name: GetAllDomains :many
+SELECT
+    id, domain, created_at, dkim_private_key, dkim_public_key, retention_days, rate_per_second, rate_per_hour, ip_pool, dkim_ed25519_private_key, dkim_ed25519_public_key, dkim_signing, dkim_selector, dkim_ed25519_selector, dkim_headers, verified, return_path_domain, daily_quota, monthly_quota, deleted_at, purge_at, default_from_email, default_from_name, default_headers, open_tracking, click_tracking, footer_html, footer_text, sandbox, sandbox_bounce_percent, paused_at, paused_reason
+FROM domains
+    WHERE deleted_at IS NULL
+`
+
+func (q *Queries) GetAllDomains(ctx context.Context) ([]Domain, error) {
+	rows, err := q.query(ctx, q.getAllDomainsStmt, getAllDomains)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Domain
+	for rows.Next() {
+		var i Domain
+		if err := rows.Scan(
+			&i.ID,
+			&i.Domain,
+			&i.CreatedAt,
+			&i.DkimPrivateKey,
+			&i.DkimPublicKey,
+			&i.RetentionDays,
+			&i.RatePerSecond,
+			&i.RatePerHour,
+			&i.IpPool,
+			&i.DkimEd25519PrivateKey,
+			&i.DkimEd25519PublicKey,
+			&i.DkimSigning,
+			&i.DkimSelector,
+			&i.DkimEd25519Selector,
+			pq.Array(&i.DkimHeaders),
+			&i.Verified,
+			&i.ReturnPathDomain,
+			&i.DailyQuota,
+			&i.MonthlyQuota,
+			&i.DeletedAt,
+			&i.PurgeAt,
+			&i.DefaultFromEmail,
+			&i.DefaultFromName,
+			&i.DefaultHeaders,
+			&i.OpenTracking,
+			&i.ClickTracking,
+			&i.FooterHtml,
+			&i.FooterText,
+			&i.Sandbox,
+			&i.SandboxBouncePercent,
+			&i.PausedAt,
+			&i.PausedReason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAuditLogs = `-- name: GetAuditLogs :many
+SELECT id, actor, method, domain, request, before_value, after_value, created_at FROM audit_logs
+WHERE ($1::varchar = '' OR domain = $1::varchar)
+    AND ($2::varchar = '' OR actor = $2::varchar)
+    AND ($3::varchar = '' OR method = $3::varchar)
+    AND created_at >= $4 AND created_at < $5
+    AND ($6::integer = 0 OR id < $6::integer)
+ORDER BY id DESC
+LIMIT $7
+`
+
+type GetAuditLogsParams struct {
+	Domain    string
+	Actor     string
+	Method    string
+	StartTime time.Time
+	EndTime   time.Time
+	Cursor    int32
+	Max       int32
+}
+
+func (q *Queries) GetAuditLogs(ctx context.Context, arg GetAuditLogsParams) ([]AuditLog, error) {
+	rows, err := q.query(ctx, q.getAuditLogsStmt, getAuditLogs,
+		arg.Domain,
+		arg.Actor,
+		arg.Method,
+		arg.StartTime,
+		arg.EndTime,
+		arg.Cursor,
+		arg.Max,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AuditLog
+	for rows.Next() {
+		var i AuditLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.Actor,
+			&i.Method,
+			&i.Domain,
+			&i.Request,
+			&i.BeforeValue,
+			&i.AfterValue,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDKIMKeys = `-- name: GetDKIMKeys :many
+SELECT
+    id, domain, selector, algorithm, private_key, public_key, status, retire_at, created_at
+FROM dkim_keys
+    WHERE domain = $1
+    ORDER BY created_at
+`
+
+func (q *Queries) GetDKIMKeys(ctx context.Context, domain string) ([]DkimKey, error) {
+	rows, err := q.query(ctx, q.getDKIMKeysStmt, getDKIMKeys, domain)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DkimKey
+	for rows.Next() {
+		var i DkimKey
+		if err := rows.Scan(
+			&i.ID,
+			&i.Domain,
+			&i.Selector,
+			&i.Algorithm,
+			&i.PrivateKey,
+			&i.PublicKey,
+			&i.Status,
+			&i.RetireAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDMARCStats = `-- name: GetDMARCStats :many
+SELECT
+    source_ip,
+    SUM(count)::bigint as messages,
+    SUM(CASE WHEN dkim_aligned OR spf_aligned THEN count ELSE 0 END)::bigint as passed,
+    SUM(CASE WHEN dkim_aligned THEN count ELSE 0 END)::bigint as dkim_aligned,
+    SUM(CASE WHEN spf_aligned THEN count ELSE 0 END)::bigint as spf_aligned,
+    SUM(CASE WHEN disposition = 'quarantine' THEN count ELSE 0 END)::bigint as quarantined,
+    SUM(CASE WHEN disposition = 'reject' THEN count ELSE 0 END)::bigint as rejected
+FROM dmarc_records
+    WHERE header_from = $1
+    AND begin_at >= $2
+    AND begin_at < $3
+    GROUP BY source_ip
+    ORDER BY messages DESC
+`
+
+type GetDMARCStatsParams struct {
+	HeaderFrom string
+	Start      time.Time
+	Stop       time.Time
+}
+
+type GetDMARCStatsRow struct {
+	SourceIp    string
+	Messages    int64
+	Passed      int64
+	DkimAligned int64
+	SpfAligned  int64
+	Quarantined int64
+	Rejected    int64
+}
+
+// messages of the reports of a sending domain by source ip
+func (q *Queries) GetDMARCStats(ctx context.Context, arg GetDMARCStatsParams) ([]GetDMARCStatsRow, error) {
+	rows, err := q.query(ctx, q.getDMARCStatsStmt, getDMARCStats, arg.HeaderFrom, arg.Start, arg.Stop)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDMARCStatsRow
+	for rows.Next() {
+		var i GetDMARCStatsRow
+		if err := rows.Scan(
+			&i.SourceIp,
+			&i.Messages,
+			&i.Passed,
+			&i.DkimAligned,
+			&i.SpfAligned,
+			&i.Quarantined,
+			&i.Rejected,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDeadLetters = `-- name: GetDeadLetters :many
+SELECT id, domain, subject, message_id, email, payload, reason, created_at, requeued_at FROM dead_letters
+    WHERE ($1::varchar = '' OR domain = $1::varchar)
+    AND ($2::boolean OR requeued_at IS NULL)
+    ORDER BY id DESC
+    LIMIT $3
+`
+
+type GetDeadLettersParams struct {
+	Domain          string
+	IncludeRequeued bool
+	Max             int32
+}
+
+func (q *Queries) GetDeadLetters(ctx context.Context, arg GetDeadLettersParams) ([]DeadLetter, error) {
+	rows, err := q.query(ctx, q.getDeadLettersStmt, getDeadLetters, arg.Domain, arg.IncludeRequeued, arg.Max)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DeadLetter
+	for rows.Next() {
+		var i DeadLetter
+		if err := rows.Scan(
+			&i.ID,
+			&i.Domain,
+			&i.Subject,
+			&i.MessageID,
+			&i.Email,
+			&i.Payload,
+			&i.Reason,
+			&i.CreatedAt,
+			&i.RequeuedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDomainRecords = `-- name: GetDomainRecords :many
+SELECT
+    id, domain, record, status, error, checked_at
+FROM domain_records
+    WHERE domain = $1
+    ORDER BY record
+`
+
+func (q *Queries) GetDomainRecords(ctx context.Context, domain string) ([]DomainRecord, error) {
+	rows, err := q.query(ctx, q.getDomainRecordsStmt, getDomainRecords, domain)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DomainRecord
+	for rows.Next() {
+		var i DomainRecord
+		if err := rows.Scan(
+			&i.ID,
+			&i.Domain,
+			&i.Record,
+			&i.Status,
+			&i.Error,
+			&i.CheckedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDomainUsage = `-- name: GetDomainUsage :one
+SELECT
+    COALESCE(SUM(sent) FILTER (WHERE day = CURRENT_DATE), 0)::integer AS daily,
+    COALESCE(SUM(sent), 0)::integer AS monthly
+FROM domain_usage
+    WHERE domain = $1
+    AND day >= date_trunc('month', CURRENT_DATE)
+`
+
+type GetDomainUsageRow struct {
+	Daily   int32
+	Monthly int32
+}
+
+func (q *Queries) GetDomainUsage(ctx context.Context, domain string) (GetDomainUsageRow, error) {
+	row := q.queryRow(ctx, q.getDomainUsageStmt, getDomainUsage, domain)
+	var i GetDomainUsageRow
+	err := row.Scan(&i.Daily, &i.Monthly)
+	return i, err
+}
+
+const getDomains = `-- name: GetDomains :many
+SELECT id, domain, created_at, dkim_private_key, dkim_public_key, retention_days, rate_per_second, rate_per_hour, ip_pool, dkim_ed25519_private_key, dkim_ed25519_public_key, dkim_signing, dkim_selector, dkim_ed25519_selector, dkim_headers, verified, return_path_domain, daily_quota, monthly_quota, deleted_at, purge_at, default_from_email, default_from_name, default_headers, open_tracking, click_tracking, footer_html, footer_text, sandbox, sandbox_bounce_percent, paused_at, paused_reason FROM domains
+`
+
+func (q *Queries) GetDomains(ctx context.Context) ([]Domain, error) {
+	rows, err := q.query(ctx, q.getDomainsStmt, getDomains)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Domain
+	for rows.Next() {
+		var i Domain
+		if err := rows.Scan(
+			&i.ID,
+			&i.Domain,
+			&i.CreatedAt,
+			&i.DkimPrivateKey,
+			&i.DkimPublicKey,
+			&i.RetentionDays,
+			&i.RatePerSecond,
+			&i.RatePerHour,
+			&i.IpPool,
+			&i.DkimEd25519PrivateKey,
+			&i.DkimEd25519PublicKey,
+			&i.DkimSigning,
+			&i.DkimSelector,
+			&i.DkimEd25519Selector,
+			pq.Array(&i.DkimHeaders),
+			&i.Verified,
+			&i.ReturnPathDomain,
+			&i.DailyQuota,
+			&i.MonthlyQuota,
+			&i.DeletedAt,
+			&i.PurgeAt,
+			&i.DefaultFromEmail,
+			&i.DefaultFromName,
+			&i.DefaultHeaders,
+			&i.OpenTracking,
+			&i.ClickTracking,
+			&i.FooterHtml,
+			&i.FooterText,
+			&i.Sandbox,
+			&i.SandboxBouncePercent,
+			&i.PausedAt,
+			&i.PausedReason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDomainsStats = `-- name: GetDomainsStats :many
+SELECT domain, type, SUM(count)::bigint AS count FROM stats
+    WHERE hour >= date_trunc('hour', $1::timestamptz) AND hour < $2
+    GROUP BY domain, type
+`
+
+type GetDomainsStatsParams struct {
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+type GetDomainsStatsRow struct {
+	Domain string
+	Type   string
+	Count  int64
+}
+
+func (q *Queries) GetDomainsStats(ctx context.Context, arg GetDomainsStatsParams) ([]GetDomainsStatsRow, error) {
+	rows, err := q.query(ctx, q.getDomainsStatsStmt, getDomainsStats, arg.StartTime, arg.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDomainsStatsRow
+	for rows.Next() {
+		var i GetDomainsStatsRow
+		if err := rows.Scan(&i.Domain, &i.Type, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDomainsToPurge = `-- name: GetDomainsToPurge :many
+SELECT id, domain, created_at, dkim_private_key, dkim_public_key, retention_days, rate_per_second, rate_per_hour, ip_pool, dkim_ed25519_private_key, dkim_ed25519_public_key, dkim_signing, dkim_selector, dkim_ed25519_selector, dkim_headers, verified, return_path_domain, daily_quota, monthly_quota, deleted_at, purge_at, default_from_email, default_from_name, default_headers, open_tracking, click_tracking, footer_html, footer_text, sandbox, sandbox_bounce_percent, paused_at, paused_reason FROM domains
+    WHERE deleted_at IS NOT NULL
+    AND purge_at <= NOW()
+`
+
+func (q *Queries) GetDomainsToPurge(ctx context.Context) ([]Domain, error) {
+	rows, err := q.query(ctx, q.getDomainsToPurgeStmt, getDomainsToPurge)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Domain
+	for rows.Next() {
+		var i Domain
+		if err := rows.Scan(
+			&i.ID,
+			&i.Domain,
+			&i.CreatedAt,
+			&i.DkimPrivateKey,
+			&i.DkimPublicKey,
+			&i.RetentionDays,
+			&i.RatePerSecond,
+			&i.RatePerHour,
+			&i.IpPool,
+			&i.DkimEd25519PrivateKey,
+			&i.DkimEd25519PublicKey,
+			&i.DkimSigning,
+			&i.DkimSelector,
+			&i.DkimEd25519Selector,
+			pq.Array(&i.DkimHeaders),
+			&i.Verified,
+			&i.ReturnPathDomain,
+			&i.DailyQuota,
+			&i.MonthlyQuota,
+			&i.DeletedAt,
+			&i.PurgeAt,
+			&i.DefaultFromEmail,
+			&i.DefaultFromName,
+			&i.DefaultHeaders,
+			&i.OpenTracking,
+			&i.ClickTracking,
+			&i.FooterHtml,
+			&i.FooterText,
+			&i.Sandbox,
+			&i.SandboxBouncePercent,
+			&i.PausedAt,
+			&i.PausedReason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEventDaysToExport = `-- name: GetEventDaysToExport :many
+SELECT DISTINCT date(me.timestamp AT TIME ZONE 'UTC') AS day FROM message_events AS me
+    JOIN messages AS m ON m.message_id = me.message_id
+    WHERE m.domain = $1
+    AND me.timestamp < $2
+    AND (me.timestamp >= $3 OR me.id > $4)
+    ORDER BY day
+`
+
+type GetEventDaysToExportParams struct {
+	Domain         string
+	Before         time.Time
+	ExportedBefore time.Time
+	LastEventID    int32
+}
+
+// the UTC days before @before with events of a domain not exported by the cursor:
+// the days after the exported ones, and the days of the events recorded after their export
+func (q *Queries) GetEventDaysToExport(ctx context.Context, arg GetEventDaysToExportParams) ([]time.Time, error) {
+	rows, err := q.query(ctx, q.getEventDaysToExportStmt, getEventDaysToExport,
+		arg.Domain,
+		arg.Before,
+		arg.ExportedBefore,
+		arg.LastEventID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []time.Time
+	for rows.Next() {
+		var day time.Time
+		if err := rows.Scan(&day); err != nil {
+			return nil, err
+		}
+		items = append(items, day)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEventExport = `-- name: GetEventExport :one
+SELECT domain, exported_before, last_event_id FROM event_exports
+    WHERE domain = $1
+`
+
+func (q *Queries) GetEventExport(ctx context.Context, domain string) (EventExport, error) {
+	row := q.queryRow(ctx, q.getEventExportStmt, getEventExport, domain)
+	var i EventExport
+	err := row.Scan(&i.Domain, &i.ExportedBefore, &i.LastEventID)
+	return i, err
+}
+
+const getFiringAlerts = `-- name: GetFiringAlerts :many
+SELECT rule, domain, value, threshold, message, fired_at FROM firing_alerts
+`
+
+func (q *Queries) GetFiringAlerts(ctx context.Context) ([]FiringAlert, error) {
+	rows, err := q.query(ctx, q.getFiringAlertsStmt, getFiringAlerts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FiringAlert
+	for rows.Next() {
+		var i FiringAlert
+		if err := rows.Scan(
+			&i.Rule,
+			&i.Domain,
+			&i.Value,
+			&i.Threshold,
+			&i.Message,
+			&i.FiredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLastMessageEventID = `-- name: GetLastMessageEventID :one
+SELECT COALESCE(MAX(id), 0)::int AS last_event_id FROM message_events
+`
+
+func (q *Queries) GetLastMessageEventID(ctx context.Context) (int32, error) {
+	row := q.queryRow(ctx, q.getLastMessageEventIDStmt, getLastMessageEventID)
+	var last_event_id int32
+	err := row.Scan(&last_event_id)
+	return last_event_id, err
+}
+
+const getMaintenance = `-- name: GetMaintenance :one
+SELECT id, halted, reason, changed_at FROM maintenance
+`
+
+func (q *Queries) GetMaintenance(ctx context.Context) (Maintenance, error) {
+	row := q.queryRow(ctx, q.getMaintenanceStmt, getMaintenance)
+	var i Maintenance
+	err := row.Scan(
+		&i.ID,
+		&i.Halted,
+		&i.Reason,
+		&i.ChangedAt,
+	)
+	return i, err
+}
+
+const getMessage = `-- name: GetMessage :one
+SELECT id, message_id, subject, sender_email, sender_alias, template_id, domain, cc, bcc, headers, reply_to, fields, template_version, idempotency_key, created_at, resent_from FROM messages
+    WHERE domain = $1 AND message_id = $2
+`
+
+type GetMessageParams struct {
+	Domain    string
+	MessageID string
+}
+
+func (q *Queries) GetMessage(ctx context.Context, arg GetMessageParams) (Message, error) {
+	row := q.queryRow(ctx, q.getMessageStmt, getMessage, arg.Domain, arg.MessageID)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.MessageID,
+		&i.Subject,
+		&i.SenderEmail,
+		&i.SenderAlias,
+		&i.TemplateID,
+		&i.Domain,
+		pq.Array(&i.Cc),
+		pq.Array(&i.Bcc),
+		&i.Headers,
+		&i.ReplyTo,
+		&i.Fields,
+		&i.TemplateVersion,
+		&i.IdempotencyKey,
+		&i.CreatedAt,
+		&i.ResentFrom,
+	)
+	return i, err
+}
+
+const getMessageAttachments = `-- name: GetMessageAttachments :many
+SELECT
+    id, message_id, filename, content, inline
+FROM attachments
+    WHERE message_id = $1
+    ORDER BY id
+`
+
+func (q *Queries) GetMessageAttachments(ctx context.Context, messageID int32) ([]Attachment, error) {
+	rows, err := q.query(ctx, q.getMessageAttachmentsStmt, getMessageAttachments, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Attachment
+	for rows.Next() {
+		var i Attachment
+		if err := rows.Scan(
+			&i.ID,
+			&i.MessageID,
+			&i.Filename,
+			&i.Content,
+			&i.Inline,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMessageEvents = `-- name: GetMessageEvents :many
+SELECT me.id, me.message_id, me.email, me.type, me.data, me.timestamp FROM message_events AS me
+    JOIN messages AS m ON m.message_id = me.message_id
+    WHERE m.domain = $1 AND m.message_id = $2
+    ORDER BY me.timestamp, me.id
+`
+
+type GetMessageEventsParams struct {
+	Domain    string
+	MessageID string
+}
+
+func (q *Queries) GetMessageEvents(ctx context.Context, arg GetMessageEventsParams) ([]MessageEvent, error) {
+	rows, err := q.query(ctx, q.getMessageEventsStmt, getMessageEvents, arg.Domain, arg.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MessageEvent
+	for rows.Next() {
+		var i MessageEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.MessageID,
+			&i.Email,
+			&i.Type,
+			&i.Data,
+			&i.Timestamp,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMessageRecipients = `-- name: GetMessageRecipients :many
+SELECT sp.id, sp.status, sp.scheduled_time, sp.original_scheduled_time, sp.trial, sp.email, sp.message_id, sp.error_msg, sp.error_code, sp.fields, sp.bounce_type, sp.priority, sp.dispatched_at, sp.trace_parent, sp.copies, sp.claimed_at, sp.quota_consumed FROM sending_pool_emails AS sp
+    JOIN messages AS m ON m.id = sp.message_id
+    WHERE m.domain = $1 AND m.message_id = $2
+    ORDER BY sp.id
+`
+
+type GetMessageRecipientsParams struct {
+	Domain    string
+	MessageID string
+}
+
+func (q *Queries) GetMessageRecipients(ctx context.Context, arg GetMessageRecipientsParams) ([]SendingPoolEmail, error) {
+	rows, err := q.query(ctx, q.getMessageRecipientsStmt, getMessageRecipients, arg.Domain, arg.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SendingPoolEmail
+	for rows.Next() {
+		var i SendingPoolEmail
+		if err := rows.Scan(
+			&i.ID,
+			&i.Status,
+			&i.ScheduledTime,
+			&i.OriginalScheduledTime,
+			&i.Trial,
+			&i.Email,
+			&i.MessageID,
+			&i.ErrorMsg,
+			&i.ErrorCode,
+			&i.Fields,
+			&i.BounceType,
+			&i.Priority,
+			&i.DispatchedAt,
+			&i.TraceParent,
+			&i.Copies,
+			&i.ClaimedAt,
+			&i.QuotaConsumed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMessageStats = `-- name: GetMessageStats :many
+SELECT type, SUM(count)::bigint AS count FROM stats
+    WHERE domain = $1 AND message_id = $2
+        AND hour >= date_trunc('hour', $3::timestamptz) AND hour < $4
+    GROUP BY type
+`
+
+type GetMessageStatsParams struct {
+	Domain    string
+	MessageID string
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+type GetMessageStatsRow struct {
+	Type  string
+	Count int64
+}
+
+func (q *Queries) GetMessageStats(ctx context.Context, arg GetMessageStatsParams) ([]GetMessageStatsRow, error) {
+	rows, err := q.query(ctx, q.getMessageStatsStmt, getMessageStats,
+		arg.Domain,
+		arg.MessageID,
+		arg.StartTime,
+		arg.EndTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetMessageStatsRow
+	for rows.Next() {
+		var i GetMessageStatsRow
+		if err := rows.Scan(&i.Type, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMessageTimeline = `-- name: GetMessageTimeline :many
+SELECT mt.id, mt.message_id, mt.email, mt.stage, mt.smtp_code, mt.smtp_response, mt.data, mt.timestamp FROM message_timeline AS mt
+    JOIN messages AS m ON m.message_id = mt.message_id
+    WHERE m.domain = $1 AND m.message_id = $2
+    ORDER BY mt.timestamp, mt.id
+`
+
+type GetMessageTimelineParams struct {
+	Domain    string
+	MessageID string
+}
+
+func (q *Queries) GetMessageTimeline(ctx context.Context, arg GetMessageTimelineParams) ([]MessageTimeline, error) {
+	rows, err := q.query(ctx, q.getMessageTimelineStmt, getMessageTimeline, arg.Domain, arg.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MessageTimeline
+	for rows.Next() {
+		var i MessageTimeline
+		if err := rows.Scan(
+			&i.ID,
+			&i.MessageID,
+			&i.Email,
+			&i.Stage,
+			&i.SmtpCode,
+			&i.SmtpResponse,
+			&i.Data,
+			&i.Timestamp,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getOldestPendingPoolEmail = `-- name: GetOldestPendingPoolEmail :one
+SELECT COALESCE(MIN(sp.scheduled_time), NOW())::timestamptz AS scheduled_time FROM sending_pool_emails AS sp
+    JOIN messages AS m ON m.id = sp.message_id
+    LEFT JOIN domains AS d ON d.domain = m.domain
+    WHERE sp.scheduled_time <= NOW() and sp.status = 'scheduled'
+    AND d.deleted_at IS NULL AND d.paused_at IS NULL
+`
+
+// emails of paused and deleted domains are not waiting to be dispatched
+func (q *Queries) GetOldestPendingPoolEmail(ctx context.Context) (time.Time, error) {
+	row := q.queryRow(ctx, q.getOldestPendingPoolEmailStmt, getOldestPendingPoolEmail)
+	var scheduled_time time.Time
+	err := row.Scan(&scheduled_time)
+	return scheduled_time, err
+}
+
+const getSMTPResponses = `-- name: GetSMTPResponses :many
+SELECT provider, code, enhanced_code, SUM(count)::bigint AS count FROM smtp_responses
+    WHERE domain = $1 AND hour >= date_trunc('hour', $2::timestamptz) AND hour < $3
+    AND ($4::varchar = '' OR provider = $4::varchar)
+    GROUP BY provider, code, enhanced_code
+    ORDER BY provider, count DESC, code, enhanced_code
+`
+
+type GetSMTPResponsesParams struct {
+	Domain    string
+	StartTime time.Time
+	EndTime   time.Time
+	Provider  string
+}
+
+type GetSMTPResponsesRow struct {
+	Provider     string
+	Code         int32
+	EnhancedCode string
+	Count        int64
+}
+
+func (q *Queries) GetSMTPResponses(ctx context.Context, arg GetSMTPResponsesParams) ([]GetSMTPResponsesRow, error) {
+	rows, err := q.query(ctx, q.getSMTPResponsesStmt, getSMTPResponses,
+		arg.Domain,
+		arg.StartTime,
+		arg.EndTime,
+		arg.Provider,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSMTPResponsesRow
+	for rows.Next() {
+		var i GetSMTPResponsesRow
+		if err := rows.Scan(
+			&i.Provider,
+			&i.Code,
+			&i.EnhancedCode,
+			&i.Count,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSendingData = `-- name: GetSendingData :one
+SELECT
+    t.html,
+    t.text,
+    m.domain,
+    d.dkim_private_key,
+    d.dkim_public_key,
+    d.dkim_ed25519_private_key,
+    d.dkim_signing,
+    d.dkim_selector,
+    d.dkim_ed25519_selector,
+    d.dkim_headers,
+    d.return_path_domain,
+    d.ip_pool,
+    d.default_from_email,
+    d.default_from_name,
+    d.default_headers,
+    d.open_tracking,
+    d.click_tracking,
+    d.footer_html,
+    d.footer_text,
+    d.sandbox,
+    d.sandbox_bounce_percent,
+    m.subject,
+    m.message_id,
+    m.sender_email,
+    m.sender_alias,
+    m.cc,
+    m.bcc,
+    m.headers,
+    m.reply_to,
+    m.fields
+FROM messages as m
+    JOIN templates as t ON t.template_id = m.template_id AND t.version = m.template_version
+    JOIN domains as d ON d.domain = m.domain
+    WHERE m.id=$1
+`
+
+type GetSendingDataRow struct {
+	Html                  string
+	Text                  string
+	Domain                string
+	DkimPrivateKey        string
+	DkimPublicKey         string
+	DkimEd25519PrivateKey string
+	DkimSigning           string
+	DkimSelector          string
+	DkimEd25519Selector   string
+	DkimHeaders           []string
+	ReturnPathDomain      string
+	IpPool                string
+	DefaultFromEmail      string
+	DefaultFromName       string
+	DefaultHeaders        json.RawMessage
+	OpenTracking          bool
+	ClickTracking         bool
+	FooterHtml            string
+	FooterText            string
+	Sandbox               bool
+	SandboxBouncePercent  int32
+	Subject               string
+	MessageID             string
+	SenderEmail           string
+	SenderAlias           string
+	Cc                    []string
+	Bcc                   []string
+	Headers               json.RawMessage
+	ReplyTo               string
+	Fields                json.RawMessage
+}
+
+func (q *Queries) GetSendingData(ctx context.Context, messageID int32) (GetSendingDataRow, error) {
+	row := q.queryRow(ctx, q.getSendingDataStmt, getSendingData, messageID)
+	var i GetSendingDataRow
+	err := row.Scan(
+		&i.Html,
+		&i.Text,
+		&i.Domain,
+		&i.DkimPrivateKey,
+		&i.DkimPublicKey,
+		&i.DkimEd25519PrivateKey,
+		&i.DkimSigning,
+		&i.DkimSelector,
+		&i.DkimEd25519Selector,
+		pq.Array(&i.DkimHeaders),
+		&i.ReturnPathDomain,
+		&i.IpPool,
+		&i.DefaultFromEmail,
+		&i.DefaultFromName,
+		&i.DefaultHeaders,
+		&i.OpenTracking,
+		&i.ClickTracking,
+		&i.FooterHtml,
+		&i.FooterText,
+		&i.Sandbox,
+		&i.SandboxBouncePercent,
+		&i.Subject,
+		&i.MessageID,
+		&i.SenderEmail,
+		&i.SenderAlias,
+		pq.Array(&i.Cc),
+		pq.Array(&i.Bcc),
+		&i.Headers,
+		&i.ReplyTo,
+		&i.Fields,
+	)
+	return i, err
+}
+
+const getStats = `-- name: GetStats :many
+SELECT type, SUM(count)::bigint AS count FROM stats
+    WHERE domain = $1 AND hour >= date_trunc('hour', $2::timestamptz) AND hour < $3
+    GROUP BY type
+`
+
+type GetStatsParams struct {
+	Domain    string
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+type GetStatsRow struct {
+	Type  string
+	Count int64
+}
+
+func (q *Queries) GetStats(ctx context.Context, arg GetStatsParams) ([]GetStatsRow, error) {
+	rows, err := q.query(ctx, q.getStatsStmt, getStats, arg.Domain, arg.StartTime, arg.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetStatsRow
+	for rows.Next() {
+		var i GetStatsRow
+		if err := rows.Scan(&i.Type, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSuppressions = `-- name: GetSuppressions :many
+SELECT id, domain, email, reason, created_at FROM suppressions
+    WHERE domain = $1
+    ORDER BY created_at DESC
+`
+
+func (q *Queries) GetSuppressions(ctx context.Context, domain string) ([]Suppression, error) {
+	rows, err := q.query(ctx, q.getSuppressionsStmt, getSuppressions, domain)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Suppression
+	for rows.Next() {
+		var i Suppression
+		if err := rows.Scan(
+			&i.ID,
+			&i.Domain,
+			&i.Email,
+			&i.Reason,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTemplates = `-- name: GetTemplates :many
+SELECT id, template_id, html, domain, text, version, active, name, deleted_at FROM templates
+    WHERE domain = $1
+    AND name <> ''
+    AND active
+    AND deleted_at IS NULL
+    ORDER BY name
+`
+
+// returns the active versions of the named templates of a domain
+func (q *Queries) GetTemplates(ctx context.Context, domain string) ([]Template, error) {
+	rows, err := q.query(ctx, q.getTemplatesStmt, getTemplates, domain)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Template
+	for rows.Next() {
+		var i Template
+		if err := rows.Scan(
+			&i.ID,
+			&i.TemplateID,
+			&i.Html,
+			&i.Domain,
+			&i.Text,
+			&i.Version,
+			&i.Active,
+			&i.Name,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWebhookDeliveries = `-- name: GetWebhookDeliveries :many
+SELECT wd.id, wd.webhook_id, wd.event, wd.payload, wd.status, wd.attempts, wd.next_attempt_time, wd.response_code, wd.error_msg, wd.created_at FROM webhook_deliveries AS wd
+    JOIN webhooks AS w ON w.id = wd.webhook_id
+    WHERE w.domain = $1 AND w.id = $2
+    ORDER BY wd.created_at DESC
+    LIMIT $3
+`
+
+type GetWebhookDeliveriesParams struct {
+	Domain    string
+	WebhookID int32
+	Max       int32
+}
+
+func (q *Queries) GetWebhookDeliveries(ctx context.Context, arg GetWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	rows, err := q.query(ctx, q.getWebhookDeliveriesStmt, getWebhookDeliveries, arg.Domain, arg.WebhookID, arg.Max)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookDelivery
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.WebhookID,
+			&i.Event,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.NextAttemptTime,
+			&i.ResponseCode,
+			&i.ErrorMsg,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWebhooks = `-- name: GetWebhooks :many
+SELECT id, domain, url, secret, events, created_at FROM webhooks
+    WHERE domain = $1
+    ORDER BY id
+`
+
+func (q *Queries) GetWebhooks(ctx context.Context, domain string) ([]Webhook, error) {
+	rows, err := q.query(ctx, q.getWebhooksStmt, getWebhooks, domain)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Webhook
+	for rows.Next() {
+		var i Webhook
+		if err := rows.Scan(
+			&i.ID,
+			&i.Domain,
+			&i.Url,
+			&i.Secret,
+			pq.Array(&i.Events),
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const incrementSMTPResponses = `-- name: IncrementSMTPResponses :exec
+INSERT INTO smtp_responses (domain, provider, code, enhanced_code, hour, count) VALUES
+    ($1, $2, $3, $4, date_trunc('hour', $5::timestamptz), 1)
+    ON CONFLICT (domain, provider, code, enhanced_code, hour) DO UPDATE
+    SET count = smtp_responses.count + 1
+`
+
+type IncrementSMTPResponsesParams struct {
+	Domain       string
+	Provider     string
+	Code         int32
+	EnhancedCode string
+	Timestamp    time.Time
+}
+
+func (q *Queries) IncrementSMTPResponses(ctx context.Context, arg IncrementSMTPResponsesParams) error {
+	_, err := q.exec(ctx, q.incrementSMTPResponsesStmt, incrementSMTPResponses,
+		arg.Domain,
+		arg.Provider,
+		arg.Code,
+		arg.EnhancedCode,
+		arg.Timestamp,
+	)
+	return err
+}
+
+const incrementStats = `-- name: IncrementStats :exec
+INSERT INTO stats (domain, message_id, type, hour, count) VALUES
+    ($1, $2, $3, date_trunc('hour', $4::timestamptz), 1)
+    ON CONFLICT (domain, message_id, type, hour) DO UPDATE
+    SET count = stats.count + 1
+`
+
+type IncrementStatsParams struct {
+	Domain    string
+	MessageID string
+	Type      string
+	Timestamp time.Time
+}
+
+func (q *Queries) IncrementStats(ctx context.Context, arg IncrementStatsParams) error {
+	_, err := q.exec(ctx, q.incrementStatsStmt, incrementStats,
+		arg.Domain,
+		arg.MessageID,
+		arg.Type,
+		arg.Timestamp,
+	)
+	return err
+}
+
+const isRecipientSuppressed = `-- name: IsRecipientSuppressed :one
+SELECT EXISTS (
+    SELECT 1 FROM suppressions AS s
+        JOIN messages AS m ON m.domain = s.domain
+        WHERE m.id = $1 AND s.email = lower($2::varchar)
+)
+`
+
+type IsRecipientSuppressedParams struct {
+	MessageID int32
+	Email     string
+}
+
+func (q *Queries) IsRecipientSuppressed(ctx context.Context, arg IsRecipientSuppressedParams) (bool, error) {
+	row := q.queryRow(ctx, q.isRecipientSuppressedStmt, isRecipientSuppressed, arg.MessageID, arg.Email)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const pauseDomain = `-- name: PauseDomain :one
+UPDATE domains
+    SET paused_at = NOW(), paused_reason = $1
+    WHERE domain = $2
+    AND deleted_at IS NULL
+    RETURNING id, domain, created_at, dkim_private_key, dkim_public_key, retention_days, rate_per_second, rate_per_hour, ip_pool, dkim_ed25519_private_key, dkim_ed25519_public_key, dkim_signing, dkim_selector, dkim_ed25519_selector, dkim_headers, verified, return_path_domain, daily_quota, monthly_quota, deleted_at, purge_at, default_from_email, default_from_name, default_headers, open_tracking, click_tracking, footer_html, footer_text, sandbox, sandbox_bounce_percent, paused_at, paused_reason
+`
+
+type PauseDomainParams struct {
+	PausedReason string
+	Domain       string
+}
+
+func (q *Queries) PauseDomain(ctx context.Context, arg PauseDomainParams) (Domain, error) {
+	row := q.queryRow(ctx, q.pauseDomainStmt, pauseDomain, arg.PausedReason, arg.Domain)
+	var i Domain
+	err := row.Scan(
+		&i.ID,
+		&i.Domain,
+		&i.CreatedAt,
+		&i.DkimPrivateKey,
+		&i.DkimPublicKey,
+		&i.RetentionDays,
+		&i.RatePerSecond,
+		&i.RatePerHour,
+		&i.IpPool,
+		&i.DkimEd25519PrivateKey,
+		&i.DkimEd25519PublicKey,
+		&i.DkimSigning,
+		&i.DkimSelector,
+		&i.DkimEd25519Selector,
+		pq.Array(&i.DkimHeaders),
+		&i.Verified,
+		&i.ReturnPathDomain,
+		&i.DailyQuota,
+		&i.MonthlyQuota,
+		&i.DeletedAt,
+		&i.PurgeAt,
+		&i.DefaultFromEmail,
+		&i.DefaultFromName,
+		&i.DefaultHeaders,
+		&i.OpenTracking,
+		&i.ClickTracking,
+		&i.FooterHtml,
+		&i.FooterText,
+		&i.Sandbox,
+		&i.SandboxBouncePercent,
+		&i.PausedAt,
+		&i.PausedReason,
+	)
+	return i, err
+}
+
+const prepareForSend = `-- name: PrepareForSend :many
+WITH usage AS (
+    SELECT
+        m.domain,
+        COUNT(*) FILTER (WHERE sp.dispatched_at > NOW() - interval '1 second') AS last_second,
+        COUNT(*) AS last_hour
+    FROM sending_pool_emails AS sp
+        JOIN messages AS m ON m.id = sp.message_id
+    WHERE sp.dispatched_at > NOW() - interval '1 hour'
+    GROUP BY m.domain
+), ranked AS (
+    -- position of the email in the queue of its domain, for the rate limits.
+    -- Rows are locked apart, FOR UPDATE is not allowed with window functions
+    SELECT
+        sp.id,
+        ROW_NUMBER() OVER (PARTITION BY m.domain ORDER BY sp.priority DESC, sp.scheduled_time) AS rn
+    FROM sending_pool_emails AS sp
+        JOIN messages AS m ON m.id = sp.message_id
+    WHERE sp.scheduled_time <= NOW() and sp.status = 'scheduled'
+), locked AS (
+    -- rows claimed by other dispatchers are skipped, a batch of the emails
+    -- with the highest priority that can be sent is locked
+    SELECT sp.id FROM sending_pool_emails AS sp
+        JOIN ranked AS r ON r.id = sp.id
+        JOIN messages AS m ON m.id = sp.message_id
+        LEFT JOIN domains AS d ON d.domain = m.domain
+        LEFT JOIN usage AS u ON u.domain = m.domain
+    WHERE sp.scheduled_time <= NOW() and sp.status = 'scheduled'
+        -- emails of deleted domains are never sent,
+        -- emails of paused domains wait until they are resumed
+        AND d.deleted_at IS NULL AND d.paused_at IS NULL
+        AND (COALESCE(d.rate_per_second, 0) = 0 OR r.rn <= d.rate_per_second - COALESCE(u.last_second, 0))
+        AND (COALESCE(d.rate_per_hour, 0) = 0 OR r.rn <= d.rate_per_hour - COALESCE(u.last_hour, 0))
+    ORDER BY sp.priority DESC, sp.scheduled_time
+    LIMIT $1
+    FOR UPDATE OF sp SKIP LOCKED
+)
+UPDATE sending_pool_emails AS sp
+    SET status = 'sending', dispatched_at = NOW(), claimed_at = NOW()
+    FROM locked AS l
+    WHERE sp.id = l.id
+    RETURNING sp.id, sp.status, sp.scheduled_time, sp.original_scheduled_time, sp.trial, sp.email, sp.message_id, sp.error_msg, sp.error_code, sp.fields, sp.bounce_type, sp.priority, sp.dispatched_at, sp.trace_parent, sp.copies, sp.claimed_at, sp.quota_consumed
+`
+
+func (q *Queries) PrepareForSend(ctx context.Context, limit int32) ([]SendingPoolEmail, error) {
+	rows, err := q.query(ctx, q.prepareForSendStmt, prepareForSend, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SendingPoolEmail
+	for rows.Next() {
+		var i SendingPoolEmail
+		if err := rows.Scan(
+			&i.ID,
+			&i.Status,
+			&i.ScheduledTime,
+			&i.OriginalScheduledTime,
+			&i.Trial,
+			&i.Email,
+			&i.MessageID,
+			&i.ErrorMsg,
+			&i.ErrorCode,
+			&i.Fields,
+			&i.BounceType,
+			&i.Priority,
+			&i.DispatchedAt,
+			&i.TraceParent,
+			&i.Copies,
+			&i.ClaimedAt,
+			&i.QuotaConsumed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const prepareWebhookDeliveries = `-- name: PrepareWebhookDeliveries :many
+UPDATE webhook_deliveries AS wd
+    SET next_attempt_time = $1
+    FROM (
+            -- deliveries claimed by other webhooks daemons are skipped
+            SELECT id FROM webhook_deliveries
+            WHERE next_attempt_time <= NOW() AND status = 'pending'
+            ORDER BY next_attempt_time
+            LIMIT $2
+            FOR UPDATE SKIP LOCKED
+        ) AS t, webhooks AS w
+    WHERE wd.id = t.id AND w.id = wd.webhook_id
+    RETURNING wd.id, wd.event, wd.payload, wd.attempts, w.url, w.secret
+`
+
+type PrepareWebhookDeliveriesParams struct {
+	LeaseUntil time.Time
+	Max        int32
+}
+
+type PrepareWebhookDeliveriesRow struct {
+	ID       int32
+	Event    string
+	Payload  json.RawMessage
+	Attempts int32
+	Url      string
+	Secret   string
+}
+
+func (q *Queries) PrepareWebhookDeliveries(ctx context.Context, arg PrepareWebhookDeliveriesParams) ([]PrepareWebhookDeliveriesRow, error) {
+	rows, err := q.query(ctx, q.prepareWebhookDeliveriesStmt, prepareWebhookDeliveries, arg.LeaseUntil, arg.Max)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PrepareWebhookDeliveriesRow
+	for rows.Next() {
+		var i PrepareWebhookDeliveriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Event,
+			&i.Payload,
+			&i.Attempts,
+			&i.Url,
+			&i.Secret,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const promoteDKIMKey = `-- name: PromoteDKIMKey :one
+WITH pending AS (
+    DELETE FROM dkim_keys
+    WHERE dkim_keys.domain = $1
+        AND dkim_keys.selector = $2
+        AND dkim_keys.status = 'pending'
+    RETURNING id, domain, selector, algorithm, private_key, public_key, status, retire_at, created_at
+), retiring AS (
+    INSERT INTO dkim_keys (domain, selector, algorithm, private_key, public_key, status, retire_at)
+    SELECT
+        d.domain,
+        CASE WHEN p.algorithm = 'rsa' THEN d.dkim_selector ELSE d.dkim_ed25519_selector END,
+        p.algorithm,
+        CASE WHEN p.algorithm = 'rsa' THEN d.dkim_private_key ELSE d.dkim_ed25519_private_key END,
+        CASE WHEN p.algorithm = 'rsa' THEN d.dkim_public_key ELSE d.dkim_ed25519_public_key END,
+        'retiring',
+        $3::timestamptz
+    FROM domains as d
+        JOIN pending as p ON p.domain = d.domain
+        WHERE p.algorithm = 'rsa' OR d.dkim_ed25519_private_key <> ''
+)
+UPDATE domains
+    SET dkim_selector = CASE WHEN p.algorithm = 'rsa' THEN p.selector ELSE domains.dkim_selector END,
+        dkim_private_key = CASE WHEN p.algorithm = 'rsa' THEN p.private_key ELSE domains.dkim_private_key END,
+        dkim_public_key = CASE WHEN p.algorithm = 'rsa' THEN p.public_key ELSE domains.dkim_public_key END,
+        dkim_ed25519_selector = CASE WHEN p.algorithm = 'ed25519' THEN p.selector ELSE domains.dkim_ed25519_selector END,
+        dkim_ed25519_private_key = CASE WHEN p.algorithm = 'ed25519' THEN p.private_key ELSE domains.dkim_ed25519_private_key END,
+        dkim_ed25519_public_key = CASE WHEN p.algorithm = 'ed25519' THEN p.public_key ELSE domains.dkim_ed25519_public_key END
+    FROM pending as p
+    WHERE domains.domain = p.domain
+    RETURNING domains.id, domains.domain, domains.created_at, domains.dkim_private_key, domains.dkim_public_key, domains.retention_days, domains.rate_per_second, domains.rate_per_hour, domains.ip_pool, domains.dkim_ed25519_private_key, domains.dkim_ed25519_public_key, domains.dkim_signing, domains.dkim_selector, domains.dkim_ed25519_selector, domains.dkim_headers, domains.verified, domains.return_path_domain, domains.daily_quota, domains.monthly_quota, domains.deleted_at, domains.purge_at, domains.default_from_email, domains.default_from_name, domains.default_headers, domains.open_tracking, domains.click_tracking, domains.footer_html, domains.footer_text, domains.sandbox, domains.sandbox_bounce_percent, domains.paused_at, domains.paused_reason
+`
+
+type PromoteDKIMKeyParams struct {
+	Domain   string
+	Selector string
+	RetireAt time.Time
+}
+
+// the pending key becomes the active key of its algorithm,
+// the previous active key is retired at retire_at
+func (q *Queries) PromoteDKIMKey(ctx context.Context, arg PromoteDKIMKeyParams) (Domain, error) {
+	row := q.queryRow(ctx, q.promoteDKIMKeyStmt, promoteDKIMKey, arg.Domain, arg.Selector, arg.RetireAt)
+	var i Domain
+	err := row.Scan(
+		&i.ID,
+		&i.Domain,
+		&i.CreatedAt,
+		&i.DkimPrivateKey,
+		&i.DkimPublicKey,
+		&i.RetentionDays,
+		&i.RatePerSecond,
+		&i.RatePerHour,
+		&i.IpPool,
+		&i.DkimEd25519PrivateKey,
+		&i.DkimEd25519PublicKey,
+		&i.DkimSigning,
+		&i.DkimSelector,
+		&i.DkimEd25519Selector,
+		pq.Array(&i.DkimHeaders),
+		&i.Verified,
+		&i.ReturnPathDomain,
+		&i.DailyQuota,
+		&i.MonthlyQuota,
+		&i.DeletedAt,
+		&i.PurgeAt,
+		&i.DefaultFromEmail,
+		&i.DefaultFromName,
+		&i.DefaultHeaders,
+		&i.OpenTracking,
+		&i.ClickTracking,
+		&i.FooterHtml,
+		&i.FooterText,
+		&i.Sandbox,
+		&i.SandboxBouncePercent,
+		&i.PausedAt,
+		&i.PausedReason,
+	)
+	return i, err
+}
+
+const purgeAPIKeyCalls = `-- name: PurgeAPIKeyCalls :execrows
+DELETE FROM api_key_calls
+    WHERE domain = $1
+    AND called_at < $2
+`
+
+type PurgeAPIKeyCallsParams struct {
+	Domain string
+	Before time.Time
+}
+
+func (q *Queries) PurgeAPIKeyCalls(ctx context.Context, arg PurgeAPIKeyCallsParams) (int64, error) {
+	result, err := q.exec(ctx, q.purgeAPIKeyCallsStmt, purgeAPIKeyCalls, arg.Domain, arg.Before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const purgeDomain = `-- name: PurgeDomain :one
+WITH purged AS (
+    SELECT pd.domain FROM domains AS pd
+        WHERE pd.domain = $1
+        AND pd.deleted_at IS NOT NULL
+        AND pd.purge_at <= NOW()
+), deleted_templates AS (
+    DELETE FROM templates AS t USING purged AS p
+        WHERE t.domain = p.domain
+        RETURNING t.id
+), deleted_stats AS (
+    DELETE FROM stats AS s USING purged AS p
+        WHERE s.domain = p.domain
+        RETURNING s.id
+), deleted_smtp_responses AS (
+    DELETE FROM smtp_responses AS r USING purged AS p
+        WHERE r.domain = p.domain
+        RETURNING r.id
+), deleted_suppressions AS (
+    DELETE FROM suppressions AS s USING purged AS p
+        WHERE s.domain = p.domain
+        RETURNING s.id
+), deleted_webhook_deliveries AS (
+    DELETE FROM webhook_deliveries AS wd USING webhooks AS w, purged AS p
+        WHERE wd.webhook_id = w.id AND w.domain = p.domain
+        RETURNING wd.id
+), deleted_webhooks AS (
+    DELETE FROM webhooks AS w USING purged AS p
+        WHERE w.domain = p.domain
+        RETURNING w.id
+), deleted_dkim_keys AS (
+    DELETE FROM dkim_keys AS k USING purged AS p
+        WHERE k.domain = p.domain
+        RETURNING k.id
+), deleted_records AS (
+    DELETE FROM domain_records AS r USING purged AS p
+        WHERE r.domain = p.domain
+        RETURNING r.id
+), deleted_usage AS (
+    DELETE FROM domain_usage AS u USING purged AS p
+        WHERE u.domain = p.domain
+        RETURNING u.id
+), deleted_api_keys AS (
+    DELETE FROM api_keys AS k USING purged AS p
+        WHERE k.domain = p.domain
+        RETURNING k.id
+), deleted_api_key_calls AS (
+    DELETE FROM api_key_calls AS c USING purged AS p
+        WHERE c.domain = p.domain
+        RETURNING c.id
+), deleted_dead_letters AS (
+    DELETE FROM dead_letters AS dl USING purged AS p
+        WHERE dl.domain = p.domain
+        RETURNING dl.id
+), deleted_dmarc_records AS (
+    DELETE FROM dmarc_records AS dr USING dmarc_reports AS r, purged AS p
+        WHERE dr.report_id = r.id AND r.domain = p.domain
+        RETURNING dr.id
+), deleted_dmarc_reports AS (
+    DELETE FROM dmarc_reports AS r USING purged AS p
+        WHERE r.domain = p.domain
+        RETURNING r.id
+), deleted_domains AS (
+    DELETE FROM domains AS d USING purged AS p
+        WHERE d.domain = p.domain
+        RETURNING d.id
+)
+SELECT
+    (SELECT COUNT(*) FROM deleted_domains) AS domains,
+    (SELECT COUNT(*) FROM deleted_templates) AS templates,
+    (SELECT COUNT(*) FROM deleted_stats) AS stats,
+    (SELECT COUNT(*) FROM deleted_smtp_responses) AS smtp_responses,
+    (SELECT COUNT(*) FROM deleted_suppressions) AS suppressions,
+    (SELECT COUNT(*) FROM deleted_webhooks) AS webhooks,
+    (SELECT COUNT(*) FROM deleted_webhook_deliveries) AS webhook_deliveries,
+    (SELECT COUNT(*) FROM deleted_dkim_keys) AS dkim_keys,
+    (SELECT COUNT(*) FROM deleted_api_keys) AS api_keys,
+    (SELECT COUNT(*) FROM deleted_api_key_calls) AS api_key_calls,
+    (SELECT COUNT(*) FROM deleted_dead_letters) AS dead_letters,
+    (SELECT COUNT(*) FROM deleted_dmarc_reports) AS dmarc_reports
+`
+
+type PurgeDomainRow struct {
+	Domains           int64
+	Templates         int64
+	Stats             int64
+	SmtpResponses     int64
+	Suppressions      int64
+	Webhooks          int64
+	WebhookDeliveries int64
+	DkimKeys          int64
+	ApiKeys           int64
+	ApiKeyCalls       int64
+	DeadLetters       int64
+	DmarcReports      int64
+}
+
+// deletes the domain and the rest of its data, its messages must
+// be purged first. Statements in WITH run even when not counted
+func (q *Queries) PurgeDomain(ctx context.Context, domain string) (PurgeDomainRow, error) {
+	row := q.queryRow(ctx, q.purgeDomainStmt, purgeDomain, domain)
+	var i PurgeDomainRow
+	err := row.Scan(
+		&i.Domains,
+		&i.Templates,
+		&i.Stats,
+		&i.SmtpResponses,
+		&i.Suppressions,
+		&i.Webhooks,
+		&i.WebhookDeliveries,
+		&i.DkimKeys,
+		&i.ApiKeys,
+		&i.ApiKeyCalls,
+		&i.DeadLetters,
+		&i.DmarcReports,
+	)
+	return i, err
+}
+
+const purgeMessages = `-- name: PurgeMessages :one
+WITH expired AS (
+    SELECT m.id, m.message_id FROM messages AS m
+        WHERE m.domain = $1 AND m.created_at < $2
+        AND NOT EXISTS (
+            SELECT 1 FROM sending_pool_emails AS sp
+                WHERE sp.message_id = m.id AND sp.status IN ('initializing', 'scheduled', 'sending')
+        )
+        LIMIT $3
+), deleted_emails AS (
+    DELETE FROM sending_pool_emails AS sp USING expired AS e
+        WHERE sp.message_id = e.id
+        RETURNING sp.id
+), deleted_attachments AS (
+    DELETE FROM attachments AS a USING expired AS e
+        WHERE a.message_id = e.id
+        RETURNING a.id
+), deleted_opens AS (
+    DELETE FROM opens AS o USING expired AS e
+        WHERE o.message_id = e.message_id
+        RETURNING o.id
+), deleted_complaints AS (
+    DELETE FROM complaints AS c USING expired AS e
+        WHERE c.message_id = e.message_id
+        RETURNING c.id
+), deleted_events AS (
+    DELETE FROM message_events AS me USING expired AS e
+        WHERE me.message_id = e.message_id
+        RETURNING me.id
+), deleted_timeline AS (
+    DELETE FROM message_timeline AS mt USING expired AS e
+        WHERE mt.message_id = e.message_id
+        RETURNING mt.id
+), deleted_counted_events AS (
+    DELETE FROM counted_events AS ce USING expired AS e
+        WHERE ce.message_id = e.message_id
+), deleted_messages AS (
+    DELETE FROM messages AS m USING expired AS e
+        WHERE m.id = e.id
+        RETURNING m.id
+)
+SELECT
+    (SELECT COUNT(*) FROM deleted_messages) AS messages,
+    (SELECT COUNT(*) FROM deleted_emails) AS emails,
+    (SELECT COUNT(*) FROM deleted_attachments) AS attachments,
+    (SELECT COUNT(*) FROM deleted_opens) AS opens,
+    (SELECT COUNT(*) FROM deleted_complaints) AS complaints,
+    (SELECT COUNT(*) FROM deleted_events) AS events,
+    (SELECT COUNT(*) FROM deleted_timeline) AS timeline
+`
+
+type PurgeMessagesParams struct {
+	Domain string
+	Before time.Time
+	Max    int32
+}
+
+type PurgeMessagesRow struct {
+	Messages    int64
+	Emails      int64
+	Attachments int64
+	Opens       int64
+	Complaints  int64
+	Events      int64
+	Timeline    int64
+}
+
+func (q *Queries) PurgeMessages(ctx context.Context, arg PurgeMessagesParams) (PurgeMessagesRow, error) {
+	row := q.queryRow(ctx, q.purgeMessagesStmt, purgeMessages, arg.Domain, arg.Before, arg.Max)
+	var i PurgeMessagesRow
+	err := row.Scan(
+		&i.Messages,
+		&i.Emails,
+		&i.Attachments,
+		&i.Opens,
+		&i.Complaints,
+		&i.Events,
+		&i.Timeline,
+	)
+	return i, err
+}
+
+const purgeWebhookDeliveries = `-- name: PurgeWebhookDeliveries :execrows
+DELETE FROM webhook_deliveries AS wd
+    USING webhooks AS w
+    WHERE w.id = wd.webhook_id AND w.domain = $1
+        AND wd.created_at < $2 AND wd.status <> 'pending'
+`
+
+type PurgeWebhookDeliveriesParams struct {
+	Domain string
+	Before time.Time
+}
+
+func (q *Queries) PurgeWebhookDeliveries(ctx context.Context, arg PurgeWebhookDeliveriesParams) (int64, error) {
+	result, err := q.exec(ctx, q.purgeWebhookDeliveriesStmt, purgeWebhookDeliveries, arg.Domain, arg.Before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const reclaimSendingPoolEmails = `-- name: ReclaimSendingPoolEmails :execrows
+UPDATE sending_pool_emails
+    SET status = 'scheduled', claimed_at = NULL
+    WHERE status = 'sending' AND claimed_at < $1
+`
+
+func (q *Queries) ReclaimSendingPoolEmails(ctx context.Context, claimedBefore sql.NullTime) (int64, error) {
+	result, err := q.exec(ctx, q.reclaimSendingPoolEmailsStmt, reclaimSendingPoolEmails, claimedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const releaseDeadLetter = `-- name: ReleaseDeadLetter :exec
+UPDATE dead_letters
+    SET requeued_at = NULL
+    WHERE id = $1
+`
+
+func (q *Queries) ReleaseDeadLetter(ctx context.Context, id int32) error {
+	_, err := q.exec(ctx, q.releaseDeadLetterStmt, releaseDeadLetter, id)
+	return err
+}
+
+const releaseSendingPoolEmail = `-- name: ReleaseSendingPoolEmail :exec
+UPDATE sending_pool_emails
+    SET status = 'scheduled', claimed_at = NULL, scheduled_time = $1
+    WHERE id = $2 AND status = 'sending'
+`
+
+type ReleaseSendingPoolEmailParams struct {
+	ScheduledTime time.Time
+	ID            int32
+}
+
+func (q *Queries) ReleaseSendingPoolEmail(ctx context.Context, arg ReleaseSendingPoolEmailParams) error {
+	_, err := q.exec(ctx, q.releaseSendingPoolEmailStmt, releaseSendingPoolEmail, arg.ScheduledTime, arg.ID)
+	return err
+}
+
+const requeueSendingPoolEmail = `-- name: RequeueSendingPoolEmail :execrows
+UPDATE sending_pool_emails AS sp
+    SET status = 'scheduled', trial = 0, scheduled_time = NOW()
+    FROM messages AS m
+    WHERE m.id = sp.message_id AND m.message_id = $1 AND sp.email = $2
+        AND sp.status = 'error' AND sp.bounce_type = 'soft'
+`
+
+type RequeueSendingPoolEmailParams struct {
+	MessageID string
+	Email     string
+}
+
+func (q *Queries) RequeueSendingPoolEmail(ctx context.Context, arg RequeueSendingPoolEmailParams) (int64, error) {
+	result, err := q.exec(ctx, q.requeueSendingPoolEmailStmt, requeueSendingPoolEmail, arg.MessageID, arg.Email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const resolveAlert = `-- name: ResolveAlert :execrows
+DELETE FROM firing_alerts
+    WHERE rule = $1 AND domain = $2
+`
+
+type ResolveAlertParams struct {
+	Rule   string
+	Domain string
+}
+
+func (q *Queries) ResolveAlert(ctx context.Context, arg ResolveAlertParams) (int64, error) {
+	result, err := q.exec(ctx, q.resolveAlertStmt, resolveAlert, arg.Rule, arg.Domain)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreDomain = `-- name: RestoreDomain :one
+UPDATE domains
+    SET deleted_at = NULL, purge_at = NULL
+    WHERE domain = $1
+    AND deleted_at IS NOT NULL
+    AND purge_at > NOW()
+    RETURNING id, domain, created_at, dkim_private_key, dkim_public_key, retention_days, rate_per_second, rate_per_hour, ip_pool, dkim_ed25519_private_key, dkim_ed25519_public_key, dkim_signing, dkim_selector, dkim_ed25519_selector, dkim_headers, verified, return_path_domain, daily_quota, monthly_quota, deleted_at, purge_at, default_from_email, default_from_name, default_headers, open_tracking, click_tracking, footer_html, footer_text, sandbox, sandbox_bounce_percent, paused_at, paused_reason
+`
+
+func (q *Queries) RestoreDomain(ctx context.Context, domain string) (Domain, error) {
+	row := q.queryRow(ctx, q.restoreDomainStmt, restoreDomain, domain)
+	var i Domain
+	err := row.Scan(
+		&i.ID,
+		&i.Domain,
+		&i.CreatedAt,
+		&i.DkimPrivateKey,
+		&i.DkimPublicKey,
+		&i.RetentionDays,
+		&i.RatePerSecond,
+		&i.RatePerHour,
+		&i.IpPool,
+		&i.DkimEd25519PrivateKey,
+		&i.DkimEd25519PublicKey,
+		&i.DkimSigning,
+		&i.DkimSelector,
+		&i.DkimEd25519Selector,
+		pq.Array(&i.DkimHeaders),
+		&i.Verified,
+		&i.ReturnPathDomain,
+		&i.DailyQuota,
+		&i.MonthlyQuota,
+		&i.DeletedAt,
+		&i.PurgeAt,
+		&i.DefaultFromEmail,
+		&i.DefaultFromName,
+		&i.DefaultHeaders,
+		&i.OpenTracking,
+		&i.ClickTracking,
+		&i.FooterHtml,
+		&i.FooterText,
+		&i.Sandbox,
+		&i.SandboxBouncePercent,
+		&i.PausedAt,
+		&i.PausedReason,
+	)
+	return i, err
+}
+
+const resumeDomain = `-- name: ResumeDomain :one
+UPDATE domains
+    SET paused_at = NULL, paused_reason = ''
+    WHERE domain = $1
+    AND deleted_at IS NULL
+    RETURNING id, domain, created_at, dkim_private_key, dkim_public_key, retention_days, rate_per_second, rate_per_hour, ip_pool, dkim_ed25519_private_key, dkim_ed25519_public_key, dkim_signing, dkim_selector, dkim_ed25519_selector, dkim_headers, verified, return_path_domain, daily_quota, monthly_quota, deleted_at, purge_at, default_from_email, default_from_name, default_headers, open_tracking, click_tracking, footer_html, footer_text, sandbox, sandbox_bounce_percent, paused_at, paused_reason
+`
+
+func (q *Queries) ResumeDomain(ctx context.Context, domain string) (Domain, error) {
+	row := q.queryRow(ctx, q.resumeDomainStmt, resumeDomain, domain)
+	var i Domain
+	err := row.Scan(
+		&i.ID,
+		&i.Domain,
+		&i.CreatedAt,
+		&i.DkimPrivateKey,
+		&i.DkimPublicKey,
+		&i.RetentionDays,
+		&i.RatePerSecond,
+		&i.RatePerHour,
+		&i.IpPool,
+		&i.DkimEd25519PrivateKey,
+		&i.DkimEd25519PublicKey,
+		&i.DkimSigning,
+		&i.DkimSelector,
+		&i.DkimEd25519Selector,
+		pq.Array(&i.DkimHeaders),
+		&i.Verified,
+		&i.ReturnPathDomain,
+		&i.DailyQuota,
+		&i.MonthlyQuota,
+		&i.DeletedAt,
+		&i.PurgeAt,
+		&i.DefaultFromEmail,
+		&i.DefaultFromName,
+		&i.DefaultHeaders,
+		&i.OpenTracking,
+		&i.ClickTracking,
+		&i.FooterHtml,
+		&i.FooterText,
+		&i.Sandbox,
+		&i.SandboxBouncePercent,
+		&i.PausedAt,
+		&i.PausedReason,
+	)
+	return i, err
+}
+
+const retireDKIMKeys = `-- name: RetireDKIMKeys :many
+UPDATE dkim_keys
+    SET status = 'retired',
+        private_key = ''
+    WHERE status = 'retiring'
+    AND retire_at <= now()
+    RETURNING id, domain, selector, algorithm, private_key, public_key, status, retire_at, created_at
+`
+
+// retired keys can't sign and their DNS records can be removed
+func (q *Queries) RetireDKIMKeys(ctx context.Context) ([]DkimKey, error) {
+	rows, err := q.query(ctx, q.retireDKIMKeysStmt, retireDKIMKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DkimKey
+	for rows.Next() {
+		var i DkimKey
+		if err := rows.Scan(
+			&i.ID,
+			&i.Domain,
+			&i.Selector,
+			&i.Algorithm,
+			&i.PrivateKey,
+			&i.PublicKey,
+			&i.Status,
+			&i.RetireAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeAPIKey = `-- name: RevokeAPIKey :one
+UPDATE api_keys
+    SET revoked_at = NOW()
+    WHERE domain = $1
+    AND id = $2
+    AND revoked_at IS NULL
+    RETURNING id, domain, name, prefix, key_hash, scopes, created_at, last_used_at, revoked_at, expires_at
+`
+
+type RevokeAPIKeyParams struct {
+	Domain string
+	ID     int32
+}
+
+func (q *Queries) RevokeAPIKey(ctx context.Context, arg RevokeAPIKeyParams) (ApiKey, error) {
+	row := q.queryRow(ctx, q.revokeAPIKeyStmt, revokeAPIKey, arg.Domain, arg.ID)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.Domain,
+		&i.Name,
+		&i.Prefix,
+		&i.KeyHash,
+		pq.Array(&i.Scopes),
+		&i.CreatedAt,
+		&i.LastUsedAt,
+		&i.RevokedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const revokeAPIKeysByName = `-- name: RevokeAPIKeysByName :exec
+UPDATE api_keys
+    SET revoked_at = NOW()
+    WHERE domain = $1
+    AND name = $2
+    AND revoked_at IS NULL
+`
+
+type RevokeAPIKeysByNameParams struct {
+	Domain string
+	Name   string
+}
+
+func (q *Queries) RevokeAPIKeysByName(ctx context.Context, arg RevokeAPIKeysByNameParams) error {
+	_, err := q.exec(ctx, q.revokeAPIKeysByNameStmt, revokeAPIKeysByName, arg.Domain, arg.Name)
+	return err
+}
+
+const searchMessages = `-- name: SearchMessages :many
+SELECT
+    sp.id,
+    m.message_id,
+    m.domain,
+    m.template_id,
+    m.subject,
+    m.sender_email,
+    sp.email,
+    sp.status,
+    sp.bounce_type,
+    sp.error_msg,
+    m.created_at
+FROM sending_pool_emails AS sp
+    JOIN messages AS m ON m.id = sp.message_id
+WHERE ($1::varchar = '' OR m.domain = $1::varchar)
+    AND ($2::varchar = '' OR sp.email = $2::varchar)
+    AND ($3::varchar = '' OR m.template_id = $3::varchar)
+    AND ($4::varchar = '' OR sp.status::varchar = $4::varchar)
+    AND m.created_at >= $5 AND m.created_at < $6
+    AND ($7::integer = 0 OR sp.id < $7::integer)
+ORDER BY sp.id DESC
+LIMIT $8
+`
+
+type SearchMessagesParams struct {
+	Domain     string
+	Email      string
+	TemplateID string
+	Status     string
+	StartTime  time.Time
+	EndTime    time.Time
+	Cursor     int32
+	Max        int32
+}
+
+type SearchMessagesRow struct {
+	ID          int32
+	MessageID   string
+	Domain      string
+	TemplateID  string
+	Subject     string
+	SenderEmail string
+	Email       string
+	Status      SendingPoolStatus
+	BounceType  BounceType
+	ErrorMsg    string
+	CreatedAt   time.Time
+}
+
+func (q *Queries) SearchMessages(ctx context.Context, arg SearchMessagesParams) ([]SearchMessagesRow, error) {
+	rows, err := q.query(ctx, q.searchMessagesStmt, searchMessages,
+		arg.Domain,
+		arg.Email,
+		arg.TemplateID,
+		arg.Status,
+		arg.StartTime,
+		arg.EndTime,
+		arg.Cursor,
+		arg.Max,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchMessagesRow
+	for rows.Next() {
+		var i SearchMessagesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.MessageID,
+			&i.Domain,
+			&i.TemplateID,
+			&i.Subject,
+			&i.SenderEmail,
+			&i.Email,
+			&i.Status,
+			&i.BounceType,
+			&i.ErrorMsg,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setActiveTemplateVersion = `-- name: SetActiveTemplateVersion :execrows
+UPDATE templates
+    SET active = (version = $1)
+    WHERE template_id = $2
+    AND domain = $3
+`
+
+type SetActiveTemplateVersionParams struct {
+	Version    int32
+	TemplateID string
+	Domain     string
+}
+
+func (q *Queries) SetActiveTemplateVersion(ctx context.Context, arg SetActiveTemplateVersionParams) (int64, error) {
+	result, err := q.exec(ctx, q.setActiveTemplateVersionStmt, setActiveTemplateVersion, arg.Version, arg.TemplateID, arg.Domain)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setDomainDKIMHeaders = `-- name: SetDomainDKIMHeaders :one
+UPDATE domains
+    SET dkim_headers = $1
+    WHERE domain = $2
+    RETURNING id, domain, created_at, dkim_private_key, dkim_public_key, retention_days, rate_per_second, rate_per_hour, ip_pool, dkim_ed25519_private_key, dkim_ed25519_public_key, dkim_signing, dkim_selector, dkim_ed25519_selector, dkim_headers, verified, return_path_domain, daily_quota, monthly_quota, deleted_at, purge_at, default_from_email, default_from_name, default_headers, open_tracking, click_tracking, footer_html, footer_text, sandbox, sandbox_bounce_percent, paused_at, paused_reason
+`
+
+type SetDomainDKIMHeadersParams struct {
+	DkimHeaders []string
+	Domain      string
+}
+
+func (q *Queries) SetDomainDKIMHeaders(ctx context.Context, arg SetDomainDKIMHeadersParams) (Domain, error) {
+	row := q.queryRow(ctx, q.setDomainDKIMHeadersStmt, setDomainDKIMHeaders, pq.Array(arg.DkimHeaders), arg.Domain)
+	var i Domain
+	err := row.Scan(
+		&i.ID,
+		&i.Domain,
+		&i.CreatedAt,
+		&i.DkimPrivateKey,
+		&i.DkimPublicKey,
+		&i.RetentionDays,
+		&i.RatePerSecond,
+		&i.RatePerHour,
+		&i.IpPool,
+		&i.DkimEd25519PrivateKey,
+		&i.DkimEd25519PublicKey,
+		&i.DkimSigning,
+		&i.DkimSelector,
+		&i.DkimEd25519Selector,
+		pq.Array(&i.DkimHeaders),
+		&i.Verified,
+		&i.ReturnPathDomain,
+		&i.DailyQuota,
+		&i.MonthlyQuota,
+		&i.DeletedAt,
+		&i.PurgeAt,
+		&i.DefaultFromEmail,
+		&i.DefaultFromName,
+		&i.DefaultHeaders,
+		&i.OpenTracking,
+		&i.ClickTracking,
+		&i.FooterHtml,
+		&i.FooterText,
+		&i.Sandbox,
+		&i.SandboxBouncePercent,
+		&i.PausedAt,
+		&i.PausedReason,
+	)
+	return i, err
+}
+
+const setDomainDKIMSigning = `-- name: SetDomainDKIMSigning :one
+UPDATE domains
+    SET dkim_signing = $1,
+        dkim_ed25519_private_key = COALESCE(NULLIF(dkim_ed25519_private_key, ''), $2),
+        dkim_ed25519_public_key = COALESCE(NULLIF(dkim_ed25519_public_key, ''), $3)
+    WHERE domain = $4
+    RETURNING id, domain, created_at, dkim_private_key, dkim_public_key, retention_days, rate_per_second, rate_per_hour, ip_pool, dkim_ed25519_private_key, dkim_ed25519_public_key, dkim_signing, dkim_selector, dkim_ed25519_selector, dkim_headers, verified, return_path_domain, daily_quota, monthly_quota, deleted_at, purge_at, default_from_email, default_from_name, default_headers, open_tracking, click_tracking, footer_html, footer_text, sandbox, sandbox_bounce_percent, paused_at, paused_reason
+`
+
+type SetDomainDKIMSigningParams struct {
+	DkimSigning           string
+	DkimEd25519PrivateKey string
+	DkimEd25519PublicKey  string
+	Domain                string
+}
+
+// the Ed25519 keys are set only when the domain has none
+func (q *Queries) SetDomainDKIMSigning(ctx context.Context, arg SetDomainDKIMSigningParams) (Domain, error) {
+	row := q.queryRow(ctx, q.setDomainDKIMSigningStmt, setDomainDKIMSigning,
+		arg.DkimSigning,
+		arg.DkimEd25519PrivateKey,
+		arg.DkimEd25519PublicKey,
+		arg.Domain,
+	)
+	var i Domain
+	err := row.Scan(
+		&i.ID,
+		&i.Domain,
+		&i.CreatedAt,
+		&i.DkimPrivateKey,
+		&i.DkimPublicKey,
+		&i.RetentionDays,
+		&i.RatePerSecond,
+		&i.RatePerHour,
+		&i.IpPool,
+		&i.DkimEd25519PrivateKey,
+		&i.DkimEd25519PublicKey,
+		&i.DkimSigning,
+		&i.DkimSelector,
+		&i.DkimEd25519Selector,
+		pq.Array(&i.DkimHeaders),
+		&i.Verified,
+		&i.ReturnPathDomain,
+		&i.DailyQuota,
+		&i.MonthlyQuota,
+		&i.DeletedAt,
+		&i.PurgeAt,
+		&i.DefaultFromEmail,
+		&i.DefaultFromName,
+		&i.DefaultHeaders,
+		&i.OpenTracking,
+		&i.ClickTracking,
+		&i.FooterHtml,
+		&i.FooterText,
+		&i.Sandbox,
+		&i.SandboxBouncePercent,
+		&i.PausedAt,
+		&i.PausedReason,
+	)
+	return i, err
+}
+
+const setDomainIPPool = `-- name: SetDomainIPPool :one
+UPDATE domains
+    SET ip_pool = $1
+    WHERE domain = $2
+    RETURNING id, domain, created_at, dkim_private_key, dkim_public_key, retention_days, rate_per_second, rate_per_hour, ip_pool, dkim_ed25519_private_key, dkim_ed25519_public_key, dkim_signing, dkim_selector, dkim_ed25519_selector, dkim_headers, verified, return_path_domain, daily_quota, monthly_quota, deleted_at, purge_at, default_from_email, default_from_name, default_headers, open_tracking, click_tracking, footer_html, footer_text, sandbox, sandbox_bounce_percent, paused_at, paused_reason
+`
+
+type SetDomainIPPoolParams struct {
+	IpPool string
+	Domain string
+}
+
+func (q *Queries) SetDomainIPPool(ctx context.Context, arg SetDomainIPPoolParams) (Domain, error) {
+	row := q.queryRow(ctx, q.setDomainIPPoolStmt, setDomainIPPool, arg.IpPool, arg.Domain)
+	var i Domain
+	err := row.Scan(
+		&i.ID,
+		&i.Domain,
+		&i.CreatedAt,
+		&i.DkimPrivateKey,
+		&i.DkimPublicKey,
+		&i.RetentionDays,
+		&i.RatePerSecond,
+		&i.RatePerHour,
+		&i.IpPool,
+		&i.DkimEd25519PrivateKey,
+		&i.DkimEd25519PublicKey,
+		&i.DkimSigning,
+		&i.DkimSelector,
+		&i.DkimEd25519Selector,
+		pq.Array(&i.DkimHeaders),
+		&i.Verified,
+		&i.ReturnPathDomain,
+		&i.DailyQuota,
+		&i.MonthlyQuota,
+		&i.DeletedAt,
+		&i.PurgeAt,
+		&i.DefaultFromEmail,
+		&i.DefaultFromName,
+		&i.DefaultHeaders,
+		&i.OpenTracking,
+		&i.ClickTracking,
+		&i.FooterHtml,
+		&i.FooterText,
+		&i.Sandbox,
+		&i.SandboxBouncePercent,
+		&i.PausedAt,
+		&i.PausedReason,
+	)
+	return i, err
+}
+
+const setDomainQuota = `-- name: SetDomainQuota :one
+UPDATE domains
+    SET daily_quota = $1, monthly_quota = $2
+    WHERE domain = $3
+    RETURNING id, domain, created_at, dkim_private_key, dkim_public_key, retention_days, rate_per_second, rate_per_hour, ip_pool, dkim_ed25519_private_key, dkim_ed25519_public_key, dkim_signing, dkim_selector, dkim_ed25519_selector, dkim_headers, verified, return_path_domain, daily_quota, monthly_quota, deleted_at, purge_at, default_from_email, default_from_name, default_headers, open_tracking, click_tracking, footer_html, footer_text, sandbox, sandbox_bounce_percent, paused_at, paused_reason
+`
+
+type SetDomainQuotaParams struct {
+	DailyQuota   int32
+	MonthlyQuota int32
+	Domain       string
+}
+
+func (q *Queries) SetDomainQuota(ctx context.Context, arg SetDomainQuotaParams) (Domain, error) {
+	row := q.queryRow(ctx, q.setDomainQuotaStmt, setDomainQuota, arg.DailyQuota, arg.MonthlyQuota, arg.Domain)
+	var i Domain
+	err := row.Scan(
+		&i.ID,
+		&i.Domain,
+		&i.CreatedAt,
+		&i.DkimPrivateKey,
+		&i.DkimPublicKey,
+		&i.RetentionDays,
+		&i.RatePerSecond,
+		&i.RatePerHour,
+		&i.IpPool,
+		&i.DkimEd25519PrivateKey,
+		&i.DkimEd25519PublicKey,
+		&i.DkimSigning,
+		&i.DkimSelector,
+		&i.DkimEd25519Selector,
+		pq.Array(&i.DkimHeaders),
+		&i.Verified,
+		&i.ReturnPathDomain,
+		&i.DailyQuota,
+		&i.MonthlyQuota,
+		&i.DeletedAt,
+		&i.PurgeAt,
+		&i.DefaultFromEmail,
+		&i.DefaultFromName,
+		&i.DefaultHeaders,
+		&i.OpenTracking,
+		&i.ClickTracking,
+		&i.FooterHtml,
+		&i.FooterText,
+		&i.Sandbox,
+		&i.SandboxBouncePercent,
+		&i.PausedAt,
+		&i.PausedReason,
+	)
+	return i, err
+}
+
+const setDomainRateLimit = `-- name: SetDomainRateLimit :one
+UPDATE domains
+    SET rate_per_second = $1, rate_per_hour = $2
+    WHERE domain = $3
+    RETURNING id, domain, created_at, dkim_private_key, dkim_public_key, retention_days, rate_per_second, rate_per_hour, ip_pool, dkim_ed25519_private_key, dkim_ed25519_public_key, dkim_signing, dkim_selector, dkim_ed25519_selector, dkim_headers, verified, return_path_domain, daily_quota, monthly_quota, deleted_at, purge_at, default_from_email, default_from_name, default_headers, open_tracking, click_tracking, footer_html, footer_text, sandbox, sandbox_bounce_percent, paused_at, paused_reason
+`
+
+type SetDomainRateLimitParams struct {
+	RatePerSecond int32
+	RatePerHour   int32
+	Domain        string
+}
+
+func (q *Queries) SetDomainRateLimit(ctx context.Context, arg SetDomainRateLimitParams) (Domain, error) {
+	row := q.queryRow(ctx, q.setDomainRateLimitStmt, setDomainRateLimit, arg.RatePerSecond, arg.RatePerHour, arg.Domain)
+	var i Domain
+	err := row.Scan(
+		&i.ID,
+		&i.Domain,
+		&i.CreatedAt,
+		&i.DkimPrivateKey,
+		&i.DkimPublicKey,
+		&i.RetentionDays,
+		&i.RatePerSecond,
+		&i.RatePerHour,
+		&i.IpPool,
+		&i.DkimEd25519PrivateKey,
+		&i.DkimEd25519PublicKey,
+		&i.DkimSigning,
+		&i.DkimSelector,
+		&i.DkimEd25519Selector,
+		pq.Array(&i.DkimHeaders),
+		&i.Verified,
+		&i.ReturnPathDomain,
+		&i.DailyQuota,
+		&i.MonthlyQuota,
+		&i.DeletedAt,
+		&i.PurgeAt,
+		&i.DefaultFromEmail,
+		&i.DefaultFromName,
+		&i.DefaultHeaders,
+		&i.OpenTracking,
+		&i.ClickTracking,
+		&i.FooterHtml,
+		&i.FooterText,
+		&i.Sandbox,
+		&i.SandboxBouncePercent,
+		&i.PausedAt,
+		&i.PausedReason,
+	)
+	return i, err
+}
+
+const setDomainRecord = `-- name: SetDomainRecord :one
+INSERT INTO domain_records (domain, record, status, error)
+    VALUES ($1, $2, $3, $4)
+    ON CONFLICT (domain, record) DO UPDATE
+    SET status = $3, error = $4, checked_at = now()
+    RETURNING id, domain, record, status, error, checked_at
+`
+
+type SetDomainRecordParams struct {
+	Domain string
+	Record string
+	Status DomainRecordStatus
+	Error  string
+}
+
+func (q *Queries) SetDomainRecord(ctx context.Context, arg SetDomainRecordParams) (DomainRecord, error) {
+	row := q.queryRow(ctx, q.setDomainRecordStmt, setDomainRecord,
+		arg.Domain,
+		arg.Record,
+		arg.Status,
+		arg.Error,
+	)
+	var i DomainRecord
+	err := row.Scan(
+		&i.ID,
+		&i.Domain,
+		&i.Record,
+		&i.Status,
+		&i.Error,
+		&i.CheckedAt,
+	)
+	return i, err
+}
+
+const setDomainRetention = `-- name: SetDomainRetention :one
+UPDATE domains
+    SET retention_days = $1
+    WHERE domain = $2
+    RETURNING id, domain, created_at, dkim_private_key, dkim_public_key, retention_days, rate_per_second, rate_per_hour, ip_pool, dkim_ed25519_private_key, dkim_ed25519_public_key, dkim_signing, dkim_selector, dkim_ed25519_selector, dkim_headers, verified, return_path_domain, daily_quota, monthly_quota, deleted_at, purge_at, default_from_email, default_from_name, default_headers, open_tracking, click_tracking, footer_html, footer_text, sandbox, sandbox_bounce_percent, paused_at, paused_reason
+`
+
+type SetDomainRetentionParams struct {
+	RetentionDays int32
+	Domain        string
+}
+
+func (q *Queries) SetDomainRetention(ctx context.Context, arg SetDomainRetentionParams) (Domain, error) {
+	row := q.queryRow(ctx, q.setDomainRetentionStmt, setDomainRetention, arg.RetentionDays, arg.Domain)
+	var i Domain
+	err := row.Scan(
+		&i.ID,
+		&i.Domain,
+		&i.CreatedAt,
+		&i.DkimPrivateKey,
+		&i.DkimPublicKey,
+		&i.RetentionDays,
+		&i.RatePerSecond,
+		&i.RatePerHour,
+		&i.IpPool,
+		&i.DkimEd25519PrivateKey,
+		&i.DkimEd25519PublicKey,
+		&i.DkimSigning,
+		&i.DkimSelector,
+		&i.DkimEd25519Selector,
+		pq.Array(&i.DkimHeaders),
+		&i.Verified,
+		&i.ReturnPathDomain,
+		&i.DailyQuota,
+		&i.MonthlyQuota,
+		&i.DeletedAt,
+		&i.PurgeAt,
+		&i.DefaultFromEmail,
+		&i.DefaultFromName,
+		&i.DefaultHeaders,
+		&i.OpenTracking,
+		&i.ClickTracking,
+		&i.FooterHtml,
+		&i.FooterText,
+		&i.Sandbox,
+		&i.SandboxBouncePercent,
+		&i.PausedAt,
+		&i.PausedReason,
+	)
+	return i, err
+}
+
+const setDomainReturnPathDomain = `-- name: SetDomainReturnPathDomain :one
+UPDATE domains
+    SET return_path_domain = $1
+    WHERE domain = $2
+    RETURNING id, domain, created_at, dkim_private_key, dkim_public_key, retention_days, rate_per_second, rate_per_hour, ip_pool, dkim_ed25519_private_key, dkim_ed25519_public_key, dkim_signing, dkim_selector, dkim_ed25519_selector, dkim_headers, verified, return_path_domain, daily_quota, monthly_quota, deleted_at, purge_at, default_from_email, default_from_name, default_headers, open_tracking, click_tracking, footer_html, footer_text, sandbox, sandbox_bounce_percent, paused_at, paused_reason
+`
+
+type SetDomainReturnPathDomainParams struct {
+	ReturnPathDomain string
+	Domain           string
+}
+
+func (q *Queries) SetDomainReturnPathDomain(ctx context.Context, arg SetDomainReturnPathDomainParams) (Domain, error) {
+	row := q.queryRow(ctx, q.setDomainReturnPathDomainStmt, setDomainReturnPathDomain, arg.ReturnPathDomain, arg.Domain)
+	var i Domain
+	err := row.Scan(
+		&i.ID,
+		&i.Domain,
+		&i.CreatedAt,
+		&i.DkimPrivateKey,
+		&i.DkimPublicKey,
+		&i.RetentionDays,
+		&i.RatePerSecond,
+		&i.RatePerHour,
+		&i.IpPool,
+		&i.DkimEd25519PrivateKey,
+		&i.DkimEd25519PublicKey,
+		&i.DkimSigning,
+		&i.DkimSelector,
+		&i.DkimEd25519Selector,
+		pq.Array(&i.DkimHeaders),
+		&i.Verified,
+		&i.ReturnPathDomain,
+		&i.DailyQuota,
+		&i.MonthlyQuota,
+		&i.DeletedAt,
+		&i.PurgeAt,
+		&i.DefaultFromEmail,
+		&i.DefaultFromName,
+		&i.DefaultHeaders,
+		&i.OpenTracking,
+		&i.ClickTracking,
+		&i.FooterHtml,
+		&i.FooterText,
+		&i.Sandbox,
+		&i.SandboxBouncePercent,
+		&i.PausedAt,
+		&i.PausedReason,
+	)
+	return i, err
+}
+
+const setDomainSandbox = `-- name: SetDomainSandbox :one
+UPDATE domains
+    SET sandbox = $1,
+        sandbox_bounce_percent = $2
+    WHERE domain = $3
+    RETURNING id, domain, created_at, dkim_private_key, dkim_public_key, retention_days, rate_per_second, rate_per_hour, ip_pool, dkim_ed25519_private_key, dkim_ed25519_public_key, dkim_signing, dkim_selector, dkim_ed25519_selector, dkim_headers, verified, return_path_domain, daily_quota, monthly_quota, deleted_at, purge_at, default_from_email, default_from_name, default_headers, open_tracking, click_tracking, footer_html, footer_text, sandbox, sandbox_bounce_percent, paused_at, paused_reason
+`
+
+type SetDomainSandboxParams struct {
+	Sandbox              bool
+	SandboxBouncePercent int32
+	Domain               string
+}
+
+func (q *Queries) SetDomainSandbox(ctx context.Context, arg SetDomainSandboxParams) (Domain, error) {
+	row := q.queryRow(ctx, q.setDomainSandboxStmt, setDomainSandbox, arg.Sandbox, arg.SandboxBouncePercent, arg.Domain)
+	var i Domain
+	err := row.Scan(
+		&i.ID,
+		&i.Domain,
+		&i.CreatedAt,
+		&i.DkimPrivateKey,
+		&i.DkimPublicKey,
+		&i.RetentionDays,
+		&i.RatePerSecond,
+		&i.RatePerHour,
+		&i.IpPool,
+		&i.DkimEd25519PrivateKey,
+		&i.DkimEd25519PublicKey,
+		&i.DkimSigning,
+		&i.DkimSelector,
+		&i.DkimEd25519Selector,
+		pq.Array(&i.DkimHeaders),
+		&i.Verified,
+		&i.ReturnPathDomain,
+		&i.DailyQuota,
+		&i.MonthlyQuota,
+		&i.DeletedAt,
+		&i.PurgeAt,
+		&i.DefaultFromEmail,
+		&i.DefaultFromName,
+		&i.DefaultHeaders,
+		&i.OpenTracking,
+		&i.ClickTracking,
+		&i.FooterHtml,
+		&i.FooterText,
+		&i.Sandbox,
+		&i.SandboxBouncePercent,
+		&i.PausedAt,
+		&i.PausedReason,
+	)
+	return i, err
+}
+
+const setDomainSendingSettings = `-- name: SetDomainSendingSettings :one
+UPDATE domains
+    SET default_from_email = $1,
+        default_from_name = $2,
+        default_headers = $3,
+        open_tracking = $4,
+        click_tracking = $5,
+        footer_html = $6,
+        footer_text = $7
+    WHERE domain = $8
+    RETURNING id, domain, created_at, dkim_private_key, dkim_public_key, retention_days, rate_per_second, rate_per_hour, ip_pool, dkim_ed25519_private_key, dkim_ed25519_public_key, dkim_signing, dkim_selector, dkim_ed25519_selector, dkim_headers, verified, return_path_domain, daily_quota, monthly_quota, deleted_at, purge_at, default_from_email, default_from_name, default_headers, open_tracking, click_tracking, footer_html, footer_text, sandbox, sandbox_bounce_percent, paused_at, paused_reason
+`
+
+type SetDomainSendingSettingsParams struct {
+	DefaultFromEmail string
+	DefaultFromName  string
+	DefaultHeaders   json.RawMessage
+	OpenTracking     bool
+	ClickTracking    bool
+	FooterHtml       string
+	FooterText       string
+	Domain           string
+}
+
+func (q *Queries) SetDomainSendingSettings(ctx context.Context, arg SetDomainSendingSettingsParams) (Domain, error) {
+	row := q.queryRow(ctx, q.setDomainSendingSettingsStmt, setDomainSendingSettings,
+		arg.DefaultFromEmail,
+		arg.DefaultFromName,
+		arg.DefaultHeaders,
+		arg.OpenTracking,
+		arg.ClickTracking,
+		arg.FooterHtml,
+		arg.FooterText,
+		arg.Domain,
+	)
+	var i Domain
+	err := row.Scan(
+		&i.ID,
+		&i.Domain,
+		&i.CreatedAt,
+		&i.DkimPrivateKey,
+		&i.DkimPublicKey,
+		&i.RetentionDays,
+		&i.RatePerSecond,
+		&i.RatePerHour,
+		&i.IpPool,
+		&i.DkimEd25519PrivateKey,
+		&i.DkimEd25519PublicKey,
+		&i.DkimSigning,
+		&i.DkimSelector,
+		&i.DkimEd25519Selector,
+		pq.Array(&i.DkimHeaders),
+		&i.Verified,
+		&i.ReturnPathDomain,
+		&i.DailyQuota,
+		&i.MonthlyQuota,
+		&i.DeletedAt,
+		&i.PurgeAt,
+		&i.DefaultFromEmail,
+		&i.DefaultFromName,
+		&i.DefaultHeaders,
+		&i.OpenTracking,
+		&i.ClickTracking,
+		&i.FooterHtml,
+		&i.FooterText,
+		&i.Sandbox,
+		&i.SandboxBouncePercent,
+		&i.PausedAt,
+		&i.PausedReason,
+	)
+	return i, err
+}
+
+const setDomainVerified = `-- name: SetDomainVerified :one
+UPDATE domains
+    SET verified = $1
+    WHERE domain = $2
+    RETURNING id, domain, created_at, dkim_private_key, dkim_public_key, retention_days, rate_per_second, rate_per_hour, ip_pool, dkim_ed25519_private_key, dkim_ed25519_public_key, dkim_signing, dkim_selector, dkim_ed25519_selector, dkim_headers, verified, return_path_domain, daily_quota, monthly_quota, deleted_at, purge_at, default_from_email, default_from_name, default_headers, open_tracking, click_tracking, footer_html, footer_text, sandbox, sandbox_bounce_percent, paused_at, paused_reason
+`
+
+type SetDomainVerifiedParams struct {
+	Verified bool
+	Domain   string
+}
+
+func (q *Queries) SetDomainVerified(ctx context.Context, arg SetDomainVerifiedParams) (Domain, error) {
+	row := q.queryRow(ctx, q.setDomainVerifiedStmt, setDomainVerified, arg.Verified, arg.Domain)
+	var i Domain
+	err := row.Scan(
+		&i.ID,
+		&i.Domain,
+		&i.CreatedAt,
+		&i.DkimPrivateKey,
+		&i.DkimPublicKey,
+		&i.RetentionDays,
+		&i.RatePerSecond,
+		&i.RatePerHour,
+		&i.IpPool,
+		&i.DkimEd25519PrivateKey,
+		&i.DkimEd25519PublicKey,
+		&i.DkimSigning,
+		&i.DkimSelector,
+		&i.DkimEd25519Selector,
+		pq.Array(&i.DkimHeaders),
+		&i.Verified,
+		&i.ReturnPathDomain,
+		&i.DailyQuota,
+		&i.MonthlyQuota,
+		&i.DeletedAt,
+		&i.PurgeAt,
+		&i.DefaultFromEmail,
+		&i.DefaultFromName,
+		&i.DefaultHeaders,
+		&i.OpenTracking,
+		&i.ClickTracking,
+		&i.FooterHtml,
+		&i.FooterText,
+		&i.Sandbox,
+		&i.SandboxBouncePercent,
+		&i.PausedAt,
+		&i.PausedReason,
+	)
+	return i, err
+}
+
+const setEventExport = `-- name: SetEventExport :exec
+INSERT INTO event_exports (domain, exported_before, last_event_id) VALUES
+    ($1, $2, $3)
+    ON CONFLICT (domain) DO UPDATE
+    SET exported_before = EXCLUDED.exported_before, last_event_id = EXCLUDED.last_event_id
+`
+
+type SetEventExportParams struct {
+	Domain         string
+	ExportedBefore time.Time
+	LastEventID    int32
+}
+
+func (q *Queries) SetEventExport(ctx context.Context, arg SetEventExportParams) error {
+	_, err := q.exec(ctx, q.setEventExportStmt, setEventExport, arg.Domain, arg.ExportedBefore, arg.LastEventID)
+	return err
+}
+
+const setMaintenance = `-- name: SetMaintenance :one
+INSERT INTO maintenance (id, halted, reason, changed_at)
+    VALUES (true, $1, $2, NOW())
+    ON CONFLICT (id) DO UPDATE
+    SET halted = $1, reason = $2, changed_at = NOW()
+    RETURNING id, halted, reason, changed_at
+`
+
+type SetMaintenanceParams struct {
+	Halted bool
+	Reason string
+}
+
+func (q *Queries) SetMaintenance(ctx context.Context, arg SetMaintenanceParams) (Maintenance, error) {
+	row := q.queryRow(ctx, q.setMaintenanceStmt, setMaintenance, arg.Halted, arg.Reason)
+	var i Maintenance
+	err := row.Scan(
+		&i.ID,
+		&i.Halted,
+		&i.Reason,
+		&i.ChangedAt,
+	)
+	return i, err
+}
+
+const setSendingPoolEmailBounced = `-- name: SetSendingPoolEmailBounced :exec
+UPDATE sending_pool_emails AS sp
+    SET
+        status = $1,
+        bounce_type = $2,
+        error_code = $3,
+        error_msg = $4,
+        trial = sp.trial + 1,
+        scheduled_time = $5,
+        -- the cc and bcc were tried with the email, retries are sent to it only
+        copies = false
+    FROM messages AS m
+    WHERE m.id = sp.message_id AND m.message_id = $6 AND sp.email = $7
+`
+
+type SetSendingPoolEmailBouncedParams struct {
+	Status        SendingPoolStatus
+	BounceType    BounceType
+	ErrorCode     int32
+	ErrorMsg      string
+	ScheduledTime time.Time
+	MessageID     string
+	Email         string
+}
+
+func (q *Queries) SetSendingPoolEmailBounced(ctx context.Context, arg SetSendingPoolEmailBouncedParams) error {
+	_, err := q.exec(ctx, q.setSendingPoolEmailBouncedStmt, setSendingPoolEmailBounced,
+		arg.Status,
+		arg.BounceType,
+		arg.ErrorCode,
+		arg.ErrorMsg,
+		arg.ScheduledTime,
+		arg.MessageID,
+		arg.Email,
+	)
+	return err
+}
+
+const setSendingPoolEmailDelivered = `-- name: SetSendingPoolEmailDelivered :exec
+UPDATE sending_pool_emails AS sp
+    SET status = 'sent'
+    FROM messages AS m
+    WHERE m.id = sp.message_id AND m.message_id = $1 AND sp.email = $2
+`
+
+type SetSendingPoolEmailDeliveredParams struct {
+	MessageID string
+	Email     string
+}
+
+func (q *Queries) SetSendingPoolEmailDelivered(ctx context.Context, arg SetSendingPoolEmailDeliveredParams) error {
+	_, err := q.exec(ctx, q.setSendingPoolEmailDeliveredStmt, setSendingPoolEmailDelivered, arg.MessageID, arg.Email)
+	return err
+}
+
+const setSendingPoolEmailPublished = `-- name: SetSendingPoolEmailPublished :exec
+UPDATE sending_pool_emails SET claimed_at = NULL WHERE id = $1
+`
+
+func (q *Queries) SetSendingPoolEmailPublished(ctx context.Context, id int32) error {
+	_, err := q.exec(ctx, q.setSendingPoolEmailPublishedStmt, setSendingPoolEmailPublished, id)
+	return err
+}
+
+const setSendingPoolEmailQuotaConsumed = `-- name: SetSendingPoolEmailQuotaConsumed :exec
+UPDATE sending_pool_emails SET quota_consumed = true WHERE id = $1
+`
+
+func (q *Queries) SetSendingPoolEmailQuotaConsumed(ctx context.Context, id int32) error {
+	_, err := q.exec(ctx, q.setSendingPoolEmailQuotaConsumedStmt, setSendingPoolEmailQuotaConsumed, id)
+	return err
+}
+
+const setSendingPoolEmailStatus = `-- name: SetSendingPoolEmailStatus :exec
+UPDATE sending_pool_emails
+    SET status = $1
+    WHERE id = $2
+`
+
+type SetSendingPoolEmailStatusParams struct {
+	Status SendingPoolStatus
+	ID     int32
+}
+
+func (q *Queries) SetSendingPoolEmailStatus(ctx context.Context, arg SetSendingPoolEmailStatusParams) error {
+	_, err := q.exec(ctx, q.setSendingPoolEmailStatusStmt, setSendingPoolEmailStatus, arg.Status, arg.ID)
+	return err
+}
+
+const setWebhookDeliveryResult = `-- name: SetWebhookDeliveryResult :exec
+UPDATE webhook_deliveries
+    SET
+        status = $1,
+        attempts = attempts + 1,
+        response_code = $2,
+        error_msg = $3,
+        next_attempt_time = $4
+    WHERE id = $5
+`
+
+type SetWebhookDeliveryResultParams struct {
+	Status          WebhookDeliveryStatus
+	ResponseCode    int32
+	ErrorMsg        string
+	NextAttemptTime time.Time
+	ID              int32
+}
+
+func (q *Queries) SetWebhookDeliveryResult(ctx context.Context, arg SetWebhookDeliveryResultParams) error {
+	_, err := q.exec(ctx, q.setWebhookDeliveryResultStmt, setWebhookDeliveryResult,
+		arg.Status,
+		arg.ResponseCode,
+		arg.ErrorMsg,
+		arg.NextAttemptTime,
+		arg.ID,
+	)
+	return err
+}
+
+const suppressMessageRecipient = `-- name: SuppressMessageRecipient :exec
+INSERT INTO suppressions (domain, email, reason)
+    SELECT m.domain, lower($1::varchar), $2::suppression_reason
+    FROM messages AS m
+    WHERE m.message_id = $3
+    ON CONFLICT (domain, email) DO NOTHING
+`
+
+type SuppressMessageRecipientParams struct {
+	Email     string
+	Reason    SuppressionReason
+	MessageID string
+}
+
+func (q *Queries) SuppressMessageRecipient(ctx context.Context, arg SuppressMessageRecipientParams) error {
+	_, err := q.exec(ctx, q.suppressMessageRecipientStmt, suppressMessageRecipient, arg.Email, arg.Reason, arg.MessageID)
+	return err
+}
+
+const touchAPIKey = `-- name: TouchAPIKey :exec
+UPDATE api_keys
+    SET last_used_at = NOW()
+    WHERE id = $1
+    AND (last_used_at IS NULL OR last_used_at < NOW() - interval '1 minute')
+`
+
+// last_used_at is updated at most once a minute
+func (q *Queries) TouchAPIKey(ctx context.Context, id int32) error {
+	_, err := q.exec(ctx, q.touchAPIKeyStmt, touchAPIKey, id)
+	return err
+}
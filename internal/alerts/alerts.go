@@ -0,0 +1,338 @@
+package alerts
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"kannon.gyozatech.dev/generated/pb"
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/metrics"
+	"kannon.gyozatech.dev/internal/pool"
+	"kannon.gyozatech.dev/internal/queue"
+	"kannon.gyozatech.dev/internal/stats"
+	"kannon.gyozatech.dev/internal/webhooks"
+)
+
+var log = logging.Logger("alerts")
+
+// Subject is the subject of the alerts published on the queue
+const Subject = "emails.alerts"
+
+// Rules of the alerts
+const (
+	BounceRate    = "bounce_rate"
+	ComplaintRate = "complaint_rate"
+	QueueAge      = "queue_age"
+)
+
+// Status is the status of an alert
+type Status string
+
+const (
+	Firing   Status = "firing"
+	Resolved Status = "resolved"
+)
+
+// Config configures the alert rules and where the alerts are sent,
+// rules with a 0 threshold are disabled
+type Config struct {
+	// Interval is the time between the evaluations of the rules
+	Interval time.Duration `default:"1m"`
+	// Window is the time the rates are computed over
+	Window time.Duration `default:"1h"`
+	// MinSent are the emails a domain sends in Window for its rates to fire,
+	// the rates of few emails are not significant
+	MinSent int64 `default:"100"`
+	// MaxBounceRate fires for the domains whose bounces of the sent emails exceed it, like 0.05
+	MaxBounceRate float64
+	// MaxComplaintRate fires for the domains whose complaints of the delivered emails exceed it, like 0.003
+	MaxComplaintRate float64
+	// MaxQueueAge fires when the oldest email waiting to be dispatched is older, like 15m
+	MaxQueueAge time.Duration
+	// WebhookURL receives the alerts as JSON posts signed with WebhookSecret
+	WebhookURL    string
+	WebhookSecret string
+	// Publish publishes the alerts on emails.alerts
+	Publish bool
+}
+
+// Enabled reports if a rule is configured
+func (c Config) Enabled() bool {
+	return c.MaxBounceRate > 0 || c.MaxComplaintRate > 0 || c.MaxQueueAge > 0
+}
+
+// Alert is a rule firing or resolved, for a domain or
+// for every domain with an empty Domain
+type Alert struct {
+	Rule      string    `json:"rule"`
+	Status    Status    `json:"status"`
+	Domain    string    `json:"domain,omitempty"`
+	Value     float64   `json:"value"`
+	Threshold float64   `json:"threshold"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func (a Alert) key() string {
+	return a.Rule + "/" + a.Domain
+}
+
+// Evaluator evaluates the alert rules and sends the alerts changing status,
+// a rule firing is sent once and again when it's resolved. Firing alerts are
+// stored in the database, shared by every stats service and kept on restart
+type Evaluator struct {
+	config Config
+	stats  stats.Manager
+	pool   pool.SendingPoolManager
+	db     *sqlc.Queries
+	p      queue.Publisher
+	client *http.Client
+}
+
+// NewEvaluator builds an Evaluator sending the alerts of config with p
+func NewEvaluator(db *sql.DB, config Config, p queue.Publisher) (*Evaluator, error) {
+	sm, err := stats.NewStatsManager(db)
+	if err != nil {
+		return nil, err
+	}
+	pm, err := pool.NewSendingPoolManager(db)
+	if err != nil {
+		return nil, err
+	}
+	return &Evaluator{
+		config: config,
+		stats:  sm,
+		pool:   pm,
+		db:     sqlc.New(metrics.InstrumentDB(db)),
+		p:      p,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Run evaluates the rules every interval until ctx is canceled
+func (e *Evaluator) Run(ctx context.Context) {
+	if !e.config.Enabled() {
+		return
+	}
+	ticker := time.NewTicker(e.config.Interval)
+	defer ticker.Stop()
+	for {
+		if err := e.Evaluate(); err != nil {
+			log.Errorf("cannot evaluate alerts: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Evaluate evaluates the rules once and sends the alerts changing status
+func (e *Evaluator) Evaluate() error {
+	now := time.Now()
+	var domains map[string]stats.Stats
+	if e.config.MaxBounceRate > 0 || e.config.MaxComplaintRate > 0 {
+		var err error
+		domains, err = e.stats.GetDomainsStats(now.Add(-e.config.Window), now)
+		if err != nil {
+			return err
+		}
+	}
+	var queueAge time.Duration
+	if e.config.MaxQueueAge > 0 {
+		var err error
+		queueAge, err = e.pool.GetQueueAge()
+		if err != nil {
+			return err
+		}
+	}
+	firing, err := e.firing()
+	if err != nil {
+		return err
+	}
+	for _, a := range transitions(firing, check(e.config, domains, queueAge, now), now) {
+		// the alerts recorded by another stats service are sent by it
+		recorded, err := e.record(a)
+		if err != nil {
+			log.Errorf("cannot record alert %v: %v", a.key(), err)
+			continue
+		}
+		if !recorded {
+			continue
+		}
+		if a.Status == Firing {
+			log.Warnf("[🚨 alert] %v", a.Message)
+		} else {
+			log.Infof("[✅ resolved] %v", a.Message)
+		}
+		if e.config.WebhookURL != "" {
+			if err := e.post(a); err != nil {
+				log.Errorf("cannot post alert %v: %v", a.key(), err)
+			}
+		}
+		if e.config.Publish {
+			if err := e.publish(a); err != nil {
+				log.Errorf("cannot publish alert %v: %v", a.key(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// firing returns the alerts firing by rule and domain
+func (e *Evaluator) firing() (map[string]Alert, error) {
+	rows, err := e.db.GetFiringAlerts(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+	firing := make(map[string]Alert, len(rows))
+	for _, r := range rows {
+		a := Alert{
+			Rule:      r.Rule,
+			Status:    Firing,
+			Domain:    r.Domain,
+			Value:     r.Value,
+			Threshold: r.Threshold,
+			Message:   r.Message,
+			Timestamp: r.FiredAt,
+		}
+		firing[a.key()] = a
+	}
+	return firing, nil
+}
+
+// record stores a firing alert or deletes a resolved one, false is
+// returned when another stats service already did
+func (e *Evaluator) record(a Alert) (bool, error) {
+	var n int64
+	var err error
+	if a.Status == Firing {
+		n, err = e.db.FireAlert(context.TODO(), sqlc.FireAlertParams{
+			Rule:      a.Rule,
+			Domain:    a.Domain,
+			Value:     a.Value,
+			Threshold: a.Threshold,
+			Message:   a.Message,
+			FiredAt:   a.Timestamp,
+		})
+	} else {
+		n, err = e.db.ResolveAlert(context.TODO(), sqlc.ResolveAlertParams{
+			Rule:   a.Rule,
+			Domain: a.Domain,
+		})
+	}
+	return n > 0, err
+}
+
+// post posts an alert as JSON to the webhook of the config
+func (e *Evaluator) post(a Alert) error {
+	payload, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	_, err = webhooks.Deliver(e.client, e.config.WebhookURL, e.config.WebhookSecret, "alert."+string(a.Status), payload)
+	return err
+}
+
+// publish publishes an alert on Subject
+func (e *Evaluator) publish(a Alert) error {
+	msg, err := proto.Marshal(&pb.Alert{
+		Rule:      a.Rule,
+		Status:    string(a.Status),
+		Domain:    a.Domain,
+		Value:     a.Value,
+		Threshold: a.Threshold,
+		Message:   a.Message,
+		Timestamp: timestamppb.New(a.Timestamp),
+	})
+	if err != nil {
+		return err
+	}
+	return e.p.Publish(Subject, msg)
+}
+
+// check returns the alerts of the rules exceeded by the stats of the domains
+// and the queue age, sorted by rule and domain
+func check(config Config, domains map[string]stats.Stats, queueAge time.Duration, now time.Time) []Alert {
+	var res []Alert
+	for domain, s := range domains {
+		if s.Sent < config.MinSent {
+			continue
+		}
+		if r := rate(s.Bounced, s.Sent); config.MaxBounceRate > 0 && r > config.MaxBounceRate {
+			res = append(res, Alert{
+				Rule:      BounceRate,
+				Domain:    domain,
+				Value:     r,
+				Threshold: config.MaxBounceRate,
+				Message:   fmt.Sprintf("bounce rate of %v %.2f%% over %v", domain, r*100, config.Window),
+			})
+		}
+		if r := rate(s.Complained, s.Delivered); config.MaxComplaintRate > 0 && r > config.MaxComplaintRate {
+			res = append(res, Alert{
+				Rule:      ComplaintRate,
+				Domain:    domain,
+				Value:     r,
+				Threshold: config.MaxComplaintRate,
+				Message:   fmt.Sprintf("complaint rate of %v %.2f%% over %v", domain, r*100, config.Window),
+			})
+		}
+	}
+	if config.MaxQueueAge > 0 && queueAge > config.MaxQueueAge {
+		res = append(res, Alert{
+			Rule:      QueueAge,
+			Value:     queueAge.Minutes(),
+			Threshold: config.MaxQueueAge.Minutes(),
+			Message:   fmt.Sprintf("oldest email waiting to be dispatched for %v", queueAge.Round(time.Second)),
+		})
+	}
+	for i := range res {
+		res[i].Status = Firing
+		res[i].Timestamp = now
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].key() < res[j].key() })
+	return res
+}
+
+// transitions updates firing with the alerts exceeded now and returns
+// the new firing alerts and the resolved ones
+func transitions(firing map[string]Alert, exceeded []Alert, now time.Time) []Alert {
+	var res []Alert
+	current := make(map[string]bool, len(exceeded))
+	for _, a := range exceeded {
+		current[a.key()] = true
+		if _, ok := firing[a.key()]; !ok {
+			firing[a.key()] = a
+			res = append(res, a)
+		}
+	}
+	var resolved []Alert
+	for key, a := range firing {
+		if current[key] {
+			continue
+		}
+		delete(firing, key)
+		a.Status = Resolved
+		a.Timestamp = now
+		a.Message = "resolved: " + a.Message
+		resolved = append(resolved, a)
+	}
+	sort.Slice(resolved, func(i, j int) bool { return resolved[i].key() < resolved[j].key() })
+	return append(res, resolved...)
+}
+
+func rate(n, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
@@ -0,0 +1,70 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"kannon.gyozatech.dev/internal/stats"
+)
+
+func TestCheck(t *testing.T) {
+	now := time.Now()
+	config := Config{Window: time.Hour, MinSent: 100, MaxBounceRate: 0.05, MaxComplaintRate: 0.003, MaxQueueAge: 15 * time.Minute}
+	domains := map[string]stats.Stats{
+		"bounces.com":    {Sent: 200, Delivered: 180, Bounced: 20},
+		"complaints.com": {Sent: 1000, Delivered: 1000, Complained: 4},
+		"ok.com":         {Sent: 1000, Delivered: 990, Bounced: 10, Complained: 1},
+		// too few emails
+		"few.com": {Sent: 10, Bounced: 10},
+	}
+
+	alerts := check(config, domains, 20*time.Minute, now)
+	assert.Len(t, alerts, 3)
+	assert.Equal(t, Alert{
+		Rule:      BounceRate,
+		Status:    Firing,
+		Domain:    "bounces.com",
+		Value:     0.1,
+		Threshold: 0.05,
+		Message:   "bounce rate of bounces.com 10.00% over 1h0m0s",
+		Timestamp: now,
+	}, alerts[0])
+	assert.Equal(t, ComplaintRate, alerts[1].Rule)
+	assert.Equal(t, "complaints.com", alerts[1].Domain)
+	assert.Equal(t, QueueAge, alerts[2].Rule)
+	assert.Equal(t, 20.0, alerts[2].Value)
+
+	assert.Len(t, check(config, domains, 10*time.Minute, now), 2)
+	// disabled rules
+	assert.Empty(t, check(Config{MinSent: 100}, domains, time.Hour, now))
+}
+
+func TestTransitions(t *testing.T) {
+	now := time.Now()
+	firing := make(map[string]Alert)
+	bounces := Alert{Rule: BounceRate, Status: Firing, Domain: "bounces.com", Message: "bounce rate"}
+	queue := Alert{Rule: QueueAge, Status: Firing, Message: "queue age"}
+
+	assert.Equal(t, []Alert{bounces, queue}, transitions(firing, []Alert{bounces, queue}, now))
+	// firing alerts are sent once
+	assert.Empty(t, transitions(firing, []Alert{bounces, queue}, now))
+
+	resolved := transitions(firing, []Alert{queue}, now)
+	assert.Equal(t, []Alert{{
+		Rule:      BounceRate,
+		Status:    Resolved,
+		Domain:    "bounces.com",
+		Message:   "resolved: bounce rate",
+		Timestamp: now,
+	}}, resolved)
+	assert.Len(t, firing, 1)
+
+	// resolved alerts fire again
+	assert.Equal(t, []Alert{bounces}, transitions(firing, []Alert{bounces, queue}, now))
+}
+
+func TestEnabled(t *testing.T) {
+	assert.False(t, Config{Interval: time.Minute, MinSent: 100}.Enabled())
+	assert.True(t, Config{MaxQueueAge: time.Minute}.Enabled())
+}
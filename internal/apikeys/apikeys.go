@@ -0,0 +1,265 @@
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/metrics"
+)
+
+// Scopes of the API keys, admin keys have every scope
+const (
+	ScopeSend  = "send"
+	ScopeStats = "stats"
+	ScopeAdmin = "admin"
+)
+
+// Scopes are the valid scopes of the API keys
+var Scopes = []string{ScopeSend, ScopeStats, ScopeAdmin}
+
+// DefaultName is the name of the admin key created with a domain
+const DefaultName = "default"
+
+// maxNameLen is the size of api_keys.name
+const maxNameLen = 100
+
+// prefixLen is the number of characters of a key stored in clear
+// to recognize it, the key is stored hashed
+const prefixLen = 8
+
+// DefaultOverlap is how long a rotated key stays valid when no
+// overlap is given, MaxOverlap is the longest overlap
+const (
+	DefaultOverlap = 24 * time.Hour
+	MaxOverlap     = 30 * 24 * time.Hour
+)
+
+// ErrInvalidKey is returned for revoked, expired or unknown keys
+var ErrInvalidKey = errors.New("invalid api key")
+
+// Validate checks the name and the scopes of a new key
+func Validate(name string, scopes []string) error {
+	if len(name) > maxNameLen {
+		return fmt.Errorf("name longer than %v characters", maxNameLen)
+	}
+	if len(scopes) == 0 {
+		return errors.New("no scopes")
+	}
+	for _, s := range scopes {
+		valid := false
+		for _, scope := range Scopes {
+			if s == scope {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("invalid scope: %v", s)
+		}
+	}
+	return nil
+}
+
+// HasScope reports if a key has a scope
+func HasScope(key sqlc.ApiKey, scope string) bool {
+	for _, s := range key.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Active reports if a key is neither revoked nor expired at now
+func Active(key sqlc.ApiKey, now time.Time) bool {
+	if key.RevokedAt.Valid {
+		return false
+	}
+	return !key.ExpiresAt.Valid || key.ExpiresAt.Time.After(now)
+}
+
+// ParseBasicAuth returns the domain and the key of a Basic
+// authorization of base64 <domain>:<key>
+func ParseBasicAuth(auth string) (string, string, bool) {
+	if !strings.HasPrefix(auth, "Basic ") {
+		return "", "", false
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(auth, "Basic "))
+	if err != nil {
+		return "", "", false
+	}
+	parts := strings.SplitN(string(data), ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// Manager manages the API keys of domains
+type Manager interface {
+	// Create creates a key of a domain, the key is returned only once
+	Create(domain string, name string, scopes []string) (sqlc.ApiKey, string, error)
+	// Regenerate creates a new default admin key and revokes the previous ones
+	Regenerate(domain string) (sqlc.ApiKey, string, error)
+	// Find returns the active key of a domain and tracks its use
+	Find(domain string, key string) (sqlc.ApiKey, error)
+	GetKeys(domain string) ([]sqlc.ApiKey, error)
+	Revoke(domain string, id int32) (sqlc.ApiKey, error)
+	// Rotate creates a key with the name and the scopes of an active key,
+	// which stays valid for overlap, and returns both keys
+	Rotate(domain string, id int32, overlap time.Duration) (sqlc.ApiKey, sqlc.ApiKey, string, error)
+	// LogCall records the call of method authenticated by a key,
+	// allowed is false when the key misses the scope of the call
+	LogCall(key sqlc.ApiKey, method string, allowed bool) error
+	// GetCalls returns the calls of a domain between from and to, most
+	// recent first, of the key id or of every key when id is 0
+	GetCalls(domain string, id int32, from time.Time, to time.Time, limit uint) ([]sqlc.ApiKeyCall, error)
+}
+
+type manager struct {
+	db *sqlc.Queries
+}
+
+// NewAPIKeyManager builds an API Key Manager
+func NewAPIKeyManager(db *sql.DB) (Manager, error) {
+	return &manager{
+		db: sqlc.New(metrics.InstrumentDB(db)),
+	}, nil
+}
+
+func (m *manager) Create(domain string, name string, scopes []string) (sqlc.ApiKey, string, error) {
+	key, err := generateKey()
+	if err != nil {
+		return sqlc.ApiKey{}, "", err
+	}
+	k, err := m.db.CreateAPIKey(context.TODO(), sqlc.CreateAPIKeyParams{
+		Domain:  domain,
+		Name:    name,
+		Prefix:  key[:prefixLen],
+		KeyHash: hashKey(key),
+		Scopes:  scopes,
+	})
+	if err != nil {
+		return sqlc.ApiKey{}, "", err
+	}
+	return k, key, nil
+}
+
+func (m *manager) Regenerate(domain string) (sqlc.ApiKey, string, error) {
+	err := m.db.RevokeAPIKeysByName(context.TODO(), sqlc.RevokeAPIKeysByNameParams{
+		Domain: domain,
+		Name:   DefaultName,
+	})
+	if err != nil {
+		return sqlc.ApiKey{}, "", err
+	}
+	return m.Create(domain, DefaultName, []string{ScopeAdmin})
+}
+
+func (m *manager) Find(domain string, key string) (sqlc.ApiKey, error) {
+	k, err := m.db.FindAPIKey(context.TODO(), sqlc.FindAPIKeyParams{
+		Domain:  domain,
+		KeyHash: hashKey(key),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return sqlc.ApiKey{}, ErrInvalidKey
+	}
+	if err != nil {
+		return sqlc.ApiKey{}, err
+	}
+	if err := m.db.TouchAPIKey(context.TODO(), k.ID); err != nil {
+		return sqlc.ApiKey{}, err
+	}
+	return k, nil
+}
+
+func (m *manager) GetKeys(domain string) ([]sqlc.ApiKey, error) {
+	return m.db.GetAPIKeys(context.TODO(), domain)
+}
+
+func (m *manager) Revoke(domain string, id int32) (sqlc.ApiKey, error) {
+	return m.db.RevokeAPIKey(context.TODO(), sqlc.RevokeAPIKeyParams{
+		Domain: domain,
+		ID:     id,
+	})
+}
+
+func (m *manager) Rotate(domain string, id int32, overlap time.Duration) (sqlc.ApiKey, sqlc.ApiKey, string, error) {
+	old, err := m.db.GetAPIKey(context.TODO(), sqlc.GetAPIKeyParams{
+		Domain: domain,
+		ID:     id,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return sqlc.ApiKey{}, sqlc.ApiKey{}, "", ErrInvalidKey
+	}
+	if err != nil {
+		return sqlc.ApiKey{}, sqlc.ApiKey{}, "", err
+	}
+	now := time.Now()
+	if !Active(old, now) {
+		return sqlc.ApiKey{}, sqlc.ApiKey{}, "", ErrInvalidKey
+	}
+
+	apiKey, key, err := m.Create(domain, old.Name, old.Scopes)
+	if err != nil {
+		return sqlc.ApiKey{}, sqlc.ApiKey{}, "", err
+	}
+
+	expired, err := m.db.ExpireAPIKey(context.TODO(), sqlc.ExpireAPIKeyParams{
+		Domain:    domain,
+		ID:        id,
+		ExpiresAt: sql.NullTime{Time: now.Add(overlap), Valid: true},
+	})
+	// the key already expires before the end of the overlap
+	if errors.Is(err, sql.ErrNoRows) {
+		return old, apiKey, key, nil
+	}
+	if err != nil {
+		return sqlc.ApiKey{}, sqlc.ApiKey{}, "", err
+	}
+	return expired, apiKey, key, nil
+}
+
+func (m *manager) LogCall(key sqlc.ApiKey, method string, allowed bool) error {
+	return m.db.CreateAPIKeyCall(context.TODO(), sqlc.CreateAPIKeyCallParams{
+		ApiKeyID: key.ID,
+		Domain:   key.Domain,
+		Method:   method,
+		Allowed:  allowed,
+	})
+}
+
+func (m *manager) GetCalls(domain string, id int32, from time.Time, to time.Time, limit uint) ([]sqlc.ApiKeyCall, error) {
+	return m.db.GetAPIKeyCalls(context.TODO(), sqlc.GetAPIKeyCallsParams{
+		Domain:   domain,
+		ApiKeyID: id,
+		Start:    from,
+		Stop:     to,
+		Max:      int32(limit),
+	})
+}
+
+// generateKey returns a random key of 32 characters
+func generateKey() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashKey is the hex sha256 of a key, as hashed by the migration
+// of the domain keys
+func hashKey(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
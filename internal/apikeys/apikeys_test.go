@@ -0,0 +1,61 @@
+package apikeys
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"kannon.gyozatech.dev/generated/sqlc"
+)
+
+func TestParseBasicAuth(t *testing.T) {
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte("kannon.io:a-b_c:d"))
+	domain, key, ok := ParseBasicAuth(auth)
+	assert.True(t, ok)
+	assert.Equal(t, "kannon.io", domain)
+	assert.Equal(t, "a-b_c:d", key)
+
+	for _, auth := range []string{
+		"Bearer abc",
+		"Basic !!!",
+		"Basic " + base64.StdEncoding.EncodeToString([]byte("kannon.io")),
+		"Basic " + base64.StdEncoding.EncodeToString([]byte("kannon.io:")),
+	} {
+		_, _, ok := ParseBasicAuth(auth)
+		assert.False(t, ok, auth)
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	send := sqlc.ApiKey{Scopes: []string{ScopeSend}}
+	assert.True(t, HasScope(send, ScopeSend))
+	assert.False(t, HasScope(send, ScopeStats))
+
+	admin := sqlc.ApiKey{Scopes: []string{ScopeAdmin}}
+	assert.True(t, HasScope(admin, ScopeSend))
+	assert.True(t, HasScope(admin, ScopeStats))
+}
+
+func TestActive(t *testing.T) {
+	now := time.Now()
+	assert.True(t, Active(sqlc.ApiKey{}, now))
+	assert.True(t, Active(sqlc.ApiKey{ExpiresAt: sql.NullTime{Time: now.Add(time.Hour), Valid: true}}, now))
+	assert.False(t, Active(sqlc.ApiKey{ExpiresAt: sql.NullTime{Time: now, Valid: true}}, now))
+	assert.False(t, Active(sqlc.ApiKey{RevokedAt: sql.NullTime{Time: now, Valid: true}}, now))
+}
+
+func TestValidate(t *testing.T) {
+	assert.Nil(t, Validate("ci", []string{ScopeSend, ScopeStats}))
+	assert.NotNil(t, Validate("ci", nil))
+	assert.NotNil(t, Validate("ci", []string{"write"}))
+}
+
+func TestGenerateKey(t *testing.T) {
+	key, err := generateKey()
+	assert.Nil(t, err)
+	assert.Len(t, key, 32)
+	// same hash as encode(sha256(key::bytea), 'hex') of the migration
+	assert.Equal(t, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", hashKey("abc"))
+}
@@ -0,0 +1,86 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"kannon.gyozatech.dev/internal/s3"
+)
+
+// ErrNotFound is returned by Get for emails not in the archive
+var ErrNotFound = errors.New("email not archived")
+
+// Config configures the archive of the sent emails on S3 or MinIO,
+// like APP_ARCHIVE_BUCKET, an empty Bucket disables the archive
+type Config = s3.Config
+
+// Archive stores the signed RFC 5322 emails sent to the recipients of
+// the messages, keyed by <prefix><domain>/<message id>/<email>.eml
+type Archive interface {
+	Put(ctx context.Context, messageID string, email string, eml []byte) error
+	Get(ctx context.Context, messageID string, email string) ([]byte, error)
+	// Purge deletes the emails of domain archived before a time,
+	// returns the number of deleted emails
+	Purge(ctx context.Context, domain string, before time.Time) (int64, error)
+}
+
+type s3Archive struct {
+	bucket s3.Bucket
+}
+
+// NewArchive builds an Archive on the bucket of config
+func NewArchive(config Config) (Archive, error) {
+	bucket, err := s3.NewBucket(config)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Archive{bucket: bucket}, nil
+}
+
+func (a *s3Archive) Put(ctx context.Context, messageID string, email string, eml []byte) error {
+	key, err := key(messageID, email)
+	if err != nil {
+		return err
+	}
+	return a.bucket.Put(ctx, key, eml, "message/rfc822")
+}
+
+func (a *s3Archive) Get(ctx context.Context, messageID string, email string) ([]byte, error) {
+	key, err := key(messageID, email)
+	if err != nil {
+		return nil, err
+	}
+	eml, err := a.bucket.Get(ctx, key)
+	if errors.Is(err, s3.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	return eml, err
+}
+
+func (a *s3Archive) Purge(ctx context.Context, domain string, before time.Time) (int64, error) {
+	var deleted int64
+	err := a.bucket.List(ctx, domain+"/", func(o s3.Object) error {
+		if !o.LastModified.Before(before) {
+			return nil
+		}
+		if err := a.bucket.Delete(ctx, o.Key); err != nil {
+			return err
+		}
+		deleted++
+		return nil
+	})
+	return deleted, err
+}
+
+// key returns the key of the email of a message recipient,
+// message ids are msg_<id>@<domain>
+func key(messageID string, email string) (string, error) {
+	i := strings.LastIndex(messageID, "@")
+	if i < 0 || strings.Contains(messageID, "/") || strings.Contains(email, "/") {
+		return "", fmt.Errorf("invalid message id %v", messageID)
+	}
+	return fmt.Sprintf("%v/%v/%v.eml", messageID[i+1:], messageID, email), nil
+}
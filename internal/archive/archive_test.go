@@ -0,0 +1,119 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeS3 is a bucket of objects served on the path-style S3 API
+type fakeS3 struct {
+	mu       sync.Mutex
+	objects  map[string][]byte
+	modified map[string]time.Time
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/") || r.Header.Get("X-Amz-Content-Sha256") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`))
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		f.list(w, r.URL.Query().Get("prefix"), r.URL.Query().Get("continuation-token"))
+	case r.Method == http.MethodPut:
+		body, _ := ioutil.ReadAll(r.Body)
+		f.objects[key] = body
+		f.modified[key] = time.Now()
+	case r.Method == http.MethodGet:
+		body, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`<Error><Code>NoSuchKey</Code></Error>`))
+			return
+		}
+		w.Write(body)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// list lists the keys with prefix by pages of 1
+func (f *fakeS3) list(w http.ResponseWriter, prefix string, token string) {
+	var keys []string
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) && key > token {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if len(keys) == 0 {
+		w.Write([]byte(`<ListBucketResult></ListBucketResult>`))
+		return
+	}
+	fmt.Fprintf(w, `<ListBucketResult><Contents><Key>%v</Key><LastModified>%v</LastModified></Contents>
+		<IsTruncated>%v</IsTruncated><NextContinuationToken>%v</NextContinuationToken></ListBucketResult>`,
+		keys[0], f.modified[keys[0]].Format(time.RFC3339), len(keys) > 1, keys[0])
+}
+
+func TestArchive(t *testing.T) {
+	f := &fakeS3{objects: make(map[string][]byte), modified: make(map[string]time.Time)}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	a, err := NewArchive(Config{Endpoint: srv.URL, Region: "eu-west-1", Bucket: "bucket", Prefix: "kannon/", AccessKeyID: "key", SecretAccessKey: "secret"})
+	assert.Nil(t, err)
+	ctx := context.Background()
+
+	assert.Nil(t, a.Put(ctx, "msg_1@kannon.io", "test+1@test.com", []byte("Subject: 1\r\n\r\nhello")))
+	assert.Nil(t, a.Put(ctx, "msg_1@kannon.io", "test@test.com", []byte("Subject: 1\r\n\r\nhello")))
+	assert.Nil(t, a.Put(ctx, "msg_2@other.io", "test@test.com", []byte("Subject: 2\r\n\r\nhello")))
+	assert.Contains(t, f.objects, "kannon/kannon.io/msg_1@kannon.io/test+1@test.com.eml")
+
+	eml, err := a.Get(ctx, "msg_1@kannon.io", "test+1@test.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "Subject: 1\r\n\r\nhello", string(eml))
+
+	_, err = a.Get(ctx, "msg_3@kannon.io", "test@test.com")
+	assert.Equal(t, ErrNotFound, err)
+
+	_, err = a.Get(ctx, "msg_1", "test@test.com")
+	assert.NotNil(t, err)
+
+	// emails archived after the cutoff are kept
+	deleted, err := a.Purge(ctx, "kannon.io", time.Now().Add(-time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), deleted)
+
+	deleted, err = a.Purge(ctx, "kannon.io", time.Now().Add(time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), deleted)
+	assert.Len(t, f.objects, 1)
+}
+
+func TestArchiveError(t *testing.T) {
+	srv := httptest.NewServer(&fakeS3{})
+	defer srv.Close()
+
+	a, err := NewArchive(Config{Endpoint: srv.URL, Bucket: "bucket", AccessKeyID: "wrong"})
+	assert.Nil(t, err)
+	err = a.Put(context.Background(), "msg_1@kannon.io", "test@test.com", nil)
+	assert.EqualError(t, err, "s3 responded 403 Forbidden: AccessDenied Access Denied")
+
+	_, err = NewArchive(Config{})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,139 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/metrics"
+)
+
+// Redacted replaces the secrets in the recorded values
+const Redacted = "[redacted]"
+
+// secretFields are the fields of the API messages with secrets,
+// like the keys returned by CreateAPIKey and the webhook secrets
+var secretFields = map[protoreflect.Name]bool{
+	"key":    true,
+	"secret": true,
+}
+
+// Entry is a mutation of the admin API
+type Entry struct {
+	// Actor is the subject of the token of the caller, empty when the admin API is not authenticated
+	Actor string
+	// Method is the RPC of the mutation, like SetDomainQuota
+	Method string
+	// Domain is the changed domain, empty for mutations without domain
+	Domain  string
+	Request proto.Message
+	// Before and After are the values of the changed resource, nil when there is none
+	Before proto.Message
+	After  proto.Message
+}
+
+// Filter filters the audit logs, empty fields match every log
+type Filter struct {
+	Domain string
+	Actor  string
+	Method string
+	From   time.Time
+	To     time.Time
+}
+
+// Manager records the mutations of the admin API
+type Manager interface {
+	// Record records a mutation, the secrets of its values are redacted
+	Record(ctx context.Context, entry Entry) (sqlc.AuditLog, error)
+	// GetLogs returns the logs matching filter, most recent first,
+	// older than the log with id cursor when cursor is not 0
+	GetLogs(filter Filter, cursor int32, limit uint) ([]sqlc.AuditLog, error)
+}
+
+type manager struct {
+	db *sqlc.Queries
+}
+
+// NewAuditManager builds an Audit Manager
+func NewAuditManager(db *sql.DB) (Manager, error) {
+	return &manager{
+		db: sqlc.New(metrics.InstrumentDB(db)),
+	}, nil
+}
+
+func (m *manager) Record(ctx context.Context, entry Entry) (sqlc.AuditLog, error) {
+	request, err := Marshal(entry.Request)
+	if err != nil {
+		return sqlc.AuditLog{}, err
+	}
+	before, err := Marshal(entry.Before)
+	if err != nil {
+		return sqlc.AuditLog{}, err
+	}
+	after, err := Marshal(entry.After)
+	if err != nil {
+		return sqlc.AuditLog{}, err
+	}
+	return m.db.CreateAuditLog(ctx, sqlc.CreateAuditLogParams{
+		Actor:       entry.Actor,
+		Method:      entry.Method,
+		Domain:      entry.Domain,
+		Request:     request,
+		BeforeValue: before,
+		AfterValue:  after,
+	})
+}
+
+func (m *manager) GetLogs(filter Filter, cursor int32, limit uint) ([]sqlc.AuditLog, error) {
+	return m.db.GetAuditLogs(context.TODO(), sqlc.GetAuditLogsParams{
+		Domain:    filter.Domain,
+		Actor:     filter.Actor,
+		Method:    filter.Method,
+		StartTime: filter.From,
+		EndTime:   filter.To,
+		Cursor:    cursor,
+		Max:       int32(limit),
+	})
+}
+
+// Marshal returns the JSON of msg with its secrets redacted, null when msg is nil
+func Marshal(msg proto.Message) (json.RawMessage, error) {
+	if msg == nil || !msg.ProtoReflect().IsValid() {
+		return json.RawMessage("null"), nil
+	}
+	msg = proto.Clone(msg)
+	redact(msg.ProtoReflect())
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(data), nil
+}
+
+// redact replaces the non empty secrets of m and of its messages
+func redact(m protoreflect.Message) {
+	var secrets []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.Kind() == protoreflect.StringKind && fd.Cardinality() != protoreflect.Repeated && secretFields[fd.Name()]:
+			secrets = append(secrets, fd)
+		case fd.Kind() == protoreflect.MessageKind && fd.IsList():
+			l := v.List()
+			for i := 0; i < l.Len(); i++ {
+				redact(l.Get(i).Message())
+			}
+		case fd.Kind() == protoreflect.MessageKind && !fd.IsMap():
+			redact(v.Message())
+		}
+		return true
+	})
+	// fields are not set while ranging over them
+	for _, fd := range secrets {
+		m.Set(fd, protoreflect.ValueOfString(Redacted))
+	}
+}
@@ -0,0 +1,38 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"kannon.gyozatech.dev/generated/pb"
+)
+
+func TestMarshal(t *testing.T) {
+	domain := &pb.Domain{Domain: "example.com", Key: "admin-key", DkimPubKey: "MIIB"}
+	data, err := Marshal(domain)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"domain": "example.com", "key": "[redacted]", "dkimPubKey": "MIIB"}`, string(data))
+	// the message is not changed
+	assert.Equal(t, "admin-key", domain.Key)
+
+	// secrets of nested messages are redacted
+	data, err = Marshal(&pb.RotateAPIKeyResponse{Key: &pb.APIKey{Id: 1, Key: "k_123"}})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"key": {"id": 1, "key": "[redacted]"}}`, string(data))
+
+	data, err = Marshal(&pb.GetWebhooksResponse{Webhooks: []*pb.Webhook{{Id: 2, Secret: "whsec"}}})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"webhooks": [{"id": 2, "secret": "[redacted]"}]}`, string(data))
+
+	// empty secrets are left empty
+	data, err = Marshal(&pb.Domain{Domain: "example.com"})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"domain": "example.com"}`, string(data))
+
+	data, err = Marshal(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "null", string(data))
+	data, err = Marshal((*pb.Domain)(nil))
+	assert.Nil(t, err)
+	assert.Equal(t, "null", string(data))
+}
@@ -0,0 +1,81 @@
+package bounce
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// ErrNotARF is returned when a message is not an abuse report
+var ErrNotARF = errors.New("message is not an abuse reporting format report")
+
+// Complaint is a feedback report (RFC 5965) sent by a mailbox provider
+// when a recipient marks a message as spam
+type Complaint struct {
+	FeedbackType   string
+	OriginalRcptTo string
+	// MessageID is the Message-ID of the reported message
+	MessageID string
+}
+
+// ParseARF parses a multipart/report feedback report
+func ParseARF(r io.Reader) (Complaint, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return Complaint{}, err
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/report" || !strings.EqualFold(params["report-type"], "feedback-report") {
+		return Complaint{}, ErrNotARF
+	}
+
+	var complaint Complaint
+	var isReport bool
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Complaint{}, err
+		}
+
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		switch partType {
+		case "message/feedback-report":
+			h, err := readHeader(part)
+			if err != nil {
+				return Complaint{}, err
+			}
+			isReport = true
+			complaint.FeedbackType = strings.ToLower(strings.TrimSpace(h.Get("Feedback-Type")))
+			complaint.OriginalRcptTo = address(h.Get("Original-Rcpt-To"))
+		case "message/rfc822", "text/rfc822-headers":
+			h, err := readHeader(part)
+			if err != nil {
+				return Complaint{}, err
+			}
+			complaint.MessageID = strings.TrimSpace(h.Get("Message-Id"))
+		}
+	}
+
+	if !isReport {
+		return Complaint{}, ErrNotARF
+	}
+	return complaint, nil
+}
+
+func readHeader(r io.Reader) (textproto.MIMEHeader, error) {
+	h, err := textproto.NewReader(bufio.NewReader(r)).ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return h, nil
+}
@@ -0,0 +1,52 @@
+package bounce
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const arf = "From: staff@hotmail.com\r\n" +
+	"To: fbl@kannon.io\r\n" +
+	"Subject: complaint about message\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/report; report-type=feedback-report; boundary=\"part1\"\r\n" +
+	"\r\n" +
+	"--part1\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"This is an email abuse report.\r\n" +
+	"--part1\r\n" +
+	"Content-Type: message/feedback-report\r\n" +
+	"\r\n" +
+	"Feedback-Type: abuse\r\n" +
+	"User-Agent: SomeGenerator/1.0\r\n" +
+	"Version: 1\r\n" +
+	"Original-Rcpt-To: <to@email.com>\r\n" +
+	"\r\n" +
+	"--part1\r\n" +
+	"Content-Type: message/rfc822\r\n" +
+	"Content-Disposition: inline\r\n" +
+	"\r\n" +
+	"From: <sender@kannon.io>\r\n" +
+	"Subject: Buy now\r\n" +
+	"Message-ID: <dG9AZW1haWwuY29t/msg_123@kannon.io>\r\n" +
+	"\r\n" +
+	"original body\r\n" +
+	"--part1--\r\n"
+
+func TestParseARF(t *testing.T) {
+	c, err := ParseARF(strings.NewReader(arf))
+	assert.Nil(t, err)
+	assert.Equal(t, Complaint{
+		FeedbackType:   "abuse",
+		OriginalRcptTo: "to@email.com",
+		MessageID:      "<dG9AZW1haWwuY29t/msg_123@kannon.io>",
+	}, c)
+}
+
+func TestParseARFNotReport(t *testing.T) {
+	_, err := ParseARF(strings.NewReader(dsn))
+	assert.Equal(t, ErrNotARF, err)
+}
@@ -0,0 +1,123 @@
+package bounce
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// ErrNotDSN is returned when a message is not a delivery status notification
+var ErrNotDSN = errors.New("message is not a delivery status notification")
+
+// Recipient is the delivery status of a recipient reported by a DSN (RFC 3464)
+type Recipient struct {
+	FinalRecipient string
+	Action         string
+	Status         string
+	DiagnosticCode string
+}
+
+// Failed checks if the delivery to the recipient failed permanently
+// for the reporting MTA, delayed deliveries are still in progress
+func (r Recipient) Failed() bool {
+	return strings.EqualFold(r.Action, "failed")
+}
+
+// Code returns the smtp reply code of the diagnostic,
+// falling back to a generic code of the status class
+func (r Recipient) Code() int {
+	if len(r.DiagnosticCode) >= 3 {
+		if code, err := strconv.Atoi(r.DiagnosticCode[:3]); err == nil {
+			return code
+		}
+	}
+	switch {
+	case strings.HasPrefix(r.Status, "5."):
+		return 550
+	case strings.HasPrefix(r.Status, "4."):
+		return 450
+	default:
+		return 0
+	}
+}
+
+// ParseDSN parses a multipart/report delivery status notification
+// and returns the status of its recipients
+func ParseDSN(r io.Reader) ([]Recipient, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/report" || !strings.EqualFold(params["report-type"], "delivery-status") {
+		return nil, ErrNotDSN
+	}
+
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, ErrNotDSN
+		}
+		if err != nil {
+			return nil, err
+		}
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if partType == "message/delivery-status" || partType == "message/global-delivery-status" {
+			return parseDeliveryStatus(part)
+		}
+	}
+}
+
+// parseDeliveryStatus parses the per-message fields block
+// followed by a block for every recipient
+func parseDeliveryStatus(r io.Reader) ([]Recipient, error) {
+	tp := textproto.NewReader(bufio.NewReader(r))
+
+	// per-message fields
+	if _, err := tp.ReadMIMEHeader(); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	var recipients []Recipient
+	for {
+		h, err := tp.ReadMIMEHeader()
+		if len(h) > 0 {
+			recipients = append(recipients, Recipient{
+				FinalRecipient: address(h.Get("Final-Recipient")),
+				Action:         strings.TrimSpace(h.Get("Action")),
+				Status:         strings.TrimSpace(h.Get("Status")),
+				DiagnosticCode: diagnostic(h.Get("Diagnostic-Code")),
+			})
+		}
+		if err == io.EOF {
+			return recipients, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// address strips the address type from a recipient field, like rfc822;
+func address(field string) string {
+	if i := strings.Index(field, ";"); i >= 0 {
+		field = field[i+1:]
+	}
+	return strings.Trim(strings.TrimSpace(field), "<>")
+}
+
+// diagnostic strips the diagnostic type from a diagnostic code, like smtp;
+func diagnostic(field string) string {
+	if i := strings.Index(field, ";"); i >= 0 {
+		field = field[i+1:]
+	}
+	return strings.Join(strings.Fields(field), " ")
+}
@@ -0,0 +1,62 @@
+package bounce
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const dsn = "From: MAILER-DAEMON@mx.email.com\r\n" +
+	"To: bump_dG9AZW1haWwuY29t-msg_123@kannon.io\r\n" +
+	"Subject: Undelivered Mail Returned to Sender\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/report; report-type=delivery-status; boundary=\"XXX\"\r\n" +
+	"\r\n" +
+	"--XXX\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"Your message could not be delivered.\r\n" +
+	"--XXX\r\n" +
+	"Content-Type: message/delivery-status\r\n" +
+	"\r\n" +
+	"Reporting-MTA: dns; mx.email.com\r\n" +
+	"Arrival-Date: Fri, 28 May 2021 10:00:00 +0000\r\n" +
+	"\r\n" +
+	"Final-Recipient: rfc822; to@email.com\r\n" +
+	"Action: failed\r\n" +
+	"Status: 5.1.1\r\n" +
+	"Diagnostic-Code: smtp; 550 5.1.1 <to@email.com>:\r\n" +
+	"    Recipient address rejected: User unknown\r\n" +
+	"\r\n" +
+	"Final-Recipient: rfc822; other@email.com\r\n" +
+	"Action: delayed\r\n" +
+	"Status: 4.4.1\r\n" +
+	"--XXX--\r\n"
+
+func TestParseDSN(t *testing.T) {
+	recipients, err := ParseDSN(strings.NewReader(dsn))
+	assert.Nil(t, err)
+	assert.Equal(t, []Recipient{
+		{
+			FinalRecipient: "to@email.com",
+			Action:         "failed",
+			Status:         "5.1.1",
+			DiagnosticCode: "550 5.1.1 <to@email.com>: Recipient address rejected: User unknown",
+		},
+		{
+			FinalRecipient: "other@email.com",
+			Action:         "delayed",
+			Status:         "4.4.1",
+		},
+	}, recipients)
+	assert.True(t, recipients[0].Failed())
+	assert.Equal(t, 550, recipients[0].Code())
+	assert.Equal(t, 450, recipients[1].Code())
+	assert.False(t, recipients[1].Failed())
+}
+
+func TestParseDSNNotReport(t *testing.T) {
+	_, err := ParseDSN(strings.NewReader("Subject: hello\r\nContent-Type: text/plain\r\n\r\nhello\r\n"))
+	assert.Equal(t, ErrNotDSN, err)
+}
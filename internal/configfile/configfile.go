@@ -0,0 +1,482 @@
+package configfile
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/kelseyhightower/envconfig"
+	"gopkg.in/yaml.v3"
+	"kannon.gyozatech.dev/internal/logging"
+)
+
+var log = logging.Logger("config")
+
+// EnvVar is the environment variable with the path of the config file
+const EnvVar = "KANNON_CONFIG"
+
+// prefix is the prefix of the environment variables of the daemons
+const prefix = "app"
+
+// WatchInterval is the interval of the checks of the changes of the config file
+const WatchInterval = 10 * time.Second
+
+// databaseKey is the database of the daemons, set as DATABASE_URL and DB_CONN
+const databaseKey = "database_url"
+
+// Daemons are the sections of the settings of single daemons
+var Daemons = []string{"api", "bouncer", "dispatcher", "purger", "sender", "stats", "tracker", "verifier", "webhooks"}
+
+// setting is a value of the config file and its environment variable
+type setting struct {
+	// name is the path of the setting in the file, like api.oidc.issuer
+	name  string
+	key   string
+	value string
+}
+
+// loaded are the environment variables and the flags set by the config file, a
+// reload replaces them and they don't hide the environment and the command line
+var loaded = struct {
+	sync.Mutex
+	env   map[string]bool
+	flags map[string]bool
+}{
+	env:   make(map[string]bool),
+	flags: make(map[string]bool),
+}
+
+// Load fills spec like envconfig.Process("app", spec), with the settings of the config
+// file of KANNON_CONFIG when set. The top level settings are shared by every daemon,
+// like natsconn or tls.certfile for APP_NATSCONN and APP_TLS_CERTFILE, and the section
+// of daemon overrides them. Environment variables override the file, unknown settings
+// of the section of daemon and invalid values are errors naming the setting.
+// Load can be called again to read the changes of the file
+func Load(daemon string, spec interface{}) error {
+	loaded.Lock()
+	defer loaded.Unlock()
+
+	path := os.Getenv(EnvVar)
+	origins := make(map[string]string)
+	if path != "" {
+		file, err := read(path)
+		if err != nil {
+			return err
+		}
+		settings, err := envSettings(file, daemon, spec)
+		if err != nil {
+			return fmt.Errorf("%v: %w", path, err)
+		}
+		for _, s := range settings {
+			if _, ok := os.LookupEnv(s.key); ok && origins[s.key] == "" && !loaded.env[s.key] {
+				continue
+			}
+			if err := os.Setenv(s.key, s.value); err != nil {
+				return err
+			}
+			origins[s.key] = s.name
+		}
+	}
+	// the settings removed from the file
+	for key := range loaded.env {
+		if _, ok := origins[key]; !ok {
+			os.Unsetenv(key)
+		}
+	}
+	loaded.env = make(map[string]bool, len(origins))
+	for key := range origins {
+		loaded.env[key] = true
+	}
+
+	err := envconfig.Process(prefix, spec)
+	var parseErr *envconfig.ParseError
+	if errors.As(err, &parseErr) {
+		if name, ok := origins[parseErr.KeyName]; ok {
+			return fmt.Errorf("%v: invalid %v %q: %v", path, name, parseErr.Value, parseErr.Err)
+		}
+	}
+	return err
+}
+
+// LoadFlags sets the flags of fs not set on the command line with the section of daemon
+// of the config file of KANNON_CONFIG, for the daemons configured by flags. The keys
+// of the section are the names of the flags, like workers for -workers. LoadFlags can
+// be called again to read the changes of the file, removed flags get their default
+func LoadFlags(daemon string, fs *flag.FlagSet) error {
+	loaded.Lock()
+	defer loaded.Unlock()
+
+	path := os.Getenv(EnvVar)
+	if path == "" {
+		return nil
+	}
+	file, err := read(path)
+	if err != nil {
+		return err
+	}
+	settings, err := flagSettings(file, daemon, fs)
+	if err != nil {
+		return fmt.Errorf("%v: %w", path, err)
+	}
+
+	cmdline := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		if !loaded.flags[f.Name] {
+			cmdline[f.Name] = true
+		}
+	})
+	applied := make(map[string]bool)
+	for _, s := range settings {
+		if cmdline[s.key] {
+			continue
+		}
+		if err := fs.Set(s.key, s.value); err != nil {
+			return fmt.Errorf("%v: invalid %v %q: %v", path, s.name, s.value, err)
+		}
+		applied[s.key] = true
+	}
+	for name := range loaded.flags {
+		if f := fs.Lookup(name); f != nil && !applied[name] {
+			if err := fs.Set(name, f.DefValue); err != nil {
+				return err
+			}
+		}
+	}
+	loaded.flags = applied
+	return nil
+}
+
+// Watch calls reload in background when the process receives SIGHUP and when the
+// modification time of the config file of KANNON_CONFIG changes, checked every
+// WatchInterval. reload loads the config again and applies the settings that can
+// change without a restart, like the log levels
+func Watch(reload func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go watch(signals, os.Getenv(EnvVar), WatchInterval, reload)
+}
+
+func watch(signals <-chan os.Signal, path string, interval time.Duration, reload func()) {
+	var changes <-chan time.Time
+	last := modTime(path)
+	if path != "" {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		changes = ticker.C
+	}
+	for {
+		select {
+		case s := <-signals:
+			log.Infof("received %v, reloading config", s)
+		case <-changes:
+			t := modTime(path)
+			if t.Equal(last) {
+				continue
+			}
+			last = t
+			log.Infof("%v changed, reloading config", path)
+		}
+		reload()
+	}
+}
+
+// modTime returns the modification time of the file of path, zero when it cannot be read
+func modTime(path string) time.Time {
+	if path == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// read decodes the config file of path, YAML or TOML by its extension
+func read(path string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read config file: %w", err)
+	}
+	file := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &file)
+	case ".toml":
+		err = toml.Unmarshal(data, &file)
+	default:
+		return nil, fmt.Errorf("%v: unknown config file format, use .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return file, nil
+}
+
+// envSettings returns the environment variables of daemon set by file,
+// the section of daemon after the shared settings
+func envSettings(file map[string]interface{}, daemon string, spec interface{}) ([]setting, error) {
+	known, err := envKeys(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	var settings []setting
+	for _, name := range sortedKeys(file) {
+		switch v := file[name]; {
+		case isDaemon(name):
+			// the section of daemon is read after the shared settings
+		case strings.EqualFold(name, databaseKey):
+			value, err := envValue(name, v)
+			if err != nil {
+				return nil, err
+			}
+			settings = append(settings, setting{name, "DATABASE_URL", value}, setting{name, "DB_CONN", value})
+		default:
+			// shared settings unknown to daemon are settings of other daemons
+			s, err := flatten(name, strings.ToUpper(prefix+"_"+name), v, known, false)
+			if err != nil {
+				return nil, err
+			}
+			settings = append(settings, s...)
+		}
+	}
+
+	for name, v := range file {
+		if !strings.EqualFold(name, daemon) {
+			continue
+		}
+		section, ok := asMap(v)
+		if !ok {
+			return nil, fmt.Errorf("%v must be a section", name)
+		}
+		for _, key := range sortedKeys(section) {
+			s, err := flatten(name+"."+key, strings.ToUpper(prefix+"_"+key), section[key], known, true)
+			if err != nil {
+				return nil, err
+			}
+			settings = append(settings, s...)
+		}
+	}
+	return settings, nil
+}
+
+// flatten returns the settings of the value v of name, the environment variables of nested
+// sections join their keys with _. Unknown settings are errors when strict, skipped otherwise
+func flatten(name string, key string, v interface{}, known map[string]bool, strict bool) ([]setting, error) {
+	if m, ok := asMap(v); ok && !known[key] {
+		var settings []setting
+		for _, k := range sortedKeys(m) {
+			s, err := flatten(name+"."+k, key+"_"+strings.ToUpper(k), m[k], known, strict)
+			if err != nil {
+				return nil, err
+			}
+			settings = append(settings, s...)
+		}
+		return settings, nil
+	}
+	if !known[key] {
+		if !strict {
+			return nil, nil
+		}
+		return nil, unknownSetting(name, strings.TrimPrefix(key, strings.ToUpper(prefix)+"_"), envNames(known))
+	}
+	value, err := envValue(name, v)
+	if err != nil {
+		return nil, err
+	}
+	return []setting{{name, key, value}}, nil
+}
+
+// envValue returns v as the value of an environment variable: lists are
+// comma separated and maps are comma separated key:value pairs, like envconfig
+func envValue(name string, v interface{}) (string, error) {
+	if m, ok := asMap(v); ok {
+		pairs := make([]string, 0, len(m))
+		for _, k := range sortedKeys(m) {
+			value, err := scalar(name+"."+k, m[k])
+			if err != nil {
+				return "", err
+			}
+			pairs = append(pairs, k+":"+value)
+		}
+		return strings.Join(pairs, ","), nil
+	}
+	return listValue(name, v)
+}
+
+// flagSettings returns the flags set by the section of daemon of file
+func flagSettings(file map[string]interface{}, daemon string, fs *flag.FlagSet) ([]setting, error) {
+	var settings []setting
+	for name, v := range file {
+		if !strings.EqualFold(name, daemon) {
+			continue
+		}
+		section, ok := asMap(v)
+		if !ok {
+			return nil, fmt.Errorf("%v must be a section", name)
+		}
+		for _, key := range sortedKeys(section) {
+			if fs.Lookup(key) == nil {
+				var flags []string
+				fs.VisitAll(func(f *flag.Flag) { flags = append(flags, f.Name) })
+				return nil, unknownSetting(name+"."+key, key, flags)
+			}
+			value, err := listValue(name+"."+key, section[key])
+			if err != nil {
+				return nil, err
+			}
+			settings = append(settings, setting{name + "." + key, key, value})
+		}
+	}
+	return settings, nil
+}
+
+// listValue returns v as a string, lists are comma separated
+func listValue(name string, v interface{}) (string, error) {
+	l, ok := v.([]interface{})
+	if !ok {
+		return scalar(name, v)
+	}
+	values := make([]string, 0, len(l))
+	for _, e := range l {
+		value, err := scalar(name, e)
+		if err != nil {
+			return "", err
+		}
+		values = append(values, value)
+	}
+	return strings.Join(values, ","), nil
+}
+
+func scalar(name string, v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case time.Time:
+		return v.Format(time.RFC3339), nil
+	case map[string]interface{}, map[interface{}]interface{}, []interface{}, []map[string]interface{}:
+		return "", fmt.Errorf("%v must be a value, not a section or a list", name)
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// envKeys returns the environment variables of spec
+func envKeys(spec interface{}) (map[string]bool, error) {
+	var out bytes.Buffer
+	if err := envconfig.Usagef(prefix, spec, &out, "{{range .}}{{usage_key .}}\n{{end}}"); err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool)
+	for _, key := range strings.Fields(out.String()) {
+		known[key] = true
+	}
+	return known, nil
+}
+
+// envNames returns the names of the environment variables of known without prefix
+func envNames(known map[string]bool) []string {
+	p := strings.ToUpper(prefix) + "_"
+	names := make([]string, 0, len(known))
+	for key := range known {
+		names = append(names, strings.TrimPrefix(key, p))
+	}
+	return names
+}
+
+// unknownSetting returns the error of the unknown setting name of a daemon
+// section, with key, the setting without the section, and the closest of
+// names, the settings of the daemon, as suggestion
+func unknownSetting(name string, key string, names []string) error {
+	key = strings.ToLower(key)
+	best, bestDistance := "", 3
+	for _, n := range names {
+		n = strings.ToLower(n)
+		if d := distance(key, n); d < bestDistance {
+			best, bestDistance = n, d
+		}
+	}
+	if best == "" {
+		return fmt.Errorf("unknown setting %v", name)
+	}
+	section := strings.SplitN(name, ".", 2)[0]
+	return fmt.Errorf("unknown setting %v, did you mean %v.%v?", name, section, strings.ReplaceAll(best, "_", "."))
+}
+
+// distance is the Levenshtein distance of a and b
+func distance(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+func isDaemon(name string) bool {
+	for _, d := range Daemons {
+		if strings.EqualFold(name, d) {
+			return true
+		}
+	}
+	return false
+}
+
+// asMap returns v as a section, YAML sections with non string keys have interface keys
+func asMap(v interface{}) (map[string]interface{}, bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return v, true
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = e
+		}
+		return m, true
+	}
+	return nil, false
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
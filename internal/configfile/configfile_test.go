@@ -0,0 +1,229 @@
+package configfile
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type tlsConfig struct {
+	CertFile string
+	KeyFile  string
+}
+
+type testConfig struct {
+	NatsConn     string `default:"nats://127.0.0.1:4222"`
+	KafkaBrokers []string
+	GatewayPort  uint16 `default:"8081"`
+	PollInterval time.Duration
+	GroupRoles   map[string]string
+	TLS          tlsConfig
+}
+
+const testYAML = `
+database_url: postgres://kannon@db/kannon
+natsconn: nats://nats:4222
+kafkabrokers: [kafka-1:9092, kafka-2:9092]
+tls:
+  certfile: /etc/kannon/tls.crt
+# settings of the other daemons
+maxinflight: 1000
+api:
+  gatewayport: 9081
+  grouproles:
+    admins: owner
+    ops: operator
+  tls:
+    keyfile: /etc/kannon/tls.key
+dispatcher:
+  pollinterval: 5s
+sender:
+  workers: 10
+`
+
+// writeConfig writes a config file and sets KANNON_CONFIG to its path
+func writeConfig(t *testing.T, name string, content string) {
+	dir, err := ioutil.TempDir("", "configfile")
+	assert.Nil(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, name)
+	assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0600))
+	setenv(t, EnvVar, path)
+	t.Cleanup(func() {
+		loaded.env = make(map[string]bool)
+		loaded.flags = make(map[string]bool)
+	})
+}
+
+// setenv sets an environment variable for the test
+func setenv(t *testing.T, key string, value string) {
+	old, ok := os.LookupEnv(key)
+	assert.Nil(t, os.Setenv(key, value))
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+// unsetenv removes the environment variables set by the file at the end of the test
+func unsetenv(t *testing.T, keys ...string) {
+	t.Cleanup(func() {
+		for _, k := range keys {
+			os.Unsetenv(k)
+		}
+	})
+}
+
+func TestLoad(t *testing.T) {
+	writeConfig(t, "kannon.yaml", testYAML)
+	unsetenv(t, "DATABASE_URL", "DB_CONN", "APP_NATSCONN", "APP_KAFKABROKERS", "APP_GATEWAYPORT", "APP_GROUPROLES", "APP_TLS_CERTFILE", "APP_TLS_KEYFILE")
+	// environment variables override the file
+	setenv(t, "APP_NATSCONN", "nats://env:4222")
+
+	var config testConfig
+	assert.Nil(t, Load("api", &config))
+	assert.Equal(t, testConfig{
+		NatsConn:     "nats://env:4222",
+		KafkaBrokers: []string{"kafka-1:9092", "kafka-2:9092"},
+		GatewayPort:  9081,
+		GroupRoles:   map[string]string{"admins": "owner", "ops": "operator"},
+		TLS:          tlsConfig{CertFile: "/etc/kannon/tls.crt", KeyFile: "/etc/kannon/tls.key"},
+	}, config)
+	assert.Equal(t, "postgres://kannon@db/kannon", os.Getenv("DATABASE_URL"))
+	assert.Equal(t, "postgres://kannon@db/kannon", os.Getenv("DB_CONN"))
+}
+
+func TestLoadTOML(t *testing.T) {
+	writeConfig(t, "kannon.toml", `
+natsconn = "nats://nats:4222"
+
+[dispatcher]
+pollinterval = "5s"
+kafkabrokers = ["kafka-1:9092"]
+`)
+	unsetenv(t, "APP_NATSCONN", "APP_POLLINTERVAL", "APP_KAFKABROKERS")
+
+	var config testConfig
+	assert.Nil(t, Load("dispatcher", &config))
+	assert.Equal(t, "nats://nats:4222", config.NatsConn)
+	assert.Equal(t, 5*time.Second, config.PollInterval)
+	assert.Equal(t, []string{"kafka-1:9092"}, config.KafkaBrokers)
+}
+
+func TestLoadErrors(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"kannon.yaml", "api:\n  gatewayprot: 9081\n", "unknown setting api.gatewayprot, did you mean api.gatewayport?"},
+		{"kannon.yaml", "api:\n  tls:\n    certfiel: tls.crt\n", "unknown setting api.tls.certfiel, did you mean api.tls.certfile?"},
+		{"kannon.yaml", "api:\n  oidc: true\n", "unknown setting api.oidc"},
+		{"kannon.yaml", "api:\n  gatewayport: http\n", `invalid api.gatewayport "http"`},
+		{"kannon.yaml", "api: 8081\n", "api must be a section"},
+		{"kannon.yaml", "api:\n  natsconn: [[a]]\n", "api.natsconn must be a value"},
+		{"kannon.yaml", "api:\n\tgatewayport: 8081\n", "yaml: line 2"},
+		{"kannon.json", "{}", "unknown config file format"},
+	} {
+		writeConfig(t, c.name, c.content)
+		unsetenv(t, "APP_GATEWAYPORT")
+		var config testConfig
+		err := Load("api", &config)
+		if assert.NotNil(t, err, c.content) {
+			assert.Contains(t, err.Error(), c.err)
+		}
+	}
+}
+
+func TestLoadWithoutFile(t *testing.T) {
+	setenv(t, EnvVar, "")
+	var config testConfig
+	assert.Nil(t, Load("api", &config))
+	assert.Equal(t, uint16(8081), config.GatewayPort)
+}
+
+func TestLoadFlags(t *testing.T) {
+	writeConfig(t, "kannon.yaml", testYAML+"  ipv6: false\n  require-tls: [example.com, example.org]\n")
+
+	fs := flag.NewFlagSet("sender", flag.ContinueOnError)
+	workers := fs.Uint("workers", 100, "")
+	ipv6 := fs.Bool("ipv6", true, "")
+	requireTLS := fs.String("require-tls", "", "")
+	// flags on the command line override the file
+	assert.Nil(t, fs.Parse([]string{"-ipv6=true"}))
+
+	assert.Nil(t, LoadFlags("sender", fs))
+	assert.Equal(t, uint(10), *workers)
+	assert.True(t, *ipv6)
+	assert.Equal(t, "example.com,example.org", *requireTLS)
+
+	// removed flags get their default, flags of the command line are kept
+	assert.Nil(t, ioutil.WriteFile(os.Getenv(EnvVar), []byte("sender:\n  workers: 20\n"), 0600))
+	assert.Nil(t, LoadFlags("sender", fs))
+	assert.Equal(t, uint(20), *workers)
+	assert.True(t, *ipv6)
+	assert.Equal(t, "", *requireTLS)
+
+	writeConfig(t, "kannon.yaml", "sender:\n  worker: 10\n")
+	err := LoadFlags("sender", fs)
+	assert.EqualError(t, err, filepath.Join(filepath.Dir(os.Getenv(EnvVar)), "kannon.yaml")+": unknown setting sender.worker, did you mean sender.workers?")
+}
+
+func TestReload(t *testing.T) {
+	writeConfig(t, "kannon.yaml", "natsconn: nats://nats:4222\napi:\n  gatewayport: 9081\n")
+	unsetenv(t, "APP_NATSCONN", "APP_GATEWAYPORT", "APP_POLLINTERVAL")
+	setenv(t, "APP_POLLINTERVAL", "1s")
+
+	var config testConfig
+	assert.Nil(t, Load("api", &config))
+	assert.Equal(t, uint16(9081), config.GatewayPort)
+
+	// the changes of the file replace its settings, not the environment
+	content := "natsconn: nats://nats-2:4222\npollinterval: 5s\n"
+	assert.Nil(t, ioutil.WriteFile(os.Getenv(EnvVar), []byte(content), 0600))
+	var next testConfig
+	assert.Nil(t, Load("api", &next))
+	assert.Equal(t, "nats://nats-2:4222", next.NatsConn)
+	assert.Equal(t, uint16(8081), next.GatewayPort)
+	assert.Equal(t, time.Second, next.PollInterval)
+}
+
+func TestWatch(t *testing.T) {
+	writeConfig(t, "kannon.yaml", "natsconn: nats://nats:4222\n")
+	path := os.Getenv(EnvVar)
+
+	signals := make(chan os.Signal)
+	reloads := make(chan bool, 2)
+	go watch(signals, path, 10*time.Millisecond, func() { reloads <- true })
+
+	signals <- syscall.SIGHUP
+	select {
+	case <-reloads:
+	case <-time.After(time.Second):
+		t.Fatal("not reloaded on SIGHUP")
+	}
+
+	later := time.Now().Add(time.Minute)
+	assert.Nil(t, os.Chtimes(path, later, later))
+	select {
+	case <-reloads:
+	case <-time.After(time.Second):
+		t.Fatal("not reloaded on change")
+	}
+	// the file is reloaded once
+	select {
+	case <-reloads:
+		t.Fatal("reloaded without changes")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,1351 @@
+package adminapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"kannon.gyozatech.dev/generated/pb"
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/apikeys"
+	"kannon.gyozatech.dev/internal/audit"
+	"kannon.gyozatech.dev/internal/deadletters"
+	"kannon.gyozatech.dev/internal/dkim"
+	"kannon.gyozatech.dev/internal/dnsprovider"
+	"kannon.gyozatech.dev/internal/domains"
+	"kannon.gyozatech.dev/internal/events"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/mailbuilder"
+	"kannon.gyozatech.dev/internal/maintenance"
+	"kannon.gyozatech.dev/internal/pool"
+	"kannon.gyozatech.dev/internal/queue"
+	"kannon.gyozatech.dev/internal/reputation"
+	"kannon.gyozatech.dev/internal/smtp"
+	"kannon.gyozatech.dev/internal/suppressions"
+	"kannon.gyozatech.dev/internal/templates"
+	"kannon.gyozatech.dev/internal/verification"
+	"kannon.gyozatech.dev/internal/webhooks"
+)
+
+var log = logging.Logger("api")
+
+type adminAPIService struct {
+	dm  domains.DomainManager
+	km  apikeys.Manager
+	tm  templates.Manager
+	sm  suppressions.Manager
+	wm  webhooks.Manager
+	pm  pool.SendingPoolManager
+	dlm deadletters.Manager
+	vm  verification.Manager
+	rm  reputation.Manager
+	am  audit.Manager
+	mm  maintenance.Manager
+	p   queue.Publisher
+	// spm is pm on the read database, serving SearchMessages
+	spm pool.SendingPoolManager
+	// dp is the DNS provider of domains, nil when records are not provisioned
+	dp     dnsprovider.Provider
+	dnsTTL int
+}
+
+func (s *adminAPIService) GetDomains(ctx context.Context, in *emptypb.Empty) (*pb.GetDomainsResponse, error) {
+	domains, err := s.dm.GetAllDomains()
+	if err != nil {
+		return nil, err
+	}
+
+	res := pb.GetDomainsResponse{}
+	for _, domain := range domains {
+		res.Domains = append(res.Domains, dbDomainToProtoDomain(domain))
+	}
+	return &res, nil
+}
+
+func (s *adminAPIService) CreateDomain(ctx context.Context, in *pb.CreateDomainRequest) (*pb.Domain, error) {
+	if _, err := s.dm.FindDeletedDomain(in.Domain); err == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "domain %v is deleted, restore it or wait for its purge", in.Domain)
+	}
+
+	domain, err := s.dm.CreateDomain(in.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	_, key, err := s.km.Create(domain.Domain, apikeys.DefaultName, []string{apikeys.ScopeAdmin})
+	if err != nil {
+		return nil, err
+	}
+
+	if s.dp != nil {
+		// the records can be provisioned again with ProvisionDomainDNS
+		if _, err := s.provisionDNS(ctx, domain); err != nil {
+			logging.Domain(log, domain.Domain).Warnf("[🌐 dns] cannot provision records of %v: %v", domain.Domain, err)
+		}
+	}
+
+	res := dbDomainToProtoDomain(domain)
+	res.Key = key
+	return res, nil
+}
+
+func (s *adminAPIService) RegenerateDomainKey(ctx context.Context, in *pb.RegenerateDomainKeyRequest) (*pb.Domain, error) {
+	domain, err := s.dm.FindDomain(in.Domain)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find domain: %v", in.Domain)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, key, err := s.km.Regenerate(domain.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	res := dbDomainToProtoDomain(domain)
+	res.Key = key
+	return res, nil
+}
+
+const maxDeletionGraceDays = 365
+
+func (s *adminAPIService) DeleteDomain(ctx context.Context, in *pb.DeleteDomainRequest) (*pb.DeleteDomainResponse, error) {
+	if in.GraceDays > maxDeletionGraceDays {
+		return nil, status.Errorf(codes.InvalidArgument, "grace days cannot be greater than %v", maxDeletionGraceDays)
+	}
+	grace := domains.DefaultDeletionGracePeriod
+	if in.GraceDays > 0 {
+		grace = time.Duration(in.GraceDays) * 24 * time.Hour
+	}
+
+	domain, canceled, err := s.dm.DeleteDomain(in.Domain, grace)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find domain: %v", in.Domain)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Domain(log, domain.Domain).Infof("[🗑 deleted] %v, %v emails canceled, purge at %v", domain.Domain, canceled, domain.PurgeAt.Time.Format(time.RFC3339))
+	return &pb.DeleteDomainResponse{
+		Domain:         dbDomainToProtoDomain(domain),
+		CanceledEmails: uint32(canceled),
+	}, nil
+}
+
+func (s *adminAPIService) RestoreDomain(ctx context.Context, in *pb.RestoreDomainRequest) (*pb.Domain, error) {
+	domain, err := s.dm.RestoreDomain(in.Domain)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find deleted domain: %v", in.Domain)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return dbDomainToProtoDomain(domain), nil
+}
+
+func (s *adminAPIService) CreateAPIKey(ctx context.Context, in *pb.CreateAPIKeyRequest) (*pb.APIKey, error) {
+	if err := apikeys.Validate(in.Name, in.Scopes); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid api key: %v", err)
+	}
+
+	if _, err := s.dm.FindDomain(in.Domain); err != nil {
+		return nil, status.Errorf(codes.NotFound, "cannot find domain: %v", in.Domain)
+	}
+
+	apiKey, key, err := s.km.Create(in.Domain, in.Name, in.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	res := dbAPIKeyToProtoAPIKey(apiKey)
+	res.Key = key
+	return res, nil
+}
+
+func (s *adminAPIService) GetAPIKeys(ctx context.Context, in *pb.GetAPIKeysRequest) (*pb.GetAPIKeysResponse, error) {
+	keys, err := s.km.GetKeys(in.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	res := pb.GetAPIKeysResponse{}
+	for _, k := range keys {
+		res.Keys = append(res.Keys, dbAPIKeyToProtoAPIKey(k))
+	}
+	return &res, nil
+}
+
+func (s *adminAPIService) RevokeAPIKey(ctx context.Context, in *pb.RevokeAPIKeyRequest) (*pb.APIKey, error) {
+	key, err := s.km.Revoke(in.Domain, in.Id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find active api key: %v", in.Id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return dbAPIKeyToProtoAPIKey(key), nil
+}
+
+func (s *adminAPIService) RotateAPIKey(ctx context.Context, in *pb.RotateAPIKeyRequest) (*pb.RotateAPIKeyResponse, error) {
+	overlap := time.Duration(in.OverlapSeconds) * time.Second
+	if overlap == 0 {
+		overlap = apikeys.DefaultOverlap
+	}
+	if overlap > apikeys.MaxOverlap {
+		return nil, status.Errorf(codes.InvalidArgument, "overlap cannot be longer than %v", apikeys.MaxOverlap)
+	}
+
+	previous, apiKey, key, err := s.km.Rotate(in.Domain, in.Id, overlap)
+	if errors.Is(err, apikeys.ErrInvalidKey) {
+		return nil, status.Errorf(codes.NotFound, "cannot find active api key: %v", in.Id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res := pb.RotateAPIKeyResponse{
+		Previous: dbAPIKeyToProtoAPIKey(previous),
+		Key:      dbAPIKeyToProtoAPIKey(apiKey),
+	}
+	res.Key.Key = key
+	return &res, nil
+}
+
+func (s *adminAPIService) GetAPIKeyCalls(ctx context.Context, in *pb.GetAPIKeyCallsRequest) (*pb.GetAPIKeyCallsResponse, error) {
+	limit := uint(in.Limit)
+	if limit == 0 {
+		limit = 100
+	}
+	if limit > maxSearchLimit {
+		return nil, status.Errorf(codes.InvalidArgument, "limit cannot be greater than %v", maxSearchLimit)
+	}
+
+	var from time.Time
+	to := time.Now()
+	if in.From != nil {
+		from = in.From.AsTime()
+	}
+	if in.To != nil {
+		to = in.To.AsTime()
+	}
+
+	calls, err := s.km.GetCalls(in.Domain, in.ApiKeyId, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	res := pb.GetAPIKeyCallsResponse{}
+	for _, c := range calls {
+		res.Calls = append(res.Calls, &pb.APIKeyCall{
+			ApiKeyId: c.ApiKeyID,
+			Method:   c.Method,
+			Allowed:  c.Allowed,
+			CalledAt: timestamppb.New(c.CalledAt),
+		})
+	}
+	return &res, nil
+}
+
+func (s *adminAPIService) SetDomainRetention(ctx context.Context, in *pb.SetDomainRetentionRequest) (*pb.Domain, error) {
+	domain, err := s.dm.SetRetention(in.Domain, uint(in.RetentionDays))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find domain: %v", in.Domain)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return dbDomainToProtoDomain(domain), nil
+}
+
+func (s *adminAPIService) CreateTemplate(ctx context.Context, in *pb.CreateTemplateRequest) (*pb.Template, error) {
+	if in.Name == "" || len(in.Name) > 100 {
+		return nil, status.Errorf(codes.InvalidArgument, "template name should have 1 to 100 characters")
+	}
+	if err := templates.Validate(in.Html, in.Text, in.Fields); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+
+	if _, err := s.dm.FindDomain(in.Domain); err != nil {
+		return nil, status.Errorf(codes.NotFound, "cannot find domain: %v", in.Domain)
+	}
+
+	template, err := s.tm.CreateNamedTemplate(in.Domain, in.Name, in.Html, in.Text)
+	if errors.Is(err, templates.ErrNameTaken) {
+		return nil, status.Errorf(codes.AlreadyExists, "template %v already exists", in.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return dbTemplateToProtoTemplate(template), nil
+}
+
+func (s *adminAPIService) GetTemplate(ctx context.Context, in *pb.GetTemplateRequest) (*pb.Template, error) {
+	var template sqlc.Template
+	var err error
+	if in.Version == 0 {
+		template, err = s.tm.FindTemplate(in.Domain, in.TemplateId)
+	} else {
+		template, err = s.tm.FindTemplateVersion(in.Domain, in.TemplateId, uint(in.Version))
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find template with id: %v", in.TemplateId)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return dbTemplateToProtoTemplate(template), nil
+}
+
+func (s *adminAPIService) GetTemplates(ctx context.Context, in *pb.GetTemplatesRequest) (*pb.GetTemplatesResponse, error) {
+	templates, err := s.tm.GetTemplates(in.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	res := pb.GetTemplatesResponse{}
+	for _, template := range templates {
+		res.Templates = append(res.Templates, dbTemplateToProtoTemplate(template))
+	}
+	return &res, nil
+}
+
+func (s *adminAPIService) UpdateTemplate(ctx context.Context, in *pb.UpdateTemplateRequest) (*pb.Template, error) {
+	if err := templates.Validate(in.Html, in.Text, in.Fields); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+
+	template, err := s.tm.UpdateTemplate(in.Domain, in.TemplateId, in.Html, in.Text)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find template with id: %v", in.TemplateId)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return dbTemplateToProtoTemplate(template), nil
+}
+
+func (s *adminAPIService) RollbackTemplate(ctx context.Context, in *pb.RollbackTemplateRequest) (*pb.Template, error) {
+	template, err := s.tm.RollbackTemplate(in.Domain, in.TemplateId, uint(in.Version))
+	if errors.Is(err, templates.ErrVersionNotFound) {
+		return nil, status.Errorf(codes.NotFound, "cannot find version %v of template %v", in.Version, in.TemplateId)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return dbTemplateToProtoTemplate(template), nil
+}
+
+func (s *adminAPIService) DeleteTemplate(ctx context.Context, in *pb.DeleteTemplateRequest) (*emptypb.Empty, error) {
+	err := s.tm.DeleteTemplate(in.Domain, in.TemplateId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find template with id: %v", in.TemplateId)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *adminAPIService) SetDomainRateLimit(ctx context.Context, in *pb.SetDomainRateLimitRequest) (*pb.Domain, error) {
+	domain, err := s.dm.SetRateLimit(in.Domain, uint(in.RatePerSecond), uint(in.RatePerHour))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find domain: %v", in.Domain)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return dbDomainToProtoDomain(domain), nil
+}
+
+// maxIPPoolLen is the size of domains.ip_pool
+const maxIPPoolLen = 50
+
+// maxPausedReasonLen is the size of domains.paused_reason
+const maxPausedReasonLen = 254
+
+func (s *adminAPIService) SetDomainQuota(ctx context.Context, in *pb.SetDomainQuotaRequest) (*pb.Domain, error) {
+	if in.DailyQuota > math.MaxInt32 || in.MonthlyQuota > math.MaxInt32 {
+		return nil, status.Errorf(codes.InvalidArgument, "quota greater than %v", math.MaxInt32)
+	}
+	domain, err := s.dm.SetQuota(in.Domain, uint(in.DailyQuota), uint(in.MonthlyQuota))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find domain: %v", in.Domain)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return dbDomainToProtoDomain(domain), nil
+}
+
+func (s *adminAPIService) SetDomainIPPool(ctx context.Context, in *pb.SetDomainIPPoolRequest) (*pb.Domain, error) {
+	if len(in.IpPool) > maxIPPoolLen {
+		return nil, status.Errorf(codes.InvalidArgument, "ip pool longer than %v characters", maxIPPoolLen)
+	}
+	domain, err := s.dm.SetIPPool(in.Domain, in.IpPool)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find domain: %v", in.Domain)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return dbDomainToProtoDomain(domain), nil
+}
+
+func (s *adminAPIService) SetDomainReturnPath(ctx context.Context, in *pb.SetDomainReturnPathRequest) (*pb.Domain, error) {
+	returnPathDomain := strings.ToLower(strings.TrimSuffix(in.ReturnPathDomain, "."))
+	if err := mailbuilder.ValidateReturnPathDomain(in.Domain, returnPathDomain); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	domain, err := s.dm.SetReturnPathDomain(in.Domain, returnPathDomain)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find domain: %v", in.Domain)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return dbDomainToProtoDomain(domain), nil
+}
+
+func (s *adminAPIService) SetDomainSendingSettings(ctx context.Context, in *pb.SetDomainSendingSettingsRequest) (*pb.Domain, error) {
+	settings := mailbuilder.SendingSettings{
+		FromEmail:     strings.TrimSpace(in.DefaultFromEmail),
+		FromName:      strings.TrimSpace(in.DefaultFromName),
+		Headers:       in.DefaultHeaders,
+		OpenTracking:  in.OpenTracking,
+		ClickTracking: in.ClickTracking,
+		FooterHTML:    in.FooterHtml,
+		FooterText:    in.FooterText,
+	}
+	if err := mailbuilder.ValidateSendingSettings(in.Domain, settings); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	domain, err := s.dm.SetSendingSettings(in.Domain, settings)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find domain: %v", in.Domain)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return dbDomainToProtoDomain(domain), nil
+}
+
+func (s *adminAPIService) SetDomainSandbox(ctx context.Context, in *pb.SetDomainSandboxRequest) (*pb.Domain, error) {
+	if in.BouncePercent > smtp.MaxSandboxBouncePercent {
+		return nil, status.Errorf(codes.InvalidArgument, "bounce percent greater than %v", smtp.MaxSandboxBouncePercent)
+	}
+	domain, err := s.dm.SetSandbox(in.Domain, in.Sandbox, uint(in.BouncePercent))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find domain: %v", in.Domain)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return dbDomainToProtoDomain(domain), nil
+}
+
+func (s *adminAPIService) PauseDomain(ctx context.Context, in *pb.PauseDomainRequest) (*pb.Domain, error) {
+	if len(in.Reason) > maxPausedReasonLen {
+		return nil, status.Errorf(codes.InvalidArgument, "reason longer than %v characters", maxPausedReasonLen)
+	}
+	domain, err := s.dm.PauseDomain(in.Domain, in.Reason)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find domain: %v", in.Domain)
+	}
+	if err != nil {
+		return nil, err
+	}
+	logging.Domain(log, domain.Domain).Infof("[⏸ paused] %v: %v", domain.Domain, in.Reason)
+
+	return dbDomainToProtoDomain(domain), nil
+}
+
+func (s *adminAPIService) ResumeDomain(ctx context.Context, in *pb.ResumeDomainRequest) (*pb.Domain, error) {
+	domain, err := s.dm.ResumeDomain(in.Domain)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find domain: %v", in.Domain)
+	}
+	if err != nil {
+		return nil, err
+	}
+	logging.Domain(log, domain.Domain).Infof("[▶️ resumed] %v", domain.Domain)
+
+	return dbDomainToProtoDomain(domain), nil
+}
+
+func (s *adminAPIService) HaltSending(ctx context.Context, in *pb.HaltSendingRequest) (*pb.SendingStatus, error) {
+	state, err := s.mm.Halt(in.Reason)
+	if err != nil {
+		return nil, err
+	}
+	log.Warnf("[🛑 halted] every dispatch and send: %v", in.Reason)
+	// the dispatchers publish the state again every sync interval
+	if err := maintenance.Publish(s.p, state); err != nil {
+		log.Errorf("cannot publish maintenance state: %v", err)
+	}
+
+	return buildSendingStatus(state), nil
+}
+
+func (s *adminAPIService) ResumeSending(ctx context.Context, in *emptypb.Empty) (*pb.SendingStatus, error) {
+	state, err := s.mm.Resume()
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("[▶️ resumed] every dispatch and send")
+	if err := maintenance.Publish(s.p, state); err != nil {
+		log.Errorf("cannot publish maintenance state: %v", err)
+	}
+
+	return buildSendingStatus(state), nil
+}
+
+func (s *adminAPIService) GetSendingStatus(ctx context.Context, in *emptypb.Empty) (*pb.SendingStatus, error) {
+	state, err := s.mm.Get()
+	if err != nil {
+		return nil, err
+	}
+	return buildSendingStatus(state), nil
+}
+
+func buildSendingStatus(state maintenance.State) *pb.SendingStatus {
+	res := &pb.SendingStatus{
+		Halted: state.Halted,
+		Reason: state.Reason,
+	}
+	if !state.ChangedAt.IsZero() {
+		res.ChangedAt = timestamppb.New(state.ChangedAt)
+	}
+	return res
+}
+
+func (s *adminAPIService) GetDomainReputation(ctx context.Context, in *pb.GetDomainReputationRequest) (*pb.DomainReputation, error) {
+	domain, err := s.dm.FindDomain(in.Domain)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find domain: %v", in.Domain)
+	}
+	if err != nil {
+		return nil, err
+	}
+	windows, err := s.rm.GetReputation(domain.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.DomainReputation{
+		Domain:       domain.Domain,
+		PausedReason: domain.PausedReason,
+	}
+	if domain.PausedAt.Valid {
+		res.PausedAt = timestamppb.New(domain.PausedAt.Time)
+	}
+	for _, w := range windows {
+		res.Windows = append(res.Windows, reputationWindowToProto(w))
+	}
+	return res, nil
+}
+
+func (s *adminAPIService) SetDomainDKIMSigning(ctx context.Context, in *pb.SetDomainDKIMSigningRequest) (*pb.Domain, error) {
+	if !dkim.ValidSigning(in.Signing) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid dkim signing: %v", in.Signing)
+	}
+	domain, err := s.dm.SetDKIMSigning(in.Domain, in.Signing)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find domain: %v", in.Domain)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return dbDomainToProtoDomain(domain), nil
+}
+
+func (s *adminAPIService) SetDomainDKIMHeaders(ctx context.Context, in *pb.SetDomainDKIMHeadersRequest) (*pb.Domain, error) {
+	if err := mailbuilder.ValidateDKIMHeaders(in.Headers); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid dkim headers: %v", err)
+	}
+	domain, err := s.dm.SetDKIMHeaders(in.Domain, in.Headers)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find domain: %v", in.Domain)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return dbDomainToProtoDomain(domain), nil
+}
+
+func (s *adminAPIService) RotateDomainDKIMKey(ctx context.Context, in *pb.RotateDomainDKIMKeyRequest) (*pb.DKIMKey, error) {
+	if in.Algorithm != dkim.AlgorithmRSA && in.Algorithm != dkim.AlgorithmEd25519 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid dkim algorithm: %v", in.Algorithm)
+	}
+	if in.Selector != "" && !dkim.ValidSelector(in.Selector) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid dkim selector: %v", in.Selector)
+	}
+	key, err := s.dm.RotateDKIMKey(in.Domain, in.Algorithm, in.Selector)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find domain: %v", in.Domain)
+	}
+	if errors.Is(err, dkim.ErrSelectorInUse) {
+		return nil, status.Errorf(codes.InvalidArgument, "dkim selector %v already in use", in.Selector)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return dbDKIMKeyToProtoDKIMKey(key), nil
+}
+
+func (s *adminAPIService) PromoteDomainDKIMKey(ctx context.Context, in *pb.PromoteDomainDKIMKeyRequest) (*pb.Domain, error) {
+	gracePeriod := domains.DefaultDKIMGracePeriod
+	if in.GraceDays > 0 {
+		gracePeriod = time.Duration(in.GraceDays) * 24 * time.Hour
+	}
+	domain, err := s.dm.PromoteDKIMKey(in.Domain, in.Selector, gracePeriod)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find pending key %v of domain %v", in.Selector, in.Domain)
+	}
+	if errors.Is(err, dkim.ErrRecordNotPublished) {
+		return nil, status.Errorf(codes.FailedPrecondition, "%v", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return dbDomainToProtoDomain(domain), nil
+}
+
+func (s *adminAPIService) GetDomainDKIMKeys(ctx context.Context, in *pb.GetDomainDKIMKeysRequest) (*pb.GetDomainDKIMKeysResponse, error) {
+	domain, err := s.dm.FindDomain(in.Domain)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find domain: %v", in.Domain)
+	}
+	if err != nil {
+		return nil, err
+	}
+	keys, err := s.dm.GetDKIMKeys(in.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	// active keys are the keys of the domain
+	res := pb.GetDomainDKIMKeysResponse{}
+	res.Keys = append(res.Keys, &pb.DKIMKey{
+		Domain:    domain.Domain,
+		Selector:  domain.DkimSelector,
+		Algorithm: dkim.AlgorithmRSA,
+		PublicKey: domain.DkimPublicKey,
+		Status:    "active",
+		CreatedAt: timestamppb.New(domain.CreatedAt),
+	})
+	if domain.DkimEd25519PublicKey != "" {
+		res.Keys = append(res.Keys, &pb.DKIMKey{
+			Domain:    domain.Domain,
+			Selector:  domain.DkimEd25519Selector,
+			Algorithm: dkim.AlgorithmEd25519,
+			PublicKey: domain.DkimEd25519PublicKey,
+			Status:    "active",
+			CreatedAt: timestamppb.New(domain.CreatedAt),
+		})
+	}
+	for _, key := range keys {
+		res.Keys = append(res.Keys, dbDKIMKeyToProtoDKIMKey(key))
+	}
+	return &res, nil
+}
+
+func (s *adminAPIService) VerifyDomain(ctx context.Context, in *pb.VerifyDomainRequest) (*pb.DomainVerification, error) {
+	domain, err := s.dm.FindDomain(in.Domain)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find domain: %v", in.Domain)
+	}
+	if err != nil {
+		return nil, err
+	}
+	domain, records, err := s.vm.VerifyDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	return dbDomainVerificationToProtoDomainVerification(domain, records), nil
+}
+
+func (s *adminAPIService) GetDomainVerification(ctx context.Context, in *pb.GetDomainVerificationRequest) (*pb.DomainVerification, error) {
+	domain, err := s.dm.FindDomain(in.Domain)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find domain: %v", in.Domain)
+	}
+	if err != nil {
+		return nil, err
+	}
+	records, err := s.vm.GetRecords(in.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	return dbDomainVerificationToProtoDomainVerification(domain, records), nil
+}
+
+func (s *adminAPIService) GetDomainDNSRecords(ctx context.Context, in *pb.GetDomainDNSRecordsRequest) (*pb.GetDomainDNSRecordsResponse, error) {
+	domain, err := s.dm.FindDomain(in.Domain)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find domain: %v", in.Domain)
+	}
+	if err != nil {
+		return nil, err
+	}
+	records, err := s.vm.GetDNSRecords(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	res := pb.GetDomainDNSRecordsResponse{}
+	for _, r := range records {
+		res.Records = append(res.Records, dnsRecordToProtoDNSRecord(r))
+	}
+	return &res, nil
+}
+
+func (s *adminAPIService) ProvisionDomainDNS(ctx context.Context, in *pb.ProvisionDomainDNSRequest) (*pb.ProvisionDomainDNSResponse, error) {
+	if s.dp == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "no dns provider configured")
+	}
+	domain, err := s.dm.FindDomain(in.Domain)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find domain: %v", in.Domain)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := s.provisionDNS(ctx, domain)
+	if errors.Is(err, dnsprovider.ErrZoneNotFound) {
+		return nil, status.Errorf(codes.FailedPrecondition, "the dns provider has no zone of %v", in.Domain)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ProvisionDomainDNSResponse{Records: records}, nil
+}
+
+// provisionDNS creates the records of domain with the DNS provider
+func (s *adminAPIService) provisionDNS(ctx context.Context, domain sqlc.Domain) ([]*pb.ProvisionedDNSRecord, error) {
+	expected, err := s.vm.ExpectedRecords(domain)
+	if err != nil {
+		return nil, err
+	}
+	var records []dnsprovider.Record
+	for _, r := range expected {
+		records = append(records, dnsprovider.Record{Type: r.Type, Name: r.Name, Value: r.Value})
+	}
+	results, err := dnsprovider.Provision(ctx, s.dp, domain.Domain, records, s.dnsTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*pb.ProvisionedDNSRecord
+	for i, r := range results {
+		record := &pb.ProvisionedDNSRecord{
+			Record:  expected[i].Record,
+			Type:    r.Type,
+			Name:    r.Name,
+			Value:   r.Value,
+			Changed: r.Changed,
+		}
+		if r.Err != nil {
+			record.Error = r.Err.Error()
+			log.Warnf("[🌐 dns] cannot provision %v record %v: %v", r.Type, r.Name, r.Err)
+		} else if r.Changed {
+			log.Infof("[🌐 dns] provisioned %v record %v", r.Type, r.Name)
+		}
+		res = append(res, record)
+	}
+	return res, nil
+}
+
+func (s *adminAPIService) GetSuppressions(ctx context.Context, in *pb.GetSuppressionsRequest) (*pb.GetSuppressionsResponse, error) {
+	suppressions, err := s.sm.GetSuppressions(in.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	res := pb.GetSuppressionsResponse{}
+	for _, suppression := range suppressions {
+		res.Suppressions = append(res.Suppressions, dbSuppressionToProtoSuppression(suppression))
+	}
+	return &res, nil
+}
+
+func (s *adminAPIService) AddSuppression(ctx context.Context, in *pb.AddSuppressionRequest) (*pb.Suppression, error) {
+	if !smtp.Validate(in.Email) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid email address: %v", in.Email)
+	}
+
+	if _, err := s.dm.FindDomain(in.Domain); err != nil {
+		return nil, status.Errorf(codes.NotFound, "cannot find domain: %v", in.Domain)
+	}
+
+	suppression, err := s.sm.Suppress(in.Domain, in.Email, sqlc.SuppressionReasonManual)
+	if err != nil {
+		return nil, err
+	}
+
+	return dbSuppressionToProtoSuppression(suppression), nil
+}
+
+func (s *adminAPIService) RemoveSuppression(ctx context.Context, in *pb.RemoveSuppressionRequest) (*emptypb.Empty, error) {
+	removed, err := s.sm.Unsuppress(in.Domain, in.Email)
+	if err != nil {
+		return nil, err
+	}
+	if !removed {
+		return nil, status.Errorf(codes.NotFound, "%v is not suppressed", in.Email)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *adminAPIService) CreateWebhook(ctx context.Context, in *pb.CreateWebhookRequest) (*pb.Webhook, error) {
+	u, err := url.Parse(in.Url)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid webhook url: %v", in.Url)
+	}
+
+	types, err := events.ParseTypes(in.Events)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	if len(types) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "webhook should subscribe at least an event")
+	}
+
+	if _, err := s.dm.FindDomain(in.Domain); err != nil {
+		return nil, status.Errorf(codes.NotFound, "cannot find domain: %v", in.Domain)
+	}
+
+	webhook, err := s.wm.CreateWebhook(in.Domain, in.Url, types)
+	if err != nil {
+		return nil, err
+	}
+
+	return dbWebhookToProtoWebhook(webhook), nil
+}
+
+func (s *adminAPIService) GetWebhooks(ctx context.Context, in *pb.GetWebhooksRequest) (*pb.GetWebhooksResponse, error) {
+	webhooks, err := s.wm.GetWebhooks(in.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	res := pb.GetWebhooksResponse{}
+	for _, webhook := range webhooks {
+		res.Webhooks = append(res.Webhooks, dbWebhookToProtoWebhook(webhook))
+	}
+	return &res, nil
+}
+
+func (s *adminAPIService) DeleteWebhook(ctx context.Context, in *pb.DeleteWebhookRequest) (*emptypb.Empty, error) {
+	deleted, err := s.wm.DeleteWebhook(in.Domain, in.Id)
+	if err != nil {
+		return nil, err
+	}
+	if !deleted {
+		return nil, status.Errorf(codes.NotFound, "cannot find webhook: %v", in.Id)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *adminAPIService) GetWebhookDeliveries(ctx context.Context, in *pb.GetWebhookDeliveriesRequest) (*pb.GetWebhookDeliveriesResponse, error) {
+	limit := uint(in.Limit)
+	if limit == 0 {
+		limit = 100
+	}
+
+	deliveries, err := s.wm.GetDeliveries(in.Domain, in.WebhookId, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	res := pb.GetWebhookDeliveriesResponse{}
+	for _, d := range deliveries {
+		res.Deliveries = append(res.Deliveries, &pb.WebhookDelivery{
+			Id:              d.ID,
+			Event:           d.Event,
+			Payload:         string(d.Payload),
+			Status:          string(d.Status),
+			Attempts:        d.Attempts,
+			ResponseCode:    d.ResponseCode,
+			ErrorMsg:        d.ErrorMsg,
+			CreatedAt:       timestamppb.New(d.CreatedAt),
+			NextAttemptTime: timestamppb.New(d.NextAttemptTime),
+		})
+	}
+	return &res, nil
+}
+
+// maxSearchLimit is the max number of emails of a search page
+const maxSearchLimit = 1000
+
+func (s *adminAPIService) SearchMessages(ctx context.Context, in *pb.SearchMessagesRequest) (*pb.SearchMessagesResponse, error) {
+	limit := uint(in.Limit)
+	if limit == 0 {
+		limit = 100
+	}
+	if limit > maxSearchLimit {
+		return nil, status.Errorf(codes.InvalidArgument, "limit cannot be greater than %v", maxSearchLimit)
+	}
+
+	poolStatus, err := parseSendingPoolStatus(in.Status)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := pool.SearchFilter{
+		Domain:     in.Domain,
+		Email:      in.Email,
+		TemplateID: in.TemplateId,
+		Status:     poolStatus,
+		To:         time.Now(),
+	}
+	if in.From != nil {
+		filter.From = in.From.AsTime()
+	}
+	if in.To != nil {
+		filter.To = in.To.AsTime()
+	}
+
+	cursor, err := parseCursor(in.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	emails, err := s.spm.SearchMessages(filter, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	res := pb.SearchMessagesResponse{}
+	for _, e := range emails {
+		res.Emails = append(res.Emails, &pb.MessageEmail{
+			MessageId:   e.MessageID,
+			Domain:      e.Domain,
+			TemplateId:  e.TemplateID,
+			Subject:     e.Subject,
+			SenderEmail: e.SenderEmail,
+			Email:       e.Email,
+			Status:      string(e.Status),
+			BounceType:  string(e.BounceType),
+			ErrorMsg:    e.ErrorMsg,
+			CreatedAt:   timestamppb.New(e.CreatedAt),
+		})
+	}
+	if uint(len(emails)) == limit {
+		res.NextCursor = strconv.Itoa(int(emails[len(emails)-1].ID))
+	}
+	return &res, nil
+}
+
+func (s *adminAPIService) GetDeadLetters(ctx context.Context, in *pb.GetDeadLettersRequest) (*pb.GetDeadLettersResponse, error) {
+	limit := uint(in.Limit)
+	if limit == 0 {
+		limit = 100
+	}
+
+	letters, err := s.dlm.GetDeadLetters(in.Domain, in.IncludeRequeued, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	res := pb.GetDeadLettersResponse{}
+	for _, l := range letters {
+		res.DeadLetters = append(res.DeadLetters, dbDeadLetterToProtoDeadLetter(l))
+	}
+	return &res, nil
+}
+
+func (s *adminAPIService) RequeueDeadLetter(ctx context.Context, in *pb.RequeueDeadLetterRequest) (*pb.DeadLetterEntry, error) {
+	letter, err := s.dlm.Requeue(in.Id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find dead letter to requeue: %v", in.Id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return dbDeadLetterToProtoDeadLetter(letter), nil
+}
+
+func (s *adminAPIService) GetAuditLogs(ctx context.Context, in *pb.GetAuditLogsRequest) (*pb.GetAuditLogsResponse, error) {
+	limit := uint(in.Limit)
+	if limit == 0 {
+		limit = 100
+	}
+	if limit > maxSearchLimit {
+		return nil, status.Errorf(codes.InvalidArgument, "limit cannot be greater than %v", maxSearchLimit)
+	}
+
+	filter := audit.Filter{
+		Domain: in.Domain,
+		Actor:  in.Actor,
+		Method: in.Method,
+		To:     time.Now(),
+	}
+	if in.From != nil {
+		filter.From = in.From.AsTime()
+	}
+	if in.To != nil {
+		filter.To = in.To.AsTime()
+	}
+
+	cursor, err := parseCursor(in.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	logs, err := s.am.GetLogs(filter, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	res := pb.GetAuditLogsResponse{}
+	for _, l := range logs {
+		auditLog, err := dbAuditLogToProtoAuditLog(l)
+		if err != nil {
+			return nil, err
+		}
+		res.Logs = append(res.Logs, auditLog)
+	}
+	if uint(len(logs)) == limit {
+		res.NextCursor = strconv.Itoa(int(logs[len(logs)-1].ID))
+	}
+	return &res, nil
+}
+
+func CreateAdminAPIService(db *sql.DB, readDB *sql.DB, p queue.Publisher, verificationConfig verification.Config, dnsConfig dnsprovider.Config) (pb.ApiServer, error) {
+	log.Infof("Connected to db\n")
+	dm, err := domains.NewDomainManager(db)
+	if err != nil {
+		return nil, err
+	}
+	km, err := apikeys.NewAPIKeyManager(db)
+	if err != nil {
+		return nil, err
+	}
+	tm, err := templates.NewTemplateManager(db)
+	if err != nil {
+		return nil, err
+	}
+	sm, err := suppressions.NewSuppressionManager(db)
+	if err != nil {
+		return nil, err
+	}
+	wm, err := webhooks.NewWebhookManager(db)
+	if err != nil {
+		return nil, err
+	}
+	pm, err := pool.NewSendingPoolManager(db)
+	if err != nil {
+		return nil, err
+	}
+	spm, err := pool.NewSendingPoolManager(readDB)
+	if err != nil {
+		return nil, err
+	}
+	dlm, err := deadletters.NewDeadLetterManager(db, p)
+	if err != nil {
+		return nil, err
+	}
+	vm, err := verification.NewVerificationManager(db, verificationConfig)
+	if err != nil {
+		return nil, err
+	}
+	rm, err := reputation.NewReputationManager(db, reputation.Config{})
+	if err != nil {
+		return nil, err
+	}
+	am, err := audit.NewAuditManager(db)
+	if err != nil {
+		return nil, err
+	}
+	mm, err := maintenance.NewManager(db)
+	if err != nil {
+		return nil, err
+	}
+	dp, err := dnsprovider.New(dnsConfig, &http.Client{Timeout: 30 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	dnsTTL := dnsConfig.TTL
+	if dnsTTL == 0 {
+		dnsTTL = dnsprovider.DefaultTTL
+	}
+	api := adminAPIService{
+		dm:  dm,
+		km:  km,
+		tm:  tm,
+		sm:  sm,
+		wm:  wm,
+		pm:  pm,
+		spm: spm,
+		dlm: dlm,
+		vm:  vm,
+		rm:  rm,
+		am:  am,
+		mm:  mm,
+		p:   p,
+
+		dp:     dp,
+		dnsTTL: dnsTTL,
+	}
+
+	return &api, nil
+}
+
+func dbDomainToProtoDomain(in sqlc.Domain) *pb.Domain {
+	res := &pb.Domain{
+		Domain:               in.Domain,
+		DkimPubKey:           in.DkimPublicKey,
+		RetentionDays:        uint32(in.RetentionDays),
+		RatePerSecond:        uint32(in.RatePerSecond),
+		RatePerHour:          uint32(in.RatePerHour),
+		IpPool:               in.IpPool,
+		DkimEd25519PubKey:    in.DkimEd25519PublicKey,
+		DkimSigning:          in.DkimSigning,
+		DkimSelector:         in.DkimSelector,
+		DkimEd25519Selector:  in.DkimEd25519Selector,
+		DkimHeaders:          in.DkimHeaders,
+		Verified:             in.Verified,
+		ReturnPathDomain:     in.ReturnPathDomain,
+		DailyQuota:           uint32(in.DailyQuota),
+		MonthlyQuota:         uint32(in.MonthlyQuota),
+		DefaultFromEmail:     in.DefaultFromEmail,
+		DefaultFromName:      in.DefaultFromName,
+		OpenTracking:         in.OpenTracking,
+		ClickTracking:        in.ClickTracking,
+		FooterHtml:           in.FooterHtml,
+		FooterText:           in.FooterText,
+		Sandbox:              in.Sandbox,
+		SandboxBouncePercent: uint32(in.SandboxBouncePercent),
+	}
+	if headers, err := mailbuilder.ParseDefaultHeaders(in.DefaultHeaders); err == nil {
+		res.DefaultHeaders = headers
+	}
+	if in.PausedAt.Valid {
+		res.PausedAt = timestamppb.New(in.PausedAt.Time)
+		res.PausedReason = in.PausedReason
+	}
+	if in.DeletedAt.Valid {
+		res.DeletedAt = timestamppb.New(in.DeletedAt.Time)
+	}
+	if in.PurgeAt.Valid {
+		res.PurgeAt = timestamppb.New(in.PurgeAt.Time)
+	}
+	return res
+}
+
+func reputationWindowToProto(in reputation.Window) *pb.ReputationWindow {
+	return &pb.ReputationWindow{
+		Hours:         uint32(in.Duration / time.Hour),
+		Sent:          in.Sent,
+		Delivered:     in.Delivered,
+		Bounced:       in.Bounced,
+		Blocked:       in.Blocked,
+		Complained:    in.Complained,
+		BounceRate:    in.BounceRate,
+		BlockRate:     in.BlockRate,
+		ComplaintRate: in.ComplaintRate,
+	}
+}
+
+func dbDomainVerificationToProtoDomainVerification(domain sqlc.Domain, records []sqlc.DomainRecord) *pb.DomainVerification {
+	res := &pb.DomainVerification{
+		Domain:   domain.Domain,
+		Verified: domain.Verified,
+	}
+	for _, r := range records {
+		res.Records = append(res.Records, &pb.DomainRecord{
+			Record:    r.Record,
+			Status:    string(r.Status),
+			Error:     r.Error,
+			CheckedAt: timestamppb.New(r.CheckedAt),
+		})
+	}
+	return res
+}
+
+func dnsRecordToProtoDNSRecord(in verification.DNSRecord) *pb.DNSRecord {
+	record := &pb.DNSRecord{
+		Record:    in.Record,
+		Type:      in.Type,
+		Name:      in.Name,
+		Value:     in.Value,
+		Published: in.Published,
+		Valid:     in.Err == nil,
+	}
+	if in.Err != nil {
+		record.Error = in.Err.Error()
+	}
+	return record
+}
+
+func dbDKIMKeyToProtoDKIMKey(in sqlc.DkimKey) *pb.DKIMKey {
+	key := &pb.DKIMKey{
+		Domain:    in.Domain,
+		Selector:  in.Selector,
+		Algorithm: in.Algorithm,
+		PublicKey: in.PublicKey,
+		Status:    string(in.Status),
+		CreatedAt: timestamppb.New(in.CreatedAt),
+	}
+	if in.RetireAt.Valid {
+		key.RetireAt = timestamppb.New(in.RetireAt.Time)
+	}
+	return key
+}
+
+func dbTemplateToProtoTemplate(in sqlc.Template) *pb.Template {
+	return &pb.Template{
+		TemplateId: in.TemplateID,
+		Domain:     in.Domain,
+		Version:    uint32(in.Version),
+		Active:     in.Active,
+		Html:       in.Html,
+		Text:       in.Text,
+		Name:       in.Name,
+		Fields:     templates.Fields(in.Html + "\n" + in.Text),
+	}
+}
+
+func dbSuppressionToProtoSuppression(in sqlc.Suppression) *pb.Suppression {
+	return &pb.Suppression{
+		Domain:    in.Domain,
+		Email:     in.Email,
+		Reason:    string(in.Reason),
+		CreatedAt: timestamppb.New(in.CreatedAt),
+	}
+}
+
+func dbAPIKeyToProtoAPIKey(in sqlc.ApiKey) *pb.APIKey {
+	res := &pb.APIKey{
+		Id:        in.ID,
+		Domain:    in.Domain,
+		Name:      in.Name,
+		Prefix:    in.Prefix,
+		Scopes:    in.Scopes,
+		CreatedAt: timestamppb.New(in.CreatedAt),
+	}
+	if in.LastUsedAt.Valid {
+		res.LastUsedAt = timestamppb.New(in.LastUsedAt.Time)
+	}
+	if in.RevokedAt.Valid {
+		res.RevokedAt = timestamppb.New(in.RevokedAt.Time)
+	}
+	if in.ExpiresAt.Valid {
+		res.ExpiresAt = timestamppb.New(in.ExpiresAt.Time)
+	}
+	return res
+}
+
+func dbWebhookToProtoWebhook(in sqlc.Webhook) *pb.Webhook {
+	return &pb.Webhook{
+		Id:        in.ID,
+		Domain:    in.Domain,
+		Url:       in.Url,
+		Secret:    in.Secret,
+		Events:    in.Events,
+		CreatedAt: timestamppb.New(in.CreatedAt),
+	}
+}
+
+func parseSendingPoolStatus(in string) (sqlc.SendingPoolStatus, error) {
+	switch s := sqlc.SendingPoolStatus(in); s {
+	case "", sqlc.SendingPoolStatusScheduled, sqlc.SendingPoolStatusSending, sqlc.SendingPoolStatusSent,
+		sqlc.SendingPoolStatusError, sqlc.SendingPoolStatusSuppressed, sqlc.SendingPoolStatusQuotaExceeded,
+		sqlc.SendingPoolStatusCanceled:
+		return s, nil
+	default:
+		return "", status.Errorf(codes.InvalidArgument, "invalid status: %v", in)
+	}
+}
+
+func parseCursor(in string) (int32, error) {
+	if in == "" {
+		return 0, nil
+	}
+	cursor, err := strconv.ParseInt(in, 10, 32)
+	if err != nil || cursor <= 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid cursor: %v", in)
+	}
+	return int32(cursor), nil
+}
+
+func dbAuditLogToProtoAuditLog(in sqlc.AuditLog) (*pb.AuditLog, error) {
+	res := &pb.AuditLog{
+		Id:        in.ID,
+		Actor:     in.Actor,
+		Method:    in.Method,
+		Domain:    in.Domain,
+		Request:   &structpb.Value{},
+		Before:    &structpb.Value{},
+		After:     &structpb.Value{},
+		CreatedAt: timestamppb.New(in.CreatedAt),
+	}
+	for _, v := range []struct {
+		json  []byte
+		value *structpb.Value
+	}{
+		{in.Request, res.Request},
+		{in.BeforeValue, res.Before},
+		{in.AfterValue, res.After},
+	} {
+		if err := protojson.Unmarshal(v.json, v.value); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func dbDeadLetterToProtoDeadLetter(in sqlc.DeadLetter) *pb.DeadLetterEntry {
+	res := &pb.DeadLetterEntry{
+		Id:        in.ID,
+		Domain:    in.Domain,
+		Subject:   in.Subject,
+		Payload:   in.Payload,
+		Reason:    in.Reason,
+		MessageId: in.MessageID,
+		Email:     in.Email,
+		CreatedAt: timestamppb.New(in.CreatedAt),
+	}
+	if in.RequeuedAt.Valid {
+		res.RequeuedAt = timestamppb.New(in.RequeuedAt.Time)
+	}
+	return res
+}
@@ -0,0 +1,215 @@
+package adminapi
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"kannon.gyozatech.dev/generated/pb"
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/domains"
+	"kannon.gyozatech.dev/internal/templates"
+)
+
+// fakeDomains finds the domains of a list
+type fakeDomains struct {
+	domains.DomainManager
+	domains []string
+}
+
+func (f *fakeDomains) FindDomain(d string) (sqlc.Domain, error) {
+	for _, domain := range f.domains {
+		if domain == d {
+			return sqlc.Domain{Domain: d}, nil
+		}
+	}
+	return sqlc.Domain{}, sql.ErrNoRows
+}
+
+// fakeTemplates keeps the versions of the templates in memory,
+// like the templates table
+type fakeTemplates struct {
+	templates.Manager
+	versions []sqlc.Template
+	deleted  map[string]bool
+}
+
+func (f *fakeTemplates) find(domain string, templateID string, match func(sqlc.Template) bool) (sqlc.Template, error) {
+	for _, t := range f.versions {
+		if t.Domain == domain && t.TemplateID == templateID && !f.deleted[t.TemplateID] && match(t) {
+			return t, nil
+		}
+	}
+	return sqlc.Template{}, sql.ErrNoRows
+}
+
+func (f *fakeTemplates) FindTemplate(domain string, templateID string) (sqlc.Template, error) {
+	return f.find(domain, templateID, func(t sqlc.Template) bool { return t.Active })
+}
+
+func (f *fakeTemplates) FindTemplateVersion(domain string, templateID string, version uint) (sqlc.Template, error) {
+	return f.find(domain, templateID, func(t sqlc.Template) bool { return t.Version == int32(version) })
+}
+
+func (f *fakeTemplates) CreateNamedTemplate(domain string, name string, html string, text string) (sqlc.Template, error) {
+	for _, t := range f.versions {
+		if t.Domain == domain && t.Name == name && !f.deleted[t.TemplateID] {
+			return sqlc.Template{}, templates.ErrNameTaken
+		}
+	}
+	t := sqlc.Template{
+		TemplateID: fmt.Sprintf("template_%v@%v", len(f.versions), domain),
+		Domain:     domain,
+		Name:       name,
+		Html:       html,
+		Text:       text,
+		Version:    1,
+		Active:     true,
+	}
+	f.versions = append(f.versions, t)
+	return t, nil
+}
+
+func (f *fakeTemplates) GetTemplates(domain string) ([]sqlc.Template, error) {
+	var res []sqlc.Template
+	for _, t := range f.versions {
+		if t.Domain == domain && t.Name != "" && t.Active && !f.deleted[t.TemplateID] {
+			res = append(res, t)
+		}
+	}
+	return res, nil
+}
+
+func (f *fakeTemplates) DeleteTemplate(domain string, templateID string) error {
+	if _, err := f.FindTemplate(domain, templateID); err != nil {
+		return err
+	}
+	f.deleted[templateID] = true
+	return nil
+}
+
+func newTemplatesService() *adminAPIService {
+	return &adminAPIService{
+		dm: &fakeDomains{domains: []string{"kannon.io"}},
+		tm: &fakeTemplates{deleted: make(map[string]bool)},
+	}
+}
+
+func assertCode(t *testing.T, code codes.Code, err error) {
+	t.Helper()
+	assert.Equal(t, code, status.Code(err), err)
+}
+
+func TestCreateTemplate(t *testing.T) {
+	s := newTemplatesService()
+	ctx := context.Background()
+
+	template, err := s.CreateTemplate(ctx, &pb.CreateTemplateRequest{
+		Domain: "kannon.io",
+		Name:   "welcome",
+		Html:   "<p>Hello {{ name }}</p>",
+		Fields: []string{"name"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "welcome", template.Name)
+	assert.Equal(t, uint32(1), template.Version)
+	assert.Equal(t, []string{"name"}, template.Fields)
+
+	_, err = s.CreateTemplate(ctx, &pb.CreateTemplateRequest{Domain: "kannon.io", Name: "welcome", Html: "<p>Hi</p>"})
+	assertCode(t, codes.AlreadyExists, err)
+
+	_, err = s.CreateTemplate(ctx, &pb.CreateTemplateRequest{Domain: "unknown.io", Name: "welcome", Html: "<p>Hi</p>"})
+	assertCode(t, codes.NotFound, err)
+
+	_, err = s.CreateTemplate(ctx, &pb.CreateTemplateRequest{Domain: "kannon.io", Html: "<p>Hi</p>"})
+	assertCode(t, codes.InvalidArgument, err)
+
+	_, err = s.CreateTemplate(ctx, &pb.CreateTemplateRequest{Domain: "kannon.io", Name: "invoice", Html: "<p>Hi {{ name }}</p>", Fields: []string{"total"}})
+	assertCode(t, codes.InvalidArgument, err)
+
+	// the names of deleted templates are used again
+	_, err = s.DeleteTemplate(ctx, &pb.DeleteTemplateRequest{Domain: "kannon.io", TemplateId: template.TemplateId})
+	assert.Nil(t, err)
+	_, err = s.CreateTemplate(ctx, &pb.CreateTemplateRequest{Domain: "kannon.io", Name: "welcome", Html: "<p>Hi</p>"})
+	assert.Nil(t, err)
+}
+
+func TestGetTemplate(t *testing.T) {
+	s := newTemplatesService()
+	ctx := context.Background()
+	tm := s.tm.(*fakeTemplates)
+	tm.versions = []sqlc.Template{
+		{TemplateID: "template_1@kannon.io", Domain: "kannon.io", Name: "welcome", Html: "<p>v1</p>", Version: 1},
+		{TemplateID: "template_1@kannon.io", Domain: "kannon.io", Name: "welcome", Html: "<p>v2 {{ name }}</p>", Version: 2, Active: true},
+	}
+
+	template, err := s.GetTemplate(ctx, &pb.GetTemplateRequest{Domain: "kannon.io", TemplateId: "template_1@kannon.io"})
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2), template.Version)
+	assert.Equal(t, []string{"name"}, template.Fields)
+
+	template, err = s.GetTemplate(ctx, &pb.GetTemplateRequest{Domain: "kannon.io", TemplateId: "template_1@kannon.io", Version: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(1), template.Version)
+	assert.Equal(t, "<p>v1</p>", template.Html)
+	assert.False(t, template.Active)
+
+	_, err = s.GetTemplate(ctx, &pb.GetTemplateRequest{Domain: "kannon.io", TemplateId: "template_1@kannon.io", Version: 3})
+	assertCode(t, codes.NotFound, err)
+
+	_, err = s.GetTemplate(ctx, &pb.GetTemplateRequest{Domain: "other.io", TemplateId: "template_1@kannon.io"})
+	assertCode(t, codes.NotFound, err)
+
+	// deleted templates are not found, not even their versions
+	tm.deleted["template_1@kannon.io"] = true
+	_, err = s.GetTemplate(ctx, &pb.GetTemplateRequest{Domain: "kannon.io", TemplateId: "template_1@kannon.io"})
+	assertCode(t, codes.NotFound, err)
+	_, err = s.GetTemplate(ctx, &pb.GetTemplateRequest{Domain: "kannon.io", TemplateId: "template_1@kannon.io", Version: 1})
+	assertCode(t, codes.NotFound, err)
+}
+
+func TestGetTemplates(t *testing.T) {
+	s := newTemplatesService()
+	ctx := context.Background()
+	tm := s.tm.(*fakeTemplates)
+	tm.versions = []sqlc.Template{
+		{TemplateID: "template_1@kannon.io", Domain: "kannon.io", Name: "welcome", Version: 1, Active: true},
+		{TemplateID: "template_2@kannon.io", Domain: "kannon.io", Name: "invoice", Version: 1, Active: true},
+		// a template of a SendHTML
+		{TemplateID: "template_3@kannon.io", Domain: "kannon.io", Version: 1, Active: true},
+	}
+
+	res, err := s.GetTemplates(ctx, &pb.GetTemplatesRequest{Domain: "kannon.io"})
+	assert.Nil(t, err)
+	assert.Len(t, res.Templates, 2)
+
+	_, err = s.DeleteTemplate(ctx, &pb.DeleteTemplateRequest{Domain: "kannon.io", TemplateId: "template_2@kannon.io"})
+	assert.Nil(t, err)
+	res, err = s.GetTemplates(ctx, &pb.GetTemplatesRequest{Domain: "kannon.io"})
+	assert.Nil(t, err)
+	if assert.Len(t, res.Templates, 1) {
+		assert.Equal(t, "welcome", res.Templates[0].Name)
+	}
+}
+
+func TestDeleteTemplate(t *testing.T) {
+	s := newTemplatesService()
+	ctx := context.Background()
+	s.tm.(*fakeTemplates).versions = []sqlc.Template{
+		{TemplateID: "template_1@kannon.io", Domain: "kannon.io", Name: "welcome", Version: 1, Active: true},
+	}
+
+	_, err := s.DeleteTemplate(ctx, &pb.DeleteTemplateRequest{Domain: "other.io", TemplateId: "template_1@kannon.io"})
+	assertCode(t, codes.NotFound, err)
+
+	_, err = s.DeleteTemplate(ctx, &pb.DeleteTemplateRequest{Domain: "kannon.io", TemplateId: "template_1@kannon.io"})
+	assert.Nil(t, err)
+
+	// a deleted template is deleted once
+	_, err = s.DeleteTemplate(ctx, &pb.DeleteTemplateRequest{Domain: "kannon.io", TemplateId: "template_1@kannon.io"})
+	assertCode(t, codes.NotFound, err)
+}
@@ -0,0 +1,206 @@
+package adminapi
+
+import (
+	"context"
+	"database/sql"
+	"path"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"kannon.gyozatech.dev/generated/pb"
+	"kannon.gyozatech.dev/internal/apikeys"
+	"kannon.gyozatech.dev/internal/audit"
+	"kannon.gyozatech.dev/internal/domains"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/rbac"
+	"kannon.gyozatech.dev/internal/suppressions"
+	"kannon.gyozatech.dev/internal/templates"
+	"kannon.gyozatech.dev/internal/tracing"
+	"kannon.gyozatech.dev/internal/webhooks"
+)
+
+// apiPrefix is the prefix of the methods of the admin API
+const apiPrefix = "/kannon.Api/"
+
+type actorKey struct{}
+
+// contextWithActor returns ctx with the subject of the token of the caller
+func contextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// actorFromContext returns the subject of the token of the caller, empty when not authenticated
+func actorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// Chain returns an interceptor calling interceptors in order, the
+// gateway and the gRPC server take a single admin interceptor
+func Chain(interceptors ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, h := interceptors[i], next
+			next = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, h)
+			}
+		}
+		return next(ctx, req)
+	}
+}
+
+type auditor struct {
+	am audit.Manager
+	dm domains.DomainManager
+	km apikeys.Manager
+	tm templates.Manager
+	sm suppressions.Manager
+	wm webhooks.Manager
+}
+
+// NewAuditInterceptor returns an interceptor recording the successful mutations of
+// the admin API in the audit log, the RPCs not read-only, with the caller and the
+// changed resource before and after the call. It must follow the auth interceptor
+func NewAuditInterceptor(db *sql.DB) (grpc.UnaryServerInterceptor, error) {
+	am, err := audit.NewAuditManager(db)
+	if err != nil {
+		return nil, err
+	}
+	dm, err := domains.NewDomainManager(db)
+	if err != nil {
+		return nil, err
+	}
+	km, err := apikeys.NewAPIKeyManager(db)
+	if err != nil {
+		return nil, err
+	}
+	tm, err := templates.NewTemplateManager(db)
+	if err != nil {
+		return nil, err
+	}
+	sm, err := suppressions.NewSuppressionManager(db)
+	if err != nil {
+		return nil, err
+	}
+	wm, err := webhooks.NewWebhookManager(db)
+	if err != nil {
+		return nil, err
+	}
+	a := &auditor{am: am, dm: dm, km: km, tm: tm, sm: sm, wm: wm}
+	return a.intercept, nil
+}
+
+func (a *auditor) intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !strings.HasPrefix(info.FullMethod, apiPrefix) || rbac.MethodRole(info.FullMethod) == rbac.ReadOnly {
+		return handler(ctx, req)
+	}
+	before := a.before(req)
+	res, err := handler(ctx, req)
+	if err != nil {
+		return res, err
+	}
+
+	entry := audit.Entry{
+		Actor:   actorFromContext(ctx),
+		Method:  path.Base(info.FullMethod),
+		Request: message(req),
+		Before:  before,
+		After:   message(res),
+	}
+	if r, ok := req.(interface{ GetDomain() string }); ok {
+		entry.Domain = r.GetDomain()
+	}
+	// the mutation is done, the log is recorded even when the call is canceled
+	recordCtx := tracing.ContextWithSpanContext(context.Background(), tracing.SpanContextFromContext(ctx))
+	if _, err := a.am.Record(recordCtx, entry); err != nil {
+		logging.Domain(log, entry.Domain).Errorf("cannot record %v of %v in the audit log: %v", entry.Method, entry.Actor, err)
+	}
+	return res, nil
+}
+
+// before returns the resource changed by the call of req, nil when
+// the call creates it or it cannot be found
+func (a *auditor) before(req interface{}) proto.Message {
+	switch in := req.(type) {
+	case *pb.CreateDomainRequest, *pb.CreateAPIKeyRequest, *pb.AddSuppressionRequest, *pb.CreateWebhookRequest, *pb.CreateTemplateRequest:
+		return nil
+	case *pb.UpdateTemplateRequest:
+		return a.template(in.Domain, in.TemplateId)
+	case *pb.RollbackTemplateRequest:
+		return a.template(in.Domain, in.TemplateId)
+	case *pb.DeleteTemplateRequest:
+		return a.template(in.Domain, in.TemplateId)
+	case *pb.RevokeAPIKeyRequest:
+		return a.apiKey(in.Domain, in.Id)
+	case *pb.RotateAPIKeyRequest:
+		return a.apiKey(in.Domain, in.Id)
+	case *pb.RemoveSuppressionRequest:
+		return a.suppression(in.Domain, in.Email)
+	case *pb.DeleteWebhookRequest:
+		return a.webhook(in.Domain, in.Id)
+	case *pb.RestoreDomainRequest:
+		if d, err := a.dm.FindDeletedDomain(in.Domain); err == nil {
+			return dbDomainToProtoDomain(d)
+		}
+	case interface{ GetDomain() string }:
+		if d, err := a.dm.FindDomain(in.GetDomain()); err == nil {
+			return dbDomainToProtoDomain(d)
+		}
+	}
+	return nil
+}
+
+func (a *auditor) template(domain string, templateID string) proto.Message {
+	t, err := a.tm.FindTemplate(domain, templateID)
+	if err != nil {
+		return nil
+	}
+	return dbTemplateToProtoTemplate(t)
+}
+
+func (a *auditor) apiKey(domain string, id int32) proto.Message {
+	keys, err := a.km.GetKeys(domain)
+	if err != nil {
+		return nil
+	}
+	for _, k := range keys {
+		if k.ID == id {
+			return dbAPIKeyToProtoAPIKey(k)
+		}
+	}
+	return nil
+}
+
+func (a *auditor) suppression(domain string, email string) proto.Message {
+	suppressions, err := a.sm.GetSuppressions(domain)
+	if err != nil {
+		return nil
+	}
+	for _, s := range suppressions {
+		if s.Email == email {
+			return dbSuppressionToProtoSuppression(s)
+		}
+	}
+	return nil
+}
+
+func (a *auditor) webhook(domain string, id int32) proto.Message {
+	webhooks, err := a.wm.GetWebhooks(domain)
+	if err != nil {
+		return nil
+	}
+	for _, w := range webhooks {
+		if w.ID == id {
+			return dbWebhookToProtoWebhook(w)
+		}
+	}
+	return nil
+}
+
+// message returns v as a message, nil when v is not a message
+func message(v interface{}) proto.Message {
+	m, _ := v.(proto.Message)
+	return m
+}
@@ -0,0 +1,86 @@
+package adminapi
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"kannon.gyozatech.dev/internal/oidc"
+	"kannon.gyozatech.dev/internal/rbac"
+)
+
+// healthPrefix is the prefix of the methods of the gRPC health service
+const healthPrefix = "/grpc.health.v1.Health/"
+
+// NewAuthInterceptor returns an interceptor authenticating the calls of the
+// admin API with the JWT of their "authorization: Bearer <token>" metadata,
+// the role of the token must include the role of the called RPC. Health
+// checks are not authenticated, for the probes of the orchestrator
+func NewAuthInterceptor(v *oidc.Verifier) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticate(ctx, v, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// NewAuthStreamInterceptor returns an interceptor authenticating the streams
+// of the admin API server, like the server reflection, as NewAuthInterceptor
+func NewAuthStreamInterceptor(v *oidc.Verifier) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), v, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authStream is a stream with the context of its authenticated actor
+type authStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authStream) Context() context.Context {
+	return s.ctx
+}
+
+// authenticate checks the token of the call of fullMethod in the metadata of ctx,
+// it returns the context of the call with the subject of the token as actor
+func authenticate(ctx context.Context, v *oidc.Verifier, fullMethod string) (context.Context, error) {
+	if strings.HasPrefix(fullMethod, healthPrefix) {
+		return ctx, nil
+	}
+	m, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, status.Errorf(codes.Unauthenticated, "missing token")
+	}
+	auths := m.Get("authorization")
+	if len(auths) != 1 || !strings.HasPrefix(auths[0], "Bearer ") {
+		return nil, status.Errorf(codes.Unauthenticated, "missing token")
+	}
+
+	claims, err := v.Verify(strings.TrimPrefix(auths[0], "Bearer "))
+	if err != nil {
+		log.Debugf("Invalid admin token: %v\n", err)
+		return nil, status.Errorf(codes.Unauthenticated, "invalid token")
+	}
+
+	role, ok := v.Config().Role(claims)
+	if !ok {
+		return nil, status.Errorf(codes.PermissionDenied, "token without role")
+	}
+	if required := rbac.MethodRole(fullMethod); !role.Includes(required) {
+		log.Infof("[🔑 admin] %v (%v) denied %v", claims.Subject, role, fullMethod)
+		return nil, status.Errorf(codes.PermissionDenied, "%v role required", required)
+	}
+
+	log.Infof("[🔑 admin] %v (%v) called %v", claims.Subject, role, fullMethod)
+	return contextWithActor(ctx, claims.Subject), nil
+}
@@ -0,0 +1,415 @@
+// Package api is the api daemon, it serves the admin and mailer APIs,
+// their JSON gateway and the events of the emails
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/joho/godotenv"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"kannon.gyozatech.dev/generated/pb"
+	"kannon.gyozatech.dev/internal/archive"
+	"kannon.gyozatech.dev/internal/configfile"
+	"kannon.gyozatech.dev/internal/daemons/api/adminapi"
+	"kannon.gyozatech.dev/internal/daemons/api/mailapi"
+	"kannon.gyozatech.dev/internal/dbconfig"
+	"kannon.gyozatech.dev/internal/dnsprovider"
+	"kannon.gyozatech.dev/internal/errorreport"
+	"kannon.gyozatech.dev/internal/gateway"
+	khealth "kannon.gyozatech.dev/internal/health"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/metrics"
+	"kannon.gyozatech.dev/internal/migrate"
+	"kannon.gyozatech.dev/internal/oidc"
+	"kannon.gyozatech.dev/internal/queue"
+	"kannon.gyozatech.dev/internal/tlsconfig"
+	"kannon.gyozatech.dev/internal/tracing"
+	"kannon.gyozatech.dev/internal/verification"
+)
+
+var log = logging.Logger("api")
+
+type appConfig struct {
+	queue.Config
+	// MaxAttachmentSize is the max size in bytes of the attachments of a single send request
+	MaxAttachmentSize uint `default:"10485760"`
+	// EventsPort is the port of the server-sent events, events export and DMARC reports endpoints
+	EventsPort uint16 `default:"8080"`
+	// SendGridAPI serves the SendGrid compatible POST /v3/mail/send endpoint on the events port
+	SendGridAPI bool
+	// GatewayPort is the port of the JSON gateway of the admin and mailer APIs, 0 disables it
+	GatewayPort uint16 `default:"8081"`
+	// MetricsPort is the port of the metrics and health endpoints, 0 disables them
+	MetricsPort uint16 `default:"9090"`
+	// DebugAddr is the address of the pprof and runtime stats server, like localhost:6060, empty disables it
+	DebugAddr string
+	// Log configures the log lines, like APP_LOG_FORMAT=json and APP_LOG_LEVELS=queue=debug
+	Log logging.Config
+	// DB configures the connection pool to the database, like APP_DB_MAXOPENCONNS
+	DB dbconfig.Config
+	// ReadDBConn is the connection string of a read replica serving the stats, search
+	// and export queries, the database of DB_CONN when empty
+	ReadDBConn string
+	// Sentry reports the errors and panics, like APP_SENTRY_DSN
+	Sentry errorreport.Config
+	// Verification are the records checked by VerifyDomain, like APP_VERIFICATION_SPFINCLUDE
+	Verification verification.Config
+	// DNS is the provider creating the records of the new domains, like APP_DNS_PROVIDER
+	DNS dnsprovider.Config
+	// RequireVerified rejects the sends of domains whose DNS records are not verified
+	RequireVerified bool
+	// OIDC is the identity provider of the tokens of the admin API, like APP_OIDC_ISSUER
+	OIDC oidc.Config
+	// Tracing is the collector of the spans of the send requests, like APP_TRACING_ENDPOINT
+	Tracing tracing.Config
+	// AutoMigrate applies the pending database migrations on start, like the migrate command
+	AutoMigrate bool
+	// TLS is the certificate of the gRPC servers, like APP_TLS_CERTFILE,
+	// APP_TLS_CAFILE requires client certificates signed by its CAs
+	TLS tlsconfig.Config
+	// Archive is the bucket of the sent emails returned by GetMessageEML, like APP_ARCHIVE_BUCKET
+	Archive archive.Config
+}
+
+// stopTimeout is the max wait on shutdown for the calls in progress
+const stopTimeout = 10 * time.Second
+
+// Run runs the api daemon until ctx is canceled, or until a server fails
+func Run(ctx context.Context) error {
+	_ = godotenv.Load()
+
+	var config appConfig
+	if err := configfile.Load("api", &config); err != nil {
+		return fmt.Errorf("cannot read config: %w", err)
+	}
+	if err := logging.Setup(config.Log); err != nil {
+		return fmt.Errorf("invalid log config: %w", err)
+	}
+	stopReports, err := errorreport.Start(config.Sentry, "kannon-api")
+	if err != nil {
+		return fmt.Errorf("invalid sentry config: %w", err)
+	}
+	defer stopReports()
+	defer errorreport.Recover()
+
+	if err := config.Verification.Validate(); err != nil {
+		return fmt.Errorf("invalid verification config: %w", err)
+	}
+
+	if err := config.DNS.Validate(); err != nil {
+		return fmt.Errorf("invalid dns config: %w", err)
+	}
+
+	if err := config.OIDC.Validate(); err != nil {
+		return fmt.Errorf("invalid oidc config: %w", err)
+	}
+
+	if err := config.TLS.Validate(); err != nil {
+		return fmt.Errorf("invalid tls config: %w", err)
+	}
+
+	stopTracing, err := tracing.Start(config.Tracing, "kannon-api")
+	if err != nil {
+		return fmt.Errorf("invalid tracing config: %w", err)
+	}
+	defer stopTracing()
+
+	// calls are spans of the trace of their traceparent metadata,
+	// their panics are reported and fail the call
+	serverOpts := []grpc.ServerOption{grpc.ChainUnaryInterceptor(errorreport.UnaryServerInterceptor, tracing.UnaryServerInterceptor)}
+	if config.TLS.Enabled() {
+		tlsConfig, err := config.TLS.ServerConfig()
+		if err != nil {
+			return err
+		}
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	dbi, err := sql.Open("postgres", os.Getenv("DB_CONN"))
+	if err != nil {
+		return err
+	}
+	defer dbi.Close()
+	config.DB.Apply(dbi)
+
+	checks := []khealth.Check{khealth.DB(dbi)}
+	readDB := dbi
+	if config.ReadDBConn != "" {
+		if readDB, err = sql.Open("postgres", config.ReadDBConn); err != nil {
+			return err
+		}
+		defer readDB.Close()
+		config.DB.Apply(readDB)
+		checks = append(checks, khealth.Check{Name: "read_db", Check: readDB.PingContext})
+	}
+
+	if config.AutoMigrate {
+		migrations, err := migrate.Embedded()
+		if err != nil {
+			return err
+		}
+		if _, err := migrate.Up(ctx, dbi, migrations); err != nil {
+			return fmt.Errorf("cannot migrate database: %w", err)
+		}
+	}
+
+	// the mutations of the admin API are recorded with the subject of their token
+	adminInterceptor, err := adminapi.NewAuditInterceptor(dbi)
+	if err != nil {
+		return fmt.Errorf("cannot create audit log: %w", err)
+	}
+	var adminStreamInterceptor grpc.StreamServerInterceptor
+	if config.OIDC.Enabled() {
+		verifier, err := oidc.NewVerifier(config.OIDC, &http.Client{Timeout: 10 * time.Second})
+		if err != nil {
+			return err
+		}
+		adminInterceptor = adminapi.Chain(adminapi.NewAuthInterceptor(verifier), adminInterceptor)
+		// the streams of the admin server, like the server reflection, are authenticated too
+		adminStreamInterceptor = adminapi.NewAuthStreamInterceptor(verifier)
+	} else {
+		log.Warnf("APP_OIDC_ISSUER not set, the Admin API is not authenticated\n")
+	}
+	adminOpts := append([]grpc.ServerOption{grpc.UnaryInterceptor(adminInterceptor)}, serverOpts...)
+	if adminStreamInterceptor != nil {
+		adminOpts = append(adminOpts, grpc.StreamInterceptor(adminStreamInterceptor))
+	}
+
+	b, err := queue.Open(config.Config, nil)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	adminAPIService, err := adminapi.CreateAdminAPIService(dbi, readDB, b, config.Verification, config.DNS)
+	if err != nil {
+		return fmt.Errorf("cannot create Admin API service: %w", err)
+	}
+
+	var ar archive.Archive
+	if config.Archive.Enabled() {
+		if ar, err = archive.NewArchive(config.Archive); err != nil {
+			return fmt.Errorf("invalid archive config: %w", err)
+		}
+	}
+
+	mailAPIService, err := mailapi.NewMailAPIService(dbi, readDB, b, ar, config.MaxAttachmentSize, config.RequireVerified)
+	if err != nil {
+		return fmt.Errorf("cannot create Mailer API service: %w", err)
+	}
+
+	eventsHandler, err := mailapi.NewEventsHandler(dbi, b)
+	if err != nil {
+		return fmt.Errorf("cannot create events handler: %w", err)
+	}
+
+	dmarcHandler, err := mailapi.NewDMARCHandler(dbi)
+	if err != nil {
+		return fmt.Errorf("cannot create DMARC handler: %w", err)
+	}
+
+	exportHandler, err := mailapi.NewExportHandler(dbi, readDB)
+	if err != nil {
+		return fmt.Errorf("cannot create export handler: %w", err)
+	}
+
+	var sendGridHandler http.Handler
+	if config.SendGridAPI {
+		sendGridHandler, err = mailapi.NewSendGridHandler(dbi, b, config.MaxAttachmentSize, config.RequireVerified)
+		if err != nil {
+			return fmt.Errorf("cannot create SendGrid handler: %w", err)
+		}
+	}
+
+	gatewayHandler, err := gateway.NewHandler(
+		gateway.Service{Desc: &pb.Api_ServiceDesc, Impl: adminAPIService, Interceptor: adminInterceptor},
+		gateway.Service{Desc: &pb.Mailer_ServiceDesc, Impl: mailAPIService, Interceptor: tracing.UnaryServerInterceptor},
+	)
+	if err != nil {
+		return fmt.Errorf("cannot create gateway: %w", err)
+	}
+
+	metrics.Serve(config.MetricsPort, append(checks, queue.HealthCheck(b))...)
+	metrics.ServeDebug(config.DebugAddr)
+
+	// the log levels and the database pool change without a restart
+	configfile.Watch(func() {
+		var next appConfig
+		if err := configfile.Load("api", &next); err != nil {
+			log.Errorf("cannot reload config: %v", err)
+			return
+		}
+		if err := logging.Setup(next.Log); err != nil {
+			log.Errorf("cannot reload log config: %v", err)
+			return
+		}
+		next.DB.Apply(dbi)
+		next.DB.Apply(readDB)
+		log.Infof("config reloaded")
+	})
+
+	// a server failing stops the others
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	serve := func(name string, start func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer errorreport.Recover()
+			if err := start(); err != nil {
+				errs <- fmt.Errorf("cannot run %v: %w", name, err)
+				cancel()
+			}
+		}()
+	}
+
+	serve("api server", func() error { return startAPIServer(ctx, 50051, adminAPIService, adminOpts...) })
+	serve("mailer server", func() error { return startMailerServer(ctx, 50052, mailAPIService, serverOpts...) })
+	serve("events server", func() error {
+		return startEventsServer(ctx, config.EventsPort, eventsHandler, dmarcHandler, exportHandler, sendGridHandler)
+	})
+	if config.GatewayPort != 0 {
+		serve("gateway server", func() error { return startGatewayServer(ctx, config.GatewayPort, gatewayHandler, config.TLS) })
+	}
+
+	wg.Wait()
+	close(errs)
+	if err := <-errs; err != nil {
+		return err
+	}
+	log.Infof("api stopped")
+	return nil
+}
+
+func startAPIServer(ctx context.Context, port uint16, srv pb.ApiServer, opts ...grpc.ServerOption) error {
+	addr := fmt.Sprintf("0.0.0.0:%d", port)
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer lis.Close()
+
+	s := grpc.NewServer(opts...)
+	pb.RegisterApiServer(s, srv)
+	registerHealthAndReflection(s)
+
+	go stopGRPC(ctx, s)
+
+	log.Infof("🚀 starting Admin API Service on %v\n", lis.Addr())
+	if err := s.Serve(lis); err != nil {
+		return err
+	}
+	return nil
+}
+
+func startMailerServer(ctx context.Context, port uint16, srv pb.MailerServer, opts ...grpc.ServerOption) error {
+	addr := fmt.Sprintf("0.0.0.0:%d", port)
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer lis.Close()
+
+	s := grpc.NewServer(opts...)
+	pb.RegisterMailerServer(s, srv)
+	registerHealthAndReflection(s)
+
+	go stopGRPC(ctx, s)
+
+	log.Infof("🚀 starting Mailer API Service on %v\n", lis.Addr())
+	if err := s.Serve(lis); err != nil {
+		return err
+	}
+	return nil
+}
+
+// stopGRPC stops s when ctx is done, the calls in progress
+// are canceled after stopTimeout
+func stopGRPC(ctx context.Context, s *grpc.Server) {
+	<-ctx.Done()
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(stopTimeout):
+		s.Stop()
+	}
+}
+
+// serveHTTP serves srv with serve until ctx is done, the requests
+// in progress are closed after stopTimeout
+func serveHTTP(ctx context.Context, srv *http.Server, serve func() error) error {
+	go func() {
+		<-ctx.Done()
+		stopCtx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+		defer cancel()
+		if err := srv.Shutdown(stopCtx); err != nil {
+			srv.Close()
+		}
+	}()
+	if err := serve(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// registerHealthAndReflection registers the gRPC health service, serving
+// the services of s and the server, and the server reflection on s
+func registerHealthAndReflection(s *grpc.Server) {
+	hs := health.NewServer()
+	for name := range s.GetServiceInfo() {
+		hs.SetServingStatus(name, healthpb.HealthCheckResponse_SERVING)
+	}
+	healthpb.RegisterHealthServer(s, hs)
+	reflection.Register(s)
+}
+
+func startGatewayServer(ctx context.Context, port uint16, handler *gateway.Handler, tlsConfig tlsconfig.Config) error {
+	mux := http.NewServeMux()
+	mux.Handle(gateway.PathPrefix, handler)
+	mux.HandleFunc("/openapi.json", handler.ServeOpenAPI)
+
+	srv := &http.Server{Addr: fmt.Sprintf("0.0.0.0:%d", port), Handler: mux}
+	if !tlsConfig.Enabled() {
+		log.Infof("🚀 starting Gateway Service on port %v\n", port)
+		return serveHTTP(ctx, srv, srv.ListenAndServe)
+	}
+	config, err := tlsConfig.ServerConfig()
+	if err != nil {
+		return err
+	}
+	srv.TLSConfig = config
+	log.Infof("🚀 starting Gateway Service with TLS on port %v\n", port)
+	return serveHTTP(ctx, srv, func() error { return srv.ListenAndServeTLS("", "") })
+}
+
+func startEventsServer(ctx context.Context, port uint16, handler http.Handler, dmarcHandler http.Handler, exportHandler http.Handler, sendGridHandler http.Handler) error {
+	mux := http.NewServeMux()
+	mux.Handle("/events", handler)
+	mux.Handle("/events/export", exportHandler)
+	mux.Handle("/dmarc/reports", dmarcHandler)
+	if sendGridHandler != nil {
+		mux.Handle("/v3/mail/send", sendGridHandler)
+	}
+
+	srv := &http.Server{Addr: fmt.Sprintf("0.0.0.0:%d", port), Handler: mux}
+	log.Infof("🚀 starting Events Service on port %v\n", port)
+	return serveHTTP(ctx, srv, srv.ListenAndServe)
+}
@@ -0,0 +1,72 @@
+package mailapi
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"kannon.gyozatech.dev/internal/apikeys"
+	"kannon.gyozatech.dev/internal/dmarc"
+	"kannon.gyozatech.dev/internal/domains"
+)
+
+type dmarcHandler struct {
+	domains domains.DomainManager
+	apiKeys apikeys.Manager
+	dmarc   dmarc.Manager
+}
+
+// NewDMARCHandler creates an http handler storing the DMARC aggregate
+// reports uploaded by the authenticated domain, the body is the report
+// xml, raw or compressed with gzip or zip
+func NewDMARCHandler(dbi *sql.DB) (http.Handler, error) {
+	domainsCli, err := domains.NewDomainManager(dbi)
+	if err != nil {
+		return nil, err
+	}
+
+	dmarcCli, err := dmarc.NewDMARCManager(dbi)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeysCli, err := apikeys.NewAPIKeyManager(dbi)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dmarcHandler{
+		domains: domainsCli,
+		apiKeys: apiKeysCli,
+		dmarc:   dmarcCli,
+	}, nil
+}
+
+func (h *dmarcHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	domain, ok := httpAuthDomain(w, r, h.domains, h.apiKeys, apikeys.ScopeAdmin)
+	if !ok {
+		return
+	}
+
+	report, err := dmarc.Parse(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if report.Domain != domain.Domain && !strings.HasSuffix(domain.Domain, "."+report.Domain) {
+		http.Error(w, "report is not of the domain", http.StatusForbidden)
+		return
+	}
+
+	if err := h.dmarc.Store(report); err != nil {
+		log.Errorf("cannot store DMARC report %v\n", err)
+		http.Error(w, "cannot store report", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,64 @@
+package mailapi
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"kannon.gyozatech.dev/generated/pb"
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/dkim"
+	"kannon.gyozatech.dev/internal/domains"
+	"kannon.gyozatech.dev/internal/mailbuilder"
+	"kannon.gyozatech.dev/internal/pool"
+)
+
+// dryRunSend renders and signs the email of every recipient of a send
+// as it would be sent, nothing is stored or enqueued
+func (s mailAPIService) dryRunSend(domain sqlc.Domain, pm pool.PoolMessage) (*pb.SendResponse, error) {
+	settings, err := domains.DomainSendingSettings(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := make([]pool.Recipient, 0, len(pm.To)+len(pm.Recipients))
+	for _, email := range pm.To {
+		recipients = append(recipients, pool.Recipient{Email: email})
+	}
+	recipients = append(recipients, pm.Recipients...)
+
+	res := &pb.SendResponse{
+		TemplateId:      pm.Template.TemplateID,
+		TemplateVersion: uint32(pm.Template.Version),
+		ScheduledTime:   timestamppb.New(pm.ScheduledTime),
+	}
+	for _, r := range recipients {
+		preview, err := mailbuilder.PreviewMessage(pm, r, domainKeys(domain), domain.DkimHeaders, settings)
+		if err != nil {
+			log.Errorf("cannot render dry run %v\n", err)
+			return nil, status.Errorf(codes.Internal, "cannot render email of %v: %v", r.Email, err)
+		}
+		res.DryRun = append(res.DryRun, previewToProto(r.Email, preview))
+	}
+	return res, nil
+}
+
+func domainKeys(domain sqlc.Domain) dkim.DomainKeys {
+	return dkim.DomainKeys{
+		Signing:           domain.DkimSigning,
+		RSAPrivateKey:     domain.DkimPrivateKey,
+		Ed25519PrivateKey: domain.DkimEd25519PrivateKey,
+		RSASelector:       domain.DkimSelector,
+		Ed25519Selector:   domain.DkimEd25519Selector,
+	}
+}
+
+func previewToProto(to string, preview mailbuilder.Preview) *pb.PreviewResponse {
+	return &pb.PreviewResponse{
+		Subject: preview.Subject,
+		Html:    preview.HTML,
+		Text:    preview.Text,
+		Headers: preview.Headers,
+		Message: preview.Message,
+		To:      to,
+	}
+}
@@ -0,0 +1,105 @@
+package mailapi
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"kannon.gyozatech.dev/internal/apikeys"
+	"kannon.gyozatech.dev/internal/domains"
+	"kannon.gyozatech.dev/internal/events"
+	"kannon.gyozatech.dev/internal/export"
+)
+
+type exportHandler struct {
+	domains domains.DomainManager
+	apiKeys apikeys.Manager
+	export  export.Manager
+}
+
+// NewExportHandler creates an http handler downloading the events of the
+// authenticated domain between the from and to RFC 3339 query parameters,
+// as csv or parquet with the format query parameter. Types can be filtered
+// with a comma separated types query parameter. The events are read from readDB
+func NewExportHandler(dbi *sql.DB, readDB *sql.DB) (http.Handler, error) {
+	domainsCli, err := domains.NewDomainManager(dbi)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeysCli, err := apikeys.NewAPIKeyManager(dbi)
+	if err != nil {
+		return nil, err
+	}
+
+	exportCli, err := export.NewExportManager(readDB)
+	if err != nil {
+		return nil, err
+	}
+
+	return &exportHandler{
+		domains: domainsCli,
+		apiKeys: apiKeysCli,
+		export:  exportCli,
+	}, nil
+}
+
+func (h *exportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	domain, ok := httpAuthDomain(w, r, h.domains, h.apiKeys, apikeys.ScopeStats)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	from, err := time.Parse(time.RFC3339, q.Get("from"))
+	if err != nil {
+		http.Error(w, "invalid from: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	to, err := time.Parse(time.RFC3339, q.Get("to"))
+	if err != nil {
+		http.Error(w, "invalid to: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !from.Before(to) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	}
+	var names []string
+	if q.Get("types") != "" {
+		names = strings.Split(q.Get("types"), ",")
+	}
+	types, err := events.ParseTypes(names)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	format, err := export.ParseFormat(q.Get("format"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%v-events-%v.%v"`, domain.Domain, from.UTC().Format("2006-01-02"), format))
+	ew, err := export.NewWriter(format, w)
+	if err != nil {
+		log.Errorf("cannot export events of %v: %v\n", domain.Domain, err)
+		return
+	}
+	// the response has started, errors truncate the file
+	if _, err := h.export.Export(r.Context(), domain.Domain, from, to, types, ew); err != nil {
+		log.Errorf("cannot export events of %v: %v\n", domain.Domain, err)
+		return
+	}
+	if err := ew.Close(); err != nil {
+		log.Errorf("cannot export events of %v: %v\n", domain.Domain, err)
+	}
+}
@@ -0,0 +1,1094 @@
+package mailapi
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"kannon.gyozatech.dev/generated/pb"
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/apikeys"
+	"kannon.gyozatech.dev/internal/archive"
+	"kannon.gyozatech.dev/internal/dmarc"
+	"kannon.gyozatech.dev/internal/domains"
+	"kannon.gyozatech.dev/internal/events"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/mailbuilder"
+	"kannon.gyozatech.dev/internal/pool"
+	"kannon.gyozatech.dev/internal/queue"
+	"kannon.gyozatech.dev/internal/quotas"
+	"kannon.gyozatech.dev/internal/smtp"
+	"kannon.gyozatech.dev/internal/stats"
+	"kannon.gyozatech.dev/internal/templates"
+)
+
+var log = logging.Logger("api")
+
+// maxIdempotencyKeyLen is the size of messages.idempotency_key
+const maxIdempotencyKeyLen = 255
+
+type mailAPIService struct {
+	domains           domains.DomainManager
+	apiKeys           apikeys.Manager
+	templates         templates.Manager
+	sendingPoll       pool.SendingPoolManager
+	stats             stats.Manager
+	dmarc             dmarc.Manager
+	quotas            quotas.Manager
+	b                 queue.Broker
+	maxAttachmentSize uint
+	// requireVerified rejects the sends of domains whose DNS records are not verified,
+	// sandbox domains don't deliver their emails and are never rejected
+	requireVerified bool
+	// archive returns the sent emails, nil when they are not archived
+	archive archive.Archive
+}
+
+func (s mailAPIService) SendHTML(ctx context.Context, in *pb.SendHTMLRequest) (*pb.SendResponse, error) {
+	domain, err := s.getCallDomainFromContext(ctx, apikeys.ScopeSend)
+	if err != nil {
+		return nil, err
+	}
+	return s.sendHTML(ctx, domain, in)
+}
+
+// sendHTML sends in as the authenticated domain
+func (s mailAPIService) sendHTML(ctx context.Context, domain sqlc.Domain, in *pb.SendHTMLRequest) (*pb.SendResponse, error) {
+	if s.requireVerified && !domain.Verified && !domain.Sandbox {
+		return nil, status.Errorf(codes.FailedPrecondition, "domain %v is not verified", domain.Domain)
+	}
+
+	if domain.PausedAt.Valid {
+		return nil, status.Errorf(codes.FailedPrecondition, "domain %v is paused: %v", domain.Domain, domain.PausedReason)
+	}
+
+	if !in.DryRun {
+		if res, ok, err := s.findIdempotentSend(domain.Domain, in.IdempotencyKey); ok || err != nil {
+			return res, err
+		}
+	}
+
+	attachments, err := s.buildAttachments(in.Attachments)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateReplyTo(in.ReplyTo); err != nil {
+		return nil, err
+	}
+
+	if err := validateSender(domain, in.Sender); err != nil {
+		return nil, err
+	}
+
+	if err := mailbuilder.ValidateCustomHeaders(in.Headers); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid headers: %v", err)
+	}
+
+	recipients := buildRecipients(in.Recipients)
+	if err := validateRecipients(in.To, recipientsEmails(recipients), in.Cc, in.Bcc); err != nil {
+		return nil, err
+	}
+
+	if in.StrictFields {
+		if err := validateFields(in.Fields, in.To, recipients, in.Subject, in.Html, in.Text); err != nil {
+			return nil, err
+		}
+	}
+
+	scheduledTime, err := buildScheduledTime(in.ScheduledTime)
+	if err != nil {
+		return nil, err
+	}
+
+	pm := pool.PoolMessage{
+		To:         in.To,
+		Recipients: recipients,
+		Cc:         in.Cc,
+		Bcc:        in.Bcc,
+		From: pool.Sender{
+			Email: in.GetSender().GetEmail(),
+			Alias: in.GetSender().GetAlias(),
+		},
+		Subject:        in.Subject,
+		Domain:         domain.Domain,
+		Attachments:    attachments,
+		Headers:        in.Headers,
+		ReplyTo:        in.ReplyTo,
+		Fields:         in.Fields,
+		ScheduledTime:  scheduledTime,
+		IdempotencyKey: in.IdempotencyKey,
+		Priority:       buildPriority(in.Priority),
+	}
+	if err := s.checkQuota(domain, pm.EmailsCount()); err != nil {
+		return nil, err
+	}
+	if in.DryRun {
+		pm.Template = sqlc.Template{Html: in.Html, Text: in.Text, Domain: domain.Domain}
+		return s.dryRunSend(domain, pm)
+	}
+
+	template, err := s.templates.CreateTemplate(in.Html, in.Text, domain.Domain)
+	if err != nil {
+		logging.Domain(log, domain.Domain).Errorf("cannot create template %v\n", err)
+		return nil, status.Errorf(codes.Internal, "cannot create template %v", err)
+	}
+	pm.Template = template
+
+	msg, err := s.sendingPoll.AddPool(ctx, pm)
+	if err != nil {
+		logging.Domain(log, domain.Domain).Errorf("cannot create pool %v\n", err)
+		return nil, err
+	}
+	if err := pool.NotifyScheduled(s.b); err != nil {
+		log.Errorf("cannot notify scheduled pool %v\n", err)
+	}
+
+	response := pb.SendResponse{
+		MessageId:       msg.MessageID,
+		TemplateId:      template.TemplateID,
+		TemplateVersion: uint32(template.Version),
+		ScheduledTime:   timestamppb.New(scheduledTime),
+	}
+
+	return &response, nil
+}
+
+func (s mailAPIService) SendTemplate(ctx context.Context, in *pb.SendTemplateRequest) (*pb.SendResponse, error) {
+	domain, err := s.getCallDomainFromContext(ctx, apikeys.ScopeSend)
+	if err != nil {
+		return nil, err
+	}
+	return s.sendTemplate(ctx, domain, in)
+}
+
+func (s mailAPIService) sendTemplate(ctx context.Context, domain sqlc.Domain, in *pb.SendTemplateRequest) (*pb.SendResponse, error) {
+	if s.requireVerified && !domain.Verified && !domain.Sandbox {
+		return nil, status.Errorf(codes.FailedPrecondition, "domain %v is not verified", domain.Domain)
+	}
+
+	if domain.PausedAt.Valid {
+		return nil, status.Errorf(codes.FailedPrecondition, "domain %v is paused: %v", domain.Domain, domain.PausedReason)
+	}
+
+	if !in.DryRun {
+		if res, ok, err := s.findIdempotentSend(domain.Domain, in.IdempotencyKey); ok || err != nil {
+			return res, err
+		}
+	}
+
+	template, err := s.findTemplate(domain.Domain, in.TemplateId, in.TemplateVersion)
+	if err != nil {
+		logging.Domain(log, domain.Domain).Errorf("cannot create template %v\n", err)
+		return nil, status.Errorf(codes.InvalidArgument, "cannot find template with id: %v", in.TemplateId)
+	}
+
+	attachments, err := s.buildAttachments(in.Attachments)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateReplyTo(in.ReplyTo); err != nil {
+		return nil, err
+	}
+
+	if err := validateSender(domain, in.Sender); err != nil {
+		return nil, err
+	}
+
+	if err := mailbuilder.ValidateCustomHeaders(in.Headers); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid headers: %v", err)
+	}
+
+	recipients := buildRecipients(in.Recipients)
+	if err := validateRecipients(in.To, recipientsEmails(recipients), in.Cc, in.Bcc); err != nil {
+		return nil, err
+	}
+
+	if in.StrictFields {
+		if err := validateFields(in.Fields, in.To, recipients, in.Subject, template.Html, template.Text); err != nil {
+			return nil, err
+		}
+	}
+
+	scheduledTime, err := buildScheduledTime(in.ScheduledTime)
+	if err != nil {
+		return nil, err
+	}
+
+	pm := pool.PoolMessage{
+		Template:   template,
+		To:         in.To,
+		Recipients: recipients,
+		Cc:         in.Cc,
+		Bcc:        in.Bcc,
+		From: pool.Sender{
+			Email: in.GetSender().GetEmail(),
+			Alias: in.GetSender().GetAlias(),
+		},
+		Subject:        in.Subject,
+		Domain:         domain.Domain,
+		Attachments:    attachments,
+		Headers:        in.Headers,
+		ReplyTo:        in.ReplyTo,
+		Fields:         in.Fields,
+		ScheduledTime:  scheduledTime,
+		IdempotencyKey: in.IdempotencyKey,
+		Priority:       buildPriority(in.Priority),
+	}
+	if err := s.checkQuota(domain, pm.EmailsCount()); err != nil {
+		return nil, err
+	}
+	if in.DryRun {
+		return s.dryRunSend(domain, pm)
+	}
+
+	msg, err := s.sendingPoll.AddPool(ctx, pm)
+	if err != nil {
+		logging.Domain(log, domain.Domain).Errorf("cannot create pool %v\n", err)
+		return nil, err
+	}
+	if err := pool.NotifyScheduled(s.b); err != nil {
+		log.Errorf("cannot notify scheduled pool %v\n", err)
+	}
+
+	response := pb.SendResponse{
+		MessageId:       msg.MessageID,
+		TemplateId:      template.TemplateID,
+		TemplateVersion: uint32(template.Version),
+		ScheduledTime:   timestamppb.New(scheduledTime),
+	}
+
+	return &response, nil
+}
+
+func (s mailAPIService) ResendMessage(ctx context.Context, in *pb.ResendMessageRequest) (*pb.SendResponse, error) {
+	domain, err := s.getCallDomainFromContext(ctx, apikeys.ScopeSend)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.requireVerified && !domain.Verified && !domain.Sandbox {
+		return nil, status.Errorf(codes.FailedPrecondition, "domain %v is not verified", domain.Domain)
+	}
+
+	if domain.PausedAt.Valid {
+		return nil, status.Errorf(codes.FailedPrecondition, "domain %v is paused: %v", domain.Domain, domain.PausedReason)
+	}
+
+	if res, ok, err := s.findIdempotentSend(domain.Domain, in.IdempotencyKey); ok || err != nil {
+		return res, err
+	}
+
+	pm, err := s.sendingPoll.GetPoolMessage(domain.Domain, in.MessageId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find message with id: %v", in.MessageId)
+	}
+	if err != nil {
+		logging.Domain(log, domain.Domain).Errorf("cannot get message %v\n", err)
+		return nil, status.Errorf(codes.Internal, "cannot get message: %v", err)
+	}
+
+	if len(in.To) > 0 {
+		if err := validateRecipients(in.To); err != nil {
+			return nil, err
+		}
+		pm = pm.ResendTo(in.To)
+	}
+
+	// every recipient is counted, with the cc and bcc
+	if err := s.checkQuota(domain, pm.EmailsCount()); err != nil {
+		return nil, err
+	}
+
+	version := uint32(pm.Template.Version)
+	if in.Rerender {
+		version = 0
+	}
+	template, err := s.findTemplate(domain.Domain, pm.Template.TemplateID, version)
+	if err != nil {
+		logging.Domain(log, domain.Domain).Errorf("cannot find template %v\n", err)
+		return nil, status.Errorf(codes.FailedPrecondition, "cannot find template with id: %v", pm.Template.TemplateID)
+	}
+	pm.Template = template
+
+	scheduledTime, err := buildScheduledTime(in.ScheduledTime)
+	if err != nil {
+		return nil, err
+	}
+	pm.ScheduledTime = scheduledTime
+	pm.IdempotencyKey = in.IdempotencyKey
+
+	msg, err := s.sendingPoll.AddPool(ctx, pm)
+	if err != nil {
+		logging.Domain(log, domain.Domain).Errorf("cannot create pool %v\n", err)
+		return nil, err
+	}
+	if err := pool.NotifyScheduled(s.b); err != nil {
+		log.Errorf("cannot notify scheduled pool %v\n", err)
+	}
+	logging.Message(log, msg.MessageID).Infof("[🔁 resent] %v as %v", in.MessageId, msg.MessageID)
+
+	return &pb.SendResponse{
+		MessageId:       msg.MessageID,
+		TemplateId:      template.TemplateID,
+		TemplateVersion: uint32(template.Version),
+		ScheduledTime:   timestamppb.New(scheduledTime),
+	}, nil
+}
+
+func (s mailAPIService) CancelMessage(ctx context.Context, in *pb.CancelMessageRequest) (*pb.CancelMessageResponse, error) {
+	domain, err := s.getCallDomainFromContext(ctx, apikeys.ScopeSend)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.sendingPoll.GetMessage(domain.Domain, in.MessageId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "cannot find message with id: %v", in.MessageId)
+	}
+	if err != nil {
+		logging.Domain(log, domain.Domain).Errorf("cannot get message %v\n", err)
+		return nil, status.Errorf(codes.Internal, "cannot get message: %v", err)
+	}
+
+	canceled, err := s.sendingPoll.CancelMessage(ctx, domain.Domain, in.MessageId, in.Emails)
+	if err != nil {
+		logging.Domain(log, domain.Domain).Errorf("cannot cancel message %v\n", err)
+		return nil, status.Errorf(codes.Internal, "cannot cancel message: %v", err)
+	}
+	logging.Message(log, in.MessageId).Infof("[🚫 canceled] %v emails of %v", len(canceled), in.MessageId)
+
+	return &pb.CancelMessageResponse{Canceled: canceled}, nil
+}
+
+// PreviewTemplate renders a version of a template for a recipient like SendTemplate,
+// with the DKIM signature of the domain, without sending it
+func (s mailAPIService) PreviewTemplate(ctx context.Context, in *pb.PreviewTemplateRequest) (*pb.PreviewResponse, error) {
+	domain, err := s.getCallDomainFromContext(ctx, apikeys.ScopeSend)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := s.findTemplate(domain.Domain, in.TemplateId, in.TemplateVersion)
+	if err != nil {
+		log.Errorf("cannot find template %v\n", err)
+		return nil, status.Errorf(codes.InvalidArgument, "cannot find template with id: %v", in.TemplateId)
+	}
+
+	if err := validateReplyTo(in.ReplyTo); err != nil {
+		return nil, err
+	}
+
+	if err := validateSender(domain, in.Sender); err != nil {
+		return nil, err
+	}
+
+	if err := mailbuilder.ValidateCustomHeaders(in.Headers); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid headers: %v", err)
+	}
+
+	if err := validateRecipients([]string{in.To}, in.Cc); err != nil {
+		return nil, err
+	}
+
+	if in.StrictFields {
+		if err := validateFields(in.Fields, []string{in.To}, nil, in.Subject, template.Html, template.Text); err != nil {
+			return nil, err
+		}
+	}
+
+	settings, err := domains.DomainSendingSettings(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	preview, err := mailbuilder.PreviewMessage(pool.PoolMessage{
+		Template: template,
+		Cc:       in.Cc,
+		From: pool.Sender{
+			Email: in.GetSender().GetEmail(),
+			Alias: in.GetSender().GetAlias(),
+		},
+		Subject: in.Subject,
+		Domain:  domain.Domain,
+		Headers: in.Headers,
+		ReplyTo: in.ReplyTo,
+		Fields:  in.Fields,
+	}, pool.Recipient{Email: in.To}, domainKeys(domain), domain.DkimHeaders, settings)
+	if err != nil {
+		log.Errorf("cannot render preview %v\n", err)
+		return nil, status.Errorf(codes.Internal, "cannot render preview: %v", err)
+	}
+
+	return previewToProto(in.To, preview), nil
+}
+
+func (s mailAPIService) GetStats(ctx context.Context, in *pb.GetStatsRequest) (*pb.Stats, error) {
+	domain, err := s.getCallDomainFromContext(ctx, apikeys.ScopeStats)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := in.From.CheckValid(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid from: %v", err)
+	}
+	to := time.Now()
+	if in.To != nil {
+		if err := in.To.CheckValid(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid to: %v", err)
+		}
+		to = in.To.AsTime()
+	}
+
+	var res stats.Stats
+	if in.MessageId != "" {
+		res, err = s.stats.GetMessageStats(domain.Domain, in.MessageId, in.From.AsTime(), to)
+	} else {
+		res, err = s.stats.GetStats(domain.Domain, in.From.AsTime(), to)
+	}
+	if err != nil {
+		log.Errorf("cannot get stats %v\n", err)
+		return nil, status.Errorf(codes.Internal, "cannot get stats: %v", err)
+	}
+
+	return &pb.Stats{
+		Sent:         res.Sent,
+		Delivered:    res.Delivered,
+		Bounced:      res.Bounced,
+		Opened:       res.Opened,
+		Clicked:      res.Clicked,
+		Unsubscribed: res.Unsubscribed,
+		Complained:   res.Complained,
+		Blocked:      res.Blocked,
+	}, nil
+}
+
+func (s mailAPIService) GetSMTPResponses(ctx context.Context, in *pb.GetSMTPResponsesRequest) (*pb.SMTPResponses, error) {
+	domain, err := s.getCallDomainFromContext(ctx, apikeys.ScopeStats)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := in.From.CheckValid(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid from: %v", err)
+	}
+	to := time.Now()
+	if in.To != nil {
+		if err := in.To.CheckValid(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid to: %v", err)
+		}
+		to = in.To.AsTime()
+	}
+
+	responses, err := s.stats.GetSMTPResponses(domain.Domain, in.Provider, in.From.AsTime(), to)
+	if err != nil {
+		log.Errorf("cannot get smtp responses %v\n", err)
+		return nil, status.Errorf(codes.Internal, "cannot get smtp responses: %v", err)
+	}
+
+	res := &pb.SMTPResponses{}
+	for _, r := range responses {
+		res.Responses = append(res.Responses, &pb.SMTPResponse{
+			Provider:     r.Provider,
+			Code:         r.Code,
+			EnhancedCode: r.EnhancedCode,
+			Count:        r.Count,
+		})
+	}
+	return res, nil
+}
+
+func (s mailAPIService) GetDMARCStats(ctx context.Context, in *pb.GetDMARCStatsRequest) (*pb.DMARCStats, error) {
+	domain, err := s.getCallDomainFromContext(ctx, apikeys.ScopeStats)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := in.From.CheckValid(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid from: %v", err)
+	}
+	to := time.Now()
+	if in.To != nil {
+		if err := in.To.CheckValid(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid to: %v", err)
+		}
+		to = in.To.AsTime()
+	}
+
+	sources, err := s.dmarc.GetStats(domain.Domain, in.From.AsTime(), to)
+	if err != nil {
+		log.Errorf("cannot get DMARC stats %v\n", err)
+		return nil, status.Errorf(codes.Internal, "cannot get DMARC stats: %v", err)
+	}
+
+	res := &pb.DMARCStats{Total: &pb.DMARCSourceStats{}}
+	for _, src := range sources {
+		res.Sources = append(res.Sources, dmarcStatsToProtoDMARCSourceStats(src))
+		res.Total.Messages += src.Messages
+		res.Total.Passed += src.Passed
+		res.Total.DkimAligned += src.DKIMAligned
+		res.Total.SpfAligned += src.SPFAligned
+		res.Total.Quarantined += src.Quarantined
+		res.Total.Rejected += src.Rejected
+	}
+	return res, nil
+}
+
+func dmarcStatsToProtoDMARCSourceStats(s dmarc.Stats) *pb.DMARCSourceStats {
+	return &pb.DMARCSourceStats{
+		SourceIp:    s.SourceIP,
+		Messages:    s.Messages,
+		Passed:      s.Passed,
+		DkimAligned: s.DKIMAligned,
+		SpfAligned:  s.SPFAligned,
+		Quarantined: s.Quarantined,
+		Rejected:    s.Rejected,
+	}
+}
+
+func (s mailAPIService) GetQuota(ctx context.Context, in *pb.GetQuotaRequest) (*pb.Quota, error) {
+	domain, err := s.getCallDomainFromContext(ctx, apikeys.ScopeStats)
+	if err != nil {
+		return nil, err
+	}
+
+	quota, err := s.quotas.GetQuota(domain)
+	if err != nil {
+		log.Errorf("cannot get quota %v\n", err)
+		return nil, status.Errorf(codes.Internal, "cannot get quota: %v", err)
+	}
+
+	res := &pb.Quota{
+		Daily:       uint32(quota.Daily),
+		Monthly:     uint32(quota.Monthly),
+		DailyUsed:   uint32(quota.DailyUsed),
+		MonthlyUsed: uint32(quota.MonthlyUsed),
+		Unlimited:   !quota.Limited(),
+	}
+	if quota.Limited() {
+		res.Remaining = uint32(quota.Remaining())
+	}
+	return res, nil
+}
+
+func (s mailAPIService) GetMessageEML(ctx context.Context, in *pb.GetMessageEMLRequest) (*pb.MessageEML, error) {
+	domain, err := s.getCallDomainFromContext(ctx, apikeys.ScopeStats)
+	if err != nil {
+		return nil, err
+	}
+	if s.archive == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "emails are not archived")
+	}
+	// only the emails of the messages of the domain are returned
+	recipients, err := s.sendingPoll.GetMessageRecipients(domain.Domain, in.MessageId)
+	if err != nil {
+		log.Errorf("cannot get message recipients %v\n", err)
+		return nil, status.Errorf(codes.Internal, "cannot get message: %v", err)
+	}
+	if len(recipients) == 0 {
+		return nil, status.Errorf(codes.NotFound, "cannot find message with id: %v", in.MessageId)
+	}
+
+	eml, err := s.archive.Get(ctx, in.MessageId, in.Email)
+	if errors.Is(err, archive.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "email to %v of %v not archived", in.Email, in.MessageId)
+	}
+	if err != nil {
+		log.Errorf("cannot get archived email %v\n", err)
+		return nil, status.Errorf(codes.Internal, "cannot get archived email: %v", err)
+	}
+	return &pb.MessageEML{Eml: eml}, nil
+}
+
+func (s mailAPIService) GetMessageStatus(ctx context.Context, in *pb.GetMessageStatusRequest) (*pb.MessageStatus, error) {
+	domain, err := s.getCallDomainFromContext(ctx, apikeys.ScopeStats)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients, err := s.sendingPoll.GetMessageRecipients(domain.Domain, in.MessageId)
+	if err != nil {
+		log.Errorf("cannot get message recipients %v\n", err)
+		return nil, status.Errorf(codes.Internal, "cannot get message: %v", err)
+	}
+	if len(recipients) == 0 {
+		return nil, status.Errorf(codes.NotFound, "cannot find message with id: %v", in.MessageId)
+	}
+
+	history, err := s.stats.GetMessageEvents(domain.Domain, in.MessageId)
+	if err != nil {
+		log.Errorf("cannot get message events %v\n", err)
+		return nil, status.Errorf(codes.Internal, "cannot get message events: %v", err)
+	}
+	events := make(map[string][]*pb.MessageEvent)
+	for _, e := range history {
+		events[e.Email] = append(events[e.Email], buildMessageEvent(e))
+	}
+
+	entries, err := s.sendingPoll.GetMessageTimeline(domain.Domain, in.MessageId)
+	if err != nil {
+		log.Errorf("cannot get message timeline %v\n", err)
+		return nil, status.Errorf(codes.Internal, "cannot get message timeline: %v", err)
+	}
+	timeline := make(map[string][]*pb.TimelineEntry)
+	for _, e := range entries {
+		timeline[e.Email] = append(timeline[e.Email], buildTimelineEntry(e))
+	}
+
+	msg, err := s.sendingPoll.GetMessage(domain.Domain, in.MessageId)
+	if err != nil {
+		log.Errorf("cannot get message %v\n", err)
+		return nil, status.Errorf(codes.Internal, "cannot get message: %v", err)
+	}
+
+	res := pb.MessageStatus{
+		MessageId:  in.MessageId,
+		Recipients: make([]*pb.RecipientStatus, 0, len(recipients)),
+		ResentFrom: msg.ResentFrom,
+	}
+	for _, r := range recipients {
+		res.Recipients = append(res.Recipients, &pb.RecipientStatus{
+			Email:         r.Email,
+			Status:        recipientStatus(r.Status),
+			BounceType:    string(r.BounceType),
+			ErrorCode:     uint32(r.ErrorCode),
+			ErrorMsg:      r.ErrorMsg,
+			Attempts:      uint32(r.Trial),
+			ScheduledTime: timestamppb.New(r.ScheduledTime),
+			Events:        events[r.Email],
+			Timeline:      timeline[r.Email],
+		})
+	}
+	return &res, nil
+}
+
+func (s mailAPIService) StreamEvents(in *pb.StreamEventsRequest, stream pb.Mailer_StreamEventsServer) error {
+	domain, err := s.getCallDomainFromContext(stream.Context(), apikeys.ScopeStats)
+	if err != nil {
+		return err
+	}
+
+	types, err := events.ParseTypes(in.Types)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+
+	nb, ok := s.b.(*queue.NatsBroker)
+	if !ok {
+		return status.Errorf(codes.Unimplemented, "event streaming requires the nats broker")
+	}
+
+	err = events.Tail(stream.Context(), nb.Conn, nb.Manager, domain.Domain, types, func(e events.Event) error {
+		return stream.Send(eventToProtoEvent(e))
+	})
+	if err != nil {
+		log.Errorf("cannot stream events %v\n", err)
+		return status.Errorf(codes.Internal, "cannot stream events: %v", err)
+	}
+	return nil
+}
+
+func eventToProtoEvent(e events.Event) *pb.MessageEvent {
+	res := pb.MessageEvent{
+		Type:      string(e.Type),
+		Timestamp: timestamppb.New(e.Timestamp),
+		Data:      make(map[string]string, len(e.Data)),
+		MessageId: e.MessageID,
+		Email:     e.Email,
+	}
+	for k, v := range e.Data {
+		res.Data[k] = fmt.Sprint(v)
+	}
+	return &res
+}
+
+// recipientStatus converts the status of a pool email
+// to the status of the message recipient
+func recipientStatus(s sqlc.SendingPoolStatus) string {
+	switch s {
+	case sqlc.SendingPoolStatusSending:
+		return "dispatched"
+	case sqlc.SendingPoolStatusSent:
+		return "delivered"
+	case sqlc.SendingPoolStatusError:
+		return "bounced"
+	case sqlc.SendingPoolStatusSuppressed:
+		return "suppressed"
+	case sqlc.SendingPoolStatusQuotaExceeded:
+		return "quota_exceeded"
+	case sqlc.SendingPoolStatusCanceled:
+		return "canceled"
+	default:
+		return "accepted"
+	}
+}
+
+func buildMessageEvent(e sqlc.MessageEvent) *pb.MessageEvent {
+	data, err := stringData(e.Data)
+	if err != nil {
+		log.Warnf("invalid data of message event %v: %v", e.ID, err)
+	}
+	return &pb.MessageEvent{
+		Type:      e.Type,
+		Timestamp: timestamppb.New(e.Timestamp),
+		Data:      data,
+	}
+}
+
+func buildTimelineEntry(e sqlc.MessageTimeline) *pb.TimelineEntry {
+	data, err := stringData(e.Data)
+	if err != nil {
+		log.Warnf("invalid data of timeline entry %v: %v", e.ID, err)
+	}
+	return &pb.TimelineEntry{
+		Stage:        e.Stage,
+		Timestamp:    timestamppb.New(e.Timestamp),
+		SmtpCode:     uint32(e.SmtpCode),
+		SmtpResponse: e.SmtpResponse,
+		Data:         data,
+	}
+}
+
+// stringData converts the json data of an event to a string map
+func stringData(raw []byte) (map[string]string, error) {
+	var data map[string]interface{}
+	err := json.Unmarshal(raw, &data)
+	res := make(map[string]string, len(data))
+	for k, v := range data {
+		res[k] = fmt.Sprint(v)
+	}
+	return res, err
+}
+
+// checkQuota rejects the sends of n emails exceeding the quota of a domain,
+// the dispatcher enforces the quota of the emails already accepted
+func (s mailAPIService) checkQuota(domain sqlc.Domain, n int) error {
+	quota, err := s.quotas.GetQuota(domain)
+	if err != nil {
+		log.Errorf("cannot get quota %v\n", err)
+		return status.Errorf(codes.Internal, "cannot get quota: %v", err)
+	}
+	if !quota.Allows(uint(n)) {
+		return status.Errorf(codes.ResourceExhausted, "quota of domain %v exceeded, %v emails remaining", domain.Domain, quota.Remaining())
+	}
+	return nil
+}
+
+// findIdempotentSend returns the response of a previous send
+// with the same idempotency key, if any
+func (s mailAPIService) findIdempotentSend(domain string, idempotencyKey string) (*pb.SendResponse, bool, error) {
+	if idempotencyKey == "" {
+		return nil, false, nil
+	}
+	if len(idempotencyKey) > maxIdempotencyKeyLen {
+		return nil, false, status.Errorf(codes.InvalidArgument, "idempotency key longer than %v characters", maxIdempotencyKeyLen)
+	}
+
+	msg, err := s.sendingPoll.FindIdempotentMessage(domain, idempotencyKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		log.Errorf("cannot find message %v\n", err)
+		return nil, false, status.Errorf(codes.Internal, "cannot find message with idempotency key: %v", idempotencyKey)
+	}
+
+	return &pb.SendResponse{
+		MessageId:       msg.MessageID,
+		TemplateId:      msg.TemplateID,
+		TemplateVersion: uint32(msg.TemplateVersion),
+	}, true, nil
+}
+
+// findTemplate finds a version of a template, the active one when version is 0
+func (s mailAPIService) findTemplate(domain string, templateID string, version uint32) (sqlc.Template, error) {
+	if version == 0 {
+		return s.templates.FindTemplate(domain, templateID)
+	}
+	return s.templates.FindTemplateVersion(domain, templateID, uint(version))
+}
+
+// buildAttachments validates request attachments against
+// the configured max size
+func (s mailAPIService) buildAttachments(in []*pb.Attachment) ([]pool.Attachment, error) {
+	attachments := make([]pool.Attachment, 0, len(in))
+	var size uint
+	for _, a := range in {
+		if a.Filename == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "attachment filename cannot be empty")
+		}
+		if a.Inline && !strings.HasPrefix(mime.TypeByExtension(filepath.Ext(a.Filename)), "image/") {
+			return nil, status.Errorf(codes.InvalidArgument, "inline attachment %v is not an image", a.Filename)
+		}
+		size += uint(len(a.Content))
+		if size > s.maxAttachmentSize {
+			return nil, status.Errorf(codes.InvalidArgument, "attachments exceed max size of %v bytes", s.maxAttachmentSize)
+		}
+		attachments = append(attachments, pool.Attachment{
+			Filename: a.Filename,
+			Content:  a.Content,
+			Inline:   a.Inline,
+		})
+	}
+	return attachments, nil
+}
+
+func validateRecipients(recipients ...[]string) error {
+	for _, emails := range recipients {
+		for _, email := range emails {
+			if !smtp.Validate(email) {
+				return status.Errorf(codes.InvalidArgument, "invalid email address: %v", email)
+			}
+		}
+	}
+	return nil
+}
+
+func buildRecipients(in []*pb.Recipient) []pool.Recipient {
+	recipients := make([]pool.Recipient, 0, len(in))
+	for _, r := range in {
+		recipients = append(recipients, pool.Recipient{
+			Email:  r.Email,
+			Fields: r.Fields,
+		})
+	}
+	return recipients
+}
+
+func recipientsEmails(recipients []pool.Recipient) []string {
+	emails := make([]string, 0, len(recipients))
+	for _, r := range recipients {
+		emails = append(emails, r.Email)
+	}
+	return emails
+}
+
+// validateFields checks that all the fields used by subject and
+// template content are provided for every recipient
+func validateFields(fields map[string]string, to []string, recipients []pool.Recipient, contents ...string) error {
+	if len(to) > 0 {
+		if err := checkFields(fields, nil, contents); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid fields: %v", err)
+		}
+	}
+	for _, r := range recipients {
+		if err := checkFields(fields, r.Fields, contents); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid fields for %v: %v", r.Email, err)
+		}
+	}
+	return nil
+}
+
+func checkFields(fields map[string]string, recipientFields map[string]string, contents []string) error {
+	f := map[string]string{
+		templates.EmailField: "",
+	}
+	for _, m := range []map[string]string{fields, recipientFields} {
+		for k, v := range m {
+			f[k] = v
+		}
+	}
+	for _, c := range contents {
+		if _, err := templates.Render(c, f, templates.Strict); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func buildPriority(in pb.Priority) pool.Priority {
+	switch in {
+	case pb.Priority_PRIORITY_TRANSACTIONAL:
+		return pool.Transactional
+	case pb.Priority_PRIORITY_BULK:
+		return pool.Bulk
+	default:
+		return pool.Normal
+	}
+}
+
+// buildScheduledTime returns the time a send is scheduled at,
+// now when not set
+func buildScheduledTime(in *timestamppb.Timestamp) (time.Time, error) {
+	if in == nil {
+		return time.Now(), nil
+	}
+	if err := in.CheckValid(); err != nil {
+		return time.Time{}, status.Errorf(codes.InvalidArgument, "invalid scheduled time: %v", err)
+	}
+	return in.AsTime(), nil
+}
+
+func validateReplyTo(replyTo string) error {
+	if replyTo != "" && !smtp.Validate(replyTo) {
+		return status.Errorf(codes.InvalidArgument, "invalid reply to address: %v", replyTo)
+	}
+	return nil
+}
+
+// validateSender checks that a message has a sender or that its domain has a default one
+func validateSender(domain sqlc.Domain, sender *pb.Sender) error {
+	if sender.GetEmail() == "" && domain.DefaultFromEmail == "" {
+		return status.Errorf(codes.InvalidArgument, "missing sender, domain %v has no default sender", domain.Domain)
+	}
+	return nil
+}
+
+func (s mailAPIService) Close() error {
+	return s.domains.Close()
+}
+
+// getCallDomainFromContext returns the domain of the API key of a call,
+// the key must have scope
+func (s mailAPIService) getCallDomainFromContext(ctx context.Context, scope string) (sqlc.Domain, error) {
+	m, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		log.Debugf("Cannot find metatada\n")
+		return sqlc.Domain{}, status.Errorf(codes.Unauthenticated, "invalid or wrong auth")
+	}
+
+	auths := m.Get("authorization")
+	if len(auths) != 1 {
+		log.Debugf("Cannot find authorization header\n")
+		return sqlc.Domain{}, status.Errorf(codes.Unauthenticated, "invalid or wrong auth")
+	}
+
+	method, _ := grpc.Method(ctx)
+	domain, err := findAuthDomain(s.domains, s.apiKeys, auths[0], scope, method)
+	if errors.Is(err, errMissingScope) {
+		return sqlc.Domain{}, status.Errorf(codes.PermissionDenied, "api key without %v scope", scope)
+	}
+	if err != nil {
+		log.Errorf("invalid login\n")
+		return sqlc.Domain{}, status.Errorf(codes.Unauthenticated, "invalid or wrong auth")
+	}
+	return domain, nil
+}
+
+// errMissingScope is returned for valid API keys without the scope of a call
+var errMissingScope = errors.New("api key without scope")
+
+// findAuthDomain finds the domain of a Basic authorization of domain
+// and API key, the key must have scope. The call of method is logged
+// in the audit trail of the key
+func findAuthDomain(dm domains.DomainManager, km apikeys.Manager, auth string, scope string, method string) (sqlc.Domain, error) {
+	d, k, ok := apikeys.ParseBasicAuth(auth)
+	if !ok {
+		log.Debugf("Invalid Basic auth: %v\n", auth)
+		return sqlc.Domain{}, apikeys.ErrInvalidKey
+	}
+	return findKeyDomain(dm, km, d, k, scope, method)
+}
+
+// findKeyDomain finds the domain d of the API key k, like findAuthDomain
+func findKeyDomain(dm domains.DomainManager, km apikeys.Manager, d string, k string, scope string, method string) (sqlc.Domain, error) {
+	key, err := km.Find(d, k)
+	if err != nil {
+		log.Debugf("Cannot find api key: %v\n", err)
+		return sqlc.Domain{}, err
+	}
+	allowed := apikeys.HasScope(key, scope)
+	if err := km.LogCall(key, method, allowed); err != nil {
+		log.Errorf("Cannot log api key call: %v\n", err)
+		return sqlc.Domain{}, err
+	}
+	if !allowed {
+		return sqlc.Domain{}, errMissingScope
+	}
+
+	domain, err := dm.FindDomain(d)
+	if err != nil {
+		log.Debugf("Cannot find domain: %v\n", err)
+		return sqlc.Domain{}, err
+	}
+
+	return domain, nil
+}
+
+// httpAuthDomain finds the domain of the Basic authorization of an http
+// request with scope, errors are written to w
+func httpAuthDomain(w http.ResponseWriter, r *http.Request, dm domains.DomainManager, km apikeys.Manager, scope string) (sqlc.Domain, bool) {
+	domain, err := findAuthDomain(dm, km, r.Header.Get("Authorization"), scope, r.Method+" "+r.URL.Path)
+	if errors.Is(err, errMissingScope) {
+		http.Error(w, fmt.Sprintf("api key without %v scope", scope), http.StatusForbidden)
+		return sqlc.Domain{}, false
+	}
+	if err != nil {
+		http.Error(w, "invalid or wrong auth", http.StatusUnauthorized)
+		return sqlc.Domain{}, false
+	}
+	return domain, true
+}
+
+// NewMailAPIService creates a Mailer API service, maxAttachmentSize
+// is the max size in bytes of all the attachments of a send request,
+// requireVerified rejects the sends of domains not verified,
+// ar returns the archived emails and is nil when they are not archived.
+// The stats are read from readDB
+func NewMailAPIService(dbi *sql.DB, readDB *sql.DB, b queue.Broker, ar archive.Archive, maxAttachmentSize uint, requireVerified bool) (pb.MailerServer, error) {
+	return newMailAPIService(dbi, readDB, b, ar, maxAttachmentSize, requireVerified)
+}
+
+func newMailAPIService(dbi *sql.DB, readDB *sql.DB, b queue.Broker, ar archive.Archive, maxAttachmentSize uint, requireVerified bool) (*mailAPIService, error) {
+	domainsCli, err := domains.NewDomainManager(dbi)
+	if err != nil {
+		return nil, err
+	}
+
+	sendingPoolCli, err := pool.NewSendingPoolManager(dbi)
+	if err != nil {
+		return nil, err
+	}
+
+	templates, err := templates.NewTemplateManager(dbi)
+	if err != nil {
+		return nil, err
+	}
+
+	statsCli, err := stats.NewStatsManager(readDB)
+	if err != nil {
+		return nil, err
+	}
+
+	dmarcCli, err := dmarc.NewDMARCManager(readDB)
+	if err != nil {
+		return nil, err
+	}
+
+	quotasCli, err := quotas.NewQuotaManager(dbi)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeysCli, err := apikeys.NewAPIKeyManager(dbi)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mailAPIService{
+		domains:           domainsCli,
+		apiKeys:           apiKeysCli,
+		sendingPoll:       sendingPoolCli,
+		templates:         templates,
+		stats:             statsCli,
+		dmarc:             dmarcCli,
+		quotas:            quotasCli,
+		b:                 b,
+		archive:           ar,
+		maxAttachmentSize: maxAttachmentSize,
+		requireVerified:   requireVerified,
+	}, nil
+}
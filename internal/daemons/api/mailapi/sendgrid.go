@@ -0,0 +1,158 @@
+package mailapi
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"kannon.gyozatech.dev/generated/pb"
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/apikeys"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/queue"
+	"kannon.gyozatech.dev/internal/sendgrid"
+)
+
+type sendGridHandler struct {
+	mailer      *mailAPIService
+	maxBodySize int64
+}
+
+// NewSendGridHandler creates an http handler of the SendGrid v3/mail/send requests, authenticated
+// with a Bearer API key with the send scope. The domain of the key is the domain of the from
+// email, or the prefix of a <domain>:<key> bearer. Every personalization is sent like SendHTML,
+// or like SendTemplate with a template_id, and the message ids are the X-Message-Id headers
+func NewSendGridHandler(dbi *sql.DB, b queue.Broker, maxAttachmentSize uint, requireVerified bool) (http.Handler, error) {
+	mailer, err := newMailAPIService(dbi, dbi, b, nil, maxAttachmentSize, requireVerified)
+	if err != nil {
+		return nil, err
+	}
+	// the attachments are base64 encoded in the json body
+	return &sendGridHandler{mailer: mailer, maxBodySize: int64(maxAttachmentSize)*4/3 + 1<<20}, nil
+}
+
+func (h *sendGridHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeSendGridError(w, http.StatusMethodNotAllowed, "", "Method not allowed.")
+		return
+	}
+
+	var m sendgrid.Mail
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, h.maxBodySize)).Decode(&m); err != nil {
+		writeSendGridError(w, http.StatusBadRequest, "", "The request body is not valid JSON: "+err.Error())
+		return
+	}
+
+	domain, err := h.authDomain(r, m)
+	if errors.Is(err, errMissingScope) {
+		writeSendGridError(w, http.StatusForbidden, "", "The API key does not have the send scope.")
+		return
+	}
+	if err != nil {
+		writeSendGridError(w, http.StatusUnauthorized, "", "The provided authorization grant is invalid, expired, or revoked.")
+		return
+	}
+
+	sends, err := m.Sends()
+	var invalid *sendgrid.Error
+	if errors.As(err, &invalid) {
+		writeSendGridError(w, http.StatusBadRequest, invalid.Field, invalid.Message)
+		return
+	}
+	if err != nil {
+		writeSendGridError(w, http.StatusBadRequest, "", err.Error())
+		return
+	}
+
+	// the personalizations are checked before the first is sent, the sends are not atomic:
+	// a failure after the check leaves the previous ones sent, with their X-Message-Id headers
+	if len(sends) > 1 && !m.MailSettings.SandboxMode.Enable {
+		for _, send := range sends {
+			if _, err := h.send(r, domain, send, true); err != nil {
+				writeSendError(w, err)
+				return
+			}
+		}
+	}
+	for _, send := range sends {
+		res, err := h.send(r, domain, send, m.MailSettings.SandboxMode.Enable)
+		if err != nil {
+			writeSendError(w, err)
+			return
+		}
+		w.Header().Add("X-Message-Id", res.MessageId)
+	}
+	logging.Domain(log, domain.Domain).Infof("[📨 sendgrid] %v messages", len(sends))
+	if m.MailSettings.SandboxMode.Enable {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// send sends a personalization, as a dry run with dryRun
+func (h *sendGridHandler) send(r *http.Request, domain sqlc.Domain, send sendgrid.Send, dryRun bool) (*pb.SendResponse, error) {
+	if send.Template != nil {
+		send.Template.DryRun = dryRun
+		return h.mailer.sendTemplate(r.Context(), domain, send.Template)
+	}
+	send.HTML.DryRun = dryRun
+	return h.mailer.sendHTML(r.Context(), domain, send.HTML)
+}
+
+// authDomain finds the domain of the Bearer key of r, of the domain of the from email of m
+// or of the prefix of a <domain>:<key> bearer
+func (h *sendGridHandler) authDomain(r *http.Request, m sendgrid.Mail) (sqlc.Domain, error) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return sqlc.Domain{}, apikeys.ErrInvalidKey
+	}
+	key := strings.TrimPrefix(auth, "Bearer ")
+	var d string
+	if i := strings.LastIndex(key, ":"); i >= 0 {
+		d, key = key[:i], key[i+1:]
+	} else if m.From != nil {
+		if i := strings.LastIndex(m.From.Email, "@"); i >= 0 {
+			d = strings.ToLower(m.From.Email[i+1:])
+		}
+	}
+	return findKeyDomain(h.mailer.domains, h.mailer.apiKeys, d, key, apikeys.ScopeSend, r.Method+" "+r.URL.Path)
+}
+
+// writeSendError writes the error of a send with the status of its code
+func writeSendError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	switch st.Code() {
+	case codes.InvalidArgument:
+		writeSendGridError(w, http.StatusBadRequest, "", st.Message())
+	case codes.FailedPrecondition, codes.PermissionDenied:
+		writeSendGridError(w, http.StatusForbidden, "", st.Message())
+	case codes.ResourceExhausted:
+		writeSendGridError(w, http.StatusTooManyRequests, "", st.Message())
+	default:
+		log.Errorf("cannot send sendgrid request: %v", err)
+		writeSendGridError(w, http.StatusInternalServerError, "", "Cannot send the message, try again later.")
+	}
+}
+
+// writeSendGridError writes an error in the format of the SendGrid responses, without a field when empty
+func writeSendGridError(w http.ResponseWriter, code int, field string, message string) {
+	type sendGridError struct {
+		Message string      `json:"message"`
+		Field   *string     `json:"field"`
+		Help    interface{} `json:"help"`
+	}
+	e := sendGridError{Message: message}
+	if field != "" {
+		e.Field = &field
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(map[string][]sendGridError{"errors": {e}}); err != nil {
+		log.Errorf("cannot write sendgrid error: %v", err)
+	}
+}
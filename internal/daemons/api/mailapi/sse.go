@@ -0,0 +1,97 @@
+package mailapi
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"kannon.gyozatech.dev/internal/apikeys"
+	"kannon.gyozatech.dev/internal/domains"
+	"kannon.gyozatech.dev/internal/events"
+	"kannon.gyozatech.dev/internal/queue"
+)
+
+type eventsHandler struct {
+	domains domains.DomainManager
+	apiKeys apikeys.Manager
+	b       queue.Broker
+}
+
+// NewEventsHandler creates an http handler streaming the events of the
+// authenticated domain as server-sent events, types can be filtered with
+// a comma separated types query parameter, streaming is only
+// available with the nats broker
+func NewEventsHandler(dbi *sql.DB, b queue.Broker) (http.Handler, error) {
+	domainsCli, err := domains.NewDomainManager(dbi)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeysCli, err := apikeys.NewAPIKeyManager(dbi)
+	if err != nil {
+		return nil, err
+	}
+
+	return &eventsHandler{
+		domains: domainsCli,
+		apiKeys: apiKeysCli,
+		b:       b,
+	}, nil
+}
+
+func (h *eventsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	domain, ok := httpAuthDomain(w, r, h.domains, h.apiKeys, apikeys.ScopeStats)
+	if !ok {
+		return
+	}
+
+	var names []string
+	if q := r.URL.Query().Get("types"); q != "" {
+		names = strings.Split(q, ",")
+	}
+	types, err := events.ParseTypes(names)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	nb, ok := h.b.(*queue.NatsBroker)
+	if !ok {
+		http.Error(w, "event streaming requires the nats broker", http.StatusNotImplemented)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	err = events.Tail(r.Context(), nb.Conn, nb.Manager, domain.Domain, types, func(e events.Event) error {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: %v\ndata: %s\n\n", e.Type, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	})
+	if err != nil {
+		log.Errorf("cannot stream events %v\n", err)
+	}
+}
@@ -0,0 +1,160 @@
+package mailapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-smtp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"kannon.gyozatech.dev/generated/pb"
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/apikeys"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/queue"
+	ksmtp "kannon.gyozatech.dev/internal/smtp"
+	"kannon.gyozatech.dev/internal/submission"
+)
+
+// submissionTimeout bounds the send of a submitted message
+const submissionTimeout = 30 * time.Second
+
+type submissionBackend struct {
+	mailer *mailAPIService
+}
+
+// NewSubmissionBackend creates the SMTP backend of the submissions of legacy apps, authenticated
+// with the domain as username and an API key with the send scope as password. Every submitted
+// message is sent like SendHTML to the envelope recipients, the Message-ID and the recipients
+// are the idempotency key
+func NewSubmissionBackend(dbi *sql.DB, b queue.Broker, maxAttachmentSize uint, requireVerified bool) (smtp.Backend, error) {
+	mailer, err := newMailAPIService(dbi, dbi, b, nil, maxAttachmentSize, requireVerified)
+	if err != nil {
+		return nil, err
+	}
+	return &submissionBackend{mailer: mailer}, nil
+}
+
+func (b *submissionBackend) Login(state *smtp.ConnectionState, username, password string) (smtp.Session, error) {
+	domain, err := findKeyDomain(b.mailer.domains, b.mailer.apiKeys, username, password, apikeys.ScopeSend, "SMTP AUTH")
+	if err != nil {
+		log.Infof("[🔒 submission] invalid login of %v from %v", username, state.RemoteAddr)
+		return nil, &smtp.SMTPError{
+			Code:         535,
+			EnhancedCode: smtp.EnhancedCode{5, 7, 8},
+			Message:      "invalid domain or api key",
+		}
+	}
+	return &submissionSession{mailer: b.mailer, domain: domain}, nil
+}
+
+func (b *submissionBackend) AnonymousLogin(state *smtp.ConnectionState) (smtp.Session, error) {
+	return nil, smtp.ErrAuthRequired
+}
+
+// submissionSession sends the messages of an authenticated domain
+type submissionSession struct {
+	mailer *mailAPIService
+	domain sqlc.Domain
+	to     []string
+}
+
+func (s *submissionSession) Reset() {
+	s.to = nil
+}
+
+func (s *submissionSession) Logout() error {
+	return nil
+}
+
+// Mail accepts every envelope sender, the return path of the emails is set by kannon
+func (s *submissionSession) Mail(from string, opts smtp.MailOptions) error {
+	return nil
+}
+
+func (s *submissionSession) Rcpt(to string) error {
+	if !ksmtp.Validate(to) {
+		return &smtp.SMTPError{
+			Code:         553,
+			EnhancedCode: smtp.EnhancedCode{5, 1, 3},
+			Message:      "invalid recipient address",
+		}
+	}
+	s.to = append(s.to, to)
+	return nil
+}
+
+func (s *submissionSession) Data(r io.Reader) error {
+	msg, err := submission.Parse(r)
+	if err != nil {
+		return &smtp.SMTPError{
+			Code:         554,
+			EnhancedCode: smtp.EnhancedCode{5, 6, 0},
+			Message:      "invalid message: " + err.Error(),
+		}
+	}
+
+	in := &pb.SendHTMLRequest{
+		To:             s.to,
+		Subject:        msg.Subject,
+		Html:           msg.HTML,
+		Text:           msg.Text,
+		Headers:        msg.Headers,
+		ReplyTo:        msg.ReplyTo,
+		IdempotencyKey: submissionIdempotencyKey(msg.MessageID, s.to),
+	}
+	if msg.From != nil {
+		in.Sender = &pb.Sender{Email: msg.From.Address, Alias: msg.From.Name}
+	}
+	for _, a := range msg.Attachments {
+		in.Attachments = append(in.Attachments, &pb.Attachment{Filename: a.Filename, Content: a.Content, Inline: a.Inline})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), submissionTimeout)
+	defer cancel()
+	res, err := s.mailer.sendHTML(ctx, s.domain, in)
+	if err != nil {
+		return submissionError(err)
+	}
+	logging.Domain(log, s.domain.Domain).Infof("[📨 submission] %v - %v recipients", res.MessageId, len(s.to))
+	return nil
+}
+
+// submissionIdempotencyKey returns the idempotency key of a message submitted to
+// recipients, the hex sha256 of its Message-ID and sorted recipients. Apps sending
+// a message in a transaction per recipient reuse the Message-ID, each transaction
+// is a send of its own. Messages without Message-ID have no key
+func submissionIdempotencyKey(messageID string, recipients []string) string {
+	if messageID == "" {
+		return ""
+	}
+	rcpts := make([]string, len(recipients))
+	for i, r := range recipients {
+		rcpts[i] = strings.ToLower(r)
+	}
+	sort.Strings(rcpts)
+	h := sha256.Sum256([]byte(messageID + "\n" + strings.Join(rcpts, "\n")))
+	return hex.EncodeToString(h[:])
+}
+
+// submissionError returns the SMTP reply of the error of a send, the
+// invalid submissions are rejected and the other errors are temporary
+func submissionError(err error) error {
+	st := status.Convert(err)
+	switch st.Code() {
+	case codes.InvalidArgument:
+		return &smtp.SMTPError{Code: 554, EnhancedCode: smtp.EnhancedCode{5, 6, 0}, Message: st.Message()}
+	case codes.FailedPrecondition:
+		return &smtp.SMTPError{Code: 554, EnhancedCode: smtp.EnhancedCode{5, 7, 1}, Message: st.Message()}
+	case codes.ResourceExhausted:
+		return &smtp.SMTPError{Code: 451, EnhancedCode: smtp.EnhancedCode{4, 7, 1}, Message: st.Message()}
+	}
+	log.Errorf("cannot send submission: %v", err)
+	return &smtp.SMTPError{Code: 451, EnhancedCode: smtp.EnhancedCode{4, 3, 0}, Message: "cannot send message, try again later"}
+}
@@ -0,0 +1,19 @@
+package mailapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubmissionIdempotencyKey(t *testing.T) {
+	key := submissionIdempotencyKey("<1@app.io>", []string{"a@test.com", "B@test.com"})
+	assert.Len(t, key, 64)
+	assert.Equal(t, key, submissionIdempotencyKey("<1@app.io>", []string{"b@test.com", "a@test.com"}))
+
+	// a transaction per recipient of the same message
+	assert.NotEqual(t, key, submissionIdempotencyKey("<1@app.io>", []string{"a@test.com"}))
+	assert.NotEqual(t, key, submissionIdempotencyKey("<2@app.io>", []string{"a@test.com", "b@test.com"}))
+
+	assert.Equal(t, "", submissionIdempotencyKey("", []string{"a@test.com"}))
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+
+	"github.com/joho/godotenv"
+	"kannon.gyozatech.dev/internal/configfile"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/migrate"
+)
+
+// Migrate runs the migrate command with the migrations built in the
+// binary on the database of DB_CONN: up (default) applies the pending
+// migrations, down rolls back the last one and status lists them
+func Migrate(args []string) error {
+	_ = godotenv.Load()
+
+	var config appConfig
+	if err := configfile.Load("api", &config); err != nil {
+		return fmt.Errorf("cannot read config: %w", err)
+	}
+	if err := logging.Setup(config.Log); err != nil {
+		return fmt.Errorf("invalid log config: %w", err)
+	}
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	if len(args) > 1 || (command != "up" && command != "down" && command != "status") {
+		return fmt.Errorf("usage: migrate [up|down|status]")
+	}
+
+	migrations, err := migrate.Embedded()
+	if err != nil {
+		return err
+	}
+	db, err := sql.Open("postgres", os.Getenv("DB_CONN"))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	switch command {
+	case "up":
+		applied, err := migrate.Up(ctx, db, migrations)
+		if err != nil {
+			return err
+		}
+		log.Infof("%v migrations applied", len(applied))
+	case "down":
+		m, err := migrate.Down(ctx, db, migrations)
+		if err != nil {
+			return err
+		}
+		if m == nil {
+			log.Infof("no migration to roll back")
+		}
+	case "status":
+		statuses, err := migrate.GetStatus(ctx, db, migrations)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			status := "pending"
+			if s.Applied {
+				status = "applied"
+			}
+			fmt.Printf("%v\t%v\n", status, s.Name)
+		}
+	}
+	return nil
+}
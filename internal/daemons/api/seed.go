@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/joho/godotenv"
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/apikeys"
+	"kannon.gyozatech.dev/internal/configfile"
+	"kannon.gyozatech.dev/internal/domains"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/migrate"
+	"kannon.gyozatech.dev/internal/smtp"
+	"kannon.gyozatech.dev/internal/templates"
+)
+
+const (
+	seedHTML = `<html><body><h1>Hello {{ name }}!</h1><p>This email was sent by kannon to {{ email }}.</p></body></html>`
+	seedText = "Hello {{ name }}!\n\nThis email was sent by kannon to {{ email }}.\n"
+)
+
+// Seed runs the seed command for local development: it migrates the
+// database of DB_CONN and creates a demo domain with its DKIM keys, an API
+// key and a sample template. Seeding an existing domain regenerates its key.
+// With -mailhog the config of a sender delivering to MailHog is written
+func Seed(args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	domain := fs.String("domain", "kannon.test", "Demo domain to create")
+	mailhog := fs.String("mailhog", "", "SMTP address of MailHog receiving the emails of the sender, like localhost:1025, empty writes no config")
+	configPath := fs.String("config", "kannon.dev.yaml", "Config file written with -mailhog, used with KANNON_CONFIG")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("usage: seed [-domain <domain>] [-mailhog <host:port> [-config <file>]]")
+	}
+	if _, err := smtp.ParseRelay(*mailhog); err != nil {
+		return err
+	}
+
+	_ = godotenv.Load()
+
+	var config appConfig
+	if err := configfile.Load("api", &config); err != nil {
+		return fmt.Errorf("cannot read config: %w", err)
+	}
+	if err := logging.Setup(config.Log); err != nil {
+		return fmt.Errorf("invalid log config: %w", err)
+	}
+
+	db, err := sql.Open("postgres", os.Getenv("DB_CONN"))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrations, err := migrate.Embedded()
+	if err != nil {
+		return err
+	}
+	if _, err := migrate.Up(context.Background(), db, migrations); err != nil {
+		return fmt.Errorf("cannot migrate database: %w", err)
+	}
+
+	dm, err := domains.NewDomainManager(db)
+	if err != nil {
+		return err
+	}
+	km, err := apikeys.NewAPIKeyManager(db)
+	if err != nil {
+		return err
+	}
+	tm, err := templates.NewTemplateManager(db)
+	if err != nil {
+		return err
+	}
+
+	d, key, template, err := seedDomain(dm, km, tm, *domain)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("domain:   %v\n", d.Domain)
+	fmt.Printf("api key:  %v\n", key)
+	if template != "" {
+		fmt.Printf("template: %v\n", template)
+	}
+	fmt.Printf("dkim:     %v._domainkey.%v\n", d.DkimSelector, d.Domain)
+
+	if *mailhog != "" {
+		if err := writeDevConfig(*configPath, *mailhog); err != nil {
+			return err
+		}
+		fmt.Printf("config:   %v, start the daemons with KANNON_CONFIG=%v\n", *configPath, *configPath)
+	}
+	return nil
+}
+
+// seedDomain creates domain with an admin key and a sample template, or
+// regenerates the key of an existing domain. It returns the domain, its key
+// and the id of the template created
+func seedDomain(dm domains.DomainManager, km apikeys.Manager, tm templates.Manager, domain string) (sqlc.Domain, string, string, error) {
+	d, err := dm.FindDomain(domain)
+	if err == nil {
+		_, key, err := km.Regenerate(d.Domain)
+		if err != nil {
+			return sqlc.Domain{}, "", "", err
+		}
+		log.Infof("🌱 domain %v already seeded, its key is regenerated", d.Domain)
+		return d, key, "", nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return sqlc.Domain{}, "", "", err
+	}
+
+	d, err = dm.CreateDomain(domain)
+	if err != nil {
+		return sqlc.Domain{}, "", "", err
+	}
+	_, key, err := km.Create(d.Domain, apikeys.DefaultName, []string{apikeys.ScopeAdmin})
+	if err != nil {
+		return sqlc.Domain{}, "", "", err
+	}
+	t, err := tm.CreateNamedTemplate(d.Domain, "welcome", seedHTML, seedText)
+	if err != nil {
+		return sqlc.Domain{}, "", "", err
+	}
+	log.Infof("🌱 seeded domain %v", d.Domain)
+	return d, key, t.TemplateID, nil
+}
+
+// writeDevConfig writes a config file whose sender delivers every email to
+// the MailHog of mailhog, ready without port 25 reachable
+func writeDevConfig(path string, mailhog string) error {
+	content := fmt.Sprintf(`# written by api seed for local development
+database_url: %q
+sender:
+  relay: %q
+  health-smtp-addr: %q
+`, os.Getenv("DB_CONN"), mailhog, mailhog)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		return fmt.Errorf("cannot write config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+// Package bouncer is the bouncer daemon, its SMTP server receives the bounces,
+// the feedback loop reports and the DMARC reports of the emails
+package bouncer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-smtp"
+	"github.com/joho/godotenv"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"kannon.gyozatech.dev/generated/pb"
+	"kannon.gyozatech.dev/internal/bounce"
+	"kannon.gyozatech.dev/internal/configfile"
+	"kannon.gyozatech.dev/internal/dmarc"
+	"kannon.gyozatech.dev/internal/errorreport"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/mailbuilder"
+	"kannon.gyozatech.dev/internal/metrics"
+	"kannon.gyozatech.dev/internal/queue"
+	ksmtp "kannon.gyozatech.dev/internal/smtp"
+)
+
+var log = logging.Logger("bouncer")
+
+type appConfig struct {
+	queue.Config
+	// MetricsPort is the port of the metrics and health endpoints, 0 disables them
+	MetricsPort uint16 `default:"9090"`
+	// DebugAddr is the address of the pprof and runtime stats server, like localhost:6060, empty disables it
+	DebugAddr string
+	// Log configures the log lines, like APP_LOG_FORMAT=json and APP_LOG_LEVELS=queue=debug
+	Log logging.Config
+	// Sentry reports the errors and panics, like APP_SENTRY_DSN
+	Sentry         errorreport.Config
+	Addr           string `default:":25"`
+	Hostname       string `default:"localhost"`
+	MaxMessageSize int    `default:"10485760"`
+	// FeedbackAddresses receive feedback loop reports of mailbox providers
+	FeedbackAddresses []string
+	// DMARCAddresses receive the DMARC aggregate reports of the rua of domains
+	DMARCAddresses []string
+}
+
+// Run runs the bouncer daemon until ctx is canceled
+func Run(ctx context.Context) error {
+	_ = godotenv.Load()
+
+	var config appConfig
+	if err := configfile.Load("bouncer", &config); err != nil {
+		return err
+	}
+	if err := logging.Setup(config.Log); err != nil {
+		return fmt.Errorf("invalid log config: %w", err)
+	}
+	stopReports, err := errorreport.Start(config.Sentry, "kannon-bouncer")
+	if err != nil {
+		return fmt.Errorf("invalid sentry config: %w", err)
+	}
+	defer stopReports()
+	defer errorreport.Recover()
+
+	b, err := queue.Open(config.Config, nil)
+	if err != nil {
+		return fmt.Errorf("cannot connect to %v: %w", config.Broker, err)
+	}
+	defer b.Close()
+
+	feedbackAddresses := make(map[string]bool)
+	for _, addr := range config.FeedbackAddresses {
+		feedbackAddresses[strings.ToLower(addr)] = true
+	}
+
+	dmarcAddresses := make(map[string]bool)
+	for _, addr := range config.DMARCAddresses {
+		dmarcAddresses[strings.ToLower(addr)] = true
+	}
+
+	s := smtp.NewServer(&backend{p: b, feedbackAddresses: feedbackAddresses, dmarcAddresses: dmarcAddresses})
+	s.Addr = config.Addr
+	s.Domain = config.Hostname
+	s.MaxMessageBytes = config.MaxMessageSize
+	s.MaxRecipients = 50
+	s.ReadTimeout = 60 * time.Second
+	s.WriteTimeout = 60 * time.Second
+
+	metrics.Serve(config.MetricsPort, queue.HealthCheck(b))
+	metrics.ServeDebug(config.DebugAddr)
+
+	// the log levels change without a restart
+	configfile.Watch(func() {
+		var next appConfig
+		if err := configfile.Load("bouncer", &next); err != nil {
+			log.Errorf("cannot reload config: %v", err)
+			return
+		}
+		if err := logging.Setup(next.Log); err != nil {
+			log.Errorf("cannot reload log config: %v", err)
+			return
+		}
+		log.Infof("config reloaded")
+	})
+
+	go func() {
+		<-ctx.Done()
+		s.Close()
+	}()
+
+	log.Infof("🚀 starting bouncer on %v\n", config.Addr)
+	if err := s.ListenAndServe(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("cannot start bouncer: %w", err)
+	}
+	log.Infof("bouncer stopped")
+	return nil
+}
+
+type backend struct {
+	p                 queue.Publisher
+	feedbackAddresses map[string]bool
+	dmarcAddresses    map[string]bool
+}
+
+func (b *backend) Login(state *smtp.ConnectionState, username, password string) (smtp.Session, error) {
+	return nil, smtp.ErrAuthUnsupported
+}
+
+func (b *backend) AnonymousLogin(state *smtp.ConnectionState) (smtp.Session, error) {
+	return &session{p: b.p, feedbackAddresses: b.feedbackAddresses, dmarcAddresses: b.dmarcAddresses}, nil
+}
+
+// returnPath is a recipient of a bounce, an email
+// return path identifying the original message
+type returnPath struct {
+	to        string
+	messageID string
+}
+
+// session accepts only mail to return paths, feedback and dmarc addresses
+type session struct {
+	p                 queue.Publisher
+	feedbackAddresses map[string]bool
+	dmarcAddresses    map[string]bool
+	returnPaths       []returnPath
+	dmarc             bool
+}
+
+func (s *session) Reset() {
+	s.returnPaths = nil
+	s.dmarc = false
+}
+
+func (s *session) Logout() error {
+	return nil
+}
+
+func (s *session) Mail(from string, opts smtp.MailOptions) error {
+	return nil
+}
+
+func (s *session) Rcpt(to string) error {
+	if s.feedbackAddresses[strings.ToLower(to)] {
+		return nil
+	}
+	if s.dmarcAddresses[strings.ToLower(to)] {
+		s.dmarc = true
+		return nil
+	}
+	rcpt, messageID, err := mailbuilder.ParseReturnPath(to)
+	if err != nil {
+		return &smtp.SMTPError{
+			Code:         550,
+			EnhancedCode: smtp.EnhancedCode{5, 1, 1},
+			Message:      "mailbox unavailable",
+		}
+	}
+	s.returnPaths = append(s.returnPaths, returnPath{to: rcpt, messageID: messageID})
+	return nil
+}
+
+func (s *session) Data(r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	if s.dmarc {
+		return s.handleDMARCReport(data)
+	}
+
+	// some providers send feedback reports to the return path
+	complaint, err := bounce.ParseARF(bytes.NewReader(data))
+	if err == nil {
+		return s.handleComplaint(complaint)
+	}
+	if !errors.Is(err, bounce.ErrNotARF) {
+		log.Warnf("cannot parse ARF: %v", err)
+		return nil
+	}
+
+	recipients, err := bounce.ParseDSN(bytes.NewReader(data))
+	if errors.Is(err, bounce.ErrNotDSN) {
+		// auto replies and other mail sent to return paths are discarded
+		log.Debugf("ignoring message to return path, not a DSN")
+		return nil
+	}
+	if err != nil {
+		log.Warnf("cannot parse DSN: %v", err)
+		return nil
+	}
+
+	for _, rp := range s.returnPaths {
+		rcpt, ok := findRecipient(recipients, rp.to)
+		if !ok || !rcpt.Failed() {
+			continue
+		}
+		if err := publishBounce(s.p, rp, rcpt); err != nil {
+			log.Errorf("cannot publish bounce: %v", err)
+			return &smtp.SMTPError{
+				Code:         451,
+				EnhancedCode: smtp.EnhancedCode{4, 3, 0},
+				Message:      "cannot process bounce, try again later",
+			}
+		}
+		log.Infof("[🛑 async bump] %v %v - %v", rp.to, rp.messageID, rcpt.DiagnosticCode)
+	}
+	return nil
+}
+
+// handleDMARCReport publishes the aggregate report attached to a message,
+// other messages are discarded
+func (s *session) handleDMARCReport(data []byte) error {
+	report, err := dmarc.ParseMessage(bytes.NewReader(data))
+	if err != nil {
+		log.Warnf("cannot parse DMARC report: %v", err)
+		return nil
+	}
+	msg, err := dmarc.Marshal(report)
+	if err != nil {
+		return err
+	}
+	if err := s.p.Publish("emails.dmarc", msg); err != nil {
+		log.Errorf("cannot publish DMARC report: %v", err)
+		return &smtp.SMTPError{
+			Code:         451,
+			EnhancedCode: smtp.EnhancedCode{4, 3, 0},
+			Message:      "cannot process report, try again later",
+		}
+	}
+	log.Infof("[📊 dmarc report] %v %v - %v", report.Domain, report.OrgName, report.ReportID)
+	return nil
+}
+
+// handleComplaint publishes a complaint for the message reported
+// by a feedback report
+func (s *session) handleComplaint(c bounce.Complaint) error {
+	to, messageID, err := mailbuilder.ParseEmailMessageID(c.MessageID)
+	if err != nil {
+		log.Warnf("cannot find message of feedback report: %v", err)
+		return nil
+	}
+	// providers redact the Original-Rcpt-To or replace it with an opaque
+	// token, the recipient encoded in the Message-ID is the one complaining
+	if to == "" {
+		to = c.OriginalRcptTo
+	}
+	if to == "" {
+		log.Warnf("cannot find recipient of feedback report of %v", messageID)
+		return nil
+	}
+
+	msg, err := proto.Marshal(&pb.Complaint{
+		MessageId:    messageID,
+		Email:        to,
+		FeedbackType: c.FeedbackType,
+		Timestamp:    timestamppb.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.p.Publish("emails.complained", msg); err != nil {
+		log.Errorf("cannot publish complaint: %v", err)
+		return &smtp.SMTPError{
+			Code:         451,
+			EnhancedCode: smtp.EnhancedCode{4, 3, 0},
+			Message:      "cannot process report, try again later",
+		}
+	}
+	log.Infof("[😡 complained] %v %v - %v", to, messageID, c.FeedbackType)
+	return nil
+}
+
+// findRecipient returns the status of the recipient encoded in a return path,
+// a DSN with a single recipient is about the return path recipient
+func findRecipient(recipients []bounce.Recipient, to string) (bounce.Recipient, bool) {
+	for _, r := range recipients {
+		if strings.EqualFold(r.FinalRecipient, to) {
+			return r, true
+		}
+	}
+	if len(recipients) == 1 {
+		return recipients[0], true
+	}
+	return bounce.Recipient{}, false
+}
+
+func publishBounce(p queue.Publisher, rp returnPath, rcpt bounce.Recipient) error {
+	diagnostic := rcpt.DiagnosticCode
+	if diagnostic == "" {
+		diagnostic = rcpt.Status
+	}
+	code := rcpt.Code()
+	msg, err := proto.Marshal(&pb.Error{
+		MessageId:   mailbuilder.BuildEmailMessageID(rp.to, rp.messageID),
+		Email:       rp.to,
+		Code:        uint32(code),
+		Msg:         diagnostic,
+		IsPermanent: ksmtp.ClassifyBounce(code, rcpt.Status, true) == ksmtp.HardBounce,
+		Timestamp:   timestamppb.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	return p.Publish("emails.error", msg)
+}
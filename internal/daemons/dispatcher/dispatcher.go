@@ -0,0 +1,749 @@
+// Package dispatcher is the dispatcher daemon, it builds the scheduled emails
+// for the senders and handles their results
+package dispatcher
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"github.com/joho/godotenv"
+	"google.golang.org/protobuf/proto"
+	"kannon.gyozatech.dev/generated/pb"
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/archive"
+	"kannon.gyozatech.dev/internal/configfile"
+	"kannon.gyozatech.dev/internal/dbconfig"
+	"kannon.gyozatech.dev/internal/deadletters"
+	"kannon.gyozatech.dev/internal/errorreport"
+	"kannon.gyozatech.dev/internal/health"
+	"kannon.gyozatech.dev/internal/jetstream"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/mailbuilder"
+	"kannon.gyozatech.dev/internal/maintenance"
+	"kannon.gyozatech.dev/internal/metrics"
+	"kannon.gyozatech.dev/internal/pool"
+	"kannon.gyozatech.dev/internal/queue"
+	"kannon.gyozatech.dev/internal/quotas"
+	"kannon.gyozatech.dev/internal/suppressions"
+	"kannon.gyozatech.dev/internal/tracing"
+	"kannon.gyozatech.dev/internal/tracking"
+)
+
+var log = logging.Logger("dispatcher")
+
+// inFlightWait is the wait before fetching emails again
+// when the max in-flight emails are dispatched or sending is halted
+const inFlightWait = time.Second
+
+// releaseDelay is the wait before an email that cannot be dispatched is fetched again
+const releaseDelay = time.Minute
+
+type appConfig struct {
+	queue.Config
+	// MetricsPort is the port of the metrics and health endpoints, 0 disables them
+	MetricsPort uint16 `default:"9090"`
+	// DebugAddr is the address of the pprof and runtime stats server, like localhost:6060, empty disables it
+	DebugAddr string
+	// Log configures the log lines, like APP_LOG_FORMAT=json and APP_LOG_LEVELS=queue=debug
+	Log logging.Config
+	// DB configures the connection pool to the database, like APP_DB_MAXOPENCONNS
+	DB dbconfig.Config
+	// Sentry reports the errors and panics, like APP_SENTRY_DSN
+	Sentry errorreport.Config
+	// MetricsInterval is the interval between updates of the pool metrics
+	MetricsInterval time.Duration `default:"15s"`
+	// Tracing is the collector of the spans, like APP_TRACING_ENDPOINT
+	Tracing tracing.Config
+	// TrackingURL is the base url of the tracker, open tracking is disabled when empty.
+	// TrackingSecret signs the tracking urls, it is required with TrackingURL
+	TrackingURL    string
+	TrackingSecret string
+	// Complaints enables recording of feedback loop complaints published by the bouncer
+	Complaints bool
+	// MaxAttempts is the number of sends of soft bounced emails before they are marked as failed
+	MaxAttempts    uint          `default:"5"`
+	RetryBaseDelay time.Duration `default:"15m"`
+	RetryMaxDelay  time.Duration `default:"6h"`
+	// PollInterval is the max wait between fetches of scheduled emails,
+	// dispatchers are woken up earlier when the api schedules emails
+	PollInterval time.Duration `default:"10s"`
+	// BatchSize is the max number of emails fetched at once
+	BatchSize uint `default:"100"`
+	// MaxInFlight is the max number of emails dispatched and
+	// not yet delivered or bounced, 0 is unlimited
+	MaxInFlight uint `default:"0"`
+	// ClaimLease is the max time between the fetch of an email and its publish to the
+	// senders, the emails fetched before and not published are scheduled again.
+	// With the poll interval it's shorter than jetstream.DuplicateWindow, so an
+	// email published before a crash and published again is sent once
+	ClaimLease time.Duration `default:"5m"`
+	// IPPools are the ip pools of the sender used by the emails of every
+	// priority when their domain has no pool, like APP_IPPOOLS_BULK
+	IPPools priorityIPPools
+	// ErrorConsumer configures the email-error consumer, like APP_ERRORCONSUMER_ACKWAIT
+	ErrorConsumer jetstream.ConsumerConfig
+	// DeliveredConsumer configures the email-delivered consumer, like APP_DELIVEREDCONSUMER_ACKWAIT
+	DeliveredConsumer jetstream.ConsumerConfig
+	// Archive is the bucket of the signed emails dispatched, like APP_ARCHIVE_BUCKET
+	Archive archive.Config
+	// MaintenanceInterval is the interval between the reads of the maintenance switch,
+	// published to the senders
+	MaintenanceInterval time.Duration `default:"10s"`
+}
+
+// liveConfig is the config of the dispatcher, reloads replace its
+// runtime settings between the batches of emails
+type liveConfig struct {
+	mu     sync.RWMutex
+	config appConfig
+}
+
+func (c *liveConfig) get() appConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config
+}
+
+// reload applies the runtime settings of next: the log, the batches,
+// the ip pools of the priorities and the retries of soft bounces
+func (c *liveConfig) reload(next appConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.config.Log = next.Log
+	c.config.PollInterval = next.PollInterval
+	c.config.BatchSize = next.BatchSize
+	c.config.MaxInFlight = next.MaxInFlight
+	c.config.IPPools = next.IPPools
+	c.config.MaxAttempts = next.MaxAttempts
+	c.config.RetryBaseDelay = next.RetryBaseDelay
+	c.config.RetryMaxDelay = next.RetryMaxDelay
+}
+
+// retryPolicy is the policy of the retries of soft bounced emails
+func (c appConfig) retryPolicy() pool.RetryPolicy {
+	return pool.RetryPolicy{
+		MaxAttempts: c.MaxAttempts,
+		BaseDelay:   c.RetryBaseDelay,
+		MaxDelay:    c.RetryMaxDelay,
+	}
+}
+
+// priorityIPPools are the ip pools of the emails of every priority
+type priorityIPPools struct {
+	Transactional string
+	Normal        string
+	Bulk          string
+}
+
+// ipPool returns the ip pool of an email, the pool of its
+// domain or the pool of its priority
+func (p priorityIPPools) ipPool(domainPool string, priority pool.Priority) string {
+	if domainPool != "" {
+		return domainPool
+	}
+	switch {
+	case priority >= pool.Transactional:
+		return p.Transactional
+	case priority <= pool.Bulk:
+		return p.Bulk
+	default:
+		return p.Normal
+	}
+}
+
+// Run runs the dispatcher daemon until ctx is canceled
+func Run(ctx context.Context) error {
+	_ = godotenv.Load()
+
+	var config appConfig
+	if err := configfile.Load("dispatcher", &config); err != nil {
+		return err
+	}
+	if err := logging.Setup(config.Log); err != nil {
+		return fmt.Errorf("invalid log config: %w", err)
+	}
+	if config.ClaimLease+config.PollInterval >= jetstream.DuplicateWindow {
+		return fmt.Errorf("invalid claim lease: %v with the poll interval %v must be shorter than the duplicate window %v", config.ClaimLease, config.PollInterval, jetstream.DuplicateWindow)
+	}
+	if config.TrackingURL != "" && config.TrackingSecret == "" {
+		return fmt.Errorf("invalid tracking config: a tracking secret is required with the tracking url %v", config.TrackingURL)
+	}
+	stopReports, err := errorreport.Start(config.Sentry, "kannon-dispatcher")
+	if err != nil {
+		return fmt.Errorf("invalid sentry config: %w", err)
+	}
+	defer stopReports()
+	defer errorreport.Recover()
+
+	stopTracing, err := tracing.Start(config.Tracing, "kannon-dispatcher")
+	if err != nil {
+		return fmt.Errorf("invalid tracing config: %w", err)
+	}
+	defer stopTracing()
+
+	db, err := sqlc.Conn()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	config.DB.Apply(db)
+
+	pm, err := pool.NewSendingPoolManager(db)
+	if err != nil {
+		return err
+	}
+
+	var tracker tracking.Tracker
+	if config.TrackingURL != "" {
+		tracker = tracking.NewTracker(config.TrackingURL, config.TrackingSecret)
+	}
+	mb := mailbuilder.NewMailBuilder(db, tracker)
+
+	var ar archive.Archive
+	if config.Archive.Enabled() {
+		if ar, err = archive.NewArchive(config.Archive); err != nil {
+			return fmt.Errorf("invalid archive config: %w", err)
+		}
+	}
+
+	sm, err := suppressions.NewSuppressionManager(db)
+	if err != nil {
+		return err
+	}
+
+	qm, err := quotas.NewQuotaManager(db)
+	if err != nil {
+		return err
+	}
+
+	b, err := queue.Open(config.Config, map[string]jetstream.ConsumerConfig{
+		"email-error":     config.ErrorConsumer,
+		"email-delivered": config.DeliveredConsumer,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot connect to %v: %w", config.Broker, err)
+	}
+	defer b.Close()
+
+	dm, err := deadletters.NewDeadLetterManager(db, b)
+	if err != nil {
+		return err
+	}
+
+	mm, err := maintenance.NewManager(db)
+	if err != nil {
+		return err
+	}
+	sw, err := maintenance.Watch(b)
+	if err != nil {
+		return err
+	}
+
+	metrics.Serve(config.MetricsPort, health.DB(db), queue.HealthCheck(b))
+	metrics.ServeDebug(config.DebugAddr)
+
+	// the log levels, the batches, the retries and the database pool change without a restart
+	live := &liveConfig{config: config}
+	configfile.Watch(func() {
+		var next appConfig
+		if err := configfile.Load("dispatcher", &next); err != nil {
+			log.Errorf("cannot reload config: %v", err)
+			return
+		}
+		if err := logging.Setup(next.Log); err != nil {
+			log.Errorf("cannot reload log config: %v", err)
+			return
+		}
+		next.DB.Apply(db)
+		live.reload(next)
+		log.Infof("config reloaded")
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(6)
+
+	go func() {
+		defer errorreport.Recover()
+		handleErrors(ctx, b, pm, sm, live)
+		wg.Done()
+	}()
+	go func() {
+		defer errorreport.Recover()
+		handleDelivereds(ctx, b, pm)
+		wg.Done()
+	}()
+	go func() {
+		defer errorreport.Recover()
+		handleDeferreds(ctx, b, pm)
+		wg.Done()
+	}()
+	go func() {
+		defer errorreport.Recover()
+		dispatcherLoop(ctx, pm, mb, ar, sm, qm, b, sw, live)
+		wg.Done()
+	}()
+	go func() {
+		defer errorreport.Recover()
+		maintenance.Sync(ctx, mm, sw, b, config.MaintenanceInterval)
+		wg.Done()
+	}()
+	go func() {
+		defer errorreport.Recover()
+		handleDeadLetters(ctx, b, dm)
+		wg.Done()
+	}()
+	if config.MetricsPort != 0 {
+		wg.Add(1)
+		go func() {
+			defer errorreport.Recover()
+			reportPoolMetrics(ctx, pm, config.MetricsInterval)
+			wg.Done()
+		}()
+	}
+	if config.Complaints {
+		wg.Add(1)
+		go func() {
+			defer errorreport.Recover()
+			handleComplaints(ctx, b, sqlc.New(metrics.InstrumentDB(db)), sm)
+			wg.Done()
+		}()
+	}
+	if tracker != nil {
+		wg.Add(2)
+		go func() {
+			defer errorreport.Recover()
+			handleOpens(ctx, b, sqlc.New(metrics.InstrumentDB(db)))
+			wg.Done()
+		}()
+		go func() {
+			defer errorreport.Recover()
+			handleUnsubscribes(ctx, b, sm)
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	log.Infof("dispatcher stopped")
+	return nil
+}
+
+// reportPoolMetrics updates the pending and in-flight
+// pool emails metrics every interval until ctx is done
+func reportPoolMetrics(ctx context.Context, pm pool.SendingPoolManager, interval time.Duration) {
+	for ctx.Err() == nil {
+		if pending, err := pm.CountPending(); err != nil {
+			log.Errorf("cannot count pending emails: %v", err)
+		} else {
+			metrics.PendingPoolEmails.Set(float64(pending))
+		}
+		if inFlight, err := pm.CountInFlight(); err != nil {
+			log.Errorf("cannot count in-flight emails: %v", err)
+		} else {
+			metrics.InFlightPoolEmails.Set(float64(inFlight))
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(interval):
+		}
+	}
+}
+
+func dispatcherLoop(ctx context.Context, pm pool.SendingPoolManager, mb mailbuilder.MailBulder, ar archive.Archive, sm suppressions.Manager, qm quotas.Manager, b queue.Broker, sw *maintenance.Switch, live *liveConfig) {
+	scheduled, err := pool.SubscribeScheduled(b)
+	if err != nil {
+		panic(err)
+	}
+	for ctx.Err() == nil {
+		// a reload applies to the next batch
+		config := live.get()
+		if sw.State().Halted {
+			select {
+			case <-ctx.Done():
+			case <-time.After(inFlightWait):
+			}
+			continue
+		}
+		// the emails of dispatchers stopped while dispatching them are sent again
+		if n, err := pm.ReclaimStale(ctx, time.Now().Add(-config.ClaimLease)); err != nil {
+			log.Errorf("cannot reclaim stale emails: %v", err)
+		} else if n > 0 {
+			log.Warnf("%v stale emails scheduled again", n)
+		}
+		max, err := batchSize(pm, config.BatchSize, config.MaxInFlight)
+		if err != nil {
+			log.Fatalf("cannot count in-flight emails: %v", err)
+		}
+		if max == 0 {
+			log.Debugf("max in-flight emails dispatched")
+			select {
+			case <-ctx.Done():
+			case <-time.After(inFlightWait):
+			}
+			continue
+		}
+		emails, err := pm.PrepareForSend(max)
+		if err != nil {
+			log.Fatalf("cannot prepare for send: %v", err)
+		}
+		log.Debugf("Fetched %v emails\n", len(emails))
+		for _, email := range emails {
+			emailCtx := tracing.ContextWithTraceParent(context.Background(), email.TraceParent)
+			emailCtx, span := tracing.StartSpan(emailCtx, "dispatch", tracing.Producer,
+				tracing.String("messaging.destination", "emails.sending"),
+				tracing.String("kannon.email", email.Email),
+				tracing.Int("kannon.trial", int64(email.Trial)),
+			)
+			result, err := dispatch(emailCtx, email, pm, mb, ar, sm, qm, b, config)
+			if err != nil {
+				log.WithField("email", email.Email).Errorf("Cannot dispatch email %v: %v", email.Email, err)
+				span.SetError(err)
+				// the email is not published, it is fetched again later
+				if err := pm.Release(emailCtx, email.ID, time.Now().Add(releaseDelay)); err != nil {
+					log.WithField("email", email.Email).Errorf("Cannot release email %v: %v", email.Email, err)
+				}
+			}
+			if result != "" {
+				metrics.DispatchedEmails.WithLabelValues(result).Inc()
+				span.SetAttributes(tracing.String("kannon.dispatch.result", result))
+			}
+			span.End()
+		}
+		log.Debugf("done sending emails")
+		if uint(len(emails)) == max {
+			// more emails are waiting
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case <-scheduled:
+		case <-time.After(config.PollInterval):
+		}
+	}
+}
+
+// dispatch publishes a pool email to the senders with the trace context of ctx,
+// unless its recipient is suppressed or the quota of its domain is exceeded,
+// the signed email is archived by ar when not nil.
+// It returns the result of the email: dispatched, suppressed, quota_exceeded
+// or failed, empty when the email cannot be checked
+func dispatch(ctx context.Context, email sqlc.SendingPoolEmail, pm pool.SendingPoolManager, mb mailbuilder.MailBulder, ar archive.Archive, sm suppressions.Manager, qm quotas.Manager, b queue.Broker, config appConfig) (string, error) {
+	log := log.WithField("email", email.Email)
+	suppressed, err := sm.IsRecipientSuppressed(email.MessageID, email.Email)
+	if err != nil {
+		return "", fmt.Errorf("cannot check suppression: %w", err)
+	}
+	if suppressed {
+		if err := pm.SetSuppressed(email.ID); err != nil {
+			log.Errorf("Cannot set %v as suppressed: %v", email.Email, err)
+		}
+		log.Infof("[🔇 suppressed]: %v", email.Email)
+		return "suppressed", nil
+	}
+	// retries and deferrals of emails are already counted
+	if !email.QuotaConsumed {
+		allowed, err := qm.Consume(email.ID)
+		if err != nil {
+			return "", fmt.Errorf("cannot check quota: %w", err)
+		}
+		if !allowed {
+			if err := pm.SetQuotaExceeded(email.ID); err != nil {
+				log.Errorf("Cannot set %v as quota exceeded: %v", email.Email, err)
+			}
+			log.Infof("[⛔ quota exceeded]: %v", email.Email)
+			return "quota_exceeded", nil
+		}
+		if err := pm.SetQuotaConsumed(email.ID); err != nil {
+			log.Errorf("Cannot set %v as quota consumed: %v", email.Email, err)
+		}
+	}
+	data, err := mb.PerpareForSend(email)
+	if err != nil {
+		return "failed", fmt.Errorf("cannot build email: %w", err)
+	}
+	data.IpPool = config.IPPools.ipPool(data.IpPool, pool.Priority(email.Priority))
+	if ar != nil {
+		// retries replace the email archived by the previous attempt, an archive
+		// down doesn't stop the sends
+		_, messageID, err := mailbuilder.ParseEmailMessageID(data.MessageId)
+		if err == nil {
+			err = ar.Put(ctx, messageID, email.Email, data.Body)
+		}
+		if err != nil {
+			log.Errorf("Cannot archive %v: %v", email.Email, err)
+		}
+	}
+	msg, err := proto.Marshal(&data)
+	if err != nil {
+		return "failed", fmt.Errorf("cannot marshal email: %w", err)
+	}
+	// a dispatcher publishing the email again
+	// can't cause a duplicate send
+	id := fmt.Sprintf("%v/%v/%v", data.MessageId, email.Email, email.Trial)
+	if err := queue.PublishOnceContext(ctx, b, "emails.sending", id, msg); err != nil {
+		return "failed", fmt.Errorf("cannot publish on nats: %w", err)
+	}
+	if err := pm.SetDispatched(ctx, email.ID, data.IpPool); err != nil {
+		log.Errorf("Cannot record dispatch of %v: %v", email.Email, err)
+	}
+	logging.Message(log, data.MessageId).Infof("[✅ accepted]: %v %v", data.To, data.MessageId)
+	return "dispatched", nil
+}
+
+// batchSize returns the number of emails to fetch,
+// 0 when maxInFlight emails are already dispatched
+func batchSize(pm pool.SendingPoolManager, size uint, maxInFlight uint) (uint, error) {
+	if maxInFlight == 0 {
+		return size, nil
+	}
+	inFlight, err := pm.CountInFlight()
+	if err != nil {
+		return 0, err
+	}
+	if inFlight >= maxInFlight {
+		return 0, nil
+	}
+	if maxInFlight-inFlight < size {
+		return maxInFlight - inFlight, nil
+	}
+	return size, nil
+}
+
+func handleErrors(ctx context.Context, b queue.Broker, pm pool.SendingPoolManager, sm suppressions.Manager, live *liveConfig) {
+	b.Consume(ctx, "email-error", func(msg queue.Message) {
+		errMsg := pb.Error{}
+		err := proto.Unmarshal(msg.Data(), &errMsg)
+		if err != nil {
+			log.Errorf("cannot marshal message %v", err.Error())
+			publishDeadLetter(b, deadletters.Unprocessable(msg, err))
+		} else {
+			log := logging.Message(log, errMsg.MessageId)
+			log.Printf("[🛑 bump] %v %v - %v", errMsg.Email, errMsg.MessageId, errMsg.Msg)
+			ctx, span := startEventSpan(msg, errMsg.MessageId, errMsg.Email)
+			if err := handleBounce(ctx, &errMsg, b, pm, sm, live.get().retryPolicy()); err != nil {
+				log.Errorf("cannot record bounce: %v", err)
+				span.SetError(err)
+			}
+			span.End()
+		}
+		if err := msg.Ack(); err != nil {
+			log.Errorf("Cannot hack msg to nats: %v\n", err)
+		}
+	})
+}
+
+func publishDeadLetter(p queue.Publisher, letter *pb.DeadLetter) {
+	if err := deadletters.Publish(p, letter); err != nil {
+		log.Errorf("cannot publish dead letter: %v", err)
+	}
+}
+
+// handleDeadLetters persists the dead letters of every service
+func handleDeadLetters(ctx context.Context, b queue.Broker, dm deadletters.Manager) {
+	b.Consume(ctx, "dead-letters", func(msg queue.Message) {
+		letter := pb.DeadLetter{}
+		err := proto.Unmarshal(msg.Data(), &letter)
+		if err != nil {
+			log.Errorf("cannot marshal message %v", err.Error())
+		} else {
+			log := logging.Message(log, letter.MessageId)
+			log.Printf("[💀 dead] %v %v %v - %v", letter.Subject, letter.Email, letter.MessageId, letter.Reason)
+			if _, err := dm.Create(&letter); err != nil {
+				log.Errorf("cannot record dead letter: %v", err)
+			}
+		}
+		if err := msg.Ack(); err != nil {
+			log.Errorf("Cannot hack msg to nats: %v\n", err)
+		}
+	})
+}
+
+// handleBounce records the bounce of a pool email, hard bounced recipients
+// are suppressed while soft bounced emails are scheduled again
+// until they reach the max attempts of retryPolicy
+func handleBounce(ctx context.Context, errMsg *pb.Error, p queue.Publisher, pm pool.SendingPoolManager, sm suppressions.Manager, retryPolicy pool.RetryPolicy) error {
+	to, messageID, err := mailbuilder.ParseEmailMessageID(errMsg.MessageId)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(to, errMsg.Email) {
+		// cc and bcc copies are not tracked in the pool
+		return nil
+	}
+
+	if !errMsg.IsPermanent {
+		retried, err := pm.SetSoftBounced(ctx, messageID, to, errMsg.Code, errMsg.Msg, retryPolicy)
+		if err != nil {
+			return err
+		}
+		if !retried {
+			logging.Message(log, messageID).Infof("[🛑 failed] %v %v - max attempts reached", to, messageID)
+			publishDeadLetter(p, deadletters.Exhausted(messageID, to, errMsg.Msg))
+		}
+		return nil
+	}
+	if err := pm.SetHardBounced(ctx, messageID, to, errMsg.Code, errMsg.Msg); err != nil {
+		return err
+	}
+	return sm.SuppressMessageRecipient(messageID, to, sqlc.SuppressionReasonBounced)
+}
+
+// handleDeferreds schedules again the emails postponed by the sender
+func handleDeferreds(ctx context.Context, b queue.Broker, pm pool.SendingPoolManager) {
+	b.Consume(ctx, "email-deferred", func(msg queue.Message) {
+		deferred := pb.Deferred{}
+		err := proto.Unmarshal(msg.Data(), &deferred)
+		if err != nil {
+			log.Errorf("cannot marshal message %v", err.Error())
+			publishDeadLetter(b, deadletters.Unprocessable(msg, err))
+		} else {
+			log := logging.Message(log, deferred.MessageId)
+			log.Printf("[⏳ deferred] %v %v - %v", deferred.Email, deferred.MessageId, deferred.Reason)
+			ctx, span := startEventSpan(msg, deferred.MessageId, deferred.Email)
+			if err := handleDeferred(ctx, &deferred, pm); err != nil {
+				log.Errorf("cannot record deferral: %v", err)
+				span.SetError(err)
+			}
+			span.End()
+		}
+		if err := msg.Ack(); err != nil {
+			log.Errorf("Cannot hack msg to nats: %v\n", err)
+		}
+	})
+}
+
+func handleDeferred(ctx context.Context, deferred *pb.Deferred, pm pool.SendingPoolManager) error {
+	to, messageID, err := mailbuilder.ParseEmailMessageID(deferred.MessageId)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(to, deferred.Email) {
+		return nil
+	}
+	return pm.SetDeferred(ctx, messageID, to, deferred.Reason, deferred.RetryAt.AsTime())
+}
+
+func handleDelivereds(ctx context.Context, b queue.Broker, pm pool.SendingPoolManager) {
+	b.Consume(ctx, "email-delivered", func(msg queue.Message) {
+		deliveredMsg := pb.Delivered{}
+		err := proto.Unmarshal(msg.Data(), &deliveredMsg)
+		if err != nil {
+			log.Errorf("cannot marshal message %v", err.Error())
+			publishDeadLetter(b, deadletters.Unprocessable(msg, err))
+		} else {
+			log := logging.Message(log, deliveredMsg.MessageId)
+			log.Printf("[🚀 delivered] %v %v", deliveredMsg.Email, deliveredMsg.MessageId)
+			ctx, span := startEventSpan(msg, deliveredMsg.MessageId, deliveredMsg.Email)
+			if err := handleDelivered(ctx, &deliveredMsg, pm); err != nil {
+				log.Errorf("cannot record delivery: %v", err)
+				span.SetError(err)
+			}
+			span.End()
+		}
+		if err := msg.Ack(); err != nil {
+			log.Errorf("Cannot hack msg to nats: %v\n", err)
+		}
+	})
+}
+
+func handleDelivered(ctx context.Context, deliveredMsg *pb.Delivered, pm pool.SendingPoolManager) error {
+	to, messageID, err := mailbuilder.ParseEmailMessageID(deliveredMsg.MessageId)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(to, deliveredMsg.Email) {
+		return nil
+	}
+	return pm.SetDelivered(ctx, messageID, to, deliveredMsg.Response)
+}
+
+// startEventSpan starts the span handling a delivery event of the sender,
+// child of the span of the delivery in the headers of msg
+func startEventSpan(msg queue.Message, messageID string, email string) (context.Context, *tracing.Span) {
+	return tracing.StartSpan(queue.MessageContext(context.Background(), msg), "handle "+msg.Subject(), tracing.Consumer,
+		tracing.String("messaging.destination", msg.Subject()),
+		tracing.String("kannon.message_id", messageID),
+		tracing.String("kannon.email", email),
+	)
+}
+
+func handleOpens(ctx context.Context, b queue.Broker, q *sqlc.Queries) {
+	b.Consume(ctx, "email-opened", func(msg queue.Message) {
+		openMsg := pb.Open{}
+		err := proto.Unmarshal(msg.Data(), &openMsg)
+		if err != nil {
+			log.Errorf("cannot marshal message %v", err.Error())
+			publishDeadLetter(b, deadletters.Unprocessable(msg, err))
+		} else {
+			log := logging.Message(log, openMsg.MessageId)
+			log.Printf("[👀 opened] %v %v", openMsg.Email, openMsg.MessageId)
+			err = q.CreateOpen(context.Background(), sqlc.CreateOpenParams{
+				MessageID: openMsg.MessageId,
+				Email:     openMsg.Email,
+				Ip:        openMsg.Ip,
+				UserAgent: openMsg.UserAgent,
+				Timestamp: openMsg.Timestamp.AsTime(),
+			})
+			if err != nil {
+				log.Errorf("cannot record open: %v", err)
+			}
+		}
+		if err := msg.Ack(); err != nil {
+			log.Errorf("Cannot hack msg to nats: %v\n", err)
+		}
+	})
+}
+
+func handleUnsubscribes(ctx context.Context, b queue.Broker, sm suppressions.Manager) {
+	b.Consume(ctx, "email-unsubscribed", func(msg queue.Message) {
+		unsubscribeMsg := pb.Unsubscribe{}
+		err := proto.Unmarshal(msg.Data(), &unsubscribeMsg)
+		if err != nil {
+			log.Errorf("cannot marshal message %v", err.Error())
+			publishDeadLetter(b, deadletters.Unprocessable(msg, err))
+		} else {
+			log := logging.Message(log, unsubscribeMsg.MessageId)
+			log.Printf("[✋ unsubscribed] %v %v", unsubscribeMsg.Email, unsubscribeMsg.MessageId)
+			err = sm.SuppressMessageRecipient(unsubscribeMsg.MessageId, unsubscribeMsg.Email, sqlc.SuppressionReasonUnsubscribed)
+			if err != nil {
+				log.Errorf("cannot record unsubscribe: %v", err)
+			}
+		}
+		if err := msg.Ack(); err != nil {
+			log.Errorf("Cannot hack msg to nats: %v\n", err)
+		}
+	})
+}
+
+func handleComplaints(ctx context.Context, b queue.Broker, q *sqlc.Queries, sm suppressions.Manager) {
+	b.Consume(ctx, "email-complained", func(msg queue.Message) {
+		complaintMsg := pb.Complaint{}
+		err := proto.Unmarshal(msg.Data(), &complaintMsg)
+		if err != nil {
+			log.Errorf("cannot marshal message %v", err.Error())
+			publishDeadLetter(b, deadletters.Unprocessable(msg, err))
+		} else {
+			log := logging.Message(log, complaintMsg.MessageId)
+			log.Printf("[😡 complained] %v %v", complaintMsg.Email, complaintMsg.MessageId)
+			err = q.CreateComplaint(context.Background(), sqlc.CreateComplaintParams{
+				MessageID:    complaintMsg.MessageId,
+				Email:        complaintMsg.Email,
+				FeedbackType: complaintMsg.FeedbackType,
+				Timestamp:    complaintMsg.Timestamp.AsTime(),
+			})
+			if err != nil {
+				log.Errorf("cannot record complaint: %v", err)
+			}
+			err = sm.SuppressMessageRecipient(complaintMsg.MessageId, complaintMsg.Email, sqlc.SuppressionReasonComplained)
+			if err != nil {
+				log.Errorf("cannot suppress %v: %v", complaintMsg.Email, err)
+			}
+		}
+		if err := msg.Ack(); err != nil {
+			log.Errorf("Cannot hack msg to nats: %v\n", err)
+		}
+	})
+}
@@ -0,0 +1,528 @@
+// Package sender is the sender daemon, it delivers the emails of the
+// sending pool to the MXs of the recipients
+package sender
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"kannon.gyozatech.dev/generated/pb"
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/configfile"
+	"kannon.gyozatech.dev/internal/deadletters"
+	"kannon.gyozatech.dev/internal/errorreport"
+	"kannon.gyozatech.dev/internal/health"
+	"kannon.gyozatech.dev/internal/jetstream"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/maintenance"
+	"kannon.gyozatech.dev/internal/metrics"
+	"kannon.gyozatech.dev/internal/queue"
+	"kannon.gyozatech.dev/internal/smtp"
+	"kannon.gyozatech.dev/internal/tlsconfig"
+	"kannon.gyozatech.dev/internal/tracing"
+	"kannon.gyozatech.dev/internal/warmup"
+)
+
+var log = logging.Logger("sender")
+
+// haltedRetry is the delay of the emails received while sending is halted, they are
+// dispatched again once resumed. It's longer than jetstream.DuplicateWindow so the
+// dispatch again isn't dropped as a duplicate
+const haltedRetry = jetstream.DuplicateWindow + time.Minute
+
+// Run runs the sender daemon with the flags of args until ctx is canceled
+func Run(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("sender", flag.ExitOnError)
+	senderHost := fs.String("sender-host", "sender.kannon.io", "Sender hostname for SMTP presentation")
+	natsURL := fs.String("nasts-url", "nats", "Nats url connection")
+	broker := fs.String("broker", "nats", "Message broker: nats, kafka or memory")
+	natsTLSCert := fs.String("nats-tls-cert", "", "Client certificate of the NATS connection")
+	natsTLSKey := fs.String("nats-tls-key", "", "Key of the client certificate of the NATS connection")
+	natsTLSCA := fs.String("nats-tls-ca", "", "CAs of the NATS server, the connection uses TLS when set")
+	natsTLSServerName := fs.String("nats-tls-server-name", "", "Name in the certificate of the NATS server, the host of the url when empty")
+	kafkaBrokers := fs.String("kafka-brokers", "", "Comma separated addresses of the kafka brokers, like kafka:9092")
+	workers := fs.Uint("workers", 100, "Number of workers sending emails in parallel")
+	maxSendingJobs := fs.Uint("max-sending-jobs", 0, "Deprecated: use -workers")
+	dialTimeout := fs.Duration("dial-timeout", smtp.DefaultTimeouts.Dial, "Max time to connect to a MX")
+	smtpTimeout := fs.Duration("smtp-timeout", smtp.DefaultTimeouts.Total, "Max time of a SMTP delivery after connecting")
+	idleTimeout := fs.Duration("mx-idle-timeout", 30*time.Second, "Max time a connection to a MX is kept open for the next deliveries, 0 disables reuse")
+	maxIdle := fs.Int("mx-max-idle", 5, "Max idle connections kept open to a MX")
+	ipPools := fs.String("ip-pools", "", "Source IPs by pool, like default=192.0.2.1|192.0.2.2,bulk=192.0.2.3")
+	warmupStart := fs.String("warmup", "", "First day of warm-up of new IPs, like 192.0.2.3=2021-07-01")
+	warmupSchedule := fs.String("warmup-schedule", scheduleString(smtp.DefaultWarmupSchedule), "Max daily deliveries to a provider of IPs in warm-up for every day of warm-up")
+	ipv6 := fs.Bool("ipv6", true, "Deliver to the IPv6 addresses of MXs first, falling back to IPv4")
+	requireTLS := fs.String("require-tls", "", "Comma separated recipient domains whose MXs must offer STARTTLS with a valid certificate")
+	mtaSTS := fs.Bool("mta-sts", false, "Enforce the MTA-STS policies of the recipient domains")
+	daneResolver := fs.String("dane-resolver", "", "DNSSEC validating resolver for the DANE TLSA records of MXs, like 127.0.0.1:53, empty disables DANE")
+	proxyURL := fs.String("proxy", "", "Proxy of the SMTP connections, like socks5://bastion:1080 or http://bastion:3128, with the public IP of the proxy as egress_ip, like ?egress_ip=203.0.113.5")
+	relayAddr := fs.String("relay", "", "SMTP server receiving every email instead of the MXs of the recipients, like MailHog on localhost:1025, empty disables the relay")
+	poolProxies := fs.String("pool-proxies", "", "Proxies of the SMTP connections by ip pool, like bulk=socks5://bastion:1080")
+	maintenanceInterval := fs.Duration("maintenance-interval", 10*time.Second, "Interval between the reads of the maintenance switch in the database, with brokers not publishing it")
+	drainTimeout := fs.Duration("drain-timeout", 2*time.Minute, "Max wait on shutdown for the emails being sent, 0 waits forever")
+	mxMaxConnections := fs.Int("mx-max-connections", 20, "Max concurrent deliveries to a receiving provider")
+	mxLimits := fs.String("mx-limits", "gmail=50,outlook=20,yahoo=10", "Max concurrent deliveries of providers, like gmail=50")
+	mxBackoff := fs.Duration("mx-backoff", time.Minute, "Pause of deliveries to a provider that throttled the sender")
+	mxMaxWait := fs.Duration("mx-max-wait", 30*time.Second, "Max wait for a free connection to a provider")
+	ackWait := fs.Duration("ack-wait", 5*time.Minute, "Time an email waits for its ack before being sent again, longer than a delivery")
+	maxDeliver := fs.Int("max-deliver", jetstream.DefaultConsumerConfig.MaxDeliver, "Max deliveries of an email to the sender, -1 is unlimited")
+	maxAckPending := fs.Uint("max-ack-pending", jetstream.DefaultConsumerConfig.MaxAckPending, "Max emails waiting for their ack, 0 is the server default")
+	deliverPolicy := fs.String("deliver-policy", jetstream.DefaultConsumerConfig.DeliverPolicy, "First email delivered to a new consumer: all, last or new")
+	metricsPort := fs.Uint("metrics-port", 9090, "Port of the metrics and health endpoints, 0 disables them")
+	debugAddr := fs.String("debug-addr", "", "Address of the pprof and runtime stats server, like localhost:6060, empty disables it")
+	healthSMTPAddr := fs.String("health-smtp-addr", "gmail-smtp-in.l.google.com:25", "MX whose port 25 must be reachable for the sender to be ready, empty disables the check")
+	diagnosticsMX := fs.String("diagnostics-mx", "gmail-smtp-in.l.google.com:25", "MX of the checks of the reverse DNS, the HELO hostname and port 25 on start, empty disables the checks")
+	logFormat := fs.String("log-format", "text", "Format of the log lines: text or json")
+	logLevel := fs.String("log-level", "info", "Level of the components without a level in -log-levels: debug, info, warn or error")
+	logLevels := fs.String("log-levels", "", "Levels of components, like sender=debug,smtp=warn")
+	sentryDSN := fs.String("sentry-dsn", "", "Sentry DSN of the project receiving the errors and panics, empty disables the reports")
+	sentryEnvironment := fs.String("sentry-environment", "", "Environment of the reported errors, like production")
+	tracingEndpoint := fs.String("tracing-endpoint", "", "OTLP HTTP endpoint of the collector of the spans, like http://tempo:4318, empty disables tracing")
+	tracingSampleRatio := fs.Float64("tracing-sample-ratio", 1, "Ratio of the traces started by the sender that are exported")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := configfile.LoadFlags("sender", fs); err != nil {
+		return fmt.Errorf("invalid config file: %w", err)
+	}
+	if err := logging.Setup(logging.Config{Format: *logFormat, Level: *logLevel, Levels: *logLevels}); err != nil {
+		return fmt.Errorf("invalid log config: %w", err)
+	}
+	stopReports, err := errorreport.Start(errorreport.Config{DSN: *sentryDSN, Environment: *sentryEnvironment}, "kannon-sender")
+	if err != nil {
+		return fmt.Errorf("invalid sentry config: %w", err)
+	}
+	defer stopReports()
+	defer errorreport.Recover()
+	if *maxSendingJobs != 0 {
+		log.Warnf("-max-sending-jobs is deprecated, use -workers")
+		*workers = *maxSendingJobs
+	}
+
+	stopTracing, err := tracing.Start(tracing.Config{
+		Endpoint:    *tracingEndpoint,
+		SampleRatio: *tracingSampleRatio,
+	}, "kannon-sender")
+	if err != nil {
+		return fmt.Errorf("invalid tracing config: %w", err)
+	}
+	defer stopTracing()
+
+	queueConfig := queue.Config{
+		Broker:   *broker,
+		NatsConn: *natsURL,
+		NatsTLS: tlsconfig.Config{
+			CertFile:   *natsTLSCert,
+			KeyFile:    *natsTLSKey,
+			CAFile:     *natsTLSCA,
+			ServerName: *natsTLSServerName,
+		},
+	}
+	if *kafkaBrokers != "" {
+		queueConfig.KafkaBrokers = strings.Split(*kafkaBrokers, ",")
+	}
+	b, err := queue.Open(queueConfig, map[string]jetstream.ConsumerConfig{
+		"sending-pool": {
+			AckWait:       *ackWait,
+			MaxDeliver:    *maxDeliver,
+			MaxAckPending: *maxAckPending,
+			DeliverPolicy: *deliverPolicy,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("cannot connect to %v: %w", *broker, err)
+	}
+	defer b.Close()
+
+	// the database of DATABASE_URL is opened only when needed: by the warm-up counts
+	// and, with brokers without subscriptions, by the maintenance switch
+	var db *sql.DB
+	openDB := func() (*sql.DB, error) {
+		if db != nil {
+			return db, nil
+		}
+		conn, err := sqlc.Conn()
+		if err != nil {
+			return nil, err
+		}
+		db = conn
+		return db, nil
+	}
+	defer func() {
+		if db != nil {
+			db.Close()
+		}
+	}()
+
+	throttleConfig := func() (smtp.ThrottleConfig, error) {
+		limits, err := smtp.ParseThrottleLimits(*mxLimits)
+		if err != nil {
+			return smtp.ThrottleConfig{}, err
+		}
+		return smtp.ThrottleConfig{
+			MaxConnections: *mxMaxConnections,
+			Limits:         limits,
+			Backoff:        *mxBackoff,
+			MaxWait:        *mxMaxWait,
+		}, nil
+	}
+	throttle, err := throttleConfig()
+	if err != nil {
+		return fmt.Errorf("cannot parse mx limits: %w", err)
+	}
+
+	pools, err := smtp.ParseIPPools(*ipPools)
+	if err != nil {
+		return fmt.Errorf("cannot parse ip pools: %w", err)
+	}
+
+	proxy, err := smtp.ParseProxy(*proxyURL)
+	if err != nil {
+		return fmt.Errorf("cannot parse proxy: %w", err)
+	}
+	proxies, err := smtp.ParsePoolProxies(*poolProxies)
+	if err != nil {
+		return fmt.Errorf("cannot parse pool proxies: %w", err)
+	}
+
+	relay, err := smtp.ParseRelay(*relayAddr)
+	if err != nil {
+		return fmt.Errorf("cannot parse relay: %w", err)
+	}
+
+	start, err := smtp.ParseWarmupStart(*warmupStart)
+	if err != nil {
+		return fmt.Errorf("cannot parse warm-up: %w", err)
+	}
+	schedule, err := smtp.ParseWarmupSchedule(*warmupSchedule)
+	if err != nil {
+		return fmt.Errorf("cannot parse warm-up schedule: %w", err)
+	}
+
+	smtpConfig := smtp.Config{
+		Throttle: throttle,
+		Timeouts: smtp.Timeouts{
+			Dial:  *dialTimeout,
+			Total: *smtpTimeout,
+		},
+		Pool: smtp.PoolConfig{
+			IdleTimeout: *idleTimeout,
+			MaxIdle:     *maxIdle,
+		},
+		IPPools: pools,
+		Warmup: smtp.WarmupConfig{
+			Start:    start,
+			Schedule: schedule,
+		},
+		Proxies: smtp.ProxyConfig{
+			URL:   proxy,
+			Pools: proxies,
+		},
+		IPv6: *ipv6,
+		TLS: smtp.TLSConfig{
+			RequireTLS:   smtp.ParseRequireTLS(*requireTLS),
+			MTASTS:       *mtaSTS,
+			DANEResolver: *daneResolver,
+		},
+		Relay: relay,
+	}
+	if len(start) > 0 {
+		// the warm-up limits are shared by every sender
+		db, err := openDB()
+		if err != nil {
+			return fmt.Errorf("cannot connect to the database of the warm-up counts: %w", err)
+		}
+		smtpConfig.Warmup.Counter = warmup.NewCounter(db)
+	}
+	sender := smtp.NewSender(*senderHost, smtpConfig)
+
+	checks := []health.Check{queue.HealthCheck(b)}
+	if *healthSMTPAddr != "" {
+		// outgoing connections to port 25 are blocked by many networks
+		checks = append(checks, health.Dial("smtp", *healthSMTPAddr, time.Minute))
+	}
+	metrics.Serve(uint16(*metricsPort), checks...)
+	metrics.ServeDebug(*debugAddr)
+	if *diagnosticsMX != "" {
+		go diagnose(*senderHost, smtpConfig, *diagnosticsMX)
+	}
+
+	// the log levels and the throttles of the providers change without a restart
+	configfile.Watch(func() {
+		if err := configfile.LoadFlags("sender", fs); err != nil {
+			log.Errorf("cannot reload config: %v", err)
+			return
+		}
+		if err := logging.Setup(logging.Config{Format: *logFormat, Level: *logLevel, Levels: *logLevels}); err != nil {
+			log.Errorf("cannot reload log config: %v", err)
+		}
+		throttle, err := throttleConfig()
+		if err != nil {
+			log.Errorf("cannot parse mx limits: %v", err)
+			return
+		}
+		sender.SetThrottle(throttle)
+		log.Infof("config reloaded")
+	})
+	sw, err := watchMaintenance(ctx, b, func() (maintenance.Manager, error) {
+		db, err := openDB()
+		if err != nil {
+			return nil, err
+		}
+		return maintenance.NewManager(db)
+	}, *maintenanceInterval)
+	if err != nil {
+		return fmt.Errorf("cannot watch the maintenance switch: %w", err)
+	}
+	handleSend(ctx, sender, b, sw, *workers, *drainTimeout)
+	log.Infof("sender stopped")
+	return nil
+}
+
+// watchMaintenance returns the maintenance switch published by the dispatchers. Brokers
+// without subscriptions don't publish it, the manager of newManager reads it every interval
+// until ctx is done
+func watchMaintenance(ctx context.Context, b queue.Broker, newManager func() (maintenance.Manager, error), interval time.Duration) (*maintenance.Switch, error) {
+	sw, err := maintenance.Watch(b)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := b.(queue.Subscriber); ok {
+		return sw, nil
+	}
+	mm, err := newManager()
+	if err != nil {
+		return nil, err
+	}
+	go maintenance.Sync(ctx, mm, sw, b, interval)
+	return sw, nil
+}
+
+// diagnose logs the checks of the setup of the sender and exposes them as metrics,
+// misconfigured hosts are caught before their emails bounce
+func diagnose(hostname string, config smtp.Config, mx string) {
+	failed := 0
+	for _, d := range smtp.Diagnose(hostname, config, mx) {
+		if d.Err != nil {
+			failed++
+			log.Errorf("[🩺 diagnostic] %v %v failed: %v", d.Check, d.Target, d.Err)
+			metrics.SenderDiagnostics.WithLabelValues(d.Check, d.Target).Set(0)
+		} else {
+			log.Infof("[🩺 diagnostic] %v %v passed", d.Check, d.Target)
+			metrics.SenderDiagnostics.WithLabelValues(d.Check, d.Target).Set(1)
+		}
+	}
+	if failed > 0 {
+		log.Warnf("%v diagnostics failed, deliveries may be rejected", failed)
+	}
+}
+
+// handleSend sends the emails of the sending pool with a pool of workers
+// until ctx is canceled, then waits up to drainTimeout for the emails being
+// sent, emails not acked are delivered again to the senders
+func handleSend(ctx context.Context, sender smtp.Sender, b queue.Broker, sw *maintenance.Switch, workers uint, drainTimeout time.Duration) {
+	log.Infof("🚀 Ready to send with %v workers!\n", workers)
+	jobs := make(chan queue.Message)
+	var wg sync.WaitGroup
+	for i := uint(0); i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer errorreport.Recover()
+			for msg := range jobs {
+				handleMessage(msg, sender, sw, b)
+				if err := msg.Ack(); err != nil {
+					log.Errorf("cannot hack message: %v\n", err.Error())
+				}
+			}
+		}()
+	}
+
+	// messages are fetched only when a worker is free
+	b.Consume(ctx, "sending-pool", func(msg queue.Message) {
+		jobs <- msg
+	})
+	close(jobs)
+
+	drained := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(drained)
+	}()
+	var timeout <-chan time.Time
+	if drainTimeout > 0 {
+		timeout = time.After(drainTimeout)
+	}
+	select {
+	case <-drained:
+	case <-timeout:
+		log.Warnf("emails still being sent after %v, stopping", drainTimeout)
+	}
+}
+
+// handleMessage sends an email of the sending pool, failures are logged with the message.
+// Emails received while sw is halted are deferred without trying them
+func handleMessage(msg queue.Message, sender smtp.Sender, sw *maintenance.Switch, p queue.Publisher) {
+	data := pb.EmailToSend{}
+	err := proto.Unmarshal(msg.Data(), &data)
+	if err != nil {
+		log.Errorf("error in handling message: %v\n", err.Error())
+		if err := deadletters.Publish(p, deadletters.Unprocessable(msg, err)); err != nil {
+			log.Errorf("cannot publish dead letter: %v", err)
+		}
+		return
+	}
+	log := logging.Message(log, data.MessageId)
+	// the email is sent in the trace of its dispatch
+	ctx, span := tracing.StartSpan(queue.MessageContext(context.Background(), msg), "send", tracing.Consumer,
+		tracing.String("messaging.destination", msg.Subject()),
+		tracing.String("kannon.message_id", data.MessageId),
+		tracing.String("kannon.email", data.To),
+	)
+	defer span.End()
+
+	// the same body is delivered to every envelope recipient,
+	// cc and bcc are not visible in the To header
+	recipients := append([]string{data.To}, data.Cc...)
+	recipients = append(recipients, data.Bcc...)
+
+	// bounces are sent to the return path, handled by the bouncer
+	from := data.ReturnPath
+	if from == "" {
+		from = data.From
+	}
+	if state := sw.State(); state.Halted {
+		log.Infof("[🛑 halted] %v - %v", data.To, data.MessageId)
+		retryAt := time.Now().Add(haltedRetry)
+		for _, rcpt := range recipients {
+			if err := publishDeferred(ctx, &data, rcpt, "sending halted: "+state.Reason, retryAt, p); err != nil {
+				span.SetError(err)
+				log.Errorf("error in handling message: %v\n", err.Error())
+				return
+			}
+		}
+		return
+	}
+
+	var sendErrs []smtp.SenderError
+	// sandbox deliveries have no provider and no response
+	results := make([]smtp.Result, len(recipients))
+	if data.Sandbox {
+		log.Infof("[🧪 sandbox] %v - %v", data.To, data.MessageId)
+		sendErrs = smtp.SandboxSend(data.MessageId, recipients, data.SandboxBouncePercent)
+	} else {
+		_, smtpSpan := tracing.StartSpan(ctx, "smtp", tracing.Client,
+			tracing.String("kannon.ip_pool", data.IpPool),
+			tracing.Int("kannon.recipients", int64(len(recipients))),
+		)
+		// recipients of the same domain share a single SMTP transaction
+		sendErrs, results = sender.SendBatch(data.IpPool, from, recipients, data.Body)
+		for _, sendErr := range sendErrs {
+			if sendErr != nil {
+				smtpSpan.SetAttributes(tracing.Int("kannon.smtp.code", int64(sendErr.Code())))
+				smtpSpan.SetError(sendErr)
+				break
+			}
+		}
+		smtpSpan.End()
+	}
+	for i, rcpt := range recipients {
+		if err := handleSendResult(ctx, log, sendErrs[i], &data, rcpt, results[i], p); err != nil {
+			span.SetError(err)
+			log.Errorf("error in handling message: %v\n", err.Error())
+			return
+		}
+	}
+}
+
+func handleSendResult(ctx context.Context, log *logrus.Entry, sendErr smtp.SenderError, data *pb.EmailToSend, rcpt string, result smtp.Result, p queue.Publisher) error {
+	if sendErr != nil && !sendErr.DeferredUntil().IsZero() {
+		log.Infof("Email deferred: %v - %v: %v", rcpt, data.MessageId, sendErr.Error())
+		return handleSendDeferred(ctx, sendErr, data, rcpt, p)
+	}
+	if sendErr != nil {
+		log.Infof("Cannot send email %v - %v: %v", rcpt, data.MessageId, sendErr.Error())
+		return handleSendError(ctx, sendErr, data, rcpt, result.Provider, p)
+	}
+	log.Infof("Email delivered: %v - %v", rcpt, data.MessageId)
+	return handleSendSuccess(ctx, data, rcpt, result, p)
+}
+
+func handleSendSuccess(ctx context.Context, data *pb.EmailToSend, rcpt string, result smtp.Result, p queue.Publisher) error {
+	msgProto := pb.Delivered{
+		MessageId: data.MessageId,
+		Email:     rcpt,
+		Timestamp: timestamppb.Now(),
+		Provider:  result.Provider,
+		Response:  result.Response,
+	}
+	msg, err := proto.Marshal(&msgProto)
+	if err != nil {
+		return err
+	}
+	err = queue.PublishContext(ctx, p, "emails.delivered", msg)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func handleSendDeferred(ctx context.Context, sendErr smtp.SenderError, data *pb.EmailToSend, rcpt string, p queue.Publisher) error {
+	return publishDeferred(ctx, data, rcpt, sendErr.Error(), sendErr.DeferredUntil(), p)
+}
+
+// publishDeferred publishes the email of rcpt postponed until retryAt for reason,
+// the dispatcher schedules it again
+func publishDeferred(ctx context.Context, data *pb.EmailToSend, rcpt string, reason string, retryAt time.Time, p queue.Publisher) error {
+	msg := pb.Deferred{
+		MessageId: data.MessageId,
+		Email:     rcpt,
+		Reason:    reason,
+		RetryAt:   timestamppb.New(retryAt),
+		Timestamp: timestamppb.Now(),
+	}
+	deferredMsg, err := proto.Marshal(&msg)
+	if err != nil {
+		return err
+	}
+	return queue.PublishContext(ctx, p, "emails.deferred", deferredMsg)
+}
+
+func handleSendError(ctx context.Context, sendErr smtp.SenderError, data *pb.EmailToSend, rcpt string, provider string, p queue.Publisher) error {
+	msg := pb.Error{
+		MessageId:   data.MessageId,
+		Code:        uint32(sendErr.Code()),
+		Msg:         sendErr.Error(),
+		Email:       rcpt,
+		IsPermanent: smtp.ClassifyBounce(sendErr.Code(), sendErr.Error(), sendErr.IsPermanent()) == smtp.HardBounce,
+		Timestamp:   timestamppb.Now(),
+		Reason:      sendErr.Reason(),
+		Provider:    provider,
+	}
+	errMsg, err := proto.Marshal(&msg)
+	if err != nil {
+		return err
+	}
+	err = queue.PublishContext(ctx, p, "emails.error", errMsg)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func scheduleString(schedule []int) string {
+	limits := make([]string, len(schedule))
+	for i, l := range schedule {
+		limits[i] = strconv.Itoa(l)
+	}
+	return strings.Join(limits, ",")
+}
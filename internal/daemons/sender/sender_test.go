@@ -0,0 +1,52 @@
+package sender
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"kannon.gyozatech.dev/internal/maintenance"
+	"kannon.gyozatech.dev/internal/queue"
+)
+
+// kafkaBroker is a broker without subscriptions, like Kafka
+type kafkaBroker struct {
+	queue.Broker
+}
+
+func (b kafkaBroker) Publish(subject string, data []byte) error {
+	return nil
+}
+
+// haltedManager is the maintenance switch of the database, halted
+type haltedManager struct {
+	maintenance.Manager
+}
+
+func (m haltedManager) Get() (maintenance.State, error) {
+	return maintenance.State{Halted: true, Reason: "incident", ChangedAt: time.Now()}, nil
+}
+
+func TestWatchMaintenance(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// without subscriptions the switch is read from the database
+	sw, err := watchMaintenance(ctx, kafkaBroker{}, func() (maintenance.Manager, error) {
+		return haltedManager{}, nil
+	}, time.Hour)
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool { return sw.State().Halted }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "incident", sw.State().Reason)
+
+	// the switch of brokers with subscriptions is published
+	b, err := queue.Open(queue.Config{Broker: "memory"}, nil)
+	assert.Nil(t, err)
+	sw, err = watchMaintenance(ctx, b, func() (maintenance.Manager, error) {
+		t.Fatal("the database is not read with subscriptions")
+		return nil, nil
+	}, time.Hour)
+	assert.Nil(t, err)
+	assert.False(t, sw.State().Halted)
+}
@@ -0,0 +1,179 @@
+// Package stats is the stats daemon, it counts the events of the emails,
+// stores the DMARC reports and fires the alerts
+package stats
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	_ "github.com/lib/pq"
+
+	"github.com/joho/godotenv"
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/alerts"
+	"kannon.gyozatech.dev/internal/configfile"
+	"kannon.gyozatech.dev/internal/dbconfig"
+	"kannon.gyozatech.dev/internal/dmarc"
+	"kannon.gyozatech.dev/internal/errorreport"
+	"kannon.gyozatech.dev/internal/events"
+	"kannon.gyozatech.dev/internal/health"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/metrics"
+	"kannon.gyozatech.dev/internal/queue"
+	"kannon.gyozatech.dev/internal/reputation"
+	"kannon.gyozatech.dev/internal/stats"
+)
+
+var log = logging.Logger("stats")
+
+type appConfig struct {
+	queue.Config
+	// Reputation pauses the domains with too many complaints
+	Reputation reputation.Config
+	// Alerts fires the alert rules on the rates of the domains and the age of the queue
+	Alerts alerts.Config
+	// MetricsPort is the port of the metrics and health endpoints, 0 disables them
+	MetricsPort uint16 `default:"9090"`
+	// DebugAddr is the address of the pprof and runtime stats server, like localhost:6060, empty disables it
+	DebugAddr string
+	// Log configures the log lines, like APP_LOG_FORMAT=json and APP_LOG_LEVELS=queue=debug
+	Log logging.Config
+	// DB configures the connection pool to the database, like APP_DB_MAXOPENCONNS
+	DB dbconfig.Config
+	// Sentry reports the errors and panics, like APP_SENTRY_DSN
+	Sentry errorreport.Config
+}
+
+// Run runs the stats daemon until ctx is canceled
+func Run(ctx context.Context) error {
+	_ = godotenv.Load()
+
+	var config appConfig
+	if err := configfile.Load("stats", &config); err != nil {
+		return err
+	}
+	if err := logging.Setup(config.Log); err != nil {
+		return fmt.Errorf("invalid log config: %w", err)
+	}
+	stopReports, err := errorreport.Start(config.Sentry, "kannon-stats")
+	if err != nil {
+		return fmt.Errorf("invalid sentry config: %w", err)
+	}
+	defer stopReports()
+	defer errorreport.Recover()
+
+	db, err := sqlc.Conn()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	config.DB.Apply(db)
+
+	sm, err := stats.NewStatsManager(db)
+	if err != nil {
+		return err
+	}
+
+	b, err := queue.Open(config.Config, nil)
+	if err != nil {
+		return fmt.Errorf("cannot connect to %v: %w", config.Broker, err)
+	}
+	defer b.Close()
+
+	dmm, err := dmarc.NewDMARCManager(db)
+	if err != nil {
+		return err
+	}
+
+	rm, err := reputation.NewReputationManager(db, config.Reputation)
+	if err != nil {
+		return err
+	}
+
+	evaluator, err := alerts.NewEvaluator(db, config.Alerts, b)
+	if err != nil {
+		return err
+	}
+
+	metrics.Serve(config.MetricsPort, health.DB(db), queue.HealthCheck(b))
+	metrics.ServeDebug(config.DebugAddr)
+
+	// the log levels and the database pool change without a restart
+	configfile.Watch(func() {
+		var next appConfig
+		if err := configfile.Load("stats", &next); err != nil {
+			log.Errorf("cannot reload config: %v", err)
+			return
+		}
+		if err := logging.Setup(next.Log); err != nil {
+			log.Errorf("cannot reload log config: %v", err)
+			return
+		}
+		next.DB.Apply(db)
+		log.Infof("config reloaded")
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer errorreport.Recover()
+		handleEvents(ctx, b, sm, rm)
+		wg.Done()
+	}()
+	go func() {
+		defer errorreport.Recover()
+		handleDMARCReports(ctx, b, dmm)
+		wg.Done()
+	}()
+	go func() {
+		defer errorreport.Recover()
+		evaluator.Run(ctx)
+		wg.Done()
+	}()
+	wg.Wait()
+	log.Infof("stats stopped")
+	return nil
+}
+
+// handleEvents counts the events published on emails subjects
+// in the hourly rollups and records them in the message history,
+// domains are paused when their complaints exceed the max rate
+func handleEvents(ctx context.Context, b queue.Consumer, sm stats.Manager, rm reputation.Manager) {
+	b.Consume(ctx, "stats", func(msg queue.Message) {
+		event, ok, err := events.Parse(msg.Subject(), msg.Data())
+		if err != nil {
+			log.Errorf("cannot parse event on %v: %v", msg.Subject(), err)
+		} else if ok {
+			if err := sm.Increment(event); err != nil {
+				log.Errorf("cannot increment stats: %v", err)
+			}
+			if err := sm.Record(event); err != nil {
+				log.Errorf("cannot record event: %v", err)
+			}
+			if event.Type == events.Complained {
+				if _, err := rm.CheckComplaints(event.Domain()); err != nil {
+					log.Errorf("cannot check complaints of %v: %v", event.Domain(), err)
+				}
+			}
+		}
+		if err := msg.Ack(); err != nil {
+			log.Errorf("Cannot hack msg to nats: %v\n", err)
+		}
+	})
+}
+
+// handleDMARCReports stores the aggregate reports received by the bouncer
+func handleDMARCReports(ctx context.Context, b queue.Consumer, dmm dmarc.Manager) {
+	b.Consume(ctx, "dmarc-reports", func(msg queue.Message) {
+		report, err := dmarc.Unmarshal(msg.Data())
+		if err != nil {
+			log.Errorf("cannot parse DMARC report: %v", err)
+		} else if err := dmm.Store(report); err != nil {
+			log.Errorf("cannot store DMARC report: %v", err)
+		}
+		if err := msg.Ack(); err != nil {
+			log.Errorf("Cannot hack msg to nats: %v\n", err)
+		}
+	})
+}
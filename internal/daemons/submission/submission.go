@@ -0,0 +1,153 @@
+// Package submission is the submission daemon, its SMTP server accepts the messages
+// of legacy apps authenticated with an API key and sends them like the Mailer API
+package submission
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/emersion/go-sasl"
+	"github.com/emersion/go-smtp"
+	"github.com/joho/godotenv"
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/configfile"
+	"kannon.gyozatech.dev/internal/daemons/api/mailapi"
+	"kannon.gyozatech.dev/internal/dbconfig"
+	"kannon.gyozatech.dev/internal/errorreport"
+	"kannon.gyozatech.dev/internal/health"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/metrics"
+	"kannon.gyozatech.dev/internal/queue"
+	"kannon.gyozatech.dev/internal/tlsconfig"
+)
+
+var log = logging.Logger("submission")
+
+type appConfig struct {
+	queue.Config
+	// MetricsPort is the port of the metrics and health endpoints, 0 disables them
+	MetricsPort uint16 `default:"9090"`
+	// DebugAddr is the address of the pprof and runtime stats server, like localhost:6060, empty disables it
+	DebugAddr string
+	// Log configures the log lines, like APP_LOG_FORMAT=json and APP_LOG_LEVELS=queue=debug
+	Log logging.Config
+	// DB configures the connection pool to the database, like APP_DB_MAXOPENCONNS
+	DB dbconfig.Config
+	// Sentry reports the errors and panics, like APP_SENTRY_DSN
+	Sentry         errorreport.Config
+	Addr           string `default:":587"`
+	Hostname       string `default:"localhost"`
+	MaxMessageSize int    `default:"10485760"`
+	MaxRecipients  int    `default:"100"`
+	// TLS is the certificate of STARTTLS, like APP_TLS_CERTFILE
+	TLS tlsconfig.Config
+	// AllowInsecureAuth accepts logins without STARTTLS, for the apps of a trusted network
+	AllowInsecureAuth bool
+	// RequireVerified rejects the messages of domains whose DNS records are not verified
+	RequireVerified bool
+}
+
+// Run runs the submission daemon until ctx is canceled
+func Run(ctx context.Context) error {
+	_ = godotenv.Load()
+
+	var config appConfig
+	if err := configfile.Load("submission", &config); err != nil {
+		return err
+	}
+	if err := logging.Setup(config.Log); err != nil {
+		return fmt.Errorf("invalid log config: %w", err)
+	}
+	stopReports, err := errorreport.Start(config.Sentry, "kannon-submission")
+	if err != nil {
+		return fmt.Errorf("invalid sentry config: %w", err)
+	}
+	defer stopReports()
+	defer errorreport.Recover()
+
+	if err := config.TLS.Validate(); err != nil {
+		return fmt.Errorf("invalid tls config: %w", err)
+	}
+	if config.TLS.CertFile == "" && !config.AllowInsecureAuth {
+		// the SMTP server refuses AUTH without TLS
+		log.Warnf("APP_TLS_CERTFILE not set, logins are rejected without APP_ALLOWINSECUREAUTH\n")
+	}
+
+	db, err := sqlc.Conn()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	config.DB.Apply(db)
+
+	b, err := queue.Open(config.Config, nil)
+	if err != nil {
+		return fmt.Errorf("cannot connect to %v: %w", config.Broker, err)
+	}
+	defer b.Close()
+
+	be, err := mailapi.NewSubmissionBackend(db, b, uint(config.MaxMessageSize), config.RequireVerified)
+	if err != nil {
+		return fmt.Errorf("cannot create submission backend: %w", err)
+	}
+
+	s := smtp.NewServer(be)
+	s.Addr = config.Addr
+	s.Domain = config.Hostname
+	s.MaxMessageBytes = config.MaxMessageSize
+	s.MaxRecipients = config.MaxRecipients
+	s.AllowInsecureAuth = config.AllowInsecureAuth
+	s.ReadTimeout = 60 * time.Second
+	s.WriteTimeout = 60 * time.Second
+	// many legacy apps support only AUTH LOGIN, the server supports AUTH PLAIN
+	s.EnableAuth(sasl.Login, func(conn *smtp.Conn) sasl.Server {
+		return sasl.NewLoginServer(func(username, password string) error {
+			state := conn.State()
+			session, err := be.Login(&state, username, password)
+			if err != nil {
+				return err
+			}
+			conn.SetSession(session)
+			return nil
+		})
+	})
+	if config.TLS.CertFile != "" {
+		// clients authenticate with their API key, not with a certificate
+		tlsConfig, err := tlsconfig.Config{CertFile: config.TLS.CertFile, KeyFile: config.TLS.KeyFile}.ServerConfig()
+		if err != nil {
+			return err
+		}
+		s.TLSConfig = tlsConfig
+	}
+
+	metrics.Serve(config.MetricsPort, health.DB(db), queue.HealthCheck(b))
+	metrics.ServeDebug(config.DebugAddr)
+
+	// the log levels and the database pool change without a restart
+	configfile.Watch(func() {
+		var next appConfig
+		if err := configfile.Load("submission", &next); err != nil {
+			log.Errorf("cannot reload config: %v", err)
+			return
+		}
+		if err := logging.Setup(next.Log); err != nil {
+			log.Errorf("cannot reload log config: %v", err)
+			return
+		}
+		next.DB.Apply(db)
+		log.Infof("config reloaded")
+	})
+
+	go func() {
+		<-ctx.Done()
+		s.Close()
+	}()
+
+	log.Infof("🚀 starting submission on %v\n", config.Addr)
+	if err := s.ListenAndServe(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("cannot start submission: %w", err)
+	}
+	log.Infof("submission stopped")
+	return nil
+}
@@ -0,0 +1,249 @@
+// Package tracker is the tracker daemon, it serves the open pixel, the tracked
+// links and the unsubscribe links of the emails and publishes their events
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"kannon.gyozatech.dev/generated/pb"
+	"kannon.gyozatech.dev/internal/configfile"
+	"kannon.gyozatech.dev/internal/errorreport"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/metrics"
+	"kannon.gyozatech.dev/internal/queue"
+	"kannon.gyozatech.dev/internal/tracking"
+)
+
+var log = logging.Logger("tracker")
+
+type appConfig struct {
+	queue.Config
+	// MetricsPort is the port of the metrics and health endpoints, 0 disables them
+	MetricsPort uint16 `default:"9090"`
+	// DebugAddr is the address of the pprof and runtime stats server, like localhost:6060, empty disables it
+	DebugAddr string
+	// Log configures the log lines, like APP_LOG_FORMAT=json and APP_LOG_LEVELS=queue=debug
+	Log logging.Config
+	// Sentry reports the errors and panics, like APP_SENTRY_DSN
+	Sentry         errorreport.Config
+	Port           uint   `default:"8080"`
+	TrackingSecret string `required:"true"`
+}
+
+// pixel is a transparent 1x1 gif
+var pixel = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// stopTimeout is the max wait on shutdown for the requests in progress
+const stopTimeout = 10 * time.Second
+
+// Run runs the tracker daemon until ctx is canceled
+func Run(ctx context.Context) error {
+	_ = godotenv.Load()
+
+	var config appConfig
+	if err := configfile.Load("tracker", &config); err != nil {
+		return err
+	}
+	if err := logging.Setup(config.Log); err != nil {
+		return fmt.Errorf("invalid log config: %w", err)
+	}
+	stopReports, err := errorreport.Start(config.Sentry, "kannon-tracker")
+	if err != nil {
+		return fmt.Errorf("invalid sentry config: %w", err)
+	}
+	defer stopReports()
+	defer errorreport.Recover()
+
+	b, err := queue.Open(config.Config, nil)
+	if err != nil {
+		return fmt.Errorf("cannot connect to %v: %w", config.Broker, err)
+	}
+	defer b.Close()
+
+	metrics.Serve(config.MetricsPort, queue.HealthCheck(b))
+	metrics.ServeDebug(config.DebugAddr)
+
+	// the log levels change without a restart
+	configfile.Watch(func() {
+		var next appConfig
+		if err := configfile.Load("tracker", &next); err != nil {
+			log.Errorf("cannot reload config: %v", err)
+			return
+		}
+		if err := logging.Setup(next.Log); err != nil {
+			log.Errorf("cannot reload log config: %v", err)
+			return
+		}
+		log.Infof("config reloaded")
+	})
+
+	tracker := tracking.NewTracker("", config.TrackingSecret)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/o/", handleOpen(tracker, b))
+	mux.HandleFunc("/c/", handleClick(tracker, b))
+	mux.HandleFunc("/u/", handleUnsubscribe(tracker, b))
+
+	srv := &http.Server{Addr: fmt.Sprintf(":%v", config.Port), Handler: mux}
+	go func() {
+		<-ctx.Done()
+		stopCtx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+		defer cancel()
+		if err := srv.Shutdown(stopCtx); err != nil {
+			srv.Close()
+		}
+	}()
+
+	log.Infof("🚀 starting tracker on port %v\n", config.Port)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("cannot start tracker: %w", err)
+	}
+	log.Infof("tracker stopped")
+	return nil
+}
+
+// handleOpen publishes an open for valid tokens, the pixel is
+// always served so invalid tokens are not disclosed
+func handleOpen(tracker tracking.Tracker, p queue.Publisher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/o/"), ".gif")
+		target, err := tracker.ParseToken(tracking.Open, token)
+		if err != nil {
+			log.Warnf("invalid open token %v: %v", token, err)
+		} else if err := publishOpen(p, target, r); err != nil {
+			log.Errorf("cannot publish open: %v", err)
+		}
+
+		w.Header().Set("Content-Type", "image/gif")
+		w.Header().Set("Cache-Control", "no-store, no-cache, must-revalidate, max-age=0")
+		w.Header().Set("Pragma", "no-cache")
+		if _, err := w.Write(pixel); err != nil {
+			log.Errorf("cannot write pixel: %v", err)
+		}
+	}
+}
+
+// handleClick publishes a click and redirects to the url of the link,
+// the url is signed in the token so the tracker is not an open redirect
+func handleClick(tracker tracking.Tracker, p queue.Publisher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.URL.Path, "/c/")
+		target, url, err := tracker.ParseClickToken(token)
+		if err != nil {
+			log.Warnf("invalid click token %v: %v", token, err)
+			http.Error(w, "invalid link", http.StatusNotFound)
+			return
+		}
+		if err := publishClick(p, target, url, r); err != nil {
+			log.Errorf("cannot publish click: %v", err)
+		}
+
+		w.Header().Set("Cache-Control", "no-store, no-cache, must-revalidate, max-age=0")
+		http.Redirect(w, r, url, http.StatusFound)
+	}
+}
+
+// handleUnsubscribe unsubscribes on POST, as done by one-click clients (RFC 8058),
+// GET shows a confirmation form so link scanners don't unsubscribe
+func handleUnsubscribe(tracker tracking.Tracker, p queue.Publisher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.URL.Path, "/u/")
+		target, err := tracker.ParseToken(tracking.Unsubscribe, token)
+		if err != nil {
+			log.Warnf("invalid unsubscribe token %v: %v", token, err)
+			http.Error(w, "invalid unsubscribe link", http.StatusNotFound)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprintf(w, unsubscribePage, html.EscapeString(target.Email))
+		case http.MethodPost:
+			if err := publishUnsubscribe(p, target); err != nil {
+				log.Errorf("cannot publish unsubscribe: %v", err)
+				http.Error(w, "cannot unsubscribe", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			fmt.Fprintf(w, "%v has been unsubscribed\n", target.Email)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+const unsubscribePage = `<!DOCTYPE html>
+<html><body>
+<form method="post">
+<p>Unsubscribe %v?</p>
+<button type="submit">Unsubscribe</button>
+</form>
+</body></html>
+`
+
+func publishUnsubscribe(p queue.Publisher, target tracking.Target) error {
+	msg, err := proto.Marshal(&pb.Unsubscribe{
+		MessageId: target.MessageID,
+		Email:     target.Email,
+		Timestamp: timestamppb.New(time.Now()),
+	})
+	if err != nil {
+		return err
+	}
+	return p.Publish("emails.unsubscribed", msg)
+}
+
+func publishOpen(p queue.Publisher, target tracking.Target, r *http.Request) error {
+	msg, err := proto.Marshal(&pb.Open{
+		MessageId: target.MessageID,
+		Email:     target.Email,
+		Ip:        remoteIP(r),
+		UserAgent: r.UserAgent(),
+		Timestamp: timestamppb.New(time.Now()),
+	})
+	if err != nil {
+		return err
+	}
+	return p.Publish("emails.opened", msg)
+}
+
+func publishClick(p queue.Publisher, target tracking.Target, url string, r *http.Request) error {
+	msg, err := proto.Marshal(&pb.Click{
+		MessageId: target.MessageID,
+		Email:     target.Email,
+		Url:       url,
+		Ip:        remoteIP(r),
+		UserAgent: r.UserAgent(),
+		Timestamp: timestamppb.New(time.Now()),
+	})
+	if err != nil {
+		return err
+	}
+	return p.Publish("emails.clicked", msg)
+}
+
+func remoteIP(r *http.Request) string {
+	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+		return strings.TrimSpace(strings.Split(fwd, ",")[0])
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
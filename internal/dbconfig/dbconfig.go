@@ -0,0 +1,34 @@
+package dbconfig
+
+import (
+	"database/sql"
+	"time"
+
+	"kannon.gyozatech.dev/internal/metrics"
+)
+
+// Config configures the connection pool to the database of a daemon,
+// like APP_DB_MAXOPENCONNS, the defaults are the ones of database/sql
+type Config struct {
+	// MaxOpenConns bounds the open connections, 0 is unlimited
+	MaxOpenConns int
+	// MaxIdleConns are the idle connections kept open, 0 keeps none
+	MaxIdleConns int `default:"2"`
+	// ConnMaxLifetime and ConnMaxIdleTime close the connections open
+	// or idle for longer, 0 never closes them
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// SlowQueryThreshold logs the queries slower than it with their name, 0 disables the logs
+	SlowQueryThreshold time.Duration `default:"1s"`
+}
+
+// Apply sets the pool of db and the slow query threshold, and exports the stats
+// of the pool. It can be called again with a reloaded config
+func (c Config) Apply(db *sql.DB) {
+	db.SetMaxOpenConns(c.MaxOpenConns)
+	db.SetMaxIdleConns(c.MaxIdleConns)
+	db.SetConnMaxLifetime(c.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(c.ConnMaxIdleTime)
+	metrics.SetSlowQueryThreshold(c.SlowQueryThreshold)
+	metrics.WatchDB(db)
+}
@@ -0,0 +1,21 @@
+package dbconfig
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApply(t *testing.T) {
+	// connections are opened by the first query
+	db, err := sql.Open("postgres", "postgresql://localhost/kannon")
+	assert.Nil(t, err)
+	defer db.Close()
+
+	Config{MaxOpenConns: 10, MaxIdleConns: 2}.Apply(db)
+	assert.Equal(t, 10, db.Stats().MaxOpenConnections)
+	Config{}.Apply(db)
+	assert.Equal(t, 0, db.Stats().MaxOpenConnections)
+}
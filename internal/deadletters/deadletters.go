@@ -0,0 +1,127 @@
+package deadletters
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"kannon.gyozatech.dev/generated/pb"
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/events"
+	"kannon.gyozatech.dev/internal/metrics"
+	"kannon.gyozatech.dev/internal/pool"
+	"kannon.gyozatech.dev/internal/queue"
+)
+
+// Subject is the subject dead letters are published on
+const Subject = "emails.dead"
+
+// Unprocessable builds the dead letter of a message
+// that cannot be unmarshalled
+func Unprocessable(msg queue.Message, reason error) *pb.DeadLetter {
+	return &pb.DeadLetter{
+		Subject:   msg.Subject(),
+		Payload:   msg.Data(),
+		Reason:    reason.Error(),
+		Timestamp: timestamppb.Now(),
+	}
+}
+
+// Exhausted builds the dead letter of the email of a message
+// recipient that reached the max send attempts
+func Exhausted(messageID string, email string, reason string) *pb.DeadLetter {
+	return &pb.DeadLetter{
+		Reason:    reason,
+		MessageId: messageID,
+		Email:     email,
+		Timestamp: timestamppb.Now(),
+	}
+}
+
+// Publish publishes a dead letter on Subject
+func Publish(p queue.Publisher, letter *pb.DeadLetter) error {
+	msg, err := proto.Marshal(letter)
+	if err != nil {
+		return err
+	}
+	return p.Publish(Subject, msg)
+}
+
+// Manager persists dead letters and requeues them
+type Manager interface {
+	Create(letter *pb.DeadLetter) (sqlc.DeadLetter, error)
+	GetDeadLetters(domain string, includeRequeued bool, max uint) ([]sqlc.DeadLetter, error)
+	Requeue(id int32) (sqlc.DeadLetter, error)
+}
+
+type manager struct {
+	db *sqlc.Queries
+	p  queue.Publisher
+}
+
+// NewDeadLetterManager builds a Dead Letter Manager,
+// requeued messages are published on p
+func NewDeadLetterManager(db *sql.DB, p queue.Publisher) (Manager, error) {
+	return &manager{
+		db: sqlc.New(metrics.InstrumentDB(db)),
+		p:  p,
+	}, nil
+}
+
+func (m *manager) Create(letter *pb.DeadLetter) (sqlc.DeadLetter, error) {
+	return m.db.CreateDeadLetter(context.TODO(), sqlc.CreateDeadLetterParams{
+		Domain:    events.Event{MessageID: letter.MessageId}.Domain(),
+		Subject:   letter.Subject,
+		MessageID: letter.MessageId,
+		Email:     letter.Email,
+		Payload:   letter.Payload,
+		Reason:    letter.Reason,
+		CreatedAt: letter.Timestamp.AsTime(),
+	})
+}
+
+// GetDeadLetters returns the dead letters of a domain, or of every
+// domain when domain is empty, most recent first
+func (m *manager) GetDeadLetters(domain string, includeRequeued bool, max uint) ([]sqlc.DeadLetter, error) {
+	return m.db.GetDeadLetters(context.TODO(), sqlc.GetDeadLettersParams{
+		Domain:          domain,
+		IncludeRequeued: includeRequeued,
+		Max:             int32(max),
+	})
+}
+
+// Requeue processes a dead letter again: the payload is published again
+// on its subject, exhausted emails are scheduled again with reset attempts.
+// The letter is claimed first, concurrent requeues of a letter process it once.
+// Returns sql.ErrNoRows when the letter does not exist or is already requeued
+func (m *manager) Requeue(id int32) (sqlc.DeadLetter, error) {
+	letter, err := m.db.ClaimDeadLetter(context.TODO(), id)
+	if err != nil {
+		return sqlc.DeadLetter{}, err
+	}
+	if err := m.requeue(letter); err != nil {
+		// the letter can be requeued again
+		if rerr := m.db.ReleaseDeadLetter(context.TODO(), id); rerr != nil {
+			return sqlc.DeadLetter{}, fmt.Errorf("%v, cannot release the letter: %w", err, rerr)
+		}
+		return sqlc.DeadLetter{}, err
+	}
+	return letter, nil
+}
+
+// requeue publishes the payload of a claimed letter or schedules its email again
+func (m *manager) requeue(letter sqlc.DeadLetter) error {
+	if letter.Subject != "" {
+		return m.p.Publish(letter.Subject, letter.Payload)
+	}
+	_, err := m.db.RequeueSendingPoolEmail(context.TODO(), sqlc.RequeueSendingPoolEmailParams{
+		MessageID: letter.MessageID,
+		Email:     letter.Email,
+	})
+	if err != nil {
+		return err
+	}
+	return pool.NotifyScheduled(m.p)
+}
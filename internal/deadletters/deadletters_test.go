@@ -0,0 +1,25 @@
+package deadletters
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type message struct {
+	subject string
+	data    []byte
+}
+
+func (m message) Subject() string { return m.subject }
+func (m message) Data() []byte    { return m.data }
+func (m message) Ack() error      { return nil }
+
+func TestUnprocessable(t *testing.T) {
+	letter := Unprocessable(message{"emails.error", []byte("invalid")}, errors.New("cannot unmarshal"))
+	assert.Equal(t, "emails.error", letter.Subject)
+	assert.Equal(t, []byte("invalid"), letter.Payload)
+	assert.Equal(t, "cannot unmarshal", letter.Reason)
+	assert.Equal(t, "", letter.MessageId)
+}
@@ -0,0 +1,317 @@
+package dkim
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ARC chain validation results of the previous sets of a message, RFC 8617
+const (
+	ChainNone = "none"
+	ChainPass = "pass"
+	ChainFail = "fail"
+)
+
+// ARC headers of a set
+const (
+	arcSealHeader    = "ARC-Seal"
+	arcMessageHeader = "ARC-Message-Signature"
+	arcResultsHeader = "ARC-Authentication-Results"
+)
+
+// maxARCInstances is the max number of ARC sets of a message
+const maxARCInstances = 50
+
+// defaultARCHeaders are signed by the ARC-Message-Signature
+// of seals without headers, when present
+var defaultARCHeaders = []string{"From", "To", "Cc", "Subject", "Date", "Message-ID", "Reply-To", "DKIM-Signature"}
+
+// ErrARCChainFailed is the error of messages whose ARC chain failed
+// validation by a previous sealer, they can't be sealed again
+var ErrARCChainFailed = errors.New("arc chain failed")
+
+// ARCSealData to seal a message forwarded on behalf of other systems,
+// the headers of SignData are signed by the ARC-Message-Signature
+type ARCSealData struct {
+	SignData
+	// AuthServID is the authserv-id of the ARC-Authentication-Results,
+	// usually the hostname of the forwarder
+	AuthServID string
+	// Results are the authentication results of the received message,
+	// like spf=pass smtp.mailfrom=example.com; dkim=pass header.d=example.com
+	Results string
+	// ChainValidation is the result of the validation of the ARC sets
+	// of the received message: none when it has none, pass or fail
+	ChainValidation string
+}
+
+// SealMessage adds an ARC set to msg: the authentication results, a
+// signature of the message and a seal of the ARC sets, RFC 8617
+func SealMessage(data ARCSealData, msg []byte) ([]byte, error) {
+	signer, err := decodeKey(data.Algorithm, data.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	algorithm := "rsa-sha256"
+	if data.Algorithm == AlgorithmEd25519 {
+		algorithm = "ed25519-sha256"
+	}
+
+	fields, body := splitMessage(msg)
+	sets, err := arcSets(fields)
+	if err != nil {
+		return nil, err
+	}
+	instance := len(sets) + 1
+	if instance > maxARCInstances {
+		return nil, fmt.Errorf("message has %v arc sets", len(sets))
+	}
+	switch {
+	case instance == 1 && data.ChainValidation != ChainNone:
+		return nil, fmt.Errorf("invalid chain validation of the first arc set: %v", data.ChainValidation)
+	case instance > 1 && data.ChainValidation != ChainPass && data.ChainValidation != ChainFail:
+		return nil, fmt.Errorf("invalid chain validation: %v", data.ChainValidation)
+	case instance > 1 && tagValue(sets[len(sets)-1][2], "cv") == ChainFail:
+		return nil, ErrARCChainFailed
+	}
+
+	results := data.Results
+	if results == "" {
+		results = "none"
+	}
+	aar := fmt.Sprintf("%v: i=%v; %v; %v\r\n", arcResultsHeader, instance, data.AuthServID, results)
+
+	headers := data.Headers
+	if len(headers) == 0 {
+		headers = defaultARCHeaders
+	}
+	headers = signedFields(fields, headers)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	bodyHash := sha256.Sum256(relaxedBody(body))
+	amsTags := fmt.Sprintf("%v: i=%v; a=%v; c=relaxed/relaxed; d=%v; s=%v; t=%v;\r\n h=%v;\r\n bh=%v;\r\n b=",
+		arcMessageHeader, instance, algorithm, data.Domain, data.Selector, now,
+		strings.Join(headers, ":"), base64.StdEncoding.EncodeToString(bodyHash[:]))
+	h := sha256.New()
+	for _, f := range selectFields(fields, headers) {
+		h.Write(relaxedHeader(f))
+	}
+	h.Write(bytes.TrimSuffix(relaxedHeader(amsTags), []byte("\r\n")))
+	amsSignature, err := signHash(signer, h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	ams := amsTags + amsSignature + "\r\n"
+
+	asTags := fmt.Sprintf("%v: i=%v; a=%v; t=%v; cv=%v; d=%v; s=%v;\r\n b=",
+		arcSealHeader, instance, algorithm, now, data.ChainValidation, data.Domain, data.Selector)
+	h = sha256.New()
+	for _, set := range append(sets, [3]string{aar, ams, ""}) {
+		h.Write(relaxedHeader(set[0]))
+		h.Write(relaxedHeader(set[1]))
+		if set[2] != "" {
+			h.Write(relaxedHeader(set[2]))
+		}
+	}
+	h.Write(bytes.TrimSuffix(relaxedHeader(asTags), []byte("\r\n")))
+	asSignature, err := signHash(signer, h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.WriteString(asTags + asSignature + "\r\n")
+	b.WriteString(ams)
+	b.WriteString(aar)
+	b.Write(msg)
+	return b.Bytes(), nil
+}
+
+// signHash signs a SHA-256 hash, Ed25519 keys sign the hash, RFC 8463
+func signHash(signer crypto.Signer, hashed []byte) (string, error) {
+	opts := crypto.SignerOpts(crypto.SHA256)
+	if _, ok := signer.(ed25519.PrivateKey); ok {
+		opts = crypto.Hash(0)
+	}
+	sig, err := signer.Sign(rand.Reader, hashed, opts)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// arcSets returns the AAR, AMS and AS headers of the ARC sets of a message
+// by instance, the sets of a valid chain have instances from 1 to n
+func arcSets(fields []string) ([][3]string, error) {
+	byInstance := make(map[int]*[3]string)
+	for _, f := range fields {
+		var pos int
+		switch strings.ToLower(fieldName(f)) {
+		case strings.ToLower(arcResultsHeader):
+			pos = 0
+		case strings.ToLower(arcMessageHeader):
+			pos = 1
+		case strings.ToLower(arcSealHeader):
+			pos = 2
+		default:
+			continue
+		}
+		i, err := strconv.Atoi(tagValue(f, "i"))
+		if err != nil || i < 1 || i > maxARCInstances {
+			return nil, fmt.Errorf("invalid arc instance: %v", tagValue(f, "i"))
+		}
+		set, ok := byInstance[i]
+		if !ok {
+			set = &[3]string{}
+			byInstance[i] = set
+		}
+		if set[pos] != "" {
+			return nil, fmt.Errorf("duplicated arc header of instance %v", i)
+		}
+		set[pos] = f
+	}
+
+	sets := make([][3]string, len(byInstance))
+	for i := range sets {
+		set, ok := byInstance[i+1]
+		if !ok || set[0] == "" || set[1] == "" || set[2] == "" {
+			return nil, fmt.Errorf("incomplete arc set %v", i+1)
+		}
+		sets[i] = *set
+	}
+	return sets, nil
+}
+
+// splitMessage splits msg in its header fields, with their folding and
+// CRLF, and its body
+func splitMessage(msg []byte) ([]string, []byte) {
+	var fields []string
+	rest := msg
+	for len(rest) > 0 {
+		end := bytes.Index(rest, []byte("\r\n"))
+		if end < 0 {
+			fields = appendLine(fields, string(rest))
+			return fields, nil
+		}
+		line := string(rest[:end+2])
+		rest = rest[end+2:]
+		if line == "\r\n" {
+			return fields, rest
+		}
+		fields = appendLine(fields, line)
+	}
+	return fields, nil
+}
+
+// appendLine appends a line to the fields, continuation lines are part
+// of the previous field
+func appendLine(fields []string, line string) []string {
+	if len(fields) > 0 && (line[0] == ' ' || line[0] == '\t') {
+		fields[len(fields)-1] += line
+		return fields
+	}
+	return append(fields, line)
+}
+
+func fieldName(field string) string {
+	i := strings.Index(field, ":")
+	if i < 0 {
+		return ""
+	}
+	return strings.TrimSpace(field[:i])
+}
+
+// tagValue returns the value of a tag of an ARC or DKIM header
+func tagValue(field, tag string) string {
+	i := strings.Index(field, ":")
+	return parseTags(field[i+1:])[tag]
+}
+
+// signedFields returns the names of headers present in fields,
+// ARC headers are never signed by the ARC-Message-Signature
+func signedFields(fields []string, headers []string) []string {
+	count := make(map[string]int)
+	for _, f := range fields {
+		count[strings.ToLower(fieldName(f))]++
+	}
+	var signed []string
+	for _, k := range headers {
+		key := strings.ToLower(k)
+		if strings.HasPrefix(key, "arc-") || count[key] == 0 {
+			continue
+		}
+		count[key]--
+		signed = append(signed, k)
+	}
+	return signed
+}
+
+// selectFields returns the fields of headers, like DKIM the
+// occurrences of a header are selected from the bottom
+func selectFields(fields []string, headers []string) []string {
+	used := make(map[string]int)
+	var selected []string
+	for _, k := range headers {
+		key := strings.ToLower(k)
+		n := 0
+		for i := len(fields) - 1; i >= 0; i-- {
+			if strings.ToLower(fieldName(fields[i])) != key {
+				continue
+			}
+			if n == used[key] {
+				selected = append(selected, fields[i])
+				break
+			}
+			n++
+		}
+		used[key]++
+	}
+	return selected
+}
+
+// relaxedHeader is the relaxed canonicalization of a header field, RFC 6376
+func relaxedHeader(field string) []byte {
+	i := strings.Index(field, ":")
+	name := strings.ToLower(strings.TrimSpace(field[:i]))
+	value := strings.NewReplacer("\r\n", "").Replace(field[i+1:])
+	value = strings.Join(strings.FieldsFunc(value, isWSP), " ")
+	return []byte(name + ":" + value + "\r\n")
+}
+
+// relaxedBody is the relaxed canonicalization of a body, RFC 6376
+func relaxedBody(body []byte) []byte {
+	lines := strings.Split(string(body), "\r\n")
+	var b strings.Builder
+	empty := 0
+	for i, line := range lines {
+		if i == len(lines)-1 && line == "" {
+			break
+		}
+		line = strings.TrimRight(wspRun.ReplaceAllString(line, " "), " ")
+		if line == "" {
+			empty++
+			continue
+		}
+		for ; empty > 0; empty-- {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(line + "\r\n")
+	}
+	return []byte(b.String())
+}
+
+// wspRun are the sequences of whitespace of relaxed canonicalization
+var wspRun = regexp.MustCompile(`[ \t]+`)
+
+func isWSP(r rune) bool {
+	return r == ' ' || r == '\t'
+}
@@ -0,0 +1,141 @@
+package dkim
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelaxedCanonicalization(t *testing.T) {
+	// RFC 6376 3.4.5
+	assert.Equal(t, "a:X\r\n", string(relaxedHeader("A: X\r\n")))
+	assert.Equal(t, "b:Y Z\r\n", string(relaxedHeader("B : Y\t\r\n\tZ  \r\n")))
+	assert.Equal(t, " C\r\nD E\r\n", string(relaxedBody([]byte(" C \r\nD \t E\r\n\r\n\r\n"))))
+	assert.Equal(t, "", string(relaxedBody(nil)))
+}
+
+func TestSealMessage(t *testing.T) {
+	rsaKeys, err := GenerateDKIMKeysPair()
+	assert.Nil(t, err)
+	edKeys, err := GenerateEd25519KeysPair()
+	assert.Nil(t, err)
+
+	first := ARCSealData{
+		SignData: SignData{
+			PrivateKey: rsaKeys.PrivateKey,
+			Domain:     "forwarder.com",
+			Selector:   "arc",
+		},
+		AuthServID:      "mx.forwarder.com",
+		Results:         "spf=pass smtp.mailfrom=example.com; dkim=pass header.d=example.com",
+		ChainValidation: ChainNone,
+	}
+	sealed, err := SealMessage(first, []byte(testMessage))
+	assert.Nil(t, err)
+	assert.True(t, bytes.HasSuffix(sealed, []byte(testMessage)))
+	assert.Contains(t, string(sealed), "ARC-Authentication-Results: i=1; mx.forwarder.com; spf=pass")
+	verifyARC(t, sealed, rsaKeys.PrivateKey, AlgorithmRSA)
+
+	second := ARCSealData{
+		SignData: SignData{
+			PrivateKey: edKeys.PrivateKey,
+			Domain:     "list.com",
+			Selector:   "arc",
+			Algorithm:  AlgorithmEd25519,
+			Headers:    []string{"From", "Subject"},
+		},
+		AuthServID:      "mx.list.com",
+		ChainValidation: ChainPass,
+	}
+	resealed, err := SealMessage(second, sealed)
+	assert.Nil(t, err)
+	assert.Contains(t, string(resealed), "ARC-Seal: i=2; a=ed25519-sha256;")
+	assert.Contains(t, string(resealed), "h=From:Subject;")
+	verifyARC(t, resealed, edKeys.PrivateKey, AlgorithmEd25519)
+
+	// the first set has no chain to validate
+	_, err = SealMessage(second, []byte(testMessage))
+	assert.NotNil(t, err)
+	_, err = SealMessage(first, sealed)
+	assert.NotNil(t, err)
+
+	second.ChainValidation = ChainFail
+	failed, err := SealMessage(second, sealed)
+	assert.Nil(t, err)
+	_, err = SealMessage(second, failed)
+	assert.True(t, errors.Is(err, ErrARCChainFailed), err)
+}
+
+func TestSealMessageIncompleteChain(t *testing.T) {
+	keys, err := GenerateDKIMKeysPair()
+	assert.Nil(t, err)
+
+	msg := "ARC-Seal: i=1; a=rsa-sha256; cv=none; d=example.com; s=arc; b=abc\r\n" + testMessage
+	_, err = SealMessage(ARCSealData{
+		SignData:        SignData{PrivateKey: keys.PrivateKey, Domain: "forwarder.com", Selector: "arc"},
+		ChainValidation: ChainPass,
+	}, []byte(msg))
+	assert.NotNil(t, err)
+}
+
+// signatureValue matches the value of the b= tag of a signature
+var signatureValue = regexp.MustCompile(`(^|[;\s])b=[^;]*`)
+
+// verifyARC verifies the signature and the seal of the last ARC set of msg
+func verifyARC(t *testing.T, msg []byte, privateKey string, algorithm string) {
+	t.Helper()
+	signer, err := decodeKey(algorithm, privateKey)
+	assert.Nil(t, err)
+
+	fields, body := splitMessage(msg)
+	sets, err := arcSets(fields)
+	assert.Nil(t, err)
+	last := sets[len(sets)-1]
+	unsigned := func(field string) []byte {
+		stripped := signatureValue.ReplaceAllString(field, "${1}b=")
+		return bytes.TrimSuffix(relaxedHeader(stripped), []byte("\r\n"))
+	}
+
+	bodyHash := sha256.Sum256(relaxedBody(body))
+	assert.Equal(t, base64.StdEncoding.EncodeToString(bodyHash[:]), tagValue(last[1], "bh"))
+	h := sha256.New()
+	for _, f := range selectFields(fields, strings.Split(tagValue(last[1], "h"), ":")) {
+		h.Write(relaxedHeader(f))
+	}
+	h.Write(unsigned(last[1]))
+	verifySignature(t, signer.Public(), h.Sum(nil), tagValue(last[1], "b"))
+
+	h = sha256.New()
+	for i, set := range sets {
+		h.Write(relaxedHeader(set[0]))
+		h.Write(relaxedHeader(set[1]))
+		if i < len(sets)-1 {
+			h.Write(relaxedHeader(set[2]))
+		}
+	}
+	h.Write(unsigned(last[2]))
+	verifySignature(t, signer.Public(), h.Sum(nil), tagValue(last[2], "b"))
+}
+
+func verifySignature(t *testing.T, key crypto.PublicKey, hashed []byte, signature string) {
+	t.Helper()
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	assert.Nil(t, err)
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		assert.Nil(t, rsa.VerifyPKCS1v15(k, crypto.SHA256, hashed, sig))
+	case ed25519.PublicKey:
+		assert.True(t, ed25519.Verify(k, hashed, sig))
+	default:
+		t.Fatalf("unexpected key %T", key)
+	}
+}
@@ -1,6 +1,7 @@
 package dkim
 
 import (
+	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -33,6 +34,24 @@ func GenerateDKIMKeysPair() (KeysPair, error) {
 	}, nil
 }
 
+// GenerateEd25519KeysPair generates Ed25519 DKIM private and public keys pair,
+// the public key is the raw key of the p= tag of the DNS record, RFC 8463
+func GenerateEd25519KeysPair() (KeysPair, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return KeysPair{}, err
+	}
+
+	privkeyBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return KeysPair{}, err
+	}
+	return KeysPair{
+		PrivateKey: base64.StdEncoding.EncodeToString(privkeyBytes),
+		PublicKey:  base64.StdEncoding.EncodeToString(publicKey),
+	}, nil
+}
+
 func exportRsaPrivateKeyAsStr(privkey *rsa.PrivateKey) string {
 	privkeyBytes := x509.MarshalPKCS1PrivateKey(privkey)
 	return base64.StdEncoding.EncodeToString(privkeyBytes)
@@ -19,3 +19,19 @@ func TestDKIMKeyGeneration(t *testing.T) {
 	t.Logf("Generated Private Key: \n%v\n\n", dkimKeys.PrivateKey)
 	t.Logf("Generated Public Key: \n%v\n\n", dkimKeys.PublicKey)
 }
+
+func TestEd25519KeyGeneration(t *testing.T) {
+	dkimKeys, err := GenerateEd25519KeysPair()
+	if err != nil {
+		t.Errorf("Cannot generate key, %v", err)
+	}
+
+	if _, err := decodeKey(AlgorithmEd25519, dkimKeys.PrivateKey); err != nil {
+		t.Errorf("private key is not valid: %v", err)
+	}
+
+	// the public key is the base64 encoded raw key, 44 characters of 32 bytes
+	if len(dkimKeys.PublicKey) != 44 {
+		t.Errorf("public key is not valid: %v", dkimKeys.PublicKey)
+	}
+}
@@ -0,0 +1,109 @@
+package dkim
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// ErrRecordNotPublished is the error of keys whose DNS record is missing
+// or doesn't match the key
+var ErrRecordNotPublished = errors.New("dkim record not published")
+
+// ErrSelectorInUse is the error of custom selectors of a domain
+// already used by one of its keys
+var ErrSelectorInUse = errors.New("dkim selector already in use")
+
+// lookupTXT resolves the DNS records of the keys
+var lookupTXT = net.LookupTXT
+
+// GenerateKeysPair generates the keys pair of algorithm
+func GenerateKeysPair(algorithm string) (KeysPair, error) {
+	switch algorithm {
+	case AlgorithmRSA:
+		return GenerateDKIMKeysPair()
+	case AlgorithmEd25519:
+		return GenerateEd25519KeysPair()
+	default:
+		return KeysPair{}, fmt.Errorf("unsupported dkim algorithm: %v", algorithm)
+	}
+}
+
+// NewSelector returns the selector of a key of algorithm generated at t,
+// like kannon-20210707094518 or kannon-ed25519-20210707094518
+func NewSelector(algorithm string, t time.Time) string {
+	prefix := RSASelector
+	if algorithm == AlgorithmEd25519 {
+		prefix = Ed25519Selector
+	}
+	return prefix + "-" + t.UTC().Format("20060102150405")
+}
+
+// maxSelectorLen is the size of the selector columns
+const maxSelectorLen = 63
+
+// ValidSelector checks that a custom selector is a sequence of DNS labels,
+// like marketing or 2021.marketing
+func ValidSelector(selector string) bool {
+	if selector == "" || len(selector) > maxSelectorLen {
+		return false
+	}
+	for _, label := range strings.Split(selector, ".") {
+		if label == "" || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// VerifyRecord checks that the DNS record of selector on domain
+// publishes publicKey of algorithm
+func VerifyRecord(domain, selector, algorithm, publicKey string) error {
+	name := selector + "._domainkey." + domain
+	txts, err := lookupTXT(name)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRecordNotPublished, err)
+	}
+	for _, txt := range txts {
+		if MatchRecord(txt, algorithm, publicKey) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %v doesn't have the %v key", ErrRecordNotPublished, name, algorithm)
+}
+
+// MatchRecord reports if txt is a DKIM record of publicKey of algorithm
+func MatchRecord(txt, algorithm, publicKey string) bool {
+	tags := parseTags(txt)
+	k := tags["k"]
+	if k == "" {
+		k = AlgorithmRSA
+	}
+	return k == algorithm && tags["p"] == publicKey
+}
+
+// Record is the value of the DNS TXT record of publicKey of algorithm
+func Record(algorithm, publicKey string) string {
+	return "v=DKIM1; k=" + algorithm + "; p=" + publicKey
+}
+
+// parseTags parses the tags of a record like v=DKIM1; k=rsa; p=...,
+// whitespace in values is ignored
+func parseTags(txt string) map[string]string {
+	tags := make(map[string]string)
+	for _, t := range strings.Split(txt, ";") {
+		parts := strings.SplitN(t, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		tags[strings.TrimSpace(parts[0])] = strings.Join(strings.Fields(parts[1]), "")
+	}
+	return tags
+}
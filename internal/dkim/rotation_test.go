@@ -0,0 +1,59 @@
+package dkim
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSelector(t *testing.T) {
+	at := time.Date(2021, 7, 7, 9, 45, 18, 0, time.UTC)
+	assert.Equal(t, "kannon-20210707094518", NewSelector(AlgorithmRSA, at))
+	assert.Equal(t, "kannon-ed25519-20210707094518", NewSelector(AlgorithmEd25519, at))
+}
+
+func TestValidSelector(t *testing.T) {
+	for _, s := range []string{"kannon", "marketing-2021", "2021.marketing"} {
+		assert.True(t, ValidSelector(s), s)
+	}
+	for _, s := range []string{"", "-kannon", "kannon-", "a..b", "kannon_1", strings.Repeat("a", 64)} {
+		assert.False(t, ValidSelector(s), s)
+	}
+}
+
+func TestVerifyRecord(t *testing.T) {
+	records := map[string][]string{
+		"kannon-1._domainkey.example.com": {"v=DKIM1; p=AAAA BBBB"},
+		"kannon-2._domainkey.example.com": {"v=spf1 -all", "v=DKIM1; k=ed25519; p=CCCC"},
+	}
+	lookup := lookupTXT
+	defer func() { lookupTXT = lookup }()
+	lookupTXT = func(name string) ([]string, error) {
+		if r, ok := records[name]; ok {
+			return r, nil
+		}
+		return nil, fmt.Errorf("no such host %v", name)
+	}
+
+	assert.Nil(t, VerifyRecord("example.com", "kannon-1", AlgorithmRSA, "AAAABBBB"))
+	assert.Nil(t, VerifyRecord("example.com", "kannon-2", AlgorithmEd25519, "CCCC"))
+
+	for _, err := range []error{
+		VerifyRecord("example.com", "kannon-1", AlgorithmRSA, "DDDD"),
+		VerifyRecord("example.com", "kannon-2", AlgorithmRSA, "CCCC"),
+		VerifyRecord("example.com", "kannon-3", AlgorithmRSA, "AAAABBBB"),
+	} {
+		assert.True(t, errors.Is(err, ErrRecordNotPublished), err)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	txt := Record(AlgorithmEd25519, "CCCC")
+	assert.Equal(t, "v=DKIM1; k=ed25519; p=CCCC", txt)
+	assert.True(t, MatchRecord(txt, AlgorithmEd25519, "CCCC"))
+	assert.False(t, MatchRecord(txt, AlgorithmRSA, "CCCC"))
+}
@@ -2,24 +2,69 @@ package dkim
 
 import (
 	"bytes"
-	"crypto/rsa"
+	"crypto"
+	"crypto/ed25519"
 	"crypto/x509"
 	"encoding/base64"
+	"errors"
+	"fmt"
 
 	"github.com/emersion/go-msgauth/dkim"
 )
 
+// Signing modes of a domain, dual signs with both keys
+const (
+	SigningRSA     = "rsa"
+	SigningEd25519 = "ed25519"
+	SigningDual    = "dual"
+)
+
+// Default selectors of the DNS records of the keys of a domain
+const (
+	RSASelector     = "kannon"
+	Ed25519Selector = "kannon-ed25519"
+)
+
+// Key algorithms
+const (
+	AlgorithmRSA     = "rsa"
+	AlgorithmEd25519 = "ed25519"
+)
+
+// ValidSigning reports if signing is a signing mode
+func ValidSigning(signing string) bool {
+	switch signing {
+	case SigningRSA, SigningEd25519, SigningDual:
+		return true
+	}
+	return false
+}
+
 // SignData to pass to dkim
 type SignData struct {
 	PrivateKey string
 	Domain     string
 	Selector   string
 	Headers    []string
+	// Algorithm of PrivateKey, empty is AlgorithmRSA
+	Algorithm string
+}
+
+// DomainKeys are the private keys of a domain
+// and the signing mode that selects them
+type DomainKeys struct {
+	Signing           string
+	RSAPrivateKey     string
+	Ed25519PrivateKey string
+	// RSASelector and Ed25519Selector are the selectors of the
+	// keys, empty are the default selectors
+	RSASelector     string
+	Ed25519Selector string
 }
 
 // SignMessage signes an email message with DKIM
 func SignMessage(data SignData, reader *bytes.Reader) ([]byte, error) {
-	signer, err := decodeKey(data.PrivateKey)
+	signer, err := decodeKey(data.Algorithm, data.PrivateKey)
 	if err != nil {
 		return nil, err
 	}
@@ -37,10 +82,67 @@ func SignMessage(data SignData, reader *bytes.Reader) ([]byte, error) {
 	return b.Bytes(), nil
 }
 
-func decodeKey(dkimPrivateKey string) (*rsa.PrivateKey, error) {
+// SignDomainMessage signs msg with the keys of the signing mode of domain,
+// dual signed messages have an Ed25519 and a RSA signature, RFC 8463
+func SignDomainMessage(keys DomainKeys, domain string, headers []string, msg []byte) ([]byte, error) {
+	var signatures []SignData
+	switch keys.Signing {
+	case SigningRSA, "":
+	case SigningEd25519, SigningDual:
+		signatures = append(signatures, SignData{
+			PrivateKey: keys.Ed25519PrivateKey,
+			Selector:   orDefault(keys.Ed25519Selector, Ed25519Selector),
+			Algorithm:  AlgorithmEd25519,
+		})
+	default:
+		return nil, fmt.Errorf("invalid dkim signing: %v", keys.Signing)
+	}
+	if keys.Signing != SigningEd25519 {
+		signatures = append(signatures, SignData{
+			PrivateKey: keys.RSAPrivateKey,
+			Selector:   orDefault(keys.RSASelector, RSASelector),
+			Algorithm:  AlgorithmRSA,
+		})
+	}
+
+	for _, data := range signatures {
+		data.Domain = domain
+		data.Headers = headers
+		signed, err := SignMessage(data, bytes.NewReader(msg))
+		if err != nil {
+			return nil, err
+		}
+		msg = signed
+	}
+	return msg, nil
+}
+
+func orDefault(selector string, def string) string {
+	if selector == "" {
+		return def
+	}
+	return selector
+}
+
+func decodeKey(algorithm string, dkimPrivateKey string) (crypto.Signer, error) {
 	dkimPrivateKeyInBytes, err := base64.StdEncoding.DecodeString(dkimPrivateKey)
 	if err != nil {
 		return nil, err
 	}
-	return x509.ParsePKCS1PrivateKey(dkimPrivateKeyInBytes)
+	switch algorithm {
+	case AlgorithmRSA, "":
+		return x509.ParsePKCS1PrivateKey(dkimPrivateKeyInBytes)
+	case AlgorithmEd25519:
+		key, err := x509.ParsePKCS8PrivateKey(dkimPrivateKeyInBytes)
+		if err != nil {
+			return nil, err
+		}
+		edKey, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New("not an ed25519 private key")
+		}
+		return edKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported dkim algorithm: %v", algorithm)
+	}
 }
@@ -0,0 +1,84 @@
+package dkim
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/emersion/go-msgauth/dkim"
+	"github.com/stretchr/testify/assert"
+)
+
+const testMessage = "From: Test <test@example.com>\r\n" +
+	"To: rcpt@example.org\r\n" +
+	"Subject: test\r\n" +
+	"\r\n" +
+	"Hello\r\n"
+
+func TestSignDomainMessage(t *testing.T) {
+	rsaKeys, err := GenerateDKIMKeysPair()
+	assert.Nil(t, err)
+	edKeys, err := GenerateEd25519KeysPair()
+	assert.Nil(t, err)
+	records := map[string]string{
+		RSASelector + "._domainkey.example.com":     "v=DKIM1; k=rsa; p=" + rsaKeys.PublicKey,
+		Ed25519Selector + "._domainkey.example.com": "v=DKIM1; k=ed25519; p=" + edKeys.PublicKey,
+	}
+	lookup := func(domain string) ([]string, error) {
+		if r, ok := records[domain]; ok {
+			return []string{r}, nil
+		}
+		return nil, fmt.Errorf("no record for %v", domain)
+	}
+
+	tests := map[string][]string{
+		SigningRSA:     {RSASelector},
+		SigningEd25519: {Ed25519Selector},
+		SigningDual:    {RSASelector, Ed25519Selector},
+	}
+	for signing, selectors := range tests {
+		keys := DomainKeys{
+			Signing:           signing,
+			RSAPrivateKey:     rsaKeys.PrivateKey,
+			Ed25519PrivateKey: edKeys.PrivateKey,
+		}
+		signed, err := SignDomainMessage(keys, "example.com", []string{"From", "To", "Subject"}, []byte(testMessage))
+		assert.Nil(t, err)
+
+		verifications, err := dkim.VerifyWithOptions(bytes.NewReader(signed), &dkim.VerifyOptions{LookupTXT: lookup})
+		assert.Nil(t, err)
+		assert.Len(t, verifications, len(selectors), signing)
+		for _, v := range verifications {
+			assert.Nil(t, v.Err, signing)
+			assert.Equal(t, "example.com", v.Domain)
+		}
+	}
+
+	_, err = SignDomainMessage(DomainKeys{Signing: "dsa"}, "example.com", nil, []byte(testMessage))
+	assert.NotNil(t, err)
+}
+
+func TestSignDomainMessageOversigned(t *testing.T) {
+	keys, err := GenerateDKIMKeysPair()
+	assert.Nil(t, err)
+	lookup := func(domain string) ([]string, error) {
+		return []string{"v=DKIM1; k=rsa; p=" + keys.PublicKey}, nil
+	}
+	msg := "List-Unsubscribe: <https://example.com/u>\r\n" + testMessage
+
+	signed, err := SignDomainMessage(DomainKeys{RSAPrivateKey: keys.PrivateKey}, "example.com",
+		[]string{"From", "List-Unsubscribe", "List-Unsubscribe"}, []byte(msg))
+	assert.Nil(t, err)
+
+	verifications, err := dkim.VerifyWithOptions(bytes.NewReader(signed), &dkim.VerifyOptions{LookupTXT: lookup})
+	assert.Nil(t, err)
+	assert.Len(t, verifications, 1)
+	assert.Nil(t, verifications[0].Err)
+
+	// a List-Unsubscribe added after signing breaks the signature
+	tampered := append([]byte("List-Unsubscribe: <https://evil.com/u>\r\n"), signed...)
+	verifications, err = dkim.VerifyWithOptions(bytes.NewReader(tampered), &dkim.VerifyOptions{LookupTXT: lookup})
+	assert.Nil(t, err)
+	assert.Len(t, verifications, 1)
+	assert.NotNil(t, verifications[0].Err)
+}
@@ -0,0 +1,97 @@
+package dmarc
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/metrics"
+)
+
+// Stats are the messages of a source ip in the reports of a sending domain
+type Stats struct {
+	SourceIP string
+	Messages int64
+	// Passed are the messages with DKIM or SPF aligned
+	Passed      int64
+	DKIMAligned int64
+	SPFAligned  int64
+	Quarantined int64
+	Rejected    int64
+}
+
+// Manager stores the aggregate reports of the sending domains
+type Manager interface {
+	// Store stores a report, reports already stored are ignored
+	Store(report Report) error
+	// GetStats returns the stats by source ip of the messages sent from
+	// domain in the reports beginning between from and to
+	GetStats(domain string, from time.Time, to time.Time) ([]Stats, error)
+}
+
+type manager struct {
+	db *sqlc.Queries
+}
+
+// NewDMARCManager builds a DMARC Manager
+func NewDMARCManager(db *sql.DB) (Manager, error) {
+	return &manager{
+		db: sqlc.New(metrics.InstrumentDB(db)),
+	}, nil
+}
+
+func (m *manager) Store(report Report) error {
+	reports, err := m.db.CreateDMARCReport(context.TODO(), sqlc.CreateDMARCReportParams{
+		OrgName:  report.OrgName,
+		ReportID: report.ReportID,
+		Domain:   report.Domain,
+		BeginAt:  report.Begin,
+		EndAt:    report.End,
+	})
+	if err != nil {
+		return err
+	}
+	for _, r := range reports {
+		for _, row := range report.Rows {
+			err := m.db.CreateDMARCRecord(context.TODO(), sqlc.CreateDMARCRecordParams{
+				ReportID:    r.ID,
+				HeaderFrom:  row.HeaderFrom,
+				SourceIp:    row.SourceIP,
+				Count:       int64(row.Count),
+				Disposition: row.Disposition,
+				DkimAligned: row.DKIMAligned,
+				SpfAligned:  row.SPFAligned,
+				BeginAt:     r.BeginAt,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *manager) GetStats(domain string, from time.Time, to time.Time) ([]Stats, error) {
+	rows, err := m.db.GetDMARCStats(context.TODO(), sqlc.GetDMARCStatsParams{
+		HeaderFrom: domain,
+		Start:      from,
+		Stop:       to,
+	})
+	if err != nil {
+		return nil, err
+	}
+	stats := make([]Stats, 0, len(rows))
+	for _, r := range rows {
+		stats = append(stats, Stats{
+			SourceIP:    r.SourceIp,
+			Messages:    r.Messages,
+			Passed:      r.Passed,
+			DKIMAligned: r.DkimAligned,
+			SPFAligned:  r.SpfAligned,
+			Quarantined: r.Quarantined,
+			Rejected:    r.Rejected,
+		})
+	}
+	return stats, nil
+}
@@ -0,0 +1,274 @@
+package dmarc
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"path"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"kannon.gyozatech.dev/generated/pb"
+)
+
+// ErrNotReport is returned when a message has no aggregate report
+var ErrNotReport = errors.New("message is not a dmarc aggregate report")
+
+// maxReportSize is the max size of an uncompressed report
+const maxReportSize = 50 * 1024 * 1024
+
+// Report is a DMARC aggregate report (RFC 7489 appendix C) sent by a
+// mailbox provider to the rua address of the DMARC record of a domain
+type Report struct {
+	OrgName  string
+	ReportID string
+	// Domain is the domain of the DMARC policy of the report
+	Domain string
+	Begin  time.Time
+	End    time.Time
+	Rows   []Row
+}
+
+// Row are the messages of a source ip with the same authentication results
+type Row struct {
+	SourceIP string
+	Count    uint32
+	// HeaderFrom is the domain of the From header of the messages
+	HeaderFrom string
+	// Disposition is the policy applied: none, quarantine or reject
+	Disposition string
+	// DKIMAligned and SPFAligned report if DKIM and SPF passed aligned
+	// with HeaderFrom, the messages pass DMARC when one of them passed
+	DKIMAligned bool
+	SPFAligned  bool
+}
+
+// Pass reports if the messages of r passed DMARC
+func (r Row) Pass() bool {
+	return r.DKIMAligned || r.SPFAligned
+}
+
+// feedback is the xml of an aggregate report
+type feedback struct {
+	XMLName  xml.Name `xml:"feedback"`
+	Metadata struct {
+		OrgName   string `xml:"org_name"`
+		ReportID  string `xml:"report_id"`
+		DateRange struct {
+			Begin int64 `xml:"begin"`
+			End   int64 `xml:"end"`
+		} `xml:"date_range"`
+	} `xml:"report_metadata"`
+	Policy struct {
+		Domain string `xml:"domain"`
+	} `xml:"policy_published"`
+	Records []struct {
+		Row struct {
+			SourceIP        string `xml:"source_ip"`
+			Count           uint32 `xml:"count"`
+			PolicyEvaluated struct {
+				Disposition string `xml:"disposition"`
+				DKIM        string `xml:"dkim"`
+				SPF         string `xml:"spf"`
+			} `xml:"policy_evaluated"`
+		} `xml:"row"`
+		Identifiers struct {
+			HeaderFrom string `xml:"header_from"`
+		} `xml:"identifiers"`
+	} `xml:"record"`
+}
+
+// Parse parses an aggregate report, raw or compressed with gzip or zip
+func Parse(r io.Reader) (Report, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r, maxReportSize))
+	if err != nil {
+		return Report{}, err
+	}
+	data, err = decompress(data)
+	if err != nil {
+		return Report{}, err
+	}
+
+	var f feedback
+	if err := xml.Unmarshal(data, &f); err != nil {
+		return Report{}, fmt.Errorf("invalid report: %w", err)
+	}
+	if f.Metadata.OrgName == "" || f.Metadata.ReportID == "" || f.Policy.Domain == "" {
+		return Report{}, errors.New("invalid report: missing org_name, report_id or domain")
+	}
+
+	report := Report{
+		OrgName:  strings.TrimSpace(f.Metadata.OrgName),
+		ReportID: strings.TrimSpace(f.Metadata.ReportID),
+		Domain:   strings.ToLower(strings.TrimSpace(f.Policy.Domain)),
+		Begin:    time.Unix(f.Metadata.DateRange.Begin, 0),
+		End:      time.Unix(f.Metadata.DateRange.End, 0),
+	}
+	for _, rec := range f.Records {
+		e := rec.Row.PolicyEvaluated
+		report.Rows = append(report.Rows, Row{
+			SourceIP:    strings.TrimSpace(rec.Row.SourceIP),
+			Count:       rec.Row.Count,
+			HeaderFrom:  strings.ToLower(strings.TrimSpace(rec.Identifiers.HeaderFrom)),
+			Disposition: strings.ToLower(strings.TrimSpace(e.Disposition)),
+			DKIMAligned: strings.EqualFold(strings.TrimSpace(e.DKIM), "pass"),
+			SPFAligned:  strings.EqualFold(strings.TrimSpace(e.SPF), "pass"),
+		})
+	}
+	return report, nil
+}
+
+// decompress returns the xml of a gzip or zip report
+func decompress(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return ioutil.ReadAll(io.LimitReader(zr, maxReportSize))
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if !strings.HasSuffix(strings.ToLower(f.Name), ".xml") {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			return ioutil.ReadAll(io.LimitReader(rc, maxReportSize))
+		}
+		return nil, errors.New("invalid report: no xml in zip")
+	default:
+		return data, nil
+	}
+}
+
+// ParseMessage parses the aggregate report attached to an email
+func ParseMessage(r io.Reader) (Report, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return Report{}, err
+	}
+	return parsePart(msg.Header, msg.Body)
+}
+
+// header is the header of a message or of a part
+type header interface {
+	Get(key string) string
+}
+
+// parsePart finds the report in a part, multipart parts are searched
+// until a report is found
+func parsePart(h header, body io.Reader) (Report, error) {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return Report{}, ErrNotReport
+			}
+			if err != nil {
+				return Report{}, err
+			}
+			report, err := parsePart(part.Header, part)
+			if !errors.Is(err, ErrNotReport) {
+				return report, err
+			}
+		}
+	}
+
+	if !isReportPart(mediaType, h) {
+		return Report{}, ErrNotReport
+	}
+	if strings.EqualFold(strings.TrimSpace(h.Get("Content-Transfer-Encoding")), "base64") {
+		// line breaks are ignored by the decoder
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+	return Parse(body)
+}
+
+// isReportPart reports if a part is a report by its media type
+// or, for generic types, by its file name like report.xml.gz
+func isReportPart(mediaType string, h header) bool {
+	switch mediaType {
+	case "application/zip", "application/x-zip-compressed", "application/gzip",
+		"application/x-gzip", "application/xml", "text/xml":
+		return true
+	case "application/octet-stream":
+		_, params, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+		name := strings.ToLower(path.Base(params["filename"]))
+		return strings.HasSuffix(name, ".xml") || strings.HasSuffix(name, ".gz") || strings.HasSuffix(name, ".zip")
+	}
+	return false
+}
+
+// Marshal encodes a report as the DMARCReport published by the bouncer
+func Marshal(report Report) ([]byte, error) {
+	m := &pb.DMARCReport{
+		OrgName:  report.OrgName,
+		ReportId: report.ReportID,
+		Domain:   report.Domain,
+		Begin:    timestamppb.New(report.Begin),
+		End:      timestamppb.New(report.End),
+	}
+	for _, r := range report.Rows {
+		m.Rows = append(m.Rows, &pb.DMARCReport_Row{
+			SourceIp:    r.SourceIP,
+			Count:       r.Count,
+			HeaderFrom:  r.HeaderFrom,
+			Disposition: r.Disposition,
+			DkimAligned: r.DKIMAligned,
+			SpfAligned:  r.SPFAligned,
+		})
+	}
+	return proto.Marshal(m)
+}
+
+// Unmarshal decodes a DMARCReport published by the bouncer
+func Unmarshal(data []byte) (Report, error) {
+	m := pb.DMARCReport{}
+	if err := proto.Unmarshal(data, &m); err != nil {
+		return Report{}, err
+	}
+	report := Report{
+		OrgName:  m.OrgName,
+		ReportID: m.ReportId,
+		Domain:   m.Domain,
+		Begin:    m.Begin.AsTime(),
+		End:      m.End.AsTime(),
+	}
+	for _, r := range m.Rows {
+		report.Rows = append(report.Rows, Row{
+			SourceIP:    r.SourceIp,
+			Count:       r.Count,
+			HeaderFrom:  r.HeaderFrom,
+			Disposition: r.Disposition,
+			DKIMAligned: r.DkimAligned,
+			SPFAligned:  r.SpfAligned,
+		})
+	}
+	return report, nil
+}
@@ -0,0 +1,147 @@
+package dmarc
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const aggregate = `<?xml version="1.0" encoding="UTF-8" ?>
+<feedback>
+  <report_metadata>
+    <org_name>google.com</org_name>
+    <email>noreply-dmarc-support@google.com</email>
+    <report_id>5717107811868587391</report_id>
+    <date_range>
+      <begin>1626220800</begin>
+      <end>1626307199</end>
+    </date_range>
+  </report_metadata>
+  <policy_published>
+    <domain>Kannon.io</domain>
+    <p>quarantine</p>
+  </policy_published>
+  <record>
+    <row>
+      <source_ip>192.0.2.1</source_ip>
+      <count>12</count>
+      <policy_evaluated>
+        <disposition>none</disposition>
+        <dkim>pass</dkim>
+        <spf>fail</spf>
+      </policy_evaluated>
+    </row>
+    <identifiers>
+      <header_from>kannon.io</header_from>
+    </identifiers>
+  </record>
+  <record>
+    <row>
+      <source_ip>198.51.100.7</source_ip>
+      <count>3</count>
+      <policy_evaluated>
+        <disposition>quarantine</disposition>
+        <dkim>fail</dkim>
+        <spf>fail</spf>
+      </policy_evaluated>
+    </row>
+    <identifiers>
+      <header_from>news.kannon.io</header_from>
+    </identifiers>
+  </record>
+</feedback>`
+
+func assertAggregate(t *testing.T, r Report) {
+	assert.Equal(t, "google.com", r.OrgName)
+	assert.Equal(t, "5717107811868587391", r.ReportID)
+	assert.Equal(t, "kannon.io", r.Domain)
+	assert.True(t, r.Begin.Equal(time.Unix(1626220800, 0)))
+	assert.True(t, r.End.Equal(time.Unix(1626307199, 0)))
+	assert.Equal(t, []Row{
+		{SourceIP: "192.0.2.1", Count: 12, HeaderFrom: "kannon.io", Disposition: "none", DKIMAligned: true},
+		{SourceIP: "198.51.100.7", Count: 3, HeaderFrom: "news.kannon.io", Disposition: "quarantine"},
+	}, r.Rows)
+	assert.True(t, r.Rows[0].Pass())
+	assert.False(t, r.Rows[1].Pass())
+}
+
+func gzipped(t *testing.T) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write([]byte(aggregate))
+	assert.Nil(t, err)
+	assert.Nil(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestParse(t *testing.T) {
+	r, err := Parse(strings.NewReader(aggregate))
+	assert.Nil(t, err)
+	assertAggregate(t, r)
+
+	r, err = Parse(bytes.NewReader(gzipped(t)))
+	assert.Nil(t, err)
+	assertAggregate(t, r)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, err := zw.Create("google.com!kannon.io!1626220800!1626307199.xml")
+	assert.Nil(t, err)
+	_, err = f.Write([]byte(aggregate))
+	assert.Nil(t, err)
+	assert.Nil(t, zw.Close())
+	r, err = Parse(&buf)
+	assert.Nil(t, err)
+	assertAggregate(t, r)
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := Parse(strings.NewReader("not a report"))
+	assert.NotNil(t, err)
+	_, err = Parse(strings.NewReader("<feedback><report_metadata></report_metadata></feedback>"))
+	assert.NotNil(t, err)
+}
+
+func TestParseMessage(t *testing.T) {
+	msg := "From: noreply-dmarc-support@google.com\r\n" +
+		"To: dmarc@kannon.io\r\n" +
+		"Subject: Report domain: kannon.io Submitter: google.com\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/mixed; boundary=\"part1\"\r\n" +
+		"\r\n" +
+		"--part1\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"This is an aggregate report from google.com.\r\n" +
+		"--part1\r\n" +
+		"Content-Type: application/octet-stream\r\n" +
+		"Content-Disposition: attachment; filename=\"google.com!kannon.io!1626220800!1626307199.xml.gz\"\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		base64.StdEncoding.EncodeToString(gzipped(t)) + "\r\n" +
+		"--part1--\r\n"
+
+	r, err := ParseMessage(strings.NewReader(msg))
+	assert.Nil(t, err)
+	assertAggregate(t, r)
+
+	_, err = ParseMessage(strings.NewReader("From: a@b.com\r\nContent-Type: text/plain\r\n\r\nhello\r\n"))
+	assert.True(t, errors.Is(err, ErrNotReport))
+}
+
+func TestMarshal(t *testing.T) {
+	r, err := Parse(strings.NewReader(aggregate))
+	assert.Nil(t, err)
+	data, err := Marshal(r)
+	assert.Nil(t, err)
+	r, err = Unmarshal(data)
+	assert.Nil(t, err)
+	assertAggregate(t, r)
+}
@@ -0,0 +1,176 @@
+package dnsprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// CloudflareConfig is the API token of Cloudflare, like APP_DNS_CLOUDFLARE_APITOKEN,
+// the token needs the Zone:Read and DNS:Edit permissions
+type CloudflareConfig struct {
+	APIToken string
+}
+
+const cloudflareEndpoint = "https://api.cloudflare.com/client/v4"
+
+type cloudflare struct {
+	config   CloudflareConfig
+	client   *http.Client
+	endpoint string
+}
+
+func newCloudflare(config CloudflareConfig, client *http.Client) *cloudflare {
+	return &cloudflare{
+		config:   config,
+		client:   client,
+		endpoint: cloudflareEndpoint,
+	}
+}
+
+// cloudflareRecord is a DNS record of Cloudflare, the preference of
+// MX records is their priority
+type cloudflareRecord struct {
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Content  string `json:"content"`
+	TTL      int    `json:"ttl"`
+	Priority *int   `json:"priority,omitempty"`
+}
+
+// value returns the value of a record
+func (r cloudflareRecord) value() string {
+	if r.Type == TypeMX && r.Priority != nil {
+		return fmt.Sprintf("%v %v", *r.Priority, r.Content)
+	}
+	return r.Content
+}
+
+func (c *cloudflare) Zone(ctx context.Context, name string) (string, error) {
+	var zones []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/zones?"+url.Values{"name": {name}}.Encode(), nil, &zones); err != nil {
+		return "", err
+	}
+	for _, z := range zones {
+		if strings.EqualFold(z.Name, name) {
+			return z.ID, nil
+		}
+	}
+	return "", ErrZoneNotFound
+}
+
+func (c *cloudflare) records(ctx context.Context, zone, typ, name string) ([]cloudflareRecord, error) {
+	var records []cloudflareRecord
+	query := url.Values{"type": {typ}, "name": {name}, "per_page": {"100"}}
+	err := c.do(ctx, http.MethodGet, "/zones/"+zone+"/dns_records?"+query.Encode(), nil, &records)
+	return records, err
+}
+
+func (c *cloudflare) Get(ctx context.Context, zone, typ, name string) ([]string, error) {
+	records, err := c.records(ctx, zone, typ, name)
+	if err != nil {
+		return nil, err
+	}
+	var values []string
+	for _, r := range records {
+		values = append(values, r.value())
+	}
+	return values, nil
+}
+
+// Set deletes the records of name that are not in values and creates the missing ones
+func (c *cloudflare) Set(ctx context.Context, zone, typ, name string, values []string, ttl int) error {
+	records, err := c.records(ctx, zone, typ, name)
+	if err != nil {
+		return err
+	}
+	wanted := make(map[string]bool, len(values))
+	for _, v := range values {
+		wanted[v] = true
+	}
+	for _, r := range records {
+		if wanted[r.value()] {
+			delete(wanted, r.value())
+			continue
+		}
+		if err := c.do(ctx, http.MethodDelete, "/zones/"+zone+"/dns_records/"+r.ID, nil, nil); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range values {
+		if !wanted[v] {
+			continue
+		}
+		r := cloudflareRecord{Type: typ, Name: name, Content: v, TTL: ttl}
+		if typ == TypeMX {
+			fields := strings.Fields(v)
+			if len(fields) != 2 {
+				return fmt.Errorf("invalid mx value: %v", v)
+			}
+			pref, err := strconv.Atoi(fields[0])
+			if err != nil {
+				return fmt.Errorf("invalid mx value: %v", v)
+			}
+			r.Priority, r.Content = &pref, fields[1]
+		}
+		body, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		if err := c.do(ctx, http.MethodPost, "/zones/"+zone+"/dns_records", body, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// do sends a request to the Cloudflare API and decodes the result of its response in res
+func (c *cloudflare) do(ctx context.Context, method, path string, body []byte, res interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.config.APIToken)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var r struct {
+		Success bool `json:"success"`
+		Errors  []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&r); err != nil {
+		return apiError(ProviderCloudflare, resp, "")
+	}
+	if !r.Success || resp.StatusCode >= 300 {
+		var messages []string
+		for _, e := range r.Errors {
+			messages = append(messages, e.Message)
+		}
+		return apiError(ProviderCloudflare, resp, strings.Join(messages, ", "))
+	}
+	if res == nil {
+		return nil
+	}
+	return json.Unmarshal(r.Result, res)
+}
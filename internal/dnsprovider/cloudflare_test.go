@@ -0,0 +1,63 @@
+package dnsprovider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloudflare(t *testing.T) {
+	var created []cloudflareRecord
+	var deleted []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		switch {
+		case r.URL.Path == "/zones" && r.URL.Query().Get("name") == "example.com":
+			w.Write([]byte(`{"success":true,"result":[{"id":"z1","name":"example.com"}]}`))
+		case r.URL.Path == "/zones":
+			w.Write([]byte(`{"success":true,"result":[]}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/zones/z1/dns_records":
+			w.Write([]byte(`{"success":true,"result":[
+				{"id":"r1","type":"MX","name":"example.com","content":"old.example.com","priority":20,"ttl":300},
+				{"id":"r2","type":"MX","name":"example.com","content":"mx.example.com","priority":10,"ttl":300}]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/zones/z1/dns_records":
+			var record cloudflareRecord
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&record))
+			created = append(created, record)
+			w.Write([]byte(`{"success":true,"result":{}}`))
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, r.URL.Path)
+			w.Write([]byte(`{"success":true,"result":{}}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"success":false,"errors":[{"code":1,"message":"unexpected request"}]}`))
+		}
+	}))
+	defer srv.Close()
+
+	p := newCloudflare(CloudflareConfig{APIToken: "token"}, srv.Client())
+	p.endpoint = srv.URL
+	ctx := context.Background()
+
+	zone, err := FindZone(ctx, p, "mail.example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "z1", zone)
+
+	values, err := p.Get(ctx, zone, TypeMX, "example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"20 old.example.com", "10 mx.example.com"}, values)
+
+	assert.Nil(t, p.Set(ctx, zone, TypeMX, "example.com", []string{"10 mx.example.com", "30 bounces.kannon.io"}, 3600))
+	assert.Equal(t, []string{"/zones/z1/dns_records/r1"}, deleted)
+	assert.Len(t, created, 1)
+	assert.Equal(t, "bounces.kannon.io", created[0].Content)
+	assert.Equal(t, 30, *created[0].Priority)
+
+	_, err = p.Get(ctx, "z2", TypeMX, "example.com")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "unexpected request")
+}
@@ -0,0 +1,115 @@
+package dnsprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Providers of the DNS of domains
+const (
+	ProviderRoute53    = "route53"
+	ProviderCloudflare = "cloudflare"
+	ProviderGoogle     = "google"
+)
+
+// DefaultTTL is the TTL of the provisioned records
+const DefaultTTL = 3600
+
+// ErrZoneNotFound is returned when the provider has no zone of a domain
+var ErrZoneNotFound = errors.New("zone not found")
+
+// Config is the provider of the DNS of domains, like APP_DNS_PROVIDER,
+// records are not provisioned when Provider is empty
+type Config struct {
+	// Provider is route53, cloudflare or google
+	Provider string
+	// TTL is the TTL of the provisioned records
+	TTL        int `default:"3600"`
+	Route53    Route53Config
+	Cloudflare CloudflareConfig
+	Google     GoogleConfig
+}
+
+// Enabled reports if records are provisioned
+func (c Config) Enabled() bool {
+	return c.Provider != ""
+}
+
+// Validate checks that the credentials of the provider are set
+func (c Config) Validate() error {
+	switch c.Provider {
+	case "":
+		return nil
+	case ProviderRoute53:
+		if c.Route53.AccessKeyID == "" || c.Route53.SecretAccessKey == "" {
+			return errors.New("missing route53 access key")
+		}
+	case ProviderCloudflare:
+		if c.Cloudflare.APIToken == "" {
+			return errors.New("missing cloudflare api token")
+		}
+	case ProviderGoogle:
+		if c.Google.Project == "" {
+			return errors.New("missing google project")
+		}
+	default:
+		return fmt.Errorf("unknown dns provider: %v", c.Provider)
+	}
+	if c.TTL < 0 {
+		return fmt.Errorf("invalid ttl: %v", c.TTL)
+	}
+	return nil
+}
+
+// Provider manages the record sets of the zones of a DNS provider. Values
+// are like the values of verification.DNSRecord: TXT values are not quoted,
+// MX values are <preference> <host> and hosts have no trailing dot
+type Provider interface {
+	// Zone returns the id of the zone named name, ErrZoneNotFound when there is none
+	Zone(ctx context.Context, name string) (string, error)
+	// Get returns the values of the records of type typ at name, nil when there is none
+	Get(ctx context.Context, zone, typ, name string) ([]string, error)
+	// Set replaces the records of type typ at name with values
+	Set(ctx context.Context, zone, typ, name string, values []string, ttl int) error
+}
+
+// New returns the Provider of config, nil when it is not enabled
+func New(config Config, client *http.Client) (Provider, error) {
+	switch config.Provider {
+	case "":
+		return nil, nil
+	case ProviderRoute53:
+		return newRoute53(config.Route53, client), nil
+	case ProviderCloudflare:
+		return newCloudflare(config.Cloudflare, client), nil
+	case ProviderGoogle:
+		return newGoogle(config.Google, client)
+	}
+	return nil, fmt.Errorf("unknown dns provider: %v", config.Provider)
+}
+
+// FindZone returns the id of the zone of domain, the zone of its
+// closest parent when the provider has no zone named domain
+func FindZone(ctx context.Context, p Provider, domain string) (string, error) {
+	labels := strings.Split(strings.TrimSuffix(domain, "."), ".")
+	// top level domains are not zones of the provider
+	for i := 0; i < len(labels)-1; i++ {
+		zone, err := p.Zone(ctx, strings.Join(labels[i:], "."))
+		if errors.Is(err, ErrZoneNotFound) {
+			continue
+		}
+		return zone, err
+	}
+	return "", fmt.Errorf("%w: %v", ErrZoneNotFound, domain)
+}
+
+// apiError returns the error of a response of a provider API
+func apiError(provider string, res *http.Response, message string) error {
+	if message == "" {
+		message = res.Status
+	}
+	return fmt.Errorf("%v: %v %v: %v", provider, res.Request.Method, res.Request.URL.Path, message)
+}
@@ -0,0 +1,289 @@
+package dnsprovider
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GoogleConfig is the project of Google Cloud DNS, like APP_DNS_GOOGLE_PROJECT
+type GoogleConfig struct {
+	Project string
+	// CredentialsFile is the JSON key of a service account with the DNS Administrator
+	// role, the service account of the instance is used when empty
+	CredentialsFile string
+}
+
+const (
+	googleEndpoint      = "https://dns.googleapis.com/dns/v1/projects/"
+	googleMetadataToken = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	googleScope         = "https://www.googleapis.com/auth/ndev.clouddns.readwrite"
+)
+
+// googleServiceAccount are the fields of the JSON key of a service account
+type googleServiceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+type google struct {
+	config   GoogleConfig
+	client   *http.Client
+	endpoint string
+	// account is nil when the tokens are the tokens of the instance
+	account *googleServiceAccount
+	key     *rsa.PrivateKey
+	now     func() time.Time
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func newGoogle(config GoogleConfig, client *http.Client) (*google, error) {
+	g := &google{
+		config:   config,
+		client:   client,
+		endpoint: googleEndpoint + url.PathEscape(config.Project),
+		now:      time.Now,
+	}
+	if config.CredentialsFile == "" {
+		return g, nil
+	}
+
+	data, err := ioutil.ReadFile(config.CredentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read google credentials: %w", err)
+	}
+	var account googleServiceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("invalid google credentials: %w", err)
+	}
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return nil, errors.New("invalid google credentials: missing private key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid google credentials: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("invalid google credentials: private key is not rsa")
+	}
+	g.account, g.key = &account, rsaKey
+	return g, nil
+}
+
+type googleRecordSet struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	TTL     int      `json:"ttl"`
+	Rrdatas []string `json:"rrdatas"`
+}
+
+func (g *google) Zone(ctx context.Context, name string) (string, error) {
+	var res struct {
+		ManagedZones []struct {
+			Name    string `json:"name"`
+			DNSName string `json:"dnsName"`
+		} `json:"managedZones"`
+	}
+	if err := g.do(ctx, http.MethodGet, "/managedZones?"+url.Values{"dnsName": {fqdn(name)}}.Encode(), nil, &res); err != nil {
+		return "", err
+	}
+	for _, z := range res.ManagedZones {
+		if strings.EqualFold(z.DNSName, fqdn(name)) {
+			return z.Name, nil
+		}
+	}
+	return "", ErrZoneNotFound
+}
+
+func (g *google) recordSet(ctx context.Context, zone, typ, name string) (*googleRecordSet, error) {
+	var res struct {
+		Rrsets []googleRecordSet `json:"rrsets"`
+	}
+	query := url.Values{"name": {fqdn(name)}, "type": {typ}}
+	if err := g.do(ctx, http.MethodGet, "/managedZones/"+url.PathEscape(zone)+"/rrsets?"+query.Encode(), nil, &res); err != nil {
+		return nil, err
+	}
+	if len(res.Rrsets) == 0 {
+		return nil, nil
+	}
+	return &res.Rrsets[0], nil
+}
+
+func (g *google) Get(ctx context.Context, zone, typ, name string) ([]string, error) {
+	rrset, err := g.recordSet(ctx, zone, typ, name)
+	if err != nil || rrset == nil {
+		return nil, err
+	}
+	var values []string
+	for _, v := range rrset.Rrdatas {
+		values = append(values, fromZoneValue(typ, v))
+	}
+	return values, nil
+}
+
+// Set replaces the record set of name in a change, the change
+// fails when the record set changed since it was read
+func (g *google) Set(ctx context.Context, zone, typ, name string, values []string, ttl int) error {
+	existing, err := g.recordSet(ctx, zone, typ, name)
+	if err != nil {
+		return err
+	}
+	var change struct {
+		Additions []googleRecordSet `json:"additions"`
+		Deletions []googleRecordSet `json:"deletions,omitempty"`
+	}
+	addition := googleRecordSet{Name: fqdn(name), Type: typ, TTL: ttl}
+	for _, v := range values {
+		addition.Rrdatas = append(addition.Rrdatas, toZoneValue(typ, v))
+	}
+	change.Additions = []googleRecordSet{addition}
+	if existing != nil {
+		change.Deletions = []googleRecordSet{*existing}
+	}
+
+	body, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	return g.do(ctx, http.MethodPost, "/managedZones/"+url.PathEscape(zone)+"/changes", body, nil)
+}
+
+// do sends an authorized request to the Cloud DNS API and decodes its JSON response in res
+func (g *google) do(ctx context.Context, method, path string, body []byte, res interface{}) error {
+	token, err := g.accessToken(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot get google token: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, g.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&e)
+		return apiError(ProviderGoogle, resp, e.Error.Message)
+	}
+	if res == nil {
+		return nil
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(res)
+}
+
+// tokenRefreshMargin is the time before their expiration tokens are refreshed
+const tokenRefreshMargin = time.Minute
+
+// accessToken returns an OAuth token of the service account, from the
+// metadata server of the instance when there are no credentials
+func (g *google) accessToken(ctx context.Context) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.token != "" && g.now().Add(tokenRefreshMargin).Before(g.expiresAt) {
+		return g.token, nil
+	}
+
+	var req *http.Request
+	var err error
+	if g.account == nil {
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, googleMetadataToken, nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Metadata-Flavor", "Google")
+	} else {
+		assertion, err := g.assertion()
+		if err != nil {
+			return "", err
+		}
+		form := url.Values{
+			"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+			"assertion":  {assertion},
+		}
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, g.account.TokenURI, strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request failed: %v", resp.Status)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return "", err
+	}
+	g.token = token.AccessToken
+	g.expiresAt = g.now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return g.token, nil
+}
+
+// assertion returns the JWT of the service account exchanged for a token
+func (g *google) assertion() (string, error) {
+	now := g.now()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   g.account.ClientEmail,
+		"scope": googleScope,
+		"aud":   g.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hashed := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, g.key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
@@ -0,0 +1,166 @@
+package dnsprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DNS record types provisioned
+const (
+	TypeTXT   = "TXT"
+	TypeMX    = "MX"
+	TypeCNAME = "CNAME"
+)
+
+// ErrConflict is returned for records that cannot be provisioned
+// without replacing records of the zone not created by kannon
+var ErrConflict = errors.New("conflicting records")
+
+// Record is a record a domain must publish
+type Record struct {
+	Type  string
+	Name  string
+	Value string
+}
+
+// Result is the result of the provisioning of a record
+type Result struct {
+	Record
+	// Changed reports if the records of the zone were changed
+	Changed bool
+	// Err is the reason the record was not provisioned, nil when it was
+	Err error
+}
+
+// Provision publishes records in the zone of domain with ttl, keeping the
+// other records of their names: TXT records replace the records with the same
+// version tag (like v=DKIM1), SPF records are merged with the SPF record of the
+// zone, CNAME records replace the CNAME of their name and MX records are only
+// added to names without MX since they would receive the emails of the domain
+func Provision(ctx context.Context, p Provider, domain string, records []Record, ttl int) ([]Result, error) {
+	zone, err := FindZone(ctx, p, domain)
+	if err != nil {
+		return nil, err
+	}
+	var results []Result
+	for _, r := range records {
+		res := Result{Record: r}
+		res.Changed, res.Err = provisionRecord(ctx, p, zone, r, ttl)
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+func provisionRecord(ctx context.Context, p Provider, zone string, r Record, ttl int) (bool, error) {
+	existing, err := p.Get(ctx, zone, r.Type, r.Name)
+	if err != nil {
+		return false, err
+	}
+	values, err := mergeValues(r, existing)
+	if err != nil {
+		return false, err
+	}
+	if equalValues(values, existing) {
+		return false, nil
+	}
+	if err := p.Set(ctx, zone, r.Type, r.Name, values, ttl); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// mergeValues returns the values of the records of the name of r with r
+func mergeValues(r Record, existing []string) ([]string, error) {
+	switch r.Type {
+	case TypeTXT:
+		tag := versionTag(r.Value)
+		var values []string
+		merged := false
+		for _, v := range existing {
+			if tag == "" || !strings.EqualFold(versionTag(v), tag) {
+				values = append(values, v)
+				continue
+			}
+			if strings.EqualFold(tag, "v=spf1") && !merged {
+				values = append(values, mergeSPF(v, r.Value))
+				merged = true
+			}
+		}
+		if !merged {
+			values = append(values, r.Value)
+		}
+		return values, nil
+	case TypeCNAME:
+		return []string{r.Value}, nil
+	case TypeMX:
+		if len(existing) == 0 {
+			return []string{r.Value}, nil
+		}
+		for _, v := range existing {
+			if strings.EqualFold(v, r.Value) {
+				return existing, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: %v has MX records", ErrConflict, r.Name)
+	}
+	return nil, fmt.Errorf("unknown record type: %v", r.Type)
+}
+
+// versionTag returns the v= tag starting a TXT value, like v=spf1
+func versionTag(v string) string {
+	term := strings.Fields(strings.Split(v, ";")[0])
+	if len(term) == 0 || !strings.HasPrefix(strings.ToLower(term[0]), "v=") {
+		return ""
+	}
+	return term[0]
+}
+
+// mergeSPF adds to the SPF record spf the mechanisms of the SPF record
+// with, before the all or redirect term ending spf
+func mergeSPF(spf, with string) string {
+	terms := strings.Fields(spf)
+	present := make(map[string]bool, len(terms))
+	for _, t := range terms {
+		present[strings.ToLower(t)] = true
+	}
+	var added []string
+	for _, t := range strings.Fields(with)[1:] {
+		if isSPFEnd(t) || present[strings.ToLower(t)] {
+			continue
+		}
+		added = append(added, t)
+	}
+	if len(added) == 0 {
+		return spf
+	}
+
+	end := len(terms)
+	for i, t := range terms {
+		if isSPFEnd(t) {
+			end = i
+			break
+		}
+	}
+	merged := append(append(append([]string{}, terms[:end]...), added...), terms[end:]...)
+	return strings.Join(merged, " ")
+}
+
+// isSPFEnd reports if t is an all or redirect term
+func isSPFEnd(t string) bool {
+	t = strings.ToLower(strings.TrimLeft(t, "+-~?"))
+	return t == "all" || strings.HasPrefix(t, "redirect=")
+}
+
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
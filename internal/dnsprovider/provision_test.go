@@ -0,0 +1,115 @@
+package dnsprovider
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memProvider is a provider of the zones of a map
+type memProvider struct {
+	zones   map[string]map[string][]string
+	setCall int
+}
+
+func (p *memProvider) Zone(ctx context.Context, name string) (string, error) {
+	if _, ok := p.zones[name]; ok {
+		return name, nil
+	}
+	return "", ErrZoneNotFound
+}
+
+func (p *memProvider) Get(ctx context.Context, zone, typ, name string) ([]string, error) {
+	return p.zones[zone][typ+" "+name], nil
+}
+
+func (p *memProvider) Set(ctx context.Context, zone, typ, name string, values []string, ttl int) error {
+	p.setCall++
+	p.zones[zone][typ+" "+name] = values
+	return nil
+}
+
+func TestFindZone(t *testing.T) {
+	p := &memProvider{zones: map[string]map[string][]string{"example.com": {}}}
+
+	zone, err := FindZone(context.Background(), p, "mail.example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "example.com", zone)
+
+	_, err = FindZone(context.Background(), p, "example.org")
+	assert.True(t, errors.Is(err, ErrZoneNotFound))
+}
+
+func TestProvision(t *testing.T) {
+	p := &memProvider{zones: map[string]map[string][]string{
+		"example.com": {
+			"TXT example.com":                     {"google-site-verification=abc", "v=spf1 include:_spf.google.com ~all"},
+			"TXT kannon-1._domainkey.example.com": {"v=DKIM1; k=rsa; p=OLD"},
+			"MX example.com":                      {"1 aspmx.l.google.com"},
+		},
+	}}
+
+	results, err := Provision(context.Background(), p, "example.com", []Record{
+		{Type: TypeTXT, Name: "kannon-1._domainkey.example.com", Value: "v=DKIM1; k=rsa; p=NEW"},
+		{Type: TypeTXT, Name: "example.com", Value: "v=spf1 include:mailer.kannon.io ~all"},
+		{Type: TypeCNAME, Name: "bounces.example.com", Value: "bounces.kannon.io"},
+		{Type: TypeMX, Name: "example.com", Value: "10 bounces.kannon.io"},
+	}, DefaultTTL)
+	assert.Nil(t, err)
+	assert.Len(t, results, 4)
+
+	zone := p.zones["example.com"]
+	assert.Equal(t, []string{"v=DKIM1; k=rsa; p=NEW"}, zone["TXT kannon-1._domainkey.example.com"])
+	assert.Equal(t, []string{"google-site-verification=abc", "v=spf1 include:_spf.google.com include:mailer.kannon.io ~all"}, zone["TXT example.com"])
+	assert.Equal(t, []string{"bounces.kannon.io"}, zone["CNAME bounces.example.com"])
+	for _, r := range results[:3] {
+		assert.True(t, r.Changed, r.Name)
+		assert.Nil(t, r.Err, r.Name)
+	}
+
+	// the MX of the emails of the domain is not replaced
+	assert.False(t, results[3].Changed)
+	assert.True(t, errors.Is(results[3].Err, ErrConflict))
+	assert.Equal(t, []string{"1 aspmx.l.google.com"}, zone["MX example.com"])
+
+	// provisioning again doesn't change the zone
+	calls := p.setCall
+	results, err = Provision(context.Background(), p, "example.com", []Record{
+		{Type: TypeTXT, Name: "kannon-1._domainkey.example.com", Value: "v=DKIM1; k=rsa; p=NEW"},
+		{Type: TypeTXT, Name: "example.com", Value: "v=spf1 include:mailer.kannon.io ~all"},
+	}, DefaultTTL)
+	assert.Nil(t, err)
+	for _, r := range results {
+		assert.False(t, r.Changed, r.Name)
+	}
+	assert.Equal(t, calls, p.setCall)
+}
+
+func TestMergeSPF(t *testing.T) {
+	tests := []struct {
+		spf  string
+		with string
+		exp  string
+	}{
+		{"v=spf1 -all", "v=spf1 include:a.com ~all", "v=spf1 include:a.com -all"},
+		{"v=spf1 ip4:1.2.3.4 redirect=b.com", "v=spf1 include:a.com ip4:1.2.3.4 ~all", "v=spf1 ip4:1.2.3.4 include:a.com redirect=b.com"},
+		{"v=spf1 include:A.com ~all", "v=spf1 include:a.com ~all", "v=spf1 include:A.com ~all"},
+		{"v=spf1 mx", "v=spf1 include:a.com ~all", "v=spf1 mx include:a.com"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.exp, mergeSPF(tt.spf, tt.with), tt.spf)
+	}
+}
+
+func TestQuoteTXT(t *testing.T) {
+	long := "v=DKIM1; k=rsa; p=" + strings.Repeat("A", 400)
+	quoted := quoteTXT(long)
+	assert.Equal(t, 2, strings.Count(quoted, `" "`)+1)
+	assert.Equal(t, long, unquoteTXT(quoted))
+
+	assert.Equal(t, `"say \"hi\""`, quoteTXT(`say "hi"`))
+	assert.Equal(t, `say "hi"`, unquoteTXT(`"say \"hi\""`))
+}
@@ -0,0 +1,168 @@
+package dnsprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"kannon.gyozatech.dev/internal/sigv4"
+)
+
+// Route53Config are the credentials of Route53, like APP_DNS_ROUTE53_ACCESSKEYID
+type Route53Config struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is the token of temporary credentials
+	SessionToken string
+}
+
+const (
+	route53Endpoint = "https://route53.amazonaws.com/2013-04-01"
+	route53Region   = "us-east-1"
+	route53Service  = "route53"
+	route53XMLNS    = "https://route53.amazonaws.com/doc/2013-04-01/"
+)
+
+type route53 struct {
+	config   Route53Config
+	client   *http.Client
+	endpoint string
+	now      func() time.Time
+}
+
+func newRoute53(config Route53Config, client *http.Client) *route53 {
+	return &route53{
+		config:   config,
+		client:   client,
+		endpoint: route53Endpoint,
+		now:      time.Now,
+	}
+}
+
+type route53RecordSet struct {
+	Name            string `xml:"Name"`
+	Type            string `xml:"Type"`
+	TTL             int    `xml:"TTL"`
+	ResourceRecords []struct {
+		Value string `xml:"Value"`
+	} `xml:"ResourceRecords>ResourceRecord"`
+}
+
+func (r *route53) Zone(ctx context.Context, name string) (string, error) {
+	var res struct {
+		HostedZones []struct {
+			ID   string `xml:"Id"`
+			Name string `xml:"Name"`
+		} `xml:"HostedZones>HostedZone"`
+	}
+	query := url.Values{"dnsname": {name}, "maxitems": {"1"}}
+	if err := r.do(ctx, http.MethodGet, "/hostedzonesbyname", query, nil, &res); err != nil {
+		return "", err
+	}
+	// zones are listed from name, the first zone is another zone when there is none
+	if len(res.HostedZones) == 0 || !strings.EqualFold(res.HostedZones[0].Name, fqdn(name)) {
+		return "", ErrZoneNotFound
+	}
+	return strings.TrimPrefix(res.HostedZones[0].ID, "/hostedzone/"), nil
+}
+
+func (r *route53) Get(ctx context.Context, zone, typ, name string) ([]string, error) {
+	var res struct {
+		RecordSets []route53RecordSet `xml:"ResourceRecordSets>ResourceRecordSet"`
+	}
+	query := url.Values{"name": {name}, "type": {typ}, "maxitems": {"1"}}
+	if err := r.do(ctx, http.MethodGet, "/hostedzone/"+zone+"/rrset", query, nil, &res); err != nil {
+		return nil, err
+	}
+	// record sets are listed from name and type
+	if len(res.RecordSets) == 0 || !strings.EqualFold(res.RecordSets[0].Name, fqdn(name)) || res.RecordSets[0].Type != typ {
+		return nil, nil
+	}
+	var values []string
+	for _, rr := range res.RecordSets[0].ResourceRecords {
+		values = append(values, fromZoneValue(typ, rr.Value))
+	}
+	return values, nil
+}
+
+func (r *route53) Set(ctx context.Context, zone, typ, name string, values []string, ttl int) error {
+	type resourceRecord struct {
+		Value string `xml:"Value"`
+	}
+	type change struct {
+		Action    string `xml:"Action"`
+		RecordSet struct {
+			Name            string           `xml:"Name"`
+			Type            string           `xml:"Type"`
+			TTL             int              `xml:"TTL"`
+			ResourceRecords []resourceRecord `xml:"ResourceRecords>ResourceRecord"`
+		} `xml:"ResourceRecordSet"`
+	}
+	var req struct {
+		XMLName xml.Name `xml:"ChangeResourceRecordSetsRequest"`
+		XMLNS   string   `xml:"xmlns,attr"`
+		Changes []change `xml:"ChangeBatch>Changes>Change"`
+	}
+	req.XMLNS = route53XMLNS
+	c := change{Action: "UPSERT"}
+	c.RecordSet.Name = fqdn(name)
+	c.RecordSet.Type = typ
+	c.RecordSet.TTL = ttl
+	for _, v := range values {
+		c.RecordSet.ResourceRecords = append(c.RecordSet.ResourceRecords, resourceRecord{Value: toZoneValue(typ, v)})
+	}
+	req.Changes = []change{c}
+
+	body, err := xml.Marshal(req)
+	if err != nil {
+		return err
+	}
+	return r.do(ctx, http.MethodPost, "/hostedzone/"+zone+"/rrset/", nil, body, nil)
+}
+
+// do sends a signed request to the Route53 API and decodes its XML response in res
+func (r *route53) do(ctx context.Context, method, path string, query url.Values, body []byte, res interface{}) error {
+	u := r.endpoint + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/xml")
+	}
+	if r.config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", r.config.SessionToken)
+	}
+	sigv4.Sign(req, body, r.config.AccessKeyID, r.config.SecretAccessKey, route53Region, route53Service, r.now())
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Message string `xml:"Error>Message"`
+		}
+		_ = xml.Unmarshal(data, &e)
+		return apiError(ProviderRoute53, resp, e.Message)
+	}
+	if res == nil {
+		return nil
+	}
+	return xml.Unmarshal(data, res)
+}
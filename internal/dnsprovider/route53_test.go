@@ -0,0 +1,62 @@
+package dnsprovider
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoute53(t *testing.T) {
+	var changes string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/"))
+		switch {
+		case r.URL.Path == "/hostedzonesbyname":
+			w.Write([]byte(`<ListHostedZonesByNameResponse><HostedZones><HostedZone>
+				<Id>/hostedzone/Z1</Id><Name>example.com.</Name></HostedZone></HostedZones></ListHostedZonesByNameResponse>`))
+		case r.Method == http.MethodGet && r.URL.Path == "/hostedzone/Z1/rrset":
+			w.Write([]byte(`<ListResourceRecordSetsResponse><ResourceRecordSets><ResourceRecordSet>
+				<Name>example.com.</Name><Type>TXT</Type><TTL>300</TTL><ResourceRecords>
+				<ResourceRecord><Value>"v=spf1 -all"</Value></ResourceRecord>
+				</ResourceRecords></ResourceRecordSet></ResourceRecordSets></ListResourceRecordSetsResponse>`))
+		case r.Method == http.MethodPost && r.URL.Path == "/hostedzone/Z1/rrset/":
+			body, _ := ioutil.ReadAll(r.Body)
+			changes = string(body)
+			w.Write([]byte(`<ChangeResourceRecordSetsResponse></ChangeResourceRecordSetsResponse>`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`<ErrorResponse><Error><Message>unexpected request</Message></Error></ErrorResponse>`))
+		}
+	}))
+	defer srv.Close()
+
+	p := newRoute53(Route53Config{AccessKeyID: "key", SecretAccessKey: "secret"}, srv.Client())
+	p.endpoint = srv.URL
+	ctx := context.Background()
+
+	zone, err := FindZone(ctx, p, "mail.example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "Z1", zone)
+
+	values, err := p.Get(ctx, zone, TypeTXT, "example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"v=spf1 -all"}, values)
+
+	values, err = p.Get(ctx, zone, TypeTXT, "other.example.com")
+	assert.Nil(t, err)
+	assert.Nil(t, values)
+
+	assert.Nil(t, p.Set(ctx, zone, TypeTXT, "example.com", []string{"v=spf1 include:a.com -all"}, 3600))
+	assert.Contains(t, changes, "<Action>UPSERT</Action>")
+	assert.Contains(t, changes, "<Name>example.com.</Name>")
+	assert.Contains(t, changes, "<Value>&#34;v=spf1 include:a.com -all&#34;</Value>")
+
+	_, err = p.Get(ctx, "Z2", TypeTXT, "example.com")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "unexpected request")
+}
@@ -0,0 +1,73 @@
+package dnsprovider
+
+import "strings"
+
+// maxTXTString is the max length of a character string of a TXT record
+const maxTXTString = 255
+
+// quoteTXT returns the zone file format of a TXT value, split in
+// quoted strings of maxTXTString characters, like "v=spf1 ~all"
+func quoteTXT(v string) string {
+	var parts []string
+	for len(v) > maxTXTString {
+		parts = append(parts, v[:maxTXTString])
+		v = v[maxTXTString:]
+	}
+	parts = append(parts, v)
+	for i, p := range parts {
+		p = strings.ReplaceAll(p, `\`, `\\`)
+		parts[i] = `"` + strings.ReplaceAll(p, `"`, `\"`) + `"`
+	}
+	return strings.Join(parts, " ")
+}
+
+// unquoteTXT returns the value of a TXT record in zone file format,
+// its quoted strings are joined
+func unquoteTXT(s string) string {
+	var b strings.Builder
+	quoted, escaped := false, false
+	for _, c := range s {
+		switch {
+		case escaped:
+			b.WriteRune(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+			b.WriteRune(c)
+		case c != ' ':
+			// unquoted strings
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// toZoneValue returns the zone file format of a value of type typ
+func toZoneValue(typ, v string) string {
+	switch typ {
+	case TypeTXT:
+		return quoteTXT(v)
+	case TypeCNAME, TypeMX:
+		return v + "."
+	}
+	return v
+}
+
+// fromZoneValue returns the value of a record in zone file format
+func fromZoneValue(typ, v string) string {
+	switch typ {
+	case TypeTXT:
+		return unquoteTXT(v)
+	case TypeCNAME, TypeMX:
+		return strings.TrimSuffix(v, ".")
+	}
+	return v
+}
+
+// fqdn returns name with a trailing dot
+func fqdn(name string) string {
+	return strings.TrimSuffix(name, ".") + "."
+}
@@ -3,17 +3,15 @@ package domains
 import (
 	"context"
 	"database/sql"
-	"math/rand"
+	"encoding/json"
 	"time"
 
 	"kannon.gyozatech.dev/generated/sqlc"
 	"kannon.gyozatech.dev/internal/dkim"
+	"kannon.gyozatech.dev/internal/mailbuilder"
+	"kannon.gyozatech.dev/internal/metrics"
 )
 
-func init() {
-	rand.Seed(time.Now().UnixNano())
-}
-
 type domainManager struct {
 	db *sqlc.Queries
 }
@@ -22,15 +20,33 @@ type domainManager struct {
 type DomainManager interface {
 	CreateDomain(domain string) (sqlc.Domain, error)
 	FindDomain(domain string) (sqlc.Domain, error)
-	FindDomainWithKey(domain string, key string) (sqlc.Domain, error)
 	GetAllDomains() ([]sqlc.Domain, error)
+	SetRetention(domain string, days uint) (sqlc.Domain, error)
+	SetRateLimit(domain string, perSecond uint, perHour uint) (sqlc.Domain, error)
+	SetIPPool(domain string, ipPool string) (sqlc.Domain, error)
+	SetQuota(domain string, daily uint, monthly uint) (sqlc.Domain, error)
+	SetReturnPathDomain(domain string, returnPathDomain string) (sqlc.Domain, error)
+	SetSendingSettings(domain string, settings mailbuilder.SendingSettings) (sqlc.Domain, error)
+	SetSandbox(domain string, sandbox bool, bouncePercent uint) (sqlc.Domain, error)
+	PauseDomain(domain string, reason string) (sqlc.Domain, error)
+	ResumeDomain(domain string) (sqlc.Domain, error)
+	SetDKIMSigning(domain string, signing string) (sqlc.Domain, error)
+	SetDKIMHeaders(domain string, headers []string) (sqlc.Domain, error)
+	RotateDKIMKey(domain string, algorithm string, selector string) (sqlc.DkimKey, error)
+	PromoteDKIMKey(domain string, selector string, gracePeriod time.Duration) (sqlc.Domain, error)
+	GetDKIMKeys(domain string) ([]sqlc.DkimKey, error)
+	RetireDKIMKeys() ([]sqlc.DkimKey, error)
+	DeleteDomain(domain string, gracePeriod time.Duration) (sqlc.Domain, int64, error)
+	RestoreDomain(domain string) (sqlc.Domain, error)
+	FindDeletedDomain(domain string) (sqlc.Domain, error)
+	GetDomainsToPurge() ([]sqlc.Domain, error)
 	Close() error
 }
 
 // NewDomainManager is the contrusctor for a Domain Manager
 func NewDomainManager(db *sql.DB) (DomainManager, error) {
 	return &domainManager{
-		db: sqlc.New(db),
+		db: sqlc.New(metrics.InstrumentDB(db)),
 	}, nil
 }
 
@@ -40,12 +56,17 @@ func (dm *domainManager) CreateDomain(domain string) (sqlc.Domain, error) {
 	if err != nil {
 		return sqlc.Domain{}, err
 	}
+	edKeys, err := dkim.GenerateEd25519KeysPair()
+	if err != nil {
+		return sqlc.Domain{}, err
+	}
 
 	d, err := dm.db.CreateDomain(context.TODO(), sqlc.CreateDomainParams{
-		Domain:         domain,
-		Key:            generateRandomKey(20),
-		DkimPrivateKey: keys.PrivateKey,
-		DkimPublicKey:  keys.PublicKey,
+		Domain:                domain,
+		DkimPrivateKey:        keys.PrivateKey,
+		DkimPublicKey:         keys.PublicKey,
+		DkimEd25519PrivateKey: edKeys.PrivateKey,
+		DkimEd25519PublicKey:  edKeys.PublicKey,
 	})
 
 	if err != nil {
@@ -63,34 +84,272 @@ func (dm *domainManager) FindDomain(d string) (sqlc.Domain, error) {
 	return domain, nil
 }
 
-func (dm *domainManager) FindDomainWithKey(d string, k string) (sqlc.Domain, error) {
-	domain, err := dm.db.FindDomainWithKey(context.TODO(), sqlc.FindDomainWithKeyParams{
-		Domain: d,
-		Key:    k,
+func (dm *domainManager) GetAllDomains() ([]sqlc.Domain, error) {
+	domains, err := dm.db.GetAllDomains(context.TODO())
+	if err != nil {
+		return domains, err
+	}
+	return domains, nil
+}
+
+// SetRetention sets the days the messages of a domain are kept,
+// 0 uses the default retention of the purger
+func (dm *domainManager) SetRetention(domain string, days uint) (sqlc.Domain, error) {
+	return dm.db.SetDomainRetention(context.TODO(), sqlc.SetDomainRetentionParams{
+		Domain:        domain,
+		RetentionDays: int32(days),
+	})
+}
+
+// SetRateLimit sets the max emails of a domain dispatched every second
+// and every hour, 0 is unlimited
+func (dm *domainManager) SetRateLimit(domain string, perSecond uint, perHour uint) (sqlc.Domain, error) {
+	return dm.db.SetDomainRateLimit(context.TODO(), sqlc.SetDomainRateLimitParams{
+		Domain:        domain,
+		RatePerSecond: int32(perSecond),
+		RatePerHour:   int32(perHour),
+	})
+}
+
+// SetQuota sets the max emails of a domain dispatched every day
+// and every month, 0 is unlimited
+func (dm *domainManager) SetQuota(domain string, daily uint, monthly uint) (sqlc.Domain, error) {
+	return dm.db.SetDomainQuota(context.TODO(), sqlc.SetDomainQuotaParams{
+		Domain:       domain,
+		DailyQuota:   int32(daily),
+		MonthlyQuota: int32(monthly),
+	})
+}
+
+// SetIPPool sets the pool of source IPs used to send the
+// emails of a domain, empty is the pool of the email priority
+func (dm *domainManager) SetIPPool(domain string, ipPool string) (sqlc.Domain, error) {
+	return dm.db.SetDomainIPPool(context.TODO(), sqlc.SetDomainIPPoolParams{
+		Domain: domain,
+		IpPool: ipPool,
+	})
+}
+
+// SetReturnPathDomain sets the domain of the return path of the emails
+// of a domain, empty is the domain itself
+func (dm *domainManager) SetReturnPathDomain(domain string, returnPathDomain string) (sqlc.Domain, error) {
+	return dm.db.SetDomainReturnPathDomain(context.TODO(), sqlc.SetDomainReturnPathDomainParams{
+		Domain:           domain,
+		ReturnPathDomain: returnPathDomain,
 	})
+}
+
+// SetSendingSettings sets the default sender and headers, the tracking
+// and the footers of the emails of a domain
+func (dm *domainManager) SetSendingSettings(domain string, settings mailbuilder.SendingSettings) (sqlc.Domain, error) {
+	headers := settings.Headers
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	defaultHeaders, err := json.Marshal(headers)
 	if err != nil {
-		return domain, err
+		return sqlc.Domain{}, err
 	}
-	return domain, nil
+	return dm.db.SetDomainSendingSettings(context.TODO(), sqlc.SetDomainSendingSettingsParams{
+		Domain:           domain,
+		DefaultFromEmail: settings.FromEmail,
+		DefaultFromName:  settings.FromName,
+		DefaultHeaders:   defaultHeaders,
+		OpenTracking:     settings.OpenTracking,
+		ClickTracking:    settings.ClickTracking,
+		FooterHtml:       settings.FooterHTML,
+		FooterText:       settings.FooterText,
+	})
 }
 
-func (dm *domainManager) GetAllDomains() ([]sqlc.Domain, error) {
-	domains, err := dm.db.GetAllDomains(context.TODO())
+// SetSandbox sets if the emails of a domain are simulated instead of delivered,
+// bouncePercent of the recipients of a sandbox domain bounce
+func (dm *domainManager) SetSandbox(domain string, sandbox bool, bouncePercent uint) (sqlc.Domain, error) {
+	return dm.db.SetDomainSandbox(context.TODO(), sqlc.SetDomainSandboxParams{
+		Domain:               domain,
+		Sandbox:              sandbox,
+		SandboxBouncePercent: int32(bouncePercent),
+	})
+}
+
+// PauseDomain pauses the dispatch of the emails of a domain,
+// they stay scheduled until the domain is resumed
+func (dm *domainManager) PauseDomain(domain string, reason string) (sqlc.Domain, error) {
+	return dm.db.PauseDomain(context.TODO(), sqlc.PauseDomainParams{
+		Domain:       domain,
+		PausedReason: reason,
+	})
+}
+
+// ResumeDomain resumes the dispatch of the emails of a paused domain
+func (dm *domainManager) ResumeDomain(domain string) (sqlc.Domain, error) {
+	return dm.db.ResumeDomain(context.TODO(), domain)
+}
+
+// DomainSendingSettings returns the sending settings of a domain
+func DomainSendingSettings(d sqlc.Domain) (mailbuilder.SendingSettings, error) {
+	headers, err := mailbuilder.ParseDefaultHeaders(d.DefaultHeaders)
 	if err != nil {
-		return domains, err
+		return mailbuilder.SendingSettings{}, err
 	}
-	return domains, nil
+	return mailbuilder.SendingSettings{
+		FromEmail:     d.DefaultFromEmail,
+		FromName:      d.DefaultFromName,
+		Headers:       headers,
+		OpenTracking:  d.OpenTracking,
+		ClickTracking: d.ClickTracking,
+		FooterHTML:    d.FooterHtml,
+		FooterText:    d.FooterText,
+	}, nil
 }
 
-func (dm *domainManager) Close() error {
+// SetDKIMSigning sets the keys signing the emails of a domain: rsa, ed25519
+// or dual, domains without an Ed25519 key get a new one
+func (dm *domainManager) SetDKIMSigning(domain string, signing string) (sqlc.Domain, error) {
+	edKeys, err := dkim.GenerateEd25519KeysPair()
+	if err != nil {
+		return sqlc.Domain{}, err
+	}
+	return dm.db.SetDomainDKIMSigning(context.TODO(), sqlc.SetDomainDKIMSigningParams{
+		Domain:                domain,
+		DkimSigning:           signing,
+		DkimEd25519PrivateKey: edKeys.PrivateKey,
+		DkimEd25519PublicKey:  edKeys.PublicKey,
+	})
+}
+
+// DefaultDKIMGracePeriod is the time the previous key of a domain
+// stays published after the promotion of a new key, so that the
+// emails signed before the promotion can still be verified
+const DefaultDKIMGracePeriod = 7 * 24 * time.Hour
+
+// RotateDKIMKey generates a pending key of algorithm for a domain, the key
+// signs the emails of the domain once it's published and promoted. An empty
+// selector is a new selector based on the current time
+func (dm *domainManager) RotateDKIMKey(domain string, algorithm string, selector string) (sqlc.DkimKey, error) {
+	d, err := dm.db.FindDomain(context.TODO(), domain)
+	if err != nil {
+		return sqlc.DkimKey{}, err
+	}
+	if selector == "" {
+		selector = dkim.NewSelector(algorithm, time.Now())
+	} else if err := dm.checkSelector(d, algorithm, selector); err != nil {
+		return sqlc.DkimKey{}, err
+	}
+	keys, err := dkim.GenerateKeysPair(algorithm)
+	if err != nil {
+		return sqlc.DkimKey{}, err
+	}
+	return dm.db.CreateDKIMKey(context.TODO(), sqlc.CreateDKIMKeyParams{
+		Domain:     domain,
+		Selector:   selector,
+		Algorithm:  algorithm,
+		PrivateKey: keys.PrivateKey,
+		PublicKey:  keys.PublicKey,
+	})
+}
+
+// checkSelector checks that selector is not used by the keys of a domain,
+// except the pending key of algorithm that is replaced by the new one
+func (dm *domainManager) checkSelector(d sqlc.Domain, algorithm string, selector string) error {
+	if selector == d.DkimSelector || selector == d.DkimEd25519Selector {
+		return dkim.ErrSelectorInUse
+	}
+	keys, err := dm.db.GetDKIMKeys(context.TODO(), d.Domain)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if k.Selector == selector && !(k.Status == sqlc.DkimKeyStatusPending && k.Algorithm == algorithm) {
+			return dkim.ErrSelectorInUse
+		}
+	}
 	return nil
 }
 
-func generateRandomKey(size uint) string {
-	letterRunes := []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
-	b := make([]rune, size)
-	for i := range b {
-		b[i] = letterRunes[rand.Intn(len(letterRunes))]
+// SetDKIMHeaders sets the headers signed with DKIM by a domain,
+// empty is the default set
+func (dm *domainManager) SetDKIMHeaders(domain string, headers []string) (sqlc.Domain, error) {
+	if headers == nil {
+		headers = []string{}
+	}
+	return dm.db.SetDomainDKIMHeaders(context.TODO(), sqlc.SetDomainDKIMHeadersParams{
+		Domain:      domain,
+		DkimHeaders: headers,
+	})
+}
+
+// PromoteDKIMKey makes the pending key of selector the active key of its
+// algorithm when its DNS record is published, the previous key is retired
+// after gracePeriod
+func (dm *domainManager) PromoteDKIMKey(domain string, selector string, gracePeriod time.Duration) (sqlc.Domain, error) {
+	key, err := dm.db.FindPendingDKIMKey(context.TODO(), sqlc.FindPendingDKIMKeyParams{
+		Domain:   domain,
+		Selector: selector,
+	})
+	if err != nil {
+		return sqlc.Domain{}, err
+	}
+	if err := dkim.VerifyRecord(domain, selector, key.Algorithm, key.PublicKey); err != nil {
+		return sqlc.Domain{}, err
 	}
-	return string(b)
+	return dm.db.PromoteDKIMKey(context.TODO(), sqlc.PromoteDKIMKeyParams{
+		Domain:   domain,
+		Selector: selector,
+		RetireAt: time.Now().Add(gracePeriod),
+	})
+}
+
+// GetDKIMKeys returns the pending, retiring and retired keys of a domain
+func (dm *domainManager) GetDKIMKeys(domain string) ([]sqlc.DkimKey, error) {
+	return dm.db.GetDKIMKeys(context.TODO(), domain)
+}
+
+// RetireDKIMKeys retires the keys at the end of their grace period
+func (dm *domainManager) RetireDKIMKeys() ([]sqlc.DkimKey, error) {
+	return dm.db.RetireDKIMKeys(context.TODO())
+}
+
+// DefaultDeletionGracePeriod is the time a deleted domain can be
+// restored before its data is purged
+const DefaultDeletionGracePeriod = 7 * 24 * time.Hour
+
+// DeleteDomain deletes a domain and cancels its emails not sent yet,
+// the domain can be restored for gracePeriod, then the purger deletes
+// its data. It returns the number of canceled emails
+func (dm *domainManager) DeleteDomain(domain string, gracePeriod time.Duration) (sqlc.Domain, int64, error) {
+	d, err := dm.db.DeleteDomain(context.TODO(), sqlc.DeleteDomainParams{
+		Domain:  domain,
+		PurgeAt: sql.NullTime{Time: time.Now().Add(gracePeriod), Valid: true},
+	})
+	if err != nil {
+		return sqlc.Domain{}, 0, err
+	}
+	canceled, err := dm.db.CancelDomainPool(context.TODO(), sqlc.CancelDomainPoolParams{
+		Domain: domain,
+	})
+	if err != nil {
+		return sqlc.Domain{}, 0, err
+	}
+	return d, canceled, nil
+}
+
+// RestoreDomain restores a deleted domain within its grace period,
+// canceled emails are not sent again
+func (dm *domainManager) RestoreDomain(domain string) (sqlc.Domain, error) {
+	return dm.db.RestoreDomain(context.TODO(), domain)
+}
+
+// FindDeletedDomain returns a deleted domain not purged yet
+func (dm *domainManager) FindDeletedDomain(domain string) (sqlc.Domain, error) {
+	return dm.db.FindDeletedDomain(context.TODO(), domain)
+}
+
+// GetDomainsToPurge returns the deleted domains at the end of their grace period
+func (dm *domainManager) GetDomainsToPurge() ([]sqlc.Domain, error) {
+	return dm.db.GetDomainsToPurge(context.TODO())
+}
+
+func (dm *domainManager) Close() error {
+	return nil
 }
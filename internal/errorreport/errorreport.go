@@ -0,0 +1,127 @@
+package errorreport
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"kannon.gyozatech.dev/internal/logging"
+)
+
+var log = logging.Logger("errorreport")
+
+// Config configures the reports of the errors of a service to Sentry,
+// or a service accepting the events of Sentry like GlitchTip
+type Config struct {
+	// DSN is the DSN of the project, like https://<key>@o0.ingest.sentry.io/<project>,
+	// errors are only logged when empty
+	DSN string
+	// Environment of the reported errors, like production
+	Environment string
+}
+
+// Enabled reports if errors are reported
+func (c Config) Enabled() bool {
+	return c.DSN != ""
+}
+
+// Validate checks the DSN of an enabled config
+func (c Config) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+	_, err := parseDSN(c.DSN)
+	return err
+}
+
+// current is the reporter of the errors of the process, nil when errors are not reported
+var current *reporter
+
+// Start reports the errors of service to the project of config until the
+// returned function is called, which sends the reports left. The lines
+// logged at error level or above are reported with their fields, like
+// the component and the message_id. Daemons started in the process of
+// a reporter share it, their errors are not reported twice
+func Start(config Config, service string) (func(), error) {
+	if !config.Enabled() || current != nil {
+		return func() {}, nil
+	}
+	d, err := parseDSN(config.DSN)
+	if err != nil {
+		return nil, err
+	}
+	r := newReporter(d, config.Environment, service)
+	current = r
+	logging.AddHook(hook{r})
+	return r.stop, nil
+}
+
+// Recover reports the panic of the calling goroutine and panics again,
+// it must be deferred at the start of the goroutine
+func Recover() {
+	if v := recover(); v != nil {
+		reportPanic(v)
+		panic(v)
+	}
+}
+
+// UnaryServerInterceptor reports the panics of the calls, which
+// fail with an internal error instead of stopping the server
+func UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res interface{}, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			log.WithField("method", info.FullMethod).Warnf("[💥 panic] %v: %v", info.FullMethod, v)
+			reportPanic(v, logrus.Fields{"method": info.FullMethod})
+			err = status.Errorf(codes.Internal, "internal error")
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// reportPanic sends the report of a panic, waiting for its delivery
+// since the process can stop right after
+func reportPanic(v interface{}, fields ...logrus.Fields) {
+	r := current
+	if r == nil {
+		return
+	}
+	e := r.event(logrus.FatalLevel, fmt.Sprintf("panic: %v", v), time.Now(), nil)
+	e.Exception = &exceptions{Values: []exception{{
+		Type:       "panic",
+		Value:      fmt.Sprint(v),
+		Stacktrace: callers(),
+	}}}
+	for _, f := range fields {
+		e.addFields(f)
+	}
+	r.send(e)
+}
+
+// hook reports the log lines at error level or above
+type hook struct {
+	r *reporter
+}
+
+func (h hook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.ErrorLevel, logrus.FatalLevel, logrus.PanicLevel}
+}
+
+func (h hook) Fire(entry *logrus.Entry) error {
+	e := h.r.event(entry.Level, entry.Message, entry.Time, entry.Data)
+	ex := exception{Type: "error", Value: entry.Message, Stacktrace: callers()}
+	if err, ok := entry.Data[logrus.ErrorKey].(error); ok {
+		ex.Type, ex.Value = fmt.Sprintf("%T", err), err.Error()
+	}
+	e.Exception = &exceptions{Values: []exception{ex}}
+	// fatal and panic lines stop the process
+	if entry.Level <= logrus.FatalLevel {
+		h.r.send(e)
+	} else {
+		h.r.queue(e)
+	}
+	return nil
+}
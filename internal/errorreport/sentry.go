@@ -0,0 +1,288 @@
+package errorreport
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maxQueueSize is the max number of events waiting to be sent,
+	// events reported when the queue is full are dropped
+	maxQueueSize = 100
+	// stopTimeout is the max wait for the events left on stop
+	stopTimeout = 10 * time.Second
+)
+
+// dsn is the store endpoint and the key of a Sentry project
+type dsn struct {
+	storeURL  string
+	publicKey string
+	secretKey string
+}
+
+// parseDSN parses a DSN like https://<key>@<host>/<project>, the path
+// before the project is kept for the servers under a path
+func parseDSN(s string) (dsn, error) {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return dsn{}, fmt.Errorf("invalid DSN %v, must be an http or https url", s)
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return dsn{}, fmt.Errorf("invalid DSN %v, missing key", s)
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	i := strings.LastIndex(path, "/")
+	if i < 0 || path[i+1:] == "" {
+		return dsn{}, fmt.Errorf("invalid DSN %v, missing project", s)
+	}
+	secret, _ := u.User.Password()
+	return dsn{
+		storeURL:  fmt.Sprintf("%v://%v%v/api/%v/store/", u.Scheme, u.Host, path[:i], path[i+1:]),
+		publicKey: u.User.Username(),
+		secretKey: secret,
+	}, nil
+}
+
+// reporter sends the events of a service to the store endpoint of a project
+type reporter struct {
+	client      *http.Client
+	dsn         dsn
+	environment string
+	service     string
+	serverName  string
+
+	events   chan *event
+	done     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+func newReporter(d dsn, environment string, service string) *reporter {
+	serverName, _ := os.Hostname()
+	r := &reporter{
+		client:      &http.Client{Timeout: 10 * time.Second},
+		dsn:         d,
+		environment: environment,
+		service:     service,
+		serverName:  serverName,
+		events:      make(chan *event, maxQueueSize),
+		done:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// queue queues an event to be sent
+func (r *reporter) queue(e *event) {
+	select {
+	case r.events <- e:
+	default:
+		log.Warnf("[💥 errors] queue full, dropping %v", e.Message)
+	}
+}
+
+// stop sends the queued events and stops the reporter
+func (r *reporter) stop() {
+	r.stopOnce.Do(func() {
+		close(r.done)
+		select {
+		case <-r.stopped:
+		case <-time.After(stopTimeout):
+			log.Warnf("[💥 errors] events not sent after %v, stopping", stopTimeout)
+		}
+	})
+}
+
+func (r *reporter) run() {
+	defer close(r.stopped)
+	for {
+		select {
+		case e := <-r.events:
+			r.send(e)
+		case <-r.done:
+			for {
+				select {
+				case e := <-r.events:
+					r.send(e)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// send sends e to the project, failures are logged
+func (r *reporter) send(e *event) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		log.Warnf("[💥 errors] cannot encode event: %v", err)
+		return
+	}
+	req, err := http.NewRequest(http.MethodPost, r.dsn.storeURL, bytes.NewReader(body))
+	if err != nil {
+		log.Warnf("[💥 errors] cannot send event: %v", err)
+		return
+	}
+	auth := fmt.Sprintf("Sentry sentry_version=7, sentry_client=kannon/1.0, sentry_key=%v", r.dsn.publicKey)
+	if r.dsn.secretKey != "" {
+		auth += ", sentry_secret=" + r.dsn.secretKey
+	}
+	req.Header.Set("X-Sentry-Auth", auth)
+	req.Header.Set("Content-Type", "application/json")
+	res, err := r.client.Do(req)
+	if err != nil {
+		log.Warnf("[💥 errors] cannot send event: %v", err)
+		return
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		log.Warnf("[💥 errors] cannot send event: %v: %v", res.Status, strings.TrimSpace(string(msg)))
+	}
+}
+
+// event is a Sentry event, the fields of the log lines about a message
+// are tags to search its errors
+type event struct {
+	EventID     string                 `json:"event_id"`
+	Timestamp   string                 `json:"timestamp"`
+	Level       string                 `json:"level"`
+	Logger      string                 `json:"logger,omitempty"`
+	Platform    string                 `json:"platform"`
+	ServerName  string                 `json:"server_name,omitempty"`
+	Environment string                 `json:"environment,omitempty"`
+	Message     string                 `json:"message"`
+	Exception   *exceptions            `json:"exception,omitempty"`
+	Tags        map[string]string      `json:"tags"`
+	Extra       map[string]interface{} `json:"extra,omitempty"`
+}
+
+// tagFields are the log fields sent as tags
+var tagFields = map[string]bool{
+	"component":  true,
+	"message_id": true,
+	"domain":     true,
+	"method":     true,
+}
+
+// exceptions are the errors of an event
+type exceptions struct {
+	Values []exception `json:"values"`
+}
+
+type exception struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value"`
+	Stacktrace *stacktrace `json:"stacktrace,omitempty"`
+}
+
+type stacktrace struct {
+	Frames []frame `json:"frames"`
+}
+
+type frame struct {
+	Function string `json:"function"`
+	Filename string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+func (r *reporter) event(level logrus.Level, message string, t time.Time, fields logrus.Fields) *event {
+	e := &event{
+		EventID:     newEventID(),
+		Timestamp:   t.UTC().Format(time.RFC3339Nano),
+		Level:       eventLevel(level),
+		Platform:    "go",
+		ServerName:  r.serverName,
+		Environment: r.environment,
+		Message:     message,
+		Tags:        map[string]string{"service": r.service},
+	}
+	e.addFields(fields)
+	return e
+}
+
+// addFields adds the fields of a log line as tags and extra data
+func (e *event) addFields(fields logrus.Fields) {
+	for k, v := range fields {
+		if k == logrus.ErrorKey {
+			continue
+		}
+		if tagFields[k] {
+			e.Tags[k] = fmt.Sprint(v)
+			if k == "component" {
+				e.Logger = fmt.Sprint(v)
+			}
+			continue
+		}
+		if e.Extra == nil {
+			e.Extra = make(map[string]interface{})
+		}
+		e.Extra[k] = fmt.Sprint(v)
+	}
+}
+
+func eventLevel(level logrus.Level) string {
+	if level <= logrus.FatalLevel {
+		return "fatal"
+	}
+	if level == logrus.ErrorLevel {
+		return "error"
+	}
+	return "warning"
+}
+
+// callers returns the stack of the caller without the frames of the
+// runtime, logrus and this package, the oldest frame first
+func callers() *stacktrace {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var fs []frame
+	for {
+		f, more := frames.Next()
+		if !internalFrame(f.Function) {
+			fs = append(fs, frame{
+				Function: f.Function,
+				Filename: f.File,
+				Lineno:   f.Line,
+				InApp:    strings.HasPrefix(f.Function, "main.") || strings.HasPrefix(f.Function, "kannon.gyozatech.dev/"),
+			})
+		}
+		if !more {
+			break
+		}
+	}
+	for i, j := 0, len(fs)-1; i < j; i, j = i+1, j-1 {
+		fs[i], fs[j] = fs[j], fs[i]
+	}
+	return &stacktrace{Frames: fs}
+}
+
+func internalFrame(function string) bool {
+	return strings.HasPrefix(function, "runtime.") ||
+		strings.HasPrefix(function, "github.com/sirupsen/logrus") ||
+		strings.HasPrefix(function, "kannon.gyozatech.dev/internal/errorreport.")
+}
+
+func newEventID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
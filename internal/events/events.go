@@ -0,0 +1,204 @@
+package events
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"kannon.gyozatech.dev/generated/pb"
+	"kannon.gyozatech.dev/internal/mailbuilder"
+	"kannon.gyozatech.dev/internal/smtp"
+)
+
+// Type is the type of an email event
+type Type string
+
+const (
+	Accepted     Type = "accepted"
+	Delivered    Type = "delivered"
+	Bounced      Type = "bounced"
+	Opened       Type = "opened"
+	Clicked      Type = "clicked"
+	Unsubscribed Type = "unsubscribed"
+	Complained   Type = "complained"
+)
+
+// Types are all the event types
+var Types = []Type{
+	Accepted,
+	Delivered,
+	Bounced,
+	Opened,
+	Clicked,
+	Unsubscribed,
+	Complained,
+}
+
+// ParseTypes validates event type names
+func ParseTypes(names []string) ([]Type, error) {
+	res := make([]Type, 0, len(names))
+	for _, n := range names {
+		valid := false
+		for _, t := range Types {
+			if string(t) == n {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("invalid event type: %v", n)
+		}
+		res = append(res, Type(n))
+	}
+	return res, nil
+}
+
+// Event is an event of the email sent to a recipient of a message
+type Event struct {
+	// ID identifies the message of the event, a message delivered
+	// again is the same event
+	ID        string                 `json:"-"`
+	Type      Type                   `json:"type"`
+	MessageID string                 `json:"message_id"`
+	Email     string                 `json:"email"`
+	Timestamp time.Time              `json:"timestamp"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// Domain returns the sender domain of the event message,
+// message ids are msg_<id>@<domain>
+func (e Event) Domain() string {
+	i := strings.LastIndex(e.MessageID, "@")
+	if i < 0 {
+		return ""
+	}
+	return e.MessageID[i+1:]
+}
+
+// Parse converts a message published on an emails subject to an event,
+// returns false for subjects without events. The id of the event is the
+// hash of the subject and the data of the message
+func Parse(subject string, data []byte) (Event, bool, error) {
+	e, ok, err := parse(subject, data)
+	if !ok || err != nil {
+		return e, ok, err
+	}
+	h := sha256.Sum256(append([]byte(subject+"\n"), data...))
+	e.ID = hex.EncodeToString(h[:])
+	return e, true, nil
+}
+
+func parse(subject string, data []byte) (Event, bool, error) {
+	switch subject {
+	case "emails.sending":
+		m := pb.EmailToSend{}
+		if err := proto.Unmarshal(data, &m); err != nil {
+			return Event{}, false, err
+		}
+		return emailEvent(Accepted, m.MessageId, time.Now(), nil)
+	case "emails.delivered":
+		m := pb.Delivered{}
+		if err := proto.Unmarshal(data, &m); err != nil {
+			return Event{}, false, err
+		}
+		var data map[string]interface{}
+		if m.Provider != "" {
+			data = map[string]interface{}{"provider": m.Provider}
+		}
+		return emailEvent(Delivered, m.MessageId, m.Timestamp.AsTime(), data)
+	case "emails.error":
+		m := pb.Error{}
+		if err := proto.Unmarshal(data, &m); err != nil {
+			return Event{}, false, err
+		}
+		data := map[string]interface{}{
+			"code":         m.Code,
+			"msg":          m.Msg,
+			"is_permanent": m.IsPermanent,
+			"blocked":      smtp.IsBlock(m.Msg),
+		}
+		if m.Reason != "" {
+			data["reason"] = m.Reason
+		}
+		if m.Provider != "" {
+			data["provider"] = m.Provider
+		}
+		return emailEvent(Bounced, m.MessageId, m.Timestamp.AsTime(), data)
+	case "emails.opened":
+		m := pb.Open{}
+		if err := proto.Unmarshal(data, &m); err != nil {
+			return Event{}, false, err
+		}
+		return Event{
+			Type:      Opened,
+			MessageID: m.MessageId,
+			Email:     m.Email,
+			Timestamp: m.Timestamp.AsTime(),
+			Data: map[string]interface{}{
+				"ip":         m.Ip,
+				"user_agent": m.UserAgent,
+			},
+		}, true, nil
+	case "emails.clicked":
+		m := pb.Click{}
+		if err := proto.Unmarshal(data, &m); err != nil {
+			return Event{}, false, err
+		}
+		return Event{
+			Type:      Clicked,
+			MessageID: m.MessageId,
+			Email:     m.Email,
+			Timestamp: m.Timestamp.AsTime(),
+			Data: map[string]interface{}{
+				"url":        m.Url,
+				"ip":         m.Ip,
+				"user_agent": m.UserAgent,
+			},
+		}, true, nil
+	case "emails.unsubscribed":
+		m := pb.Unsubscribe{}
+		if err := proto.Unmarshal(data, &m); err != nil {
+			return Event{}, false, err
+		}
+		return Event{
+			Type:      Unsubscribed,
+			MessageID: m.MessageId,
+			Email:     m.Email,
+			Timestamp: m.Timestamp.AsTime(),
+		}, true, nil
+	case "emails.complained":
+		m := pb.Complaint{}
+		if err := proto.Unmarshal(data, &m); err != nil {
+			return Event{}, false, err
+		}
+		return Event{
+			Type:      Complained,
+			MessageID: m.MessageId,
+			Email:     m.Email,
+			Timestamp: m.Timestamp.AsTime(),
+			Data: map[string]interface{}{
+				"feedback_type": m.FeedbackType,
+			},
+		}, true, nil
+	default:
+		return Event{}, false, nil
+	}
+}
+
+// emailEvent builds an event of an email message id
+func emailEvent(t Type, emailMessageID string, ts time.Time, data map[string]interface{}) (Event, bool, error) {
+	to, messageID, err := mailbuilder.ParseEmailMessageID(emailMessageID)
+	if err != nil {
+		return Event{}, false, err
+	}
+	return Event{
+		Type:      t,
+		MessageID: messageID,
+		Email:     to,
+		Timestamp: ts,
+		Data:      data,
+	}, true, nil
+}
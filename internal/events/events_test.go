@@ -0,0 +1,72 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"kannon.gyozatech.dev/generated/pb"
+	"kannon.gyozatech.dev/internal/mailbuilder"
+)
+
+func TestEventDomain(t *testing.T) {
+	e := Event{MessageID: "msg_123@kannon.io"}
+	assert.Equal(t, "kannon.io", e.Domain())
+}
+
+func TestParseTypes(t *testing.T) {
+	types, err := ParseTypes([]string{"delivered", "opened"})
+	assert.Nil(t, err)
+	assert.Equal(t, []Type{Delivered, Opened}, types)
+
+	_, err = ParseTypes([]string{"unknown"})
+	assert.NotNil(t, err)
+}
+
+func TestHasType(t *testing.T) {
+	assert.True(t, hasType(nil, Opened))
+	assert.True(t, hasType([]Type{Delivered, Opened}, Opened))
+	assert.False(t, hasType([]Type{Delivered}, Opened))
+}
+
+func TestParseProvider(t *testing.T) {
+	id := mailbuilder.BuildEmailMessageID("test@gmail.com", "msg_1@kannon.io")
+	data, err := proto.Marshal(&pb.Delivered{MessageId: id, Provider: "gmail"})
+	assert.Nil(t, err)
+	e, ok, err := Parse("emails.delivered", data)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"provider": "gmail"}, e.Data)
+
+	data, err = proto.Marshal(&pb.Error{MessageId: id, Code: 550, Msg: "550 5.1.1 user unknown", IsPermanent: true, Provider: "gmail"})
+	assert.Nil(t, err)
+	e, ok, err = Parse("emails.error", data)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "gmail", e.Data["provider"])
+
+	// sandbox deliveries have no provider
+	data, err = proto.Marshal(&pb.Delivered{MessageId: id})
+	assert.Nil(t, err)
+	e, _, err = Parse("emails.delivered", data)
+	assert.Nil(t, err)
+	assert.Nil(t, e.Data)
+}
+
+func TestParseID(t *testing.T) {
+	id := mailbuilder.BuildEmailMessageID("test@gmail.com", "msg_1@kannon.io")
+	data, err := proto.Marshal(&pb.Delivered{MessageId: id, Provider: "gmail"})
+	assert.Nil(t, err)
+	a, _, err := Parse("emails.delivered", data)
+	assert.Nil(t, err)
+	b, _, err := Parse("emails.delivered", data)
+	assert.Nil(t, err)
+	assert.Len(t, a.ID, 64)
+	assert.Equal(t, a.ID, b.ID)
+
+	data, err = proto.Marshal(&pb.Delivered{MessageId: id, Provider: "outlook"})
+	assert.Nil(t, err)
+	c, _, err := Parse("emails.delivered", data)
+	assert.Nil(t, err)
+	assert.NotEqual(t, a.ID, c.ID)
+}
@@ -0,0 +1,79 @@
+package events
+
+import (
+	"context"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/nats.go"
+	"kannon.gyozatech.dev/internal/jetstream"
+	"kannon.gyozatech.dev/internal/logging"
+)
+
+var log = logging.Logger("events")
+
+// tailBuffer is the number of messages of a tail waiting to be handled,
+// slow tails drop the messages over it
+const tailBuffer = 256
+
+// Tail calls handle for every event of domain published from now on,
+// of the given types or of every type when types is empty.
+// Events are read by an ephemeral consumer deleted when ctx is done
+// or handle returns an error
+func Tail(ctx context.Context, nc *nats.Conn, mgr *jsm.Manager, domain string, types []Type, handle func(Event) error) error {
+	inbox := nats.NewInbox()
+	msgs := make(chan *nats.Msg, tailBuffer)
+	sub, err := nc.ChanSubscribe(inbox, msgs)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := sub.Unsubscribe(); err != nil {
+			log.Warnf("cannot unsubscribe tail: %v", err)
+		}
+	}()
+
+	con, err := mgr.NewConsumer(jetstream.Stream,
+		jsm.DeliverySubject(inbox),
+		jsm.StartWithNextReceived(),
+		jsm.AcknowledgeNone(),
+	)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := con.Delete(); err != nil {
+			log.Warnf("cannot delete tail consumer: %v", err)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-msgs:
+			event, ok, err := Parse(msg.Subject, msg.Data)
+			if err != nil {
+				log.Warnf("cannot parse event on %v: %v", msg.Subject, err)
+				continue
+			}
+			if !ok || event.Domain() != domain || !hasType(types, event.Type) {
+				continue
+			}
+			if err := handle(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func hasType(types []Type, t Type) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, tt := range types {
+		if tt == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,270 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/events"
+	"kannon.gyozatech.dev/internal/metrics"
+	"kannon.gyozatech.dev/internal/s3"
+)
+
+// Format is the file format of the exported events
+type Format string
+
+const (
+	CSV     Format = "csv"
+	Parquet Format = "parquet"
+)
+
+// ParseFormat validates a format name, empty is CSV
+func ParseFormat(name string) (Format, error) {
+	switch Format(name) {
+	case "", CSV:
+		return CSV, nil
+	case Parquet:
+		return Parquet, nil
+	}
+	return "", fmt.Errorf("invalid export format: %v", name)
+}
+
+// ContentType returns the media type of the files of f
+func (f Format) ContentType() string {
+	if f == Parquet {
+		return "application/vnd.apache.parquet"
+	}
+	return "text/csv"
+}
+
+// Config configures the daily exports of the events of every domain
+// to S3 or MinIO, like APP_EXPORT_BUCKET, an empty Bucket disables them
+type Config struct {
+	s3.Config
+	Format Format `default:"parquet"`
+}
+
+// Event is an exported event of a message recipient
+type Event struct {
+	Timestamp time.Time
+	MessageID string
+	Email     string
+	Type      string
+	// Data is the JSON object of the data of the event, like the bounce
+	// code or the clicked url
+	Data json.RawMessage
+}
+
+// columns are the columns of the exported files, in the order of Event
+var columns = []string{"timestamp", "message_id", "email", "type", "data"}
+
+// Writer writes the events of an export,
+// Close writes the end of the file and must be called once
+type Writer interface {
+	Write(e Event) error
+	Close() error
+}
+
+// NewWriter returns a Writer of the events to w in format
+func NewWriter(format Format, w io.Writer) (Writer, error) {
+	switch format {
+	case CSV:
+		return newCSVWriter(w)
+	case Parquet:
+		return newParquetWriter(w)
+	}
+	return nil, fmt.Errorf("invalid export format: %v", format)
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return nil, err
+	}
+	return &csvWriter{w: cw}, nil
+}
+
+func (w *csvWriter) Write(e Event) error {
+	return w.w.Write([]string{e.Timestamp.UTC().Format(time.RFC3339Nano), e.MessageID, e.Email, e.Type, string(e.Data)})
+}
+
+func (w *csvWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// pageSize is the number of events read by a single query
+const pageSize = 1000
+
+// Cursor is the position of the daily exports of a domain: the days before
+// Before are exported with the events up to LastEventID
+type Cursor struct {
+	Before      time.Time
+	LastEventID int32
+}
+
+// Manager exports the event history of the messages of the domains
+type Manager interface {
+	// Export writes the events of domain between from and to, of types or of every
+	// type when empty, and returns the number of written events
+	Export(ctx context.Context, domain string, from time.Time, to time.Time, types []events.Type, w Writer) (int64, error)
+	// GetCursor returns the cursor of the daily exports of domain,
+	// the zero Cursor when the domain was never exported
+	GetCursor(ctx context.Context, domain string) (Cursor, error)
+	SetCursor(ctx context.Context, domain string, cursor Cursor) error
+	// LastEventID returns the id of the last recorded event of every domain
+	LastEventID(ctx context.Context) (int32, error)
+	// DaysToExport returns the UTC days before before with events of domain not exported by cursor
+	DaysToExport(ctx context.Context, domain string, cursor Cursor, before time.Time) ([]time.Time, error)
+}
+
+type manager struct {
+	db *sqlc.Queries
+}
+
+// NewExportManager builds an export Manager
+func NewExportManager(db *sql.DB) (Manager, error) {
+	return &manager{
+		db: sqlc.New(metrics.InstrumentDB(db)),
+	}, nil
+}
+
+func (m *manager) Export(ctx context.Context, domain string, from time.Time, to time.Time, types []events.Type, w Writer) (int64, error) {
+	names := make([]string, 0, len(types))
+	for _, t := range types {
+		names = append(names, string(t))
+	}
+	var written int64
+	var after int32
+	for {
+		rows, err := m.db.ExportMessageEvents(ctx, sqlc.ExportMessageEventsParams{
+			Domain:    domain,
+			StartTime: from,
+			EndTime:   to,
+			Types:     names,
+			After:     after,
+			Max:       pageSize,
+		})
+		if err != nil {
+			return written, err
+		}
+		for _, r := range rows {
+			err := w.Write(Event{
+				Timestamp: r.Timestamp,
+				MessageID: r.MessageID,
+				Email:     r.Email,
+				Type:      r.Type,
+				Data:      r.Data,
+			})
+			if err != nil {
+				return written, err
+			}
+			written++
+			after = r.ID
+		}
+		if len(rows) < pageSize {
+			return written, nil
+		}
+	}
+}
+
+func (m *manager) GetCursor(ctx context.Context, domain string) (Cursor, error) {
+	e, err := m.db.GetEventExport(ctx, domain)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Cursor{}, nil
+	}
+	if err != nil {
+		return Cursor{}, err
+	}
+	return Cursor{Before: e.ExportedBefore, LastEventID: e.LastEventID}, nil
+}
+
+func (m *manager) SetCursor(ctx context.Context, domain string, cursor Cursor) error {
+	return m.db.SetEventExport(ctx, sqlc.SetEventExportParams{
+		Domain:         domain,
+		ExportedBefore: cursor.Before,
+		LastEventID:    cursor.LastEventID,
+	})
+}
+
+func (m *manager) LastEventID(ctx context.Context) (int32, error) {
+	return m.db.GetLastMessageEventID(ctx)
+}
+
+func (m *manager) DaysToExport(ctx context.Context, domain string, cursor Cursor, before time.Time) ([]time.Time, error) {
+	return m.db.GetEventDaysToExport(ctx, sqlc.GetEventDaysToExportParams{
+		Domain:         domain,
+		Before:         before,
+		ExportedBefore: cursor.Before,
+		LastEventID:    cursor.LastEventID,
+	})
+}
+
+// DayKey returns the key of the export of the events of a domain of the UTC day of day,
+// like kannon.io/events/2021-08-12.parquet
+func DayKey(domain string, day time.Time, format Format) string {
+	return fmt.Sprintf("%v/events/%v.%v", domain, day.UTC().Format("2006-01-02"), format)
+}
+
+// ExportDays uploads to b the events of a domain of every UTC day before the day of now
+// not exported yet, like the days missed while the exports were stopped, and exports again
+// the days with events recorded after their export. It returns the number of exported
+// events by key, the cursor of the domain moves only when every day is uploaded
+func ExportDays(ctx context.Context, m Manager, b s3.Bucket, format Format, domain string, now time.Time) (map[string]int64, error) {
+	cursor, err := m.GetCursor(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+	// the events recorded from now on are exported by the next run
+	last, err := m.LastEventID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	before := now.UTC().Truncate(24 * time.Hour)
+	days, err := m.DaysToExport(ctx, domain, cursor, before)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]int64, len(days))
+	for _, day := range days {
+		written, err := ExportDay(ctx, m, b, format, domain, day)
+		if err != nil {
+			return res, err
+		}
+		res[DayKey(domain, day, format)] = written
+	}
+	return res, m.SetCursor(ctx, domain, Cursor{Before: before, LastEventID: last})
+}
+
+// ExportDay uploads to b the events of a domain of the UTC day of day, replacing
+// the previous export of the day. It returns the number of exported events
+func ExportDay(ctx context.Context, m Manager, b s3.Bucket, format Format, domain string, day time.Time) (int64, error) {
+	var buf bytes.Buffer
+	w, err := NewWriter(format, &buf)
+	if err != nil {
+		return 0, err
+	}
+	from := day.UTC().Truncate(24 * time.Hour)
+	written, err := m.Export(ctx, domain, from, from.Add(24*time.Hour), nil, w)
+	if err != nil {
+		return written, err
+	}
+	if err := w.Close(); err != nil {
+		return written, err
+	}
+	if err := b.Put(ctx, DayKey(domain, day, format), buf.Bytes(), format.ContentType()); err != nil {
+		return written, err
+	}
+	return written, nil
+}
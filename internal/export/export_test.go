@@ -0,0 +1,293 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"kannon.gyozatech.dev/internal/events"
+	"kannon.gyozatech.dev/internal/s3"
+)
+
+var testEvents = []Event{
+	{Timestamp: time.Date(2021, 8, 12, 10, 0, 0, 0, time.UTC), MessageID: "msg_1@kannon.io", Email: "test@test.com", Type: "delivered", Data: json.RawMessage(`{}`)},
+	{Timestamp: time.Date(2021, 8, 12, 10, 5, 0, 0, time.UTC), MessageID: "msg_1@kannon.io", Email: "test@test.com", Type: "clicked", Data: json.RawMessage(`{"url":"https://kannon.io","ip":"1.1.1.1"}`)},
+}
+
+func TestCSV(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(CSV, &buf)
+	assert.Nil(t, err)
+	for _, e := range testEvents {
+		assert.Nil(t, w.Write(e))
+	}
+	assert.Nil(t, w.Close())
+	assert.Equal(t, "timestamp,message_id,email,type,data\n"+
+		"2021-08-12T10:00:00Z,msg_1@kannon.io,test@test.com,delivered,{}\n"+
+		`2021-08-12T10:05:00Z,msg_1@kannon.io,test@test.com,clicked,"{""url"":""https://kannon.io"",""ip"":""1.1.1.1""}"`+"\n", buf.String())
+}
+
+func TestParquet(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(Parquet, &buf)
+	assert.Nil(t, err)
+	for _, e := range testEvents {
+		assert.Nil(t, w.Write(e))
+	}
+	assert.Nil(t, w.Close())
+
+	file := buf.Bytes()
+	assert.Equal(t, parquetMagic, string(file[:4]))
+	assert.Equal(t, parquetMagic, string(file[len(file)-4:]))
+	length := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	footer := bytes.NewReader(file[len(file)-8-length : len(file)-8])
+	meta := readThriftStruct(t, footer)
+	assert.Equal(t, 0, footer.Len())
+
+	assert.Equal(t, int64(2), meta[3])
+	schema := meta[2].([]interface{})
+	assert.Len(t, schema, len(columns)+1)
+	for i, name := range columns {
+		assert.Equal(t, name, string(schema[i+1].(map[int16]interface{})[4].([]byte)))
+	}
+
+	groups := meta[4].([]interface{})
+	assert.Len(t, groups, 1)
+	chunks := groups[0].(map[int16]interface{})[1].([]interface{})
+	assert.Len(t, chunks, len(columns))
+
+	values := readPage(t, file, chunks[0].(map[int16]interface{}))
+	assert.Equal(t, uint64(testEvents[1].Timestamp.UnixNano()/1e6), binary.LittleEndian.Uint64(values[8:]))
+
+	values = readPage(t, file, chunks[4].(map[int16]interface{}))
+	var data []string
+	for len(values) > 0 {
+		n := binary.LittleEndian.Uint32(values)
+		data = append(data, string(values[4:4+n]))
+		values = values[4+n:]
+	}
+	assert.Equal(t, []string{"{}", `{"url":"https://kannon.io","ip":"1.1.1.1"}`}, data)
+}
+
+// update rewrites the golden files of the tests, go test ./internal/export -update
+var update = flag.Bool("update", false, "update the golden files")
+
+// TestParquetGolden compares the file of testEvents with testdata/events.parquet.
+// A golden file updated with -update is checked with a parquet reader before it is committed, like
+// python3 -c 'import pyarrow.parquet as pq; print(pq.read_table("testdata/events.parquet"))'
+func TestParquetGolden(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(Parquet, &buf)
+	assert.Nil(t, err)
+	for _, e := range testEvents {
+		assert.Nil(t, w.Write(e))
+	}
+	assert.Nil(t, w.Close())
+
+	golden := filepath.Join("testdata", "events.parquet")
+	if *update {
+		assert.Nil(t, ioutil.WriteFile(golden, buf.Bytes(), 0644))
+	}
+	want, err := ioutil.ReadFile(golden)
+	assert.Nil(t, err)
+	assert.Equal(t, want, buf.Bytes())
+}
+
+func TestParquetEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(Parquet, &buf)
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+	file := buf.Bytes()
+	length := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	meta := readThriftStruct(t, bytes.NewReader(file[len(file)-8-length:len(file)-8]))
+	assert.Equal(t, int64(0), meta[3])
+	assert.Len(t, meta[4], 0)
+}
+
+func TestParseFormat(t *testing.T) {
+	f, err := ParseFormat("")
+	assert.Nil(t, err)
+	assert.Equal(t, CSV, f)
+	f, err = ParseFormat("parquet")
+	assert.Nil(t, err)
+	assert.Equal(t, Parquet, f)
+	_, err = ParseFormat("xlsx")
+	assert.NotNil(t, err)
+}
+
+func TestDayKey(t *testing.T) {
+	day := time.Date(2021, 8, 12, 23, 0, 0, 0, time.FixedZone("CEST", 2*3600))
+	assert.Equal(t, "kannon.io/events/2021-08-12.parquet", DayKey("kannon.io", day, Parquet))
+}
+
+// fakeManager exports the events of days, events are recorded
+// on a day with the next id
+type fakeManager struct {
+	events map[string]int32
+	last   int32
+	cursor Cursor
+}
+
+func (f *fakeManager) record(day string) {
+	f.last++
+	f.events[day] = f.last
+}
+
+func (f *fakeManager) Export(ctx context.Context, domain string, from time.Time, to time.Time, types []events.Type, w Writer) (int64, error) {
+	return 1, nil
+}
+
+func (f *fakeManager) GetCursor(ctx context.Context, domain string) (Cursor, error) {
+	return f.cursor, nil
+}
+
+func (f *fakeManager) SetCursor(ctx context.Context, domain string, cursor Cursor) error {
+	f.cursor = cursor
+	return nil
+}
+
+func (f *fakeManager) LastEventID(ctx context.Context) (int32, error) {
+	return f.last, nil
+}
+
+func (f *fakeManager) DaysToExport(ctx context.Context, domain string, cursor Cursor, before time.Time) ([]time.Time, error) {
+	var res []time.Time
+	for d, id := range f.events {
+		day, _ := time.Parse("2006-01-02", d)
+		if day.Before(before) && (!day.Before(cursor.Before) || id > cursor.LastEventID) {
+			res = append(res, day)
+		}
+	}
+	return res, nil
+}
+
+// fakeBucket keeps the keys of the uploads, failing with err
+type fakeBucket struct {
+	s3.Bucket
+	keys []string
+	err  error
+}
+
+func (f *fakeBucket) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.keys = append(f.keys, key)
+	return nil
+}
+
+func TestExportDays(t *testing.T) {
+	ctx := context.Background()
+	m := &fakeManager{events: make(map[string]int32)}
+	m.record("2021-08-10")
+	m.record("2021-08-11")
+	m.record("2021-08-12")
+
+	// the purger was stopped: every day before today is exported
+	b := &fakeBucket{err: errors.New("unavailable")}
+	now := time.Date(2021, 8, 12, 10, 0, 0, 0, time.UTC)
+	_, err := ExportDays(ctx, m, b, CSV, "kannon.io", now)
+	assert.NotNil(t, err)
+	assert.Equal(t, Cursor{}, m.cursor)
+
+	b.err = nil
+	exported, err := ExportDays(ctx, m, b, CSV, "kannon.io", now)
+	assert.Nil(t, err)
+	assert.Len(t, exported, 2)
+	assert.ElementsMatch(t, []string{"kannon.io/events/2021-08-10.csv", "kannon.io/events/2021-08-11.csv"}, b.keys)
+
+	// the next day exports today and the days with late events
+	b.keys = nil
+	m.record("2021-08-10")
+	exported, err = ExportDays(ctx, m, b, CSV, "kannon.io", now.Add(24*time.Hour))
+	assert.Nil(t, err)
+	assert.Len(t, exported, 2)
+	assert.ElementsMatch(t, []string{"kannon.io/events/2021-08-10.csv", "kannon.io/events/2021-08-12.csv"}, b.keys)
+
+	b.keys = nil
+	_, err = ExportDays(ctx, m, b, CSV, "kannon.io", now.Add(24*time.Hour))
+	assert.Nil(t, err)
+	assert.Empty(t, b.keys)
+}
+
+// readPage returns the uncompressed values of the data page of a column chunk
+func readPage(t *testing.T, file []byte, chunk map[int16]interface{}) []byte {
+	meta := chunk[3].(map[int16]interface{})
+	r := bytes.NewReader(file[meta[9].(int64):])
+	header := readThriftStruct(t, r)
+	assert.Equal(t, int64(2), header[5].(map[int16]interface{})[1])
+	compressed := make([]byte, header[3].(int64))
+	_, err := r.Read(compressed)
+	assert.Nil(t, err)
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	assert.Nil(t, err)
+	values, err := ioutil.ReadAll(zr)
+	assert.Nil(t, err)
+	assert.Len(t, values, int(header[2].(int64)))
+	return values
+}
+
+// readThriftStruct decodes a thrift compact struct, the values are int64,
+// []byte, []interface{} and map[int16]interface{} of the nested structs
+func readThriftStruct(t *testing.T, r *bytes.Reader) map[int16]interface{} {
+	res := make(map[int16]interface{})
+	var id int16
+	for {
+		b, err := r.ReadByte()
+		assert.Nil(t, err)
+		if b == 0 {
+			return res
+		}
+		if delta := int16(b >> 4); delta != 0 {
+			id += delta
+		} else {
+			v, err := binary.ReadVarint(r)
+			assert.Nil(t, err)
+			id = int16(v)
+		}
+		res[id] = readThriftValue(t, r, b&0x0f)
+	}
+}
+
+func readThriftValue(t *testing.T, r *bytes.Reader, typ byte) interface{} {
+	switch typ {
+	case thriftI32, thriftI64:
+		v, err := binary.ReadVarint(r)
+		assert.Nil(t, err)
+		return v
+	case thriftBinary:
+		n, err := binary.ReadUvarint(r)
+		assert.Nil(t, err)
+		v := make([]byte, n)
+		_, err = r.Read(v)
+		assert.Nil(t, err)
+		return v
+	case thriftList:
+		b, err := r.ReadByte()
+		assert.Nil(t, err)
+		n := uint64(b >> 4)
+		if n == 15 {
+			n, err = binary.ReadUvarint(r)
+			assert.Nil(t, err)
+		}
+		list := []interface{}{}
+		for i := uint64(0); i < n; i++ {
+			list = append(list, readThriftValue(t, r, b&0x0f))
+		}
+		return list
+	case thriftStruct:
+		return readThriftStruct(t, r)
+	}
+	t.Fatalf("unexpected thrift type %v", typ)
+	return nil
+}
@@ -0,0 +1,341 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+)
+
+// parquetMagic starts and ends the parquet files
+const parquetMagic = "PAR1"
+
+// rowGroupSize is the number of events of a row group, buffered in memory
+const rowGroupSize = 10000
+
+// the values of the parquet thrift enums, see parquet.thrift of apache/parquet-format
+const (
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetRequired = 0
+
+	parquetUTF8            = 0
+	parquetTimestampMillis = 9
+
+	parquetPlain = 0
+	parquetRLE   = 3
+
+	parquetGzip = 2
+
+	parquetDataPage = 0
+)
+
+// columnChunk is the metadata of a column written in a row group
+type columnChunk struct {
+	offset           int64
+	uncompressedSize int64
+	compressedSize   int64
+}
+
+type rowGroup struct {
+	columns []columnChunk
+	size    int64
+	rows    int64
+}
+
+// parquetWriter writes the events in row groups of rowGroupSize events, every
+// column chunk is a single gzip compressed data page of plain encoded values.
+// The columns are required, so the pages have no repetition and definition levels
+type parquetWriter struct {
+	w      io.Writer
+	offset int64
+	events []Event
+	groups []rowGroup
+}
+
+func newParquetWriter(w io.Writer) (*parquetWriter, error) {
+	pw := &parquetWriter{w: w}
+	if err := pw.write([]byte(parquetMagic)); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+func (w *parquetWriter) Write(e Event) error {
+	w.events = append(w.events, e)
+	if len(w.events) < rowGroupSize {
+		return nil
+	}
+	return w.flush()
+}
+
+func (w *parquetWriter) Close() error {
+	if len(w.events) > 0 {
+		if err := w.flush(); err != nil {
+			return err
+		}
+	}
+	footer := w.fileMetaData()
+	if err := w.write(footer); err != nil {
+		return err
+	}
+	length := make([]byte, 4)
+	binary.LittleEndian.PutUint32(length, uint32(len(footer)))
+	if err := w.write(length); err != nil {
+		return err
+	}
+	return w.write([]byte(parquetMagic))
+}
+
+func (w *parquetWriter) write(data []byte) error {
+	n, err := w.w.Write(data)
+	w.offset += int64(n)
+	return err
+}
+
+// flush writes the buffered events as a row group
+func (w *parquetWriter) flush() error {
+	group := rowGroup{rows: int64(len(w.events))}
+	for c := range columns {
+		var values bytes.Buffer
+		for _, e := range w.events {
+			if c == 0 {
+				// timestamps are milliseconds since the epoch
+				var v [8]byte
+				binary.LittleEndian.PutUint64(v[:], uint64(e.Timestamp.UnixNano()/1e6))
+				values.Write(v[:])
+				continue
+			}
+			writeByteArray(&values, columnValue(e, c))
+		}
+		var compressed bytes.Buffer
+		zw := gzip.NewWriter(&compressed)
+		if _, err := zw.Write(values.Bytes()); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+
+		var header thriftWriter
+		header.begin()
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(values.Len()))
+		header.i32(3, int32(compressed.Len()))
+		header.structField(5, func() {
+			header.i32(1, int32(len(w.events)))
+			header.i32(2, parquetPlain)
+			header.i32(3, parquetRLE)
+			header.i32(4, parquetRLE)
+		})
+		header.end()
+
+		chunk := columnChunk{
+			offset:           w.offset,
+			uncompressedSize: int64(header.buf.Len() + values.Len()),
+			compressedSize:   int64(header.buf.Len() + compressed.Len()),
+		}
+		if err := w.write(header.buf.Bytes()); err != nil {
+			return err
+		}
+		if err := w.write(compressed.Bytes()); err != nil {
+			return err
+		}
+		group.columns = append(group.columns, chunk)
+		group.size += chunk.uncompressedSize
+	}
+	w.groups = append(w.groups, group)
+	w.events = w.events[:0]
+	return nil
+}
+
+// columnValue returns the value of the byte array column c of e
+func columnValue(e Event, c int) []byte {
+	switch columns[c] {
+	case "message_id":
+		return []byte(e.MessageID)
+	case "email":
+		return []byte(e.Email)
+	case "type":
+		return []byte(e.Type)
+	}
+	if len(e.Data) == 0 {
+		return []byte("{}")
+	}
+	return e.Data
+}
+
+// writeByteArray writes a plain encoded byte array, its length and its bytes
+func writeByteArray(buf *bytes.Buffer, v []byte) {
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(v)))
+	buf.Write(length[:])
+	buf.Write(v)
+}
+
+// columnType returns the physical and the converted type of the column c
+func columnType(c int) (int32, int32) {
+	if c == 0 {
+		return parquetInt64, parquetTimestampMillis
+	}
+	return parquetByteArray, parquetUTF8
+}
+
+// fileMetaData returns the footer of the file, the FileMetaData of the schema and the row groups
+func (w *parquetWriter) fileMetaData() []byte {
+	var rows int64
+	for _, g := range w.groups {
+		rows += g.rows
+	}
+
+	var t thriftWriter
+	t.begin()
+	t.i32(1, 1)
+	t.structList(2, len(columns)+1, func(i int) {
+		if i == 0 {
+			t.binary(4, []byte("schema"))
+			t.i32(5, int32(len(columns)))
+			return
+		}
+		typ, converted := columnType(i - 1)
+		t.i32(1, typ)
+		t.i32(3, parquetRequired)
+		t.binary(4, []byte(columns[i-1]))
+		t.i32(6, converted)
+	})
+	t.i64(3, rows)
+	t.structList(4, len(w.groups), func(i int) {
+		g := w.groups[i]
+		t.structList(1, len(g.columns), func(c int) {
+			chunk := g.columns[c]
+			typ, _ := columnType(c)
+			t.i64(2, chunk.offset)
+			t.structField(3, func() {
+				t.i32(1, typ)
+				t.i32List(2, []int32{parquetPlain, parquetRLE})
+				t.binaryList(3, [][]byte{[]byte(columns[c])})
+				t.i32(4, parquetGzip)
+				t.i64(5, g.rows)
+				t.i64(6, chunk.uncompressedSize)
+				t.i64(7, chunk.compressedSize)
+				t.i64(9, chunk.offset)
+			})
+		})
+		t.i64(2, g.size)
+		t.i64(3, g.rows)
+	})
+	t.binary(6, []byte("kannon"))
+	t.end()
+	return t.buf.Bytes()
+}
+
+// the types of the fields of the thrift compact protocol
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs with the thrift compact protocol,
+// the encoding of the parquet metadata
+type thriftWriter struct {
+	buf bytes.Buffer
+	// last are the ids of the last fields of the structs being written
+	last []int16
+}
+
+// begin starts a struct, its fields are written until end
+func (t *thriftWriter) begin() {
+	t.last = append(t.last, 0)
+}
+
+// end ends the last struct with a stop field
+func (t *thriftWriter) end() {
+	t.buf.WriteByte(0)
+	t.last = t.last[:len(t.last)-1]
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	delta := id - t.last[len(t.last)-1]
+	if delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(int64(id))
+	}
+	t.last[len(t.last)-1] = id
+}
+
+// varint writes a zigzag varint
+func (t *thriftWriter) varint(v int64) {
+	t.uvarint(uint64(v<<1) ^ uint64(v>>63))
+}
+
+func (t *thriftWriter) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	t.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (t *thriftWriter) listHeader(n int, typ byte) {
+	if n < 15 {
+		t.buf.WriteByte(byte(n)<<4 | typ)
+		return
+	}
+	t.buf.WriteByte(0xf0 | typ)
+	t.uvarint(uint64(n))
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(v)
+}
+
+func (t *thriftWriter) binary(id int16, v []byte) {
+	t.field(id, thriftBinary)
+	t.uvarint(uint64(len(v)))
+	t.buf.Write(v)
+}
+
+func (t *thriftWriter) i32List(id int16, vs []int32) {
+	t.field(id, thriftList)
+	t.listHeader(len(vs), thriftI32)
+	for _, v := range vs {
+		t.varint(int64(v))
+	}
+}
+
+func (t *thriftWriter) binaryList(id int16, vs [][]byte) {
+	t.field(id, thriftList)
+	t.listHeader(len(vs), thriftBinary)
+	for _, v := range vs {
+		t.uvarint(uint64(len(v)))
+		t.buf.Write(v)
+	}
+}
+
+// structField writes a struct field whose fields are written by fn
+func (t *thriftWriter) structField(id int16, fn func()) {
+	t.field(id, thriftStruct)
+	t.begin()
+	fn()
+	t.end()
+}
+
+// structList writes a list of n structs, the fields of the struct i are written by fn(i)
+func (t *thriftWriter) structList(id int16, n int, fn func(i int)) {
+	t.field(id, thriftList)
+	t.listHeader(n, thriftStruct)
+	for i := 0; i < n; i++ {
+		t.begin()
+		fn(i)
+		t.end()
+	}
+}
@@ -0,0 +1,195 @@
+package gateway
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// PathPrefix is the prefix of the paths of the methods, like /v1/kannon.Mailer/SendHTML
+const PathPrefix = "/v1/"
+
+// maxBodySize is the max size of the JSON body of a request
+const maxBodySize = 32 << 20
+
+// forwardedHeaders are the headers passed to the services as metadata
+var forwardedHeaders = []string{"authorization", "traceparent"}
+
+// Service is a gRPC service served over HTTP
+type Service struct {
+	Desc *grpc.ServiceDesc
+	// Impl is the server of the service, like the server registered with grpc.Server
+	Impl interface{}
+	// Interceptor intercepts the calls like the interceptor of the gRPC server, nil for none
+	Interceptor grpc.UnaryServerInterceptor
+}
+
+type method struct {
+	service  Service
+	desc     grpc.MethodDesc
+	fullName string
+}
+
+// Handler serves the unary methods of gRPC services as JSON over HTTP: the JSON
+// body of POST /v1/<service>/<method> is the request of the method and the
+// response is its JSON response. Errors are a JSON status with their gRPC code,
+// the authorization and traceparent headers are passed to the service as metadata
+type Handler struct {
+	methods map[string]method
+	openAPI []byte
+}
+
+// NewHandler returns the Handler of services, their descriptors must be registered
+func NewHandler(services ...Service) (*Handler, error) {
+	h := &Handler{methods: make(map[string]method)}
+	var descs []protoreflect.ServiceDescriptor
+	for _, s := range services {
+		d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(s.Desc.ServiceName))
+		if err != nil {
+			return nil, fmt.Errorf("cannot find service %v: %w", s.Desc.ServiceName, err)
+		}
+		sd, ok := d.(protoreflect.ServiceDescriptor)
+		if !ok {
+			return nil, fmt.Errorf("%v is not a service", s.Desc.ServiceName)
+		}
+		descs = append(descs, sd)
+		for _, m := range s.Desc.Methods {
+			fullName := "/" + s.Desc.ServiceName + "/" + m.MethodName
+			h.methods[fullName] = method{service: s, desc: m, fullName: fullName}
+		}
+	}
+
+	openAPI, err := OpenAPI(descs)
+	if err != nil {
+		return nil, err
+	}
+	h.openAPI = openAPI
+	return h, nil
+}
+
+// ServeHTTP calls the method of the path of r
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m, ok := h.methods["/"+strings.TrimPrefix(r.URL.Path, PathPrefix)]
+	if !ok {
+		writeError(w, http.StatusNotFound, status.Errorf(codes.NotFound, "unknown method %v", r.URL.Path))
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, status.Errorf(codes.Unimplemented, "method %v not allowed", r.Method))
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+	if err != nil {
+		writeError(w, 0, status.Errorf(codes.InvalidArgument, "cannot read body: %v", err))
+		return
+	}
+	if len(body) > maxBodySize {
+		writeError(w, 0, status.Errorf(codes.ResourceExhausted, "body larger than %v bytes", maxBodySize))
+		return
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		body = []byte("{}")
+	}
+	dec := func(v interface{}) error {
+		if err := protojson.Unmarshal(body, v.(proto.Message)); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid body: %v", err)
+		}
+		return nil
+	}
+
+	ctx := grpc.NewContextWithServerTransportStream(r.Context(), transportStream{method: m.fullName})
+	md := metadata.MD{}
+	for _, header := range forwardedHeaders {
+		if v := r.Header.Get(header); v != "" {
+			md.Set(header, v)
+		}
+	}
+	if len(md) > 0 {
+		ctx = metadata.NewIncomingContext(ctx, md)
+	}
+	res, err := m.desc.Handler(m.service.Impl, ctx, dec, m.service.Interceptor)
+	if err != nil {
+		writeError(w, 0, err)
+		return
+	}
+	data, err := protojson.Marshal(res.(proto.Message))
+	if err != nil {
+		writeError(w, 0, status.Errorf(codes.Internal, "cannot encode response: %v", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// ServeOpenAPI serves the OpenAPI document of the methods
+func (h *Handler) ServeOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(h.openAPI)
+}
+
+// writeError writes the status of err with code, the HTTP status
+// of the gRPC code of err when 0
+func writeError(w http.ResponseWriter, code int, err error) {
+	s := status.Convert(err)
+	if code == 0 {
+		code = HTTPStatus(s.Code())
+	}
+	data, _ := protojson.Marshal(s.Proto())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+// HTTPStatus returns the HTTP status of a gRPC code
+func HTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.OutOfRange, codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// transportStream is the stream of a call, grpc.Method returns its method
+type transportStream struct {
+	method string
+}
+
+func (s transportStream) Method() string                  { return s.method }
+func (s transportStream) SetHeader(md metadata.MD) error  { return nil }
+func (s transportStream) SendHeader(md metadata.MD) error { return nil }
+func (s transportStream) SetTrailer(md metadata.MD) error { return nil }
+
+var _ grpc.ServerTransportStream = transportStream{}
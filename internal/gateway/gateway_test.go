@@ -0,0 +1,113 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"kannon.gyozatech.dev/generated/pb"
+)
+
+type testMailer struct {
+	pb.UnimplementedMailerServer
+}
+
+func (testMailer) GetQuota(ctx context.Context, in *pb.GetQuotaRequest) (*pb.Quota, error) {
+	m, _ := metadata.FromIncomingContext(ctx)
+	if auths := m.Get("authorization"); len(auths) != 1 || auths[0] != "Basic key" {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid or wrong auth")
+	}
+	if method, _ := grpc.Method(ctx); method != "/kannon.Mailer/GetQuota" {
+		return nil, status.Errorf(codes.Internal, "wrong method: %v", method)
+	}
+	return &pb.Quota{Daily: 100, DailyUsed: 10}, nil
+}
+
+func (testMailer) GetMessageStatus(ctx context.Context, in *pb.GetMessageStatusRequest) (*pb.MessageStatus, error) {
+	return nil, status.Errorf(codes.NotFound, "cannot find message %v", in.MessageId)
+}
+
+func TestHandler(t *testing.T) {
+	var intercepted []string
+	h, err := NewHandler(Service{
+		Desc: &pb.Mailer_ServiceDesc,
+		Impl: testMailer{},
+		Interceptor: func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			intercepted = append(intercepted, info.FullMethod)
+			return handler(ctx, req)
+		},
+	})
+	assert.Nil(t, err)
+
+	call := func(method, path, auth, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := call(http.MethodPost, "/v1/kannon.Mailer/GetQuota", "Basic key", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var quota map[string]interface{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &quota))
+	assert.Equal(t, map[string]interface{}{"daily": 100.0, "dailyUsed": 10.0}, quota)
+	assert.Equal(t, []string{"/kannon.Mailer/GetQuota"}, intercepted)
+
+	w = call(http.MethodPost, "/v1/kannon.Mailer/GetQuota", "", "{}")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid or wrong auth")
+
+	w = call(http.MethodPost, "/v1/kannon.Mailer/GetMessageStatus", "", `{"message_id": "message/abc@kannon.io"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "message/abc@kannon.io")
+
+	w = call(http.MethodPost, "/v1/kannon.Mailer/GetMessageStatus", "", `{"unknown": 1}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = call(http.MethodGet, "/v1/kannon.Mailer/GetQuota", "", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	// streams are not served
+	w = call(http.MethodPost, "/v1/kannon.Mailer/StreamEvents", "", "{}")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestOpenAPI(t *testing.T) {
+	h, err := NewHandler(Service{Desc: &pb.Mailer_ServiceDesc, Impl: testMailer{}})
+	assert.Nil(t, err)
+
+	w := httptest.NewRecorder()
+	h.ServeOpenAPI(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	var doc struct {
+		OpenAPI    string                            `json:"openapi"`
+		Paths      map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]map[string]interface{} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Contains(t, doc.Paths, "/v1/kannon.Mailer/SendHTML")
+	assert.NotContains(t, doc.Paths, "/v1/kannon.Mailer/StreamEvents")
+
+	send := doc.Components.Schemas["kannon.SendHTMLRequest"].Properties
+	assert.Equal(t, "array", send["to"]["type"])
+	assert.Equal(t, "object", send["headers"]["type"])
+	assert.Equal(t, "#/components/schemas/kannon.Sender", send["sender"]["$ref"])
+	assert.Equal(t, "date-time", send["scheduledTime"]["format"])
+	assert.Equal(t, "string", send["priority"]["type"])
+}
@@ -0,0 +1,160 @@
+package gateway
+
+import (
+	"encoding/json"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// schema is a schema object of OpenAPI 3
+type schema map[string]interface{}
+
+// OpenAPI returns the OpenAPI 3 document of the unary methods of services,
+// their messages are encoded like protojson encodes them
+func OpenAPI(services []protoreflect.ServiceDescriptor) ([]byte, error) {
+	schemas := make(map[string]schema)
+	paths := make(map[string]interface{})
+	for _, s := range services {
+		methods := s.Methods()
+		for i := 0; i < methods.Len(); i++ {
+			m := methods.Get(i)
+			if m.IsStreamingClient() || m.IsStreamingServer() {
+				continue
+			}
+			paths[PathPrefix+string(s.FullName())+"/"+string(m.Name())] = map[string]interface{}{
+				"post": map[string]interface{}{
+					"operationId": string(s.Name()) + "_" + string(m.Name()),
+					"tags":        []string{string(s.Name())},
+					"requestBody": map[string]interface{}{
+						"required": true,
+						"content":  jsonContent(messageSchema(m.Input(), schemas)),
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "OK",
+							"content":     jsonContent(messageSchema(m.Output(), schemas)),
+						},
+						"default": map[string]interface{}{
+							"description": "Error",
+							"content":     jsonContent(schema{"$ref": "#/components/schemas/Status"}),
+						},
+					},
+				},
+			}
+		}
+	}
+	schemas["Status"] = schema{
+		"type": "object",
+		"properties": map[string]schema{
+			"code":    {"type": "integer", "format": "int32", "description": "gRPC code"},
+			"message": {"type": "string"},
+			"details": {"type": "array", "items": schema{"type": "object"}},
+		},
+	}
+
+	doc := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "Kannon",
+			"version": "v1",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearer": map[string]interface{}{"type": "http", "scheme": "bearer"},
+				"basic":  map[string]interface{}{"type": "http", "scheme": "basic"},
+			},
+		},
+		"security": []map[string][]string{{"bearer": {}}, {"basic": {}}},
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func jsonContent(s schema) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": s},
+	}
+}
+
+// messageSchema returns the schema of a message, adding the schemas
+// of its messages to schemas
+func messageSchema(m protoreflect.MessageDescriptor, schemas map[string]schema) schema {
+	switch m.FullName() {
+	case "google.protobuf.Timestamp":
+		return schema{"type": "string", "format": "date-time"}
+	case "google.protobuf.Duration":
+		return schema{"type": "string", "example": "1.5s"}
+	case "google.protobuf.Empty", "google.protobuf.Struct":
+		return schema{"type": "object"}
+	case "google.protobuf.Value":
+		// any JSON value
+		return schema{}
+	}
+
+	name := string(m.FullName())
+	ref := schema{"$ref": "#/components/schemas/" + name}
+	if _, ok := schemas[name]; ok {
+		return ref
+	}
+	// the schema is set before its fields for recursive messages
+	s := schema{"type": "object"}
+	schemas[name] = s
+
+	properties := make(map[string]schema)
+	fields := m.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		f := fields.Get(i)
+		properties[f.JSONName()] = fieldSchema(f, schemas)
+	}
+	if len(properties) > 0 {
+		s["properties"] = properties
+	}
+	return ref
+}
+
+// fieldSchema returns the schema of a field
+func fieldSchema(f protoreflect.FieldDescriptor, schemas map[string]schema) schema {
+	if f.IsMap() {
+		return schema{
+			"type":                 "object",
+			"additionalProperties": valueSchema(f.MapValue(), schemas),
+		}
+	}
+	if f.IsList() {
+		return schema{"type": "array", "items": valueSchema(f, schemas)}
+	}
+	return valueSchema(f, schemas)
+}
+
+// valueSchema returns the schema of a single value of a field
+func valueSchema(f protoreflect.FieldDescriptor, schemas map[string]schema) schema {
+	switch f.Kind() {
+	case protoreflect.BoolKind:
+		return schema{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return schema{"type": "integer", "format": "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return schema{"type": "integer", "format": "int64", "minimum": 0}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		// 64 bit integers are strings in JSON
+		return schema{"type": "string", "format": "int64"}
+	case protoreflect.FloatKind:
+		return schema{"type": "number", "format": "float"}
+	case protoreflect.DoubleKind:
+		return schema{"type": "number", "format": "double"}
+	case protoreflect.StringKind:
+		return schema{"type": "string"}
+	case protoreflect.BytesKind:
+		return schema{"type": "string", "format": "byte"}
+	case protoreflect.EnumKind:
+		values := f.Enum().Values()
+		var names []string
+		for i := 0; i < values.Len(); i++ {
+			names = append(names, string(values.Get(i).Name()))
+		}
+		return schema{"type": "string", "enum": names}
+	}
+	return messageSchema(f.Message(), schemas)
+}
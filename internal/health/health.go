@@ -0,0 +1,110 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// checkTimeout is the max time of a check
+const checkTimeout = 3 * time.Second
+
+// Check checks a dependency of a daemon, like the database
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// DB checks the connection to the database
+func DB(db *sql.DB) Check {
+	return Check{Name: "db", Check: db.PingContext}
+}
+
+// Dial checks that address accepts TCP connections, the result
+// is kept for interval to not connect on every probe
+func Dial(name string, address string, interval time.Duration) Check {
+	var mu sync.Mutex
+	var checked time.Time
+	var last error
+	return Check{Name: name, Check: func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if !checked.IsZero() && time.Since(checked) < interval {
+			return last
+		}
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", address)
+		if err == nil {
+			conn.Close()
+		}
+		checked, last = time.Now(), err
+		return err
+	}}
+}
+
+// status is the body of the responses of the endpoints
+type status struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Handle registers the probes of a daemon on mux: /healthz answers while the
+// daemon is running, for the liveness probe, and /readyz runs the checks
+// and fails with 503 when a dependency is not reachable, for the readiness probe
+func Handle(mux *http.ServeMux, checks ...Check) {
+	HandleChecks(mux, func() []Check { return checks })
+}
+
+// HandleChecks is Handle running the checks returned by checks on every
+// probe, the daemons of a process add their checks to the same probes
+func HandleChecks(mux *http.ServeMux, checks func() []Check) {
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeStatus(w, http.StatusOK, status{Status: "ok"})
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		res := run(r.Context(), checks())
+		code := http.StatusOK
+		if res.Status != "ok" {
+			code = http.StatusServiceUnavailable
+		}
+		writeStatus(w, code, res)
+	})
+}
+
+// run runs the checks in parallel, the status is ok when every check passes
+func run(ctx context.Context, checks []Check) status {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	res := status{Status: "ok", Checks: make(map[string]string, len(checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c Check) {
+			defer wg.Done()
+			result := "ok"
+			if err := c.Check(ctx); err != nil {
+				result = err.Error()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			res.Checks[c.Name] = result
+			if result != "ok" {
+				res.Status = "unavailable"
+			}
+		}(c)
+	}
+	wg.Wait()
+	return res
+}
+
+func writeStatus(w http.ResponseWriter, code int, s status) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(s)
+}
@@ -0,0 +1,56 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandle(t *testing.T) {
+	healthy := Check{Name: "db", Check: func(ctx context.Context) error { return nil }}
+	broken := Check{Name: "queue", Check: func(ctx context.Context) error { return errors.New("nats: connection closed") }}
+
+	mux := http.NewServeMux()
+	Handle(mux, healthy)
+	assert.Equal(t, status{Status: "ok", Checks: map[string]string{"db": "ok"}}, get(t, mux, "/readyz", http.StatusOK))
+
+	mux = http.NewServeMux()
+	Handle(mux, healthy, broken)
+	assert.Equal(t, status{Status: "unavailable", Checks: map[string]string{
+		"db":    "ok",
+		"queue": "nats: connection closed",
+	}}, get(t, mux, "/readyz", http.StatusServiceUnavailable))
+
+	// the daemon is alive with broken dependencies
+	assert.Equal(t, status{Status: "ok"}, get(t, mux, "/healthz", http.StatusOK))
+}
+
+func TestDial(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	addr := l.Addr().String()
+
+	check := Dial("smtp", addr, time.Hour)
+	assert.Nil(t, check.Check(context.Background()))
+
+	// the result is kept for the interval
+	l.Close()
+	assert.Nil(t, check.Check(context.Background()))
+	assert.NotNil(t, Dial("smtp", addr, time.Hour).Check(context.Background()))
+}
+
+func get(t *testing.T, h http.Handler, path string, code int) status {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	assert.Equal(t, code, rec.Code)
+	var s status
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &s))
+	return s
+}
@@ -0,0 +1,172 @@
+package jetstream
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	"kannon.gyozatech.dev/internal/logging"
+)
+
+var log = logging.Logger("jetstream")
+
+// Stream is the stream of the messages published on emails subjects
+const Stream = "kannon"
+
+// Subjects are the subjects stored in Stream
+var Subjects = []string{"emails.>"}
+
+// MaxAge is the max age of the messages of Stream
+const MaxAge = 7 * 24 * time.Hour
+
+// DuplicateWindow is the window of Stream deduplicating
+// messages published with the same Nats-Msg-Id header, it's longer
+// than the claim lease of the dispatchers publishing an email again
+const DuplicateWindow = 10 * time.Minute
+
+// Stores reports whether the messages published on subject
+// are stored in Stream
+func Stores(subject string) bool {
+	tokens := strings.Split(subject, ".")
+	for _, s := range Subjects {
+		if matchSubject(strings.Split(s, "."), tokens) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSubject matches the tokens of a subject with the tokens
+// of a filter with the * and > wildcards
+func matchSubject(filter []string, tokens []string) bool {
+	for i, f := range filter {
+		if f == ">" {
+			return len(tokens) > i
+		}
+		if i >= len(tokens) || (f != "*" && f != tokens[i]) {
+			return false
+		}
+	}
+	return len(filter) == len(tokens)
+}
+
+// ConsumerConfig are the settings of a consumer created by LoadConsumer
+type ConsumerConfig struct {
+	// AckWait is the time a message waits for its ack before being delivered again
+	AckWait time.Duration `default:"30s"`
+	// MaxDeliver is the max number of deliveries of a message, -1 is unlimited
+	MaxDeliver int `default:"-1"`
+	// MaxAckPending is the max number of messages waiting for their ack, 0 is the server default
+	MaxAckPending uint
+	// DeliverPolicy is the first message delivered to a new consumer: all, last or new
+	DeliverPolicy string `default:"all"`
+}
+
+// DefaultConsumerConfig are the settings of consumers without a custom config
+var DefaultConsumerConfig = ConsumerConfig{
+	AckWait:       30 * time.Second,
+	MaxDeliver:    -1,
+	DeliverPolicy: "all",
+}
+
+// Provision creates Stream when it doesn't exist
+func Provision(mgr *jsm.Manager) error {
+	s, err := mgr.LoadOrNewStream(Stream,
+		jsm.Subjects(Subjects...),
+		jsm.FileStorage(),
+		jsm.LimitsRetention(),
+		jsm.MaxAge(MaxAge),
+		jsm.DuplicateWindow(DuplicateWindow),
+	)
+	if err != nil {
+		return fmt.Errorf("cannot provision stream %v: %w", Stream, err)
+	}
+	// streams created with a shorter window don't deduplicate
+	// the emails published again after the claim lease
+	if s.DuplicateWindow() < DuplicateWindow {
+		if err := s.UpdateConfiguration(s.Configuration(), jsm.DuplicateWindow(DuplicateWindow)); err != nil {
+			return fmt.Errorf("cannot update the duplicate window of stream %v: %w", Stream, err)
+		}
+		log.Infof("duplicate window of stream %v updated to %v", Stream, DuplicateWindow)
+	}
+	return nil
+}
+
+// LoadConsumer loads a durable pull consumer of subject, every subject of Stream
+// when empty, creating it with config when it doesn't exist. Existing consumers
+// can't be updated, a warning is logged when their settings are different from config
+func LoadConsumer(mgr *jsm.Manager, name string, subject string, config ConsumerConfig) (*jsm.Consumer, error) {
+	opts, err := consumerOptions(config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config of consumer %v: %w", name, err)
+	}
+	opts = append(opts, jsm.DurableName(name), jsm.AcknowledgeExplicit())
+	if subject != "" {
+		opts = append(opts, jsm.FilterStreamBySubject(subject))
+	}
+
+	desired, err := jsm.NewConsumerConfiguration(jsm.DefaultConsumer, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config of consumer %v: %w", name, err)
+	}
+
+	con, err := mgr.LoadOrNewConsumer(Stream, name, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot provision consumer %v: %w", name, err)
+	}
+	if diff := configDiff(con.Configuration(), *desired, config); diff != "" {
+		log.Warnf("consumer %v exists with different settings (%v), delete it to apply them", name, diff)
+	}
+	return con, nil
+}
+
+func consumerOptions(config ConsumerConfig) ([]jsm.ConsumerOption, error) {
+	var opts []jsm.ConsumerOption
+	switch config.DeliverPolicy {
+	case "", "all":
+		opts = append(opts, jsm.DeliverAllAvailable())
+	case "last":
+		opts = append(opts, jsm.StartWithLastReceived())
+	case "new":
+		opts = append(opts, jsm.StartWithNextReceived())
+	default:
+		return nil, fmt.Errorf("invalid deliver policy: %v", config.DeliverPolicy)
+	}
+	if config.AckWait > 0 {
+		opts = append(opts, jsm.AckWait(config.AckWait))
+	}
+	if config.MaxDeliver != 0 {
+		opts = append(opts, jsm.MaxDeliveryAttempts(config.MaxDeliver))
+	}
+	if config.MaxAckPending > 0 {
+		opts = append(opts, jsm.MaxAckPending(config.MaxAckPending))
+	}
+	return opts, nil
+}
+
+// configDiff describes the settings of config not matching
+// an existing consumer, settings left to the server default are ignored
+func configDiff(existing api.ConsumerConfig, desired api.ConsumerConfig, config ConsumerConfig) string {
+	var diff string
+	add := func(name string, existing interface{}, desired interface{}) {
+		if diff != "" {
+			diff += ", "
+		}
+		diff += fmt.Sprintf("%v %v instead of %v", name, existing, desired)
+	}
+	if config.AckWait > 0 && existing.AckWait != desired.AckWait {
+		add("ack wait", existing.AckWait, desired.AckWait)
+	}
+	if config.MaxDeliver != 0 && existing.MaxDeliver != desired.MaxDeliver {
+		add("max deliver", existing.MaxDeliver, desired.MaxDeliver)
+	}
+	if config.MaxAckPending > 0 && existing.MaxAckPending != desired.MaxAckPending {
+		add("max ack pending", existing.MaxAckPending, desired.MaxAckPending)
+	}
+	if existing.DeliverPolicy != desired.DeliverPolicy {
+		add("deliver policy", existing.DeliverPolicy, desired.DeliverPolicy)
+	}
+	return diff
+}
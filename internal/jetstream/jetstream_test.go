@@ -0,0 +1,51 @@
+package jetstream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsumerOptions(t *testing.T) {
+	opts, err := consumerOptions(ConsumerConfig{
+		AckWait:       time.Minute,
+		MaxDeliver:    5,
+		MaxAckPending: 100,
+		DeliverPolicy: "new",
+	})
+	assert.Nil(t, err)
+	config, err := jsm.NewConsumerConfiguration(jsm.DefaultConsumer, opts...)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Minute, config.AckWait)
+	assert.Equal(t, 5, config.MaxDeliver)
+	assert.Equal(t, 100, config.MaxAckPending)
+	assert.Equal(t, api.DeliverNew, config.DeliverPolicy)
+
+	_, err = consumerOptions(ConsumerConfig{DeliverPolicy: "first"})
+	assert.NotNil(t, err)
+}
+
+func TestConfigDiff(t *testing.T) {
+	config := ConsumerConfig{AckWait: time.Minute, DeliverPolicy: "all"}
+	opts, err := consumerOptions(config)
+	assert.Nil(t, err)
+	desired, err := jsm.NewConsumerConfiguration(jsm.DefaultConsumer, opts...)
+	assert.Nil(t, err)
+
+	existing := *desired
+	existing.MaxAckPending = 20000
+	assert.Equal(t, "", configDiff(existing, *desired, config))
+
+	existing.AckWait = 30 * time.Second
+	assert.Equal(t, "ack wait 30s instead of 1m0s", configDiff(existing, *desired, config))
+}
+
+func TestStores(t *testing.T) {
+	assert.True(t, Stores("emails.sending"))
+	assert.True(t, Stores("emails.dead"))
+	assert.False(t, Stores("emails"))
+	assert.False(t, Stores("pools.scheduled"))
+}
@@ -0,0 +1,184 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Config configures the logs of a service, like APP_LOG_FORMAT
+type Config struct {
+	// Format of the log lines: text or json
+	Format string `default:"text"`
+	// Level of the components without a level in Levels: debug, info, warn or error
+	Level string `default:"info"`
+	// Levels are the levels of components, like dispatcher=debug,smtp=warn
+	Levels string
+}
+
+// Validate checks the format and the levels of config
+func (c Config) Validate() error {
+	if _, err := formatter(c.Format); err != nil {
+		return err
+	}
+	if _, err := parseLevel(c.Level); err != nil {
+		return err
+	}
+	_, err := parseLevels(c.Levels)
+	return err
+}
+
+// loggers are the loggers of the components by name, they
+// share the output and the format of the standard logger
+var loggers = struct {
+	sync.Mutex
+	byName map[string]*logrus.Logger
+	levels map[string]logrus.Level
+	level  logrus.Level
+	hooks  []logrus.Hook
+}{
+	byName: make(map[string]*logrus.Logger),
+	levels: make(map[string]logrus.Level),
+	level:  logrus.InfoLevel,
+}
+
+// Setup applies config to the standard logger and the loggers
+// of the components, including the existing ones
+func Setup(config Config) error {
+	f, err := formatter(config.Format)
+	if err != nil {
+		return err
+	}
+	level, err := parseLevel(config.Level)
+	if err != nil {
+		return err
+	}
+	levels, err := parseLevels(config.Levels)
+	if err != nil {
+		return err
+	}
+
+	loggers.Lock()
+	defer loggers.Unlock()
+	logrus.SetFormatter(f)
+	logrus.SetLevel(level)
+	loggers.level = level
+	loggers.levels = levels
+	for name, l := range loggers.byName {
+		l.SetFormatter(f)
+		l.SetLevel(componentLevel(name))
+	}
+	return nil
+}
+
+// Logger returns the logger of a component, its lines have the component
+// field and are written when they are at least at the level of the component
+func Logger(component string) *logrus.Entry {
+	loggers.Lock()
+	defer loggers.Unlock()
+	l, ok := loggers.byName[component]
+	if !ok {
+		std := logrus.StandardLogger()
+		l = &logrus.Logger{
+			Out:          os.Stderr,
+			Formatter:    std.Formatter,
+			Hooks:        make(logrus.LevelHooks),
+			Level:        componentLevel(component),
+			ExitFunc:     os.Exit,
+			ReportCaller: false,
+		}
+		for _, hook := range loggers.hooks {
+			l.AddHook(hook)
+		}
+		loggers.byName[component] = l
+	}
+	return l.WithField("component", component)
+}
+
+// AddHook adds hook to the standard logger and the loggers of the components,
+// including the existing ones
+func AddHook(hook logrus.Hook) {
+	loggers.Lock()
+	defer loggers.Unlock()
+	loggers.hooks = append(loggers.hooks, hook)
+	logrus.AddHook(hook)
+	for _, l := range loggers.byName {
+		l.AddHook(hook)
+	}
+}
+
+// componentLevel is the level of a component, loggers must be locked
+func componentLevel(component string) logrus.Level {
+	if level, ok := loggers.levels[component]; ok {
+		return level
+	}
+	return loggers.level
+}
+
+// Message returns log with the message_id and domain fields of a message,
+// email message ids like <dG9AZW1haWwuY29t/msg_123@kannon.io> are logged
+// with the id of their pool message, msg_123@kannon.io
+func Message(log *logrus.Entry, messageID string) *logrus.Entry {
+	id := strings.TrimSuffix(strings.TrimPrefix(messageID, "<"), ">")
+	if i := strings.LastIndex(id, "/"); i >= 0 {
+		id = id[i+1:]
+	}
+	domain := ""
+	if i := strings.LastIndex(id, "@"); i >= 0 {
+		domain = id[i+1:]
+	}
+	return log.WithFields(logrus.Fields{
+		"message_id": id,
+		"domain":     domain,
+	})
+}
+
+// Domain returns log with the domain field
+func Domain(log *logrus.Entry, domain string) *logrus.Entry {
+	return log.WithField("domain", domain)
+}
+
+func formatter(format string) (logrus.Formatter, error) {
+	switch format {
+	case "", "text":
+		return &logrus.TextFormatter{}, nil
+	case "json":
+		return &logrus.JSONFormatter{}, nil
+	}
+	return nil, fmt.Errorf("unknown log format %v, must be text or json", format)
+}
+
+func parseLevel(level string) (logrus.Level, error) {
+	if level == "" {
+		return logrus.InfoLevel, nil
+	}
+	l, err := logrus.ParseLevel(level)
+	if err != nil {
+		return 0, fmt.Errorf("unknown log level %v", level)
+	}
+	return l, nil
+}
+
+// parseLevels parses comma separated component=level pairs
+func parseLevels(s string) (map[string]logrus.Level, error) {
+	levels := make(map[string]logrus.Level)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid log level %v, must be component=level", pair)
+		}
+		level, err := parseLevel(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		levels[parts[0]] = level
+	}
+	return levels, nil
+}
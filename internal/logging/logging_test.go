@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLevels(t *testing.T) {
+	levels, err := parseLevels("queue=debug, smtp=warn,")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]logrus.Level{"queue": logrus.DebugLevel, "smtp": logrus.WarnLevel}, levels)
+
+	levels, err = parseLevels("")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(levels))
+
+	for _, invalid := range []string{"queue", "=debug", "queue=loud"} {
+		_, err := parseLevels(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	defer func() { _ = Setup(Config{}) }()
+
+	dispatcher := Logger("test-dispatcher")
+	assert.Equal(t, "test-dispatcher", dispatcher.Data["component"])
+	assert.Equal(t, logrus.InfoLevel, dispatcher.Logger.GetLevel())
+
+	// existing loggers are updated
+	assert.Nil(t, Setup(Config{Format: "json", Level: "warn", Levels: "test-dispatcher=debug"}))
+	assert.Equal(t, logrus.DebugLevel, dispatcher.Logger.GetLevel())
+	assert.IsType(t, &logrus.JSONFormatter{}, dispatcher.Logger.Formatter)
+	assert.Equal(t, logrus.WarnLevel, Logger("test-smtp").Logger.GetLevel())
+
+	assert.NotNil(t, Setup(Config{Format: "xml"}))
+	assert.NotNil(t, Config{Level: "loud"}.Validate())
+}
+
+func TestMessage(t *testing.T) {
+	log := Logger("test")
+	entry := Message(log, "<dG9AZW1haWwuY29t/msg_123@kannon.io>")
+	assert.Equal(t, "msg_123@kannon.io", entry.Data["message_id"])
+	assert.Equal(t, "kannon.io", entry.Data["domain"])
+
+	entry = Message(log, "msg_123@kannon.io")
+	assert.Equal(t, "msg_123@kannon.io", entry.Data["message_id"])
+	assert.Equal(t, "kannon.io", entry.Data["domain"])
+
+	assert.Equal(t, "kannon.io", Domain(log, "kannon.io").Data["domain"])
+}
@@ -0,0 +1,98 @@
+package mailbuilder
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/textproto"
+
+	"kannon.gyozatech.dev/internal/dkim"
+)
+
+// DefaultDKIMHeaders are the headers signed with DKIM for domains
+// without their own set, headers are signed only when present
+var DefaultDKIMHeaders = []string{
+	"From",
+	"To",
+	"Cc",
+	"Subject",
+	"Date",
+	"Message-ID",
+	"MIME-Version",
+	"Content-Type",
+	"List-Unsubscribe",
+	"List-Unsubscribe-Post",
+	"Reply-To",
+}
+
+// maxDKIMHeaders is the max number of headers signed by a domain
+const maxDKIMHeaders = 50
+
+// maxDKIMHeaderLen is the size of the names of domains.dkim_headers
+const maxDKIMHeaderLen = 100
+
+// trackingHeaders are the unsubscribe and tracking headers added by kannon,
+// they are signed when present even if the domain doesn't list them
+var trackingHeaders = []string{
+	"List-Unsubscribe",
+	"List-Unsubscribe-Post",
+	"X-Pool-Message-ID",
+}
+
+// ValidateDKIMHeaders checks the headers signed by a domain, From
+// and the tracking headers are always signed and don't need to be listed
+func ValidateDKIMHeaders(h []string) error {
+	if len(h) > maxDKIMHeaders {
+		return fmt.Errorf("more than %v headers", maxDKIMHeaders)
+	}
+	for _, k := range h {
+		if !validHeaderName(k) || len(k) > maxDKIMHeaderLen {
+			return fmt.Errorf("invalid header name: %q", k)
+		}
+		if textproto.CanonicalMIMEHeaderKey(k) == "Dkim-Signature" {
+			return fmt.Errorf("header %v cannot be signed", k)
+		}
+	}
+	return nil
+}
+
+// dkimHeaders returns the headers of msg to sign with DKIM, the signed
+// headers of the domain or DefaultDKIMHeaders when it has none. Headers
+// missing from msg are not signed, except From that is always signed.
+// Tracking headers present in msg are signed twice (oversigned) so that
+// a copy of them added after signing breaks the signature
+func dkimHeaders(msg []byte, signed []string) []string {
+	if len(signed) == 0 {
+		signed = DefaultDKIMHeaders
+	}
+	// on malformed messages the headers read before the error are signed
+	present, _ := textproto.NewReader(bufio.NewReader(bytes.NewReader(msg))).ReadMIMEHeader()
+
+	h := []string{"From"}
+	seen := map[string]bool{"From": true}
+	for _, k := range signed {
+		key := textproto.CanonicalMIMEHeaderKey(k)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if _, ok := present[key]; ok {
+			h = append(h, k)
+		}
+	}
+	for _, k := range trackingHeaders {
+		key := textproto.CanonicalMIMEHeaderKey(k)
+		if _, ok := present[key]; !ok {
+			continue
+		}
+		if !seen[key] {
+			h = append(h, k)
+		}
+		h = append(h, k)
+	}
+	return h
+}
+
+func signMessage(domain string, keys dkim.DomainKeys, headers []string, msg []byte) ([]byte, error) {
+	return dkim.SignDomainMessage(keys, domain, headers, msg)
+}
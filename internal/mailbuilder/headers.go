@@ -0,0 +1,74 @@
+package mailbuilder
+
+import (
+	"fmt"
+	"net/textproto"
+	"strings"
+)
+
+// protectedHeaders are set by kannon and cannot be overridden
+// by custom headers
+var protectedHeaders = map[string]bool{
+	"From":                      true,
+	"Sender":                    true,
+	"To":                        true,
+	"Cc":                        true,
+	"Bcc":                       true,
+	"Subject":                   true,
+	"Reply-To":                  true,
+	"Date":                      true,
+	"Message-Id":                true,
+	"Dkim-Signature":            true,
+	"Return-Path":               true,
+	"Mime-Version":              true,
+	"Content-Type":              true,
+	"Content-Transfer-Encoding": true,
+	"X-Pool-Message-Id":         true,
+}
+
+func isProtectedHeader(name string) bool {
+	return protectedHeaders[textproto.CanonicalMIMEHeaderKey(name)]
+}
+
+// ValidateCustomHeaders checks that custom headers are valid
+// and do not override protected headers
+func ValidateCustomHeaders(h map[string]string) error {
+	for k, v := range h {
+		if !validHeaderName(k) {
+			return fmt.Errorf("invalid header name: %q", k)
+		}
+		if isProtectedHeader(k) {
+			return fmt.Errorf("header %v cannot be overridden", k)
+		}
+		if strings.ContainsAny(v, "\r\n") {
+			return fmt.Errorf("invalid value for header %v", k)
+		}
+	}
+	return nil
+}
+
+// validHeaderName checks header field name as defined in RFC 5322,
+// printable US-ASCII characters except colon
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if c < 33 || c > 126 || c == ':' {
+			return false
+		}
+	}
+	return true
+}
+
+// addUnsubscribeHeaders adds one-click List-Unsubscribe headers (RFC 8058),
+// a List-Unsubscribe set by custom headers is kept
+func addUnsubscribeHeaders(h headers, url string) {
+	for k := range h {
+		if textproto.CanonicalMIMEHeaderKey(k) == "List-Unsubscribe" {
+			return
+		}
+	}
+	h["List-Unsubscribe"] = fmt.Sprintf("<%v>", url)
+	h["List-Unsubscribe-Post"] = "List-Unsubscribe=One-Click"
+}
@@ -0,0 +1,48 @@
+package mailbuilder
+
+import "testing"
+
+func TestValidateCustomHeaders(t *testing.T) {
+	examples := []struct {
+		name    string
+		headers map[string]string
+		valid   bool
+	}{
+		{"custom headers", map[string]string{"X-Campaign-ID": "123", "X-Entity-Ref": "abc"}, true},
+		{"no headers", nil, true},
+		{"from", map[string]string{"From": "other@email.com"}, false},
+		{"message id lowercase", map[string]string{"message-id": "<123@email.com>"}, false},
+		{"dkim signature", map[string]string{"DKIM-Signature": "v=1"}, false},
+		{"invalid name", map[string]string{"X Campaign": "123"}, false},
+		{"header injection", map[string]string{"X-Campaign-ID": "123\r\nBcc: other@email.com"}, false},
+	}
+
+	for _, tt := range examples {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCustomHeaders(tt.headers)
+			if tt.valid && err != nil {
+				t.Errorf("headers should be valid: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Errorf("headers should not be valid: %v", tt.headers)
+			}
+		})
+	}
+}
+
+func TestAddUnsubscribeHeaders(t *testing.T) {
+	h := headers{}
+	addUnsubscribeHeaders(h, "https://t.io/u/token")
+	if h["List-Unsubscribe"] != "<https://t.io/u/token>" {
+		t.Errorf("wrong List-Unsubscribe: %v", h["List-Unsubscribe"])
+	}
+	if h["List-Unsubscribe-Post"] != "List-Unsubscribe=One-Click" {
+		t.Errorf("wrong List-Unsubscribe-Post: %v", h["List-Unsubscribe-Post"])
+	}
+
+	h = headers{"list-unsubscribe": "<mailto:unsubscribe@email.com>"}
+	addUnsubscribeHeaders(h, "https://t.io/u/token")
+	if len(h) != 1 {
+		t.Errorf("custom List-Unsubscribe should be kept: %v", h)
+	}
+}
@@ -0,0 +1,33 @@
+package mailbuilder
+
+import (
+	"regexp"
+
+	"kannon.gyozatech.dev/internal/pool"
+)
+
+var imgSrcRegexp = regexp.MustCompile(`(?i)(<img\b[^>]*?\bsrc\s*=\s*)("[^"]*"|'[^']*')`)
+
+// rewriteInlineImages replaces the src of img tags referencing
+// an inline image by filename with its cid: url
+func rewriteInlineImages(html string, attachments []pool.Attachment) string {
+	inlines := make(map[string]bool)
+	for _, a := range attachments {
+		if a.Inline {
+			inlines[a.Filename] = true
+		}
+	}
+	if len(inlines) == 0 {
+		return html
+	}
+
+	return imgSrcRegexp.ReplaceAllStringFunc(html, func(tag string) string {
+		m := imgSrcRegexp.FindStringSubmatch(tag)
+		quote := m[2][:1]
+		src := m[2][1 : len(m[2])-1]
+		if !inlines[src] {
+			return tag
+		}
+		return m[1] + quote + "cid:" + src + quote
+	})
+}
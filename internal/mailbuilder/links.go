@@ -0,0 +1,24 @@
+package mailbuilder
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var linkHrefRegexp = regexp.MustCompile(`(?i)(<a\b[^>]*?\bhref\s*=\s*)("[^"]*"|'[^']*')`)
+
+// rewriteLinks replaces the http and https href of the links of html
+// with the url returned by track, other links like mailto: are kept
+func rewriteLinks(body string, track func(url string) string) string {
+	return linkHrefRegexp.ReplaceAllStringFunc(body, func(tag string) string {
+		m := linkHrefRegexp.FindStringSubmatch(tag)
+		quote := m[2][:1]
+		href := strings.TrimSpace(html.UnescapeString(m[2][1 : len(m[2])-1]))
+		lower := strings.ToLower(href)
+		if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
+			return tag
+		}
+		return m[1] + quote + html.EscapeString(track(href)) + quote
+	})
+}
@@ -4,33 +4,46 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	"encoding/json"
+	"io"
 	"time"
 
-	"github.com/sirupsen/logrus"
 	"gopkg.in/mail.v2"
 	"kannon.gyozatech.dev/generated/pb"
 	"kannon.gyozatech.dev/generated/sqlc"
 	"kannon.gyozatech.dev/internal/dkim"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/metrics"
 	"kannon.gyozatech.dev/internal/pool"
+	"kannon.gyozatech.dev/internal/templates"
+	"kannon.gyozatech.dev/internal/tracking"
 )
 
+var log = logging.Logger("mailbuilder")
+
 type MailBulder interface {
 	PerpareForSend(email sqlc.SendingPoolEmail) (pb.EmailToSend, error)
 }
 
-// NewMailBuilder creates an SMTP mailer
-func NewMailBuilder(db *sql.DB) MailBulder {
+// defaultHeaders are added to every message
+var defaultHeaders = headers{
+	"X-Mailer": "SMTP Mailer",
+}
+
+// NewMailBuilder creates an SMTP mailer, when tracker is not nil
+// an open tracking pixel is added to html bodies
+func NewMailBuilder(db *sql.DB, tracker tracking.Tracker) MailBulder {
 	return &mailBuilder{
-		db: sqlc.New(db),
-		headers: headers{
-			"X-Mailer": "SMTP Mailer",
-		},
+		db:      sqlc.New(metrics.InstrumentDB(db)),
+		headers: defaultHeaders,
+		tracker: tracker,
 	}
 }
 
 type mailBuilder struct {
 	headers headers
 	db      *sqlc.Queries
+	tracker tracking.Tracker
 }
 
 func (m *mailBuilder) PerpareForSend(email sqlc.SendingPoolEmail) (pb.EmailToSend, error) {
@@ -39,60 +52,206 @@ func (m *mailBuilder) PerpareForSend(email sqlc.SendingPoolEmail) (pb.EmailToSen
 		return pb.EmailToSend{}, err
 	}
 
-	msg, err := prepareMessage(pool.Sender{
+	attachments, err := m.getAttachments(email.MessageID)
+	if err != nil {
+		return pb.EmailToSend{}, err
+	}
+
+	defaultHeaders, err := ParseDefaultHeaders(emailData.DefaultHeaders)
+	if err != nil {
+		return pb.EmailToSend{}, err
+	}
+	settings := SendingSettings{
+		FromEmail:     emailData.DefaultFromEmail,
+		FromName:      emailData.DefaultFromName,
+		Headers:       defaultHeaders,
+		OpenTracking:  emailData.OpenTracking,
+		ClickTracking: emailData.ClickTracking,
+		FooterHTML:    emailData.FooterHtml,
+		FooterText:    emailData.FooterText,
+	}
+
+	baseHeaders, err := m.messageHeaders(settings.Headers, emailData.Headers)
+	if err != nil {
+		return pb.EmailToSend{}, err
+	}
+
+	fields, err := recipientFields(emailData.Fields, email.Fields, email.Email)
+	if err != nil {
+		return pb.EmailToSend{}, err
+	}
+	subject, html, text := renderContent(emailData.Subject, emailData.Html, emailData.Text, fields)
+	html, text = settings.addFooters(html, text, fields)
+	if m.tracker != nil {
+		target := tracking.Target{
+			MessageID: emailData.MessageID,
+			Email:     email.Email,
+		}
+		if settings.ClickTracking {
+			html = rewriteLinks(html, func(url string) string {
+				return m.tracker.ClickURL(target, url)
+			})
+		}
+		if settings.OpenTracking {
+			html = injectOpenPixel(html, m.tracker.OpenURL(target))
+		}
+		addUnsubscribeHeaders(baseHeaders, m.tracker.UnsubscribeURL(target))
+	}
+
+	sender := settings.sender(pool.Sender{
 		Email: emailData.SenderEmail,
 		Alias: emailData.SenderAlias,
-	}, emailData.Subject, email.Email, emailData.MessageID, emailData.Html, m.headers)
+	})
+	msg, err := prepareMessage(sender, subject, email.Email, emailData.Cc, emailData.ReplyTo, emailData.MessageID, html, text, attachments, baseHeaders)
 	if err != nil {
 		return pb.EmailToSend{}, err
 	}
 
-	signedMsg, err := signMessage(emailData.Domain, emailData.DkimPrivateKey, msg)
+	signedMsg, err := signMessage(emailData.Domain, dkim.DomainKeys{
+		Signing:           emailData.DkimSigning,
+		RSAPrivateKey:     emailData.DkimPrivateKey,
+		Ed25519PrivateKey: emailData.DkimEd25519PrivateKey,
+		RSASelector:       emailData.DkimSelector,
+		Ed25519Selector:   emailData.DkimEd25519Selector,
+	}, dkimHeaders(msg, emailData.DkimHeaders), msg)
 	if err != nil {
 		return pb.EmailToSend{}, err
 	}
 
+	// the cc and bcc receive the email of the first recipient, once
+	var cc, bcc []string
+	if email.Copies {
+		cc, bcc = emailData.Cc, emailData.Bcc
+	}
+
 	return pb.EmailToSend{
-		From:       emailData.SenderEmail,
+		From:       sender.Email,
 		To:         email.Email,
 		Body:       signedMsg,
-		MessageId:  buildEmailMessageID(email.Email, emailData.MessageID),
-		ReturnPath: buildReturnPath(email.Email, emailData.MessageID),
+		MessageId:  BuildEmailMessageID(email.Email, emailData.MessageID),
+		ReturnPath: buildReturnPath(email.Email, emailData.MessageID, emailData.ReturnPathDomain),
+		Cc:         cc,
+		Bcc:        bcc,
+		IpPool:     emailData.IpPool,
+
+		Sandbox:              emailData.Sandbox,
+		SandboxBouncePercent: uint32(emailData.SandboxBouncePercent),
 	}, nil
 }
 
-func prepareMessage(sender pool.Sender, subject string, to string, messageID string, html string, baseHeaders headers) ([]byte, error) {
-	emailMessageID := buildEmailMessageID(to, messageID)
-	h := buildHeaders(subject, sender, to, messageID, emailMessageID, baseHeaders)
-	return renderMsg(html, h)
+// messageHeaders merges builder headers with the default headers of the domain
+// and the custom headers of a message, protected headers are ignored
+func (m *mailBuilder) messageHeaders(domainHeaders map[string]string, customHeaders json.RawMessage) (headers, error) {
+	custom := make(map[string]string)
+	if len(customHeaders) > 0 {
+		if err := json.Unmarshal(customHeaders, &custom); err != nil {
+			return nil, err
+		}
+	}
+	return mergeHeaders(mergeHeaders(m.headers, domainHeaders), custom), nil
 }
 
-func signMessage(domain string, dkimPrivateKey string, msg []byte) ([]byte, error) {
-	signData := dkim.SignData{
-		PrivateKey: dkimPrivateKey,
-		Domain:     domain,
-		Selector:   "kannon",
-		Headers:    []string{"From", "To", "Subject", "Message-ID"},
+func mergeHeaders(base headers, custom map[string]string) headers {
+	h := make(headers)
+	for k, v := range base {
+		h[k] = v
+	}
+	for k, v := range custom {
+		if isProtectedHeader(k) {
+			log.Warnf("ignoring protected custom header: %v", k)
+			continue
+		}
+		h[k] = v
 	}
+	return h
+}
 
-	return dkim.SignMessage(signData, bytes.NewReader(msg))
+// recipientFields returns the fields used to render the message of a recipient,
+// recipient fields override message fields
+func recipientFields(messageFields json.RawMessage, emailFields json.RawMessage, email string) (map[string]string, error) {
+	fields := make(map[string]string)
+	for _, f := range []json.RawMessage{messageFields, emailFields} {
+		if len(f) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(f, &fields); err != nil {
+			return nil, err
+		}
+	}
+	fields[templates.EmailField] = email
+	return fields, nil
+}
+
+// renderContent substitutes fields in subject, html and text,
+// strict checks are done by the api so missing fields are rendered empty
+func renderContent(subject, html, text string, fields map[string]string) (string, string, string) {
+	subject, _ = templates.Render(subject, fields, templates.Lenient)
+	html, _ = templates.RenderHTML(html, fields, templates.Lenient)
+	text, _ = templates.Render(text, fields, templates.Lenient)
+	return subject, html, text
+}
+
+func (m *mailBuilder) getAttachments(messageID int32) ([]pool.Attachment, error) {
+	dbAttachments, err := m.db.GetMessageAttachments(context.TODO(), messageID)
+	if err != nil {
+		return nil, err
+	}
+	attachments := make([]pool.Attachment, 0, len(dbAttachments))
+	for _, a := range dbAttachments {
+		attachments = append(attachments, pool.Attachment{
+			Filename: a.Filename,
+			Content:  a.Content,
+			Inline:   a.Inline,
+		})
+	}
+	return attachments, nil
+}
+
+func prepareMessage(sender pool.Sender, subject string, to string, cc []string, replyTo string, messageID string, html string, text string, attachments []pool.Attachment, baseHeaders headers) ([]byte, error) {
+	emailMessageID := BuildEmailMessageID(to, messageID)
+	h := buildHeaders(subject, sender, to, cc, replyTo, messageID, emailMessageID, baseHeaders)
+	if text == "" {
+		text = htmlToText(html)
+	}
+	html = rewriteInlineImages(html, attachments)
+	return renderMsg(html, text, attachments, h)
 }
 
 // renderMsg render a MsgPayload to an SMTP message
-func renderMsg(html string, headers headers) ([]byte, error) {
+// as a multipart/alternative with a plain-text and an html part,
+// wrapped in a multipart/related when there are inline images
+// and in a multipart/mixed when there are attachments
+func renderMsg(html string, text string, attachments []pool.Attachment, headers headers) ([]byte, error) {
 	msg := mail.NewMessage()
 
 	for key, value := range headers {
 		msg.SetHeader(key, value)
 	}
 	msg.SetDateHeader("Date", time.Now())
-	msg.SetBody("text/html", html)
+	msg.SetBody("text/plain", text)
+	msg.AddAlternative("text/html", html)
+	for _, a := range attachments {
+		if a.Inline {
+			msg.Embed(a.Filename, copyContent(a.Content))
+			continue
+		}
+		msg.Attach(a.Filename, copyContent(a.Content))
+	}
 
 	var buff bytes.Buffer
 	if _, err := msg.WriteTo(&buff); err != nil {
-		logrus.Warnf("🤢 Error writing message: %v\n", err)
+		log.Warnf("🤢 Error writing message: %v\n", err)
 		return nil, err
 	}
 
 	return buff.Bytes(), nil
 }
+
+// copyContent sets the content of an in-memory file part,
+// it is base64 encoded by the message writer
+func copyContent(content []byte) mail.FileSetting {
+	return mail.SetCopyFunc(func(w io.Writer) error {
+		_, err := w.Write(content)
+		return err
+	})
+}
@@ -0,0 +1,208 @@
+package mailbuilder
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"kannon.gyozatech.dev/internal/pool"
+)
+
+func TestRenderMsgIsMultipartAlternative(t *testing.T) {
+	msg, err := renderMsg("<p>html</p>", "text", nil, headers{"Subject": "test"})
+	if err != nil {
+		t.Fatalf("cannot render message: %v", err)
+	}
+	body := string(msg)
+	if !strings.Contains(body, "multipart/alternative") {
+		t.Errorf("message is not multipart/alternative: %v", body)
+	}
+	if !strings.Contains(body, "text/plain") || !strings.Contains(body, "text/html") {
+		t.Errorf("message does not contain both parts: %v", body)
+	}
+}
+
+func TestRenderMsgWithAttachments(t *testing.T) {
+	attachments := []pool.Attachment{
+		{Filename: "test.txt", Content: []byte("attachment content")},
+	}
+	msg, err := renderMsg("<p>html</p>", "text", attachments, headers{"Subject": "test"})
+	if err != nil {
+		t.Fatalf("cannot render message: %v", err)
+	}
+	body := string(msg)
+	if !strings.Contains(body, "multipart/mixed") {
+		t.Errorf("message is not multipart/mixed: %v", body)
+	}
+	if !strings.Contains(body, `filename="test.txt"`) {
+		t.Errorf("attachment not found in message: %v", body)
+	}
+	if !strings.Contains(body, base64.StdEncoding.EncodeToString([]byte("attachment content"))) {
+		t.Errorf("attachment is not base64 encoded: %v", body)
+	}
+}
+
+func TestRenderMsgWithInlineImages(t *testing.T) {
+	attachments := []pool.Attachment{
+		{Filename: "logo.png", Content: []byte("image"), Inline: true},
+	}
+	msg, err := renderMsg(`<img src="cid:logo.png">`, "text", attachments, headers{"Subject": "test"})
+	if err != nil {
+		t.Fatalf("cannot render message: %v", err)
+	}
+	body := string(msg)
+	if !strings.Contains(body, "multipart/related") {
+		t.Errorf("message is not multipart/related: %v", body)
+	}
+	if !strings.Contains(body, "Content-ID: <logo.png>") {
+		t.Errorf("inline image has no Content-ID: %v", body)
+	}
+}
+
+func TestRewriteInlineImages(t *testing.T) {
+	attachments := []pool.Attachment{
+		{Filename: "logo.png", Inline: true},
+		{Filename: "doc.pdf"},
+	}
+	examples := []struct {
+		html string
+		exp  string
+	}{
+		{`<img src="logo.png">`, `<img src="cid:logo.png">`},
+		{`<img alt="logo" src='logo.png' />`, `<img alt="logo" src='cid:logo.png' />`},
+		{`<img src="other.png">`, `<img src="other.png">`},
+		{`<img src="doc.pdf">`, `<img src="doc.pdf">`},
+		{`<a href="logo.png">logo</a>`, `<a href="logo.png">logo</a>`},
+	}
+
+	for _, tt := range examples {
+		res := rewriteInlineImages(tt.html, attachments)
+		if res != tt.exp {
+			t.Errorf("wrong rewrite: %v != %v", res, tt.exp)
+		}
+	}
+}
+
+func TestDKIMHeadersWithReplyTo(t *testing.T) {
+	withReplyTo := []byte("From: sender@kannon.io\r\nTo: to@email.com\r\nReply-To: reply@email.com\r\n\r\nbody")
+	h := dkimHeaders(withReplyTo, nil)
+	if h[len(h)-1] != "Reply-To" {
+		t.Errorf("Reply-To should be signed when present: %v", h)
+	}
+
+	for _, k := range dkimHeaders([]byte("From: sender@kannon.io\r\nTo: to@email.com\r\n\r\nbody"), nil) {
+		if k == "Reply-To" {
+			t.Errorf("Reply-To should not be signed when not present")
+		}
+	}
+}
+
+func TestDKIMHeadersPresent(t *testing.T) {
+	msg := []byte("From: sender@kannon.io\r\nTo: to@email.com\r\nSubject: hi\r\nDate: Mon, 5 Jul 2021 10:00:00 +0000\r\n" +
+		"Message-Id: <1@kannon.io>\r\nList-Unsubscribe: <https://kannon.io/u>\r\n\r\nbody")
+
+	h := strings.Join(dkimHeaders(msg, nil), ",")
+	if h != "From,To,Subject,Date,Message-ID,List-Unsubscribe,List-Unsubscribe" {
+		t.Errorf("wrong default headers: %v", h)
+	}
+
+	h = strings.Join(dkimHeaders(msg, []string{"subject", "X-Missing", "Subject"}), ",")
+	if h != "From,subject,List-Unsubscribe,List-Unsubscribe" {
+		t.Errorf("wrong domain headers: %v", h)
+	}
+}
+
+func TestValidateDKIMHeaders(t *testing.T) {
+	if err := ValidateDKIMHeaders([]string{"From", "X-Campaign"}); err != nil {
+		t.Errorf("valid headers: %v", err)
+	}
+	for _, h := range []string{"X Campaign", "DKIM-Signature", ""} {
+		if err := ValidateDKIMHeaders([]string{h}); err == nil {
+			t.Errorf("%q should be invalid", h)
+		}
+	}
+}
+
+func TestRecipientFields(t *testing.T) {
+	fields, err := recipientFields(
+		[]byte(`{"name": "message", "company": "kannon"}`),
+		[]byte(`{"name": "recipient"}`),
+		"to@email.com",
+	)
+	if err != nil {
+		t.Fatalf("cannot build fields: %v", err)
+	}
+	if fields["name"] != "recipient" {
+		t.Errorf("recipient fields should override message fields: %v", fields)
+	}
+	if fields["company"] != "kannon" {
+		t.Errorf("message fields not propagated: %v", fields)
+	}
+	if fields["email"] != "to@email.com" {
+		t.Errorf("email field not correct: %v", fields)
+	}
+}
+
+func TestInjectOpenPixel(t *testing.T) {
+	pixel := `<img src="https://t.io/o/a.gif" width="1" height="1" alt="" style="display:none;border:0">`
+
+	res := injectOpenPixel("<html><BODY><p>hi</p></BODY></html>", "https://t.io/o/a.gif")
+	if res != "<html><BODY><p>hi</p>"+pixel+"</BODY></html>" {
+		t.Errorf("pixel not added before body end: %v", res)
+	}
+
+	res = injectOpenPixel("<p>hi</p>", "https://t.io/o/a.gif")
+	if res != "<p>hi</p>"+pixel {
+		t.Errorf("pixel not appended: %v", res)
+	}
+}
+
+func TestRewriteLinks(t *testing.T) {
+	track := func(url string) string {
+		return "https://t.io/c/" + url
+	}
+	examples := []struct {
+		html string
+		exp  string
+	}{
+		{`<a href="https://kannon.io">k</a>`, `<a href="https://t.io/c/https://kannon.io">k</a>`},
+		{`<A class="btn" HREF='http://kannon.io/?a=1&amp;b=2'>k</A>`, `<A class="btn" HREF='https://t.io/c/http://kannon.io/?a=1&amp;b=2'>k</A>`},
+		{`<a href="mailto:info@kannon.io">mail</a>`, `<a href="mailto:info@kannon.io">mail</a>`},
+		{`<a href="#top">top</a>`, `<a href="#top">top</a>`},
+		{`<img src="https://kannon.io/logo.png">`, `<img src="https://kannon.io/logo.png">`},
+	}
+
+	for _, tt := range examples {
+		res := rewriteLinks(tt.html, track)
+		if res != tt.exp {
+			t.Errorf("wrong rewrite: %v != %v", res, tt.exp)
+		}
+	}
+}
+
+func TestValidateSendingSettings(t *testing.T) {
+	valid := []SendingSettings{
+		{},
+		{FromEmail: "news@kannon.io", FromName: "Kannon"},
+		{FromEmail: "news@mail.kannon.io"},
+		{Headers: map[string]string{"X-Team": "growth"}},
+	}
+	for _, s := range valid {
+		if err := ValidateSendingSettings("kannon.io", s); err != nil {
+			t.Errorf("%+v should be valid: %v", s, err)
+		}
+	}
+
+	invalid := []SendingSettings{
+		{FromEmail: "news@other.io"},
+		{FromEmail: "news@notkannon.io"},
+		{FromEmail: "not an email"},
+		{FromName: "Kannon\r\nBcc: evil@other.io"},
+		{Headers: map[string]string{"From": "evil@other.io"}},
+	}
+	for _, s := range invalid {
+		if err := ValidateSendingSettings("kannon.io", s); err == nil {
+			t.Errorf("%+v should be invalid", s)
+		}
+	}
+}
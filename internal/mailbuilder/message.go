@@ -3,22 +3,96 @@ package mailbuilder
 import (
 	"encoding/base64"
 	"fmt"
+	"regexp"
+	"strings"
 
 	"kannon.gyozatech.dev/internal/pool"
 )
 
-func buildEmailMessageID(to string, messageID string) string {
+// BuildEmailMessageID returns the message id of the email
+// sent to a recipient of a message
+func BuildEmailMessageID(to string, messageID string) string {
 	emailBase64 := base64.URLEncoding.EncodeToString([]byte(to))
 	return fmt.Sprintf("<%v/%v>", emailBase64, messageID)
 }
 
-func buildReturnPath(to string, messageID string) string {
+// ParseEmailMessageID returns the recipient and the message id
+// of an email message id built for a pool email
+func ParseEmailMessageID(emailMessageID string) (string, string, error) {
+	id := strings.TrimSuffix(strings.TrimPrefix(emailMessageID, "<"), ">")
+	parts := strings.SplitN(id, "/", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("invalid email message id: %v", emailMessageID)
+	}
+	to, err := base64.URLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", "", fmt.Errorf("invalid email message id %v: %w", emailMessageID, err)
+	}
+	return string(to), parts[1], nil
+}
+
+// buildReturnPath returns the return path of the email sent to a recipient
+// of a message, like bump_<base64 recipient>-msg_<cuid>@<domain>. With a
+// return path domain the domain of the message id is kept in the local part,
+// like bump_<base64 recipient>-msg_<cuid>=<domain>@<return path domain>
+func buildReturnPath(to string, messageID string, returnPathDomain string) string {
 	emailBase64 := base64.URLEncoding.EncodeToString([]byte(to))
+	if returnPathDomain != "" {
+		messageID = strings.Replace(messageID, "@", "=", 1) + "@" + returnPathDomain
+	}
 	return fmt.Sprintf("bump_%v-%v", emailBase64, messageID)
 }
 
+// returnPathRegexp matches return paths built by buildReturnPath,
+// message ids are msg_<cuid>@<domain>
+var returnPathRegexp = regexp.MustCompile(`^bump_([A-Za-z0-9_\-=]+)-(msg_[a-z0-9]+)(?:=([^@=]+))?@(.+)$`)
+
+// ParseReturnPath returns the recipient and the message id
+// encoded in the return path of an email
+func ParseReturnPath(returnPath string) (string, string, error) {
+	m := returnPathRegexp.FindStringSubmatch(returnPath)
+	if m == nil {
+		return "", "", fmt.Errorf("invalid return path: %v", returnPath)
+	}
+	to, err := base64.URLEncoding.DecodeString(m[1])
+	if err != nil {
+		return "", "", fmt.Errorf("invalid return path %v: %w", returnPath, err)
+	}
+	domain := m[4]
+	if m[3] != "" {
+		domain = m[3]
+	}
+	return string(to), m[2] + "@" + domain, nil
+}
+
+// maxReturnPathDomainLen is the size of domains.return_path_domain
+const maxReturnPathDomainLen = 254
+
+// ValidateReturnPathDomain checks that the return path domain of a domain is
+// one of its subdomains, like bounce.kannon.io, empty is the domain itself
+func ValidateReturnPathDomain(domain string, returnPathDomain string) error {
+	if returnPathDomain == "" {
+		return nil
+	}
+	if len(returnPathDomain) > maxReturnPathDomainLen {
+		return fmt.Errorf("return path domain longer than %v characters", maxReturnPathDomainLen)
+	}
+	if !strings.HasSuffix(returnPathDomain, "."+domain) {
+		return fmt.Errorf("return path domain %v is not a subdomain of %v", returnPathDomain, domain)
+	}
+	for _, label := range strings.Split(returnPathDomain, ".") {
+		if !returnPathLabelRegexp.MatchString(label) {
+			return fmt.Errorf("invalid return path domain: %v", returnPathDomain)
+		}
+	}
+	return nil
+}
+
+// returnPathLabelRegexp matches the lowercase labels of hostnames
+var returnPathLabelRegexp = regexp.MustCompile(`^[a-z0-9]([a-z0-9\-]{0,61}[a-z0-9])?$`)
+
 // buildHeaders for a message
-func buildHeaders(subject string, sender pool.Sender, to string, poolMessageID string, messageID string, baseHeaders headers) headers {
+func buildHeaders(subject string, sender pool.Sender, to string, cc []string, replyTo string, poolMessageID string, messageID string, baseHeaders headers) headers {
 	h := make(headers)
 	for k, v := range baseHeaders {
 		h[k] = v
@@ -26,6 +100,12 @@ func buildHeaders(subject string, sender pool.Sender, to string, poolMessageID s
 	h["Subject"] = subject
 	h["From"] = fmt.Sprintf("%v <%v>", sender.Alias, sender.Email)
 	h["To"] = to
+	if len(cc) > 0 {
+		h["Cc"] = strings.Join(cc, ", ")
+	}
+	if replyTo != "" {
+		h["Reply-To"] = replyTo
+	}
 	h["Message-ID"] = messageID
 	h["X-Pool-Message-ID"] = poolMessageID
 	return h
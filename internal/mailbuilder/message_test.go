@@ -2,6 +2,7 @@ package mailbuilder
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"kannon.gyozatech.dev/internal/pool"
@@ -16,7 +17,7 @@ func TestBuildHeaders(t *testing.T) {
 	baseHeaders := headers{
 		"testH": "testH",
 	}
-	h := buildHeaders("test subject", sender, "to@email.com", "132@email.com", "<msg-123@email.com>", baseHeaders)
+	h := buildHeaders("test subject", sender, "to@email.com", nil, "", "132@email.com", "<msg-123@email.com>", baseHeaders)
 
 	if h["testH"] != "testH" {
 		t.Errorf("baseHeaders did not propagaged: %v", baseHeaders)
@@ -45,8 +46,96 @@ func TestBuildHeadersShouldCopyBaseHeader(t *testing.T) {
 		Alias: "email",
 	}
 
-	buildHeaders("test subject", sender, "to@email.com", "132@email.com", "<msg-123@email.com>", baseHeaders)
+	buildHeaders("test subject", sender, "to@email.com", nil, "", "132@email.com", "<msg-123@email.com>", baseHeaders)
 	if len(baseHeaders) != 1 {
 		t.Errorf("base headers has changed")
 	}
 }
+
+func TestBuildHeadersWithCc(t *testing.T) {
+	sender := pool.Sender{
+		Email: "from@email.com",
+		Alias: "email",
+	}
+
+	h := buildHeaders("test subject", sender, "to@email.com", []string{"cc1@email.com", "cc2@email.com"}, "", "132@email.com", "<msg-123@email.com>", headers{})
+	if h["Cc"] != "cc1@email.com, cc2@email.com" {
+		t.Errorf("Cc headers not correct: %v", h["Cc"])
+	}
+	if _, ok := h["Bcc"]; ok {
+		t.Errorf("Bcc headers should never be set")
+	}
+}
+
+func TestBuildHeadersWithReplyTo(t *testing.T) {
+	sender := pool.Sender{
+		Email: "from@email.com",
+		Alias: "email",
+	}
+
+	h := buildHeaders("test subject", sender, "to@email.com", nil, "reply@email.com", "132@email.com", "<msg-123@email.com>", headers{})
+	if h["Reply-To"] != "reply@email.com" {
+		t.Errorf("Reply-To headers not correct: %v", h["Reply-To"])
+	}
+
+	h = buildHeaders("test subject", sender, "to@email.com", nil, "", "132@email.com", "<msg-123@email.com>", headers{})
+	if _, ok := h["Reply-To"]; ok {
+		t.Errorf("Reply-To headers should not be set")
+	}
+}
+
+func TestParseEmailMessageID(t *testing.T) {
+	to, messageID, err := ParseEmailMessageID(BuildEmailMessageID("to@email.com", "msg_123@email.com"))
+	if err != nil {
+		t.Fatalf("cannot parse message id: %v", err)
+	}
+	if to != "to@email.com" {
+		t.Errorf("wrong recipient: %v", to)
+	}
+	if messageID != "msg_123@email.com" {
+		t.Errorf("wrong message id: %v", messageID)
+	}
+
+	if _, _, err := ParseEmailMessageID("<msg_123@email.com>"); err == nil {
+		t.Errorf("invalid message id should not be parsed")
+	}
+}
+
+func TestParseReturnPath(t *testing.T) {
+	// base64 of the recipient can contain - and _
+	for _, email := range []string{"to@email.com", "a>>>?@email.com"} {
+		for _, returnPathDomain := range []string{"", "bounce.kannon.io"} {
+			returnPath := buildReturnPath(email, "msg_ckp1hz2ef0000@kannon.io", returnPathDomain)
+			if returnPathDomain != "" && !strings.HasSuffix(returnPath, "=kannon.io@bounce.kannon.io") {
+				t.Errorf("wrong return path domain: %v", returnPath)
+			}
+			to, messageID, err := ParseReturnPath(returnPath)
+			if err != nil {
+				t.Fatalf("cannot parse return path: %v", err)
+			}
+			if to != email {
+				t.Errorf("wrong recipient: %v != %v", to, email)
+			}
+			if messageID != "msg_ckp1hz2ef0000@kannon.io" {
+				t.Errorf("wrong message id: %v", messageID)
+			}
+		}
+	}
+
+	if _, _, err := ParseReturnPath("postmaster@kannon.io"); err == nil {
+		t.Errorf("invalid return path should not be parsed")
+	}
+}
+
+func TestValidateReturnPathDomain(t *testing.T) {
+	for _, d := range []string{"", "bounce.kannon.io", "em-1.mail.kannon.io"} {
+		if err := ValidateReturnPathDomain("kannon.io", d); err != nil {
+			t.Errorf("%v should be valid: %v", d, err)
+		}
+	}
+	for _, d := range []string{"kannon.io", "bounce.other.io", "bouncekannon.io", "Bounce.kannon.io", "-a.kannon.io", "a..kannon.io"} {
+		if err := ValidateReturnPathDomain("kannon.io", d); err == nil {
+			t.Errorf("%v should be invalid", d)
+		}
+	}
+}
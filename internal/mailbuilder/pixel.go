@@ -0,0 +1,24 @@
+package mailbuilder
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// injectOpenPixel adds a 1x1 tracking image before the closing
+// body tag of html, or at its end when there is no body
+func injectOpenPixel(body string, url string) string {
+	pixel := fmt.Sprintf(`<img src="%v" width="1" height="1" alt="" style="display:none;border:0">`, html.EscapeString(url))
+	return injectBeforeBodyEnd(body, pixel)
+}
+
+// injectBeforeBodyEnd adds s before the closing body tag
+// of html, or at its end when there is no body
+func injectBeforeBodyEnd(body string, s string) string {
+	i := strings.LastIndex(strings.ToLower(body), "</body>")
+	if i < 0 {
+		return body + s
+	}
+	return body[:i] + s + body[i:]
+}
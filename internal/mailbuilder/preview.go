@@ -0,0 +1,69 @@
+package mailbuilder
+
+import (
+	"bytes"
+	netmail "net/mail"
+	"strings"
+
+	"kannon.gyozatech.dev/internal/dkim"
+	"kannon.gyozatech.dev/internal/pool"
+	"kannon.gyozatech.dev/internal/templates"
+)
+
+// Preview is the rendered message of a recipient
+type Preview struct {
+	Subject string
+	HTML    string
+	Text    string
+	Headers map[string]string
+	// Message is the signed message
+	Message []byte
+}
+
+// PreviewMessage renders the message of a pool for a recipient as it would be sent,
+// with the sending settings of the domain and signed with its DKIM keys and signed headers,
+// tracking is not added
+func PreviewMessage(pm pool.PoolMessage, to pool.Recipient, keys dkim.DomainKeys, signedHeaders []string, settings SendingSettings) (Preview, error) {
+	fields := make(map[string]string)
+	for _, f := range []map[string]string{pm.Fields, to.Fields} {
+		for k, v := range f {
+			fields[k] = v
+		}
+	}
+	fields[templates.EmailField] = to.Email
+
+	subject, html, text := renderContent(pm.Subject, pm.Template.Html, pm.Template.Text, fields)
+	html, text = settings.addFooters(html, text, fields)
+
+	h := mergeHeaders(mergeHeaders(defaultHeaders, settings.Headers), pm.Headers)
+	msg, err := prepareMessage(settings.sender(pm.From), subject, to.Email, pm.Cc, pm.ReplyTo, previewMessageID(pm.Domain), html, text, pm.Attachments, h)
+	if err != nil {
+		return Preview{}, err
+	}
+
+	signedMsg, err := signMessage(pm.Domain, keys, dkimHeaders(msg, signedHeaders), msg)
+	if err != nil {
+		return Preview{}, err
+	}
+
+	parsed, err := netmail.ReadMessage(bytes.NewReader(signedMsg))
+	if err != nil {
+		return Preview{}, err
+	}
+	previewHeaders := make(map[string]string)
+	for k, v := range parsed.Header {
+		previewHeaders[k] = strings.Join(v, ", ")
+	}
+
+	return Preview{
+		Subject: subject,
+		HTML:    rewriteInlineImages(html, pm.Attachments),
+		Text:    text,
+		Headers: previewHeaders,
+		Message: signedMsg,
+	}, nil
+}
+
+func previewMessageID(domain string) string {
+	return "msg_preview@" + domain
+}
@@ -0,0 +1,83 @@
+package mailbuilder
+
+import (
+	"strings"
+	"testing"
+
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/dkim"
+	"kannon.gyozatech.dev/internal/pool"
+)
+
+func TestPreviewMessage(t *testing.T) {
+	keys, err := dkim.GenerateDKIMKeysPair()
+	if err != nil {
+		t.Fatalf("cannot generate keys: %v", err)
+	}
+
+	preview, err := PreviewMessage(pool.PoolMessage{
+		Template: sqlc.Template{Html: "<p>Hello {{ name }}</p>"},
+		From:     pool.Sender{Email: "sender@kannon.io", Alias: "Kannon"},
+		Subject:  "Hi {{ name }}",
+		Domain:   "kannon.io",
+		Headers:  map[string]string{"X-Campaign": "test"},
+		Fields:   map[string]string{"name": "Ludovico"},
+	}, pool.Recipient{Email: "to@email.com"}, dkim.DomainKeys{RSAPrivateKey: keys.PrivateKey}, nil, SendingSettings{})
+	if err != nil {
+		t.Fatalf("cannot build preview: %v", err)
+	}
+
+	if preview.Subject != "Hi Ludovico" {
+		t.Errorf("wrong subject: %v", preview.Subject)
+	}
+	if preview.HTML != "<p>Hello Ludovico</p>" {
+		t.Errorf("wrong html: %v", preview.HTML)
+	}
+	if preview.Text != "Hello Ludovico" {
+		t.Errorf("wrong text: %v", preview.Text)
+	}
+	for _, h := range []string{"X-Campaign", "X-Mailer", "Dkim-Signature", "Message-Id"} {
+		if preview.Headers[h] == "" {
+			t.Errorf("missing header %v: %v", h, preview.Headers)
+		}
+	}
+	if !strings.Contains(preview.Headers["To"], "to@email.com") {
+		t.Errorf("wrong to header: %v", preview.Headers["To"])
+	}
+}
+
+func TestPreviewMessageWithSendingSettings(t *testing.T) {
+	keys, err := dkim.GenerateDKIMKeysPair()
+	if err != nil {
+		t.Fatalf("cannot generate keys: %v", err)
+	}
+
+	preview, err := PreviewMessage(pool.PoolMessage{
+		Template: sqlc.Template{Html: "<html><body><p>Hello</p></body></html>"},
+		Subject:  "Hi",
+		Domain:   "kannon.io",
+		Headers:  map[string]string{"X-Campaign": "message"},
+	}, pool.Recipient{Email: "to@email.com"}, dkim.DomainKeys{RSAPrivateKey: keys.PrivateKey}, nil, SendingSettings{
+		FromEmail:  "news@kannon.io",
+		FromName:   "Kannon",
+		Headers:    map[string]string{"X-Campaign": "domain", "X-Team": "growth"},
+		FooterHTML: "<p>Sent to {{ email }}</p>",
+		FooterText: "Sent to {{ email }}",
+	})
+	if err != nil {
+		t.Fatalf("cannot build preview: %v", err)
+	}
+
+	if !strings.Contains(preview.Headers["From"], "news@kannon.io") || !strings.Contains(preview.Headers["From"], "Kannon") {
+		t.Errorf("default sender not used: %v", preview.Headers["From"])
+	}
+	if preview.Headers["X-Campaign"] != "message" || preview.Headers["X-Team"] != "growth" {
+		t.Errorf("wrong default headers: %v", preview.Headers)
+	}
+	if preview.HTML != "<html><body><p>Hello</p><p>Sent to to@email.com</p></body></html>" {
+		t.Errorf("wrong html footer: %v", preview.HTML)
+	}
+	if preview.Text != "Hello\n\nSent to to@email.com" {
+		t.Errorf("wrong text footer: %q", preview.Text)
+	}
+}
@@ -0,0 +1,96 @@
+package mailbuilder
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"kannon.gyozatech.dev/internal/pool"
+	"kannon.gyozatech.dev/internal/smtp"
+	"kannon.gyozatech.dev/internal/templates"
+)
+
+// maxDefaultFromLen is the size of domains.default_from_email and default_from_name
+const maxDefaultFromLen = 254
+
+// SendingSettings are the settings of a domain applied to its emails
+type SendingSettings struct {
+	// FromEmail and FromName are used when the sender of a message has none
+	FromEmail string
+	FromName  string
+	// Headers are added to every email, custom headers of messages override them
+	Headers map[string]string
+	// OpenTracking adds an open tracking pixel to html bodies
+	OpenTracking bool
+	// ClickTracking rewrites the http links of html bodies to tracked links
+	ClickTracking bool
+	// FooterHTML and FooterText are rendered with the fields of
+	// the recipient and appended to the html and text bodies
+	FooterHTML string
+	FooterText string
+}
+
+// ValidateSendingSettings checks that the default sender of a domain is an address
+// of the domain or of one of its subdomains and that the default headers are valid
+func ValidateSendingSettings(domain string, s SendingSettings) error {
+	if len(s.FromEmail) > maxDefaultFromLen || len(s.FromName) > maxDefaultFromLen {
+		return fmt.Errorf("default from longer than %v characters", maxDefaultFromLen)
+	}
+	if s.FromEmail != "" {
+		if !smtp.Validate(s.FromEmail) {
+			return fmt.Errorf("invalid default from email: %v", s.FromEmail)
+		}
+		fromDomain, err := smtp.GetEmailDomain(s.FromEmail)
+		if err != nil {
+			return err
+		}
+		fromDomain = strings.ToLower(fromDomain)
+		if fromDomain != domain && !strings.HasSuffix(fromDomain, "."+domain) {
+			return fmt.Errorf("default from email %v is not an address of %v", s.FromEmail, domain)
+		}
+	}
+	if strings.ContainsAny(s.FromName, "\r\n") {
+		return fmt.Errorf("invalid default from name")
+	}
+	return ValidateCustomHeaders(s.Headers)
+}
+
+// ParseDefaultHeaders parses domains.default_headers
+func ParseDefaultHeaders(data json.RawMessage) (map[string]string, error) {
+	h := make(map[string]string)
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &h); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// sender returns from with the default email and name of the settings
+// when it has none
+func (s SendingSettings) sender(from pool.Sender) pool.Sender {
+	if from.Email == "" {
+		from.Email = s.FromEmail
+	}
+	if from.Alias == "" {
+		from.Alias = s.FromName
+	}
+	return from
+}
+
+// addFooters appends the footers rendered with fields to html and text,
+// text is built from html before the footers are added when empty
+func (s SendingSettings) addFooters(html, text string, fields map[string]string) (string, string) {
+	if text == "" {
+		text = htmlToText(html)
+	}
+	if s.FooterHTML != "" {
+		footer, _ := templates.RenderHTML(s.FooterHTML, fields, templates.Lenient)
+		html = injectBeforeBodyEnd(html, footer)
+	}
+	if s.FooterText != "" {
+		footer, _ := templates.Render(s.FooterText, fields, templates.Lenient)
+		text = strings.TrimRight(text, "\r\n") + "\n\n" + footer
+	}
+	return html, text
+}
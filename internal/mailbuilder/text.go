@@ -0,0 +1,132 @@
+package mailbuilder
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// htmlToText builds a plain-text version of an html body,
+// used as text/plain alternative when no text is provided
+func htmlToText(body string) string {
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(body))
+
+	skip := 0
+	var href string
+	var linkText strings.Builder
+
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return cleanText(b.String())
+
+		case html.TextToken:
+			if skip > 0 {
+				continue
+			}
+			text := collapseSpaces(string(z.Text()))
+			if href != "" {
+				linkText.WriteString(text)
+				continue
+			}
+			b.WriteString(text)
+
+		case html.StartTagToken, html.SelfClosingTagToken, html.EndTagToken:
+			name, hasAttr := z.TagName()
+			a := atom.Lookup(name)
+			isEnd := tt == html.EndTagToken
+
+			switch a {
+			case atom.Script, atom.Style, atom.Head, atom.Title:
+				if tt == html.StartTagToken {
+					skip++
+				} else if isEnd && skip > 0 {
+					skip--
+				}
+			case atom.Br:
+				b.WriteString("\n")
+			case atom.P, atom.Div, atom.Tr, atom.Table, atom.Ul, atom.Ol,
+				atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+				b.WriteString("\n\n")
+			case atom.Li:
+				if !isEnd {
+					b.WriteString("\n- ")
+				}
+			case atom.Td, atom.Th:
+				if isEnd {
+					b.WriteString(" ")
+				}
+			case atom.A:
+				if !isEnd {
+					href = attr(z, hasAttr, "href")
+					linkText.Reset()
+					continue
+				}
+				text := strings.TrimSpace(linkText.String())
+				switch {
+				case href == "" || strings.HasPrefix(href, "#"):
+					b.WriteString(text)
+				case text == "" || text == href:
+					b.WriteString(href)
+				default:
+					b.WriteString(fmt.Sprintf("%v (%v)", text, href))
+				}
+				href = ""
+			}
+		}
+	}
+}
+
+func attr(z *html.Tokenizer, hasAttr bool, name string) string {
+	for hasAttr {
+		var key, val []byte
+		key, val, hasAttr = z.TagAttr()
+		if string(key) == name {
+			return string(val)
+		}
+	}
+	return ""
+}
+
+func collapseSpaces(s string) string {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		if s != "" {
+			return " "
+		}
+		return ""
+	}
+	res := strings.Join(fields, " ")
+	if strings.TrimLeft(s, " \t\r\n") != s {
+		res = " " + res
+	}
+	if strings.TrimRight(s, " \t\r\n") != s {
+		res += " "
+	}
+	return res
+}
+
+// cleanText trims every line and removes more than
+// one consecutive empty line
+func cleanText(s string) string {
+	lines := strings.Split(s, "\n")
+	res := make([]string, 0, len(lines))
+	empty := 0
+	for _, l := range lines {
+		l = strings.TrimSpace(l)
+		if l == "" {
+			empty++
+			if empty > 1 || len(res) == 0 {
+				continue
+			}
+		} else {
+			empty = 0
+		}
+		res = append(res, l)
+	}
+	return strings.TrimSpace(strings.Join(res, "\n"))
+}
@@ -0,0 +1,30 @@
+package mailbuilder
+
+import (
+	"testing"
+)
+
+func TestHTMLToText(t *testing.T) {
+	examples := []struct {
+		name string
+		html string
+		text string
+	}{
+		{"simple", "<h1>ciao</h1><p>prova</p>", "ciao\n\nprova"},
+		{"line break", "first<br>second", "first\nsecond"},
+		{"link", `<p>go to <a href="https://kannon.io">kannon</a></p>`, "go to kannon (https://kannon.io)"},
+		{"link with url text", `<a href="https://kannon.io">https://kannon.io</a>`, "https://kannon.io"},
+		{"list", "<ul><li>one</li><li>two</li></ul>", "- one\n- two"},
+		{"skip style", "<html><head><style>p {color: red}</style></head><body><p>body</p></body></html>", "body"},
+		{"spaces", "<p>  many \n   spaces  </p>", "many spaces"},
+	}
+
+	for _, tt := range examples {
+		t.Run(tt.name, func(t *testing.T) {
+			text := htmlToText(tt.html)
+			if text != tt.text {
+				t.Errorf("wrong text: %q != %q", text, tt.text)
+			}
+		})
+	}
+}
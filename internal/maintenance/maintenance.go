@@ -0,0 +1,189 @@
+package maintenance
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"kannon.gyozatech.dev/generated/pb"
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/metrics"
+	"kannon.gyozatech.dev/internal/queue"
+)
+
+var log = logging.Logger("maintenance")
+
+// Subject is the subject of the states of the switch, published by the api
+// on every change and by the dispatchers every sync interval
+const Subject = "maintenance.state"
+
+// State is the state of the switch halting every dispatch and send
+type State struct {
+	Halted bool
+	Reason string
+	// ChangedAt is the time of the last halt or resume, zero when never halted
+	ChangedAt time.Time
+}
+
+// Manager halts and resumes the dispatches and sends of every domain
+type Manager interface {
+	Get() (State, error)
+	Halt(reason string) (State, error)
+	Resume() (State, error)
+}
+
+type manager struct {
+	db *sqlc.Queries
+}
+
+// NewManager builds a Manager storing the state of the switch in db
+func NewManager(db *sql.DB) (Manager, error) {
+	return &manager{
+		db: sqlc.New(metrics.InstrumentDB(db)),
+	}, nil
+}
+
+// Get returns the state of the switch, not halted when never halted
+func (m *manager) Get() (State, error) {
+	row, err := m.db.GetMaintenance(context.TODO())
+	if errors.Is(err, sql.ErrNoRows) {
+		return State{}, nil
+	}
+	if err != nil {
+		return State{}, err
+	}
+	return buildState(row), nil
+}
+
+// Halt halts every dispatch and send for reason
+func (m *manager) Halt(reason string) (State, error) {
+	return m.set(true, reason)
+}
+
+// Resume resumes the dispatches and sends
+func (m *manager) Resume() (State, error) {
+	return m.set(false, "")
+}
+
+func (m *manager) set(halted bool, reason string) (State, error) {
+	row, err := m.db.SetMaintenance(context.TODO(), sqlc.SetMaintenanceParams{
+		Halted: halted,
+		Reason: reason,
+	})
+	if err != nil {
+		return State{}, err
+	}
+	return buildState(row), nil
+}
+
+func buildState(row sqlc.Maintenance) State {
+	return State{
+		Halted:    row.Halted,
+		Reason:    row.Reason,
+		ChangedAt: row.ChangedAt,
+	}
+}
+
+// Publish publishes a state on Subject, brokers without subscriptions have
+// no switch to notify
+func Publish(p queue.Publisher, state State) error {
+	if _, ok := p.(queue.Subscriber); !ok {
+		return nil
+	}
+	msg, err := proto.Marshal(&pb.MaintenanceState{
+		Halted:    state.Halted,
+		Reason:    state.Reason,
+		ChangedAt: timestamppb.New(state.ChangedAt),
+	})
+	if err != nil {
+		return err
+	}
+	return p.Publish(Subject, msg)
+}
+
+// Switch is the last state of the switch received by a daemon
+type Switch struct {
+	mu    sync.RWMutex
+	state State
+}
+
+// State returns the last state received, not halted before the first one
+func (s *Switch) State() State {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state
+}
+
+// set replaces the state of s with a newer state,
+// states published before the last change are ignored
+func (s *Switch) set(state State) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if state.ChangedAt.Before(s.state.ChangedAt) {
+		return
+	}
+	if state.Halted != s.state.Halted {
+		if state.Halted {
+			log.Warnf("[🛑 halted] every dispatch and send is halted: %v", state.Reason)
+		} else {
+			log.Infof("[▶️ resumed] dispatches and sends are resumed")
+		}
+	}
+	s.state = state
+	if state.Halted {
+		metrics.SendingHalted.Set(1)
+	} else {
+		metrics.SendingHalted.Set(0)
+	}
+}
+
+// Watch returns a Switch updated by the states published on Subject,
+// the switches of brokers without subscriptions are updated only by Sync
+func Watch(b queue.Broker) (*Switch, error) {
+	sw := &Switch{}
+	sub, ok := b.(queue.Subscriber)
+	if !ok {
+		return sw, nil
+	}
+	err := sub.Subscribe(Subject, func(msg queue.Message) {
+		var state pb.MaintenanceState
+		if err := proto.Unmarshal(msg.Data(), &state); err != nil {
+			log.Errorf("invalid maintenance state: %v", err)
+			return
+		}
+		sw.set(State{
+			Halted:    state.Halted,
+			Reason:    state.Reason,
+			ChangedAt: state.ChangedAt.AsTime(),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sw, nil
+}
+
+// Sync updates sw with the state of m and publishes it every interval until
+// ctx is done, the switches missing a change or started later are updated
+func Sync(ctx context.Context, m Manager, sw *Switch, p queue.Publisher, interval time.Duration) {
+	for ctx.Err() == nil {
+		state, err := m.Get()
+		if err != nil {
+			log.Errorf("cannot get maintenance state: %v", err)
+		} else {
+			sw.set(state)
+			if err := Publish(p, state); err != nil {
+				log.Errorf("cannot publish maintenance state: %v", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"kannon.gyozatech.dev/internal/queue"
+)
+
+func TestSwitch(t *testing.T) {
+	b, err := queue.Open(queue.Config{Broker: "memory"}, nil)
+	assert.Nil(t, err)
+	sw, err := Watch(b)
+	assert.Nil(t, err)
+	assert.False(t, sw.State().Halted)
+
+	now := time.Now().UTC()
+	assert.Nil(t, Publish(b, State{Halted: true, Reason: "incident", ChangedAt: now}))
+	assert.Eventually(t, func() bool { return sw.State().Halted }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "incident", sw.State().Reason)
+
+	// a state published by a dispatcher before the last change is ignored
+	sw.set(State{ChangedAt: now.Add(-time.Minute)})
+	assert.True(t, sw.State().Halted)
+
+	sw.set(State{ChangedAt: now.Add(time.Minute)})
+	assert.False(t, sw.State().Halted)
+}
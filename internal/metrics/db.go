@@ -0,0 +1,187 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"kannon.gyozatech.dev/internal/tracing"
+)
+
+// InstrumentedDB is a database observing the duration of its queries in
+// DBQueryDuration, it can be the database of sqlc.New. Queries with the
+// span of a trace in their context are spans of the trace
+type InstrumentedDB struct {
+	db conn
+}
+
+// conn runs the queries of an InstrumentedDB, *sql.DB and *sql.Tx are conns
+type conn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// InstrumentDB returns db observing the duration of its queries
+func InstrumentDB(db *sql.DB) *InstrumentedDB {
+	return &InstrumentedDB{db: db}
+}
+
+// InstrumentTx returns tx observing the duration of its queries like InstrumentDB,
+// for the queries of a transaction
+func InstrumentTx(tx *sql.Tx) *InstrumentedDB {
+	return &InstrumentedDB{db: tx}
+}
+
+// ExecContext executes a query without rows
+func (i *InstrumentedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, done := observeQuery(ctx, query)
+	res, err := i.db.ExecContext(ctx, query, args...)
+	done(err)
+	return res, err
+}
+
+// PrepareContext prepares a statement, executions of prepared statements are not observed
+func (i *InstrumentedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return i.db.PrepareContext(ctx, query)
+}
+
+// QueryContext executes a query returning rows
+func (i *InstrumentedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, done := observeQuery(ctx, query)
+	rows, err := i.db.QueryContext(ctx, query, args...)
+	done(err)
+	return rows, err
+}
+
+// QueryRowContext executes a query returning at most one row
+func (i *InstrumentedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, done := observeQuery(ctx, query)
+	row := i.db.QueryRowContext(ctx, query, args...)
+	done(row.Err())
+	return row
+}
+
+// slowQueryThreshold are the nanoseconds of the slowest queries not logged, 0 disables the logs
+var slowQueryThreshold int64
+
+// SetSlowQueryThreshold logs the queries of the instrumented databases
+// slower than threshold, 0 disables the logs
+func SetSlowQueryThreshold(threshold time.Duration) {
+	atomic.StoreInt64(&slowQueryThreshold, int64(threshold))
+}
+
+// isSlowQuery reports if a query of duration d is logged
+func isSlowQuery(d time.Duration) bool {
+	threshold := atomic.LoadInt64(&slowQueryThreshold)
+	return threshold > 0 && int64(d) > threshold
+}
+
+// observeQuery starts the observation of a query, done ends it with the
+// error of the query. The query is a span child of the span of ctx if any
+func observeQuery(ctx context.Context, query string) (context.Context, func(err error)) {
+	name := queryName(query)
+	var span *tracing.Span
+	if tracing.SpanContextFromContext(ctx).IsValid() {
+		ctx, span = tracing.StartSpan(ctx, "db "+name, tracing.Client,
+			tracing.String("db.system", "postgresql"),
+			tracing.String("db.operation", name),
+		)
+	}
+	start := time.Now()
+	return ctx, func(err error) {
+		elapsed := time.Since(start)
+		DBQueryDuration.WithLabelValues(name).Observe(elapsed.Seconds())
+		if isSlowQuery(elapsed) {
+			log.Warnf("[🐢 slow query] %v took %v", name, elapsed)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			span.SetError(err)
+		}
+		span.End()
+	}
+}
+
+// queryName returns the name of a sqlc query, like GetDomain for
+// "-- name: GetDomain :one", other for queries without name
+func queryName(query string) string {
+	if !strings.HasPrefix(query, "-- name: ") {
+		return "other"
+	}
+	fields := strings.Fields(strings.TrimPrefix(query, "-- name: "))
+	if len(fields) == 0 {
+		return "other"
+	}
+	return fields[0]
+}
+
+// dbStats collects the stats of the connection pools of the watched databases,
+// summed when a process opens more than one
+type dbStats struct {
+	mu  sync.Mutex
+	dbs []*sql.DB
+
+	open         *prometheus.Desc
+	inUse        *prometheus.Desc
+	idle         *prometheus.Desc
+	waitCount    *prometheus.Desc
+	waitDuration *prometheus.Desc
+}
+
+var watchedDBs = &dbStats{
+	open:         prometheus.NewDesc("kannon_db_open_connections", "Open connections to the database", nil, nil),
+	inUse:        prometheus.NewDesc("kannon_db_in_use_connections", "Connections to the database in use", nil, nil),
+	idle:         prometheus.NewDesc("kannon_db_idle_connections", "Idle connections to the database", nil, nil),
+	waitCount:    prometheus.NewDesc("kannon_db_wait_total", "Waits for a free connection to the database", nil, nil),
+	waitDuration: prometheus.NewDesc("kannon_db_wait_seconds_total", "Time waited for a free connection to the database", nil, nil),
+}
+
+func init() {
+	prometheus.MustRegister(watchedDBs)
+}
+
+// WatchDB exports the stats of the connection pool of db, like kannon_db_open_connections
+func WatchDB(db *sql.DB) {
+	watchedDBs.mu.Lock()
+	defer watchedDBs.mu.Unlock()
+	for _, w := range watchedDBs.dbs {
+		if w == db {
+			return
+		}
+	}
+	watchedDBs.dbs = append(watchedDBs.dbs, db)
+}
+
+func (s *dbStats) Describe(ch chan<- *prometheus.Desc) {
+	ch <- s.open
+	ch <- s.inUse
+	ch <- s.idle
+	ch <- s.waitCount
+	ch <- s.waitDuration
+}
+
+func (s *dbStats) Collect(ch chan<- prometheus.Metric) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var total sql.DBStats
+	for _, db := range s.dbs {
+		stats := db.Stats()
+		total.OpenConnections += stats.OpenConnections
+		total.InUse += stats.InUse
+		total.Idle += stats.Idle
+		total.WaitCount += stats.WaitCount
+		total.WaitDuration += stats.WaitDuration
+	}
+	ch <- prometheus.MustNewConstMetric(s.open, prometheus.GaugeValue, float64(total.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(s.inUse, prometheus.GaugeValue, float64(total.InUse))
+	ch <- prometheus.MustNewConstMetric(s.idle, prometheus.GaugeValue, float64(total.Idle))
+	ch <- prometheus.MustNewConstMetric(s.waitCount, prometheus.CounterValue, float64(total.WaitCount))
+	ch <- prometheus.MustNewConstMetric(s.waitDuration, prometheus.CounterValue, total.WaitDuration.Seconds())
+}
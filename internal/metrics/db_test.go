@@ -0,0 +1,23 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryName(t *testing.T) {
+	assert.Equal(t, "GetDomain", queryName("-- name: GetDomain :one\nSELECT * FROM domains WHERE domain = $1"))
+	assert.Equal(t, "CancelDomainPool", queryName("-- name: CancelDomainPool :execrows\nUPDATE sending_pool_emails"))
+	assert.Equal(t, "other", queryName("SELECT 1"))
+	assert.Equal(t, "other", queryName("-- name: "))
+}
+
+func TestIsSlowQuery(t *testing.T) {
+	defer SetSlowQueryThreshold(0)
+	assert.False(t, isSlowQuery(time.Hour))
+	SetSlowQueryThreshold(time.Second)
+	assert.False(t, isSlowQuery(time.Second))
+	assert.True(t, isSlowQuery(2*time.Second))
+}
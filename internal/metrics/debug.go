@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"sync"
+)
+
+var publishOnce sync.Once
+
+// debugAddrs are the addresses of the debug servers, daemons
+// of a process share the server of an address
+var debugAddrs = struct {
+	sync.Mutex
+	served map[string]bool
+}{served: make(map[string]bool)}
+
+// ServeDebug exposes the pprof profiles on /debug/pprof/ and the runtime
+// stats on /debug/vars of addr in background, like localhost:6060.
+// Nothing is exposed when addr is empty or already served
+func ServeDebug(addr string) {
+	if addr == "" {
+		return
+	}
+	debugAddrs.Lock()
+	defer debugAddrs.Unlock()
+	if debugAddrs.served[addr] {
+		return
+	}
+	debugAddrs.served[addr] = true
+	log.Infof("🐛 starting debug server on %v", addr)
+	go func() {
+		if err := http.ListenAndServe(addr, debugHandler()); err != nil {
+			log.Errorf("cannot serve debug server: %v", err)
+		}
+	}()
+}
+
+func debugHandler() http.Handler {
+	// expvar publishes the memstats and the cmdline
+	publishOnce.Do(func() {
+		expvar.Publish("goroutines", expvar.Func(func() interface{} {
+			return runtime.NumGoroutine()
+		}))
+	})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	return mux
+}
@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugHandler(t *testing.T) {
+	h := debugHandler()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var vars map[string]interface{}
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &vars))
+	assert.Contains(t, vars, "goroutines")
+	assert.Contains(t, vars, "memstats")
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/goroutine?debug=1", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// the handler is created again without publishing the vars twice
+	assert.NotNil(t, debugHandler())
+}
@@ -0,0 +1,158 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"kannon.gyozatech.dev/internal/health"
+	"kannon.gyozatech.dev/internal/logging"
+)
+
+var log = logging.Logger("metrics")
+
+var (
+	// ConsumedMessages counts the messages handled by JetStream consumers
+	ConsumedMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kannon_consumed_messages_total",
+		Help: "Messages handled by JetStream consumers",
+	}, []string{"consumer"})
+
+	// ConsumerErrors counts the errors fetching messages of JetStream consumers
+	ConsumerErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kannon_consumer_errors_total",
+		Help: "Errors fetching messages of JetStream consumers",
+	}, []string{"consumer"})
+
+	// ConsumerPending is the number of messages left to a consumer, updated
+	// when the consumer fetches a message and, on NATS, every 15s
+	ConsumerPending = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kannon_consumer_pending_messages",
+		Help: "Messages left to consumers",
+	}, []string{"consumer"})
+
+	// PublishedMessages counts the published messages by subject
+	PublishedMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kannon_published_messages_total",
+		Help: "Messages published by subject",
+	}, []string{"subject"})
+
+	// AckedMessages counts the acked messages by subject
+	AckedMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kannon_acked_messages_total",
+		Help: "Messages acked by subject",
+	}, []string{"subject"})
+
+	// SMTPDeliveries counts the deliveries of recipients by source IP,
+	// IP family (ipv4 or ipv6) and result: delivered, bounced or deferred
+	SMTPDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kannon_smtp_deliveries_total",
+		Help: "SMTP deliveries of recipients by source IP, IP family and result",
+	}, []string{"ip", "family", "result"})
+
+	// SMTPErrors counts the failed deliveries of recipients by SMTP code,
+	// 111 are the MXs that couldn't be reached
+	SMTPErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kannon_smtp_errors_total",
+		Help: "Failed SMTP deliveries of recipients by SMTP code",
+	}, []string{"code"})
+
+	// SMTPResponses counts the responses of the MXs to every delivery attempt of a
+	// recipient by receiving provider, like gmail, SMTP code and RFC 3463 enhanced
+	// status code, 250 are the delivered recipients and 111 the MXs that couldn't be reached
+	SMTPResponses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kannon_smtp_responses_total",
+		Help: "SMTP responses to the deliveries of recipients by receiving provider, SMTP code and enhanced status code",
+	}, []string{"provider", "code", "enhanced_code"})
+
+	// SMTPDeliveryDuration is the duration of the SMTP transactions, from the
+	// connection or the reuse of an idle connection, by result: delivered,
+	// bounced or deferred
+	SMTPDeliveryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kannon_smtp_delivery_duration_seconds",
+		Help:    "Duration of the SMTP transactions by result",
+		Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"result"})
+
+	// DispatchedEmails counts the emails handled by the dispatcher by result:
+	// dispatched, suppressed, quota_exceeded or failed
+	DispatchedEmails = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kannon_dispatched_emails_total",
+		Help: "Emails handled by the dispatcher by result",
+	}, []string{"result"})
+
+	// DBQueryDuration is the duration of the queries of the database by name
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kannon_db_query_duration_seconds",
+		Help:    "Duration of the database queries by name",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"query"})
+
+	// PendingPoolEmails is the number of pool emails
+	// scheduled in the past and not yet dispatched
+	PendingPoolEmails = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "kannon_pool_pending_emails",
+		Help: "Pool emails waiting to be dispatched",
+	})
+
+	// InFlightPoolEmails is the number of pool emails
+	// dispatched and not yet delivered or bounced
+	InFlightPoolEmails = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "kannon_pool_in_flight_emails",
+		Help: "Pool emails dispatched and not yet delivered or bounced",
+	})
+
+	// SenderDiagnostics are the results of the checks of the setup
+	// of the sender on start, 1 when passed and 0 when failed
+	SenderDiagnostics = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kannon_sender_diagnostic",
+		Help: "Checks of the sender setup on start, 1 when passed",
+	}, []string{"check", "target"})
+
+	// SendingHalted is 1 while the maintenance switch halts
+	// every dispatch and send
+	SendingHalted = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "kannon_sending_halted",
+		Help: "Dispatches and sends halted by the maintenance switch, 1 when halted",
+	})
+)
+
+// servers are the checks of the metrics servers by port, the daemons
+// of a process serving on the same port share its server
+var servers = struct {
+	sync.Mutex
+	checks map[uint16][]health.Check
+}{checks: make(map[uint16][]health.Check)}
+
+// Serve exposes the metrics on /metrics of port in background, with the
+// /healthz and /readyz probes of checks. Nothing is exposed when port is 0,
+// the checks of a port already served are added to its probes
+func Serve(port uint16, checks ...health.Check) {
+	if port == 0 {
+		return
+	}
+	servers.Lock()
+	defer servers.Unlock()
+	_, serving := servers.checks[port]
+	servers.checks[port] = append(servers.checks[port], checks...)
+	if serving {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	health.HandleChecks(mux, func() []health.Check {
+		servers.Lock()
+		defer servers.Unlock()
+		return servers.checks[port]
+	})
+	go func() {
+		log.Infof("🚀 starting metrics on :%v", port)
+		if err := http.ListenAndServe(fmt.Sprintf(":%v", port), mux); err != nil {
+			log.Errorf("cannot serve metrics: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"kannon.gyozatech.dev/internal/health"
+)
+
+func TestServeShared(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	port := uint16(l.Addr().(*net.TCPAddr).Port)
+	l.Close()
+
+	// the daemons of a process share the server of the port
+	Serve(port, health.Check{Name: "db", Check: func(ctx context.Context) error { return nil }})
+	Serve(port, health.Check{Name: "queue", Check: func(ctx context.Context) error { return errors.New("nats: connection closed") }})
+
+	var res *http.Response
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		if res, err = http.Get(fmt.Sprintf("http://127.0.0.1:%v/readyz", port)); err == nil {
+			break
+		}
+	}
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	var status struct{ Checks map[string]string }
+	assert.Nil(t, json.NewDecoder(res.Body).Decode(&status))
+	assert.Equal(t, map[string]string{"db": "ok", "queue": "nats: connection closed"}, status.Checks)
+}
@@ -0,0 +1,73 @@
+// gen writes the migrations of db/migrations as a Go file, the migrations
+// are built in the binaries without go:embed, run by make migrations:
+//
+//	go run ./internal/migrate/gen db/migrations generated/migrations/migrations.go
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	if len(os.Args) != 3 {
+		fmt.Fprintln(os.Stderr, "usage: gen <migrations dir> <go file>")
+		os.Exit(2)
+	}
+	if err := generate(os.Args[1], os.Args[2]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func generate(dir string, out string) error {
+	names, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	b.WriteString(`// Code generated by internal/migrate/gen. DO NOT EDIT.
+
+package migrations
+
+// File is a migration file of db/migrations
+type File struct {
+	Name string
+	SQL  string
+}
+
+// Files are the migrations of db/migrations by version
+var Files = []File{
+`)
+	for _, name := range names {
+		sql, err := ioutil.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "{Name: %q, SQL: %v},\n", filepath.Base(name), literal(string(sql)))
+	}
+	b.WriteString("}\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(out, src, 0644)
+}
+
+// literal returns s as a raw string literal when possible
+func literal(s string) string {
+	if strings.Contains(s, "`") || strings.Contains(s, "\r") {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
@@ -0,0 +1,225 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"kannon.gyozatech.dev/generated/migrations"
+	"kannon.gyozatech.dev/internal/logging"
+)
+
+var log = logging.Logger("migrate")
+
+// lockID is the key of the advisory lock taken while migrating, daemons
+// migrating on start at the same time apply the migrations once
+const lockID = 8434201
+
+const (
+	upMarker   = "-- migrate:up"
+	downMarker = "-- migrate:down"
+)
+
+// Migration is a migration of db/migrations in the format of dbmate,
+// <version>_<name>.sql files with -- migrate:up and -- migrate:down sections
+type Migration struct {
+	Version string
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is a migration and whether it's applied to the database
+type Status struct {
+	Migration
+	Applied bool
+}
+
+// Parse parses the migration file name with content sql
+func Parse(name string, sql string) (Migration, error) {
+	i := strings.Index(name, "_")
+	if i <= 0 || !strings.HasSuffix(name, ".sql") || strings.Trim(name[:i], "0123456789") != "" {
+		return Migration{}, fmt.Errorf("invalid migration name %v, use <version>_<name>.sql", name)
+	}
+	up := strings.Index(sql, upMarker)
+	down := strings.Index(sql, downMarker)
+	if up < 0 {
+		return Migration{}, fmt.Errorf("migration %v without %v", name, upMarker)
+	}
+	m := Migration{Version: name[:i], Name: strings.TrimSuffix(name, ".sql")}
+	if down < 0 {
+		m.Up = sql[up+len(upMarker):]
+	} else if down < up {
+		return Migration{}, fmt.Errorf("migration %v with %v before %v", name, downMarker, upMarker)
+	} else {
+		m.Up = sql[up+len(upMarker) : down]
+		m.Down = sql[down+len(downMarker):]
+	}
+	m.Up, m.Down = strings.TrimSpace(m.Up), strings.TrimSpace(m.Down)
+	return m, nil
+}
+
+// Embedded returns the migrations built in the binaries, by version
+func Embedded() ([]Migration, error) {
+	res := make([]Migration, 0, len(migrations.Files))
+	for _, f := range migrations.Files {
+		m, err := Parse(f.Name, f.SQL)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, m)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Version < res[j].Version })
+	return res, nil
+}
+
+// Up applies the pending migrations in order, each in a transaction,
+// and returns the applied ones
+func Up(ctx context.Context, db *sql.DB, migrations []Migration) ([]Migration, error) {
+	conn, unlock, err := lock(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for _, m := range Pending(migrations, applied) {
+		if err := apply(ctx, conn, m.Up, "INSERT INTO schema_migrations (version) VALUES ($1)", m.Version); err != nil {
+			return done, fmt.Errorf("cannot apply migration %v: %w", m.Name, err)
+		}
+		log.Infof("📦 applied migration %v", m.Name)
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// Down rolls back the last applied migration and returns it,
+// nil when no migration is applied
+func Down(ctx context.Context, db *sql.DB, migrations []Migration) (*Migration, error) {
+	conn, unlock, err := lock(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	last := ""
+	for v := range applied {
+		if v > last {
+			last = v
+		}
+	}
+	if last == "" {
+		return nil, nil
+	}
+	for _, m := range migrations {
+		if m.Version != last {
+			continue
+		}
+		if err := apply(ctx, conn, m.Down, "DELETE FROM schema_migrations WHERE version = $1", m.Version); err != nil {
+			return nil, fmt.Errorf("cannot roll back migration %v: %w", m.Name, err)
+		}
+		log.Infof("📦 rolled back migration %v", m.Name)
+		return &m, nil
+	}
+	return nil, fmt.Errorf("unknown applied migration %v", last)
+}
+
+// GetStatus returns the migrations and whether they are applied
+func GetStatus(ctx context.Context, db *sql.DB, migrations []Migration) ([]Status, error) {
+	conn, unlock, err := lock(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Status, 0, len(migrations))
+	for _, m := range migrations {
+		res = append(res, Status{Migration: m, Applied: applied[m.Version]})
+	}
+	return res, nil
+}
+
+// Pending returns the migrations whose version is not applied
+func Pending(migrations []Migration, applied map[string]bool) []Migration {
+	var res []Migration
+	for _, m := range migrations {
+		if !applied[m.Version] {
+			res = append(res, m)
+		}
+	}
+	return res
+}
+
+// lock takes the migration lock on a connection of db, unlock
+// releases it and returns the connection to db
+func lock(ctx context.Context, db *sql.DB) (*sql.Conn, func(), error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("cannot lock migrations: %w", err)
+	}
+	return conn, func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockID); err != nil {
+			log.Errorf("cannot unlock migrations: %v", err)
+		}
+		conn.Close()
+	}, nil
+}
+
+// appliedVersions returns the applied migrations, the schema_migrations table
+// is shared with dbmate and created when missing
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[string]bool, error) {
+	if _, err := conn.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (version varchar(255) PRIMARY KEY)"); err != nil {
+		return nil, fmt.Errorf("cannot create schema_migrations: %w", err)
+	}
+	rows, err := conn.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[string]bool)
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		applied[v] = true
+	}
+	return applied, rows.Err()
+}
+
+// apply runs the statements of a migration and records its version in a transaction
+func apply(ctx context.Context, conn *sql.Conn, statements string, record string, version string) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if statements != "" {
+		if _, err := tx.ExecContext(ctx, statements); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, record, version); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
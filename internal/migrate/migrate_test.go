@@ -0,0 +1,62 @@
+package migrate
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	m, err := Parse("20210806090212_audit_logs.sql", "-- migrate:up\n\nCREATE TABLE audit_logs ();\n\n-- migrate:down\n\nDROP TABLE audit_logs;\n")
+	assert.Nil(t, err)
+	assert.Equal(t, Migration{
+		Version: "20210806090212",
+		Name:    "20210806090212_audit_logs",
+		Up:      "CREATE TABLE audit_logs ();",
+		Down:    "DROP TABLE audit_logs;",
+	}, m)
+
+	m, err = Parse("20210806090212_audit_logs.sql", "-- migrate:up\nCREATE TABLE audit_logs ();\n")
+	assert.Nil(t, err)
+	assert.Equal(t, "", m.Down)
+
+	for name, sql := range map[string]string{
+		"audit_logs.sql":                 "-- migrate:up\n",
+		"2021_audit_logs.txt":            "-- migrate:up\n",
+		"20210806090212_audit_logs.sql":  "CREATE TABLE audit_logs ();\n",
+		"20210806090213_audit_logs.sql":  "-- migrate:down\nDROP TABLE audit_logs;\n-- migrate:up\n",
+		"2021080609021a_audit_logs.sql":  "-- migrate:up\n",
+		"_20210806090212_audit_logs.sql": "-- migrate:up\n",
+	} {
+		_, err := Parse(name, sql)
+		assert.NotNil(t, err, name)
+	}
+}
+
+func TestPending(t *testing.T) {
+	migrations := []Migration{{Version: "1"}, {Version: "2"}, {Version: "3"}}
+	assert.Equal(t, []Migration{{Version: "2"}, {Version: "3"}}, Pending(migrations, map[string]bool{"1": true}))
+	assert.Nil(t, Pending(migrations, map[string]bool{"1": true, "2": true, "3": true}))
+}
+
+// the embedded migrations must be generated again with make migrations
+// when the files of db/migrations change
+func TestEmbedded(t *testing.T) {
+	embedded, err := Embedded()
+	assert.Nil(t, err)
+
+	names, err := filepath.Glob("../../db/migrations/*.sql")
+	assert.Nil(t, err)
+	assert.Equal(t, len(names), len(embedded), "run make migrations")
+	for i, name := range names {
+		sql, err := ioutil.ReadFile(name)
+		assert.Nil(t, err)
+		m, err := Parse(filepath.Base(name), string(sql))
+		assert.Nil(t, err)
+		if i < len(embedded) {
+			assert.Equal(t, m, embedded[i], "run make migrations")
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// ErrInvalidToken is returned for malformed, unsigned or expired tokens
+var ErrInvalidToken = errors.New("invalid token")
+
+// leeway is the clock skew tolerated on the times of the claims
+const leeway = time.Minute
+
+// Claims are the claims of a token
+type Claims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Audience  audience `json:"aud"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf"`
+	IssuedAt  int64    `json:"iat"`
+	Email     string   `json:"email"`
+	Groups    []string `json:"groups"`
+	Roles     []string `json:"roles"`
+}
+
+// audience is the aud claim, a string or an array of strings
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+	var l []string
+	if err := json.Unmarshal(data, &l); err != nil {
+		return err
+	}
+	*a = l
+	return nil
+}
+
+func (a audience) contains(aud string) bool {
+	for _, s := range a {
+		if s == aud {
+			return true
+		}
+	}
+	return false
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// parse splits a compact JWS in its header, claims, signed content and signature
+func parse(token string) (header, Claims, string, []byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return header{}, Claims{}, "", nil, fmt.Errorf("%w: not a jws", ErrInvalidToken)
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return header{}, Claims{}, "", nil, fmt.Errorf("%w: invalid header: %v", ErrInvalidToken, err)
+	}
+	var c Claims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return header{}, Claims{}, "", nil, fmt.Errorf("%w: invalid claims: %v", ErrInvalidToken, err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return header{}, Claims{}, "", nil, fmt.Errorf("%w: invalid signature: %v", ErrInvalidToken, err)
+	}
+	return h, c, parts[0] + "." + parts[1], sig, nil
+}
+
+func decodeSegment(s string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// verifySignature checks the signature of content with key, alg must
+// be an asymmetric algorithm of the type of key
+func verifySignature(alg string, key crypto.PublicKey, content string, sig []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "PS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "PS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "PS512", "ES512":
+		hash = crypto.SHA512
+	case "EdDSA":
+		k, ok := key.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(k, []byte(content), sig) {
+			return fmt.Errorf("%w: wrong signature", ErrInvalidToken)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unsupported algorithm %v", ErrInvalidToken, alg)
+	}
+
+	h := hash.New()
+	h.Write([]byte(content))
+	digest := h.Sum(nil)
+
+	var valid bool
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		switch alg[0] {
+		case 'R':
+			valid = rsa.VerifyPKCS1v15(k, hash, digest, sig) == nil
+		case 'P':
+			valid = rsa.VerifyPSS(k, hash, digest, sig, nil) == nil
+		}
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if alg[0] == 'E' && len(sig) == 2*size {
+			r := new(big.Int).SetBytes(sig[:size])
+			s := new(big.Int).SetBytes(sig[size:])
+			valid = ecdsa.Verify(k, digest, r, s)
+		}
+	}
+	if !valid {
+		return fmt.Errorf("%w: wrong signature", ErrInvalidToken)
+	}
+	return nil
+}
+
+// validate checks the issuer, the audience and the times of claims at now
+func (c Claims) validate(issuer string, aud string, now time.Time) error {
+	if c.Issuer != issuer {
+		return fmt.Errorf("%w: wrong issuer %v", ErrInvalidToken, c.Issuer)
+	}
+	if !c.Audience.contains(aud) {
+		return fmt.Errorf("%w: wrong audience", ErrInvalidToken)
+	}
+	if c.ExpiresAt == 0 || now.Add(-leeway).After(time.Unix(c.ExpiresAt, 0)) {
+		return fmt.Errorf("%w: expired", ErrInvalidToken)
+	}
+	if c.NotBefore != 0 && now.Add(leeway).Before(time.Unix(c.NotBefore, 0)) {
+		return fmt.Errorf("%w: not valid yet", ErrInvalidToken)
+	}
+	return nil
+}
+
+// inGroups reports if the claims have one of groups
+func (c Claims) inGroups(groups []string) bool {
+	for _, g := range groups {
+		for _, cg := range c.Groups {
+			if g == cg {
+				return true
+			}
+		}
+	}
+	return false
+}
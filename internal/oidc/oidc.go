@@ -0,0 +1,219 @@
+package oidc
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"kannon.gyozatech.dev/internal/rbac"
+)
+
+// Config is the identity provider issuing the tokens of the admin API,
+// tokens are not required when Issuer is empty
+type Config struct {
+	// Issuer is the url of the identity provider, like https://accounts.google.com,
+	// its keys are discovered from <issuer>/.well-known/openid-configuration
+	Issuer string
+	// Audience is the aud of the tokens, like the client id of kannon
+	Audience string
+	// JWKSURL is the url of the keys of the identity provider, when not discovered
+	JWKSURL string
+	// Groups restricts the access to the tokens with one of these groups claims
+	Groups []string
+	// GroupRoles are the roles of the groups claims, like kannon-admins:owner,
+	// tokens have the roles of their groups and of their roles claim
+	GroupRoles map[string]string
+	// DefaultRole is the role of the tokens without roles, they are denied when empty
+	DefaultRole string
+}
+
+// Enabled reports if tokens are required
+func (c Config) Enabled() bool {
+	return c.Issuer != ""
+}
+
+// Validate checks that tokens have an audience and that the roles are valid
+func (c Config) Validate() error {
+	if c.Enabled() && c.Audience == "" {
+		return errors.New("missing audience")
+	}
+	for group, role := range c.GroupRoles {
+		if _, err := rbac.ParseRole(role); err != nil {
+			return fmt.Errorf("invalid role of group %v: %w", group, err)
+		}
+	}
+	if c.DefaultRole != "" {
+		if _, err := rbac.ParseRole(c.DefaultRole); err != nil {
+			return fmt.Errorf("invalid default role: %w", err)
+		}
+	}
+	return nil
+}
+
+// Role returns the highest role of the claims of a token, from its roles
+// and groups claims, false when the token has no role
+func (c Config) Role(claims Claims) (rbac.Role, bool) {
+	roles := append([]string{}, claims.Roles...)
+	for _, g := range claims.Groups {
+		if r, ok := c.GroupRoles[g]; ok {
+			roles = append(roles, r)
+		}
+	}
+	if r, ok := rbac.Highest(roles); ok {
+		return r, true
+	}
+	if c.DefaultRole != "" {
+		return rbac.Role(c.DefaultRole), true
+	}
+	return "", false
+}
+
+// refreshInterval is the min time between two fetches of the keys,
+// keys are fetched again for tokens signed with unknown keys
+const refreshInterval = time.Minute
+
+// Verifier verifies the tokens of an identity provider
+type Verifier struct {
+	config  Config
+	client  *http.Client
+	jwksURL string
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+// NewVerifier discovers and fetches the keys of the identity provider of config
+func NewVerifier(config Config, client *http.Client) (*Verifier, error) {
+	v := &Verifier{
+		config:  config,
+		client:  client,
+		jwksURL: config.JWKSURL,
+	}
+	if v.jwksURL == "" {
+		url, err := v.discover()
+		if err != nil {
+			return nil, fmt.Errorf("cannot discover keys of %v: %w", config.Issuer, err)
+		}
+		v.jwksURL = url
+	}
+	if err := v.fetchKeys(); err != nil {
+		return nil, fmt.Errorf("cannot fetch keys of %v: %w", config.Issuer, err)
+	}
+	return v, nil
+}
+
+// Config returns the config of the verifier
+func (v *Verifier) Config() Config {
+	return v.config
+}
+
+// Verify returns the claims of a token signed by the identity provider
+// for the audience of the config
+func (v *Verifier) Verify(token string) (Claims, error) {
+	h, claims, content, sig, err := parse(token)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	key, err := v.key(h.Kid)
+	if err != nil {
+		return Claims{}, err
+	}
+	if err := verifySignature(h.Alg, key, content, sig); err != nil {
+		return Claims{}, err
+	}
+	if err := claims.validate(v.config.Issuer, v.config.Audience, time.Now()); err != nil {
+		return Claims{}, err
+	}
+	if len(v.config.Groups) > 0 && !claims.inGroups(v.config.Groups) {
+		return Claims{}, fmt.Errorf("%w: not in groups", ErrInvalidToken)
+	}
+	return claims, nil
+}
+
+// key returns the key kid, the keys are fetched again when it's unknown
+func (v *Verifier) key(kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	key, ok := v.keys[kid]
+	stale := time.Since(v.fetchedAt) > refreshInterval
+	v.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	if stale {
+		if err := v.fetchKeys(); err != nil {
+			return nil, err
+		}
+		v.mu.Lock()
+		key, ok = v.keys[kid]
+		v.mu.Unlock()
+		if ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: unknown key %v", ErrInvalidToken, kid)
+}
+
+func (v *Verifier) discover() (string, error) {
+	res, err := v.get(strings.TrimSuffix(v.config.Issuer, "/") + "/.well-known/openid-configuration")
+	if err != nil {
+		return "", err
+	}
+	defer res.Close()
+
+	var metadata struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := json.NewDecoder(res).Decode(&metadata); err != nil {
+		return "", err
+	}
+	if metadata.Issuer != v.config.Issuer {
+		return "", fmt.Errorf("wrong issuer %v", metadata.Issuer)
+	}
+	if metadata.JWKSURI == "" {
+		return "", errors.New("missing jwks_uri")
+	}
+	return metadata.JWKSURI, nil
+}
+
+func (v *Verifier) fetchKeys() error {
+	res, err := v.get(v.jwksURL)
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+
+	keys, err := parseJWKS(res)
+	if err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	v.keys = keys
+	v.fetchedAt = time.Now()
+	v.mu.Unlock()
+	return nil
+}
+
+func (v *Verifier) get(url string) (io.ReadCloser, error) {
+	res, err := v.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		_, _ = io.Copy(ioutil.Discard, io.LimitReader(res.Body, 1<<16))
+		res.Body.Close()
+		return nil, fmt.Errorf("%v responded %v", url, res.Status)
+	}
+	return res.Body, nil
+}
@@ -0,0 +1,214 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"kannon.gyozatech.dev/internal/rbac"
+)
+
+type testKeys struct {
+	rsa     *rsa.PrivateKey
+	ecdsa   *ecdsa.PrivateKey
+	ed25519 ed25519.PrivateKey
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+	return testKeys{rsa: rsaKey, ecdsa: ecKey, ed25519: edKey}
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func (k testKeys) jwks() string {
+	set := map[string][]map[string]string{"keys": {
+		{"kty": "RSA", "kid": "rsa", "use": "sig", "n": b64(k.rsa.N.Bytes()), "e": b64(big.NewInt(int64(k.rsa.E)).Bytes())},
+		{"kty": "EC", "kid": "ec", "crv": "P-256", "x": b64(k.ecdsa.X.Bytes()), "y": b64(k.ecdsa.Y.Bytes())},
+		{"kty": "OKP", "kid": "ed", "crv": "Ed25519", "x": b64(k.ed25519.Public().(ed25519.PublicKey))},
+		{"kty": "RSA", "kid": "enc", "use": "enc", "n": b64(k.rsa.N.Bytes()), "e": "AQAB"},
+	}}
+	data, _ := json.Marshal(set)
+	return string(data)
+}
+
+func (k testKeys) sign(t *testing.T, alg string, kid string, claims map[string]interface{}) string {
+	h, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	assert.Nil(t, err)
+	c, err := json.Marshal(claims)
+	assert.Nil(t, err)
+	content := b64(h) + "." + b64(c)
+
+	digest := sha256.Sum256([]byte(content))
+	var sig []byte
+	switch alg {
+	case "RS256":
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k.rsa, crypto.SHA256, digest[:])
+		assert.Nil(t, err)
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, k.ecdsa, digest[:])
+		assert.Nil(t, err)
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	case "EdDSA":
+		sig = ed25519.Sign(k.ed25519, []byte(content))
+	}
+	return content + "." + b64(sig)
+}
+
+func newTestVerifier(t *testing.T, keys testKeys, groups []string) (*Verifier, string) {
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"issuer": %q, "jwks_uri": %q}`, srv.URL, srv.URL+"/keys")
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, keys.jwks())
+	})
+
+	v, err := NewVerifier(Config{Issuer: srv.URL, Audience: "kannon", Groups: groups}, srv.Client())
+	assert.Nil(t, err)
+	return v, srv.URL
+}
+
+func TestVerify(t *testing.T) {
+	keys := newTestKeys(t)
+	v, issuer := newTestVerifier(t, keys, nil)
+
+	claims := map[string]interface{}{
+		"iss":   issuer,
+		"sub":   "ludovico",
+		"aud":   []string{"other", "kannon"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"email": "ludovico@kannon.io",
+	}
+	for _, alg := range []struct{ alg, kid string }{{"RS256", "rsa"}, {"ES256", "ec"}, {"EdDSA", "ed"}} {
+		c, err := v.Verify(keys.sign(t, alg.alg, alg.kid, claims))
+		assert.Nil(t, err, alg.alg)
+		assert.Equal(t, "ludovico", c.Subject)
+		assert.Equal(t, "ludovico@kannon.io", c.Email)
+	}
+
+	claims["aud"] = "kannon"
+	_, err := v.Verify(keys.sign(t, "RS256", "rsa", claims))
+	assert.Nil(t, err)
+}
+
+func TestVerifyInvalid(t *testing.T) {
+	keys := newTestKeys(t)
+	v, issuer := newTestVerifier(t, keys, nil)
+
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss": issuer,
+			"sub": "ludovico",
+			"aud": "kannon",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}
+	}
+
+	expired := valid()
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	notYet := valid()
+	notYet["nbf"] = time.Now().Add(time.Hour).Unix()
+	wrongIssuer := valid()
+	wrongIssuer["iss"] = "https://evil.example"
+	wrongAudience := valid()
+	wrongAudience["aud"] = "other"
+	noExpiration := valid()
+	delete(noExpiration, "exp")
+
+	tokens := map[string]string{
+		"expired":        keys.sign(t, "RS256", "rsa", expired),
+		"not yet valid":  keys.sign(t, "RS256", "rsa", notYet),
+		"wrong issuer":   keys.sign(t, "RS256", "rsa", wrongIssuer),
+		"wrong audience": keys.sign(t, "RS256", "rsa", wrongAudience),
+		"no expiration":  keys.sign(t, "RS256", "rsa", noExpiration),
+		"unknown key":    keys.sign(t, "RS256", "unknown", valid()),
+		"encryption key": keys.sign(t, "RS256", "enc", valid()),
+		"wrong key type": keys.sign(t, "ES256", "rsa", valid()),
+		"none":           keys.sign(t, "none", "rsa", valid()),
+		"malformed":      "not.a-token",
+	}
+	tampered := keys.sign(t, "RS256", "rsa", valid())
+	tokens["tampered"] = tampered[:len(tampered)-4] + "AAAA"
+
+	for name, token := range tokens {
+		_, err := v.Verify(token)
+		assert.True(t, errors.Is(err, ErrInvalidToken), name)
+	}
+}
+
+func TestVerifyGroups(t *testing.T) {
+	keys := newTestKeys(t)
+	v, issuer := newTestVerifier(t, keys, []string{"kannon-admins"})
+
+	claims := map[string]interface{}{
+		"iss":    issuer,
+		"sub":    "ludovico",
+		"aud":    "kannon",
+		"exp":    time.Now().Add(time.Hour).Unix(),
+		"groups": []string{"users", "kannon-admins"},
+	}
+	_, err := v.Verify(keys.sign(t, "RS256", "rsa", claims))
+	assert.Nil(t, err)
+
+	claims["groups"] = []string{"users"}
+	_, err = v.Verify(keys.sign(t, "RS256", "rsa", claims))
+	assert.True(t, errors.Is(err, ErrInvalidToken))
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.Nil(t, Config{}.Validate())
+	assert.Nil(t, Config{Issuer: "https://accounts.google.com", Audience: "kannon"}.Validate())
+	assert.NotNil(t, Config{Issuer: "https://accounts.google.com"}.Validate())
+	assert.NotNil(t, Config{GroupRoles: map[string]string{"kannon-admins": "admin"}}.Validate())
+	assert.NotNil(t, Config{DefaultRole: "admin"}.Validate())
+}
+
+func TestConfigRole(t *testing.T) {
+	c := Config{GroupRoles: map[string]string{"kannon-admins": "owner", "dashboards": "read-only"}}
+
+	r, ok := c.Role(Claims{Groups: []string{"dashboards"}})
+	assert.True(t, ok)
+	assert.Equal(t, rbac.ReadOnly, r)
+
+	r, ok = c.Role(Claims{Groups: []string{"dashboards", "kannon-admins"}, Roles: []string{"operator"}})
+	assert.True(t, ok)
+	assert.Equal(t, rbac.Owner, r)
+
+	r, ok = c.Role(Claims{Roles: []string{"operator"}})
+	assert.True(t, ok)
+	assert.Equal(t, rbac.Operator, r)
+
+	_, ok = c.Role(Claims{Groups: []string{"users"}})
+	assert.False(t, ok)
+
+	c.DefaultRole = "read-only"
+	r, ok = c.Role(Claims{Groups: []string{"users"}})
+	assert.True(t, ok)
+	assert.Equal(t, rbac.ReadOnly, r)
+}
@@ -0,0 +1,38 @@
+package pool
+
+import (
+	"kannon.gyozatech.dev/internal/queue"
+)
+
+// ScheduledSubject is the subject notified when emails are scheduled
+const ScheduledSubject = "pools.scheduled"
+
+// NotifyScheduled wakes up the dispatchers waiting for emails to send,
+// dispatchers of brokers without subscriptions only poll the pool
+func NotifyScheduled(p queue.Publisher) error {
+	if _, ok := p.(queue.Subscriber); !ok {
+		return nil
+	}
+	return p.Publish(ScheduledSubject, nil)
+}
+
+// SubscribeScheduled returns a channel receiving a value when emails are
+// scheduled, notifications received while the previous one is pending are merged.
+// The channel never receives when the broker doesn't support subscriptions
+func SubscribeScheduled(b queue.Broker) (<-chan struct{}, error) {
+	ch := make(chan struct{}, 1)
+	s, ok := b.(queue.Subscriber)
+	if !ok {
+		return ch, nil
+	}
+	err := s.Subscribe(ScheduledSubject, func(msg queue.Message) {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
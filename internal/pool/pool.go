@@ -3,11 +3,16 @@ package pool
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/lib/pq"
 	"gopkg.in/lucsky/cuid.v1"
 	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/metrics"
+	"kannon.gyozatech.dev/internal/tracing"
 )
 
 type Sender struct {
@@ -15,66 +20,576 @@ type Sender struct {
 	Email string
 }
 
+// Attachment is a file attached to every email of a pool,
+// inline attachments are images embedded in the html
+type Attachment struct {
+	Filename string
+	Content  []byte
+	Inline   bool
+}
+
+// Recipient is a recipient of a pool with
+// its personalization fields
+type Recipient struct {
+	Email  string
+	Fields map[string]string
+}
+
+// PoolMessage contains the data of a message
+// to send to a pool of recipients
+type PoolMessage struct {
+	Template    sqlc.Template
+	To          []string
+	Recipients  []Recipient
+	Cc          []string
+	Bcc         []string
+	From        Sender
+	Subject     string
+	Domain      string
+	Attachments []Attachment
+	Headers     map[string]string
+	ReplyTo     string
+	Fields      map[string]string
+	// ScheduledTime is when emails are sent, now when zero
+	ScheduledTime time.Time
+	// IdempotencyKey identifies a send of a domain, a pool
+	// with an already used key is not created twice
+	IdempotencyKey string
+	Priority       Priority
+	// ResentFrom is the message_id of the message sent again, empty for new sends
+	ResentFrom string
+}
+
+// Priority orders the dispatch of pool emails, emails with
+// higher priority are sent first
+type Priority int16
+
+const (
+	// Normal is the default priority
+	Normal Priority = 0
+	// Transactional is the priority of emails like password resets
+	Transactional Priority = 1
+	// Bulk is the priority of marketing emails and newsletters
+	Bulk Priority = -1
+)
+
+// SearchFilter filters the pool emails returned by SearchMessages,
+// empty fields match every email
+type SearchFilter struct {
+	Domain     string
+	Email      string
+	TemplateID string
+	Status     sqlc.SendingPoolStatus
+	// messages created in [From, To)
+	From time.Time
+	To   time.Time
+}
+
+// RetryPolicy is the exponential backoff of soft bounced emails
+type RetryPolicy struct {
+	// MaxAttempts is the number of sends of an email, retries included
+	MaxAttempts uint
+	// BaseDelay is the delay of the first retry, doubled at every retry
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between retries
+	MaxDelay time.Duration
+}
+
+// CanRetry checks if an email can be sent again after
+// attempts failed sends
+func (p RetryPolicy) CanRetry(attempts uint) bool {
+	return attempts < p.MaxAttempts
+}
+
+// Backoff returns the delay of the retry of an email
+// that already failed previous times
+func (p RetryPolicy) Backoff(previous uint) time.Duration {
+	d := p.BaseDelay
+	for i := uint(0); i < previous && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d
+}
+
 // SendingPoolManager is a manger for sending pool
 type SendingPoolManager interface {
-	AddPool(
-		template sqlc.Template,
-		to []string,
-		from Sender,
-		subject string,
-		domain string,
-	) (sqlc.Message, error)
+	AddPool(ctx context.Context, msg PoolMessage) (sqlc.Message, error)
+	FindIdempotentMessage(domain string, idempotencyKey string) (sqlc.Message, error)
+	GetMessage(domain string, messageID string) (sqlc.Message, error)
+	GetPoolMessage(domain string, messageID string) (PoolMessage, error)
+	GetMessageRecipients(domain string, messageID string) ([]sqlc.SendingPoolEmail, error)
+	SearchMessages(filter SearchFilter, cursor int32, max uint) ([]sqlc.SearchMessagesRow, error)
 	PrepareForSend(max uint) ([]sqlc.SendingPoolEmail, error)
+	Release(ctx context.Context, id int32, retryAt time.Time) error
+	ReclaimStale(ctx context.Context, claimedBefore time.Time) (int64, error)
+	CountInFlight() (uint, error)
+	CountPending() (uint, error)
+	GetQueueAge() (time.Duration, error)
+	SetSuppressed(id int32) error
+	SetQuotaExceeded(id int32) error
+	SetQuotaConsumed(id int32) error
+	CancelMessage(ctx context.Context, domain string, messageID string, emails []string) ([]string, error)
+	SetDelivered(ctx context.Context, messageID string, email string, response string) error
+	SetSoftBounced(ctx context.Context, messageID string, email string, code uint32, msg string, policy RetryPolicy) (bool, error)
+	SetHardBounced(ctx context.Context, messageID string, email string, code uint32, msg string) error
+	SetDeferred(ctx context.Context, messageID string, email string, reason string, retryAt time.Time) error
+	SetDispatched(ctx context.Context, id int32, ipPool string) error
+	GetMessageTimeline(domain string, messageID string) ([]sqlc.MessageTimeline, error)
 }
 
 type sendingPoolManager struct {
-	db *sqlc.Queries
-}
-
-// AddPool starts a new schedule in the pool
-func (m *sendingPoolManager) AddPool(
-	template sqlc.Template,
-	to []string,
-	from Sender,
-	subject string,
-	domain string,
-) (sqlc.Message, error) {
-	msg, err := m.db.CreateMessage(context.Background(), sqlc.CreateMessageParams{
-		TemplateID:  template.TemplateID,
-		Domain:      domain,
-		Subject:     subject,
-		SenderEmail: from.Email,
-		SenderAlias: from.Alias,
-		MessageID:   createMessageID(domain),
+	db   *sqlc.Queries
+	conn *sql.DB
+}
+
+// AddPool starts a new schedule in the pool, the emails of the
+// schedule are dispatched in the trace of the span of ctx. The message,
+// its attachments and its emails are created in a transaction
+func (m *sendingPoolManager) AddPool(ctx context.Context, pm PoolMessage) (sqlc.Message, error) {
+	if pm.IdempotencyKey != "" {
+		msg, err := m.FindIdempotentMessage(pm.Domain, pm.IdempotencyKey)
+		if err == nil {
+			return msg, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return sqlc.Message{}, err
+		}
+	}
+
+	headers, err := json.Marshal(pm.Headers)
+	if err != nil {
+		return sqlc.Message{}, err
+	}
+
+	fields, err := json.Marshal(pm.Fields)
+	if err != nil {
+		return sqlc.Message{}, err
+	}
+
+	tx, err := m.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return sqlc.Message{}, err
+	}
+	// rolling back a committed transaction does nothing
+	defer tx.Rollback()
+	txm := &sendingPoolManager{db: sqlc.New(metrics.InstrumentTx(tx))}
+
+	msg, err := txm.db.CreateMessage(ctx, sqlc.CreateMessageParams{
+		TemplateID:      pm.Template.TemplateID,
+		TemplateVersion: pm.Template.Version,
+		Domain:          pm.Domain,
+		Subject:         pm.Subject,
+		SenderEmail:     pm.From.Email,
+		SenderAlias:     pm.From.Alias,
+		MessageID:       createMessageID(pm.Domain),
+		Cc:              pm.Cc,
+		Bcc:             pm.Bcc,
+		Headers:         headers,
+		ReplyTo:         pm.ReplyTo,
+		Fields:          fields,
+		IdempotencyKey: sql.NullString{
+			String: pm.IdempotencyKey,
+			Valid:  pm.IdempotencyKey != "",
+		},
+		ResentFrom: pm.ResentFrom,
 	})
+	if isUniqueViolation(err) && pm.IdempotencyKey != "" {
+		// a concurrent send with the same key created the message,
+		// it is found outside of the aborted transaction
+		tx.Rollback()
+		return m.FindIdempotentMessage(pm.Domain, pm.IdempotencyKey)
+	}
 	if err != nil {
 		return sqlc.Message{}, err
 	}
 
-	_, err = m.db.CreatePool(context.TODO(), sqlc.CreatePoolParams{
-		ScheduledTime: time.Now(), // TODO
+	for _, a := range pm.Attachments {
+		_, err = txm.db.CreateAttachment(ctx, sqlc.CreateAttachmentParams{
+			MessageID: msg.ID,
+			Filename:  a.Filename,
+			Content:   a.Content,
+			Inline:    a.Inline,
+		})
+		if err != nil {
+			return sqlc.Message{}, err
+		}
+	}
+
+	emails, recipientsFields, err := poolRecipients(pm)
+	if err != nil {
+		return sqlc.Message{}, err
+	}
+
+	scheduledTime := pm.ScheduledTime
+	if scheduledTime.IsZero() {
+		scheduledTime = time.Now()
+	}
+
+	poolEmails, err := txm.db.CreatePool(ctx, sqlc.CreatePoolParams{
+		ScheduledTime: scheduledTime,
 		MessageID:     msg.ID,
-		Emails:        to,
+		Emails:        emails,
+		Fields:        recipientsFields,
+		// the cc and bcc are sent with the email of the first recipient
+		Copies:      len(pm.Cc)+len(pm.Bcc) > 0,
+		Priority:    int16(pm.Priority),
+		TraceParent: tracing.TraceParent(ctx),
+	})
+	if err != nil {
+		return sqlc.Message{}, err
+	}
+	ids := make([]int32, len(poolEmails))
+	for i, e := range poolEmails {
+		ids[i] = e.ID
+	}
+	err = txm.addPoolTimeline(ctx, ids, StageAccepted, map[string]interface{}{
+		"scheduled_time": scheduledTime.Format(time.RFC3339),
 	})
 	if err != nil {
 		return sqlc.Message{}, err
 	}
+	if err := tx.Commit(); err != nil {
+		return sqlc.Message{}, err
+	}
 	return msg, nil
 }
 
+// FindIdempotentMessage returns the message created with an idempotency key,
+// sql.ErrNoRows when the key has never been used
+func (m *sendingPoolManager) FindIdempotentMessage(domain string, idempotencyKey string) (sqlc.Message, error) {
+	return m.db.FindMessageWithIdempotencyKey(context.TODO(), sqlc.FindMessageWithIdempotencyKeyParams{
+		Domain: domain,
+		IdempotencyKey: sql.NullString{
+			String: idempotencyKey,
+			Valid:  true,
+		},
+	})
+}
+
+// GetMessageRecipients returns the pool emails of a message of a domain
+func (m *sendingPoolManager) GetMessageRecipients(domain string, messageID string) ([]sqlc.SendingPoolEmail, error) {
+	return m.db.GetMessageRecipients(context.TODO(), sqlc.GetMessageRecipientsParams{
+		Domain:    domain,
+		MessageID: messageID,
+	})
+}
+
+// SearchMessages returns the pool emails matching filter, most recent first,
+// cursor is the id of the last email of the previous page or 0
+func (m *sendingPoolManager) SearchMessages(filter SearchFilter, cursor int32, max uint) ([]sqlc.SearchMessagesRow, error) {
+	return m.db.SearchMessages(context.TODO(), sqlc.SearchMessagesParams{
+		Domain:     filter.Domain,
+		Email:      filter.Email,
+		TemplateID: filter.TemplateID,
+		Status:     string(filter.Status),
+		StartTime:  filter.From,
+		EndTime:    filter.To,
+		Cursor:     cursor,
+		Max:        int32(max),
+	})
+}
+
 func (m *sendingPoolManager) PrepareForSend(
 	max uint,
 ) ([]sqlc.SendingPoolEmail, error) {
 	return m.db.PrepareForSend(context.TODO(), int32(max))
 }
 
+// Release schedules again at retryAt a pool email prepared for send
+// and not published, like when its email cannot be built
+func (m *sendingPoolManager) Release(ctx context.Context, id int32, retryAt time.Time) error {
+	return m.db.ReleaseSendingPoolEmail(ctx, sqlc.ReleaseSendingPoolEmailParams{
+		ScheduledTime: retryAt,
+		ID:            id,
+	})
+}
+
+// ReclaimStale schedules again the pool emails prepared for send before claimedBefore
+// and never published, like the emails of a dispatcher stopped while dispatching them.
+// It returns the number of emails scheduled again
+func (m *sendingPoolManager) ReclaimStale(ctx context.Context, claimedBefore time.Time) (int64, error) {
+	return m.db.ReclaimSendingPoolEmails(ctx, sql.NullTime{Time: claimedBefore, Valid: true})
+}
+
+// CountInFlight returns the number of emails dispatched
+// and not yet delivered or bounced
+func (m *sendingPoolManager) CountInFlight() (uint, error) {
+	count, err := m.db.CountSendingPoolEmailsInFlight(context.TODO())
+	if err != nil {
+		return 0, err
+	}
+	return uint(count), nil
+}
+
+// CountPending returns the number of emails
+// scheduled in the past and not yet dispatched
+func (m *sendingPoolManager) CountPending() (uint, error) {
+	count, err := m.db.CountPendingPoolEmails(context.TODO())
+	if err != nil {
+		return 0, err
+	}
+	return uint(count), nil
+}
+
+// GetQueueAge returns how long the oldest email scheduled in the past
+// has been waiting to be dispatched, 0 when no email is waiting
+func (m *sendingPoolManager) GetQueueAge() (time.Duration, error) {
+	oldest, err := m.db.GetOldestPendingPoolEmail(context.TODO())
+	if err != nil {
+		return 0, err
+	}
+	if age := time.Since(oldest); age > 0 {
+		return age, nil
+	}
+	return 0, nil
+}
+
+// SetSuppressed marks a pool email as not sent because
+// its recipient is suppressed
+func (m *sendingPoolManager) SetSuppressed(id int32) error {
+	return m.db.SetSendingPoolEmailStatus(context.TODO(), sqlc.SetSendingPoolEmailStatusParams{
+		Status: sqlc.SendingPoolStatusSuppressed,
+		ID:     id,
+	})
+}
+
+// SetQuotaExceeded marks a pool email as not sent because
+// the quota of its domain is exceeded
+func (m *sendingPoolManager) SetQuotaExceeded(id int32) error {
+	return m.db.SetSendingPoolEmailStatus(context.TODO(), sqlc.SetSendingPoolEmailStatusParams{
+		Status: sqlc.SendingPoolStatusQuotaExceeded,
+		ID:     id,
+	})
+}
+
+// SetQuotaConsumed marks a pool email as counted in the quota of its
+// domain, its retries and deferrals are not counted again
+func (m *sendingPoolManager) SetQuotaConsumed(id int32) error {
+	return m.db.SetSendingPoolEmailQuotaConsumed(context.TODO(), id)
+}
+
+// CancelMessage cancels the emails of a message of a domain not dispatched yet,
+// of every recipient when emails is empty. It returns the emails canceled,
+// the emails already dispatched are sent
+func (m *sendingPoolManager) CancelMessage(ctx context.Context, domain string, messageID string, emails []string) ([]string, error) {
+	if emails == nil {
+		emails = []string{}
+	}
+	var canceled []string
+	err := m.inTx(ctx, func(txm *sendingPoolManager) error {
+		rows, err := txm.db.CancelMessagePool(ctx, sqlc.CancelMessagePoolParams{
+			Domain:    domain,
+			MessageID: messageID,
+			Emails:    emails,
+		})
+		if err != nil || len(rows) == 0 {
+			return err
+		}
+		ids := make([]int32, len(rows))
+		canceled = make([]string, len(rows))
+		for i, r := range rows {
+			ids[i] = r.ID
+			canceled[i] = r.Email
+		}
+		return txm.addPoolTimeline(ctx, ids, StageCanceled, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return canceled, nil
+}
+
+// SetDelivered marks the email of a message recipient as sent, the
+// response of the MX is recorded in the timeline
+func (m *sendingPoolManager) SetDelivered(ctx context.Context, messageID string, email string, response string) error {
+	return m.inTx(ctx, func(txm *sendingPoolManager) error {
+		err := txm.db.SetSendingPoolEmailDelivered(ctx, sqlc.SetSendingPoolEmailDeliveredParams{
+			MessageID: messageID,
+			Email:     email,
+		})
+		if err != nil {
+			return err
+		}
+		return txm.addTimeline(ctx, TimelineEntry{
+			MessageID: messageID,
+			Email:     email,
+			Stage:     StageDelivered,
+			Timestamp: time.Now(),
+			// 250: requested mail action completed
+			SMTPCode:     250,
+			SMTPResponse: response,
+		})
+	})
+}
+
+// SetSoftBounced records a transient failure of the email of a message
+// recipient and schedules it again with the backoff of policy, returns false
+// when the email reached the max attempts and is marked as failed
+func (m *sendingPoolManager) SetSoftBounced(ctx context.Context, messageID string, email string, code uint32, msg string, policy RetryPolicy) (bool, error) {
+	var retry bool
+	err := m.inTx(ctx, func(txm *sendingPoolManager) error {
+		e, err := txm.db.FindSendingPoolEmail(ctx, sqlc.FindSendingPoolEmailParams{
+			MessageID: messageID,
+			Email:     email,
+		})
+		if err != nil {
+			return err
+		}
+
+		retry = policy.CanRetry(uint(e.Trial) + 1)
+		status := sqlc.SendingPoolStatusScheduled
+		stage := StageAttempt
+		if !retry {
+			status = sqlc.SendingPoolStatusError
+			stage = StageBounced
+		}
+		retryAt := time.Now().Add(policy.Backoff(uint(e.Trial)))
+		err = txm.db.SetSendingPoolEmailBounced(ctx, sqlc.SetSendingPoolEmailBouncedParams{
+			Status:        status,
+			BounceType:    sqlc.BounceTypeSoft,
+			ErrorCode:     int32(code),
+			ErrorMsg:      msg,
+			ScheduledTime: retryAt,
+			MessageID:     messageID,
+			Email:         email,
+		})
+		if err != nil {
+			return err
+		}
+		data := map[string]interface{}{"bounce_type": sqlc.BounceTypeSoft, "attempt": e.Trial + 1}
+		if retry {
+			data["retry_at"] = retryAt.Format(time.RFC3339)
+		}
+		return txm.addTimeline(ctx, TimelineEntry{
+			MessageID:    messageID,
+			Email:        email,
+			Stage:        stage,
+			Timestamp:    time.Now(),
+			SMTPCode:     code,
+			SMTPResponse: msg,
+			Data:         data,
+		})
+	})
+	return retry, err
+}
+
+// SetDeferred schedules again at retryAt an email the sender postponed
+// because of reason, the attempts of the email are unchanged
+func (m *sendingPoolManager) SetDeferred(ctx context.Context, messageID string, email string, reason string, retryAt time.Time) error {
+	return m.inTx(ctx, func(txm *sendingPoolManager) error {
+		err := txm.db.DeferSendingPoolEmail(ctx, sqlc.DeferSendingPoolEmailParams{
+			ScheduledTime: retryAt,
+			MessageID:     messageID,
+			Email:         email,
+		})
+		if err != nil {
+			return err
+		}
+		return txm.addTimeline(ctx, TimelineEntry{
+			MessageID:    messageID,
+			Email:        email,
+			Stage:        StageDeferred,
+			Timestamp:    time.Now(),
+			SMTPResponse: reason,
+			Data:         map[string]interface{}{"retry_at": retryAt.Format(time.RFC3339)},
+		})
+	})
+}
+
+// SetHardBounced records a permanent failure of the email of a message recipient,
+// the email is not sent again
+func (m *sendingPoolManager) SetHardBounced(ctx context.Context, messageID string, email string, code uint32, msg string) error {
+	return m.inTx(ctx, func(txm *sendingPoolManager) error {
+		err := txm.db.SetSendingPoolEmailBounced(ctx, sqlc.SetSendingPoolEmailBouncedParams{
+			Status:        sqlc.SendingPoolStatusError,
+			BounceType:    sqlc.BounceTypeHard,
+			ErrorCode:     int32(code),
+			ErrorMsg:      msg,
+			ScheduledTime: time.Now(),
+			MessageID:     messageID,
+			Email:         email,
+		})
+		if err != nil {
+			return err
+		}
+		return txm.addTimeline(ctx, TimelineEntry{
+			MessageID:    messageID,
+			Email:        email,
+			Stage:        StageBounced,
+			Timestamp:    time.Now(),
+			SMTPCode:     code,
+			SMTPResponse: msg,
+			Data:         map[string]interface{}{"bounce_type": sqlc.BounceTypeHard},
+		})
+	})
+}
+
+// inTx runs fn with a manager of the queries of a transaction,
+// committed when fn succeeds. The status of a pool email and
+// its timeline entry are written together
+func (m *sendingPoolManager) inTx(ctx context.Context, fn func(txm *sendingPoolManager) error) error {
+	tx, err := m.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// rolling back a committed transaction does nothing
+	defer tx.Rollback()
+	if err := fn(&sendingPoolManager{db: sqlc.New(metrics.InstrumentTx(tx))}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 // NewSendingPoolManager constructs a new Sending Pool Manager
 func NewSendingPoolManager(db *sql.DB) (SendingPoolManager, error) {
 	return &sendingPoolManager{
-		db: sqlc.New(db),
+		db:   sqlc.New(metrics.InstrumentDB(db)),
+		conn: db,
 	}, nil
 }
 
+// EmailsCount returns the number of recipients the emails of pm are
+// delivered to: its to and recipients and, when there is at least one
+// of them, its cc and bcc
+func (pm PoolMessage) EmailsCount() int {
+	n := len(pm.To) + len(pm.Recipients)
+	if n == 0 {
+		return 0
+	}
+	return n + len(pm.Cc) + len(pm.Bcc)
+}
+
+// poolRecipients returns emails and json encoded fields
+// of to and recipients of a pool
+func poolRecipients(pm PoolMessage) ([]string, []string, error) {
+	emails := make([]string, 0, len(pm.To)+len(pm.Recipients))
+	fields := make([]string, 0, len(pm.To)+len(pm.Recipients))
+	for _, email := range pm.To {
+		emails = append(emails, email)
+		fields = append(fields, "{}")
+	}
+	for _, r := range pm.Recipients {
+		f, err := json.Marshal(r.Fields)
+		if err != nil {
+			return nil, nil, err
+		}
+		if r.Fields == nil {
+			f = []byte("{}")
+		}
+		emails = append(emails, r.Email)
+		fields = append(fields, string(f))
+	}
+	return emails, fields, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
 func createMessageID(domain string) string {
 	return fmt.Sprintf("msg_%v@%v", cuid.New(), domain)
 }
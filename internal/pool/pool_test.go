@@ -0,0 +1,47 @@
+package pool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	p := RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   15 * time.Minute,
+		MaxDelay:    time.Hour,
+	}
+
+	assert.Equal(t, 15*time.Minute, p.Backoff(0))
+	assert.Equal(t, 30*time.Minute, p.Backoff(1))
+	assert.Equal(t, time.Hour, p.Backoff(2))
+	assert.Equal(t, time.Hour, p.Backoff(10))
+
+	assert.True(t, p.CanRetry(2))
+	assert.False(t, p.CanRetry(3))
+}
+
+func TestPoolRecipients(t *testing.T) {
+	emails, fields, err := poolRecipients(PoolMessage{
+		To:         []string{"a@test.com"},
+		Recipients: []Recipient{{Email: "b@test.com", Fields: map[string]string{"name": "B"}}, {Email: "c@test.com"}},
+		// the cc and bcc are sent with the email of a@test.com
+		Cc:  []string{"cc@test.com"},
+		Bcc: []string{"bcc@test.com"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a@test.com", "b@test.com", "c@test.com"}, emails)
+	assert.Equal(t, []string{"{}", `{"name":"B"}`, "{}"}, fields)
+}
+
+func TestEmailsCount(t *testing.T) {
+	assert.Equal(t, 4, PoolMessage{
+		To:         []string{"a@test.com"},
+		Recipients: []Recipient{{Email: "b@test.com"}},
+		Cc:         []string{"cc@test.com"},
+		Bcc:        []string{"bcc@test.com"},
+	}.EmailsCount())
+	assert.Equal(t, 0, PoolMessage{Cc: []string{"cc@test.com"}}.EmailsCount())
+}
@@ -0,0 +1,103 @@
+package pool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"kannon.gyozatech.dev/generated/sqlc"
+)
+
+// GetMessage returns a message of a domain, sql.ErrNoRows when not found
+func (m *sendingPoolManager) GetMessage(domain string, messageID string) (sqlc.Message, error) {
+	return m.db.GetMessage(context.TODO(), sqlc.GetMessageParams{
+		Domain:    domain,
+		MessageID: messageID,
+	})
+}
+
+// GetPoolMessage returns the send of a message of a domain, to send it again as a
+// new message linked to the original: same template version, recipients and fields.
+// It returns sql.ErrNoRows when the message is not found
+func (m *sendingPoolManager) GetPoolMessage(domain string, messageID string) (PoolMessage, error) {
+	msg, err := m.GetMessage(domain, messageID)
+	if err != nil {
+		return PoolMessage{}, err
+	}
+	attachments, err := m.db.GetMessageAttachments(context.TODO(), msg.ID)
+	if err != nil {
+		return PoolMessage{}, err
+	}
+	emails, err := m.GetMessageRecipients(domain, messageID)
+	if err != nil {
+		return PoolMessage{}, err
+	}
+	return resentPoolMessage(msg, attachments, emails)
+}
+
+// resentPoolMessage builds the send of msg to the recipients of the pool emails
+func resentPoolMessage(msg sqlc.Message, attachments []sqlc.Attachment, emails []sqlc.SendingPoolEmail) (PoolMessage, error) {
+	pm := PoolMessage{
+		Template: sqlc.Template{
+			TemplateID: msg.TemplateID,
+			Version:    msg.TemplateVersion,
+			Domain:     msg.Domain,
+		},
+		Cc:  msg.Cc,
+		Bcc: msg.Bcc,
+		From: Sender{
+			Email: msg.SenderEmail,
+			Alias: msg.SenderAlias,
+		},
+		Subject:    msg.Subject,
+		Domain:     msg.Domain,
+		ReplyTo:    msg.ReplyTo,
+		ResentFrom: msg.MessageID,
+	}
+	if err := json.Unmarshal(msg.Headers, &pm.Headers); err != nil {
+		return PoolMessage{}, fmt.Errorf("invalid headers of %v: %w", msg.MessageID, err)
+	}
+	if err := json.Unmarshal(msg.Fields, &pm.Fields); err != nil {
+		return PoolMessage{}, fmt.Errorf("invalid fields of %v: %w", msg.MessageID, err)
+	}
+	for _, a := range attachments {
+		pm.Attachments = append(pm.Attachments, Attachment{
+			Filename: a.Filename,
+			Content:  a.Content,
+			Inline:   a.Inline,
+		})
+	}
+	for _, e := range emails {
+		r := Recipient{Email: e.Email}
+		if err := json.Unmarshal(e.Fields, &r.Fields); err != nil {
+			return PoolMessage{}, fmt.Errorf("invalid fields of %v: %w", e.Email, err)
+		}
+		if len(r.Fields) == 0 {
+			r.Fields = nil
+		}
+		pm.Recipients = append(pm.Recipients, r)
+		// the emails of a message have the same priority
+		pm.Priority = Priority(e.Priority)
+	}
+	return pm, nil
+}
+
+// ResendTo returns pm sent to emails instead of its recipients, the emails of
+// recipients of pm keep their fields. The cc and bcc of pm are not sent
+// to other recipients
+func (pm PoolMessage) ResendTo(emails []string) PoolMessage {
+	fields := make(map[string]map[string]string, len(pm.Recipients))
+	for _, r := range pm.Recipients {
+		fields[strings.ToLower(r.Email)] = r.Fields
+	}
+	pm.To, pm.Recipients, pm.Cc, pm.Bcc = nil, nil, nil, nil
+	for _, email := range emails {
+		if f, ok := fields[strings.ToLower(email)]; ok {
+			pm.Recipients = append(pm.Recipients, Recipient{Email: email, Fields: f})
+			continue
+		}
+		pm.To = append(pm.To, email)
+	}
+	return pm
+}
@@ -0,0 +1,73 @@
+package pool
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"kannon.gyozatech.dev/generated/sqlc"
+)
+
+func TestResentPoolMessage(t *testing.T) {
+	msg := sqlc.Message{
+		MessageID:       "msg_1@kannon.io",
+		Subject:         "Hello {{ name }}",
+		SenderEmail:     "donotreply@kannon.io",
+		SenderAlias:     "Kannon",
+		TemplateID:      "template_1@kannon.io",
+		TemplateVersion: 2,
+		Domain:          "kannon.io",
+		Cc:              []string{"cc@test.com"},
+		Bcc:             []string{},
+		Headers:         json.RawMessage(`{"X-Campaign":"welcome"}`),
+		ReplyTo:         "support@kannon.io",
+		Fields:          json.RawMessage(`{"product":"kannon"}`),
+	}
+	attachments := []sqlc.Attachment{{Filename: "logo.png", Content: []byte("png"), Inline: true}}
+	emails := []sqlc.SendingPoolEmail{
+		{Email: "a@test.com", Fields: json.RawMessage(`{}`), Priority: 1},
+		{Email: "b@test.com", Fields: json.RawMessage(`{"name":"B"}`), Priority: 1},
+	}
+
+	pm, err := resentPoolMessage(msg, attachments, emails)
+	assert.Nil(t, err)
+	assert.Equal(t, PoolMessage{
+		Template:    sqlc.Template{TemplateID: "template_1@kannon.io", Version: 2, Domain: "kannon.io"},
+		Recipients:  []Recipient{{Email: "a@test.com"}, {Email: "b@test.com", Fields: map[string]string{"name": "B"}}},
+		Cc:          []string{"cc@test.com"},
+		Bcc:         []string{},
+		From:        Sender{Email: "donotreply@kannon.io", Alias: "Kannon"},
+		Subject:     "Hello {{ name }}",
+		Domain:      "kannon.io",
+		Attachments: []Attachment{{Filename: "logo.png", Content: []byte("png"), Inline: true}},
+		Headers:     map[string]string{"X-Campaign": "welcome"},
+		ReplyTo:     "support@kannon.io",
+		Fields:      map[string]string{"product": "kannon"},
+		Priority:    Transactional,
+		ResentFrom:  "msg_1@kannon.io",
+	}, pm)
+
+	msg.Fields = json.RawMessage(`[]`)
+	_, err = resentPoolMessage(msg, nil, nil)
+	assert.NotNil(t, err)
+}
+
+func TestResendTo(t *testing.T) {
+	pm := PoolMessage{
+		Recipients: []Recipient{
+			{Email: "a@kannon.io", Fields: map[string]string{"name": "A"}},
+			{Email: "b@kannon.io", Fields: map[string]string{"name": "B"}},
+		},
+		Cc:  []string{"c@kannon.io"},
+		Bcc: []string{"d@kannon.io"},
+	}
+
+	resent := pm.ResendTo([]string{"B@kannon.io", "new@kannon.io"})
+	assert.Equal(t, []Recipient{{Email: "B@kannon.io", Fields: map[string]string{"name": "B"}}}, resent.Recipients)
+	assert.Equal(t, []string{"new@kannon.io"}, resent.To)
+	assert.Nil(t, resent.Cc)
+	assert.Nil(t, resent.Bcc)
+	assert.Equal(t, 2, resent.EmailsCount())
+	// the original is unchanged
+	assert.Len(t, pm.Recipients, 2)
+}
@@ -0,0 +1,112 @@
+package pool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"kannon.gyozatech.dev/generated/sqlc"
+)
+
+// Stage is a lifecycle transition of the email of a message recipient,
+// recorded in the message timeline
+type Stage string
+
+const (
+	// StageAccepted is the creation of the email by a send
+	StageAccepted Stage = "accepted"
+	// StageDispatched is the email published to the sender
+	StageDispatched Stage = "dispatched"
+	// StageAttempt is a failed SMTP attempt, the email is sent again
+	StageAttempt Stage = "attempt"
+	// StageDeferred is the email postponed by the sender without an SMTP attempt
+	StageDeferred Stage = "deferred"
+	// StageDelivered is the email accepted by the recipient server
+	StageDelivered Stage = "delivered"
+	// StageBounced is the email rejected permanently or after the max attempts
+	StageBounced Stage = "bounced"
+	// StageCanceled is the email canceled before its dispatch
+	StageCanceled Stage = "canceled"
+)
+
+// TimelineEntry is a transition of the email of a message recipient
+type TimelineEntry struct {
+	MessageID    string
+	Email        string
+	Stage        Stage
+	Timestamp    time.Time
+	SMTPCode     uint32
+	SMTPResponse string
+	Data         map[string]interface{}
+}
+
+// GetMessageTimeline returns the transitions of the emails of a message
+// of a domain, oldest first
+func (m *sendingPoolManager) GetMessageTimeline(domain string, messageID string) ([]sqlc.MessageTimeline, error) {
+	return m.db.GetMessageTimeline(context.TODO(), sqlc.GetMessageTimelineParams{
+		Domain:    domain,
+		MessageID: messageID,
+	})
+}
+
+// SetDispatched records the dispatch of a pool email to the sender from ipPool,
+// the email is no longer reclaimed
+func (m *sendingPoolManager) SetDispatched(ctx context.Context, id int32, ipPool string) error {
+	return m.inTx(ctx, func(txm *sendingPoolManager) error {
+		if err := txm.db.SetSendingPoolEmailPublished(ctx, id); err != nil {
+			return err
+		}
+		return txm.addPoolTimeline(ctx, []int32{id}, StageDispatched, map[string]interface{}{"ip_pool": ipPool})
+	})
+}
+
+// addTimeline adds an entry to the timeline of a message recipient
+func (m *sendingPoolManager) addTimeline(ctx context.Context, entry TimelineEntry) error {
+	data, err := timelineData(entry.Data)
+	if err != nil {
+		return err
+	}
+	err = m.db.CreateTimelineEntry(ctx, sqlc.CreateTimelineEntryParams{
+		MessageID:    entry.MessageID,
+		Email:        entry.Email,
+		Stage:        string(entry.Stage),
+		SmtpCode:     int32(entry.SMTPCode),
+		SmtpResponse: entry.SMTPResponse,
+		Data:         data,
+		Timestamp:    entry.Timestamp,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot add %v to the timeline: %w", entry.Stage, err)
+	}
+	return nil
+}
+
+// addPoolTimeline adds an entry of stage to the timeline of the pool emails ids
+func (m *sendingPoolManager) addPoolTimeline(ctx context.Context, ids []int32, stage Stage, entryData map[string]interface{}) error {
+	data, err := timelineData(entryData)
+	if err != nil {
+		return err
+	}
+	err = m.db.CreatePoolTimelineEntries(ctx, sqlc.CreatePoolTimelineEntriesParams{
+		Stage:     string(stage),
+		Data:      data,
+		Timestamp: time.Now(),
+		Ids:       ids,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot add %v to the timeline: %w", stage, err)
+	}
+	return nil
+}
+
+// timelineData returns the json of the data of an entry, empty fields are omitted
+func timelineData(data map[string]interface{}) ([]byte, error) {
+	res := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		if v != "" && v != nil {
+			res[k] = v
+		}
+	}
+	return json.Marshal(res)
+}
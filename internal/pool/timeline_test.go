@@ -0,0 +1,23 @@
+package pool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"kannon.gyozatech.dev/generated/sqlc"
+)
+
+func TestTimelineData(t *testing.T) {
+	data, err := timelineData(map[string]interface{}{
+		"bounce_type": sqlc.BounceTypeSoft,
+		"attempt":     int16(2),
+		"retry_at":    "",
+		"ip_pool":     nil,
+	})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"bounce_type": "soft", "attempt": 2}`, string(data))
+
+	data, err = timelineData(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "{}", string(data))
+}
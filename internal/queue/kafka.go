@@ -0,0 +1,243 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/metrics"
+)
+
+var log = logging.Logger("queue")
+
+// kafkaBatchTimeout is the max wait of a published message before
+// it's written to kafka, publishes are synchronous
+const kafkaBatchTimeout = 10 * time.Millisecond
+
+// kafkaBroker is a Broker on kafka, every subject is a topic
+// and consumers are consumer groups
+type kafkaBroker struct {
+	brokers []string
+	writer  *kafka.Writer
+}
+
+func openKafka(brokers []string) (*kafkaBroker, error) {
+	if len(brokers) == 0 {
+		return nil, errors.New("no kafka brokers")
+	}
+	return &kafkaBroker{
+		brokers: brokers,
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			BatchTimeout: kafkaBatchTimeout,
+			RequiredAcks: kafka.RequireAll,
+		},
+	}, nil
+}
+
+func (b *kafkaBroker) Publish(subject string, data []byte) error {
+	return b.publishHeaders(subject, "", nil, data)
+}
+
+// PublishOnce publishes a message keyed by id, kafka doesn't
+// deduplicate messages with the same key
+func (b *kafkaBroker) PublishOnce(subject string, id string, data []byte) error {
+	return b.publishHeaders(subject, id, nil, data)
+}
+
+func (b *kafkaBroker) publishHeaders(subject string, id string, headers map[string]string, data []byte) error {
+	msg := kafka.Message{
+		Topic: subject,
+		Value: data,
+	}
+	if id != "" {
+		msg.Key = []byte(id)
+	}
+	for k, v := range headers {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+	if err := b.writer.WriteMessages(context.Background(), msg); err != nil {
+		return err
+	}
+	metrics.PublishedMessages.WithLabelValues(subject).Inc()
+	return nil
+}
+
+// Consume reads the messages of the topics of a consumer in the consumer
+// group name, offsets are committed by Ack in the order of the partitions
+func (b *kafkaBroker) Consume(ctx context.Context, name string, handle func(Message)) {
+	topics := Subjects
+	if subject := consumerSubject(name); subject != "" {
+		topics = []string{subject}
+	}
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     b.brokers,
+		GroupID:     name,
+		GroupTopics: topics,
+	})
+	defer func() {
+		if err := r.Close(); err != nil {
+			log.Warnf("cannot close kafka reader of %v: %v", name, err)
+		}
+	}()
+
+	offsets := newKafkaOffsets()
+	delay := minRetryDelay
+	for ctx.Err() == nil {
+		msg, err := r.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			metrics.ConsumerErrors.WithLabelValues(name).Inc()
+			log.Warnf("cannot fetch messages of %v, retrying in %v: %v", name, delay, err)
+			wait(ctx, delay)
+			delay = nextRetryDelay(delay)
+			continue
+		}
+		delay = minRetryDelay
+		// lag of the partition of the message
+		metrics.ConsumerPending.WithLabelValues(name).Set(float64(msg.HighWaterMark - msg.Offset - 1))
+		offsets.fetched(msg)
+		handle(kafkaMessage{r: r, msg: msg, offsets: offsets})
+		metrics.ConsumedMessages.WithLabelValues(name).Inc()
+	}
+}
+
+// ping connects to the first reachable kafka broker
+func (b *kafkaBroker) ping(ctx context.Context) error {
+	var err error
+	for _, addr := range b.brokers {
+		var conn *kafka.Conn
+		conn, err = kafka.DialContext(ctx, "tcp", addr)
+		if err == nil {
+			return conn.Close()
+		}
+	}
+	return err
+}
+
+func (b *kafkaBroker) Close() {
+	if err := b.writer.Close(); err != nil {
+		log.Warnf("cannot close kafka writer: %v", err)
+	}
+}
+
+type kafkaMessage struct {
+	r       *kafka.Reader
+	msg     kafka.Message
+	offsets *kafkaOffsets
+}
+
+func (m kafkaMessage) Subject() string {
+	return m.msg.Topic
+}
+
+func (m kafkaMessage) Data() []byte {
+	return m.msg.Value
+}
+
+func (m kafkaMessage) header(key string) string {
+	for _, h := range m.msg.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// Ack commits the offset of the message once the previous messages of
+// its partition are acked too, messages handled in parallel are acked out
+// of order and a crash must not skip the messages not handled yet
+func (m kafkaMessage) Ack() error {
+	err := m.offsets.ack(m.msg, func(commit kafka.Message) error {
+		return m.r.CommitMessages(context.Background(), commit)
+	})
+	if err != nil {
+		return err
+	}
+	metrics.AckedMessages.WithLabelValues(m.msg.Topic).Inc()
+	return nil
+}
+
+// kafkaPartition is a partition of a topic
+type kafkaPartition struct {
+	topic     string
+	partition int
+}
+
+// kafkaOffsets are the offsets of the messages fetched by a consumer
+// and not committed yet, by partition
+type kafkaOffsets struct {
+	mu         sync.Mutex
+	partitions map[kafkaPartition]*partitionOffsets
+}
+
+// partitionOffsets are the offsets of the messages of a partition not
+// committed yet in fetch order, and the acked ones
+type partitionOffsets struct {
+	pending []int64
+	acked   map[int64]bool
+}
+
+func newKafkaOffsets() *kafkaOffsets {
+	return &kafkaOffsets{partitions: make(map[kafkaPartition]*partitionOffsets)}
+}
+
+// fetched adds a fetched message to the pending ones of its partition, a message
+// not after the pending ones is fetched again from the committed offset, like
+// after a rebalance, and the pending ones are forgotten
+func (o *kafkaOffsets) fetched(msg kafka.Message) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	key := kafkaPartition{topic: msg.Topic, partition: msg.Partition}
+	p := o.partitions[key]
+	if p == nil || (len(p.pending) > 0 && msg.Offset <= p.pending[len(p.pending)-1]) {
+		p = &partitionOffsets{acked: make(map[int64]bool)}
+		o.partitions[key] = p
+	}
+	p.pending = append(p.pending, msg.Offset)
+}
+
+// ack marks msg as acked and calls commit with the last message of the contiguous
+// prefix of acked messages of its partition, if any. Commits are serialized, the
+// committed offset of a partition never goes back
+func (o *kafkaOffsets) ack(msg kafka.Message, commit func(kafka.Message) error) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	p := o.partitions[kafkaPartition{topic: msg.Topic, partition: msg.Partition}]
+	if p == nil || !p.isPending(msg.Offset) {
+		// fetched before a rebalance, it's delivered again
+		return nil
+	}
+	p.acked[msg.Offset] = true
+	n := 0
+	for n < len(p.pending) && p.acked[p.pending[n]] {
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+	last := p.pending[n-1]
+	err := commit(kafka.Message{Topic: msg.Topic, Partition: msg.Partition, Offset: last})
+	if err != nil {
+		return err
+	}
+	for _, offset := range p.pending[:n] {
+		delete(p.acked, offset)
+	}
+	p.pending = p.pending[n:]
+	return nil
+}
+
+func (p *partitionOffsets) isPending(offset int64) bool {
+	for _, o := range p.pending {
+		if o == offset {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package queue
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKafkaOffsets(t *testing.T) {
+	o := newKafkaOffsets()
+	var committed []int64
+	commit := func(msg kafka.Message) error {
+		committed = append(committed, msg.Offset)
+		return nil
+	}
+	msg := func(offset int64) kafka.Message {
+		return kafka.Message{Topic: "emails.sending", Partition: 1, Offset: offset}
+	}
+	for offset := int64(3); offset <= 6; offset++ {
+		o.fetched(msg(offset))
+	}
+	// other partitions are committed on their own
+	o.fetched(kafka.Message{Topic: "emails.sending", Partition: 2, Offset: 10})
+
+	// 5 finished before 3 and 4: nothing is committed
+	assert.Nil(t, o.ack(msg(5), commit))
+	assert.Empty(t, committed)
+	assert.Nil(t, o.ack(msg(3), commit))
+	assert.Equal(t, []int64{3}, committed)
+	assert.Nil(t, o.ack(msg(4), commit))
+	assert.Equal(t, []int64{3, 5}, committed)
+
+	// a failed commit is retried by the next ack
+	assert.NotNil(t, o.ack(msg(6), func(kafka.Message) error { return errors.New("unavailable") }))
+	assert.Nil(t, o.ack(msg(6), commit))
+	assert.Equal(t, []int64{3, 5, 6}, committed)
+
+	// messages fetched again after a rebalance reset the partition
+	o.fetched(msg(7))
+	o.fetched(msg(6))
+	assert.Nil(t, o.ack(msg(7), commit))
+	assert.Equal(t, []int64{3, 5, 6}, committed)
+	assert.Nil(t, o.ack(msg(6), commit))
+	assert.Equal(t, []int64{3, 5, 6, 6}, committed)
+	// the ack of 7 before the rebalance doesn't commit it again
+	o.fetched(msg(7))
+	o.fetched(msg(8))
+	assert.Nil(t, o.ack(msg(8), commit))
+	assert.Equal(t, []int64{3, 5, 6, 6}, committed)
+}
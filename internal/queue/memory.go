@@ -0,0 +1,252 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"kannon.gyozatech.dev/internal/jetstream"
+	"kannon.gyozatech.dev/internal/metrics"
+)
+
+// memory is the in-process broker, shared by the services of the process
+var memory struct {
+	once   sync.Once
+	broker *memoryBroker
+}
+
+// memoryBroker is an in-process Broker for installs running every service
+// in a single process, messages are kept in memory and lost on exit
+type memoryBroker struct {
+	mu        sync.Mutex
+	queues    map[string]*memoryQueue
+	subs      map[string][]func(Message)
+	consumers map[string]jetstream.ConsumerConfig
+	// published are the publish times of the ids of PublishOnce
+	published map[string]time.Time
+}
+
+func openMemory(consumers map[string]jetstream.ConsumerConfig) *memoryBroker {
+	memory.once.Do(func() {
+		memory.broker = newMemoryBroker()
+	})
+	b := memory.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for name, config := range consumers {
+		b.consumers[name] = config
+	}
+	return b
+}
+
+func newMemoryBroker() *memoryBroker {
+	b := &memoryBroker{
+		queues:    make(map[string]*memoryQueue, len(Consumers)),
+		subs:      make(map[string][]func(Message)),
+		consumers: make(map[string]jetstream.ConsumerConfig),
+		published: make(map[string]time.Time),
+	}
+	// queues are durable like JetStream consumers, messages
+	// published before Consume are not lost
+	for name := range Consumers {
+		b.queues[name] = &memoryQueue{ready: make(chan struct{}, 1)}
+	}
+	return b
+}
+
+// Publish queues a message for the consumers of subject
+// and calls the handlers subscribed to it
+func (b *memoryBroker) Publish(subject string, data []byte) error {
+	return b.publishHeaders(subject, "", nil, data)
+}
+
+// PublishOnce publishes a message unless a message with
+// the same id was published within jetstream.DuplicateWindow
+func (b *memoryBroker) PublishOnce(subject string, id string, data []byte) error {
+	return b.publishHeaders(subject, id, nil, data)
+}
+
+func (b *memoryBroker) publishHeaders(subject string, id string, headers map[string]string, data []byte) error {
+	if id != "" && b.isDuplicate(id) {
+		return nil
+	}
+	data = append([]byte(nil), data...)
+
+	b.mu.Lock()
+	subs := b.subs[subject]
+	b.mu.Unlock()
+
+	for name, q := range b.queues {
+		if consumes(Consumers[name], subject) {
+			q.push(&memoryEntry{subject: subject, headers: headers, data: data})
+		}
+	}
+	metrics.PublishedMessages.WithLabelValues(subject).Inc()
+	for _, handle := range subs {
+		go handle(&memoryMessage{entry: &memoryEntry{subject: subject, headers: headers, data: data}})
+	}
+	return nil
+}
+
+// isDuplicate reports if a message with id was published within
+// jetstream.DuplicateWindow, recording the publish when not
+func (b *memoryBroker) isDuplicate(id string) bool {
+	now := time.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for k, t := range b.published {
+		if now.Sub(t) >= jetstream.DuplicateWindow {
+			delete(b.published, k)
+		}
+	}
+	if _, duplicate := b.published[id]; duplicate {
+		return true
+	}
+	b.published[id] = now
+	return false
+}
+
+// Subscribe calls handle for every message published on subject
+func (b *memoryBroker) Subscribe(subject string, handle func(Message)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[subject] = append(b.subs[subject], handle)
+	return nil
+}
+
+// Consume reads the messages of a consumer, messages without
+// ack within the consumer ack wait are delivered again
+func (b *memoryBroker) Consume(ctx context.Context, name string, handle func(Message)) {
+	consumerSubject(name)
+	q := b.queues[name]
+
+	b.mu.Lock()
+	config, ok := b.consumers[name]
+	b.mu.Unlock()
+	if !ok {
+		config = jetstream.DefaultConsumerConfig
+	}
+	ackWait := config.AckWait
+	if ackWait <= 0 {
+		ackWait = jetstream.DefaultConsumerConfig.AckWait
+	}
+
+	for {
+		e, ok := q.pop(ctx)
+		if !ok {
+			return
+		}
+		metrics.ConsumerPending.WithLabelValues(name).Set(float64(q.len()))
+		e.deliveries++
+		msg := &memoryMessage{entry: e}
+		msg.timer = time.AfterFunc(ackWait, func() {
+			if config.MaxDeliver <= 0 || e.deliveries < config.MaxDeliver {
+				q.push(e)
+			}
+		})
+		handle(msg)
+		metrics.ConsumedMessages.WithLabelValues(name).Inc()
+	}
+}
+
+// Close does nothing, the broker is shared by the services of the process
+func (b *memoryBroker) Close() {}
+
+// consumes reports if a consumer reading subject receives the messages
+// published on published, the empty subject reads every subject
+func consumes(subject string, published string) bool {
+	if subject != "" {
+		return subject == published
+	}
+	for _, s := range Subjects {
+		if s == published {
+			return true
+		}
+	}
+	return false
+}
+
+type memoryQueue struct {
+	mu       sync.Mutex
+	messages []*memoryEntry
+	// ready wakes up a consumer waiting for messages
+	ready chan struct{}
+}
+
+func (q *memoryQueue) push(e *memoryEntry) {
+	q.mu.Lock()
+	q.messages = append(q.messages, e)
+	q.mu.Unlock()
+	q.signal()
+}
+
+func (q *memoryQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.messages)
+}
+
+// pop waits for the first message of the queue or until ctx is done
+func (q *memoryQueue) pop(ctx context.Context) (*memoryEntry, bool) {
+	for {
+		q.mu.Lock()
+		if len(q.messages) > 0 {
+			e := q.messages[0]
+			q.messages[0] = nil
+			q.messages = q.messages[1:]
+			left := len(q.messages)
+			q.mu.Unlock()
+			if left > 0 {
+				q.signal()
+			}
+			return e, true
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case <-q.ready:
+		}
+	}
+}
+
+func (q *memoryQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+type memoryEntry struct {
+	subject    string
+	headers    map[string]string
+	data       []byte
+	deliveries int
+}
+
+type memoryMessage struct {
+	entry *memoryEntry
+	// timer delivers the message again, stopped by Ack
+	timer *time.Timer
+}
+
+func (m *memoryMessage) Subject() string {
+	return m.entry.subject
+}
+
+func (m *memoryMessage) Data() []byte {
+	return m.entry.data
+}
+
+func (m *memoryMessage) header(key string) string {
+	return m.entry.headers[key]
+}
+
+func (m *memoryMessage) Ack() error {
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	metrics.AckedMessages.WithLabelValues(m.entry.subject).Inc()
+	return nil
+}
@@ -0,0 +1,103 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"kannon.gyozatech.dev/internal/jetstream"
+	"kannon.gyozatech.dev/internal/tracing"
+)
+
+func TestMemoryConsume(t *testing.T) {
+	b := newMemoryBroker()
+	assert.Nil(t, b.Publish("emails.delivered", []byte("delivered")))
+	assert.Nil(t, b.Publish("pools.scheduled", nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var received []string
+	b.Consume(ctx, "email-delivered", func(msg Message) {
+		received = append(received, string(msg.Data()))
+		assert.Nil(t, msg.Ack())
+		cancel()
+	})
+	assert.Equal(t, []string{"delivered"}, received)
+
+	// stats read every subject but pools.scheduled
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	var subjects []string
+	b.Consume(ctx, "stats", func(msg Message) {
+		subjects = append(subjects, msg.Subject())
+		assert.Nil(t, msg.Ack())
+	})
+	assert.Equal(t, []string{"emails.delivered"}, subjects)
+}
+
+func TestMemoryRedelivery(t *testing.T) {
+	b := newMemoryBroker()
+	b.consumers["email-error"] = jetstream.ConsumerConfig{AckWait: 10 * time.Millisecond, MaxDeliver: 2}
+	assert.Nil(t, b.Publish("emails.error", []byte("error")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	deliveries := 0
+	b.Consume(ctx, "email-error", func(msg Message) {
+		deliveries++
+	})
+	assert.Equal(t, 2, deliveries)
+}
+
+func TestMemoryPublishOnce(t *testing.T) {
+	b := newMemoryBroker()
+	assert.Nil(t, b.PublishOnce("emails.sending", "id", []byte("first")))
+	assert.Nil(t, b.PublishOnce("emails.sending", "id", []byte("second")))
+	assert.Nil(t, b.PublishOnce("emails.sending", "other", []byte("other")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	var received []string
+	b.Consume(ctx, "sending-pool", func(msg Message) {
+		received = append(received, string(msg.Data()))
+		assert.Nil(t, msg.Ack())
+	})
+	assert.Equal(t, []string{"first", "other"}, received)
+}
+
+func TestMemorySubscribe(t *testing.T) {
+	b := newMemoryBroker()
+	ch := make(chan string, 1)
+	assert.Nil(t, b.Subscribe("pools.scheduled", func(msg Message) {
+		ch <- msg.Subject()
+	}))
+	assert.Nil(t, b.Publish("pools.scheduled", nil))
+
+	select {
+	case subject := <-ch:
+		assert.Equal(t, "pools.scheduled", subject)
+	case <-time.After(time.Second):
+		t.Fatal("message not received")
+	}
+}
+
+func TestMemoryPublishContext(t *testing.T) {
+	b := newMemoryBroker()
+	traceParent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	ctx := tracing.ContextWithTraceParent(context.Background(), traceParent)
+	assert.Nil(t, PublishContext(ctx, b, "emails.delivered", []byte("delivered")))
+	assert.Nil(t, PublishOnceContext(ctx, b, "emails.sending", "id", []byte("sending")))
+
+	for _, name := range []string{"email-delivered", "sending-pool"} {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		b.Consume(ctx, name, func(msg Message) {
+			assert.Equal(t, traceParent, tracing.TraceParent(MessageContext(context.Background(), msg)))
+			cancel()
+		})
+		assert.Equal(t, context.Canceled, ctx.Err())
+	}
+}
@@ -0,0 +1,232 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats.go"
+	"kannon.gyozatech.dev/internal/jetstream"
+	"kannon.gyozatech.dev/internal/metrics"
+	"kannon.gyozatech.dev/internal/tlsconfig"
+)
+
+// msgIDHeader is the header of the ids deduplicated by JetStream
+const msgIDHeader = "Nats-Msg-Id"
+
+// pubAckTimeout is the max wait of the ack of JetStream to a publish
+const pubAckTimeout = 5 * time.Second
+
+// pendingInterval is the interval between the reads of the
+// messages pending of a consumer
+const pendingInterval = 15 * time.Second
+
+// NatsBroker is a Broker on NATS JetStream, messages are stored in
+// jetstream.Stream and read by durable pull consumers
+type NatsBroker struct {
+	Conn      *nats.Conn
+	Manager   *jsm.Manager
+	consumers map[string]jetstream.ConsumerConfig
+}
+
+func openNats(url string, tlsConfig tlsconfig.Config, consumers map[string]jetstream.ConsumerConfig) (*NatsBroker, error) {
+	opts := []nats.Option{nats.UseOldRequestStyle(), nats.MaxReconnects(-1)}
+	if tlsConfig.Enabled() {
+		if err := tlsConfig.Validate(); err != nil {
+			return nil, err
+		}
+		config, err := tlsConfig.ClientConfig()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, nats.Secure(config))
+	}
+	nc, err := nats.Connect(url, opts...)
+	if err != nil {
+		return nil, err
+	}
+	mgr, err := jsm.New(nc)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	if err := jetstream.Provision(mgr); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	return &NatsBroker{
+		Conn:      nc,
+		Manager:   mgr,
+		consumers: consumers,
+	}, nil
+}
+
+// Publish publishes a message on subject
+func (b *NatsBroker) Publish(subject string, data []byte) error {
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	if err := b.publishMsg(msg); err != nil {
+		return err
+	}
+	metrics.PublishedMessages.WithLabelValues(subject).Inc()
+	return nil
+}
+
+// PublishOnce publishes a message with the Nats-Msg-Id header,
+// deduplicated by the stream
+func (b *NatsBroker) PublishOnce(subject string, id string, data []byte) error {
+	return b.publishHeaders(subject, id, nil, data)
+}
+
+func (b *NatsBroker) publishHeaders(subject string, id string, headers map[string]string, data []byte) error {
+	msg := nats.NewMsg(subject)
+	for k, v := range headers {
+		msg.Header.Set(k, v)
+	}
+	if id != "" {
+		msg.Header.Set(msgIDHeader, id)
+	}
+	msg.Data = data
+	if err := b.publishMsg(msg); err != nil {
+		return err
+	}
+	metrics.PublishedMessages.WithLabelValues(subject).Inc()
+	return nil
+}
+
+// publishMsg publishes msg, the messages of the subjects of the stream
+// are published once JetStream acks they are stored
+func (b *NatsBroker) publishMsg(msg *nats.Msg) error {
+	if !jetstream.Stores(msg.Subject) {
+		return b.Conn.PublishMsg(msg)
+	}
+	res, err := b.Conn.RequestMsg(msg, pubAckTimeout)
+	if err != nil {
+		return fmt.Errorf("no ack of the publish on %v: %w", msg.Subject, err)
+	}
+	var ack api.JSPubAckResponse
+	if err := json.Unmarshal(res.Data, &ack); err != nil {
+		return fmt.Errorf("invalid ack of the publish on %v: %w", msg.Subject, err)
+	}
+	if ack.Error != nil {
+		return fmt.Errorf("publish on %v not stored: %w", msg.Subject, ack.Error)
+	}
+	return nil
+}
+
+// Subscribe calls handle for every message published on subject
+func (b *NatsBroker) Subscribe(subject string, handle func(Message)) error {
+	_, err := b.Conn.Subscribe(subject, func(msg *nats.Msg) {
+		handle(natsMessage{msg})
+	})
+	return err
+}
+
+// Consume reads the messages of a JetStream consumer, consumers
+// are loaded again after every error
+func (b *NatsBroker) Consume(ctx context.Context, name string, handle func(Message)) {
+	subject := consumerSubject(name)
+	config, ok := b.consumers[name]
+	if !ok {
+		config = jetstream.DefaultConsumerConfig
+	}
+
+	// the pending messages of a consumer stalled on a message are still reported
+	go b.reportPending(ctx, name)
+
+	var con *jsm.Consumer
+	delay := minRetryDelay
+	for ctx.Err() == nil {
+		var err error
+		if con == nil {
+			con, err = jetstream.LoadConsumer(b.Manager, name, subject, config)
+		}
+		var msg *nats.Msg
+		if err == nil {
+			msg, err = con.NextMsgContext(ctx)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			metrics.ConsumerErrors.WithLabelValues(name).Inc()
+			log.Warnf("cannot fetch messages of %v, retrying in %v: %v", name, delay, err)
+			con = nil
+			wait(ctx, delay)
+			delay = nextRetryDelay(delay)
+			continue
+		}
+		delay = minRetryDelay
+		if info, err := jsm.ParseJSMsgMetadata(msg); err == nil && info.Pending() != math.MaxUint64 {
+			metrics.ConsumerPending.WithLabelValues(name).Set(float64(info.Pending()))
+		}
+		handle(natsMessage{msg})
+		metrics.ConsumedMessages.WithLabelValues(name).Inc()
+	}
+}
+
+// reportPending sets the pending messages of a consumer every
+// pendingInterval, with or without fetches, until ctx is done
+func (b *NatsBroker) reportPending(ctx context.Context, name string) {
+	ticker := time.NewTicker(pendingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		con, err := b.Manager.LoadConsumer(jetstream.Stream, name)
+		if err != nil {
+			log.Warnf("cannot load consumer %v: %v", name, err)
+			continue
+		}
+		pending, err := con.PendingMessages()
+		if err != nil {
+			log.Warnf("cannot get pending messages of %v: %v", name, err)
+			continue
+		}
+		metrics.ConsumerPending.WithLabelValues(name).Set(float64(pending))
+	}
+}
+
+// ping waits for a round trip to the NATS server
+func (b *NatsBroker) ping(ctx context.Context) error {
+	return b.Conn.FlushWithContext(ctx)
+}
+
+// Close closes the connection to NATS
+func (b *NatsBroker) Close() {
+	b.Conn.Close()
+}
+
+type natsMessage struct {
+	msg *nats.Msg
+}
+
+func (m natsMessage) Subject() string {
+	return m.msg.Subject
+}
+
+func (m natsMessage) Data() []byte {
+	return m.msg.Data
+}
+
+func (m natsMessage) header(key string) string {
+	if m.msg.Header == nil {
+		return ""
+	}
+	return m.msg.Header.Get(key)
+}
+
+func (m natsMessage) Ack() error {
+	if err := m.msg.Ack(); err != nil {
+		return err
+	}
+	metrics.AckedMessages.WithLabelValues(m.msg.Subject).Inc()
+	return nil
+}
@@ -0,0 +1,200 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"kannon.gyozatech.dev/internal/health"
+	"kannon.gyozatech.dev/internal/jetstream"
+	"kannon.gyozatech.dev/internal/tlsconfig"
+	"kannon.gyozatech.dev/internal/tracing"
+)
+
+// Message is a message read by a Consumer
+type Message interface {
+	Subject() string
+	Data() []byte
+	// Ack marks the message as handled,
+	// messages without ack are delivered again
+	Ack() error
+}
+
+// Publisher publishes messages on subjects, *nats.Conn is a Publisher
+type Publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// Consumer reads the messages of the durable consumers of Consumers
+type Consumer interface {
+	// Consume calls handle for every message of a consumer until ctx is done,
+	// errors fetching messages are retried with exponential backoff
+	Consume(ctx context.Context, name string, handle func(Message))
+}
+
+// Subscriber receives the messages published on a subject from now on,
+// it's implemented only by the brokers supporting non durable subscriptions
+type Subscriber interface {
+	Subscribe(subject string, handle func(Message)) error
+}
+
+// Broker publishes and consumes the messages of kannon services
+type Broker interface {
+	Publisher
+	Consumer
+	// PublishOnce publishes a message deduplicated by id, messages with the id of
+	// a message published within jetstream.DuplicateWindow are dropped
+	PublishOnce(subject string, id string, data []byte) error
+	Close()
+}
+
+// headerPublisher publishes messages with headers, the brokers are headerPublishers,
+// id deduplicates the message like PublishOnce when not empty
+type headerPublisher interface {
+	publishHeaders(subject string, id string, headers map[string]string, data []byte) error
+}
+
+// headerMessage is a message with headers, the messages of the brokers are headerMessages
+type headerMessage interface {
+	header(key string) string
+}
+
+// pinger checks the connection to the broker, the brokers
+// connecting to a server are pingers
+type pinger interface {
+	ping(ctx context.Context) error
+}
+
+// Ping checks the connection of b to its server
+func Ping(ctx context.Context, b Broker) error {
+	if p, ok := b.(pinger); ok {
+		return p.ping(ctx)
+	}
+	return nil
+}
+
+// HealthCheck checks the connection of b to its server
+func HealthCheck(b Broker) health.Check {
+	return health.Check{Name: "queue", Check: func(ctx context.Context) error {
+		return Ping(ctx, b)
+	}}
+}
+
+// PublishContext publishes a message on subject with the trace context of ctx
+// in its headers, messages of publishers without headers have no trace context
+func PublishContext(ctx context.Context, p Publisher, subject string, data []byte) error {
+	traceParent := tracing.TraceParent(ctx)
+	if hp, ok := p.(headerPublisher); ok && traceParent != "" {
+		return hp.publishHeaders(subject, "", map[string]string{tracing.TraceParentHeader: traceParent}, data)
+	}
+	return p.Publish(subject, data)
+}
+
+// PublishOnceContext publishes a message deduplicated by id like PublishOnce,
+// with the trace context of ctx in its headers
+func PublishOnceContext(ctx context.Context, b Broker, subject string, id string, data []byte) error {
+	traceParent := tracing.TraceParent(ctx)
+	if hp, ok := b.(headerPublisher); ok && traceParent != "" {
+		return hp.publishHeaders(subject, id, map[string]string{tracing.TraceParentHeader: traceParent}, data)
+	}
+	return b.PublishOnce(subject, id, data)
+}
+
+// MessageContext returns ctx with the trace context of the headers of msg,
+// the parent of the spans handling the message
+func MessageContext(ctx context.Context, msg Message) context.Context {
+	if hm, ok := msg.(headerMessage); ok {
+		return tracing.ContextWithTraceParent(ctx, hm.header(tracing.TraceParentHeader))
+	}
+	return ctx
+}
+
+// Subjects are the subjects of the messages consumed by kannon services
+var Subjects = []string{
+	"emails.sending",
+	"emails.delivered",
+	"emails.error",
+	"emails.deferred",
+	"emails.opened",
+	"emails.clicked",
+	"emails.unsubscribed",
+	"emails.complained",
+	"emails.dead",
+	"emails.dmarc",
+	"emails.alerts",
+}
+
+// Consumers are the durable consumers of the kannon services with the
+// subject they read, consumers with an empty subject read every subject
+var Consumers = map[string]string{
+	"sending-pool":       "emails.sending",
+	"email-delivered":    "emails.delivered",
+	"email-error":        "emails.error",
+	"email-deferred":     "emails.deferred",
+	"email-opened":       "emails.opened",
+	"email-unsubscribed": "emails.unsubscribed",
+	"email-complained":   "emails.complained",
+	"dead-letters":       "emails.dead",
+	"dmarc-reports":      "emails.dmarc",
+	"stats":              "",
+	"webhooks":           "",
+}
+
+// Config configures the connection to the broker
+type Config struct {
+	// Broker is the message broker: nats, kafka or memory, the in-process
+	// memory broker works only for services of the same process
+	Broker   string `default:"nats"`
+	NatsConn string `default:"nats://127.0.0.1:4222"`
+	// NatsTLS are the CAs of the NATS server and the client certificate,
+	// like APP_NATSTLS_CAFILE, the connection uses TLS when set
+	NatsTLS tlsconfig.Config
+	// KafkaBrokers are the addresses of the kafka brokers, like kafka:9092
+	KafkaBrokers []string
+}
+
+// Open connects to the broker of config, consumers are the settings of the
+// JetStream consumers created by Consume, jetstream.DefaultConsumerConfig when missing
+func Open(config Config, consumers map[string]jetstream.ConsumerConfig) (Broker, error) {
+	switch config.Broker {
+	case "", "nats":
+		return openNats(config.NatsConn, config.NatsTLS, consumers)
+	case "kafka":
+		return openKafka(config.KafkaBrokers)
+	case "memory":
+		return openMemory(consumers), nil
+	default:
+		return nil, fmt.Errorf("unknown broker: %v", config.Broker)
+	}
+}
+
+const (
+	// minRetryDelay and maxRetryDelay bound the backoff
+	// of Consume when messages cannot be fetched
+	minRetryDelay = 500 * time.Millisecond
+	maxRetryDelay = 30 * time.Second
+)
+
+func nextRetryDelay(delay time.Duration) time.Duration {
+	delay *= 2
+	if delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return delay
+}
+
+// wait waits for delay or until ctx is done
+func wait(ctx context.Context, delay time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(delay):
+	}
+}
+
+func consumerSubject(name string) string {
+	subject, ok := Consumers[name]
+	if !ok {
+		panic(fmt.Errorf("unknown consumer %v", name))
+	}
+	return subject
+}
@@ -0,0 +1,30 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsumers(t *testing.T) {
+	for name, subject := range Consumers {
+		if subject != "" {
+			assert.Contains(t, Subjects, subject, "subject of %v", name)
+		}
+	}
+}
+
+func TestNextRetryDelay(t *testing.T) {
+	assert.Equal(t, time.Second, nextRetryDelay(minRetryDelay))
+	assert.Equal(t, maxRetryDelay, nextRetryDelay(20*time.Second))
+	assert.Equal(t, maxRetryDelay, nextRetryDelay(maxRetryDelay))
+}
+
+func TestOpenUnknownBroker(t *testing.T) {
+	_, err := Open(Config{Broker: "rabbitmq"}, nil)
+	assert.NotNil(t, err)
+
+	_, err = Open(Config{Broker: "kafka"}, nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,91 @@
+package quotas
+
+import (
+	"context"
+	"database/sql"
+
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/metrics"
+)
+
+// Quota is the daily and monthly quota of a domain with the emails
+// dispatched today and this month, 0 quotas are unlimited
+type Quota struct {
+	Daily       uint
+	Monthly     uint
+	DailyUsed   uint
+	MonthlyUsed uint
+}
+
+// Limited reports if the domain has a daily or monthly quota
+func (q Quota) Limited() bool {
+	return q.Daily != 0 || q.Monthly != 0
+}
+
+// Remaining returns the emails the domain can still dispatch today,
+// it's meaningful only for limited quotas
+func (q Quota) Remaining() uint {
+	remaining := ^uint(0)
+	if q.Daily != 0 {
+		remaining = left(q.Daily, q.DailyUsed)
+	}
+	if q.Monthly != 0 && left(q.Monthly, q.MonthlyUsed) < remaining {
+		remaining = left(q.Monthly, q.MonthlyUsed)
+	}
+	return remaining
+}
+
+// Allows reports if n more emails are within the quota
+func (q Quota) Allows(n uint) bool {
+	return !q.Limited() || n <= q.Remaining()
+}
+
+func left(quota uint, used uint) uint {
+	if used >= quota {
+		return 0
+	}
+	return quota - used
+}
+
+// Manager counts the emails dispatched by domains in their quotas
+type Manager interface {
+	// Consume counts a dispatched pool email in the quota of its domain, with
+	// its cc and bcc copies, false is returned when the quota is exceeded
+	Consume(emailID int32) (bool, error)
+	GetQuota(domain sqlc.Domain) (Quota, error)
+}
+
+type manager struct {
+	db *sqlc.Queries
+}
+
+// NewQuotaManager builds a Quota Manager
+func NewQuotaManager(db *sql.DB) (Manager, error) {
+	return &manager{
+		db: sqlc.New(metrics.InstrumentDB(db)),
+	}, nil
+}
+
+func (m *manager) Consume(emailID int32) (bool, error) {
+	if err := m.db.CreateDomainUsage(context.TODO(), emailID); err != nil {
+		return false, err
+	}
+	n, err := m.db.ConsumeDomainQuota(context.TODO(), emailID)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (m *manager) GetQuota(domain sqlc.Domain) (Quota, error) {
+	usage, err := m.db.GetDomainUsage(context.TODO(), domain.Domain)
+	if err != nil {
+		return Quota{}, err
+	}
+	return Quota{
+		Daily:       uint(domain.DailyQuota),
+		Monthly:     uint(domain.MonthlyQuota),
+		DailyUsed:   uint(usage.Daily),
+		MonthlyUsed: uint(usage.Monthly),
+	}, nil
+}
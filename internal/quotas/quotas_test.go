@@ -0,0 +1,25 @@
+package quotas
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemaining(t *testing.T) {
+	q := Quota{}
+	assert.False(t, q.Limited())
+	assert.True(t, q.Allows(1000000))
+
+	q = Quota{Daily: 100, DailyUsed: 40, MonthlyUsed: 900}
+	assert.Equal(t, uint(60), q.Remaining())
+	assert.True(t, q.Allows(60))
+	assert.False(t, q.Allows(61))
+
+	q = Quota{Daily: 100, Monthly: 1000, DailyUsed: 40, MonthlyUsed: 980}
+	assert.Equal(t, uint(20), q.Remaining())
+
+	q = Quota{Monthly: 1000, MonthlyUsed: 1200}
+	assert.Equal(t, uint(0), q.Remaining())
+	assert.False(t, q.Allows(1))
+}
@@ -0,0 +1,120 @@
+package rbac
+
+import (
+	"fmt"
+	"path"
+)
+
+// Role is the role of an operator of the admin API
+type Role string
+
+// Roles of the operators, every role has the rights of the previous ones
+const (
+	// ReadOnly reads domains, keys, stats and messages
+	ReadOnly Role = "read-only"
+	// Operator changes the settings of domains, templates, suppressions and webhooks
+	Operator Role = "operator"
+	// Owner creates domains and manages their API keys
+	Owner Role = "owner"
+)
+
+var ranks = map[Role]int{
+	ReadOnly: 1,
+	Operator: 2,
+	Owner:    3,
+}
+
+// ParseRole parses owner, operator or read-only
+func ParseRole(s string) (Role, error) {
+	r := Role(s)
+	if _, ok := ranks[r]; !ok {
+		return "", fmt.Errorf("invalid role: %v", s)
+	}
+	return r, nil
+}
+
+// Includes reports if r has the rights of role
+func (r Role) Includes(role Role) bool {
+	return ranks[r] > 0 && ranks[r] >= ranks[role]
+}
+
+// Highest returns the highest of the valid roles, false when there is none
+func Highest(roles []string) (Role, bool) {
+	var highest Role
+	for _, s := range roles {
+		r, err := ParseRole(s)
+		if err != nil {
+			continue
+		}
+		if ranks[r] > ranks[highest] {
+			highest = r
+		}
+	}
+	return highest, highest != ""
+}
+
+// methodRoles are the roles required by the RPCs of the admin API
+var methodRoles = map[string]Role{
+	"GetDomains":            ReadOnly,
+	"GetAPIKeys":            ReadOnly,
+	"GetAPIKeyCalls":        ReadOnly,
+	"GetDomainDKIMKeys":     ReadOnly,
+	"GetDomainVerification": ReadOnly,
+	"GetDomainDNSRecords":   ReadOnly,
+	"GetTemplate":           ReadOnly,
+	"GetTemplates":          ReadOnly,
+	"GetSuppressions":       ReadOnly,
+	"GetWebhooks":           ReadOnly,
+	"GetWebhookDeliveries":  ReadOnly,
+	"SearchMessages":        ReadOnly,
+	"GetDeadLetters":        ReadOnly,
+	"GetDomainReputation":   ReadOnly,
+	"GetAuditLogs":          ReadOnly,
+	"GetSendingStatus":      ReadOnly,
+	// the server reflection lists the RPCs of the admin API
+	"ServerReflectionInfo": ReadOnly,
+
+	"SetDomainRetention":       Operator,
+	"SetDomainRateLimit":       Operator,
+	"SetDomainQuota":           Operator,
+	"SetDomainIPPool":          Operator,
+	"SetDomainReturnPath":      Operator,
+	"SetDomainSendingSettings": Operator,
+	"SetDomainSandbox":         Operator,
+	"PauseDomain":              Operator,
+	"ResumeDomain":             Operator,
+	"SetDomainDKIMSigning":     Operator,
+	"SetDomainDKIMHeaders":     Operator,
+	"RotateDomainDKIMKey":      Operator,
+	"PromoteDomainDKIMKey":     Operator,
+	"VerifyDomain":             Operator,
+	"ProvisionDomainDNS":       Operator,
+	"CreateTemplate":           Operator,
+	"UpdateTemplate":           Operator,
+	"RollbackTemplate":         Operator,
+	"DeleteTemplate":           Operator,
+	"AddSuppression":           Operator,
+	"RemoveSuppression":        Operator,
+	"CreateWebhook":            Operator,
+	"DeleteWebhook":            Operator,
+	"RequeueDeadLetter":        Operator,
+	"HaltSending":              Operator,
+	"ResumeSending":            Operator,
+
+	"CreateDomain":        Owner,
+	"DeleteDomain":        Owner,
+	"RestoreDomain":       Owner,
+	"RegenerateDomainKey": Owner,
+	"CreateAPIKey":        Owner,
+	"RevokeAPIKey":        Owner,
+	"RotateAPIKey":        Owner,
+}
+
+// MethodRole returns the role required by a full gRPC method of the
+// admin API, like /kannon.Api/GetDomains, unknown methods require Owner
+func MethodRole(fullMethod string) Role {
+	if r, ok := methodRoles[path.Base(fullMethod)]; ok {
+		return r
+	}
+	return Owner
+}
@@ -0,0 +1,40 @@
+package rbac
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"kannon.gyozatech.dev/generated/pb"
+)
+
+func TestIncludes(t *testing.T) {
+	assert.True(t, Owner.Includes(ReadOnly))
+	assert.True(t, Owner.Includes(Owner))
+	assert.True(t, Operator.Includes(ReadOnly))
+	assert.False(t, Operator.Includes(Owner))
+	assert.False(t, ReadOnly.Includes(Operator))
+	assert.False(t, Role("admin").Includes(ReadOnly))
+}
+
+func TestHighest(t *testing.T) {
+	r, ok := Highest([]string{"read-only", "admin", "operator"})
+	assert.True(t, ok)
+	assert.Equal(t, Operator, r)
+
+	_, ok = Highest([]string{"admin"})
+	assert.False(t, ok)
+}
+
+func TestMethodRole(t *testing.T) {
+	assert.Equal(t, ReadOnly, MethodRole("/kannon.Api/GetDomains"))
+	assert.Equal(t, Operator, MethodRole("/kannon.Api/SetDomainQuota"))
+	assert.Equal(t, Owner, MethodRole("/kannon.Api/CreateAPIKey"))
+	assert.Equal(t, Owner, MethodRole("/kannon.Api/Unknown"))
+	assert.Equal(t, ReadOnly, MethodRole("/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo"))
+
+	// every RPC has its role
+	for _, m := range pb.Api_ServiceDesc.Methods {
+		_, ok := methodRoles[m.MethodName]
+		assert.True(t, ok, m.MethodName)
+	}
+}
@@ -0,0 +1,142 @@
+package reputation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/metrics"
+	"kannon.gyozatech.dev/internal/stats"
+)
+
+var log = logging.Logger("reputation")
+
+// Windows are the rolling windows of the reputation of a domain
+var Windows = []time.Duration{24 * time.Hour, 7 * 24 * time.Hour, 30 * 24 * time.Hour}
+
+// Window is the reputation of a domain over the last Duration,
+// rates are 0 when nothing was sent
+type Window struct {
+	Duration time.Duration
+	stats.Stats
+	// BounceRate and BlockRate are the bounces and the blocks of the sent emails
+	BounceRate float64
+	BlockRate  float64
+	// ComplaintRate is the complaints of the delivered emails
+	ComplaintRate float64
+}
+
+// Config configures the pause of the domains with too many complaints
+type Config struct {
+	// MaxComplaintRate pauses the domains whose complaint rate over Window
+	// exceeds it, like 0.003, 0 never pauses domains
+	MaxComplaintRate float64
+	Window           time.Duration `default:"24h"`
+	// MinDelivered are the deliveries in Window a domain needs to be paused,
+	// the rates of few emails are not significant
+	MinDelivered int64 `default:"100"`
+}
+
+// Manager computes the reputation of domains from the stats of their emails
+type Manager interface {
+	// GetReputation returns the reputation of a domain over every window of Windows
+	GetReputation(domain string) ([]Window, error)
+	// CheckComplaints pauses a domain when its complaint rate exceeds the max of
+	// the config, returns true when the domain has been paused
+	CheckComplaints(domain string) (bool, error)
+}
+
+type manager struct {
+	db     *sqlc.Queries
+	stats  stats.Manager
+	config Config
+}
+
+// NewReputationManager builds a Reputation Manager
+func NewReputationManager(db *sql.DB, config Config) (Manager, error) {
+	sm, err := stats.NewStatsManager(db)
+	if err != nil {
+		return nil, err
+	}
+	return &manager{
+		db:     sqlc.New(metrics.InstrumentDB(db)),
+		stats:  sm,
+		config: config,
+	}, nil
+}
+
+func (m *manager) GetReputation(domain string) ([]Window, error) {
+	now := time.Now()
+	windows := make([]Window, 0, len(Windows))
+	for _, d := range Windows {
+		s, err := m.stats.GetStats(domain, now.Add(-d), now)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, newWindow(d, s))
+	}
+	return windows, nil
+}
+
+func (m *manager) CheckComplaints(domain string) (bool, error) {
+	if m.config.MaxComplaintRate == 0 {
+		return false, nil
+	}
+	d, err := m.db.FindDomain(context.TODO(), domain)
+	if errors.Is(err, sql.ErrNoRows) {
+		// deleted domains
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if d.PausedAt.Valid {
+		return false, nil
+	}
+
+	now := time.Now()
+	s, err := m.stats.GetStats(domain, now.Add(-m.config.Window), now)
+	if err != nil {
+		return false, err
+	}
+	w := newWindow(m.config.Window, s)
+	if !m.config.exceeded(w) {
+		return false, nil
+	}
+
+	reason := fmt.Sprintf("complaint rate %.2f%% over %v", w.ComplaintRate*100, m.config.Window)
+	if _, err := m.db.PauseDomain(context.TODO(), sqlc.PauseDomainParams{
+		Domain:       domain,
+		PausedReason: reason,
+	}); err != nil {
+		return false, err
+	}
+	log.Warnf("[⏸ paused] %v: %v", domain, reason)
+	return true, nil
+}
+
+// exceeded reports if the complaint rate of w exceeds the max
+func (c Config) exceeded(w Window) bool {
+	return c.MaxComplaintRate > 0 && w.Delivered >= c.MinDelivered && w.ComplaintRate > c.MaxComplaintRate
+}
+
+func newWindow(d time.Duration, s stats.Stats) Window {
+	return Window{
+		Duration:      d,
+		Stats:         s,
+		BounceRate:    rate(s.Bounced, s.Sent),
+		BlockRate:     rate(s.Blocked, s.Sent),
+		ComplaintRate: rate(s.Complained, s.Delivered),
+	}
+}
+
+func rate(n, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
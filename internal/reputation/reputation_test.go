@@ -0,0 +1,31 @@
+package reputation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"kannon.gyozatech.dev/internal/stats"
+)
+
+func TestNewWindow(t *testing.T) {
+	w := newWindow(24*time.Hour, stats.Stats{Sent: 200, Delivered: 100, Bounced: 20, Blocked: 10, Complained: 1})
+	assert.Equal(t, 0.1, w.BounceRate)
+	assert.Equal(t, 0.05, w.BlockRate)
+	assert.Equal(t, 0.01, w.ComplaintRate)
+
+	w = newWindow(24*time.Hour, stats.Stats{})
+	assert.Equal(t, 0.0, w.BounceRate)
+	assert.Equal(t, 0.0, w.ComplaintRate)
+}
+
+func TestConfigExceeded(t *testing.T) {
+	c := Config{MaxComplaintRate: 0.003, MinDelivered: 100}
+
+	assert.True(t, c.exceeded(newWindow(time.Hour, stats.Stats{Delivered: 1000, Complained: 4})))
+	assert.False(t, c.exceeded(newWindow(time.Hour, stats.Stats{Delivered: 1000, Complained: 3})))
+	// too few deliveries
+	assert.False(t, c.exceeded(newWindow(time.Hour, stats.Stats{Delivered: 50, Complained: 10})))
+	// disabled
+	assert.False(t, Config{}.exceeded(newWindow(time.Hour, stats.Stats{Delivered: 1000, Complained: 500})))
+}
@@ -0,0 +1,165 @@
+package retention
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/metrics"
+)
+
+// Purged are the rows deleted by a purge
+type Purged struct {
+	Messages          int64
+	Emails            int64
+	Attachments       int64
+	Opens             int64
+	Complaints        int64
+	Events            int64
+	Timeline          int64
+	WebhookDeliveries int64
+	APIKeyCalls       int64
+}
+
+// Add adds the rows of another purge
+func (p *Purged) Add(o Purged) {
+	p.Messages += o.Messages
+	p.Emails += o.Emails
+	p.Attachments += o.Attachments
+	p.Opens += o.Opens
+	p.Complaints += o.Complaints
+	p.Events += o.Events
+	p.Timeline += o.Timeline
+	p.WebhookDeliveries += o.WebhookDeliveries
+	p.APIKeyCalls += o.APIKeyCalls
+}
+
+// DomainPurged are the rows of a deleted domain removed at the end
+// of its grace period
+type DomainPurged struct {
+	Purged
+	Templates     int64
+	Stats         int64
+	SMTPResponses int64
+	Suppressions  int64
+	Webhooks      int64
+	DKIMKeys      int64
+	APIKeys       int64
+	DeadLetters   int64
+	DMARCReports  int64
+}
+
+// Manager purges the data of domains older than their retention
+type Manager interface {
+	Purge(domain string, before time.Time, batch uint) (Purged, error)
+	// PurgeDomain deletes a deleted domain at the end of its grace
+	// period with all its data
+	PurgeDomain(domain string, batch uint) (DomainPurged, error)
+}
+
+type manager struct {
+	db *sqlc.Queries
+}
+
+// NewRetentionManager builds a Retention Manager
+func NewRetentionManager(db *sql.DB) (Manager, error) {
+	return &manager{
+		db: sqlc.New(metrics.InstrumentDB(db)),
+	}, nil
+}
+
+// Purge deletes the messages of a domain created before a time, with their
+// pool emails, attachments, events and timelines, the completed webhook deliveries and
+// the api key calls.
+// Messages with emails still to send are kept, rows are deleted in batches
+// of batch messages
+func (m *manager) Purge(domain string, before time.Time, batch uint) (Purged, error) {
+	var res Purged
+	for {
+		rows, err := m.db.PurgeMessages(context.TODO(), sqlc.PurgeMessagesParams{
+			Domain: domain,
+			Before: before,
+			Max:    int32(batch),
+		})
+		if err != nil {
+			return res, err
+		}
+		res.Add(Purged{
+			Messages:    rows.Messages,
+			Emails:      rows.Emails,
+			Attachments: rows.Attachments,
+			Opens:       rows.Opens,
+			Complaints:  rows.Complaints,
+			Events:      rows.Events,
+			Timeline:    rows.Timeline,
+		})
+		if rows.Messages < int64(batch) {
+			break
+		}
+	}
+
+	deliveries, err := m.db.PurgeWebhookDeliveries(context.TODO(), sqlc.PurgeWebhookDeliveriesParams{
+		Domain: domain,
+		Before: before,
+	})
+	if err != nil {
+		return res, err
+	}
+	res.WebhookDeliveries = deliveries
+
+	calls, err := m.db.PurgeAPIKeyCalls(context.TODO(), sqlc.PurgeAPIKeyCallsParams{
+		Domain: domain,
+		Before: before,
+	})
+	if err != nil {
+		return res, err
+	}
+	res.APIKeyCalls = calls
+	return res, nil
+}
+
+func (m *manager) PurgeDomain(domain string, batch uint) (DomainPurged, error) {
+	// emails still being sent after the grace period are never sent
+	_, err := m.db.CancelDomainPool(context.TODO(), sqlc.CancelDomainPoolParams{
+		Domain:         domain,
+		IncludeSending: true,
+	})
+	if err != nil {
+		return DomainPurged{}, err
+	}
+
+	purged, err := m.Purge(domain, time.Now(), batch)
+	if err != nil {
+		return DomainPurged{Purged: purged}, err
+	}
+
+	rows, err := m.db.PurgeDomain(context.TODO(), domain)
+	if err != nil {
+		return DomainPurged{Purged: purged}, err
+	}
+	purged.WebhookDeliveries += rows.WebhookDeliveries
+	purged.APIKeyCalls += rows.ApiKeyCalls
+	return DomainPurged{
+		Purged:        purged,
+		Templates:     rows.Templates,
+		Stats:         rows.Stats,
+		SMTPResponses: rows.SmtpResponses,
+		Suppressions:  rows.Suppressions,
+		Webhooks:      rows.Webhooks,
+		DKIMKeys:      rows.DkimKeys,
+		APIKeys:       rows.ApiKeys,
+		DeadLetters:   rows.DeadLetters,
+		DMARCReports:  rows.DmarcReports,
+	}, nil
+}
+
+// Cutoff returns the time data older than is purged,
+// domainDays overrides defaultDays when not 0
+func Cutoff(now time.Time, domainDays int32, defaultDays uint) time.Time {
+	days := int(defaultDays)
+	if domainDays > 0 {
+		days = int(domainDays)
+	}
+	return now.AddDate(0, 0, -days)
+}
@@ -0,0 +1,20 @@
+package retention
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCutoff(t *testing.T) {
+	now := time.Date(2021, 6, 18, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2021, 3, 20, 12, 0, 0, 0, time.UTC), Cutoff(now, 0, 90))
+	assert.Equal(t, time.Date(2021, 6, 11, 12, 0, 0, 0, time.UTC), Cutoff(now, 7, 90))
+}
+
+func TestPurgedAdd(t *testing.T) {
+	p := Purged{Messages: 1, Emails: 2}
+	p.Add(Purged{Messages: 2, Events: 3, WebhookDeliveries: 1, APIKeyCalls: 4})
+	assert.Equal(t, Purged{Messages: 3, Emails: 2, Events: 3, WebhookDeliveries: 1, APIKeyCalls: 4}, p)
+}
@@ -0,0 +1,200 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"kannon.gyozatech.dev/internal/sigv4"
+)
+
+// ErrNotFound is returned for the keys not in the bucket
+var ErrNotFound = errors.New("object not found")
+
+// Config configures a bucket of S3 or MinIO, like APP_ARCHIVE_BUCKET,
+// an empty Bucket disables the features storing objects in it
+type Config struct {
+	// Endpoint is the url of the S3 API, like http://minio:9000
+	Endpoint string `default:"https://s3.amazonaws.com"`
+	Region   string `default:"us-east-1"`
+	Bucket   string
+	// Prefix is prepended to the keys of the objects, like kannon/
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is the token of temporary credentials
+	SessionToken string
+	// Timeout bounds every request to the API
+	Timeout time.Duration `default:"30s"`
+}
+
+// Enabled reports if the bucket is set
+func (c Config) Enabled() bool {
+	return c.Bucket != ""
+}
+
+// Object is an object listed in the bucket
+type Object struct {
+	Key          string
+	LastModified time.Time
+}
+
+// Bucket stores objects in the bucket of a Config,
+// keys are relative to the Prefix of the config
+type Bucket interface {
+	Put(ctx context.Context, key string, body []byte, contentType string) error
+	// Get returns the body of key, ErrNotFound when it doesn't exist
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Exists reports if key exists without reading its body
+	Exists(ctx context.Context, key string) (bool, error)
+	Delete(ctx context.Context, key string) error
+	// List calls fn with the objects whose key starts with prefix
+	List(ctx context.Context, prefix string, fn func(o Object) error) error
+}
+
+type bucket struct {
+	config Config
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewBucket builds the Bucket of config
+func NewBucket(config Config) (Bucket, error) {
+	if !config.Enabled() {
+		return nil, errors.New("s3 config without a bucket")
+	}
+	if _, err := url.Parse(config.Endpoint); err != nil {
+		return nil, fmt.Errorf("invalid s3 endpoint: %w", err)
+	}
+	return &bucket{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+		now:    time.Now,
+	}, nil
+}
+
+func (b *bucket) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	_, err := b.do(ctx, http.MethodPut, b.config.Prefix+key, nil, body, contentType)
+	return err
+}
+
+func (b *bucket) Get(ctx context.Context, key string) ([]byte, error) {
+	return b.do(ctx, http.MethodGet, b.config.Prefix+key, nil, nil, "")
+}
+
+func (b *bucket) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := b.do(ctx, http.MethodHead, b.config.Prefix+key, nil, nil, "")
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (b *bucket) Delete(ctx context.Context, key string) error {
+	_, err := b.do(ctx, http.MethodDelete, b.config.Prefix+key, nil, nil, "")
+	return err
+}
+
+func (b *bucket) List(ctx context.Context, prefix string, fn func(o Object) error) error {
+	query := url.Values{"list-type": {"2"}, "prefix": {b.config.Prefix + prefix}}
+	for {
+		var res struct {
+			Contents []struct {
+				Key          string    `xml:"Key"`
+				LastModified time.Time `xml:"LastModified"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		data, err := b.do(ctx, http.MethodGet, "", query, nil, "")
+		if err != nil {
+			return err
+		}
+		if err := xml.Unmarshal(data, &res); err != nil {
+			return err
+		}
+		for _, o := range res.Contents {
+			key := strings.TrimPrefix(o.Key, b.config.Prefix)
+			if err := fn(Object{Key: key, LastModified: o.LastModified}); err != nil {
+				return err
+			}
+		}
+		if !res.IsTruncated {
+			return nil
+		}
+		query.Set("continuation-token", res.NextContinuationToken)
+	}
+}
+
+// do sends a signed request for key of the bucket and returns the body of the response,
+// ErrNotFound when the key doesn't exist
+func (b *bucket) do(ctx context.Context, method string, key string, query url.Values, body []byte, contentType string) ([]byte, error) {
+	u, err := url.Parse(strings.TrimSuffix(b.config.Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	// keys are in the path of the bucket, supported by S3 and MinIO
+	u.Path += "/" + b.config.Bucket + "/" + key
+	u.RawPath = escapePath(u.Path)
+	u.RawQuery = strings.ReplaceAll(query.Encode(), "+", "%20")
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if b.config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", b.config.SessionToken)
+	}
+	hash := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(hash[:]))
+	sigv4.Sign(req, body, b.config.AccessKeyID, b.config.SecretAccessKey, b.config.Region, "s3", b.now())
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound && (method == http.MethodGet || method == http.MethodHead) && key != "" {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		_ = xml.Unmarshal(data, &e)
+		return nil, fmt.Errorf("s3 responded %v: %v %v", resp.Status, e.Code, e.Message)
+	}
+	return data, nil
+}
+
+// escapePath escapes path like S3 in the canonical requests,
+// every byte but the unreserved characters and / is escaped
+func escapePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,33 @@
+package s3
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExists(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodHead, r.Method)
+		if r.URL.Path != "/bucket/kannon/kannon.io/events.csv" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	b, err := NewBucket(Config{Endpoint: srv.URL, Bucket: "bucket", Prefix: "kannon/"})
+	assert.Nil(t, err)
+	ok, err := b.Exists(context.Background(), "kannon.io/events.csv")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, err = b.Exists(context.Background(), "kannon.io/other.csv")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestEscapePath(t *testing.T) {
+	assert.Equal(t, "/bucket/kannon.io/msg_1%40kannon.io/test%2B1%40test.com.eml", escapePath("/bucket/kannon.io/msg_1@kannon.io/test+1@test.com.eml"))
+}
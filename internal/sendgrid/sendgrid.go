@@ -0,0 +1,279 @@
+// Package sendgrid translates the bodies of the SendGrid v3/mail/send requests into
+// the send requests of the Mailer API, so that apps migrate by changing the base URL and the key
+package sendgrid
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"kannon.gyozatech.dev/generated/pb"
+)
+
+// Mail is the body of a v3/mail/send request, categories, custom_args, asm,
+// ip_pool_name, batch_id and tracking_settings are accepted and ignored
+type Mail struct {
+	Personalizations []Personalization `json:"personalizations"`
+	From             *Address          `json:"from"`
+	ReplyTo          *Address          `json:"reply_to"`
+	Subject          string            `json:"subject"`
+	Content          []Content         `json:"content"`
+	Attachments      []Attachment      `json:"attachments"`
+	TemplateID       string            `json:"template_id"`
+	Headers          map[string]string `json:"headers"`
+	SendAt           int64             `json:"send_at"`
+	MailSettings     MailSettings      `json:"mail_settings"`
+}
+
+// Personalization is a message of a request, with its own recipients, subject and fields
+type Personalization struct {
+	To            []Address              `json:"to"`
+	Cc            []Address              `json:"cc"`
+	Bcc           []Address              `json:"bcc"`
+	Subject       string                 `json:"subject"`
+	Headers       map[string]string      `json:"headers"`
+	Substitutions map[string]string      `json:"substitutions"`
+	TemplateData  map[string]interface{} `json:"dynamic_template_data"`
+	SendAt        int64                  `json:"send_at"`
+}
+
+// Address is an email address with an optional name
+type Address struct {
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+// Content is a body of the message, text/plain or text/html
+type Content struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Attachment is a base64 encoded file, inline attachments are referenced by the html as cid:<content_id>
+type Attachment struct {
+	Content     string `json:"content"`
+	Type        string `json:"type"`
+	Filename    string `json:"filename"`
+	Disposition string `json:"disposition"`
+	ContentID   string `json:"content_id"`
+}
+
+// MailSettings are the settings of a request, only the sandbox mode is supported
+type MailSettings struct {
+	SandboxMode struct {
+		Enable bool `json:"enable"`
+	} `json:"sandbox_mode"`
+}
+
+// Error is an invalid field of a request, returned in the errors of the response
+type Error struct {
+	Field   string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Send is the send request of a personalization, HTML without a
+// template_id and Template with it
+type Send struct {
+	HTML     *pb.SendHTMLRequest
+	Template *pb.SendTemplateRequest
+}
+
+// Sends translates m into a send for each personalization. Substitutions are replaced in the
+// subject and the bodies of the sends without a template, the dynamic_template_data are the
+// fields of the sends with a template. The sandbox mode makes the sends dry runs
+func (m Mail) Sends() ([]Send, error) {
+	if m.From == nil || m.From.Email == "" {
+		return nil, &Error{Field: "from.email", Message: "The from email is required."}
+	}
+	if len(m.Personalizations) == 0 {
+		return nil, &Error{Field: "personalizations", Message: "At least one personalization is required."}
+	}
+
+	var htmlBody, text string
+	for i, c := range m.Content {
+		switch c.Type {
+		case "text/html":
+			htmlBody = c.Value
+		case "text/plain":
+			text = c.Value
+		default:
+			return nil, &Error{Field: fmt.Sprintf("content.%v.type", i), Message: fmt.Sprintf("The content type %v is not supported.", c.Type)}
+		}
+	}
+	if m.TemplateID == "" && htmlBody == "" && text == "" {
+		return nil, &Error{Field: "content", Message: "The content is required without a template_id."}
+	}
+	if htmlBody == "" {
+		htmlBody = "<pre>" + html.EscapeString(text) + "</pre>"
+	}
+
+	attachments, err := m.attachments(&htmlBody)
+	if err != nil {
+		return nil, err
+	}
+	sender := &pb.Sender{Email: m.From.Email, Alias: m.From.Name}
+	var replyTo string
+	if m.ReplyTo != nil {
+		replyTo = m.ReplyTo.Email
+	}
+
+	sends := make([]Send, 0, len(m.Personalizations))
+	for i, p := range m.Personalizations {
+		if len(p.To) == 0 {
+			return nil, &Error{Field: fmt.Sprintf("personalizations.%v.to", i), Message: "At least one recipient is required."}
+		}
+		subject := p.Subject
+		if subject == "" {
+			subject = m.Subject
+		}
+		headers := mergeHeaders(m.Headers, p.Headers)
+		var scheduledTime *timestamppb.Timestamp
+		if sendAt := p.SendAt; sendAt != 0 || m.SendAt != 0 {
+			if sendAt == 0 {
+				sendAt = m.SendAt
+			}
+			scheduledTime = &timestamppb.Timestamp{Seconds: sendAt}
+		}
+
+		if m.TemplateID == "" {
+			r := strings.NewReplacer(replacements(p.Substitutions)...)
+			sends = append(sends, Send{HTML: &pb.SendHTMLRequest{
+				Sender:        sender,
+				To:            emails(p.To),
+				Cc:            emails(p.Cc),
+				Bcc:           emails(p.Bcc),
+				Subject:       r.Replace(subject),
+				Html:          r.Replace(htmlBody),
+				Text:          r.Replace(text),
+				Attachments:   attachments,
+				Headers:       headers,
+				ReplyTo:       replyTo,
+				ScheduledTime: scheduledTime,
+				DryRun:        m.MailSettings.SandboxMode.Enable,
+			}})
+			continue
+		}
+
+		if len(p.Substitutions) > 0 {
+			return nil, &Error{Field: fmt.Sprintf("personalizations.%v.substitutions", i), Message: "Substitutions are not supported with a template_id, use dynamic_template_data."}
+		}
+		fields, err := templateFields(p.TemplateData)
+		if err != nil {
+			return nil, &Error{Field: fmt.Sprintf("personalizations.%v.dynamic_template_data", i), Message: err.Error()}
+		}
+		sends = append(sends, Send{Template: &pb.SendTemplateRequest{
+			Sender:        sender,
+			To:            emails(p.To),
+			Cc:            emails(p.Cc),
+			Bcc:           emails(p.Bcc),
+			Subject:       subject,
+			TemplateId:    m.TemplateID,
+			Attachments:   attachments,
+			Headers:       headers,
+			ReplyTo:       replyTo,
+			Fields:        fields,
+			ScheduledTime: scheduledTime,
+			DryRun:        m.MailSettings.SandboxMode.Enable,
+		}})
+	}
+	return sends, nil
+}
+
+// attachments decodes the attachments of m, the cid: references of the
+// inline attachments in htmlBody are renamed to their filename
+func (m Mail) attachments(htmlBody *string) ([]*pb.Attachment, error) {
+	res := make([]*pb.Attachment, 0, len(m.Attachments))
+	for i, a := range m.Attachments {
+		if a.Filename == "" {
+			return nil, &Error{Field: fmt.Sprintf("attachments.%v.filename", i), Message: "The attachment filename is required."}
+		}
+		content, err := base64.StdEncoding.DecodeString(a.Content)
+		if err != nil {
+			return nil, &Error{Field: fmt.Sprintf("attachments.%v.content", i), Message: "The attachment content must be base64 encoded."}
+		}
+		inline := a.Disposition == "inline"
+		if inline && a.ContentID != "" {
+			*htmlBody = strings.ReplaceAll(*htmlBody, "cid:"+a.ContentID, "cid:"+a.Filename)
+		}
+		res = append(res, &pb.Attachment{Filename: a.Filename, Content: content, Inline: inline})
+	}
+	return res, nil
+}
+
+func emails(addresses []Address) []string {
+	res := make([]string, 0, len(addresses))
+	for _, a := range addresses {
+		res = append(res, a.Email)
+	}
+	return res
+}
+
+// mergeHeaders returns the headers of a personalization over the headers of the request
+func mergeHeaders(headers map[string]string, personalization map[string]string) map[string]string {
+	if len(headers) == 0 && len(personalization) == 0 {
+		return nil
+	}
+	res := make(map[string]string, len(headers)+len(personalization))
+	for k, v := range headers {
+		res[k] = v
+	}
+	for k, v := range personalization {
+		res[k] = v
+	}
+	return res
+}
+
+// replacements are the arguments of a strings.Replacer of the substitutions,
+// longest keys first so that -name-full- is not replaced as -name-
+func replacements(substitutions map[string]string) []string {
+	keys := make([]string, 0, len(substitutions))
+	for k := range substitutions {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	res := make([]string, 0, len(keys)*2)
+	for _, k := range keys {
+		res = append(res, k, substitutions[k])
+	}
+	return res
+}
+
+// templateFields converts the dynamic_template_data to the fields of a template,
+// numbers and booleans are formatted, objects and arrays are rejected
+func templateFields(data map[string]interface{}) (map[string]string, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	fields := make(map[string]string, len(data))
+	for k, v := range data {
+		switch v := v.(type) {
+		case string:
+			fields[k] = v
+		case float64:
+			fields[k] = strconv.FormatFloat(v, 'f', -1, 64)
+		case json.Number:
+			fields[k] = v.String()
+		case bool:
+			fields[k] = strconv.FormatBool(v)
+		case nil:
+			fields[k] = ""
+		default:
+			return nil, fmt.Errorf("the value of %v must be a string, a number or a boolean", k)
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,107 @@
+package sendgrid
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testMail = `{
+	"personalizations": [
+		{"to": [{"email": "a@test.com", "name": "A"}], "cc": [{"email": "c@test.com"}], "substitutions": {"-name-": "Alice"}},
+		{"to": [{"email": "b@test.com"}], "subject": "Ciao -name-", "substitutions": {"-name-": "Bob"}, "headers": {"X-Campaign": "b"}, "send_at": 1628762400}
+	],
+	"from": {"email": "news@kannon.io", "name": "Kannon"},
+	"reply_to": {"email": "support@kannon.io"},
+	"subject": "Hello -name-",
+	"content": [{"type": "text/plain", "value": "Hi -name-"}, {"type": "text/html", "value": "<p>Hi -name-</p><img src=\"cid:logo\">"}],
+	"attachments": [
+		{"content": "iVBORw==", "type": "image/png", "filename": "logo.png", "disposition": "inline", "content_id": "logo"},
+		{"content": "JVBERg==", "filename": "invoice.pdf"}
+	],
+	"headers": {"X-Campaign": "august", "X-App": "shop"},
+	"categories": ["news"]
+}`
+
+func parse(t *testing.T, body string) Mail {
+	var m Mail
+	assert.Nil(t, json.Unmarshal([]byte(body), &m))
+	return m
+}
+
+func TestSends(t *testing.T) {
+	sends, err := parse(t, testMail).Sends()
+	assert.Nil(t, err)
+	assert.Len(t, sends, 2)
+
+	a := sends[0].HTML
+	assert.Nil(t, sends[0].Template)
+	assert.Equal(t, "news@kannon.io", a.Sender.Email)
+	assert.Equal(t, "Kannon", a.Sender.Alias)
+	assert.Equal(t, []string{"a@test.com"}, a.To)
+	assert.Equal(t, []string{"c@test.com"}, a.Cc)
+	assert.Equal(t, "Hello Alice", a.Subject)
+	assert.Equal(t, `<p>Hi Alice</p><img src="cid:logo.png">`, a.Html)
+	assert.Equal(t, "Hi Alice", a.Text)
+	assert.Equal(t, "support@kannon.io", a.ReplyTo)
+	assert.Equal(t, map[string]string{"X-Campaign": "august", "X-App": "shop"}, a.Headers)
+	assert.Nil(t, a.ScheduledTime)
+	assert.Len(t, a.Attachments, 2)
+	assert.True(t, a.Attachments[0].Inline)
+	assert.Equal(t, []byte{0x89, 'P', 'N', 'G'}, a.Attachments[0].Content)
+	assert.False(t, a.Attachments[1].Inline)
+
+	b := sends[1].HTML
+	assert.Equal(t, "Ciao Bob", b.Subject)
+	assert.Equal(t, map[string]string{"X-Campaign": "b", "X-App": "shop"}, b.Headers)
+	assert.Equal(t, int64(1628762400), b.ScheduledTime.Seconds)
+}
+
+func TestSendsTemplate(t *testing.T) {
+	sends, err := parse(t, `{
+		"personalizations": [{"to": [{"email": "a@test.com"}], "dynamic_template_data": {"name": "Alice", "total": 12.5, "vip": true}}],
+		"from": {"email": "news@kannon.io"},
+		"template_id": "template_1@kannon.io",
+		"mail_settings": {"sandbox_mode": {"enable": true}}
+	}`).Sends()
+	assert.Nil(t, err)
+	assert.Len(t, sends, 1)
+	tmpl := sends[0].Template
+	assert.Equal(t, "template_1@kannon.io", tmpl.TemplateId)
+	assert.Equal(t, map[string]string{"name": "Alice", "total": "12.5", "vip": "true"}, tmpl.Fields)
+	assert.True(t, tmpl.DryRun)
+}
+
+func TestSendsText(t *testing.T) {
+	sends, err := parse(t, `{"personalizations": [{"to": [{"email": "a@test.com"}]}], "from": {"email": "news@kannon.io"}, "content": [{"type": "text/plain", "value": "1 < 2"}]}`).Sends()
+	assert.Nil(t, err)
+	assert.Equal(t, "<pre>1 &lt; 2</pre>", sends[0].HTML.Html)
+}
+
+func TestSendsInvalid(t *testing.T) {
+	for body, field := range map[string]string{
+		`{"personalizations": [{"to": [{"email": "a@test.com"}]}], "content": [{"type": "text/html", "value": "hi"}]}`:                                                                         "from.email",
+		`{"from": {"email": "news@kannon.io"}, "content": [{"type": "text/html", "value": "hi"}]}`:                                                                                             "personalizations",
+		`{"personalizations": [{}], "from": {"email": "news@kannon.io"}, "content": [{"type": "text/html", "value": "hi"}]}`:                                                                   "personalizations.0.to",
+		`{"personalizations": [{"to": [{"email": "a@test.com"}]}], "from": {"email": "news@kannon.io"}}`:                                                                                       "content",
+		`{"personalizations": [{"to": [{"email": "a@test.com"}]}], "from": {"email": "news@kannon.io"}, "content": [{"type": "text/amp", "value": "hi"}]}`:                                     "content.0.type",
+		`{"personalizations": [{"to": [{"email": "a@test.com"}]}], "from": {"email": "news@kannon.io"}, "content": [{"type": "text/html", "value": "hi"}], "attachments": [{"content": "!"}]}`: "attachments.0.filename",
+		`{"personalizations": [{"to": [{"email": "a@test.com"}], "dynamic_template_data": {"items": [1]}}], "from": {"email": "news@kannon.io"}, "template_id": "t"}`:                          "personalizations.0.dynamic_template_data",
+		`{"personalizations": [{"to": [{"email": "a@test.com"}], "substitutions": {"-a-": "b"}}], "from": {"email": "news@kannon.io"}, "template_id": "t"}`:                                    "personalizations.0.substitutions",
+	} {
+		_, err := parse(t, body).Sends()
+		e, ok := err.(*Error)
+		if assert.True(t, ok, body) {
+			assert.Equal(t, field, e.Field, body)
+		}
+	}
+}
+
+func TestSendsOverlappingSubstitutions(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		sends, err := parse(t, `{"personalizations": [{"to": [{"email": "a@test.com"}], "substitutions": {"-name-": "Alice", "-name-full-": "Alice Smith"}}], "from": {"email": "news@kannon.io"}, "subject": "-name-full- -name-", "content": [{"type": "text/html", "value": "hi"}]}`).Sends()
+		assert.Nil(t, err)
+		assert.Equal(t, "Alice Smith Alice", sends[0].HTML.Subject)
+	}
+}
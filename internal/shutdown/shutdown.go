@@ -0,0 +1,28 @@
+package shutdown
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"kannon.gyozatech.dev/internal/logging"
+)
+
+var log = logging.Logger("shutdown")
+
+// Context returns a context canceled when the process receives SIGINT or
+// SIGTERM, daemons stop fetching messages and finish the in-flight ones.
+// A second signal kills the process.
+func Context() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		s := <-ch
+		log.Infof("received %v, shutting down", s)
+		signal.Stop(ch)
+		cancel()
+	}()
+	return ctx
+}
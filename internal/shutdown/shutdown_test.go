@@ -0,0 +1,19 @@
+package shutdown
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestContext(t *testing.T) {
+	ctx := Context()
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Error("context not canceled")
+	}
+}
@@ -0,0 +1,76 @@
+package smtp
+
+import (
+	"regexp"
+)
+
+// BounceType classifies a delivery failure
+type BounceType string
+
+const (
+	// HardBounce is a permanent failure, the email must not be retried
+	HardBounce BounceType = "hard"
+	// SoftBounce is a transient failure, the email can be retried later
+	SoftBounce BounceType = "soft"
+)
+
+// enhancedCodeRegexp matches RFC 3463 enhanced status codes, like 5.1.1,
+// but not parts of ip addresses
+var enhancedCodeRegexp = regexp.MustCompile(`(?:^|[^\d.])([245])\.(\d{1,3})\.(\d{1,3})(?:[^\d.]|$)`)
+
+// EnhancedStatus returns the RFC 3463 enhanced status code of an smtp
+// response, like 5.1.1, empty when msg has none
+func EnhancedStatus(msg string) string {
+	if m := enhancedCodeRegexp.FindStringSubmatch(msg); m != nil {
+		return m[1] + "." + m[2] + "." + m[3]
+	}
+	return ""
+}
+
+// softPermanentCodes are permanent enhanced codes that depend on the
+// recipient mailbox or on sender reputation and can succeed later
+var softPermanentCodes = map[string]bool{
+	"2.2": true, // mailbox full
+	"3.1": true, // mail system full
+	"4.7": true, // delivery time expired
+}
+
+// ClassifyBounce classifies an smtp failure by its enhanced status code when
+// present in msg, otherwise by its basic reply code
+func ClassifyBounce(code int, msg string, permanent bool) BounceType {
+	if m := enhancedCodeRegexp.FindStringSubmatch(msg); m != nil {
+		if m[1] != "5" {
+			return SoftBounce
+		}
+		// policy rejections (5.7.x) are usually caused by sender reputation
+		if m[2] == "7" || softPermanentCodes[m[2]+"."+m[3]] {
+			return SoftBounce
+		}
+		return HardBounce
+	}
+
+	switch {
+	case code == 552:
+		// exceeded storage allocation
+		return SoftBounce
+	case code >= 400 && code < 500:
+		return SoftBounce
+	case permanent:
+		return HardBounce
+	default:
+		return SoftBounce
+	}
+}
+
+// blockRegexp matches responses mentioning blocklists or the reputation of the sender
+var blockRegexp = regexp.MustCompile(`(?i)\b(blocked|blocklist(ed)?|blacklist(ed)?|spamhaus|spamcop|barracuda|dnsbl|rbl|reputation)\b`)
+
+// IsBlock reports if an smtp failure is a block of the sender: a permanent
+// policy rejection (5.7.x) or a response mentioning a blocklist or the reputation
+// of the sender, temporary policy failures like greylisting are not blocks
+func IsBlock(msg string) bool {
+	if m := enhancedCodeRegexp.FindStringSubmatch(msg); m != nil && m[1] == "5" && m[2] == "7" {
+		return true
+	}
+	return blockRegexp.MatchString(msg)
+}
@@ -0,0 +1,64 @@
+package smtp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyBounce(t *testing.T) {
+	examples := []struct {
+		name      string
+		code      int
+		msg       string
+		permanent bool
+		exp       BounceType
+	}{
+		{"user unknown", 550, "550 5.1.1 The email account that you tried to reach does not exist", true, HardBounce},
+		{"mailbox disabled", 550, "550 5.2.1 The email account that you tried to reach is disabled", true, HardBounce},
+		{"mailbox full", 550, "550 5.2.2 The email account that you tried to reach is over quota", true, SoftBounce},
+		{"policy rejection", 550, "550 5.7.1 Message rejected due to sender reputation", true, SoftBounce},
+		{"greylisting", 451, "451 4.7.1 Greylisted, please try again later", false, SoftBounce},
+		{"no enhanced permanent", 554, "554 Transaction failed", true, HardBounce},
+		{"no enhanced storage", 552, "552 Requested mail action aborted: exceeded storage allocation", true, SoftBounce},
+		{"no enhanced transient", 421, "421 Service not available", false, SoftBounce},
+		{"ip address", 421, "421 Too many connections from 10.2.1.1", false, SoftBounce},
+		{"ip address permanent", 554, "554 Rejected 192.5.1.1 listed", true, HardBounce},
+		{"connection error", 111, "dial tcp: connection refused", false, SoftBounce},
+		{"invalid address", 510, "mta: invalid mail address", true, HardBounce},
+	}
+
+	for _, tt := range examples {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.exp, ClassifyBounce(tt.code, tt.msg, tt.permanent))
+		})
+	}
+}
+
+func TestIsBlock(t *testing.T) {
+	examples := []struct {
+		name string
+		msg  string
+		exp  bool
+	}{
+		{"policy rejection", "550 5.7.1 Message rejected due to sender reputation", true},
+		{"dmarc rejection", "550 5.7.26 Unauthenticated email is not accepted", true},
+		{"blocklist", "554 Service unavailable; Client host [192.0.2.1] blocked using zen.spamhaus.org", true},
+		{"temporary block", "421 4.7.0 IP temporarily deferred due to poor reputation", true},
+		{"greylisting", "451 4.7.1 Greylisted, please try again later", false},
+		{"user unknown", "550 5.1.1 The email account that you tried to reach does not exist", false},
+		{"connection error", "dial tcp: connection refused", false},
+	}
+
+	for _, tt := range examples {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.exp, IsBlock(tt.msg))
+		})
+	}
+}
+
+func TestEnhancedStatus(t *testing.T) {
+	assert.Equal(t, "5.1.1", EnhancedStatus("all MXs failed, last error: 550 5.1.1 user unknown"))
+	assert.Equal(t, "4.7.26", EnhancedStatus("421 4.7.26 Unauthenticated email"))
+	assert.Equal(t, "", EnhancedStatus("554 Client host [192.0.2.1] blocked"))
+}
@@ -0,0 +1,295 @@
+package smtp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// typeTLSA is the DNS type of TLSA records, RFC 6698
+const typeTLSA = dnsmessage.Type(52)
+
+// TLSA certificate usages, selectors and matching types usable for SMTP, RFC 7672
+const (
+	tlsaUsageDANETA = 2
+	tlsaUsageDANEEE = 3
+
+	tlsaSelectorCert = 0
+	tlsaSelectorSPKI = 1
+
+	tlsaMatchingFull   = 0
+	tlsaMatchingSHA256 = 1
+	tlsaMatchingSHA512 = 2
+)
+
+// tlsaRecord is a TLSA record of a MX
+type tlsaRecord struct {
+	usage    uint8
+	selector uint8
+	matching uint8
+	data     []byte
+}
+
+// usableTLSA returns the records usable for SMTP, PKIX ones are ignored
+func usableTLSA(records []tlsaRecord) []tlsaRecord {
+	var usable []tlsaRecord
+	for _, r := range records {
+		if (r.usage == tlsaUsageDANETA || r.usage == tlsaUsageDANEEE) &&
+			r.selector <= tlsaSelectorSPKI && r.matching <= tlsaMatchingSHA512 {
+			usable = append(usable, r)
+		}
+	}
+	return usable
+}
+
+// matches reports if cert is the certificate or the public key of r
+func (r tlsaRecord) matches(cert *x509.Certificate) bool {
+	data := cert.Raw
+	if r.selector == tlsaSelectorSPKI {
+		data = cert.RawSubjectPublicKeyInfo
+	}
+	switch r.matching {
+	case tlsaMatchingSHA256:
+		sum := sha256.Sum256(data)
+		data = sum[:]
+	case tlsaMatchingSHA512:
+		sum := sha512.Sum512(data)
+		data = sum[:]
+	}
+	return bytes.Equal(data, r.data)
+}
+
+// verifyDANE verifies the certificates presented by mx with its TLSA records:
+// DANE-EE records match the certificate of mx, DANE-TA records match a trust
+// anchor of the chain that must be valid for mx
+func verifyDANE(records []tlsaRecord, certs []*x509.Certificate, mx string) error {
+	if len(certs) == 0 {
+		return errors.New("no certificates")
+	}
+	for _, r := range records {
+		if r.usage == tlsaUsageDANEEE && r.matches(certs[0]) {
+			return nil
+		}
+	}
+	for _, r := range records {
+		if r.usage != tlsaUsageDANETA {
+			continue
+		}
+		for _, ta := range certs[1:] {
+			if !r.matches(ta) {
+				continue
+			}
+			roots := x509.NewCertPool()
+			roots.AddCert(ta)
+			intermediates := x509.NewCertPool()
+			for _, c := range certs[1:] {
+				intermediates.AddCert(c)
+			}
+			_, err := certs[0].Verify(x509.VerifyOptions{
+				DNSName:       strings.TrimSuffix(mx, "."),
+				Roots:         roots,
+				Intermediates: intermediates,
+			})
+			if err == nil {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("certificate of %v does not match its TLSA records", mx)
+}
+
+// lookupTLSA queries resolver for the TLSA records of the SMTP port of mx,
+// secure reports if the resolver validated the answer with DNSSEC. Records
+// of answers not validated can't be trusted and must not be used
+func lookupTLSA(resolver, mx string, timeout time.Duration) ([]tlsaRecord, bool, error) {
+	name := "_" + smtpPort + "._tcp." + strings.TrimSuffix(mx, ".") + "."
+	query, id, err := tlsaQuery(name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	res, err := exchange("udp", resolver, query, timeout)
+	if err == nil && len(res) > 2 && res[2]&0x02 != 0 {
+		// truncated, asked again over TCP
+		res, err = exchange("tcp", resolver, query, timeout)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return parseTLSAResponse(res, id)
+}
+
+// tlsaQuery builds a query of the TLSA records of name with the DNSSEC OK bit
+func tlsaQuery(name string) ([]byte, uint16, error) {
+	n, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	var rnd [2]byte
+	if _, err := rand.Read(rnd[:]); err != nil {
+		return nil, 0, err
+	}
+	id := binary.BigEndian.Uint16(rnd[:])
+
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, RecursionDesired: true})
+	if err := b.StartQuestions(); err != nil {
+		return nil, 0, err
+	}
+	if err := b.Question(dnsmessage.Question{Name: n, Type: typeTLSA, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, 0, err
+	}
+	if err := b.StartAdditionals(); err != nil {
+		return nil, 0, err
+	}
+	var opt dnsmessage.ResourceHeader
+	if err := opt.SetEDNS0(1232, dnsmessage.RCodeSuccess, true); err != nil {
+		return nil, 0, err
+	}
+	if err := b.OPTResource(opt, dnsmessage.OPTResource{}); err != nil {
+		return nil, 0, err
+	}
+	msg, err := b.Finish()
+	if err != nil {
+		return nil, 0, err
+	}
+	// AD bit, the resolver reports if the answer is validated
+	msg[3] |= 0x20
+	return msg, id, nil
+}
+
+// exchange sends query to resolver and returns the response,
+// TCP messages are prefixed by their length
+func exchange(network, resolver string, query []byte, timeout time.Duration) ([]byte, error) {
+	conn, err := net.DialTimeout(network, resolver, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+
+	if network == "udp" {
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+		res := make([]byte, 4096)
+		n, err := conn.Read(res)
+		if err != nil {
+			return nil, err
+		}
+		return res[:n], nil
+	}
+
+	msg := make([]byte, 2+len(query))
+	binary.BigEndian.PutUint16(msg, uint16(len(query)))
+	copy(msg[2:], query)
+	if _, err := conn.Write(msg); err != nil {
+		return nil, err
+	}
+	var size [2]byte
+	if _, err := io.ReadFull(conn, size[:]); err != nil {
+		return nil, err
+	}
+	res := make([]byte, binary.BigEndian.Uint16(size[:]))
+	if _, err := io.ReadFull(conn, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// errMalformedDNS is the error of responses that can't be parsed
+var errMalformedDNS = errors.New("malformed DNS response")
+
+// parseTLSAResponse returns the TLSA records of the answer to query id and
+// if the answer is authenticated, names that don't exist have no records
+func parseTLSAResponse(res []byte, id uint16) ([]tlsaRecord, bool, error) {
+	if len(res) < 12 || binary.BigEndian.Uint16(res) != id {
+		return nil, false, errMalformedDNS
+	}
+	secure := res[3]&0x20 != 0
+	switch rcode := res[3] & 0x0f; rcode {
+	case 0:
+	case 3:
+		// NXDOMAIN
+		return nil, secure, nil
+	default:
+		return nil, false, fmt.Errorf("DNS error code %v", rcode)
+	}
+
+	questions := int(binary.BigEndian.Uint16(res[4:]))
+	answers := int(binary.BigEndian.Uint16(res[6:]))
+	off := 12
+	var err error
+	for i := 0; i < questions; i++ {
+		if off, err = skipDNSName(res, off); err != nil {
+			return nil, false, err
+		}
+		// type and class
+		off += 4
+	}
+
+	var records []tlsaRecord
+	for i := 0; i < answers; i++ {
+		if off, err = skipDNSName(res, off); err != nil {
+			return nil, false, err
+		}
+		// type, class, ttl and length of data
+		if off+10 > len(res) {
+			return nil, false, errMalformedDNS
+		}
+		typ := dnsmessage.Type(binary.BigEndian.Uint16(res[off:]))
+		size := int(binary.BigEndian.Uint16(res[off+8:]))
+		off += 10
+		if off+size > len(res) {
+			return nil, false, errMalformedDNS
+		}
+		data := res[off : off+size]
+		off += size
+		// answers can have CNAMEs and their signatures
+		if typ != typeTLSA || size < 3 {
+			continue
+		}
+		records = append(records, tlsaRecord{
+			usage:    data[0],
+			selector: data[1],
+			matching: data[2],
+			data:     append([]byte(nil), data[3:]...),
+		})
+	}
+	return records, secure, nil
+}
+
+// skipDNSName returns the offset after the name at off in msg
+func skipDNSName(msg []byte, off int) (int, error) {
+	for {
+		if off >= len(msg) {
+			return 0, errMalformedDNS
+		}
+		c := int(msg[off])
+		switch c & 0xC0 {
+		case 0x00:
+			off++
+			if c == 0 {
+				return off, nil
+			}
+			off += c
+		case 0xC0:
+			// pointers end the name
+			return off + 2, nil
+		default:
+			return 0, errMalformedDNS
+		}
+	}
+}
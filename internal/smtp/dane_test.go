@@ -0,0 +1,107 @@
+package smtp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testCert returns a certificate for name signed by parent,
+// self-signed when parent is nil
+func testCert(t *testing.T, name string, ca bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  ca,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	return cert, key
+}
+
+func TestVerifyDANE(t *testing.T) {
+	ca, caKey := testCert(t, "ca.example.com", true, nil, nil)
+	leaf, _ := testCert(t, "mx.example.com", false, ca, caKey)
+	other, _ := testCert(t, "mx.example.com", false, ca, caKey)
+	spki := sha256.Sum256(leaf.RawSubjectPublicKeyInfo)
+	chain := []*x509.Certificate{leaf, ca}
+
+	ee := []tlsaRecord{{usage: tlsaUsageDANEEE, selector: tlsaSelectorSPKI, matching: tlsaMatchingSHA256, data: spki[:]}}
+	assert.Nil(t, verifyDANE(ee, chain, "mx.example.com."))
+	assert.NotNil(t, verifyDANE(ee, []*x509.Certificate{other, ca}, "mx.example.com."))
+
+	ta := []tlsaRecord{{usage: tlsaUsageDANETA, selector: tlsaSelectorCert, matching: tlsaMatchingFull, data: ca.Raw}}
+	assert.Nil(t, verifyDANE(ta, chain, "mx.example.com."))
+	// DANE-TA checks the name of the MX
+	assert.NotNil(t, verifyDANE(ta, chain, "other.example.com."))
+	assert.NotNil(t, verifyDANE(ta, nil, "mx.example.com."))
+}
+
+func TestUsableTLSA(t *testing.T) {
+	records := []tlsaRecord{
+		{usage: 1, selector: 0, matching: 1},
+		{usage: 3, selector: 1, matching: 1},
+		{usage: 2, selector: 0, matching: 3},
+	}
+	assert.Equal(t, records[1:2], usableTLSA(records))
+}
+
+func TestParseTLSAResponse(t *testing.T) {
+	query, id, err := tlsaQuery("_25._tcp.mx.example.com.")
+	assert.Nil(t, err)
+	assert.Equal(t, id, binary.BigEndian.Uint16(query))
+
+	// the response repeats the question and answers with a TLSA
+	// record whose name is a pointer to the question
+	res := append([]byte(nil), query...)
+	res[2] |= 0x80
+	res[3] = 0x20
+	// one question, one answer, no additionals
+	binary.BigEndian.PutUint16(res[6:], 1)
+	binary.BigEndian.PutUint16(res[10:], 0)
+	res = res[:12+len("_25._tcp.mx.example.com.")+1+4]
+	res = append(res, 0xC0, 12, 0, 52, 0, 1, 0, 0, 0, 60, 0, 5, 3, 1, 1, 0xAB, 0xCD)
+
+	records, secure, err := parseTLSAResponse(res, id)
+	assert.Nil(t, err)
+	assert.True(t, secure)
+	assert.Equal(t, []tlsaRecord{{usage: 3, selector: 1, matching: 1, data: []byte{0xAB, 0xCD}}}, records)
+
+	_, _, err = parseTLSAResponse(res, id+1)
+	assert.NotNil(t, err)
+	_, _, err = parseTLSAResponse(res[:len(res)-1], id)
+	assert.NotNil(t, err)
+
+	// not validated
+	res[3] = 0
+	_, secure, err = parseTLSAResponse(res, id)
+	assert.Nil(t, err)
+	assert.False(t, secure)
+
+	// SERVFAIL
+	res[3] = 2
+	_, _, err = parseTLSAResponse(res, id)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,205 @@
+package smtp
+
+import (
+	"fmt"
+	"net"
+	"net/textproto"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Checks of the diagnostics of a sender
+const (
+	CheckRDNS   = "rdns"
+	CheckHELO   = "helo"
+	CheckPort25 = "port25"
+)
+
+// Diagnostic is the result of a check of the setup of a sender
+type Diagnostic struct {
+	Check string
+	// Target is the IP, hostname or MX checked
+	Target string
+	// Err is why the check failed, nil when it passed
+	Err error
+}
+
+// resolvers of the diagnostics, replaced by tests
+var (
+	lookupAddr = net.LookupAddr
+	lookupHost = net.LookupHost
+)
+
+// privateNets are the networks of addresses without a public reverse DNS
+var privateNets = parseNets("10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "fc00::/7")
+
+// Diagnose checks the setup of a sender of hostname configured by config, misconfigured
+// hosts have their deliveries rejected or marked as spam:
+//
+//   - rdns: the outbound IPs have a PTR record resolving back to the IP
+//   - helo: hostname resolves
+//   - port25: mx, like gmail-smtp-in.l.google.com:25, answers from every outbound IP
+//
+// Outbound IPs are the IPs of the pools, or the address chosen by the system to reach mx.
+// Connections through proxies and to a relay are only checked for port25
+func Diagnose(hostname string, config Config, mx string) []Diagnostic {
+	timeout := config.Timeouts.Dial
+	if config.Relay != "" {
+		return []Diagnostic{{CheckPort25, config.Relay, checkBanner(config.Relay, nil, nil, timeout)}}
+	}
+
+	var res []Diagnostic
+	pools := config.IPPools
+	if len(pools[DefaultIPPool]) == 0 {
+		// the default pool is used by the emails without a pool
+		pools = IPPools{DefaultIPPool: nil}
+		for name, ips := range config.IPPools {
+			pools[name] = ips
+		}
+	}
+	names := make([]string, 0, len(pools))
+	for name := range pools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var ips []net.IP
+	dialed := make(map[string]bool)
+	for _, name := range names {
+		proxy := config.Proxies.forPool(name)
+		sources := pools[name]
+		if len(sources) == 0 {
+			// the system chooses the source address
+			sources = []net.IP{nil}
+		}
+		for _, ip := range sources {
+			target := dialTarget(mx, ip, proxy)
+			if dialed[target] {
+				continue
+			}
+			dialed[target] = true
+			res = append(res, Diagnostic{CheckPort25, target, checkBanner(mx, ip, proxy, timeout)})
+			if proxy != nil {
+				continue
+			}
+			if ip == nil {
+				ip = outboundIP(mx)
+			}
+			if ip != nil {
+				ips = append(ips, ip)
+			}
+		}
+	}
+
+	checked := make(map[string]bool)
+	for _, ip := range ips {
+		if checked[ip.String()] || isPrivate(ip) {
+			// the public address of hosts behind NAT is not known
+			continue
+		}
+		checked[ip.String()] = true
+		res = append(res, Diagnostic{CheckRDNS, ip.String(), checkRDNS(ip)})
+	}
+	res = append(res, Diagnostic{CheckHELO, hostname, checkHELO(hostname)})
+	return res
+}
+
+// checkRDNS checks that ip has a PTR record resolving back to ip,
+// required by most receivers
+func checkRDNS(ip net.IP) error {
+	names, err := lookupAddr(ip.String())
+	if err != nil || len(names) == 0 {
+		return fmt.Errorf("no PTR record of %v", ip)
+	}
+	for _, name := range names {
+		addrs, err := lookupHost(strings.TrimSuffix(name, "."))
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if net.ParseIP(addr).Equal(ip) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("PTR records %v of %v don't resolve to %v", strings.Join(names, ", "), ip, ip)
+}
+
+// checkHELO checks that the hostname presented in the HELO resolves
+func checkHELO(hostname string) error {
+	addrs, err := lookupHost(hostname)
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("HELO hostname %v doesn't resolve", hostname)
+	}
+	return nil
+}
+
+// checkBanner connects to mx from ip through proxy and reads its 220 greeting
+func checkBanner(mx string, ip net.IP, proxy *url.URL, timeout time.Duration) error {
+	host, port := mxAddr(mx)
+	conn, err := dial(net.JoinHostPort(host, port), ip, proxy, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+	if _, _, err := textproto.NewConn(conn).ReadResponse(220); err != nil {
+		return fmt.Errorf("unexpected greeting of %v: %w", mx, err)
+	}
+	return nil
+}
+
+// outboundIP returns the source address chosen by the system to reach mx,
+// nil when mx doesn't resolve
+func outboundIP(mx string) net.IP {
+	host, port := mxAddr(mx)
+	// no packet is sent by connecting a UDP socket
+	conn, err := net.Dial("udp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+	if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+		return addr.IP
+	}
+	return nil
+}
+
+func dialTarget(mx string, ip net.IP, proxy *url.URL) string {
+	target := mx
+	if ip != nil {
+		target += " from " + ip.String()
+	}
+	if proxy != nil {
+		target += " via " + proxy.Host
+	}
+	return target
+}
+
+func isPrivate(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+		return true
+	}
+	for _, n := range privateNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseNets(cidrs ...string) []*net.IPNet {
+	res := make([]*net.IPNet, len(cidrs))
+	for i, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			panic(err)
+		}
+		res[i] = n
+	}
+	return res
+}
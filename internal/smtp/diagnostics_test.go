@@ -0,0 +1,105 @@
+package smtp
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDNS replaces the resolvers of the diagnostics with ptrs and hosts
+func fakeDNS(t *testing.T, ptrs map[string][]string, hosts map[string][]string) {
+	addr, host := lookupAddr, lookupHost
+	t.Cleanup(func() { lookupAddr, lookupHost = addr, host })
+	lookupAddr = func(ip string) ([]string, error) {
+		if names, ok := ptrs[ip]; ok {
+			return names, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	lookupHost = func(name string) ([]string, error) {
+		if addrs, ok := hosts[name]; ok {
+			return addrs, nil
+		}
+		return nil, errors.New("no such host")
+	}
+}
+
+// fakeMX starts a fake SMTP server on localhost and returns its address
+func fakeMX(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveFake(conn, nil)
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestCheckRDNS(t *testing.T) {
+	fakeDNS(t, map[string][]string{
+		"192.0.2.1": {"mail.kannon.test."},
+		"192.0.2.2": {"other.kannon.test."},
+	}, map[string][]string{
+		"mail.kannon.test":  {"192.0.2.1"},
+		"other.kannon.test": {"192.0.2.9"},
+	})
+
+	assert.Nil(t, checkRDNS(net.ParseIP("192.0.2.1")))
+	assert.EqualError(t, checkRDNS(net.ParseIP("192.0.2.2")), "PTR records other.kannon.test. of 192.0.2.2 don't resolve to 192.0.2.2")
+	assert.EqualError(t, checkRDNS(net.ParseIP("192.0.2.3")), "no PTR record of 192.0.2.3")
+}
+
+func TestCheckHELO(t *testing.T) {
+	fakeDNS(t, nil, map[string][]string{"mail.kannon.test": {"192.0.2.1"}})
+
+	assert.Nil(t, checkHELO("mail.kannon.test"))
+	assert.EqualError(t, checkHELO("sender.kannon.test"), "HELO hostname sender.kannon.test doesn't resolve")
+}
+
+func TestCheckBanner(t *testing.T) {
+	mx := fakeMX(t)
+	assert.Nil(t, checkBanner(mx, net.ParseIP("127.0.0.1"), nil, time.Second))
+
+	// a closed port
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	l.Close()
+	assert.NotNil(t, checkBanner(l.Addr().String(), nil, nil, time.Second))
+}
+
+func TestDiagnose(t *testing.T) {
+	fakeDNS(t, nil, map[string][]string{"mail.kannon.test": {"192.0.2.1"}})
+	mx := fakeMX(t)
+
+	// loopback IPs have no public reverse DNS
+	pools := IPPools{DefaultIPPool: {net.ParseIP("127.0.0.1")}, "bulk": {net.ParseIP("127.0.0.1")}}
+	res := Diagnose("mail.kannon.test", Config{Timeouts: DefaultTimeouts, IPPools: pools}, mx)
+	assert.Equal(t, []Diagnostic{
+		{CheckPort25, mx + " from 127.0.0.1", nil},
+		{CheckHELO, "mail.kannon.test", nil},
+	}, res)
+
+	// with a relay only the relay is checked
+	res = Diagnose("sender.kannon.test", Config{Timeouts: DefaultTimeouts, Relay: mx}, "gmail-smtp-in.l.google.com:25")
+	assert.Equal(t, []Diagnostic{{CheckPort25, mx, nil}}, res)
+}
+
+func TestIsPrivate(t *testing.T) {
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "172.20.0.1", "192.168.1.1", "100.64.0.1", "fd00::1", "fe80::1", "::1"} {
+		assert.True(t, isPrivate(net.ParseIP(ip)), ip)
+	}
+	for _, ip := range []string{"192.0.2.1", "8.8.8.8", "2001:db8::1"} {
+		assert.False(t, isPrivate(net.ParseIP(ip)), ip)
+	}
+}
@@ -0,0 +1,99 @@
+package smtp
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+)
+
+// DefaultIPPool is the pool of emails without a pool or with an unknown one
+const DefaultIPPool = "default"
+
+// IPPools are the source IPs of the sender by pool name,
+// deliveries of a pool rotate its IPs
+type IPPools map[string][]net.IP
+
+// ParseIPPools parses pools like default=192.0.2.1|192.0.2.2,bulk=192.0.2.3
+func ParseIPPools(s string) (IPPools, error) {
+	pools := make(IPPools)
+	if s == "" {
+		return pools, nil
+	}
+	for _, p := range strings.Split(s, ",") {
+		parts := strings.SplitN(strings.TrimSpace(p), "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid ip pool: %v", p)
+		}
+		for _, addr := range strings.Split(parts[1], "|") {
+			ip := net.ParseIP(strings.TrimSpace(addr))
+			if ip == nil {
+				return nil, fmt.Errorf("invalid ip %v of pool %v", addr, parts[0])
+			}
+			pools[parts[0]] = append(pools[parts[0]], ip)
+		}
+	}
+	return pools, nil
+}
+
+// ipSelector picks the source IPs of the deliveries of every pool
+type ipSelector struct {
+	pools map[string]*familyIPs
+}
+
+// familyIPs are the IPv4 and IPv6 addresses of a pool
+type familyIPs struct {
+	v4, v6 []net.IP
+	n4, n6 uint32
+}
+
+func newIPSelector(pools IPPools) *ipSelector {
+	s := &ipSelector{pools: make(map[string]*familyIPs, len(pools))}
+	for name, ips := range pools {
+		f := &familyIPs{}
+		for _, ip := range ips {
+			if ip.To4() != nil {
+				f.v4 = append(f.v4, ip)
+			} else {
+				f.v6 = append(f.v6, ip)
+			}
+		}
+		s.pools[name] = f
+	}
+	return s
+}
+
+// pick returns the next IP of a pool of the IPv6 or IPv4 family, nil when
+// the pool has no IPs and the system chooses the source address. It returns
+// false when the pool has IPs but none of the family
+func (s *ipSelector) pick(pool string, v6 bool) (net.IP, bool) {
+	f, ok := s.pools[pool]
+	if !ok {
+		f, ok = s.pools[DefaultIPPool]
+	}
+	if !ok || len(f.v4)+len(f.v6) == 0 {
+		return nil, true
+	}
+	ips, next := f.v4, &f.n4
+	if v6 {
+		ips, next = f.v6, &f.n6
+	}
+	if len(ips) == 0 {
+		return nil, false
+	}
+	n := atomic.AddUint32(next, 1)
+	return ips[(n-1)%uint32(len(ips))], true
+}
+
+// ipLabels are the metrics labels of the address and the family of a
+// source IP, nil when the connection failed or the address is unknown
+func ipLabels(ip net.IP) (string, string) {
+	switch {
+	case ip == nil:
+		return "unknown", "unknown"
+	case ip.To4() != nil:
+		return ip.String(), "ipv4"
+	default:
+		return ip.String(), "ipv6"
+	}
+}
@@ -0,0 +1,53 @@
+package smtp
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIPPools(t *testing.T) {
+	pools, err := ParseIPPools("default=192.0.2.1|192.0.2.2, bulk=2001:db8::1")
+	assert.Nil(t, err)
+	assert.Equal(t, IPPools{
+		"default": {net.ParseIP("192.0.2.1"), net.ParseIP("192.0.2.2")},
+		"bulk":    {net.ParseIP("2001:db8::1")},
+	}, pools)
+
+	pools, err = ParseIPPools("")
+	assert.Nil(t, err)
+	assert.Empty(t, pools)
+
+	_, err = ParseIPPools("default=192.0.2")
+	assert.NotNil(t, err)
+	_, err = ParseIPPools("192.0.2.1")
+	assert.NotNil(t, err)
+}
+
+func TestIPSelector(t *testing.T) {
+	s := newIPSelector(IPPools{
+		"default": {net.ParseIP("192.0.2.1"), net.ParseIP("192.0.2.2"), net.ParseIP("2001:db8::1")},
+		"bulk":    {net.ParseIP("192.0.2.3")},
+	})
+	pick := func(pool string, v6 bool) string {
+		ip, ok := s.pick(pool, v6)
+		if !ok {
+			return "none"
+		}
+		return ip.String()
+	}
+	assert.Equal(t, "192.0.2.1", pick("default", false))
+	assert.Equal(t, "192.0.2.2", pick("default", false))
+	assert.Equal(t, "192.0.2.1", pick("default", false))
+	assert.Equal(t, "2001:db8::1", pick("default", true))
+	assert.Equal(t, "192.0.2.3", pick("bulk", false))
+	// the pool has no IPv6 address
+	assert.Equal(t, "none", pick("bulk", true))
+	// unknown pools use the default pool
+	assert.Equal(t, "192.0.2.2", pick("unknown", false))
+
+	ip, ok := newIPSelector(IPPools{}).pick("default", true)
+	assert.Nil(t, ip)
+	assert.True(t, ok)
+}
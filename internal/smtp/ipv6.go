@@ -0,0 +1,82 @@
+package smtp
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// maxAddrsPerFamily is the max number of addresses of a MX
+// tried for every IP family, to keep delivery attempt times sane
+const maxAddrsPerFamily = 2
+
+// dialMX connects to the A and AAAA addresses of mx on r, on port 25 or the
+// port of a relay. IPv6 addresses are tried first when r can use IPv6 and
+// IPv4 ones when they fail. Connections through a proxy are resolved by the proxy
+func dialMX(mx string, r route, timeout time.Duration) (net.Conn, error) {
+	host, port := mxAddr(mx)
+	if r.proxy != nil {
+		ip := r.ip4
+		if !r.v4 {
+			ip = r.ip6
+		}
+		return dial(net.JoinHostPort(host, port), ip, r.proxy, timeout)
+	}
+
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return nil, err
+	}
+	addrs := orderAddrs(ips, r)
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no address of %v usable by the sender", mx)
+	}
+
+	var lastErr error
+	for _, addr := range addrs {
+		src := r.ip4
+		if addr.To4() == nil {
+			src = r.ip6
+		}
+		conn, err := dial(net.JoinHostPort(addr.String(), port), src, nil, timeout)
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// orderAddrs returns the addresses of a MX usable on r, IPv6 first
+func orderAddrs(ips []net.IP, r route) []net.IP {
+	var v4, v6 []net.IP
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			if r.v4 && len(v4) < maxAddrsPerFamily {
+				v4 = append(v4, ip)
+			}
+		} else if r.v6 && len(v6) < maxAddrsPerFamily {
+			v6 = append(v6, ip)
+		}
+	}
+	return append(v6, v4...)
+}
+
+// localIP is the source IP of conn, nil when it is not a TCP connection
+func localIP(conn net.Conn) net.IP {
+	if addr, ok := conn.LocalAddr().(*net.TCPAddr); ok {
+		return addr.IP
+	}
+	return nil
+}
+
+// sourceIP is the IP the MX sees the connection conn on r from: the egress IP
+// of the proxy of r, or the source IP of conn without a proxy or an egress IP
+func sourceIP(conn net.Conn, r route) net.IP {
+	if r.proxy != nil {
+		if ip := egressIP(r.proxy); ip != nil {
+			return ip
+		}
+	}
+	return localIP(conn)
+}
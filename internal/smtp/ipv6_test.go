@@ -0,0 +1,57 @@
+package smtp
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderAddrs(t *testing.T) {
+	ips := []net.IP{
+		net.ParseIP("192.0.2.1"),
+		net.ParseIP("2001:db8::1"),
+		net.ParseIP("192.0.2.2"),
+		net.ParseIP("192.0.2.3"),
+		net.ParseIP("2001:db8::2"),
+	}
+	str := func(addrs []net.IP) []string {
+		s := []string{}
+		for _, a := range addrs {
+			s = append(s, a.String())
+		}
+		return s
+	}
+
+	assert.Equal(t, []string{"2001:db8::1", "2001:db8::2", "192.0.2.1", "192.0.2.2"}, str(orderAddrs(ips, route{v4: true, v6: true})))
+	assert.Equal(t, []string{"192.0.2.1", "192.0.2.2"}, str(orderAddrs(ips, route{v4: true})))
+	assert.Equal(t, []string{"2001:db8::1", "2001:db8::2"}, str(orderAddrs(ips, route{v6: true})))
+	assert.Equal(t, []string{}, str(orderAddrs(ips, route{})))
+}
+
+func TestLocalIP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	assert.Equal(t, "127.0.0.1", localIP(conn).String())
+
+	// behind a proxy the MX sees the egress IP of the proxy
+	proxyURL, err := ParseProxy("socks5://bastion:1080?egress_ip=203.0.113.5")
+	assert.Nil(t, err)
+	assert.Equal(t, "203.0.113.5", sourceIP(conn, route{proxy: proxyURL}).String())
+	proxyURL, _ = ParseProxy("socks5://bastion:1080")
+	assert.Equal(t, "127.0.0.1", sourceIP(conn, route{proxy: proxyURL}).String())
+	assert.Equal(t, "127.0.0.1", sourceIP(conn, route{}).String())
+
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+	assert.Nil(t, localIP(c1))
+}
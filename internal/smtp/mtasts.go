@@ -0,0 +1,209 @@
+package smtp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MTA-STS policy modes, RFC 8461
+const (
+	stsModeEnforce = "enforce"
+	stsModeTesting = "testing"
+	stsModeNone    = "none"
+)
+
+const (
+	// stsMaxAge caps the max_age of policies to a year, like RFC 8461
+	stsMaxAge = 31557600 * time.Second
+	// stsMaxPolicySize is the max size of a policy file
+	stsMaxPolicySize = 64 * 1024
+	// stsFetchTimeout is the max time to fetch a policy
+	stsFetchTimeout = time.Minute
+)
+
+// stsPolicy is the MTA-STS policy of a domain
+type stsPolicy struct {
+	// id is the id of the _mta-sts TXT record of the policy
+	id      string
+	mode    string
+	mxs     []string
+	expires time.Time
+}
+
+// matches reports if mx is one of the MXs of the policy,
+// patterns like *.example.com match a single label
+func (p *stsPolicy) matches(mx string) bool {
+	mx = strings.ToLower(strings.TrimSuffix(mx, "."))
+	for _, pattern := range p.mxs {
+		if strings.HasPrefix(pattern, "*.") {
+			i := strings.Index(mx, ".")
+			if i > 0 && mx[i+1:] == pattern[2:] {
+				return true
+			}
+		} else if mx == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSTSPolicy parses a policy file like
+// version: STSv1, mode: enforce, mx: *.example.com, max_age: 86400
+func parseSTSPolicy(r io.Reader) (*stsPolicy, error) {
+	p := &stsPolicy{}
+	var version string
+	maxAge := -1
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(parts[1])
+		switch strings.TrimSpace(parts[0]) {
+		case "version":
+			version = value
+		case "mode":
+			p.mode = value
+		case "mx":
+			p.mxs = append(p.mxs, strings.ToLower(strings.TrimSuffix(value, ".")))
+		case "max_age":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid max_age: %v", value)
+			}
+			maxAge = n
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if version != "STSv1" {
+		return nil, fmt.Errorf("invalid version: %v", version)
+	}
+	switch p.mode {
+	case stsModeEnforce, stsModeTesting, stsModeNone:
+	default:
+		return nil, fmt.Errorf("invalid mode: %v", p.mode)
+	}
+	if maxAge < 0 {
+		return nil, fmt.Errorf("missing max_age")
+	}
+	if p.mode != stsModeNone && len(p.mxs) == 0 {
+		return nil, fmt.Errorf("missing mx")
+	}
+	age := time.Duration(maxAge) * time.Second
+	if age > stsMaxAge {
+		age = stsMaxAge
+	}
+	p.expires = time.Now().Add(age)
+	return p, nil
+}
+
+// mtaSTS fetches and caches the MTA-STS policies of recipient domains
+type mtaSTS struct {
+	client   *http.Client
+	mu       sync.Mutex
+	policies map[string]*stsPolicy
+	// policyURL is the address of the policy of a domain
+	policyURL func(domain string) string
+}
+
+func newMTASTS() *mtaSTS {
+	return &mtaSTS{
+		client: &http.Client{
+			Timeout: stsFetchTimeout,
+			// policies are not fetched through redirects
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		policies: make(map[string]*stsPolicy),
+		policyURL: func(domain string) string {
+			return "https://mta-sts." + domain + "/.well-known/mta-sts.txt"
+		},
+	}
+}
+
+// policy returns the policy of domain, nil when domain has no policy. A
+// cached policy is used until it expires unless its TXT record changes,
+// then it's fetched again
+func (m *mtaSTS) policy(domain string) *stsPolicy {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	id := lookupSTSRecord(domain)
+
+	m.mu.Lock()
+	cached, ok := m.policies[domain]
+	m.mu.Unlock()
+	if ok && time.Now().After(cached.expires) {
+		cached, ok = nil, false
+	}
+	if ok && (id == "" || id == cached.id) {
+		return cached
+	}
+	if id == "" {
+		return nil
+	}
+
+	p, err := m.fetch(domain)
+	if err != nil {
+		log.Warnf("cannot fetch MTA-STS policy of %v: %v", domain, err)
+		// the cached policy is used until it expires
+		return cached
+	}
+	p.id = id
+	m.mu.Lock()
+	m.policies[domain] = p
+	m.mu.Unlock()
+	return p
+}
+
+func (m *mtaSTS) fetch(domain string) (*stsPolicy, error) {
+	res, err := m.client.Get(m.policyURL(domain))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %v", res.Status)
+	}
+	return parseSTSPolicy(io.LimitReader(res.Body, stsMaxPolicySize))
+}
+
+// lookupSTSRecord returns the id of the _mta-sts TXT record
+// of domain, empty when domain has no valid record
+func lookupSTSRecord(domain string) string {
+	txts, err := net.LookupTXT("_mta-sts." + domain)
+	if err != nil {
+		return ""
+	}
+	for _, txt := range txts {
+		if id, ok := parseSTSRecord(txt); ok {
+			return id
+		}
+	}
+	return ""
+}
+
+// parseSTSRecord parses the id of a record like v=STSv1; id=20210701
+func parseSTSRecord(txt string) (string, bool) {
+	fields := strings.Split(txt, ";")
+	if strings.TrimSpace(fields[0]) != "v=STSv1" {
+		return "", false
+	}
+	for _, f := range fields[1:] {
+		parts := strings.SplitN(strings.TrimSpace(f), "=", 2)
+		if len(parts) == 2 && parts[0] == "id" && parts[1] != "" {
+			return parts[1], true
+		}
+	}
+	return "", false
+}
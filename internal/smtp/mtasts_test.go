@@ -0,0 +1,87 @@
+package smtp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSTSPolicy = "version: STSv1\r\nmode: enforce\r\nmx: mx1.example.com\r\nmx: *.mx.example.com\r\nmax_age: 86400\r\n"
+
+func TestParseSTSPolicy(t *testing.T) {
+	p, err := parseSTSPolicy(strings.NewReader(testSTSPolicy))
+	assert.Nil(t, err)
+	assert.Equal(t, stsModeEnforce, p.mode)
+	assert.Equal(t, []string{"mx1.example.com", "*.mx.example.com"}, p.mxs)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), p.expires, time.Minute)
+
+	for _, invalid := range []string{
+		"version: STSv2\nmode: enforce\nmx: mx.example.com\nmax_age: 1",
+		"version: STSv1\nmode: strict\nmx: mx.example.com\nmax_age: 1",
+		"version: STSv1\nmode: enforce\nmax_age: 1",
+		"version: STSv1\nmode: enforce\nmx: mx.example.com",
+	} {
+		_, err := parseSTSPolicy(strings.NewReader(invalid))
+		assert.NotNil(t, err, invalid)
+	}
+
+	// max_age is capped to a year
+	p, err = parseSTSPolicy(strings.NewReader("version: STSv1\nmode: none\nmax_age: 999999999"))
+	assert.Nil(t, err)
+	assert.WithinDuration(t, time.Now().Add(stsMaxAge), p.expires, time.Minute)
+}
+
+func TestSTSPolicyMatches(t *testing.T) {
+	p, err := parseSTSPolicy(strings.NewReader(testSTSPolicy))
+	assert.Nil(t, err)
+	assert.True(t, p.matches("mx1.example.com."))
+	assert.True(t, p.matches("A.MX.example.com"))
+	assert.False(t, p.matches("mx2.example.com"))
+	assert.False(t, p.matches("a.b.mx.example.com"))
+	assert.False(t, p.matches("mx.example.com"))
+}
+
+func TestParseSTSRecord(t *testing.T) {
+	id, ok := parseSTSRecord("v=STSv1; id=20210701T000000;")
+	assert.True(t, ok)
+	assert.Equal(t, "20210701T000000", id)
+
+	_, ok = parseSTSRecord("v=spf1 -all")
+	assert.False(t, ok)
+	_, ok = parseSTSRecord("v=STSv1;")
+	assert.False(t, ok)
+}
+
+func TestMTASTSFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/example.com":
+			fmt.Fprint(w, testSTSPolicy)
+		case "/redirect.example.com":
+			http.Redirect(w, r, "/example.com", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	m := newMTASTS()
+	m.policyURL = func(domain string) string {
+		return srv.URL + "/" + domain
+	}
+
+	p, err := m.fetch("example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, stsModeEnforce, p.mode)
+
+	_, err = m.fetch("missing.example.com")
+	assert.NotNil(t, err)
+	// redirects are not followed
+	_, err = m.fetch("redirect.example.com")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,117 @@
+package smtp
+
+import (
+	"net"
+	"net/smtp"
+	"sync"
+	"time"
+)
+
+// PoolConfig configures the reuse of SMTP connections to the same MX
+type PoolConfig struct {
+	// IdleTimeout is the max time a connection stays open without
+	// deliveries, 0 disables the reuse of connections
+	IdleTimeout time.Duration
+	// MaxIdle is the max number of idle connections to a MX
+	MaxIdle int
+}
+
+// pooledConn is a connection to a MX after the hello and STARTTLS
+type pooledConn struct {
+	conn     net.Conn
+	c        *smtp.Client
+	lastUsed time.Time
+}
+
+// quitTimeout is the max wait for the answer to QUIT of a closed connection
+const quitTimeout = 5 * time.Second
+
+func (p *pooledConn) close() {
+	if err := p.conn.SetDeadline(time.Now().Add(quitTimeout)); err == nil {
+		_ = p.c.Quit()
+	}
+	p.conn.Close()
+}
+
+// connPool keeps the idle connections of every MX
+type connPool struct {
+	config PoolConfig
+	mu     sync.Mutex
+	idle   map[string][]*pooledConn
+}
+
+func newConnPool(config PoolConfig) *connPool {
+	p := &connPool{
+		config: config,
+		idle:   make(map[string][]*pooledConn),
+	}
+	if config.IdleTimeout > 0 {
+		go p.closeExpired(config.IdleTimeout)
+	}
+	return p
+}
+
+// get returns the most recently used idle connection to mx that is still
+// alive with deadline set, nil when there are no connections
+func (p *connPool) get(mx string, deadline time.Time) *pooledConn {
+	for {
+		p.mu.Lock()
+		conns := p.idle[mx]
+		if len(conns) == 0 {
+			p.mu.Unlock()
+			return nil
+		}
+		conn := conns[len(conns)-1]
+		p.idle[mx] = conns[:len(conns)-1]
+		p.mu.Unlock()
+
+		if time.Since(conn.lastUsed) < p.config.IdleTimeout &&
+			conn.conn.SetDeadline(deadline) == nil && conn.c.Noop() == nil {
+			return conn
+		}
+		conn.close()
+	}
+}
+
+// put keeps conn open for the next deliveries to mx,
+// conn is closed when the pool of mx is full
+func (p *connPool) put(mx string, conn *pooledConn) {
+	conn.lastUsed = time.Now()
+	p.mu.Lock()
+	if p.config.IdleTimeout <= 0 || len(p.idle[mx]) >= p.config.MaxIdle {
+		p.mu.Unlock()
+		conn.close()
+		return
+	}
+	p.idle[mx] = append(p.idle[mx], conn)
+	p.mu.Unlock()
+}
+
+// closeExpired closes every interval the connections idle for more than
+// IdleTimeout, so receiving servers don't keep them open
+func (p *connPool) closeExpired(interval time.Duration) {
+	for range time.Tick(interval) {
+		var expired []*pooledConn
+		p.mu.Lock()
+		for mx, conns := range p.idle {
+			alive := conns[:0]
+			for _, conn := range conns {
+				if time.Since(conn.lastUsed) >= p.config.IdleTimeout {
+					expired = append(expired, conn)
+				} else {
+					alive = append(alive, conn)
+				}
+			}
+			if len(alive) == 0 {
+				delete(p.idle, mx)
+			} else {
+				p.idle[mx] = alive
+			}
+		}
+		p.mu.Unlock()
+
+		for _, conn := range expired {
+			conn.close()
+		}
+	}
+}
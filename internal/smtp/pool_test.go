@@ -0,0 +1,96 @@
+package smtp
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeConn returns a connection to a fake SMTP server accepting
+// every command but RCPT TO of the rejected recipients
+func fakeConn(t *testing.T, rejected ...string) *pooledConn {
+	client, server := net.Pipe()
+	go serveFake(server, rejected)
+
+	c, err := smtp.NewClient(client, "mx.example.com")
+	assert.Nil(t, err)
+	return &pooledConn{conn: client, c: c}
+}
+
+// serveFake is a fake SMTP server on conn accepting every command
+// but RCPT TO of the rejected recipients
+func serveFake(conn net.Conn, rejected []string) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	fmt.Fprint(conn, "220 fake\r\n")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch {
+		case strings.HasPrefix(line, "QUIT"):
+			fmt.Fprint(conn, "221 bye\r\n")
+			return
+		case strings.HasPrefix(line, "RCPT") && isRejected(line, rejected):
+			fmt.Fprint(conn, "550 no such user\r\n")
+		case strings.HasPrefix(line, "DATA"):
+			fmt.Fprint(conn, "354 go ahead\r\n")
+			for line != ".\r\n" {
+				if line, err = r.ReadString('\n'); err != nil {
+					return
+				}
+			}
+			fmt.Fprint(conn, "250 queued\r\n")
+		default:
+			fmt.Fprint(conn, "250 ok\r\n")
+		}
+	}
+}
+
+func isRejected(line string, rejected []string) bool {
+	for _, r := range rejected {
+		if strings.Contains(line, "<"+r+">") {
+			return true
+		}
+	}
+	return false
+}
+
+func TestConnPoolReuse(t *testing.T) {
+	p := newConnPool(PoolConfig{IdleTimeout: time.Minute, MaxIdle: 1})
+	deadline := time.Now().Add(time.Second)
+	assert.Nil(t, p.get("mx.example.com", deadline))
+
+	conn := fakeConn(t)
+	p.put("mx.example.com", conn)
+	assert.Equal(t, conn, p.get("mx.example.com", deadline))
+	assert.Nil(t, p.get("mx.example.com", deadline))
+	assert.Nil(t, p.get("other.example.com", deadline))
+
+	// the second connection exceeds MaxIdle
+	p.put("mx.example.com", conn)
+	p.put("mx.example.com", fakeConn(t))
+	assert.Equal(t, conn, p.get("mx.example.com", deadline))
+	assert.Nil(t, p.get("mx.example.com", deadline))
+}
+
+func TestConnPoolExpired(t *testing.T) {
+	p := newConnPool(PoolConfig{IdleTimeout: time.Minute, MaxIdle: 1})
+	conn := fakeConn(t)
+	p.put("mx.example.com", conn)
+	conn.lastUsed = time.Now().Add(-2 * time.Minute)
+	assert.Nil(t, p.get("mx.example.com", time.Now().Add(time.Second)))
+}
+
+func TestConnPoolDisabled(t *testing.T) {
+	p := newConnPool(PoolConfig{})
+	p.put("mx.example.com", fakeConn(t))
+	assert.Nil(t, p.get("mx.example.com", time.Now().Add(time.Second)))
+}
@@ -0,0 +1,30 @@
+package smtp
+
+import (
+	"fmt"
+	"net"
+)
+
+// ParseRelay parses the address of a relay like localhost:1025, empty is no relay
+func ParseRelay(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid relay %v: %w", s, err)
+	}
+	if host == "" || port == "" {
+		return "", fmt.Errorf("invalid relay %v: use host:port", s)
+	}
+	return s, nil
+}
+
+// mxAddr returns the host and the port of mx, MXs are on port 25
+// and relays have their port
+func mxAddr(mx string) (string, string) {
+	if host, port, err := net.SplitHostPort(mx); err == nil {
+		return host, port
+	}
+	return mx, smtpPort
+}
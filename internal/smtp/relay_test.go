@@ -0,0 +1,60 @@
+package smtp
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRelay(t *testing.T) {
+	relay, err := ParseRelay("localhost:1025")
+	assert.Nil(t, err)
+	assert.Equal(t, "localhost:1025", relay)
+
+	relay, err = ParseRelay("")
+	assert.Nil(t, err)
+	assert.Equal(t, "", relay)
+
+	for _, s := range []string{"localhost", ":1025", "localhost:"} {
+		_, err := ParseRelay(s)
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestMXAddr(t *testing.T) {
+	host, port := mxAddr("mx.example.com")
+	assert.Equal(t, "mx.example.com", host)
+	assert.Equal(t, "25", port)
+
+	host, port = mxAddr("localhost:1025")
+	assert.Equal(t, "localhost", host)
+	assert.Equal(t, "1025", port)
+}
+
+func TestSendRelay(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveFake(conn, []string{"rejected@example.org"})
+		}
+	}()
+
+	// the recipient domains have no MX, every email is sent to the relay
+	s := NewSender("kannon.test", Config{Timeouts: DefaultTimeouts, Relay: l.Addr().String()})
+	errs, results := s.SendBatch(DefaultIPPool, "from@kannon.test", []string{"one@example.invalid", "rejected@example.org"}, []byte("body\r\n"))
+	provider := mxProvider(l.Addr().String())
+	assert.Equal(t, []Result{{Provider: provider, Response: "250 queued"}, {Provider: provider}}, results)
+	assert.Nil(t, errs[0])
+	if assert.NotNil(t, errs[1]) {
+		assert.Equal(t, 550, errs[1].Code())
+	}
+}
@@ -0,0 +1,33 @@
+package smtp
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+// MaxSandboxBouncePercent is the max bounce percent of a sandbox domain
+const MaxSandboxBouncePercent = 100
+
+// SandboxSend simulates the delivery of the email messageID to recipients without
+// connecting to their MXs: bouncePercent of the recipients bounce, picked by the hash
+// of the message id and the recipient so deliveries sent again have the same result,
+// recipients whose local part starts with bounce, like bounce+1@example.com, always bounce
+func SandboxSend(messageID string, to []string, bouncePercent uint32) []SenderError {
+	errs := make([]SenderError, len(to))
+	for i, rcpt := range to {
+		if sandboxBounces(messageID, rcpt, bouncePercent) {
+			errs[i] = newSMTPError(fmt.Errorf("550 5.1.1 sandbox: simulated bounce of %v", rcpt), true, 550)
+		}
+	}
+	return errs
+}
+
+func sandboxBounces(messageID string, rcpt string, bouncePercent uint32) bool {
+	if strings.HasPrefix(strings.ToLower(rcpt), "bounce") {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(messageID + "\n" + strings.ToLower(rcpt)))
+	return h.Sum32()%MaxSandboxBouncePercent < bouncePercent
+}
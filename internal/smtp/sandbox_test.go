@@ -0,0 +1,34 @@
+package smtp
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSandboxSend(t *testing.T) {
+	to := make([]string, 1000)
+	for i := range to {
+		to[i] = fmt.Sprintf("user%v@email.com", i)
+	}
+
+	for _, percent := range []uint32{0, 30, 100} {
+		bounced := 0
+		for _, err := range SandboxSend("msg_test@kannon.io", to, percent) {
+			if err != nil {
+				bounced++
+				assert.True(t, err.IsPermanent())
+				assert.Equal(t, 550, err.Code())
+			}
+		}
+		assert.InDelta(t, int(percent)*len(to)/100, bounced, 50, "bounce percent %v", percent)
+	}
+
+	// results of the same email are stable
+	assert.Equal(t, SandboxSend("msg_test@kannon.io", to, 30), SandboxSend("msg_test@kannon.io", to, 30))
+
+	errs := SandboxSend("msg_test@kannon.io", []string{"bounce+1@email.com", "Bounce@email.com"}, 0)
+	assert.NotNil(t, errs[0])
+	assert.NotNil(t, errs[1])
+}
@@ -6,30 +6,47 @@ package smtp
 */
 
 import (
-	"crypto/tls"
 	"fmt"
 	"net"
 	"net/smtp"
 	"net/textproto"
+	"net/url"
+	"strconv"
 	"time"
 
-	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/idna"
+	"kannon.gyozatech.dev/internal/logging"
+	"kannon.gyozatech.dev/internal/metrics"
 )
 
-const (
-	// Timeouts for SMTP delivery.
-	smtpDialTimeout  = 15 * time.Second
-	smtpTotalTimeout = 120 * time.Second
+var log = logging.Logger("smtp")
 
-	// SMTP default port
-	smtpPort = "25"
-)
+// SMTP default port
+const smtpPort = "25"
+
+// Timeouts are the timeouts of a single SMTP delivery
+type Timeouts struct {
+	// Dial is the max time to connect to a MX
+	Dial time.Duration
+	// Total is the max time of the whole SMTP transaction after connecting
+	Total time.Duration
+}
+
+// DefaultTimeouts are the timeouts of deliveries without a custom config
+var DefaultTimeouts = Timeouts{
+	Dial:  15 * time.Second,
+	Total: 120 * time.Second,
+}
 
 type smtpError struct {
 	err         error
 	isPermanent bool
 	code        int
+	// deferredUntil is the time of the next delivery
+	// of an email the sender postponed
+	deferredUntil time.Time
+	// reason is the TLS policy the delivery failed
+	reason string
 }
 
 func (e smtpError) Error() string {
@@ -44,6 +61,14 @@ func (e smtpError) Code() int {
 	return e.code
 }
 
+func (e smtpError) DeferredUntil() time.Time {
+	return e.deferredUntil
+}
+
+func (e smtpError) Reason() string {
+	return e.reason
+}
+
 func newSMTPError(err error, isPermanent bool, code int) *smtpError {
 	return &smtpError{
 		err:         err,
@@ -53,7 +78,27 @@ func newSMTPError(err error, isPermanent bool, code int) *smtpError {
 }
 
 type sender struct {
-	Hostname string
+	Hostname  string
+	throttler *throttler
+	timeouts  Timeouts
+	pool      *connPool
+	ips       *ipSelector
+	warmup    *warmup
+	proxies   ProxyConfig
+	ipv6      bool
+	tls       TLSConfig
+	mtasts    *mtaSTS
+	relay     string
+}
+
+// route are the source IPs and the proxy of the connections of a delivery
+type route struct {
+	// ip4 and ip6 are the source IPs of every family,
+	// nil when the system chooses the address
+	ip4, ip6 net.IP
+	// v4 and v6 report if the connections can use the family
+	v4, v6 bool
+	proxy  *url.URL
 }
 
 // SenderName implements sender name function
@@ -62,116 +107,381 @@ func (s *sender) SenderName() string {
 	return s.Hostname
 }
 
-// Send email
+func (s *sender) SetThrottle(config ThrottleConfig) {
+	s.throttler.setConfig(config)
+}
+
+// Send email from the default ip pool
 func (s *sender) Send(from, to string, msg []byte) SenderError {
-	toDomain, err := GetEmailDomain(to)
-	log.Printf("domain %v\n", toDomain)
+	errs, _ := s.SendBatch(DefaultIPPool, from, []string{to}, msg)
+	return errs[0]
+}
+
+// SendBatch sends the same msg to many recipients from an IP of ipPool,
+// recipients of the same domain are sent in a single SMTP transaction with
+// a RCPT TO each. Errors are in the order of to, nil when the recipient is delivered
+func (s *sender) SendBatch(ipPool string, from string, to []string, msg []byte) ([]SenderError, []Result) {
+	errs := make([]SenderError, len(to))
+	results := make([]Result, len(to))
+	var domains []string
+	byDomain := make(map[string][]int)
+	for i, rcpt := range to {
+		toDomain, err := GetEmailDomain(rcpt)
+		if err != nil {
+			// CHECK: 510: indiritto email errato
+			errs[i] = newSMTPError(err, true, 510)
+			continue
+		}
+		if _, ok := byDomain[toDomain]; !ok {
+			domains = append(domains, toDomain)
+		}
+		byDomain[toDomain] = append(byDomain[toDomain], i)
+	}
+
+	for _, toDomain := range domains {
+		log.Printf("domain %v\n", toDomain)
+		idx := byDomain[toDomain]
+		rcpts := make([]string, len(idx))
+		for i, j := range idx {
+			rcpts[i] = to[j]
+		}
+		ip4, v4 := s.ips.pick(ipPool, false)
+		ip6, v6 := s.ips.pick(ipPool, true)
+		r := route{
+			ip4:   ip4,
+			ip6:   ip6,
+			v4:    v4,
+			v6:    v6 && s.ipv6,
+			proxy: s.proxies.forPool(ipPool),
+		}
+		domainErrs, domainResults := s.sendDomain(r, from, toDomain, rcpts, msg)
+		for i, err := range domainErrs {
+			if err != nil {
+				errs[idx[i]] = err
+			}
+			results[idx[i]] = domainResults[i]
+		}
+	}
+	return errs, results
+}
+
+// sendDomain sends msg on r to recipients of the same domain trying its
+// MXs in order, recipients with a transient error are tried on the next MX.
+// It returns the errors and the results of the last MX tried of every recipient
+func (s *sender) sendDomain(r route, from, toDomain string, to []string, msg []byte) ([]*smtpError, []Result) {
+	errs := make([]*smtpError, len(to))
+	results := make([]Result, len(to))
+	mxs, lerr := s.lookupMXs(toDomain)
+	if lerr != nil {
+		for i := range errs {
+			errs[i] = lerr
+		}
+		return errs, results
+	}
+
+	var sts *stsPolicy
+	if s.tls.MTASTS && s.relay == "" {
+		sts = s.mtasts.policy(toDomain)
+	}
+
+	pending := make([]int, len(to))
+	for i := range pending {
+		pending[i] = i
+	}
+	// 111: cannot connect, when there are no MXs
+	lastErr := newSMTPError(fmt.Errorf("no MX for %v", toDomain), false, 111)
+	for _, mx := range mxs {
+		if len(pending) == 0 {
+			break
+		}
+		policy, perr := s.tlsPolicy(toDomain, mx, sts)
+		if perr != nil {
+			lastErr = perr
+			continue
+		}
+		provider := mxProvider(mx)
+		if !s.throttler.acquire(provider) {
+			// 421: service not available, retried later
+			lastErr = newSMTPError(fmt.Errorf("throttled deliveries to %v", provider), false, 421)
+			continue
+		}
+
+		rcpts := make([]string, len(pending))
+		for i, j := range pending {
+			rcpts[i] = to[j]
+		}
+		rcptErrs, response, err := s.deliver(r, policy, from, rcpts, msg, mx)
+		s.throttler.release(provider, responseCode(rcptErrs, err))
+
+		var retry []int
+		for i, j := range pending {
+			results[j] = Result{Provider: provider}
+			rerr := err
+			if rcptErrs != nil && rcptErrs[i] != nil {
+				rerr = rcptErrs[i]
+			}
+			if rerr == nil {
+				results[j].Response = response
+			}
+			if rerr == nil || rerr.IsPermanent() {
+				errs[j] = rerr
+				continue
+			}
+			lastErr = rerr
+			retry = append(retry, j)
+		}
+		pending = retry
+	}
+
+	for _, j := range pending {
+		err := fmt.Errorf("all MXs failed, last error: %v", lastErr)
+		errs[j] = newSMTPError(err, false, lastErr.Code())
+		errs[j].deferredUntil = lastErr.deferredUntil
+		errs[j].reason = lastErr.reason
+	}
+	return errs, results
+}
+
+// responseCode is the code of the first error of a delivery, 0 when delivered
+func responseCode(rcptErrs []*smtpError, err *smtpError) int {
 	if err != nil {
-		// CHECK: 510: indiritto email errato
-		return newSMTPError(err, true, 510)
+		return err.Code()
+	}
+	for _, rerr := range rcptErrs {
+		if rerr != nil {
+			return rerr.Code()
+		}
+	}
+	return 0
+}
+
+// deliver sends msg to the recipients of mx on an idle connection of the pool
+// or a new one, connections are kept open for the next deliveries when the
+// transaction succeeds. It returns the errors of every RCPT TO, the response
+// to the message and the error of the whole transaction
+func (s *sender) deliver(r route, policy tlsPolicy, from string, to []string, msg []byte, mx string) ([]*smtpError, string, *smtpError) {
+	start := time.Now()
+	provider := mxProvider(mx)
+	key := poolKey(mx, r, policy)
+	conn := s.pool.get(key, start.Add(s.timeouts.Total))
+	if conn == nil {
+		var err *smtpError
+		conn, err = connect(mx, r, policy, false, s.Hostname, s.timeouts)
+		if err != nil {
+			countDeliveries(nil, provider, len(to), nil, err, start)
+			return nil, "", err
+		}
+	}
+
+	// the source IP is known once connected, as the family depends on
+	// the address of mx that accepted the connection, behind a proxy
+	// it's the egress IP of the proxy
+	ip := sourceIP(conn.conn, r)
+	if ok, until := s.warmup.allow(ip, provider, len(to)); !ok {
+		s.pool.put(key, conn)
+		// 451: local error in processing, sent again the next day
+		err := newSMTPError(fmt.Errorf("warm-up limit of %v to %v reached", ip, provider), false, 451)
+		err.deferredUntil = until
+		return nil, "", err
 	}
 
-	mxs, lerr := lookupMXs(toDomain)
+	rcptErrs, response, err := send(conn.c, from, to, msg)
+	countDeliveries(ip, provider, len(to), rcptErrs, err, start)
 	if err != nil {
-		return lerr
+		conn.close()
+		return rcptErrs, "", err
 	}
+	s.pool.put(key, conn)
+	return rcptErrs, response, nil
+}
 
-	var lastErr *smtpError
-	for _, mx := range mxs {
-		err := deliver(from, to, msg, mx, false, s.Hostname)
-		if err == nil {
-			return nil
+// poolKey is the key of the connections to mx on r in the pool, connections
+// of a TLS policy are not reused by deliveries of other policies
+func poolKey(mx string, r route, policy tlsPolicy) string {
+	key := mx
+	if policy.reason != "" {
+		key += "/" + policy.reason
+	}
+	if r.ip4 != nil {
+		key += "/" + r.ip4.String()
+	}
+	if r.ip6 != nil {
+		key += "/" + r.ip6.String()
+	}
+	if r.proxy != nil {
+		key += "/" + r.proxy.String()
+	}
+	return key
+}
+
+// countDeliveries counts the results of the delivery of n recipients from ip to the MX
+// of provider, rcptErrs are the errors of every recipient and err the error of all of them.
+// The duration of the transaction since start is observed with its result
+func countDeliveries(ip net.IP, provider string, n int, rcptErrs []*smtpError, err *smtpError, start time.Time) {
+	metrics.SMTPDeliveryDuration.WithLabelValues(deliveryResult(err)).Observe(time.Since(start).Seconds())
+	addr, family := ipLabels(ip)
+	for i := 0; i < n; i++ {
+		rerr := err
+		if rcptErrs != nil && rcptErrs[i] != nil {
+			rerr = rcptErrs[i]
 		}
-		if err.IsPermanent() {
-			return err
+		// 250: delivered
+		code, enhanced := 250, ""
+		if rerr != nil {
+			metrics.SMTPErrors.WithLabelValues(strconv.Itoa(rerr.code)).Inc()
+			code, enhanced = rerr.code, EnhancedStatus(rerr.Error())
 		}
-		lastErr = err
+		metrics.SMTPResponses.WithLabelValues(provider, strconv.Itoa(code), enhanced).Inc()
+		metrics.SMTPDeliveries.WithLabelValues(addr, family, deliveryResult(rerr)).Inc()
+	}
+}
+
+// deliveryResult is the result label of a delivery failed with err
+func deliveryResult(err *smtpError) string {
+	if err == nil {
+		return "delivered"
+	}
+	if err.IsPermanent() {
+		return "bounced"
 	}
-	err = fmt.Errorf("all MXs failed, last error: %v", lastErr)
-	return newSMTPError(err, false, lastErr.Code())
+	return "deferred"
 }
 
-func deliver(from, to string, msg []byte, mx string, insecure bool, domain string) *smtpError {
-	smtpURL := fmt.Sprintf("%v:%v", mx, smtpPort)
-	conn, err := net.DialTimeout("tcp", smtpURL, smtpDialTimeout)
+// connect opens a connection to mx on r, says hello and starts TLS when
+// supported, or fails when TLS is enforced by policy and not available
+func connect(mx string, r route, policy tlsPolicy, insecure bool, domain string, timeouts Timeouts) (*pooledConn, *smtpError) {
+	conn, err := dialMX(mx, r, timeouts.Dial)
 	if err != nil {
 		log.Debugf("Could not dial: %v", err)
 		// TODO: add error code
 		// Cannot dial SMTP 111
-		return newSMTPError(err, false, 111)
+		return nil, newSMTPError(err, false, 111)
 	}
-	defer conn.Close()
-	if err := conn.SetDeadline(time.Now().Add(smtpTotalTimeout)); err != nil {
+	if err := conn.SetDeadline(time.Now().Add(timeouts.Total)); err != nil {
 		log.Debugf("Cannot set deadline: %v", err)
+		conn.Close()
 		// TODO: add error code
-		return newSMTPError(err, false, 111)
+		return nil, newSMTPError(err, false, 111)
 	}
 
-	c, err := smtp.NewClient(conn, mx)
+	host, _ := mxAddr(mx)
+	c, err := smtp.NewClient(conn, host)
 	if err != nil {
 		log.Debugf("Error creating client: %v", err)
+		conn.Close()
 		// TODO: add error code
-		return newSMTPError(err, false, 111)
+		return nil, newSMTPError(err, false, 111)
 	}
 
 	if err = c.Hello(domain); err != nil {
 		log.Debugf("Error saying hello: %v", err)
+		conn.Close()
 		// TODO: add error code
-		return newSMTPError(err, false, 111)
+		return nil, newSMTPError(err, false, 111)
 	}
 
-	if ok, _ := c.Extension("STARTTLS"); ok {
-		config := &tls.Config{
-			ServerName:         mx,
-			InsecureSkipVerify: insecure,
-		}
-		err = c.StartTLS(config)
+	ok, _ := c.Extension("STARTTLS")
+	if !ok && policy.enforced() {
+		conn.Close()
+		return nil, newPolicyError(policy.reason, fmt.Errorf("%v: %w", mx, errNoSTARTTLS))
+	}
+	if !ok && policy.testing {
+		log.Warnf("MTA-STS testing: %v: %v", mx, errNoSTARTTLS)
+	}
+	if ok {
+		err = c.StartTLS(policy.tlsConfig(host, insecure))
 		if err != nil {
+			conn.Close()
+			if policy.enforced() {
+				return nil, newPolicyError(policy.reason, fmt.Errorf("%v: %w", mx, err))
+			}
+			if policy.testing && !insecure {
+				log.Warnf("MTA-STS testing: %v: %v", mx, err)
+			}
 			// Unfortunately, many servers use self-signed certs, so if we
 			// fail verification we just try again without validating.
 			if insecure {
 				log.Debugf("TLS error: %v", err)
 				// TODO: add error code
-				return newSMTPError(err, false, 111)
+				return nil, newSMTPError(err, false, 111)
 			}
 			log.Debugf("TLS error, retrying insecurely\n")
-			return deliver(from, to, msg, mx, true, domain)
+			return connect(mx, r, policy, true, domain, timeouts)
 		}
 	}
 
+	return &pooledConn{conn: conn, c: c}, nil
+}
+
+// send sends msg to every recipient in a SMTP transaction on c, recipients
+// rejected by RCPT TO have their error and are not sent. It returns the
+// response of the server to the message, like 250 2.0.0 OK, empty when no
+// recipient is accepted
+func send(c *smtp.Client, from string, to []string, msg []byte) ([]*smtpError, string, *smtpError) {
 	if err := c.Mail(from); err != nil {
 		log.Debugf("err: %v\n", err)
-		return newSMTPErrorFromSTMP(err)
+		return nil, "", newSMTPErrorFromSTMP(err)
 	}
 
-	if err := c.Rcpt(to); err != nil {
-		log.Debugf("err: %v\n", err)
-		return newSMTPErrorFromSTMP(err)
+	rcptErrs := make([]*smtpError, len(to))
+	accepted := 0
+	for i, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			log.Debugf("err: %v\n", err)
+			if _, ok := err.(*textproto.Error); !ok {
+				// the connection is broken
+				return rcptErrs, "", newSMTPErrorFromSTMP(err)
+			}
+			rcptErrs[i] = newSMTPErrorFromSTMP(err)
+			continue
+		}
+		accepted++
+	}
+	if accepted == 0 {
+		if err := c.Reset(); err != nil {
+			return rcptErrs, "", newSMTPErrorFromSTMP(err)
+		}
+		return rcptErrs, "", nil
 	}
 
-	w, err := c.Data()
+	// the DATA of the client discards the response to the message
+	id, err := c.Text.Cmd("DATA")
 	if err != nil {
 		log.Debugf("err: %v\n", err)
-		return newSMTPErrorFromSTMP(err)
+		return rcptErrs, "", newSMTPErrorFromSTMP(err)
 	}
-	_, err = w.Write(msg)
+	c.Text.StartResponse(id)
+	_, _, err = c.Text.ReadResponse(354)
+	c.Text.EndResponse(id)
 	if err != nil {
 		log.Debugf("err: %v\n", err)
-		return newSMTPErrorFromSTMP(err)
+		return rcptErrs, "", newSMTPErrorFromSTMP(err)
 	}
 
-	err = w.Close()
-	if err != nil {
+	w := c.Text.DotWriter()
+	if _, err := w.Write(msg); err != nil {
 		log.Debugf("err: %v\n", err)
-		return newSMTPErrorFromSTMP(err)
+		return rcptErrs, "", newSMTPErrorFromSTMP(err)
 	}
-
-	if err := c.Quit(); err != nil {
+	if err := w.Close(); err != nil {
 		log.Debugf("err: %v\n", err)
-		return newSMTPErrorFromSTMP(err)
+		return rcptErrs, "", newSMTPErrorFromSTMP(err)
+	}
+	code, response, err := c.Text.ReadResponse(250)
+	if err != nil {
+		log.Debugf("err: %v\n", err)
+		return rcptErrs, "", newSMTPErrorFromSTMP(err)
 	}
 
-	return nil
+	return rcptErrs, fmt.Sprintf("%d %v", code, response), nil
+}
+
+// lookupMXs returns the MXs of domain by priority, or the relay
+func (s *sender) lookupMXs(domain string) ([]string, *smtpError) {
+	if s.relay != "" {
+		return []string{s.relay}, nil
+	}
+	return lookupMXs(domain)
 }
 
 func lookupMXs(domain string) ([]string, *smtpError) {
@@ -0,0 +1,46 @@
+package smtp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendMultipleRecipients(t *testing.T) {
+	conn := fakeConn(t, "rejected@example.com")
+	defer conn.close()
+
+	rcptErrs, response, err := send(conn.c, "from@kannon.io", []string{"one@example.com", "rejected@example.com", "two@example.com"}, []byte("body\r\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "250 queued", response)
+	assert.Len(t, rcptErrs, 3)
+	assert.Nil(t, rcptErrs[0])
+	assert.Nil(t, rcptErrs[2])
+	if assert.NotNil(t, rcptErrs[1]) {
+		assert.Equal(t, 550, rcptErrs[1].Code())
+		assert.True(t, rcptErrs[1].IsPermanent())
+	}
+
+	// the connection can be reused after a transaction without recipients
+	rcptErrs, response, err = send(conn.c, "from@kannon.io", []string{"rejected@example.com"}, []byte("body\r\n"))
+	assert.Nil(t, err)
+	assert.Empty(t, response)
+	assert.NotNil(t, rcptErrs[0])
+	assert.Nil(t, conn.c.Noop())
+}
+
+func TestSendBatchInvalidRecipient(t *testing.T) {
+	s := &sender{}
+	errs, results := s.SendBatch(DefaultIPPool, "from@kannon.io", []string{"invalid"}, nil)
+	if assert.Len(t, errs, 1) && assert.NotNil(t, errs[0]) {
+		assert.Equal(t, 510, errs[0].Code())
+	}
+	// no MX responded
+	assert.Equal(t, []Result{{}}, results)
+}
+
+func TestResponseCode(t *testing.T) {
+	assert.Equal(t, 0, responseCode([]*smtpError{nil}, nil))
+	assert.Equal(t, 421, responseCode(nil, newSMTPError(nil, false, 421)))
+	assert.Equal(t, 450, responseCode([]*smtpError{nil, newSMTPError(nil, false, 450)}, nil))
+}
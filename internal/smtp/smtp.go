@@ -1,16 +1,70 @@
 package smtp
 
+import "time"
+
 // Sender interface represents a email sender
 // object that can send a message
 type Sender interface {
 	Send(from string, to string, msg []byte) SenderError
+	// SendBatch sends the same message to many recipients from an IP of ipPool,
+	// in a single transaction per recipient domain. Errors and results are in the
+	// order of to, results are the last responses of the MXs to the recipients
+	SendBatch(ipPool string, from string, to []string, msg []byte) ([]SenderError, []Result)
 	SenderName() string
+	// SetThrottle applies config to the next deliveries, the
+	// deliveries in progress are not interrupted
+	SetThrottle(config ThrottleConfig)
+}
+
+// Result is the last response of the MXs to the delivery of a recipient
+type Result struct {
+	// Provider is the receiving provider of the MX, like gmail,
+	// empty when no MX responded
+	Provider string
+	// Response is the response of the MX to a delivered email,
+	// like 250 2.0.0 OK, empty when not delivered
+	Response string
+}
+
+// Config configures the deliveries of a Sender
+type Config struct {
+	// Throttle throttles the deliveries to receiving providers
+	Throttle ThrottleConfig
+	// Timeouts bound every delivery
+	Timeouts Timeouts
+	// Pool configures the reuse of connections to the same MX
+	Pool PoolConfig
+	// IPPools are the source IPs of the connections
+	IPPools IPPools
+	// Warmup caps the deliveries of IPs in warm-up
+	Warmup WarmupConfig
+	// Proxies route the connections of ip pools through proxies
+	Proxies ProxyConfig
+	// IPv6 delivers to the IPv6 addresses of MXs first,
+	// falling back to IPv4 when they fail
+	IPv6 bool
+	// TLS are the TLS policies of the deliveries
+	TLS TLSConfig
+	// Relay is the SMTP server receiving every email instead of the MXs
+	// of the recipients, like MailHog on localhost:1025, empty is no relay
+	Relay string
 }
 
 // NewSender construct a new sender for a given hostname
-func NewSender(hostname string) Sender {
+// that delivers as configured by config
+func NewSender(hostname string, config Config) Sender {
 	return &sender{
-		Hostname: hostname,
+		Hostname:  hostname,
+		throttler: newThrottler(config.Throttle),
+		timeouts:  config.Timeouts,
+		pool:      newConnPool(config.Pool),
+		ips:       newIPSelector(config.IPPools),
+		warmup:    newWarmup(config.Warmup),
+		proxies:   config.Proxies,
+		ipv6:      config.IPv6,
+		tls:       config.TLS,
+		mtasts:    newMTASTS(),
+		relay:     config.Relay,
 	}
 }
 
@@ -19,4 +73,10 @@ type SenderError interface {
 	Error() string
 	IsPermanent() bool
 	Code() int
+	// DeferredUntil is the time of the next delivery of an email the
+	// sender postponed without trying it, zero for delivery errors
+	DeferredUntil() time.Time
+	// Reason is the TLS policy the delivery failed, like ReasonMTASTS,
+	// empty for other errors
+	Reason() string
 }
@@ -0,0 +1,175 @@
+package smtp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ThrottleConfig configures the concurrent deliveries to receiving providers
+type ThrottleConfig struct {
+	// MaxConnections is the max concurrent deliveries to a provider, 0 is unlimited
+	MaxConnections int
+	// Limits overrides MaxConnections of providers, like gmail or outlook
+	Limits map[string]int
+	// Backoff is the pause of deliveries to a provider that
+	// answered with a throttling response
+	Backoff time.Duration
+	// MaxWait is the max time a delivery waits for a free connection
+	MaxWait time.Duration
+}
+
+// throttleCodes are the transient responses of providers limiting the sender
+var throttleCodes = map[int]bool{
+	421: true,
+	450: true,
+}
+
+// providers groups MX hosts of the same receiving provider
+var providers = []struct {
+	suffix   string
+	provider string
+}{
+	{"google.com", "gmail"},
+	{"googlemail.com", "gmail"},
+	{"outlook.com", "outlook"},
+	{"hotmail.com", "outlook"},
+	{"yahoodns.net", "yahoo"},
+}
+
+// mxProvider returns the provider of a MX host, the domain
+// of the host for unknown providers
+func mxProvider(mx string) string {
+	mx = strings.ToLower(strings.TrimSuffix(mx, "."))
+	for _, p := range providers {
+		if mx == p.suffix || strings.HasSuffix(mx, "."+p.suffix) {
+			return p.provider
+		}
+	}
+	labels := strings.Split(mx, ".")
+	if len(labels) > 2 {
+		labels = labels[len(labels)-2:]
+	}
+	return strings.Join(labels, ".")
+}
+
+// ParseThrottleLimits parses provider limits like gmail=50,outlook=20
+func ParseThrottleLimits(s string) (map[string]int, error) {
+	limits := make(map[string]int)
+	if s == "" {
+		return limits, nil
+	}
+	for _, l := range strings.Split(s, ",") {
+		parts := strings.SplitN(strings.TrimSpace(l), "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid provider limit: %v", l)
+		}
+		n, err := strconv.Atoi(parts[1])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid provider limit: %v", l)
+		}
+		limits[parts[0]] = n
+	}
+	return limits, nil
+}
+
+type providerState struct {
+	max         int
+	limit       int
+	active      int
+	pausedUntil time.Time
+}
+
+// throttler limits the concurrent deliveries to providers, the limit of a
+// provider is halved on throttling responses and grows back on deliveries
+type throttler struct {
+	config    ThrottleConfig
+	mu        sync.Mutex
+	providers map[string]*providerState
+	now       func() time.Time
+}
+
+func newThrottler(config ThrottleConfig) *throttler {
+	return &throttler{
+		config:    config,
+		providers: make(map[string]*providerState),
+		now:       time.Now,
+	}
+}
+
+// tryAcquire takes a connection to a provider if one is free
+func (t *throttler) tryAcquire(provider string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.providers[provider]
+	if !ok {
+		max := t.config.MaxConnections
+		if l, ok := t.config.Limits[provider]; ok {
+			max = l
+		}
+		p = &providerState{max: max, limit: max}
+		t.providers[provider] = p
+	}
+	if t.now().Before(p.pausedUntil) {
+		return false
+	}
+	if p.max > 0 && p.active >= p.limit {
+		return false
+	}
+	p.active++
+	return true
+}
+
+// setConfig applies config to the next deliveries, the limits lowered by
+// throttling responses are kept when they are under the new max
+func (t *throttler) setConfig(config ThrottleConfig) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.config = config
+	for name, p := range t.providers {
+		max := config.MaxConnections
+		if l, ok := config.Limits[name]; ok {
+			max = l
+		}
+		if p.limit == p.max || p.limit > max || max == 0 {
+			p.limit = max
+		}
+		p.max = max
+	}
+}
+
+// acquire waits up to MaxWait for a connection to a provider
+func (t *throttler) acquire(provider string) bool {
+	t.mu.Lock()
+	deadline := t.now().Add(t.config.MaxWait)
+	t.mu.Unlock()
+	for !t.tryAcquire(provider) {
+		if t.now().After(deadline) {
+			return false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return true
+}
+
+// release frees a connection to a provider, code is the
+// response of the delivery or 0 when delivered
+func (t *throttler) release(provider string, code int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p := t.providers[provider]
+	p.active--
+	if throttleCodes[code] {
+		p.pausedUntil = t.now().Add(t.config.Backoff)
+		if p.limit > 1 {
+			p.limit /= 2
+		}
+	} else if code == 0 && p.limit < p.max {
+		p.limit++
+	}
+}
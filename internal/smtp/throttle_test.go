@@ -0,0 +1,70 @@
+package smtp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMXProvider(t *testing.T) {
+	assert.Equal(t, "gmail", mxProvider("gmail-smtp-in.l.google.com."))
+	assert.Equal(t, "outlook", mxProvider("kannon-io.mail.protection.outlook.com"))
+	assert.Equal(t, "yahoo", mxProvider("mta5.am0.yahoodns.net"))
+	assert.Equal(t, "kannon.io", mxProvider("mx1.kannon.io"))
+}
+
+func TestParseThrottleLimits(t *testing.T) {
+	limits, err := ParseThrottleLimits("gmail=50, outlook=20")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"gmail": 50, "outlook": 20}, limits)
+
+	_, err = ParseThrottleLimits("gmail")
+	assert.NotNil(t, err)
+}
+
+func TestThrottler(t *testing.T) {
+	now := time.Now()
+	th := newThrottler(ThrottleConfig{
+		MaxConnections: 4,
+		Limits:         map[string]int{"gmail": 2},
+		Backoff:        time.Minute,
+	})
+	th.now = func() time.Time { return now }
+
+	assert.True(t, th.tryAcquire("gmail"))
+	assert.True(t, th.tryAcquire("gmail"))
+	assert.False(t, th.tryAcquire("gmail"))
+	assert.True(t, th.tryAcquire("kannon.io"))
+
+	// throttling responses halve the limit and pause the provider
+	th.release("gmail", 421)
+	assert.False(t, th.tryAcquire("gmail"))
+	now = now.Add(2 * time.Minute)
+	assert.False(t, th.tryAcquire("gmail"))
+
+	// deliveries grow the limit back
+	th.release("gmail", 0)
+	assert.True(t, th.tryAcquire("gmail"))
+	assert.True(t, th.tryAcquire("gmail"))
+	assert.False(t, th.tryAcquire("gmail"))
+}
+
+func TestThrottlerSetConfig(t *testing.T) {
+	th := newThrottler(ThrottleConfig{MaxConnections: 4, Limits: map[string]int{"gmail": 2}})
+	assert.True(t, th.tryAcquire("gmail"))
+	assert.True(t, th.tryAcquire("gmail"))
+	assert.True(t, th.tryAcquire("kannon.io"))
+
+	// in-flight deliveries count against the new limits
+	th.setConfig(ThrottleConfig{MaxConnections: 1, Limits: map[string]int{"gmail": 3}})
+	assert.True(t, th.tryAcquire("gmail"))
+	assert.False(t, th.tryAcquire("gmail"))
+	assert.False(t, th.tryAcquire("kannon.io"))
+	assert.True(t, th.tryAcquire("outlook.com"))
+	assert.False(t, th.tryAcquire("outlook.com"))
+
+	th.setConfig(ThrottleConfig{})
+	assert.True(t, th.tryAcquire("kannon.io"))
+	assert.True(t, th.tryAcquire("kannon.io"))
+}
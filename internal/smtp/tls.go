@@ -0,0 +1,133 @@
+package smtp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Bounce reasons of the deliveries that failed a TLS policy
+const (
+	// ReasonTLSRequired is a failure of a domain that requires TLS
+	ReasonTLSRequired = "tls-required"
+	// ReasonMTASTS is a failure of the MTA-STS policy of a domain
+	ReasonMTASTS = "mta-sts"
+	// ReasonDANE is a failure of the TLSA records of a MX
+	ReasonDANE = "dane"
+)
+
+// TLSConfig are the TLS policies of the deliveries, deliveries
+// without a policy use STARTTLS when the MX offers it
+type TLSConfig struct {
+	// RequireTLS are the recipient domains whose MXs must
+	// offer STARTTLS with a valid certificate
+	RequireTLS map[string]bool
+	// MTASTS enforces the MTA-STS policies of the recipient domains
+	MTASTS bool
+	// DANEResolver is the address of a DNSSEC validating resolver,
+	// like 127.0.0.1:53, for the TLSA records of MXs, empty disables DANE
+	DANEResolver string
+}
+
+// ParseRequireTLS parses the domains requiring TLS like example.com,example.org
+func ParseRequireTLS(s string) map[string]bool {
+	domains := make(map[string]bool)
+	for _, d := range strings.Split(s, ",") {
+		d = strings.ToLower(strings.TrimSpace(d))
+		if d != "" {
+			domains[d] = true
+		}
+	}
+	return domains
+}
+
+// tlsPolicy is the TLS policy of the connections to a MX
+type tlsPolicy struct {
+	// reason is the bounce reason of failures of the
+	// policy, empty for opportunistic TLS
+	reason string
+	// testing logs the failures without failing the delivery,
+	// for MTA-STS policies in testing mode
+	testing bool
+	// tlsa are the usable TLSA records of the MX for DANE
+	// policies, the certificate is not verified when empty
+	tlsa []tlsaRecord
+}
+
+// enforced reports if the connections must use TLS
+func (p tlsPolicy) enforced() bool {
+	return p.reason != "" && !p.testing
+}
+
+// tlsPolicy returns the policy of the connections to mx for recipients of
+// domain, the DANE records of mx are stronger than the MTA-STS policy of
+// domain that is stronger than RequireTLS
+func (s *sender) tlsPolicy(domain, mx string, sts *stsPolicy) (tlsPolicy, *smtpError) {
+	if s.tls.DANEResolver != "" && s.relay == "" {
+		records, secure, err := lookupTLSA(s.tls.DANEResolver, mx, s.timeouts.Dial)
+		if err != nil {
+			// MXs whose TLSA records can't be resolved are not used
+			return tlsPolicy{}, newPolicyError(ReasonDANE, fmt.Errorf("cannot resolve TLSA of %v: %w", mx, err))
+		}
+		if secure && len(records) > 0 {
+			return tlsPolicy{reason: ReasonDANE, tlsa: usableTLSA(records)}, nil
+		}
+	}
+	if sts != nil && sts.mode != stsModeNone {
+		testing := sts.mode == stsModeTesting
+		if !sts.matches(mx) {
+			err := fmt.Errorf("MX %v is not in the MTA-STS policy of %v", mx, domain)
+			if !testing {
+				return tlsPolicy{}, newPolicyError(ReasonMTASTS, err)
+			}
+			log.Warnf("MTA-STS testing: %v", err)
+		}
+		return tlsPolicy{reason: ReasonMTASTS, testing: testing}, nil
+	}
+	if s.tls.RequireTLS[strings.ToLower(domain)] {
+		return tlsPolicy{reason: ReasonTLSRequired}, nil
+	}
+	return tlsPolicy{}, nil
+}
+
+// tlsConfig is the config of the STARTTLS to mx on policy, certificates of
+// DANE policies are verified by their TLSA records instead of the system roots
+func (p tlsPolicy) tlsConfig(mx string, insecure bool) *tls.Config {
+	config := &tls.Config{
+		ServerName:         mx,
+		InsecureSkipVerify: insecure,
+	}
+	if p.reason == ReasonDANE {
+		config.InsecureSkipVerify = true
+		config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(p.tlsa) == 0 {
+				// no usable records, TLS is required but not authenticated
+				return nil
+			}
+			certs := make([]*x509.Certificate, len(rawCerts))
+			for i, raw := range rawCerts {
+				cert, err := x509.ParseCertificate(raw)
+				if err != nil {
+					return err
+				}
+				certs[i] = cert
+			}
+			return verifyDANE(p.tlsa, certs, mx)
+		}
+	}
+	return config
+}
+
+// errNoSTARTTLS is the error of MXs without STARTTLS on enforced policies
+var errNoSTARTTLS = errors.New("STARTTLS not supported")
+
+// newPolicyError is the error of a delivery that failed a TLS policy, the
+// delivery is tried again like other transient errors
+func newPolicyError(reason string, err error) *smtpError {
+	// 454: TLS not available due to temporary reason, 4.7.5: cryptographic failure
+	e := newSMTPError(fmt.Errorf("4.7.5 %v policy failure: %w", reason, err), false, 454)
+	e.reason = reason
+	return e
+}
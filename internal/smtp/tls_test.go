@@ -0,0 +1,48 @@
+package smtp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRequireTLS(t *testing.T) {
+	assert.Equal(t, map[string]bool{"example.com": true, "example.org": true}, ParseRequireTLS("Example.com, example.org,"))
+	assert.Empty(t, ParseRequireTLS(""))
+}
+
+func TestTLSPolicy(t *testing.T) {
+	s := &sender{tls: TLSConfig{RequireTLS: map[string]bool{"bank.example.com": true}}}
+	enforce := &stsPolicy{mode: stsModeEnforce, mxs: []string{"*.example.com"}}
+	testingPolicy := &stsPolicy{mode: stsModeTesting, mxs: []string{"*.example.com"}}
+
+	p, err := s.tlsPolicy("example.org", "mx.example.org.", nil)
+	assert.Nil(t, err)
+	assert.False(t, p.enforced())
+
+	p, err = s.tlsPolicy("Bank.example.com", "mx.example.org.", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, ReasonTLSRequired, p.reason)
+	assert.True(t, p.enforced())
+
+	p, err = s.tlsPolicy("bank.example.com", "mx.example.com.", enforce)
+	assert.Nil(t, err)
+	assert.Equal(t, ReasonMTASTS, p.reason)
+	assert.True(t, p.enforced())
+
+	// MXs not in the policy are not used
+	_, err = s.tlsPolicy("example.com", "mx.example.org.", enforce)
+	assert.NotNil(t, err)
+	assert.Equal(t, ReasonMTASTS, err.Reason())
+	assert.False(t, err.IsPermanent())
+
+	// testing policies never fail
+	p, err = s.tlsPolicy("example.com", "mx.example.org.", testingPolicy)
+	assert.Nil(t, err)
+	assert.True(t, p.testing)
+	assert.False(t, p.enforced())
+
+	p, err = s.tlsPolicy("example.com", "mx.example.org.", &stsPolicy{mode: stsModeNone})
+	assert.Nil(t, err)
+	assert.False(t, p.enforced())
+}
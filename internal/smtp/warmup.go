@@ -0,0 +1,159 @@
+package smtp
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WarmupConfig caps the daily deliveries of new IPs to every provider
+type WarmupConfig struct {
+	// Start is the first day of warm-up by IP
+	Start map[string]time.Time
+	// Schedule are the max daily deliveries to a provider on every day of
+	// warm-up, IPs are not limited after the last day
+	Schedule []int
+	// Counter counts the daily deliveries of every sender, the
+	// deliveries are counted in memory by each sender when nil
+	Counter WarmupCounter
+}
+
+// WarmupCounter counts the daily deliveries of the IPs in warm-up
+type WarmupCounter interface {
+	// Add counts n deliveries of ip to provider on day when they are within
+	// max deliveries, false is returned when they are not counted
+	Add(ip string, provider string, day time.Time, n int, max int) (bool, error)
+}
+
+// warmupRetry is the delay of the deliveries whose warm-up count failed
+const warmupRetry = time.Minute
+
+// DefaultWarmupSchedule doubles the daily deliveries to a provider in two weeks
+var DefaultWarmupSchedule = []int{50, 100, 200, 400, 800, 1600, 3200, 6400, 12800, 25600, 51200, 102400, 204800, 409600}
+
+// ParseWarmupStart parses warm-up start days like 192.0.2.1=2021-07-01
+func ParseWarmupStart(s string) (map[string]time.Time, error) {
+	start := make(map[string]time.Time)
+	if s == "" {
+		return start, nil
+	}
+	for _, w := range strings.Split(s, ",") {
+		parts := strings.SplitN(strings.TrimSpace(w), "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid warm-up: %v", w)
+		}
+		ip := net.ParseIP(parts[0])
+		if ip == nil {
+			return nil, fmt.Errorf("invalid warm-up ip: %v", parts[0])
+		}
+		day, err := time.Parse("2006-01-02", parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid warm-up day of %v: %w", parts[0], err)
+		}
+		start[ip.String()] = day
+	}
+	return start, nil
+}
+
+// ParseWarmupSchedule parses daily limits like 50,100,200
+func ParseWarmupSchedule(s string) ([]int, error) {
+	var schedule []int
+	for _, l := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(l))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid warm-up limit: %v", l)
+		}
+		schedule = append(schedule, n)
+	}
+	return schedule, nil
+}
+
+// warmupUsage are the deliveries of an IP to a provider in a day
+type warmupUsage struct {
+	day   time.Time
+	count int
+}
+
+// memoryCounter counts the deliveries of a sender in memory,
+// counts start again when the sender restarts
+type memoryCounter struct {
+	mu    sync.Mutex
+	usage map[string]*warmupUsage
+}
+
+func (c *memoryCounter) Add(ip string, provider string, day time.Time, n int, max int) (bool, error) {
+	key := ip + "/" + provider
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	u, ok := c.usage[key]
+	if !ok || !u.day.Equal(day) {
+		u = &warmupUsage{day: day}
+		c.usage[key] = u
+	}
+	if u.count+n > max {
+		return false, nil
+	}
+	u.count += n
+	return true, nil
+}
+
+// warmup caps the daily deliveries of the IPs in warm-up
+type warmup struct {
+	config  WarmupConfig
+	counter WarmupCounter
+}
+
+func newWarmup(config WarmupConfig) *warmup {
+	counter := config.Counter
+	if counter == nil {
+		counter = &memoryCounter{usage: make(map[string]*warmupUsage)}
+	}
+	return &warmup{
+		config:  config,
+		counter: counter,
+	}
+}
+
+// limit returns the max deliveries of ip on day, false when ip is not in warm-up
+func (w *warmup) limit(ip net.IP, day time.Time) (int, bool) {
+	if ip == nil {
+		return 0, false
+	}
+	start, ok := w.config.Start[ip.String()]
+	if !ok {
+		return 0, false
+	}
+	n := int(day.Sub(start) / (24 * time.Hour))
+	if n < 0 {
+		n = 0
+	}
+	if n >= len(w.config.Schedule) {
+		return 0, false
+	}
+	return w.config.Schedule[n], true
+}
+
+// allow counts n deliveries of ip to provider when they are within the
+// daily limit of ip, otherwise it returns the start of the next day.
+// Deliveries that can't be counted are sent again after warmupRetry
+func (w *warmup) allow(ip net.IP, provider string, n int) (bool, time.Time) {
+	now := time.Now().UTC()
+	day := now.Truncate(24 * time.Hour)
+	max, ok := w.limit(ip, day)
+	if !ok {
+		return true, time.Time{}
+	}
+
+	ok, err := w.counter.Add(ip.String(), provider, day, n, max)
+	if err != nil {
+		log.Errorf("cannot count warm-up deliveries of %v to %v: %v", ip, provider, err)
+		return false, now.Add(warmupRetry)
+	}
+	if !ok {
+		return false, day.Add(24 * time.Hour)
+	}
+	return true, time.Time{}
+}
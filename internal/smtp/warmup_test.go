@@ -0,0 +1,84 @@
+package smtp
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWarmupStart(t *testing.T) {
+	start, err := ParseWarmupStart("192.0.2.1=2021-07-01")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]time.Time{"192.0.2.1": time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)}, start)
+
+	_, err = ParseWarmupStart("192.0.2.1=yesterday")
+	assert.NotNil(t, err)
+	_, err = ParseWarmupStart("mx=2021-07-01")
+	assert.NotNil(t, err)
+}
+
+func TestParseWarmupSchedule(t *testing.T) {
+	schedule, err := ParseWarmupSchedule("50, 100,200")
+	assert.Nil(t, err)
+	assert.Equal(t, []int{50, 100, 200}, schedule)
+
+	_, err = ParseWarmupSchedule("50,many")
+	assert.NotNil(t, err)
+}
+
+func TestWarmupAllow(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	ip := net.ParseIP("192.0.2.1")
+	w := newWarmup(WarmupConfig{
+		Start: map[string]time.Time{
+			ip.String(): today.Add(-24 * time.Hour),
+			"192.0.2.2": today.Add(-48 * time.Hour),
+		},
+		Schedule: []int{1, 3},
+	})
+
+	// second day of warm-up
+	ok, _ := w.allow(ip, "gmail", 2)
+	assert.True(t, ok)
+	ok, until := w.allow(ip, "gmail", 2)
+	assert.False(t, ok)
+	assert.Equal(t, today.Add(24*time.Hour), until)
+	ok, _ = w.allow(ip, "gmail", 1)
+	assert.True(t, ok)
+	// providers have their own limits
+	ok, _ = w.allow(ip, "outlook", 3)
+	assert.True(t, ok)
+
+	// warm-up is over and IPs not in warm-up are not limited
+	ok, _ = w.allow(net.ParseIP("192.0.2.2"), "gmail", 100)
+	assert.True(t, ok)
+	ok, _ = w.allow(net.ParseIP("192.0.2.3"), "gmail", 100)
+	assert.True(t, ok)
+	ok, _ = w.allow(nil, "gmail", 100)
+	assert.True(t, ok)
+}
+
+// failingCounter is a counter of an unreachable database
+type failingCounter struct{}
+
+func (failingCounter) Add(ip string, provider string, day time.Time, n int, max int) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func TestWarmupCounterError(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	ip := net.ParseIP("192.0.2.1")
+	w := newWarmup(WarmupConfig{
+		Start:    map[string]time.Time{ip.String(): today},
+		Schedule: []int{10},
+		Counter:  failingCounter{},
+	})
+
+	// deliveries not counted are sent again soon
+	ok, until := w.allow(ip, "gmail", 1)
+	assert.False(t, ok)
+	assert.WithinDuration(t, time.Now().Add(warmupRetry), until, time.Second)
+}
@@ -0,0 +1,273 @@
+package stats
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/events"
+	"kannon.gyozatech.dev/internal/metrics"
+	"kannon.gyozatech.dev/internal/smtp"
+)
+
+// Stats are the counts of the events of a domain or a message,
+// every accepted email is counted as sent
+type Stats struct {
+	Sent         int64
+	Delivered    int64
+	Bounced      int64
+	Opened       int64
+	Clicked      int64
+	Unsubscribed int64
+	Complained   int64
+	// Blocked are the bounces caused by the reputation of the sender
+	Blocked int64
+}
+
+// SMTPResponses are the responses of the MXs of a receiving provider with
+// the same SMTP code and enhanced status code
+type SMTPResponses struct {
+	Provider string
+	Code     int32
+	// EnhancedCode is the RFC 3463 enhanced status code, like 5.1.1,
+	// empty when the response has none
+	EnhancedCode string
+	Count        int64
+}
+
+// blocked is the rollup type of the bounces blocking the sender,
+// counted with the bounces
+const blocked = "blocked"
+
+// Manager manages the hourly rollups of email events
+// and the event history of messages
+type Manager interface {
+	Increment(event events.Event) error
+	Record(event events.Event) error
+	GetMessageEvents(domain string, messageID string) ([]sqlc.MessageEvent, error)
+	GetStats(domain string, from time.Time, to time.Time) (Stats, error)
+	GetDomainsStats(from time.Time, to time.Time) (map[string]Stats, error)
+	GetMessageStats(domain string, messageID string, from time.Time, to time.Time) (Stats, error)
+	// GetSMTPResponses returns the responses to the deliveries of a domain between from
+	// and to by provider, of provider or of every provider when empty
+	GetSMTPResponses(domain string, provider string, from time.Time, to time.Time) ([]SMTPResponses, error)
+}
+
+type manager struct {
+	db   *sqlc.Queries
+	conn *sql.DB
+}
+
+// NewStatsManager builds a Stats Manager
+func NewStatsManager(db *sql.DB) (Manager, error) {
+	return &manager{
+		db:   sqlc.New(metrics.InstrumentDB(db)),
+		conn: db,
+	}, nil
+}
+
+// Increment counts an event in the rollup of its message and hour,
+// bounces blocking the sender are counted in the blocked rollup too.
+// The responses of the MXs to deliveries and bounces are counted in the
+// rollup of their provider. The rollups are incremented in a transaction
+// once per event id, a redelivered event is not counted again
+func (m *manager) Increment(event events.Event) error {
+	tx, err := m.conn.BeginTx(context.TODO(), nil)
+	if err != nil {
+		return err
+	}
+	// rolling back a committed transaction does nothing
+	defer tx.Rollback()
+	if err := increment(sqlc.New(metrics.InstrumentTx(tx)), event); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// increment increments the rollups of an event not counted yet
+func increment(db *sqlc.Queries, event events.Event) error {
+	counted, err := db.CountEvent(context.TODO(), sqlc.CountEventParams{
+		ID:        event.ID,
+		MessageID: event.MessageID,
+	})
+	if err != nil {
+		return err
+	}
+	if counted == 0 {
+		return nil
+	}
+	err = db.IncrementStats(context.TODO(), sqlc.IncrementStatsParams{
+		Domain:    event.Domain(),
+		MessageID: event.MessageID,
+		Type:      string(event.Type),
+		Timestamp: event.Timestamp,
+	})
+	if err != nil {
+		return err
+	}
+	if r, ok := smtpResponse(event); ok {
+		err := db.IncrementSMTPResponses(context.TODO(), sqlc.IncrementSMTPResponsesParams{
+			Domain:       event.Domain(),
+			Provider:     r.Provider,
+			Code:         r.Code,
+			EnhancedCode: r.EnhancedCode,
+			Timestamp:    event.Timestamp,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if isBlock, _ := event.Data["blocked"].(bool); event.Type == events.Bounced && isBlock {
+		return db.IncrementStats(context.TODO(), sqlc.IncrementStatsParams{
+			Domain:    event.Domain(),
+			MessageID: event.MessageID,
+			Type:      blocked,
+			Timestamp: event.Timestamp,
+		})
+	}
+	return nil
+}
+
+// Record adds an event to the history of its message recipient
+func (m *manager) Record(event events.Event) error {
+	data := event.Data
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return m.db.CreateMessageEvent(context.TODO(), sqlc.CreateMessageEventParams{
+		MessageID: event.MessageID,
+		Email:     event.Email,
+		Type:      string(event.Type),
+		Data:      jsonData,
+		Timestamp: event.Timestamp,
+	})
+}
+
+// GetMessageEvents returns the event history of a message of a domain
+func (m *manager) GetMessageEvents(domain string, messageID string) ([]sqlc.MessageEvent, error) {
+	return m.db.GetMessageEvents(context.TODO(), sqlc.GetMessageEventsParams{
+		Domain:    domain,
+		MessageID: messageID,
+	})
+}
+
+// GetStats returns the stats of a domain between from and to,
+// rollups are hourly so from is rounded down to the hour
+func (m *manager) GetStats(domain string, from time.Time, to time.Time) (Stats, error) {
+	rows, err := m.db.GetStats(context.TODO(), sqlc.GetStatsParams{
+		Domain:    domain,
+		StartTime: from,
+		EndTime:   to,
+	})
+	if err != nil {
+		return Stats{}, err
+	}
+	counts := make(map[events.Type]int64, len(rows))
+	for _, r := range rows {
+		counts[events.Type(r.Type)] = r.Count
+	}
+	return buildStats(counts), nil
+}
+
+// GetDomainsStats returns the stats of every domain with events between from and to
+func (m *manager) GetDomainsStats(from time.Time, to time.Time) (map[string]Stats, error) {
+	rows, err := m.db.GetDomainsStats(context.TODO(), sqlc.GetDomainsStatsParams{
+		StartTime: from,
+		EndTime:   to,
+	})
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]map[events.Type]int64)
+	for _, r := range rows {
+		if counts[r.Domain] == nil {
+			counts[r.Domain] = make(map[events.Type]int64)
+		}
+		counts[r.Domain][events.Type(r.Type)] = r.Count
+	}
+	res := make(map[string]Stats, len(counts))
+	for domain, c := range counts {
+		res[domain] = buildStats(c)
+	}
+	return res, nil
+}
+
+// GetMessageStats returns the stats of a message between from and to
+func (m *manager) GetMessageStats(domain string, messageID string, from time.Time, to time.Time) (Stats, error) {
+	rows, err := m.db.GetMessageStats(context.TODO(), sqlc.GetMessageStatsParams{
+		Domain:    domain,
+		MessageID: messageID,
+		StartTime: from,
+		EndTime:   to,
+	})
+	if err != nil {
+		return Stats{}, err
+	}
+	counts := make(map[events.Type]int64, len(rows))
+	for _, r := range rows {
+		counts[events.Type(r.Type)] = r.Count
+	}
+	return buildStats(counts), nil
+}
+
+// GetSMTPResponses returns the responses to the deliveries of a domain between from and to,
+// rollups are hourly so from is rounded down to the hour
+func (m *manager) GetSMTPResponses(domain string, provider string, from time.Time, to time.Time) ([]SMTPResponses, error) {
+	rows, err := m.db.GetSMTPResponses(context.TODO(), sqlc.GetSMTPResponsesParams{
+		Domain:    domain,
+		Provider:  provider,
+		StartTime: from,
+		EndTime:   to,
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]SMTPResponses, 0, len(rows))
+	for _, r := range rows {
+		res = append(res, SMTPResponses{
+			Provider:     r.Provider,
+			Code:         r.Code,
+			EnhancedCode: r.EnhancedCode,
+			Count:        r.Count,
+		})
+	}
+	return res, nil
+}
+
+// smtpResponse returns the response of a MX to the delivery of an event, the
+// events of the sends without a MX response, like asynchronous bounces, have no provider
+func smtpResponse(event events.Event) (SMTPResponses, bool) {
+	provider, _ := event.Data["provider"].(string)
+	if provider == "" {
+		return SMTPResponses{}, false
+	}
+	switch event.Type {
+	case events.Delivered:
+		// 250: requested mail action completed
+		return SMTPResponses{Provider: provider, Code: 250}, true
+	case events.Bounced:
+		code, _ := event.Data["code"].(uint32)
+		msg, _ := event.Data["msg"].(string)
+		return SMTPResponses{Provider: provider, Code: int32(code), EnhancedCode: smtp.EnhancedStatus(msg)}, true
+	}
+	return SMTPResponses{}, false
+}
+
+func buildStats(counts map[events.Type]int64) Stats {
+	return Stats{
+		Sent:         counts[events.Accepted],
+		Delivered:    counts[events.Delivered],
+		Bounced:      counts[events.Bounced],
+		Opened:       counts[events.Opened],
+		Clicked:      counts[events.Clicked],
+		Unsubscribed: counts[events.Unsubscribed],
+		Complained:   counts[events.Complained],
+		Blocked:      counts[blocked],
+	}
+}
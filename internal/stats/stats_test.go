@@ -0,0 +1,39 @@
+package stats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"kannon.gyozatech.dev/internal/events"
+)
+
+func TestBuildStats(t *testing.T) {
+	s := buildStats(map[events.Type]int64{
+		events.Accepted:  10,
+		events.Delivered: 8,
+		events.Bounced:   2,
+		events.Opened:    5,
+		blocked:          1,
+	})
+	assert.Equal(t, Stats{Sent: 10, Delivered: 8, Bounced: 2, Opened: 5, Blocked: 1}, s)
+}
+
+func TestSMTPResponse(t *testing.T) {
+	r, ok := smtpResponse(events.Event{Type: events.Delivered, Data: map[string]interface{}{"provider": "gmail"}})
+	assert.True(t, ok)
+	assert.Equal(t, SMTPResponses{Provider: "gmail", Code: 250}, r)
+
+	r, ok = smtpResponse(events.Event{Type: events.Bounced, Data: map[string]interface{}{
+		"provider": "outlook",
+		"code":     uint32(550),
+		"msg":      "550 5.7.1 Service unavailable, client host blocked",
+	}})
+	assert.True(t, ok)
+	assert.Equal(t, SMTPResponses{Provider: "outlook", Code: 550, EnhancedCode: "5.7.1"}, r)
+
+	// asynchronous bounces
+	_, ok = smtpResponse(events.Event{Type: events.Bounced, Data: map[string]interface{}{"code": uint32(550)}})
+	assert.False(t, ok)
+	_, ok = smtpResponse(events.Event{Type: events.Opened})
+	assert.False(t, ok)
+}
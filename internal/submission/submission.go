@@ -0,0 +1,180 @@
+// Package submission parses the messages submitted by the SMTP clients of the
+// submission server into the parts of a send, like the html, the text and the attachments
+package submission
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"unicode/utf8"
+)
+
+// Message is a submitted message
+type Message struct {
+	// From is the sender of the From header, nil when missing
+	From *mail.Address
+	// ReplyTo is the address of the Reply-To header, empty when missing
+	ReplyTo string
+	Subject string
+	// MessageID is the Message-ID header without angle brackets, empty when missing
+	MessageID string
+	// HTML is the html body, or the text body escaped when the message has no html
+	HTML string
+	Text string
+	// Attachments are the parts of the message that are not the html and the text bodies
+	Attachments []Attachment
+	// Headers are the X- headers of the message, like X-Campaign
+	Headers map[string]string
+}
+
+// Attachment is a file attached to a message, inline images are referenced by the html as cid:<filename>
+type Attachment struct {
+	Filename string
+	Content  []byte
+	Inline   bool
+}
+
+// Parse parses a submitted message, bodies are converted to UTF-8 from UTF-8,
+// US-ASCII and ISO-8859-1, other charsets are rejected
+func Parse(r io.Reader) (Message, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return Message{}, err
+	}
+
+	var res Message
+	if from, err := msg.Header.AddressList("From"); err == nil && len(from) > 0 {
+		res.From = from[0]
+	}
+	if replyTo, err := msg.Header.AddressList("Reply-To"); err == nil && len(replyTo) > 0 {
+		res.ReplyTo = replyTo[0].Address
+	}
+	dec := &mime.WordDecoder{}
+	if res.Subject, err = dec.DecodeHeader(msg.Header.Get("Subject")); err != nil {
+		res.Subject = msg.Header.Get("Subject")
+	}
+	res.MessageID = strings.Trim(strings.TrimSpace(msg.Header.Get("Message-Id")), "<>")
+	for name, values := range msg.Header {
+		if strings.HasPrefix(name, "X-") && len(values) > 0 {
+			if res.Headers == nil {
+				res.Headers = make(map[string]string)
+			}
+			res.Headers[name] = values[0]
+		}
+	}
+
+	// inline parts are referenced by their Content-ID, renamed to their filename
+	cids := make(map[string]string)
+	if err := res.parsePart(textproto.MIMEHeader(msg.Header), msg.Body, cids); err != nil {
+		return Message{}, err
+	}
+	if res.HTML == "" && res.Text != "" {
+		res.HTML = "<pre>" + html.EscapeString(res.Text) + "</pre>"
+	}
+	for cid, filename := range cids {
+		res.HTML = strings.ReplaceAll(res.HTML, "cid:"+cid, "cid:"+filename)
+	}
+	return res, nil
+}
+
+// parsePart adds a part of the message to m, multipart parts are parsed recursively
+func (m *Message) parsePart(header textproto.MIMEHeader, body io.Reader, cids map[string]string) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := m.parsePart(part.Header, part, cids); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := ioutil.ReadAll(decodeTransfer(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+	disposition, dparams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := dparams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+	if decoded, err := new(mime.WordDecoder).DecodeHeader(filename); err == nil {
+		filename = decoded
+	}
+
+	isBody := disposition != "attachment" && filename == ""
+	if isBody && mediaType == "text/html" && m.HTML == "" {
+		text, err := decodeCharset(data, params["charset"])
+		m.HTML = text
+		return err
+	}
+	if isBody && mediaType == "text/plain" && m.Text == "" {
+		text, err := decodeCharset(data, params["charset"])
+		m.Text = text
+		return err
+	}
+
+	if filename == "" {
+		filename = fmt.Sprintf("attachment-%v", len(m.Attachments)+1)
+		if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+			filename += exts[0]
+		}
+	}
+	a := Attachment{Filename: filename, Content: data}
+	if cid := strings.Trim(header.Get("Content-Id"), "<> "); cid != "" && disposition != "attachment" {
+		a.Inline = true
+		cids[cid] = filename
+	}
+	m.Attachments = append(m.Attachments, a)
+	return nil
+}
+
+// decodeTransfer decodes the base64 and quoted-printable bodies
+func decodeTransfer(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	}
+	return body
+}
+
+// decodeCharset converts a body in charset to UTF-8
+func decodeCharset(data []byte, charset string) (string, error) {
+	switch strings.ToLower(charset) {
+	case "", "utf-8", "us-ascii":
+		if !utf8.Valid(data) {
+			return "", fmt.Errorf("body is not valid UTF-8")
+		}
+		return string(data), nil
+	case "iso-8859-1", "latin1":
+		// the bytes of ISO-8859-1 are the first 256 code points of unicode
+		var b bytes.Buffer
+		for _, c := range data {
+			b.WriteRune(rune(c))
+		}
+		return b.String(), nil
+	}
+	return "", fmt.Errorf("unsupported charset %v", charset)
+}
@@ -0,0 +1,82 @@
+package submission
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const multipartMessage = "From: =?utf-8?q?Caf=C3=A9?= <news@kannon.io>\r\n" +
+	"To: test@test.com\r\n" +
+	"Reply-To: support@kannon.io\r\n" +
+	"Subject: =?utf-8?B?Q2lhbyDwn5GL?=\r\n" +
+	"Message-ID: <1234@app.kannon.io>\r\n" +
+	"X-Campaign: august\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=mixed\r\n" +
+	"\r\n" +
+	"--mixed\r\n" +
+	"Content-Type: multipart/related; boundary=related\r\n" +
+	"\r\n" +
+	"--related\r\n" +
+	"Content-Type: multipart/alternative; boundary=alt\r\n" +
+	"\r\n" +
+	"--alt\r\n" +
+	"Content-Type: text/plain; charset=iso-8859-1\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Caf=E9\r\n" +
+	"--alt\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"PHA+Q2lhbzwvcD48aW1nIHNyYz0iY2lkOmxvZ29AYXBwIj4=\r\n" +
+	"--alt--\r\n" +
+	"--related\r\n" +
+	"Content-Type: image/png; name=logo.png\r\n" +
+	"Content-ID: <logo@app>\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"iVBORw==\r\n" +
+	"--related--\r\n" +
+	"--mixed\r\n" +
+	"Content-Type: application/pdf\r\n" +
+	"Content-Disposition: attachment; filename=\"invoice.pdf\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERg==\r\n" +
+	"--mixed--\r\n"
+
+func TestParse(t *testing.T) {
+	m, err := Parse(strings.NewReader(multipartMessage))
+	assert.Nil(t, err)
+	assert.Equal(t, "Café", m.From.Name)
+	assert.Equal(t, "news@kannon.io", m.From.Address)
+	assert.Equal(t, "support@kannon.io", m.ReplyTo)
+	assert.Equal(t, "Ciao 👋", m.Subject)
+	assert.Equal(t, "1234@app.kannon.io", m.MessageID)
+	assert.Equal(t, map[string]string{"X-Campaign": "august"}, m.Headers)
+	assert.Equal(t, "Café", strings.TrimSpace(m.Text))
+	assert.Equal(t, `<p>Ciao</p><img src="cid:logo.png">`, m.HTML)
+	assert.Equal(t, []Attachment{
+		{Filename: "logo.png", Content: []byte{0x89, 'P', 'N', 'G'}, Inline: true},
+		{Filename: "invoice.pdf", Content: []byte("%PDF")},
+	}, m.Attachments)
+}
+
+func TestParseText(t *testing.T) {
+	m, err := Parse(strings.NewReader("From: app@kannon.io\r\nSubject: Report\r\n\r\n1 < 2\r\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "1 < 2\r\n", m.Text)
+	assert.Equal(t, "<pre>1 &lt; 2\r\n</pre>", m.HTML)
+	assert.Empty(t, m.MessageID)
+	assert.Nil(t, m.Headers)
+}
+
+func TestParseCharset(t *testing.T) {
+	_, err := Parse(strings.NewReader("From: app@kannon.io\r\nContent-Type: text/plain; charset=koi8-r\r\n\r\n\xf0\xd2\xc9\r\n"))
+	assert.NotNil(t, err)
+	_, err = Parse(strings.NewReader("From: app@kannon.io\r\n\r\n\xff\r\n"))
+	assert.NotNil(t, err)
+}
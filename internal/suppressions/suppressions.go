@@ -0,0 +1,76 @@
+package suppressions
+
+import (
+	"context"
+	"database/sql"
+
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/metrics"
+)
+
+// Manager manages the suppression list of domains,
+// suppressed recipients never receive emails from the domain
+type Manager interface {
+	Suppress(domain string, email string, reason sqlc.SuppressionReason) (sqlc.Suppression, error)
+	Unsuppress(domain string, email string) (bool, error)
+	GetSuppressions(domain string) ([]sqlc.Suppression, error)
+	IsRecipientSuppressed(messageID int32, email string) (bool, error)
+	SuppressMessageRecipient(messageID string, email string, reason sqlc.SuppressionReason) error
+}
+
+type manager struct {
+	db *sqlc.Queries
+}
+
+// NewSuppressionManager builds a Suppression Manager
+func NewSuppressionManager(db *sql.DB) (Manager, error) {
+	return &manager{
+		db: sqlc.New(metrics.InstrumentDB(db)),
+	}, nil
+}
+
+// Suppress adds an email to the suppression list of a domain,
+// the reason of an already suppressed email is updated
+func (m *manager) Suppress(domain string, email string, reason sqlc.SuppressionReason) (sqlc.Suppression, error) {
+	return m.db.CreateSuppression(context.TODO(), sqlc.CreateSuppressionParams{
+		Domain: domain,
+		Email:  email,
+		Reason: reason,
+	})
+}
+
+// Unsuppress removes an email from the suppression list of a domain,
+// returns false when the email was not suppressed
+func (m *manager) Unsuppress(domain string, email string) (bool, error) {
+	rows, err := m.db.DeleteSuppression(context.TODO(), sqlc.DeleteSuppressionParams{
+		Domain: domain,
+		Email:  email,
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+func (m *manager) GetSuppressions(domain string) ([]sqlc.Suppression, error) {
+	return m.db.GetSuppressions(context.TODO(), domain)
+}
+
+// IsRecipientSuppressed checks if a recipient of a message
+// is in the suppression list of the message domain
+func (m *manager) IsRecipientSuppressed(messageID int32, email string) (bool, error) {
+	return m.db.IsRecipientSuppressed(context.TODO(), sqlc.IsRecipientSuppressedParams{
+		MessageID: messageID,
+		Email:     email,
+	})
+}
+
+// SuppressMessageRecipient adds a recipient of a message to the suppression
+// list of the message domain
+func (m *manager) SuppressMessageRecipient(messageID string, email string, reason sqlc.SuppressionReason) error {
+	return m.db.SuppressMessageRecipient(context.TODO(), sqlc.SuppressMessageRecipientParams{
+		Email:     email,
+		Reason:    reason,
+		MessageID: messageID,
+	})
+}
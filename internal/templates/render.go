@@ -0,0 +1,85 @@
+package templates
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// RenderMode defines how missing fields are rendered
+type RenderMode int
+
+const (
+	// Lenient renders missing fields as empty strings
+	Lenient RenderMode = iota
+	// Strict returns an error when a field is missing
+	Strict
+)
+
+// EmailField is the field always set to the recipient address
+const EmailField = "email"
+
+// fieldRegexp matches {{ field }} and {{{ field }}} placeholders,
+// triple braces are not html escaped
+var fieldRegexp = regexp.MustCompile(`\{\{(\{?)\s*([A-Za-z0-9_.\-]+)\s*(\}?)\}\}`)
+
+// MissingFieldsError is returned in Strict mode when
+// a template uses fields that are not provided
+type MissingFieldsError struct {
+	Fields []string
+}
+
+func (e MissingFieldsError) Error() string {
+	return fmt.Sprintf("missing fields: %v", strings.Join(e.Fields, ", "))
+}
+
+// Render substitutes {{ field }} placeholders of a plain-text template
+func Render(tmpl string, fields map[string]string, mode RenderMode) (string, error) {
+	return render(tmpl, fields, mode, false)
+}
+
+// RenderHTML substitutes {{ field }} placeholders of an html template,
+// values are html escaped unless the placeholder uses triple braces
+func RenderHTML(tmpl string, fields map[string]string, mode RenderMode) (string, error) {
+	return render(tmpl, fields, mode, true)
+}
+
+// Fields returns the names of the fields used by a template
+func Fields(tmpl string) []string {
+	var res []string
+	seen := make(map[string]bool)
+	for _, m := range fieldRegexp.FindAllStringSubmatch(tmpl, -1) {
+		if !seen[m[2]] {
+			seen[m[2]] = true
+			res = append(res, m[2])
+		}
+	}
+	return res
+}
+
+func render(tmpl string, fields map[string]string, mode RenderMode, escape bool) (string, error) {
+	var missing []string
+	res := fieldRegexp.ReplaceAllStringFunc(tmpl, func(placeholder string) string {
+		m := fieldRegexp.FindStringSubmatch(placeholder)
+		raw := m[1] == "{" && m[3] == "}"
+		if (m[1] == "") != (m[3] == "") {
+			// unbalanced braces are not a placeholder
+			return placeholder
+		}
+		value, ok := fields[m[2]]
+		if !ok {
+			missing = append(missing, m[2])
+			return ""
+		}
+		if escape && !raw {
+			return html.EscapeString(value)
+		}
+		return value
+	})
+
+	if mode == Strict && len(missing) > 0 {
+		return "", MissingFieldsError{Fields: missing}
+	}
+	return res, nil
+}
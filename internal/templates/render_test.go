@@ -0,0 +1,61 @@
+package templates
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	fields := map[string]string{
+		"name":    "Ludovico",
+		"surname": "Russo",
+	}
+
+	examples := []struct {
+		tmpl string
+		exp  string
+	}{
+		{"Hello {{name}}", "Hello Ludovico"},
+		{"Hello {{ name }} {{ surname }}", "Hello Ludovico Russo"},
+		{"Hello {{ missing }}", "Hello "},
+		{"no fields", "no fields"},
+		{"Hello {{ name }", "Hello {{ name }"},
+	}
+
+	for _, tt := range examples {
+		t.Run(tt.tmpl, func(t *testing.T) {
+			res, err := Render(tt.tmpl, fields, Lenient)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.exp, res)
+		})
+	}
+}
+
+func TestRenderStrict(t *testing.T) {
+	_, err := Render("Hello {{ name }} {{ surname }}", map[string]string{"name": "Ludovico"}, Strict)
+	assert.Equal(t, MissingFieldsError{Fields: []string{"surname"}}, err)
+
+	res, err := Render("Hello {{ name }}", map[string]string{"name": "Ludovico"}, Strict)
+	assert.Nil(t, err)
+	assert.Equal(t, "Hello Ludovico", res)
+}
+
+func TestRenderHTMLEscape(t *testing.T) {
+	fields := map[string]string{
+		"name": "<b>Ludovico</b>",
+	}
+
+	res, err := RenderHTML("<p>{{ name }}</p>", fields, Lenient)
+	assert.Nil(t, err)
+	assert.Equal(t, "<p>&lt;b&gt;Ludovico&lt;/b&gt;</p>", res)
+
+	res, err = RenderHTML("<p>{{{ name }}}</p>", fields, Lenient)
+	assert.Nil(t, err)
+	assert.Equal(t, "<p><b>Ludovico</b></p>", res)
+}
+
+func TestFields(t *testing.T) {
+	fields := Fields("{{ name }} {{{ link }}} {{name}}")
+	assert.Equal(t, []string{"name", "link"}, fields)
+}
@@ -2,19 +2,36 @@ package templates
 
 import (
 	"database/sql"
+	"errors"
 
 	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/metrics"
 )
 
-// Manager implement interface to manage Templates
+// ErrVersionNotFound is returned when a template version does not exist
+var ErrVersionNotFound = errors.New("template version not found")
+
+// ErrNameTaken is returned when a domain has a template with the same name
+var ErrNameTaken = errors.New("template name already used")
+
+// Manager implement interface to manage Templates,
+// every update of a template creates a new immutable version.
+// Named templates are managed with the admin API, the others
+// are the templates of the SendHTML requests
 type Manager interface {
 	FindTemplate(domain string, templateID string) (sqlc.Template, error)
-	CreateTemplate(HTML string, domain string) (sqlc.Template, error)
+	FindTemplateVersion(domain string, templateID string, version uint) (sqlc.Template, error)
+	CreateTemplate(HTML string, text string, domain string) (sqlc.Template, error)
+	CreateNamedTemplate(domain string, name string, HTML string, text string) (sqlc.Template, error)
+	GetTemplates(domain string) ([]sqlc.Template, error)
+	DeleteTemplate(domain string, templateID string) error
+	UpdateTemplate(domain string, templateID string, HTML string, text string) (sqlc.Template, error)
+	RollbackTemplate(domain string, templateID string, version uint) (sqlc.Template, error)
 }
 
 // NewTemplateManager builds a Template Manager
 func NewTemplateManager(db *sql.DB) (Manager, error) {
 	return &manager{
-		db: sqlc.New(db),
+		db: sqlc.New(metrics.InstrumentDB(db)),
 	}, nil
 }
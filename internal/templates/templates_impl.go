@@ -2,8 +2,11 @@ package templates
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 
+	"github.com/lib/pq"
 	"github.com/lucsky/cuid"
 	"kannon.gyozatech.dev/generated/sqlc"
 )
@@ -23,14 +26,122 @@ func (m *manager) FindTemplate(domain string, templateID string) (sqlc.Template,
 	return template, nil
 }
 
-func (m *manager) CreateTemplate(html string, domain string) (sqlc.Template, error) {
+func (m *manager) FindTemplateVersion(domain string, templateID string, version uint) (sqlc.Template, error) {
+	template, err := m.db.FindTemplateVersion(context.TODO(), sqlc.FindTemplateVersionParams{
+		TemplateID: templateID,
+		Domain:     domain,
+		Version:    int32(version),
+	})
+	if err != nil {
+		return sqlc.Template{}, err
+	}
+	return template, nil
+}
+
+func (m *manager) CreateTemplate(html string, text string, domain string) (sqlc.Template, error) {
+	return m.createTemplate(domain, "", html, text)
+}
+
+// CreateNamedTemplate creates a template listed by GetTemplates,
+// names are unique among the templates of a domain
+func (m *manager) CreateNamedTemplate(domain string, name string, html string, text string) (sqlc.Template, error) {
+	// the unique index of the names rejects the concurrent creations
+	// of the same name, the lookup only avoids a failed insert
+	_, err := m.db.FindTemplateByName(context.TODO(), sqlc.FindTemplateByNameParams{
+		Domain: domain,
+		Name:   name,
+	})
+	if err == nil {
+		return sqlc.Template{}, ErrNameTaken
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return sqlc.Template{}, err
+	}
+	return m.createTemplate(domain, name, html, text)
+}
+
+func (m *manager) createTemplate(domain string, name string, html string, text string) (sqlc.Template, error) {
 	template, err := m.db.CreateTemplate(context.TODO(), sqlc.CreateTemplateParams{
 		TemplateID: fmt.Sprintf("template_%v@%v", cuid.New(), domain),
 		Html:       html,
+		Text:       text,
+		Domain:     domain,
+		Name:       name,
+	})
+	if isNameTaken(err) {
+		return sqlc.Template{}, ErrNameTaken
+	}
+	if err != nil {
+		return sqlc.Template{}, err
+	}
+	return template, nil
+}
+
+// isNameTaken reports if err is the violation of the unique index of the template names
+func isNameTaken(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "templates_domain_name_idx"
+}
+
+// GetTemplates returns the active versions of the named templates of a domain
+func (m *manager) GetTemplates(domain string) ([]sqlc.Template, error) {
+	return m.db.GetTemplates(context.TODO(), domain)
+}
+
+// DeleteTemplate deletes a template, it cannot be sent anymore but the
+// emails not sent yet render their version. Missing templates are sql.ErrNoRows
+func (m *manager) DeleteTemplate(domain string, templateID string) error {
+	rows, err := m.db.DeleteTemplate(context.TODO(), sqlc.DeleteTemplateParams{
+		TemplateID: templateID,
+		Domain:     domain,
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// UpdateTemplate creates a new version of a template and makes it active,
+// pools created with previous versions still render their own version
+func (m *manager) UpdateTemplate(domain string, templateID string, html string, text string) (sqlc.Template, error) {
+	if _, err := m.FindTemplate(domain, templateID); err != nil {
+		return sqlc.Template{}, err
+	}
+
+	template, err := m.db.CreateTemplateVersion(context.TODO(), sqlc.CreateTemplateVersionParams{
+		TemplateID: templateID,
+		Html:       html,
+		Text:       text,
+		Domain:     domain,
+	})
+	if err != nil {
+		return sqlc.Template{}, err
+	}
+
+	return m.RollbackTemplate(domain, templateID, uint(template.Version))
+}
+
+// RollbackTemplate sets the active version of a template
+func (m *manager) RollbackTemplate(domain string, templateID string, version uint) (sqlc.Template, error) {
+	template, err := m.FindTemplateVersion(domain, templateID, version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sqlc.Template{}, ErrVersionNotFound
+	}
+	if err != nil {
+		return sqlc.Template{}, err
+	}
+
+	_, err = m.db.SetActiveTemplateVersion(context.TODO(), sqlc.SetActiveTemplateVersionParams{
+		Version:    template.Version,
+		TemplateID: templateID,
 		Domain:     domain,
 	})
 	if err != nil {
 		return sqlc.Template{}, err
 	}
+	template.Active = true
 	return template, nil
 }
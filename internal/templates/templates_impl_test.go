@@ -0,0 +1,18 @@
+package templates
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsNameTaken(t *testing.T) {
+	assert.True(t, isNameTaken(fmt.Errorf("insert: %w", &pq.Error{Code: "23505", Constraint: "templates_domain_name_idx"})))
+	assert.False(t, isNameTaken(&pq.Error{Code: "23505", Constraint: "templates_template_id_version_idx"}))
+	assert.False(t, isNameTaken(&pq.Error{Code: "23503", Constraint: "templates_domain_name_idx"}))
+	assert.False(t, isNameTaken(errors.New("templates_domain_name_idx")))
+	assert.False(t, isNameTaken(nil))
+}
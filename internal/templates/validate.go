@@ -0,0 +1,142 @@
+package templates
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// voidElements are the html elements without a closing tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// optionalClose are the html elements whose closing tag can be omitted
+var optionalClose = map[string]bool{
+	"html": true, "head": true, "body": true, "p": true, "li": true, "dt": true, "dd": true,
+	"option": true, "optgroup": true, "colgroup": true, "caption": true, "thead": true,
+	"tbody": true, "tfoot": true, "tr": true, "td": true, "th": true,
+}
+
+// InvalidTemplateError is returned by Validate for templates that cannot be rendered
+type InvalidTemplateError struct {
+	Reason string
+}
+
+func (e InvalidTemplateError) Error() string {
+	return "invalid template: " + e.Reason
+}
+
+// Validate checks that the html of a template is not empty and has its elements closed,
+// and that the placeholders of the html and the text are well formed. With fields, the
+// placeholders can only use them and the email field
+func Validate(htmlBody string, text string, fields []string) error {
+	if strings.TrimSpace(htmlBody) == "" {
+		return InvalidTemplateError{Reason: "html is empty"}
+	}
+	if err := validatePlaceholders(htmlBody); err != nil {
+		return err
+	}
+	if err := validatePlaceholders(text); err != nil {
+		return err
+	}
+	if err := validateHTML(htmlBody); err != nil {
+		return err
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+	allowed := map[string]bool{EmailField: true}
+	for _, f := range fields {
+		allowed[f] = true
+	}
+	var unknown []string
+	for _, f := range Fields(htmlBody + "\n" + text) {
+		if !allowed[f] {
+			unknown = append(unknown, f)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return InvalidTemplateError{Reason: fmt.Sprintf("unknown fields: %v", strings.Join(unknown, ", "))}
+	}
+	return nil
+}
+
+// validatePlaceholders checks that every {{ starts a placeholder with balanced braces
+func validatePlaceholders(tmpl string) error {
+	valid := make(map[int]bool)
+	for _, m := range fieldRegexp.FindAllStringSubmatchIndex(tmpl, -1) {
+		// unbalanced braces are not a placeholder, see render
+		if (m[3] == m[2]) == (m[7] == m[6]) {
+			valid[m[0]] = true
+		}
+	}
+	for i := 0; i < len(tmpl); i++ {
+		if !strings.HasPrefix(tmpl[i:], "{{") {
+			continue
+		}
+		if !valid[i] {
+			return InvalidTemplateError{Reason: fmt.Sprintf("invalid placeholder %q", snippet(tmpl[i:]))}
+		}
+		// skip the placeholder
+		end := strings.Index(tmpl[i:], "}}")
+		i += end + 1
+	}
+	return nil
+}
+
+// validateHTML checks that the elements of an html body are closed in
+// order, except the void elements and those with an optional closing tag
+func validateHTML(body string) error {
+	var open []string
+	z := html.NewTokenizer(strings.NewReader(body))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() != io.EOF {
+				return InvalidTemplateError{Reason: z.Err().Error()}
+			}
+			for _, tag := range open {
+				if !optionalClose[tag] {
+					return InvalidTemplateError{Reason: fmt.Sprintf("<%v> is not closed", tag)}
+				}
+			}
+			return nil
+		case html.StartTagToken:
+			name, _ := z.TagName()
+			if tag := string(name); !voidElements[tag] {
+				open = append(open, tag)
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			if voidElements[tag] {
+				continue
+			}
+			i := len(open) - 1
+			for i >= 0 && open[i] != tag {
+				if !optionalClose[open[i]] {
+					return InvalidTemplateError{Reason: fmt.Sprintf("</%v> closes <%v>", tag, open[i])}
+				}
+				i--
+			}
+			if i < 0 {
+				return InvalidTemplateError{Reason: fmt.Sprintf("</%v> is not opened", tag)}
+			}
+			open = open[:i]
+		}
+	}
+}
+
+func snippet(s string) string {
+	if len(s) > 20 {
+		return s[:20] + "..."
+	}
+	return s
+}
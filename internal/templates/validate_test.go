@@ -0,0 +1,43 @@
+package templates
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	valid := []string{
+		"<p>Hello {{ name }}</p>",
+		"<html><body><p>Hello {{{ link }}}<br><img src=\"logo.png\"></body></html>",
+		"<table><tr><td>one<td>two</table>",
+		"<ul><li>one<li>two</ul>",
+		"<!--[if mso]><table><![endif]--><div>{{name}}</div>",
+		"<style>p { color: red; }</style><p>hi",
+	}
+	for _, html := range valid {
+		assert.Nil(t, Validate(html, "Hello {{ name }}", nil), html)
+	}
+
+	invalid := map[string]string{
+		"":                           "html is empty",
+		"<p>Hello {{ name }</p>":     `invalid placeholder "{{ name }</p>"`,
+		"<p>Hello {{{ name }}</p>":   `invalid placeholder "{{{ name }}</p>"`,
+		"<p>{{ first name }}</p>":    `invalid placeholder "{{ first name }}</p>"`,
+		"<div><span>Hello</div>":     "</div> closes <span>",
+		"<div>Hello":                 "<div> is not closed",
+		"<p>Hello</p></div>":         "</div> is not opened",
+		"<table><tr><td>Hello</div>": "</div> closes <table>",
+	}
+	for html, reason := range invalid {
+		assert.Equal(t, InvalidTemplateError{Reason: reason}, Validate(html, "", nil), html)
+	}
+
+	assert.Equal(t, InvalidTemplateError{Reason: `invalid placeholder "{{ name"`}, Validate("<p>hi</p>", "{{ name", nil))
+}
+
+func TestValidateFields(t *testing.T) {
+	assert.Nil(t, Validate("<p>{{ name }} {{ email }}</p>", "{{ name }}", []string{"name"}))
+	assert.Equal(t, InvalidTemplateError{Reason: "unknown fields: link, surname"},
+		Validate("<p>{{ name }} {{ surname }}</p>", "{{{ link }}}", []string{"name"}))
+}
@@ -0,0 +1,98 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+// Config are the PEM files of the TLS connections of a service,
+// like APP_TLS_CERTFILE, TLS is disabled when no file is set
+type Config struct {
+	// CertFile and KeyFile are the certificate of the service, servers
+	// present it to clients and clients present it to mTLS servers
+	CertFile string
+	KeyFile  string
+	// CAFile are the CAs of the peers: servers require client certificates
+	// signed by them, clients verify the server with them instead of the system CAs
+	CAFile string
+	// ServerName is the name verified in the server certificate by clients,
+	// the host of the address when empty
+	ServerName string
+}
+
+// Enabled reports if TLS is configured
+func (c Config) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != "" || c.CAFile != ""
+}
+
+// Validate checks that the certificate and its key are set together
+func (c Config) Validate() error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return errors.New("cert file and key file must be set together")
+	}
+	return nil
+}
+
+// ServerConfig returns the TLS config of a server with the certificate of c,
+// client certificates signed by the CAs are required when CAFile is set
+func (c Config) ServerConfig() (*tls.Config, error) {
+	if c.CertFile == "" {
+		return nil, errors.New("missing cert file")
+	}
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load certificate: %w", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if c.CAFile != "" {
+		pool, err := loadCAs(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// ClientConfig returns the TLS config of a client, presenting the
+// certificate of c when set
+func (c Config) ClientConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName: c.ServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if c.CAFile != "" {
+		pool, err := loadCAs(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+func loadCAs(file string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read CAs: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates in %v", file)
+	}
+	return pool, nil
+}
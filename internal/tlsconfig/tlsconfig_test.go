@@ -0,0 +1,131 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeCert writes a certificate of name signed by parent, or self signed
+// when parent is nil, in dir and returns its files
+func writeCert(t *testing.T, dir string, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (string, string, *x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	assert.Nil(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.Nil(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile, cert, key
+}
+
+// handshake returns the errors of the server and client sides of a handshake
+func handshake(server *tls.Config, client *tls.Config) (error, error) {
+	sc, cc := net.Pipe()
+	s := tls.Server(sc, server)
+	c := tls.Client(cc, client)
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- s.Handshake()
+		sc.Close()
+	}()
+	clientErr := c.Handshake()
+	cc.Close()
+	return <-serverErr, clientErr
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	caFile, _, ca, caKey := writeCert(t, dir, "ca", nil, nil)
+	serverCert, serverKey, _, _ := writeCert(t, dir, "api.kannon", ca, caKey)
+	clientCert, clientKey, _, _ := writeCert(t, dir, "dispatcher.kannon", ca, caKey)
+	otherCA, _, other, otherKey := writeCert(t, dir, "other", nil, nil)
+	otherCert, otherCertKey, _, _ := writeCert(t, dir, "evil.kannon", other, otherKey)
+
+	server, err := Config{CertFile: serverCert, KeyFile: serverKey, CAFile: caFile}.ServerConfig()
+	assert.Nil(t, err)
+
+	client, err := Config{CertFile: clientCert, KeyFile: clientKey, CAFile: caFile, ServerName: "api.kannon"}.ClientConfig()
+	assert.Nil(t, err)
+	serverErr, clientErr := handshake(server, client)
+	assert.Nil(t, serverErr)
+	assert.Nil(t, clientErr)
+
+	// clients without certificate are rejected
+	client, err = Config{CAFile: caFile, ServerName: "api.kannon"}.ClientConfig()
+	assert.Nil(t, err)
+	serverErr, _ = handshake(server, client)
+	assert.NotNil(t, serverErr)
+
+	// clients with certificates of other CAs are rejected
+	client, err = Config{CertFile: otherCert, KeyFile: otherCertKey, CAFile: caFile, ServerName: "api.kannon"}.ClientConfig()
+	assert.Nil(t, err)
+	serverErr, _ = handshake(server, client)
+	assert.NotNil(t, serverErr)
+
+	// servers of other CAs are rejected
+	client, err = Config{CertFile: clientCert, KeyFile: clientKey, CAFile: otherCA, ServerName: "api.kannon"}.ClientConfig()
+	assert.Nil(t, err)
+	_, clientErr = handshake(server, client)
+	assert.NotNil(t, clientErr)
+}
+
+func TestServerTLS(t *testing.T) {
+	dir := t.TempDir()
+	caFile, _, ca, caKey := writeCert(t, dir, "ca", nil, nil)
+	serverCert, serverKey, _, _ := writeCert(t, dir, "api.kannon", ca, caKey)
+
+	// without CAs client certificates are not required
+	server, err := Config{CertFile: serverCert, KeyFile: serverKey}.ServerConfig()
+	assert.Nil(t, err)
+	client, err := Config{CAFile: caFile, ServerName: "api.kannon"}.ClientConfig()
+	assert.Nil(t, err)
+	serverErr, clientErr := handshake(server, client)
+	assert.Nil(t, serverErr)
+	assert.Nil(t, clientErr)
+
+	_, err = Config{CAFile: caFile}.ServerConfig()
+	assert.NotNil(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	assert.Nil(t, Config{}.Validate())
+	assert.Nil(t, Config{CertFile: "a.crt", KeyFile: "a.key"}.Validate())
+	assert.Nil(t, Config{CAFile: "ca.crt"}.Validate())
+	assert.NotNil(t, Config{CertFile: "a.crt"}.Validate())
+	assert.False(t, Config{}.Enabled())
+	assert.True(t, Config{CAFile: "ca.crt"}.Enabled())
+}
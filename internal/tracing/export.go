@@ -0,0 +1,245 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"kannon.gyozatech.dev/internal/logging"
+)
+
+var log = logging.Logger("tracing")
+
+const (
+	// maxQueueSize is the max number of ended spans waiting for export,
+	// spans ended when the queue is full are dropped
+	maxQueueSize = 2048
+	// maxBatchSize is the max number of spans of an export request
+	maxBatchSize = 512
+	// exportInterval is the max wait of an ended span before its export
+	exportInterval = 5 * time.Second
+	// stopTimeout is the max wait for the export of the spans left on stop
+	stopTimeout = 10 * time.Second
+)
+
+// exporter exports batches of spans to the /v1/traces endpoint of an
+// OTLP HTTP collector with the JSON encoding of the OTLP protobufs
+type exporter struct {
+	client  *http.Client
+	url     string
+	service string
+	ratio   float64
+
+	spans    chan *Span
+	done     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+func newExporter(config Config, service string, client *http.Client) *exporter {
+	e := &exporter{
+		client:  client,
+		url:     strings.TrimSuffix(config.Endpoint, "/") + "/v1/traces",
+		service: service,
+		ratio:   config.SampleRatio,
+		spans:   make(chan *Span, maxQueueSize),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// queue queues an ended span for export
+func (e *exporter) queue(s *Span) {
+	select {
+	case e.spans <- s:
+	default:
+		log.Debugf("[🔭 tracing] queue full, dropping span %v", s.name)
+	}
+}
+
+// stop exports the queued spans and stops the exporter
+func (e *exporter) stop() {
+	e.stopOnce.Do(func() {
+		close(e.done)
+		select {
+		case <-e.stopped:
+		case <-time.After(stopTimeout):
+			log.Warnf("[🔭 tracing] spans not exported after %v, stopping", stopTimeout)
+		}
+	})
+}
+
+func (e *exporter) run() {
+	defer close(e.stopped)
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	var batch []*Span
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.export(batch); err != nil {
+			log.Warnf("[🔭 tracing] cannot export %v spans: %v", len(batch), err)
+		}
+		batch = nil
+	}
+	for {
+		select {
+		case s := <-e.spans:
+			batch = append(batch, s)
+			if len(batch) >= maxBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-e.done:
+			for {
+				select {
+				case s := <-e.spans:
+					batch = append(batch, s)
+					if len(batch) >= maxBatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// export sends spans to the collector
+func (e *exporter) export(spans []*Span) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return err
+	}
+	res, err := e.client.Post(e.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("%v: %v", res.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// the messages of OTLP JSON, ids are hex and 64 bit integers are strings
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []spanData `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type spanData struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              Kind       `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            spanStatus `json:"status"`
+}
+
+type spanStatus struct {
+	// Code is 0 for unset and 2 for error
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+	BoolValue   *bool   `json:"boolValue,omitempty"`
+}
+
+func (e *exporter) request(spans []*Span) exportRequest {
+	data := make([]spanData, 0, len(spans))
+	for _, s := range spans {
+		s.mu.Lock()
+		d := spanData{
+			TraceID:           hex.EncodeToString(s.sc.TraceID[:]),
+			SpanID:            hex.EncodeToString(s.sc.SpanID[:]),
+			Name:              s.name,
+			Kind:              s.kind,
+			StartTimeUnixNano: unixNano(s.start),
+			EndTimeUnixNano:   unixNano(s.end),
+			Attributes:        keyValues(s.attrs),
+		}
+		if s.parentID != ([8]byte{}) {
+			d.ParentSpanID = hex.EncodeToString(s.parentID[:])
+		}
+		if s.err != nil {
+			d.Status = spanStatus{Code: 2, Message: s.err.Error()}
+		}
+		s.mu.Unlock()
+		data = append(data, d)
+	}
+	return exportRequest{ResourceSpans: []resourceSpans{{
+		Resource:   resource{Attributes: keyValues([]Attribute{String("service.name", e.service)})},
+		ScopeSpans: []scopeSpans{{Scope: scope{Name: "kannon"}, Spans: data}},
+	}}}
+}
+
+func keyValues(attrs []Attribute) []keyValue {
+	kvs := make([]keyValue, 0, len(attrs))
+	for _, a := range attrs {
+		var v anyValue
+		switch value := a.Value.(type) {
+		case string:
+			v.StringValue = &value
+		case int64:
+			i := strconv.FormatInt(value, 10)
+			v.IntValue = &i
+		case bool:
+			v.BoolValue = &value
+		default:
+			s := fmt.Sprint(value)
+			v.StringValue = &s
+		}
+		kvs = append(kvs, keyValue{Key: a.Key, Value: v})
+	}
+	return kvs
+}
+
+// unixNano formats t as the decimal nanoseconds of OTLP JSON
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
@@ -0,0 +1,118 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TraceParentHeader is the W3C Trace Context header of the span of a
+// request or message, like 00-<trace id>-<span id>-01
+const TraceParentHeader = "traceparent"
+
+// SpanContext identifies a span of a trace across services
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	// Sampled spans are exported
+	Sampled bool
+}
+
+// IsValid reports if the trace and span ids are set
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// TraceParent returns the traceparent header of sc, empty when not valid
+func (sc SpanContext) TraceParent() string {
+	if !sc.IsValid() {
+		return ""
+	}
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%x-%x-%v", sc.TraceID, sc.SpanID, flags)
+}
+
+// ParseTraceParent parses a traceparent header, fields added by
+// future versions of the header are ignored
+func ParseTraceParent(traceParent string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(traceParent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return SpanContext{}, fmt.Errorf("invalid traceparent: %v", traceParent)
+	}
+	var sc SpanContext
+	var flags [1]byte
+	if !decodeHex(sc.TraceID[:], parts[1]) || !decodeHex(sc.SpanID[:], parts[2]) || !decodeHex(flags[:], parts[3]) || !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("invalid traceparent: %v", traceParent)
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, nil
+}
+
+// decodeHex decodes the lowercase hex s in dst, reporting if s has the length of dst
+func decodeHex(dst []byte, s string) bool {
+	if len(s) != hex.EncodedLen(len(dst)) || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns ctx with the span sc, the parent of the spans started with ctx
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the span of ctx, not valid when ctx has no span
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	sc, _ := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc
+}
+
+// TraceParent returns the traceparent header of the span of ctx, empty when ctx has no span
+func TraceParent(ctx context.Context) string {
+	return SpanContextFromContext(ctx).TraceParent()
+}
+
+// ContextWithTraceParent returns ctx with the span of a traceparent
+// header, ctx when the header is empty or invalid
+func ContextWithTraceParent(ctx context.Context, traceParent string) context.Context {
+	if traceParent == "" {
+		return ctx
+	}
+	sc, err := ParseTraceParent(traceParent)
+	if err != nil {
+		return ctx
+	}
+	return ContextWithSpanContext(ctx, sc)
+}
+
+// UnaryServerInterceptor starts a server span for every call, child of
+// the span of the traceparent metadata of the call when present
+func UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if m, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := m.Get(TraceParentHeader); len(values) > 0 {
+			ctx = ContextWithTraceParent(ctx, values[0])
+		}
+	}
+	ctx, span := StartSpan(ctx, info.FullMethod, Server,
+		String("rpc.system", "grpc"),
+		String("rpc.method", info.FullMethod),
+	)
+	defer span.End()
+	res, err := handler(ctx, req)
+	if err != nil {
+		span.SetAttributes(String("rpc.grpc.status_code", status.Code(err).String()))
+		span.SetError(err)
+	}
+	return res, err
+}
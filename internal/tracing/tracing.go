@@ -0,0 +1,212 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Config configures the export of the spans of a service to an OpenTelemetry
+// collector with OTLP over HTTP, like Jaeger or Tempo
+type Config struct {
+	// Endpoint is the OTLP HTTP endpoint of the collector, like http://tempo:4318,
+	// spans are not exported when empty
+	Endpoint string
+	// SampleRatio is the ratio of the traces started by the service that are
+	// exported, traces continued from a parent follow the sampling of the parent
+	SampleRatio float64 `default:"1"`
+}
+
+// Enabled reports if spans are exported
+func (c Config) Enabled() bool {
+	return c.Endpoint != ""
+}
+
+// Validate checks the endpoint and the sample ratio of an enabled config
+func (c Config) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+	u, err := url.Parse(c.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid endpoint %v, must be an http or https url", c.Endpoint)
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return fmt.Errorf("sample ratio %v must be between 0 and 1", c.SampleRatio)
+	}
+	return nil
+}
+
+// current is the exporter of the spans of the process, nil when spans are not exported
+var current *exporter
+
+// Start exports the spans of service to the collector of config until the
+// returned function is called, which exports the spans left. Spans are
+// not recorded when config is not enabled, trace contexts are still propagated.
+// Daemons started in the process of an exporter share it
+func Start(config Config, service string) (func(), error) {
+	if !config.Enabled() || current != nil {
+		return func() {}, nil
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	e := newExporter(config, service, &http.Client{Timeout: 10 * time.Second})
+	current = e
+	return e.stop, nil
+}
+
+// Kind is the kind of a span, like the OTLP SpanKind
+type Kind int
+
+const (
+	// Internal spans are operations of a service
+	Internal Kind = 1
+	// Server spans handle the requests of clients
+	Server Kind = 2
+	// Client spans are requests to other services, like the database or a MX
+	Client Kind = 3
+	// Producer spans publish messages
+	Producer Kind = 4
+	// Consumer spans handle published messages
+	Consumer Kind = 5
+)
+
+// Attribute is a key and value of a span
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String returns a string attribute
+func String(key string, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int returns an integer attribute
+func Int(key string, value int64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Bool returns a boolean attribute
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span is an operation of a trace, the methods of a nil Span do nothing
+type Span struct {
+	sc       SpanContext
+	parentID [8]byte
+	name     string
+	kind     Kind
+	start    time.Time
+	exporter *exporter
+
+	mu    sync.Mutex
+	end   time.Time
+	attrs []Attribute
+	err   error
+	ended bool
+}
+
+// StartSpan starts a span child of the span of ctx, or the first span of a
+// new trace, and returns the context of the span. The span is nil when spans
+// are not exported or the trace is not sampled
+func StartSpan(ctx context.Context, name string, kind Kind, attrs ...Attribute) (context.Context, *Span) {
+	e := current
+	if e == nil {
+		return ctx, nil
+	}
+	parent := SpanContextFromContext(ctx)
+	sc := SpanContext{TraceID: parent.TraceID, Sampled: parent.Sampled}
+	if !parent.IsValid() {
+		sc.TraceID = newTraceID()
+		sc.Sampled = sample(e.ratio)
+	}
+	sc.SpanID = newSpanID()
+	ctx = ContextWithSpanContext(ctx, sc)
+	if !sc.Sampled {
+		return ctx, nil
+	}
+	return ctx, &Span{
+		sc:       sc,
+		parentID: parent.SpanID,
+		name:     name,
+		kind:     kind,
+		start:    time.Now(),
+		exporter: e,
+		attrs:    attrs,
+	}
+}
+
+// SetAttributes adds attributes to the span
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// SetError marks the span as failed with err, nil errors are ignored
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// End ends the span and queues it for export, spans are exported once
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+	s.exporter.queue(s)
+}
+
+// sample reports if a new trace is sampled with ratio
+func sample(ratio float64) bool {
+	if ratio >= 1 {
+		return true
+	}
+	if ratio <= 0 {
+		return false
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(1<<53))
+	if err != nil {
+		return false
+	}
+	return float64(n.Int64())/(1<<53) < ratio
+}
+
+func newTraceID() [16]byte {
+	var id [16]byte
+	for id == ([16]byte{}) {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() [8]byte {
+	var id [8]byte
+	for id == ([8]byte{}) {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
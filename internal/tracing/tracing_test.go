@@ -0,0 +1,127 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	sc, err := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.Nil(t, err)
+	assert.True(t, sc.IsValid())
+	assert.True(t, sc.Sampled)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sc.TraceParent())
+
+	sc, err = ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	assert.Nil(t, err)
+	assert.False(t, sc.Sampled)
+
+	// future versions can add fields
+	_, err = ParseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra")
+	assert.Nil(t, err)
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+	} {
+		_, err := ParseTraceParent(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestContextWithTraceParent(t *testing.T) {
+	ctx := ContextWithTraceParent(context.Background(), "invalid")
+	assert.Equal(t, "", TraceParent(ctx))
+
+	traceParent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	ctx = ContextWithTraceParent(context.Background(), traceParent)
+	assert.Equal(t, traceParent, TraceParent(ctx))
+
+	// spans are not recorded without exporter, the trace is still propagated
+	spanCtx, span := StartSpan(ctx, "send", Consumer)
+	assert.Nil(t, span)
+	assert.Equal(t, traceParent, TraceParent(spanCtx))
+	span.SetError(errors.New("ignored"))
+	span.End()
+}
+
+func TestExport(t *testing.T) {
+	var requests []exportRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		var req exportRequest
+		assert.Nil(t, json.Unmarshal(body, &req))
+		requests = append(requests, req)
+	}))
+	defer srv.Close()
+
+	stop, err := Start(Config{Endpoint: srv.URL, SampleRatio: 1}, "kannon-test")
+	assert.Nil(t, err)
+	defer func() { current = nil }()
+
+	ctx := ContextWithTraceParent(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx, parent := StartSpan(ctx, "send", Consumer, String("kannon.email", "test@kannon.io"))
+	_, child := StartSpan(ctx, "smtp", Client, Int("kannon.recipients", 2))
+	child.SetError(errors.New("550 unknown user"))
+	child.End()
+	parent.End()
+	parent.End()
+	stop()
+
+	assert.Equal(t, 1, len(requests))
+	rs := requests[0].ResourceSpans[0]
+	assert.Equal(t, "service.name", rs.Resource.Attributes[0].Key)
+	assert.Equal(t, "kannon-test", *rs.Resource.Attributes[0].Value.StringValue)
+
+	spans := rs.ScopeSpans[0].Spans
+	assert.Equal(t, 2, len(spans))
+	smtp, send := spans[0], spans[1]
+	assert.Equal(t, "smtp", smtp.Name)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", smtp.TraceID)
+	assert.Equal(t, send.SpanID, smtp.ParentSpanID)
+	assert.Equal(t, Client, smtp.Kind)
+	assert.Equal(t, "2", *smtp.Attributes[0].Value.IntValue)
+	assert.Equal(t, spanStatus{Code: 2, Message: "550 unknown user"}, smtp.Status)
+
+	assert.Equal(t, "send", send.Name)
+	assert.Equal(t, "00f067aa0ba902b7", send.ParentSpanID)
+	assert.Equal(t, "test@kannon.io", *send.Attributes[0].Value.StringValue)
+	assert.Equal(t, spanStatus{}, send.Status)
+}
+
+func TestSampling(t *testing.T) {
+	current = &exporter{ratio: 0}
+	defer func() { current = nil }()
+
+	// unsampled traces are propagated without spans
+	ctx, span := StartSpan(context.Background(), "dispatch", Producer)
+	assert.Nil(t, span)
+	sc := SpanContextFromContext(ctx)
+	assert.True(t, sc.IsValid())
+	assert.False(t, sc.Sampled)
+
+	// sampled parents are sampled with every ratio
+	ctx = ContextWithTraceParent(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	_, span = StartSpan(ctx, "dispatch", Producer)
+	assert.NotNil(t, span)
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.Nil(t, Config{}.Validate())
+	assert.Nil(t, Config{Endpoint: "http://tempo:4318", SampleRatio: 0.1}.Validate())
+	assert.NotNil(t, Config{Endpoint: "tempo:4318", SampleRatio: 1}.Validate())
+	assert.NotNil(t, Config{Endpoint: "http://tempo:4318", SampleRatio: 2}.Validate())
+}
@@ -0,0 +1,140 @@
+package tracking
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidToken is returned when a tracking token
+// is malformed or its signature does not match
+var ErrInvalidToken = errors.New("invalid tracking token")
+
+// Kind is the action of a tracking url, tokens
+// of a kind are not valid for the others
+type Kind string
+
+const (
+	// Open is the kind of open tracking pixels
+	Open Kind = "o"
+	// Unsubscribe is the kind of unsubscribe links
+	Unsubscribe Kind = "u"
+	// Click is the kind of tracked links, redirecting to their url
+	Click Kind = "c"
+)
+
+// Target identifies the recipient of a message
+type Target struct {
+	MessageID string
+	Email     string
+}
+
+// Tracker builds and verifies signed tracking urls
+type Tracker interface {
+	OpenURL(target Target) string
+	UnsubscribeURL(target Target) string
+	ClickURL(target Target, url string) string
+	ParseToken(kind Kind, token string) (Target, error)
+	ParseClickToken(token string) (Target, string, error)
+}
+
+type tracker struct {
+	baseURL string
+	secret  []byte
+}
+
+// OpenURL returns the url of the open tracking pixel of a target
+func (t *tracker) OpenURL(target Target) string {
+	return fmt.Sprintf("%v/%v/%v.gif", t.baseURL, Open, t.token(Open, target))
+}
+
+// UnsubscribeURL returns the one-click unsubscribe url of a target
+func (t *tracker) UnsubscribeURL(target Target) string {
+	return fmt.Sprintf("%v/%v/%v", t.baseURL, Unsubscribe, t.token(Unsubscribe, target))
+}
+
+// ClickURL returns the url of a tracked link of a target redirecting to url,
+// the url is signed with the target so it cannot be changed
+func (t *tracker) ClickURL(target Target, url string) string {
+	return fmt.Sprintf("%v/%v/%v", t.baseURL, Click, t.encode(Click, target.MessageID, target.Email, url))
+}
+
+// ParseToken verifies a token of a kind and returns its target
+func (t *tracker) ParseToken(kind Kind, token string) (Target, error) {
+	fields, err := t.decode(kind, token, 2)
+	if err != nil {
+		return Target{}, err
+	}
+	return Target{
+		MessageID: fields[0],
+		Email:     fields[1],
+	}, nil
+}
+
+// ParseClickToken verifies the token of a tracked link
+// and returns its target and the url of the link
+func (t *tracker) ParseClickToken(token string) (Target, string, error) {
+	fields, err := t.decode(Click, token, 3)
+	if err != nil {
+		return Target{}, "", err
+	}
+	return Target{
+		MessageID: fields[0],
+		Email:     fields[1],
+	}, fields[2], nil
+}
+
+func (t *tracker) token(kind Kind, target Target) string {
+	return t.encode(kind, target.MessageID, target.Email)
+}
+
+// encode signs fields separated by new lines, message ids
+// and emails cannot contain new lines
+func (t *tracker) encode(kind Kind, fields ...string) string {
+	payload := []byte(strings.Join(fields, "\n"))
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(t.sign(kind, payload))
+}
+
+// decode verifies a token of a kind and returns its n fields
+func (t *tracker) decode(kind Kind, token string, n int) ([]string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if !hmac.Equal(sig, t.sign(kind, payload)) {
+		return nil, ErrInvalidToken
+	}
+
+	fields := strings.SplitN(string(payload), "\n", n)
+	if len(fields) != n {
+		return nil, ErrInvalidToken
+	}
+	return fields, nil
+}
+
+func (t *tracker) sign(kind Kind, payload []byte) []byte {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(kind))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// NewTracker creates a tracker serving urls from baseURL,
+// tokens are signed with secret
+func NewTracker(baseURL string, secret string) Tracker {
+	return &tracker{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		secret:  []byte(secret),
+	}
+}
@@ -0,0 +1,69 @@
+package tracking
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenURL(t *testing.T) {
+	tr := NewTracker("https://track.kannon.io/", "secret")
+	target := Target{MessageID: "msg_test@kannon.io", Email: "to@email.com"}
+
+	url := tr.OpenURL(target)
+	assert.True(t, strings.HasPrefix(url, "https://track.kannon.io/o/"))
+	assert.True(t, strings.HasSuffix(url, ".gif"))
+
+	token := strings.TrimSuffix(strings.TrimPrefix(url, "https://track.kannon.io/o/"), ".gif")
+	res, err := tr.ParseToken(Open, token)
+	assert.Nil(t, err)
+	assert.Equal(t, target, res)
+}
+
+func TestUnsubscribeURL(t *testing.T) {
+	tr := NewTracker("https://track.kannon.io", "secret")
+	target := Target{MessageID: "msg_test@kannon.io", Email: "to@email.com"}
+
+	token := strings.TrimPrefix(tr.UnsubscribeURL(target), "https://track.kannon.io/u/")
+	res, err := tr.ParseToken(Unsubscribe, token)
+	assert.Nil(t, err)
+	assert.Equal(t, target, res)
+
+	// open tokens cannot be used to unsubscribe
+	_, err = tr.ParseToken(Open, token)
+	assert.Equal(t, ErrInvalidToken, err)
+}
+
+func TestParseTokenWrongSecret(t *testing.T) {
+	target := Target{MessageID: "msg_test@kannon.io", Email: "to@email.com"}
+	token := NewTracker("", "secret").(*tracker).token(Open, target)
+
+	_, err := NewTracker("", "other").ParseToken(Open, token)
+	assert.Equal(t, ErrInvalidToken, err)
+
+	_, err = NewTracker("", "secret").ParseToken(Open, "malformed")
+	assert.Equal(t, ErrInvalidToken, err)
+}
+
+func TestClickURL(t *testing.T) {
+	tr := NewTracker("https://track.kannon.io", "secret")
+	target := Target{MessageID: "msg_test@kannon.io", Email: "to@email.com"}
+
+	url := tr.ClickURL(target, "https://kannon.io/pricing?plan=pro\nfree")
+	assert.True(t, strings.HasPrefix(url, "https://track.kannon.io/c/"))
+
+	token := strings.TrimPrefix(url, "https://track.kannon.io/c/")
+	res, link, err := tr.ParseClickToken(token)
+	assert.Nil(t, err)
+	assert.Equal(t, target, res)
+	assert.Equal(t, "https://kannon.io/pricing?plan=pro\nfree", link)
+
+	// click tokens cannot be used to unsubscribe
+	_, err = tr.ParseToken(Unsubscribe, token)
+	assert.Equal(t, ErrInvalidToken, err)
+
+	// tokens without url are invalid
+	_, _, err = tr.ParseClickToken(tr.(*tracker).encode(Click, target.MessageID, target.Email))
+	assert.Equal(t, ErrInvalidToken, err)
+}
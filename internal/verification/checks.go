@@ -0,0 +1,210 @@
+package verification
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/dkim"
+)
+
+// DNS records checked by the verification of a domain
+const (
+	RecordDKIM       = "dkim"
+	RecordSPF        = "spf"
+	RecordReturnPath = "return_path"
+)
+
+// Config are the records the DNS of domains must publish,
+// checks of empty hosts are skipped
+type Config struct {
+	// SPFInclude is the host the SPF record of domains must include,
+	// like mailer.gyozatech.space
+	SPFInclude string
+	// SendingIPs are the source IPs of the senders, the SPF record of
+	// domains authorizes them when it doesn't include SPFInclude
+	SendingIPs []string
+	// ReturnPathHost is the host receiving the bounces of the return path
+	// of domains, the CNAME or a MX of domains must point to it
+	ReturnPathHost string
+}
+
+// Validate checks that the sending IPs are IPs
+func (c Config) Validate() error {
+	for _, ip := range c.SendingIPs {
+		if net.ParseIP(strings.TrimSpace(ip)) == nil {
+			return fmt.Errorf("invalid sending ip: %v", ip)
+		}
+	}
+	return nil
+}
+
+// checksSPF reports if the SPF record of domains is checked
+func (c Config) checksSPF() bool {
+	return c.SPFInclude != "" || len(c.SendingIPs) > 0
+}
+
+// DNS lookups of the checks
+var (
+	lookupTXT   = net.LookupTXT
+	lookupCNAME = net.LookupCNAME
+	lookupMX    = net.LookupMX
+)
+
+// result is the result of the check of a record, err is nil when verified
+type result struct {
+	record string
+	err    error
+}
+
+// checkDomain checks the DNS records of a domain
+func checkDomain(d sqlc.Domain, config Config) []result {
+	results := []result{{RecordDKIM, checkDKIM(d)}}
+	if config.checksSPF() {
+		results = append(results, result{RecordSPF, checkSPF(d.Domain, config)})
+	}
+	if config.ReturnPathHost != "" {
+		results = append(results, result{RecordReturnPath, checkReturnPath(returnPathDomain(d), config.ReturnPathHost)})
+	}
+	return results
+}
+
+// returnPathDomain is the domain of the return path of the emails of a domain
+func returnPathDomain(d sqlc.Domain) string {
+	if d.ReturnPathDomain != "" {
+		return d.ReturnPathDomain
+	}
+	return d.Domain
+}
+
+// checkDKIM checks the records of the keys signing the emails of a domain
+func checkDKIM(d sqlc.Domain) error {
+	if d.DkimSigning != dkim.SigningEd25519 {
+		if err := dkim.VerifyRecord(d.Domain, d.DkimSelector, dkim.AlgorithmRSA, d.DkimPublicKey); err != nil {
+			return err
+		}
+	}
+	if d.DkimSigning == dkim.SigningEd25519 || d.DkimSigning == dkim.SigningDual {
+		return dkim.VerifyRecord(d.Domain, d.DkimEd25519Selector, dkim.AlgorithmEd25519, d.DkimEd25519PublicKey)
+	}
+	return nil
+}
+
+// checkSPF checks that the SPF record of domain authorizes the senders
+func checkSPF(domain string, config Config) error {
+	records, err := spfRecords(domain)
+	if err != nil {
+		return err
+	}
+	return validateSPF(records, config)
+}
+
+// spfRecords returns the SPF records of domain
+func spfRecords(domain string) ([]string, error) {
+	txts, err := lookupTXT(domain)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve SPF record: %w", err)
+	}
+	var records []string
+	for _, txt := range txts {
+		terms := strings.Fields(strings.ToLower(txt))
+		if len(terms) > 0 && terms[0] == "v=spf1" {
+			records = append(records, txt)
+		}
+	}
+	return records, nil
+}
+
+// validateSPF checks that the SPF record of a domain includes SPFInclude
+// or authorizes all the sending IPs, RFC 7208
+func validateSPF(records []string, config Config) error {
+	switch len(records) {
+	case 0:
+		return fmt.Errorf("no SPF record")
+	case 1:
+	default:
+		// domains with many records fail SPF with a permerror
+		return fmt.Errorf("%v SPF records, a domain must have one", len(records))
+	}
+
+	// qualifiers other than + are not a pass
+	var networks []*net.IPNet
+	include := false
+	for _, t := range strings.Fields(strings.ToLower(records[0]))[1:] {
+		t = strings.TrimPrefix(t, "+")
+		switch {
+		case config.SPFInclude != "" && t == "include:"+strings.ToLower(config.SPFInclude):
+			include = true
+		case strings.HasPrefix(t, "ip4:"), strings.HasPrefix(t, "ip6:"):
+			if n := parseSPFNetwork(t[4:]); n != nil {
+				networks = append(networks, n)
+			}
+		}
+	}
+	if include {
+		return nil
+	}
+	if len(config.SendingIPs) > 0 && authorizesAll(networks, config.SendingIPs) {
+		return nil
+	}
+	return fmt.Errorf("SPF record doesn't authorize the senders: %v", records[0])
+}
+
+// parseSPFNetwork parses the network of an ip4 or ip6 mechanism,
+// like 192.0.2.0/24 or 192.0.2.1
+func parseSPFNetwork(s string) *net.IPNet {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil
+		}
+		bits := 128
+		if ip.To4() != nil {
+			bits = 32
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+	}
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil
+	}
+	return n
+}
+
+func authorizesAll(networks []*net.IPNet, ips []string) bool {
+	for _, s := range ips {
+		ip := net.ParseIP(strings.TrimSpace(s))
+		authorized := false
+		for _, n := range networks {
+			if n.Contains(ip) {
+				authorized = true
+				break
+			}
+		}
+		if !authorized {
+			return false
+		}
+	}
+	return true
+}
+
+// checkReturnPath checks that the bounces to the return path of domain are
+// received by host, the domain is a CNAME of host or one of its MXs is host
+func checkReturnPath(domain, host string) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	cname, err := lookupCNAME(domain)
+	if err == nil && strings.ToLower(strings.TrimSuffix(cname, ".")) == host {
+		return nil
+	}
+	mxs, err := lookupMX(domain)
+	if err != nil {
+		return fmt.Errorf("cannot resolve return path: %w", err)
+	}
+	for _, mx := range mxs {
+		if strings.ToLower(strings.TrimSuffix(mx.Host, ".")) == host {
+			return nil
+		}
+	}
+	return fmt.Errorf("return path is not a CNAME or a MX of %v", host)
+}
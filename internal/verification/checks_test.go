@@ -0,0 +1,74 @@
+package verification
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckSPF(t *testing.T) {
+	defer func(f func(string) ([]string, error)) { lookupTXT = f }(lookupTXT)
+	records := map[string][]string{
+		"pass.com":     {"google-site-verification=abc", "v=spf1 include:mailer.kannon.io ~all"},
+		"plus.com":     {"v=spf1 +include:Mailer.Kannon.io -all"},
+		"neutral.com":  {"v=spf1 ?include:mailer.kannon.io -all"},
+		"other.com":    {"v=spf1 include:_spf.google.com ~all"},
+		"missing.com":  {"google-site-verification=abc"},
+		"spf2only.com": {"spf2.0/pra include:mailer.kannon.io"},
+	}
+	lookupTXT = func(name string) ([]string, error) {
+		if txts, ok := records[name]; ok {
+			return txts, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	config := Config{SPFInclude: "mailer.kannon.io"}
+	assert.Nil(t, checkSPF("pass.com", config))
+	assert.Nil(t, checkSPF("plus.com", config))
+	for _, d := range []string{"neutral.com", "other.com", "missing.com", "spf2only.com", "unknown.com"} {
+		assert.NotNil(t, checkSPF(d, config), d)
+	}
+}
+
+func TestValidateSPFSendingIPs(t *testing.T) {
+	config := Config{SendingIPs: []string{"192.0.2.1", "2001:db8::1"}}
+	assert.Nil(t, validateSPF([]string{"v=spf1 ip4:192.0.2.0/24 ip6:2001:db8::1 -all"}, config))
+	assert.Nil(t, validateSPF([]string{"v=spf1 +ip4:192.0.2.1 ip6:2001:db8::/32 ~all"}, config))
+	assert.NotNil(t, validateSPF([]string{"v=spf1 ip4:192.0.2.1 -all"}, config))
+	assert.NotNil(t, validateSPF([]string{"v=spf1 ~ip4:192.0.2.1 ip6:2001:db8::1 -all"}, config))
+	assert.NotNil(t, validateSPF([]string{"v=spf1 ip4:192.0.2.1 -all", "v=spf1 ip6:2001:db8::1 -all"}, config))
+	assert.NotNil(t, validateSPF(nil, config))
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.Nil(t, Config{SendingIPs: []string{"192.0.2.1", " 2001:db8::1"}}.Validate())
+	assert.NotNil(t, Config{SendingIPs: []string{"192.0.2.0/24"}}.Validate())
+}
+
+func TestCheckReturnPath(t *testing.T) {
+	defer func(f func(string) (string, error)) { lookupCNAME = f }(lookupCNAME)
+	defer func(f func(string) ([]*net.MX, error)) { lookupMX = f }(lookupMX)
+	lookupCNAME = func(name string) (string, error) {
+		if name == "cname.com" {
+			return "mailer.kannon.io.", nil
+		}
+		return name + ".", nil
+	}
+	lookupMX = func(name string) ([]*net.MX, error) {
+		switch name {
+		case "mx.com":
+			return []*net.MX{{Host: "mx1.other.com.", Pref: 10}, {Host: "mailer.kannon.io.", Pref: 20}}, nil
+		case "other.com":
+			return []*net.MX{{Host: "mx1.other.com.", Pref: 10}}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	assert.Nil(t, checkReturnPath("cname.com", "mailer.kannon.io"))
+	assert.Nil(t, checkReturnPath("mx.com", "mailer.kannon.io."))
+	assert.NotNil(t, checkReturnPath("other.com", "mailer.kannon.io"))
+	assert.NotNil(t, checkReturnPath("unknown.com", "mailer.kannon.io"))
+}
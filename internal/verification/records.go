@@ -0,0 +1,152 @@
+package verification
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/dkim"
+)
+
+// DNS record types
+const (
+	TypeTXT   = "TXT"
+	TypeMX    = "MX"
+	TypeCNAME = "CNAME"
+)
+
+// returnPathPref is the preference of the MX of the return path
+const returnPathPref = 10
+
+// DNSRecord is a record a domain must publish, with the values
+// currently published at its name
+type DNSRecord struct {
+	// Record is the check of the record: dkim, spf or return_path
+	Record string
+	Type   string
+	Name   string
+	Value  string
+	// Published are the values of type Type published at Name
+	Published []string
+	// Err is the reason the record is not published as expected, nil when it is
+	Err error
+
+	// algorithm and publicKey are the key of DKIM records
+	algorithm string
+	publicKey string
+}
+
+// expectedRecords returns the records domain must publish for config:
+// the DKIM records of its active and pending keys, its SPF record and
+// the record of its return path. The return path of the domain itself is a
+// MX and not a CNAME since the domain has the SPF record, return path
+// subdomains are a CNAME of the return path host
+func expectedRecords(d sqlc.Domain, keys []sqlc.DkimKey, config Config) []DNSRecord {
+	var records []DNSRecord
+	dkimRecord := func(selector, algorithm, publicKey string) {
+		records = append(records, DNSRecord{
+			Record: RecordDKIM,
+			Type:   TypeTXT,
+			Name:   selector + "._domainkey." + d.Domain,
+			Value:  dkim.Record(algorithm, publicKey),
+
+			algorithm: algorithm,
+			publicKey: publicKey,
+		})
+	}
+	if d.DkimSigning != dkim.SigningEd25519 {
+		dkimRecord(d.DkimSelector, dkim.AlgorithmRSA, d.DkimPublicKey)
+	}
+	if d.DkimSigning == dkim.SigningEd25519 || d.DkimSigning == dkim.SigningDual {
+		dkimRecord(d.DkimEd25519Selector, dkim.AlgorithmEd25519, d.DkimEd25519PublicKey)
+	}
+	for _, k := range keys {
+		// pending keys are published before their promotion
+		if k.Status == sqlc.DkimKeyStatusPending {
+			dkimRecord(k.Selector, k.Algorithm, k.PublicKey)
+		}
+	}
+
+	if config.checksSPF() {
+		records = append(records, DNSRecord{
+			Record: RecordSPF,
+			Type:   TypeTXT,
+			Name:   d.Domain,
+			Value:  spfRecord(config),
+		})
+	}
+	if host := strings.TrimSuffix(config.ReturnPathHost, "."); host != "" {
+		if d.ReturnPathDomain != "" {
+			records = append(records, DNSRecord{
+				Record: RecordReturnPath,
+				Type:   TypeCNAME,
+				Name:   d.ReturnPathDomain,
+				Value:  host,
+			})
+		} else {
+			records = append(records, DNSRecord{
+				Record: RecordReturnPath,
+				Type:   TypeMX,
+				Name:   d.Domain,
+				Value:  fmt.Sprintf("%v %v", returnPathPref, host),
+			})
+		}
+	}
+	return records
+}
+
+// spfRecord is the SPF record authorizing the senders of config,
+// like v=spf1 include:mailer.gyozatech.space ~all
+func spfRecord(config Config) string {
+	terms := []string{"v=spf1"}
+	if config.SPFInclude != "" {
+		terms = append(terms, "include:"+config.SPFInclude)
+	}
+	for _, s := range config.SendingIPs {
+		ip := net.ParseIP(strings.TrimSpace(s))
+		if ip.To4() != nil {
+			terms = append(terms, "ip4:"+ip.String())
+		} else {
+			terms = append(terms, "ip6:"+ip.String())
+		}
+	}
+	return strings.Join(append(terms, "~all"), " ")
+}
+
+// validateRecord sets the published values of r and validates them
+func validateRecord(r *DNSRecord, config Config) {
+	switch r.Record {
+	case RecordDKIM:
+		txts, err := lookupTXT(r.Name)
+		if err != nil {
+			r.Err = fmt.Errorf("%w: %v", dkim.ErrRecordNotPublished, err)
+			return
+		}
+		r.Published = txts
+		for _, txt := range txts {
+			if dkim.MatchRecord(txt, r.algorithm, r.publicKey) {
+				return
+			}
+		}
+		r.Err = fmt.Errorf("%w: %v doesn't have the key", dkim.ErrRecordNotPublished, r.Name)
+	case RecordSPF:
+		r.Published, r.Err = spfRecords(r.Name)
+		if r.Err == nil {
+			r.Err = validateSPF(r.Published, config)
+		}
+	case RecordReturnPath:
+		if r.Type == TypeCNAME {
+			if cname, err := lookupCNAME(r.Name); err == nil {
+				r.Published = []string{strings.TrimSuffix(cname, ".")}
+			}
+		} else if mxs, err := lookupMX(r.Name); err == nil {
+			for _, mx := range mxs {
+				r.Published = append(r.Published, fmt.Sprintf("%v %v", mx.Pref, strings.TrimSuffix(mx.Host, ".")))
+			}
+			sort.Strings(r.Published)
+		}
+		r.Err = checkReturnPath(r.Name, config.ReturnPathHost)
+	}
+}
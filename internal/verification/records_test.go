@@ -0,0 +1,104 @@
+package verification
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/dkim"
+)
+
+func TestExpectedRecords(t *testing.T) {
+	d := sqlc.Domain{
+		Domain:               "kannon.io",
+		DkimSigning:          dkim.SigningDual,
+		DkimSelector:         "kannon",
+		DkimPublicKey:        "AAAA",
+		DkimEd25519Selector:  "kannon-ed25519",
+		DkimEd25519PublicKey: "BBBB",
+	}
+	keys := []sqlc.DkimKey{
+		{Selector: "kannon-20210707094518", Algorithm: dkim.AlgorithmRSA, PublicKey: "CCCC", Status: sqlc.DkimKeyStatusPending},
+		{Selector: "kannon-old", Algorithm: dkim.AlgorithmRSA, PublicKey: "DDDD", Status: sqlc.DkimKeyStatusRetired},
+	}
+	config := Config{
+		SPFInclude:     "mailer.kannon.io",
+		SendingIPs:     []string{"192.0.2.1", "2001:db8::1"},
+		ReturnPathHost: "mailer.kannon.io.",
+	}
+
+	var values []string
+	for _, r := range expectedRecords(d, keys, config) {
+		values = append(values, r.Type+" "+r.Name+" "+r.Value)
+	}
+	assert.Equal(t, []string{
+		"TXT kannon._domainkey.kannon.io v=DKIM1; k=rsa; p=AAAA",
+		"TXT kannon-ed25519._domainkey.kannon.io v=DKIM1; k=ed25519; p=BBBB",
+		"TXT kannon-20210707094518._domainkey.kannon.io v=DKIM1; k=rsa; p=CCCC",
+		"TXT kannon.io v=spf1 include:mailer.kannon.io ip4:192.0.2.1 ip6:2001:db8::1 ~all",
+		"MX kannon.io 10 mailer.kannon.io",
+	}, values)
+
+	d.ReturnPathDomain = "bounce.kannon.io"
+	records := expectedRecords(d, nil, config)
+	assert.Equal(t, TypeCNAME, records[len(records)-1].Type)
+	assert.Equal(t, "bounce.kannon.io", records[len(records)-1].Name)
+	assert.Equal(t, "mailer.kannon.io", records[len(records)-1].Value)
+
+	d.DkimSigning = dkim.SigningEd25519
+	records = expectedRecords(d, nil, Config{})
+	assert.Len(t, records, 1)
+	assert.Equal(t, "kannon-ed25519._domainkey.kannon.io", records[0].Name)
+}
+
+func TestValidateRecord(t *testing.T) {
+	defer func(f func(string) ([]string, error)) { lookupTXT = f }(lookupTXT)
+	defer func(f func(string) (string, error)) { lookupCNAME = f }(lookupCNAME)
+	defer func(f func(string) ([]*net.MX, error)) { lookupMX = f }(lookupMX)
+	txts := map[string][]string{
+		"kannon._domainkey.kannon.io": {"v=DKIM1; k=rsa; p=AAAA"},
+		"kannon.io":                   {"v=spf1 include:other.io ~all"},
+	}
+	lookupTXT = func(name string) ([]string, error) {
+		if r, ok := txts[name]; ok {
+			return r, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	lookupCNAME = func(name string) (string, error) {
+		if name == "bounce.kannon.io" {
+			return "mailer.kannon.io.", nil
+		}
+		return name + ".", nil
+	}
+	lookupMX = func(name string) ([]*net.MX, error) {
+		return []*net.MX{{Host: "mailer.kannon.io.", Pref: 10}}, nil
+	}
+
+	d := sqlc.Domain{Domain: "kannon.io", DkimSigning: dkim.SigningRSA, DkimSelector: "kannon", DkimPublicKey: "AAAA"}
+	config := Config{SPFInclude: "mailer.kannon.io", ReturnPathHost: "mailer.kannon.io"}
+	records := expectedRecords(d, nil, config)
+	for i := range records {
+		validateRecord(&records[i], config)
+	}
+
+	assert.Nil(t, records[0].Err)
+	assert.Equal(t, []string{"v=DKIM1; k=rsa; p=AAAA"}, records[0].Published)
+	assert.NotNil(t, records[1].Err)
+	assert.Equal(t, []string{"v=spf1 include:other.io ~all"}, records[1].Published)
+	assert.Nil(t, records[2].Err)
+	assert.Equal(t, []string{"10 mailer.kannon.io"}, records[2].Published)
+
+	d.ReturnPathDomain = "bounce.kannon.io"
+	r := expectedRecords(d, nil, config)[2]
+	validateRecord(&r, config)
+	assert.Nil(t, r.Err)
+	assert.Equal(t, []string{"mailer.kannon.io"}, r.Published)
+
+	d.DkimPublicKey = "BBBB"
+	r = expectedRecords(d, nil, Config{})[0]
+	validateRecord(&r, Config{})
+	assert.True(t, errors.Is(r.Err, dkim.ErrRecordNotPublished), r.Err)
+}
@@ -0,0 +1,90 @@
+package verification
+
+import (
+	"context"
+	"database/sql"
+
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/metrics"
+)
+
+// Manager verifies the DNS records of domains, domains are
+// verified when all their checked records are
+type Manager interface {
+	// VerifyDomain checks the records of a domain and stores their status
+	VerifyDomain(domain sqlc.Domain) (sqlc.Domain, []sqlc.DomainRecord, error)
+	// GetRecords returns the status of the records of a domain at their last check
+	GetRecords(domain string) ([]sqlc.DomainRecord, error)
+	// GetDNSRecords returns the DNS records a domain must publish,
+	// validated against the records currently published
+	GetDNSRecords(domain sqlc.Domain) ([]DNSRecord, error)
+	// ExpectedRecords returns the DNS records a domain must publish, without checking them
+	ExpectedRecords(domain sqlc.Domain) ([]DNSRecord, error)
+}
+
+type manager struct {
+	db     *sqlc.Queries
+	config Config
+}
+
+// NewVerificationManager builds a Verification Manager
+func NewVerificationManager(db *sql.DB, config Config) (Manager, error) {
+	return &manager{
+		db:     sqlc.New(metrics.InstrumentDB(db)),
+		config: config,
+	}, nil
+}
+
+func (m *manager) VerifyDomain(domain sqlc.Domain) (sqlc.Domain, []sqlc.DomainRecord, error) {
+	verified := true
+	var records []sqlc.DomainRecord
+	for _, r := range checkDomain(domain, m.config) {
+		params := sqlc.SetDomainRecordParams{
+			Domain: domain.Domain,
+			Record: r.record,
+			Status: sqlc.DomainRecordStatusVerified,
+		}
+		if r.err != nil {
+			verified = false
+			params.Status = sqlc.DomainRecordStatusFailed
+			params.Error = r.err.Error()
+		}
+		record, err := m.db.SetDomainRecord(context.TODO(), params)
+		if err != nil {
+			return sqlc.Domain{}, nil, err
+		}
+		records = append(records, record)
+	}
+
+	d, err := m.db.SetDomainVerified(context.TODO(), sqlc.SetDomainVerifiedParams{
+		Domain:   domain.Domain,
+		Verified: verified,
+	})
+	if err != nil {
+		return sqlc.Domain{}, nil, err
+	}
+	return d, records, nil
+}
+
+func (m *manager) GetRecords(domain string) ([]sqlc.DomainRecord, error) {
+	return m.db.GetDomainRecords(context.TODO(), domain)
+}
+
+func (m *manager) GetDNSRecords(domain sqlc.Domain) ([]DNSRecord, error) {
+	records, err := m.ExpectedRecords(domain)
+	if err != nil {
+		return nil, err
+	}
+	for i := range records {
+		validateRecord(&records[i], m.config)
+	}
+	return records, nil
+}
+
+func (m *manager) ExpectedRecords(domain sqlc.Domain) ([]DNSRecord, error) {
+	keys, err := m.db.GetDKIMKeys(context.TODO(), domain.Domain)
+	if err != nil {
+		return nil, err
+	}
+	return expectedRecords(domain, keys, m.config), nil
+}
@@ -0,0 +1,37 @@
+package warmup
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/metrics"
+	"kannon.gyozatech.dev/internal/smtp"
+)
+
+type counter struct {
+	db *sqlc.Queries
+}
+
+// NewCounter builds a smtp.WarmupCounter storing the daily deliveries
+// of the IPs in warm-up in db, shared by every sender and kept on restart
+func NewCounter(db *sql.DB) smtp.WarmupCounter {
+	return &counter{
+		db: sqlc.New(metrics.InstrumentDB(db)),
+	}
+}
+
+func (c *counter) Add(ip string, provider string, day time.Time, n int, max int) (bool, error) {
+	rows, err := c.db.AddWarmupUsage(context.TODO(), sqlc.AddWarmupUsageParams{
+		Ip:       ip,
+		Provider: provider,
+		Day:      day,
+		Count:    int32(n),
+		Max:      int32(max),
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
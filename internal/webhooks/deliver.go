@@ -0,0 +1,55 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// SignatureHeader contains the timestamp and the HMAC-SHA256 signature
+// of a delivery, as t=<unix timestamp>,v1=<hex signature>
+const SignatureHeader = "X-Kannon-Signature"
+
+// Sign returns the signature of a payload sent at timestamp,
+// computed on "<timestamp>.<payload>"
+func Sign(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Deliver posts a signed payload to a webhook url and returns the response code,
+// non 2xx responses are errors
+func Deliver(client *http.Client, url string, secret string, event string, payload []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Kannon-Webhooks")
+	req.Header.Set("X-Kannon-Event", event)
+	req.Header.Set(SignatureHeader, fmt.Sprintf("t=%v,v1=%v", timestamp, Sign(secret, timestamp, payload)))
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(res.Body, 1<<16))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("webhook responded %v", res.Status)
+	}
+	return res.StatusCode, nil
+}
@@ -0,0 +1,156 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"kannon.gyozatech.dev/generated/sqlc"
+	"kannon.gyozatech.dev/internal/events"
+	"kannon.gyozatech.dev/internal/metrics"
+)
+
+// MaxAttempts is the number of deliveries of an event
+// before it is marked as failed
+const MaxAttempts = 8
+
+// Manager manages the webhooks of domains and the deliveries of their events
+type Manager interface {
+	CreateWebhook(domain string, url string, events []events.Type) (sqlc.Webhook, error)
+	GetWebhooks(domain string) ([]sqlc.Webhook, error)
+	DeleteWebhook(domain string, id int32) (bool, error)
+	GetDeliveries(domain string, webhookID int32, max uint) ([]sqlc.WebhookDelivery, error)
+	Enqueue(event events.Event) error
+	PrepareDeliveries(max uint, lease time.Duration) ([]sqlc.PrepareWebhookDeliveriesRow, error)
+	SetDelivered(id int32, code int) error
+	SetFailed(id int32, attempts int32, code int, err error) error
+}
+
+type manager struct {
+	db *sqlc.Queries
+}
+
+// NewWebhookManager builds a Webhook Manager
+func NewWebhookManager(db *sql.DB) (Manager, error) {
+	return &manager{
+		db: sqlc.New(metrics.InstrumentDB(db)),
+	}, nil
+}
+
+// CreateWebhook creates a webhook of a domain with a random signing secret
+func (m *manager) CreateWebhook(domain string, url string, subscribed []events.Type) (sqlc.Webhook, error) {
+	secret, err := generateSecret()
+	if err != nil {
+		return sqlc.Webhook{}, err
+	}
+
+	eventsNames := make([]string, 0, len(subscribed))
+	for _, e := range subscribed {
+		eventsNames = append(eventsNames, string(e))
+	}
+
+	return m.db.CreateWebhook(context.TODO(), sqlc.CreateWebhookParams{
+		Domain: domain,
+		Url:    url,
+		Secret: secret,
+		Events: eventsNames,
+	})
+}
+
+func (m *manager) GetWebhooks(domain string) ([]sqlc.Webhook, error) {
+	return m.db.GetWebhooks(context.TODO(), domain)
+}
+
+// DeleteWebhook deletes a webhook with its deliveries,
+// returns false when the webhook does not exist
+func (m *manager) DeleteWebhook(domain string, id int32) (bool, error) {
+	rows, err := m.db.DeleteWebhook(context.TODO(), sqlc.DeleteWebhookParams{
+		Domain: domain,
+		ID:     id,
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+func (m *manager) GetDeliveries(domain string, webhookID int32, max uint) ([]sqlc.WebhookDelivery, error) {
+	return m.db.GetWebhookDeliveries(context.TODO(), sqlc.GetWebhookDeliveriesParams{
+		Domain:    domain,
+		WebhookID: webhookID,
+		Max:       int32(max),
+	})
+}
+
+// Enqueue creates a delivery of an event for every webhook
+// of the event domain subscribed to its type
+func (m *manager) Enqueue(event events.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = m.db.CreateWebhookDeliveries(context.TODO(), sqlc.CreateWebhookDeliveriesParams{
+		Event:   string(event.Type),
+		Payload: payload,
+		Domain:  event.Domain(),
+	})
+	return err
+}
+
+// PrepareDeliveries returns pending deliveries, a delivery is not
+// returned again before lease expires
+func (m *manager) PrepareDeliveries(max uint, lease time.Duration) ([]sqlc.PrepareWebhookDeliveriesRow, error) {
+	return m.db.PrepareWebhookDeliveries(context.TODO(), sqlc.PrepareWebhookDeliveriesParams{
+		LeaseUntil: time.Now().Add(lease),
+		Max:        int32(max),
+	})
+}
+
+func (m *manager) SetDelivered(id int32, code int) error {
+	return m.db.SetWebhookDeliveryResult(context.TODO(), sqlc.SetWebhookDeliveryResultParams{
+		Status:          sqlc.WebhookDeliveryStatusDelivered,
+		ResponseCode:    int32(code),
+		NextAttemptTime: time.Now(),
+		ID:              id,
+	})
+}
+
+// SetFailed records a failed delivery, the delivery is retried with
+// exponential backoff until MaxAttempts
+func (m *manager) SetFailed(id int32, attempts int32, code int, deliveryErr error) error {
+	status := sqlc.WebhookDeliveryStatusPending
+	if attempts+1 >= MaxAttempts {
+		status = sqlc.WebhookDeliveryStatusFailed
+	}
+	return m.db.SetWebhookDeliveryResult(context.TODO(), sqlc.SetWebhookDeliveryResultParams{
+		Status:          status,
+		ResponseCode:    int32(code),
+		ErrorMsg:        deliveryErr.Error(),
+		NextAttemptTime: time.Now().Add(Backoff(attempts)),
+		ID:              id,
+	})
+}
+
+// Backoff returns the delay before the next delivery of an event
+// delivered attempts times: 30s, 1m, 2m, ... up to 1h
+func Backoff(attempts int32) time.Duration {
+	d := 30 * time.Second
+	for i := int32(0); i < attempts && d < time.Hour; i++ {
+		d *= 2
+	}
+	if d > time.Hour {
+		d = time.Hour
+	}
+	return d
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,49 @@
+package webhooks
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, Backoff(0))
+	assert.Equal(t, time.Minute, Backoff(1))
+	assert.Equal(t, 4*time.Minute, Backoff(3))
+	assert.Equal(t, time.Hour, Backoff(20))
+}
+
+func TestDeliver(t *testing.T) {
+	payload := []byte(`{"type":"delivered"}`)
+	var signature string
+	var body []byte
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get(SignatureHeader)
+		body, _ = ioutil.ReadAll(r.Body)
+		if r.Header.Get("X-Kannon-Event") != "delivered" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	code, err := Deliver(srv.Client(), srv.URL, "secret", "delivered", payload)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, payload, body)
+
+	var timestamp int64
+	var sig string
+	_, err = fmt.Sscanf(signature, "t=%d,v1=%s", &timestamp, &sig)
+	assert.Nil(t, err)
+	assert.Equal(t, Sign("secret", timestamp, payload), sig)
+
+	code, err = Deliver(srv.Client(), srv.URL, "secret", "opened", payload)
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
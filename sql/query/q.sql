@@ -2,49 +2,131 @@
 SELECT * FROM domains;
 
 -- name: PrepareForSend :many
+WITH usage AS (
+    SELECT
+        m.domain,
+        COUNT(*) FILTER (WHERE sp.dispatched_at > NOW() - interval '1 second') AS last_second,
+        COUNT(*) AS last_hour
+    FROM sending_pool_emails AS sp
+        JOIN messages AS m ON m.id = sp.message_id
+    WHERE sp.dispatched_at > NOW() - interval '1 hour'
+    GROUP BY m.domain
+), ranked AS (
+    -- position of the email in the queue of its domain, for the rate limits.
+    -- Rows are locked apart, FOR UPDATE is not allowed with window functions
+    SELECT
+        sp.id,
+        ROW_NUMBER() OVER (PARTITION BY m.domain ORDER BY sp.priority DESC, sp.scheduled_time) AS rn
+    FROM sending_pool_emails AS sp
+        JOIN messages AS m ON m.id = sp.message_id
+    WHERE sp.scheduled_time <= NOW() and sp.status = 'scheduled'
+), locked AS (
+    -- rows claimed by other dispatchers are skipped, a batch of the emails
+    -- with the highest priority that can be sent is locked
+    SELECT sp.id FROM sending_pool_emails AS sp
+        JOIN ranked AS r ON r.id = sp.id
+        JOIN messages AS m ON m.id = sp.message_id
+        LEFT JOIN domains AS d ON d.domain = m.domain
+        LEFT JOIN usage AS u ON u.domain = m.domain
+    WHERE sp.scheduled_time <= NOW() and sp.status = 'scheduled'
+        -- emails of deleted domains are never sent,
+        -- emails of paused domains wait until they are resumed
+        AND d.deleted_at IS NULL AND d.paused_at IS NULL
+        AND (COALESCE(d.rate_per_second, 0) = 0 OR r.rn <= d.rate_per_second - COALESCE(u.last_second, 0))
+        AND (COALESCE(d.rate_per_hour, 0) = 0 OR r.rn <= d.rate_per_hour - COALESCE(u.last_hour, 0))
+    ORDER BY sp.priority DESC, sp.scheduled_time
+    LIMIT $1
+    FOR UPDATE OF sp SKIP LOCKED
+)
 UPDATE sending_pool_emails AS sp
-    SET status = 'scheduled'
-    FROM (
-            SELECT id FROM sending_pool_emails
-            WHERE scheduled_time <= NOW() and status = 'scheduled'
-            ORDER BY RANDOM()
-            LIMIT $1
-        ) AS t
-    WHERE sp.id = t.id
+    SET status = 'sending', dispatched_at = NOW(), claimed_at = NOW()
+    FROM locked AS l
+    WHERE sp.id = l.id
     RETURNING sp.*;
 
+-- name: SetSendingPoolEmailPublished :exec
+UPDATE sending_pool_emails SET claimed_at = NULL WHERE id = @id;
+
+-- name: SetSendingPoolEmailQuotaConsumed :exec
+UPDATE sending_pool_emails SET quota_consumed = true WHERE id = @id;
+
+-- name: ReleaseSendingPoolEmail :exec
+UPDATE sending_pool_emails
+    SET status = 'scheduled', claimed_at = NULL, scheduled_time = @scheduled_time
+    WHERE id = @id AND status = 'sending';
+
+-- name: ReclaimSendingPoolEmails :execrows
+UPDATE sending_pool_emails
+    SET status = 'scheduled', claimed_at = NULL
+    WHERE status = 'sending' AND claimed_at < @claimed_before;
+
 -- name: CreateMessage :one
 INSERT INTO messages
-    (message_id, subject, sender_email, sender_alias, template_id, domain) VALUES
-    ($1, $2, $3, $4, $5, $6) RETURNING *;
+    (message_id, subject, sender_email, sender_alias, template_id, template_version, domain, cc, bcc, headers, reply_to, fields, idempotency_key, resent_from) VALUES
+    ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING *;
+
+-- name: GetMessage :one
+SELECT * FROM messages
+    WHERE domain = @domain AND message_id = @message_id;
+
+-- name: FindMessageWithIdempotencyKey :one
+SELECT * FROM messages
+    WHERE domain = $1 AND idempotency_key = $2;
 
 -- name: CreatePool :many
 INSERT INTO sending_pool_emails
-    (email, status, scheduled_time, original_scheduled_time, message_id)
+    (email, fields, status, scheduled_time, original_scheduled_time, message_id, priority, trace_parent, copies)
 (
     SELECT
-        email,
+        e.email,
+        f.fields::jsonb,
         'scheduled',
         @scheduled_time,
         @scheduled_time,
-        @message_id
+        @message_id,
+        @priority,
+        @trace_parent,
+        e.i = 1 AND @copies::boolean
     FROM
-        UNNEST(@emails::varchar[]) as email
+        UNNEST(@emails::varchar[]) WITH ORDINALITY as e(email, i)
+        JOIN UNNEST(@fields::varchar[]) WITH ORDINALITY as f(fields, i) USING (i)
 )
 RETURNING *;
 
 -- name: GetSendingData :one
 SELECT
     t.html,
+    t.text,
     m.domain,
     d.dkim_private_key,
     d.dkim_public_key,
+    d.dkim_ed25519_private_key,
+    d.dkim_signing,
+    d.dkim_selector,
+    d.dkim_ed25519_selector,
+    d.dkim_headers,
+    d.return_path_domain,
+    d.ip_pool,
+    d.default_from_email,
+    d.default_from_name,
+    d.default_headers,
+    d.open_tracking,
+    d.click_tracking,
+    d.footer_html,
+    d.footer_text,
+    d.sandbox,
+    d.sandbox_bounce_percent,
     m.subject,
     m.message_id,
     m.sender_email,
-    m.sender_alias
+    m.sender_alias,
+    m.cc,
+    m.bcc,
+    m.headers,
+    m.reply_to,
+    m.fields
 FROM messages as m
-    JOIN templates as t ON t.template_id = m.template_id
+    JOIN templates as t ON t.template_id = m.template_id AND t.version = m.template_version
     JOIN domains as d ON d.domain = m.domain
     WHERE m.id=sqlc.arg(message_id)
 ;
@@ -54,26 +136,20 @@ SELECT
     *
 FROM domains
     WHERE domain = $1
+    AND deleted_at IS NULL
 ;
 
 -- name: GetAllDomains :many
 SELECT
     *
 FROM domains
-;
-
--- name: FindDomainWithKey :one
-SELECT
-    *
-FROM domains
-    WHERE domain = $1
-    AND key = $2
+    WHERE deleted_at IS NULL
 ;
 
 -- name: CreateDomain :one
 INSERT INTO domains 
-    (domain, key, dkim_private_key, dkim_public_key)
-    VALUES ($1, $2, $3, $4) 
+    (domain, dkim_private_key, dkim_public_key, dkim_ed25519_private_key, dkim_ed25519_public_key)
+    VALUES ($1, $2, $3, $4, $5) 
     RETURNING *;
 
 -- name: FindTemplate :one
@@ -82,11 +158,925 @@ SELECT
 FROM templates
     WHERE template_id = $1
     AND domain = $2
+    AND active
+    AND deleted_at IS NULL
+;
+
+-- name: FindTemplateVersion :one
+SELECT
+    *
+FROM templates
+    WHERE template_id = $1
+    AND domain = $2
+    AND version = $3
+    AND deleted_at IS NULL
+;
+
+-- name: CreateTemplateVersion :one
+INSERT INTO templates
+    (template_id, html, text, domain, version, active, name)
+    SELECT @template_id, @html, @text, @domain, COALESCE(MAX(version), 0) + 1, false, COALESCE(MAX(name), '')
+    FROM templates
+    WHERE template_id = @template_id
+    AND domain = @domain
+    RETURNING *
+;
+
+-- name: SetActiveTemplateVersion :execrows
+UPDATE templates
+    SET active = (version = @version)
+    WHERE template_id = @template_id
+    AND domain = @domain
 ;
 
 -- name: CreateTemplate :one
 INSERT INTO templates
-    (template_id, html, domain)
-    VALUES ($1, $2, $3)
+    (template_id, html, text, domain, name)
+    VALUES ($1, $2, $3, $4, $5)
+    RETURNING *
+;
+
+-- name: GetTemplates :many
+-- returns the active versions of the named templates of a domain
+SELECT * FROM templates
+    WHERE domain = $1
+    AND name <> ''
+    AND active
+    AND deleted_at IS NULL
+    ORDER BY name;
+
+-- name: FindTemplateByName :one
+SELECT * FROM templates
+    WHERE domain = $1
+    AND name = $2
+    AND active
+    AND deleted_at IS NULL;
+
+-- name: DeleteTemplate :execrows
+-- the versions stay, the emails of the template not sent yet render their own version
+UPDATE templates
+    SET deleted_at = NOW()
+    WHERE template_id = $1
+    AND domain = $2
+    AND deleted_at IS NULL;
+
+-- name: CreateAttachment :one
+INSERT INTO attachments
+    (message_id, filename, content, inline)
+    VALUES ($1, $2, $3, $4)
     RETURNING *
 ;
+
+-- name: GetMessageAttachments :many
+SELECT
+    *
+FROM attachments
+    WHERE message_id = $1
+    ORDER BY id
+;
+
+-- name: CreateOpen :exec
+INSERT INTO opens (message_id, email, ip, user_agent, timestamp) VALUES
+    ($1, $2, $3, $4, $5);
+
+-- name: SuppressMessageRecipient :exec
+INSERT INTO suppressions (domain, email, reason)
+    SELECT m.domain, lower(@email::varchar), @reason::suppression_reason
+    FROM messages AS m
+    WHERE m.message_id = @message_id
+    ON CONFLICT (domain, email) DO NOTHING;
+
+-- name: CreateSuppression :one
+INSERT INTO suppressions (domain, email, reason) VALUES
+    (@domain, lower(@email::varchar), @reason)
+    ON CONFLICT (domain, email) DO UPDATE SET reason = EXCLUDED.reason
+    RETURNING *;
+
+-- name: DeleteSuppression :execrows
+DELETE FROM suppressions
+    WHERE domain = @domain AND email = lower(@email::varchar);
+
+-- name: GetSuppressions :many
+SELECT * FROM suppressions
+    WHERE domain = $1
+    ORDER BY created_at DESC;
+
+-- name: IsRecipientSuppressed :one
+SELECT EXISTS (
+    SELECT 1 FROM suppressions AS s
+        JOIN messages AS m ON m.domain = s.domain
+        WHERE m.id = @message_id AND s.email = lower(@email::varchar)
+);
+
+-- name: CountSendingPoolEmailsInFlight :one
+SELECT COUNT(*) FROM sending_pool_emails
+    WHERE status = 'sending';
+
+-- name: CountPendingPoolEmails :one
+SELECT COUNT(*) FROM sending_pool_emails
+    WHERE scheduled_time <= NOW() and status = 'scheduled';
+
+-- name: GetOldestPendingPoolEmail :one
+-- emails of paused and deleted domains are not waiting to be dispatched
+SELECT COALESCE(MIN(sp.scheduled_time), NOW())::timestamptz AS scheduled_time FROM sending_pool_emails AS sp
+    JOIN messages AS m ON m.id = sp.message_id
+    LEFT JOIN domains AS d ON d.domain = m.domain
+    WHERE sp.scheduled_time <= NOW() and sp.status = 'scheduled'
+    AND d.deleted_at IS NULL AND d.paused_at IS NULL;
+
+-- name: SetSendingPoolEmailStatus :exec
+UPDATE sending_pool_emails
+    SET status = @status
+    WHERE id = @id;
+
+-- name: SetSendingPoolEmailDelivered :exec
+UPDATE sending_pool_emails AS sp
+    SET status = 'sent'
+    FROM messages AS m
+    WHERE m.id = sp.message_id AND m.message_id = @message_id AND sp.email = @email;
+
+-- name: SetSendingPoolEmailBounced :exec
+UPDATE sending_pool_emails AS sp
+    SET
+        status = @status,
+        bounce_type = @bounce_type,
+        error_code = @error_code,
+        error_msg = @error_msg,
+        trial = sp.trial + 1,
+        scheduled_time = @scheduled_time,
+        -- the cc and bcc were tried with the email, retries are sent to it only
+        copies = false
+    FROM messages AS m
+    WHERE m.id = sp.message_id AND m.message_id = @message_id AND sp.email = @email;
+
+-- name: DeferSendingPoolEmail :exec
+UPDATE sending_pool_emails AS sp
+    SET
+        status = 'scheduled',
+        scheduled_time = @scheduled_time
+    FROM messages AS m
+    WHERE m.id = sp.message_id AND m.message_id = @message_id AND sp.email = @email;
+
+-- name: CreateComplaint :exec
+INSERT INTO complaints (message_id, email, feedback_type, timestamp) VALUES
+    ($1, $2, $3, $4);
+
+-- name: CreateWebhook :one
+INSERT INTO webhooks (domain, url, secret, events) VALUES
+    ($1, $2, $3, $4)
+    RETURNING *;
+
+-- name: GetWebhooks :many
+SELECT * FROM webhooks
+    WHERE domain = $1
+    ORDER BY id;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks
+    WHERE domain = $1 AND id = $2;
+
+-- name: CreateWebhookDeliveries :execrows
+INSERT INTO webhook_deliveries (webhook_id, event, payload)
+    SELECT w.id, @event::varchar, @payload::jsonb
+    FROM webhooks AS w
+    WHERE w.domain = @domain AND @event::varchar = ANY(w.events);
+
+-- name: PrepareWebhookDeliveries :many
+UPDATE webhook_deliveries AS wd
+    SET next_attempt_time = @lease_until
+    FROM (
+            -- deliveries claimed by other webhooks daemons are skipped
+            SELECT id FROM webhook_deliveries
+            WHERE next_attempt_time <= NOW() AND status = 'pending'
+            ORDER BY next_attempt_time
+            LIMIT @max
+            FOR UPDATE SKIP LOCKED
+        ) AS t, webhooks AS w
+    WHERE wd.id = t.id AND w.id = wd.webhook_id
+    RETURNING wd.id, wd.event, wd.payload, wd.attempts, w.url, w.secret;
+
+-- name: SetWebhookDeliveryResult :exec
+UPDATE webhook_deliveries
+    SET
+        status = @status,
+        attempts = attempts + 1,
+        response_code = @response_code,
+        error_msg = @error_msg,
+        next_attempt_time = @next_attempt_time
+    WHERE id = @id;
+
+-- name: GetWebhookDeliveries :many
+SELECT wd.* FROM webhook_deliveries AS wd
+    JOIN webhooks AS w ON w.id = wd.webhook_id
+    WHERE w.domain = @domain AND w.id = @webhook_id
+    ORDER BY wd.created_at DESC
+    LIMIT @max;
+
+-- name: CountEvent :execrows
+-- 0 rows when the event was already counted
+INSERT INTO counted_events (id, message_id) VALUES (@id, @message_id)
+    ON CONFLICT (id) DO NOTHING;
+
+-- name: IncrementStats :exec
+INSERT INTO stats (domain, message_id, type, hour, count) VALUES
+    (@domain, @message_id, @type, date_trunc('hour', @timestamp::timestamptz), 1)
+    ON CONFLICT (domain, message_id, type, hour) DO UPDATE
+    SET count = stats.count + 1;
+
+-- name: GetStats :many
+SELECT type, SUM(count)::bigint AS count FROM stats
+    WHERE domain = @domain AND hour >= date_trunc('hour', @start_time::timestamptz) AND hour < @end_time
+    GROUP BY type;
+
+-- name: GetDomainsStats :many
+SELECT domain, type, SUM(count)::bigint AS count FROM stats
+    WHERE hour >= date_trunc('hour', @start_time::timestamptz) AND hour < @end_time
+    GROUP BY domain, type;
+
+-- name: IncrementSMTPResponses :exec
+INSERT INTO smtp_responses (domain, provider, code, enhanced_code, hour, count) VALUES
+    (@domain, @provider, @code, @enhanced_code, date_trunc('hour', @timestamp::timestamptz), 1)
+    ON CONFLICT (domain, provider, code, enhanced_code, hour) DO UPDATE
+    SET count = smtp_responses.count + 1;
+
+-- name: GetSMTPResponses :many
+SELECT provider, code, enhanced_code, SUM(count)::bigint AS count FROM smtp_responses
+    WHERE domain = @domain AND hour >= date_trunc('hour', @start_time::timestamptz) AND hour < @end_time
+    AND (@provider::varchar = '' OR provider = @provider::varchar)
+    GROUP BY provider, code, enhanced_code
+    ORDER BY provider, count DESC, code, enhanced_code;
+
+-- name: GetMessageStats :many
+SELECT type, SUM(count)::bigint AS count FROM stats
+    WHERE domain = @domain AND message_id = @message_id
+        AND hour >= date_trunc('hour', @start_time::timestamptz) AND hour < @end_time
+    GROUP BY type;
+
+-- name: CreateMessageEvent :exec
+INSERT INTO message_events (message_id, email, type, data, timestamp) VALUES
+    ($1, $2, $3, $4, $5);
+
+-- name: GetMessageRecipients :many
+SELECT sp.* FROM sending_pool_emails AS sp
+    JOIN messages AS m ON m.id = sp.message_id
+    WHERE m.domain = @domain AND m.message_id = @message_id
+    ORDER BY sp.id;
+
+-- name: GetMessageEvents :many
+SELECT me.* FROM message_events AS me
+    JOIN messages AS m ON m.message_id = me.message_id
+    WHERE m.domain = @domain AND m.message_id = @message_id
+    ORDER BY me.timestamp, me.id;
+
+-- name: ExportMessageEvents :many
+SELECT me.* FROM message_events AS me
+    JOIN messages AS m ON m.message_id = me.message_id
+    WHERE m.domain = @domain
+    AND me.timestamp >= @start_time AND me.timestamp < @end_time
+    AND (CARDINALITY(@types::varchar[]) = 0 OR me.type = ANY(@types::varchar[]))
+    AND me.id > @after
+    ORDER BY me.id
+    LIMIT @max;
+
+-- name: GetLastMessageEventID :one
+SELECT COALESCE(MAX(id), 0)::int AS last_event_id FROM message_events;
+
+-- name: GetEventExport :one
+SELECT * FROM event_exports
+    WHERE domain = @domain;
+
+-- name: SetEventExport :exec
+INSERT INTO event_exports (domain, exported_before, last_event_id) VALUES
+    (@domain, @exported_before, @last_event_id)
+    ON CONFLICT (domain) DO UPDATE
+    SET exported_before = EXCLUDED.exported_before, last_event_id = EXCLUDED.last_event_id;
+
+-- name: GetEventDaysToExport :many
+-- the UTC days before @before with events of a domain not exported by the cursor:
+-- the days after the exported ones, and the days of the events recorded after their export
+SELECT DISTINCT date(me.timestamp AT TIME ZONE 'UTC') AS day FROM message_events AS me
+    JOIN messages AS m ON m.message_id = me.message_id
+    WHERE m.domain = @domain
+    AND me.timestamp < @before
+    AND (me.timestamp >= @exported_before OR me.id > @last_event_id)
+    ORDER BY day;
+
+-- name: CreateTimelineEntry :exec
+INSERT INTO message_timeline (message_id, email, stage, smtp_code, smtp_response, data, timestamp) VALUES
+    ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: CreatePoolTimelineEntries :exec
+INSERT INTO message_timeline (message_id, email, stage, data, timestamp)
+    SELECT m.message_id, sp.email, @stage, @data, @timestamp FROM sending_pool_emails AS sp
+        JOIN messages AS m ON m.id = sp.message_id
+        WHERE sp.id = ANY(@ids::int[]);
+
+-- name: GetMessageTimeline :many
+SELECT mt.* FROM message_timeline AS mt
+    JOIN messages AS m ON m.message_id = mt.message_id
+    WHERE m.domain = @domain AND m.message_id = @message_id
+    ORDER BY mt.timestamp, mt.id;
+
+-- name: SearchMessages :many
+SELECT
+    sp.id,
+    m.message_id,
+    m.domain,
+    m.template_id,
+    m.subject,
+    m.sender_email,
+    sp.email,
+    sp.status,
+    sp.bounce_type,
+    sp.error_msg,
+    m.created_at
+FROM sending_pool_emails AS sp
+    JOIN messages AS m ON m.id = sp.message_id
+WHERE (@domain::varchar = '' OR m.domain = @domain::varchar)
+    AND (@email::varchar = '' OR sp.email = @email::varchar)
+    AND (@template_id::varchar = '' OR m.template_id = @template_id::varchar)
+    AND (@status::varchar = '' OR sp.status::varchar = @status::varchar)
+    AND m.created_at >= @start_time AND m.created_at < @end_time
+    AND (@cursor::integer = 0 OR sp.id < @cursor::integer)
+ORDER BY sp.id DESC
+LIMIT @max;
+
+-- name: SetDomainRateLimit :one
+UPDATE domains
+    SET rate_per_second = @rate_per_second, rate_per_hour = @rate_per_hour
+    WHERE domain = @domain
+    RETURNING *;
+
+-- name: SetDomainIPPool :one
+UPDATE domains
+    SET ip_pool = @ip_pool
+    WHERE domain = @domain
+    RETURNING *;
+
+-- name: SetDomainReturnPathDomain :one
+UPDATE domains
+    SET return_path_domain = @return_path_domain
+    WHERE domain = @domain
+    RETURNING *;
+
+-- name: SetDomainSendingSettings :one
+UPDATE domains
+    SET default_from_email = @default_from_email,
+        default_from_name = @default_from_name,
+        default_headers = @default_headers,
+        open_tracking = @open_tracking,
+        click_tracking = @click_tracking,
+        footer_html = @footer_html,
+        footer_text = @footer_text
+    WHERE domain = @domain
+    RETURNING *;
+
+-- name: SetDomainSandbox :one
+UPDATE domains
+    SET sandbox = @sandbox,
+        sandbox_bounce_percent = @sandbox_bounce_percent
+    WHERE domain = @domain
+    RETURNING *;
+
+-- name: SetDomainDKIMSigning :one
+-- the Ed25519 keys are set only when the domain has none
+UPDATE domains
+    SET dkim_signing = @dkim_signing,
+        dkim_ed25519_private_key = COALESCE(NULLIF(dkim_ed25519_private_key, ''), @dkim_ed25519_private_key),
+        dkim_ed25519_public_key = COALESCE(NULLIF(dkim_ed25519_public_key, ''), @dkim_ed25519_public_key)
+    WHERE domain = @domain
+    RETURNING *;
+
+-- name: SetDomainDKIMHeaders :one
+UPDATE domains
+    SET dkim_headers = @dkim_headers
+    WHERE domain = @domain
+    RETURNING *;
+
+-- name: CreateDKIMKey :one
+-- the new pending key replaces the pending key of the same algorithm
+WITH replaced AS (
+    DELETE FROM dkim_keys
+    WHERE domain = @domain
+        AND algorithm = @algorithm
+        AND status = 'pending'
+)
+INSERT INTO dkim_keys (domain, selector, algorithm, private_key, public_key)
+    VALUES (@domain, @selector, @algorithm, @private_key, @public_key)
+    RETURNING *;
+
+-- name: FindPendingDKIMKey :one
+SELECT
+    *
+FROM dkim_keys
+    WHERE domain = @domain
+    AND selector = @selector
+    AND status = 'pending'
+;
+
+-- name: GetDKIMKeys :many
+SELECT
+    *
+FROM dkim_keys
+    WHERE domain = @domain
+    ORDER BY created_at
+;
+
+-- name: PromoteDKIMKey :one
+-- the pending key becomes the active key of its algorithm,
+-- the previous active key is retired at retire_at
+WITH pending AS (
+    DELETE FROM dkim_keys
+    WHERE dkim_keys.domain = @domain
+        AND dkim_keys.selector = @selector
+        AND dkim_keys.status = 'pending'
+    RETURNING *
+), retiring AS (
+    INSERT INTO dkim_keys (domain, selector, algorithm, private_key, public_key, status, retire_at)
+    SELECT
+        d.domain,
+        CASE WHEN p.algorithm = 'rsa' THEN d.dkim_selector ELSE d.dkim_ed25519_selector END,
+        p.algorithm,
+        CASE WHEN p.algorithm = 'rsa' THEN d.dkim_private_key ELSE d.dkim_ed25519_private_key END,
+        CASE WHEN p.algorithm = 'rsa' THEN d.dkim_public_key ELSE d.dkim_ed25519_public_key END,
+        'retiring',
+        @retire_at::timestamptz
+    FROM domains as d
+        JOIN pending as p ON p.domain = d.domain
+        WHERE p.algorithm = 'rsa' OR d.dkim_ed25519_private_key <> ''
+)
+UPDATE domains
+    SET dkim_selector = CASE WHEN p.algorithm = 'rsa' THEN p.selector ELSE domains.dkim_selector END,
+        dkim_private_key = CASE WHEN p.algorithm = 'rsa' THEN p.private_key ELSE domains.dkim_private_key END,
+        dkim_public_key = CASE WHEN p.algorithm = 'rsa' THEN p.public_key ELSE domains.dkim_public_key END,
+        dkim_ed25519_selector = CASE WHEN p.algorithm = 'ed25519' THEN p.selector ELSE domains.dkim_ed25519_selector END,
+        dkim_ed25519_private_key = CASE WHEN p.algorithm = 'ed25519' THEN p.private_key ELSE domains.dkim_ed25519_private_key END,
+        dkim_ed25519_public_key = CASE WHEN p.algorithm = 'ed25519' THEN p.public_key ELSE domains.dkim_ed25519_public_key END
+    FROM pending as p
+    WHERE domains.domain = p.domain
+    RETURNING domains.*;
+
+-- name: RetireDKIMKeys :many
+-- retired keys can't sign and their DNS records can be removed
+UPDATE dkim_keys
+    SET status = 'retired',
+        private_key = ''
+    WHERE status = 'retiring'
+    AND retire_at <= now()
+    RETURNING *;
+
+-- name: SetDomainRetention :one
+UPDATE domains
+    SET retention_days = @retention_days
+    WHERE domain = @domain
+    RETURNING *;
+
+-- name: PurgeMessages :one
+WITH expired AS (
+    SELECT m.id, m.message_id FROM messages AS m
+        WHERE m.domain = @domain AND m.created_at < @before
+        AND NOT EXISTS (
+            SELECT 1 FROM sending_pool_emails AS sp
+                WHERE sp.message_id = m.id AND sp.status IN ('initializing', 'scheduled', 'sending')
+        )
+        LIMIT @max
+), deleted_emails AS (
+    DELETE FROM sending_pool_emails AS sp USING expired AS e
+        WHERE sp.message_id = e.id
+        RETURNING sp.id
+), deleted_attachments AS (
+    DELETE FROM attachments AS a USING expired AS e
+        WHERE a.message_id = e.id
+        RETURNING a.id
+), deleted_opens AS (
+    DELETE FROM opens AS o USING expired AS e
+        WHERE o.message_id = e.message_id
+        RETURNING o.id
+), deleted_complaints AS (
+    DELETE FROM complaints AS c USING expired AS e
+        WHERE c.message_id = e.message_id
+        RETURNING c.id
+), deleted_events AS (
+    DELETE FROM message_events AS me USING expired AS e
+        WHERE me.message_id = e.message_id
+        RETURNING me.id
+), deleted_timeline AS (
+    DELETE FROM message_timeline AS mt USING expired AS e
+        WHERE mt.message_id = e.message_id
+        RETURNING mt.id
+), deleted_counted_events AS (
+    DELETE FROM counted_events AS ce USING expired AS e
+        WHERE ce.message_id = e.message_id
+), deleted_messages AS (
+    DELETE FROM messages AS m USING expired AS e
+        WHERE m.id = e.id
+        RETURNING m.id
+)
+SELECT
+    (SELECT COUNT(*) FROM deleted_messages) AS messages,
+    (SELECT COUNT(*) FROM deleted_emails) AS emails,
+    (SELECT COUNT(*) FROM deleted_attachments) AS attachments,
+    (SELECT COUNT(*) FROM deleted_opens) AS opens,
+    (SELECT COUNT(*) FROM deleted_complaints) AS complaints,
+    (SELECT COUNT(*) FROM deleted_events) AS events,
+    (SELECT COUNT(*) FROM deleted_timeline) AS timeline;
+
+-- name: PurgeWebhookDeliveries :execrows
+DELETE FROM webhook_deliveries AS wd
+    USING webhooks AS w
+    WHERE w.id = wd.webhook_id AND w.domain = @domain
+        AND wd.created_at < @before AND wd.status <> 'pending';
+
+-- name: FindSendingPoolEmail :one
+SELECT sp.* FROM sending_pool_emails AS sp
+    JOIN messages AS m ON m.id = sp.message_id
+    WHERE m.message_id = @message_id AND sp.email = @email;
+
+-- name: CreateDeadLetter :one
+INSERT INTO dead_letters (domain, subject, message_id, email, payload, reason, created_at) VALUES
+    ($1, $2, $3, $4, $5, $6, $7)
+    RETURNING *;
+
+-- name: GetDeadLetters :many
+SELECT * FROM dead_letters
+    WHERE (@domain::varchar = '' OR domain = @domain::varchar)
+    AND (@include_requeued::boolean OR requeued_at IS NULL)
+    ORDER BY id DESC
+    LIMIT @max;
+
+-- name: ClaimDeadLetter :one
+-- no rows when the letter is already requeued, a letter is claimed once
+UPDATE dead_letters
+    SET requeued_at = NOW()
+    WHERE id = $1 AND requeued_at IS NULL
+    RETURNING *;
+
+-- name: ReleaseDeadLetter :exec
+UPDATE dead_letters
+    SET requeued_at = NULL
+    WHERE id = $1;
+
+-- name: RequeueSendingPoolEmail :execrows
+UPDATE sending_pool_emails AS sp
+    SET status = 'scheduled', trial = 0, scheduled_time = NOW()
+    FROM messages AS m
+    WHERE m.id = sp.message_id AND m.message_id = @message_id AND sp.email = @email
+        AND sp.status = 'error' AND sp.bounce_type = 'soft';
+
+-- name: SetDomainRecord :one
+INSERT INTO domain_records (domain, record, status, error)
+    VALUES (@domain, @record, @status, @error)
+    ON CONFLICT (domain, record) DO UPDATE
+    SET status = @status, error = @error, checked_at = now()
+    RETURNING *;
+
+-- name: GetDomainRecords :many
+SELECT
+    *
+FROM domain_records
+    WHERE domain = @domain
+    ORDER BY record
+;
+
+-- name: SetDomainVerified :one
+UPDATE domains
+    SET verified = @verified
+    WHERE domain = @domain
+    RETURNING *;
+
+-- name: CreateDMARCReport :many
+-- no rows are returned for reports already received
+INSERT INTO dmarc_reports (org_name, report_id, domain, begin_at, end_at)
+    VALUES (@org_name, @report_id, @domain, @begin_at, @end_at)
+    ON CONFLICT (org_name, report_id) DO NOTHING
+    RETURNING *;
+
+-- name: CreateDMARCRecord :exec
+INSERT INTO dmarc_records (report_id, header_from, source_ip, count, disposition, dkim_aligned, spf_aligned, begin_at)
+    VALUES (@report_id, @header_from, @source_ip, @count, @disposition, @dkim_aligned, @spf_aligned, @begin_at);
+
+-- name: GetDMARCStats :many
+-- messages of the reports of a sending domain by source ip
+SELECT
+    source_ip,
+    SUM(count)::bigint as messages,
+    SUM(CASE WHEN dkim_aligned OR spf_aligned THEN count ELSE 0 END)::bigint as passed,
+    SUM(CASE WHEN dkim_aligned THEN count ELSE 0 END)::bigint as dkim_aligned,
+    SUM(CASE WHEN spf_aligned THEN count ELSE 0 END)::bigint as spf_aligned,
+    SUM(CASE WHEN disposition = 'quarantine' THEN count ELSE 0 END)::bigint as quarantined,
+    SUM(CASE WHEN disposition = 'reject' THEN count ELSE 0 END)::bigint as rejected
+FROM dmarc_records
+    WHERE header_from = @header_from
+    AND begin_at >= @start
+    AND begin_at < @stop
+    GROUP BY source_ip
+    ORDER BY messages DESC
+;
+
+-- name: SetDomainQuota :one
+UPDATE domains
+    SET daily_quota = @daily_quota, monthly_quota = @monthly_quota
+    WHERE domain = @domain
+    RETURNING *;
+
+-- name: CreateDomainUsage :exec
+-- creates the usage of today of the domain of a pool email, consumed by ConsumeDomainQuota
+INSERT INTO domain_usage (domain, day, sent)
+SELECT m.domain, CURRENT_DATE, 0
+FROM sending_pool_emails AS sp
+    JOIN messages AS m ON m.id = sp.message_id
+    WHERE sp.id = @id
+ON CONFLICT (domain, day) DO NOTHING;
+
+-- name: ConsumeDomainQuota :execrows
+-- counts a pool email in the usage of today of its domain, with its copies for the email
+-- carrying the cc and bcc. No rows are affected when the daily or monthly quota is exceeded.
+-- The check and the increment are a single update of the row of today, concurrent
+-- consumes of a domain wait for each other and check the updated count
+WITH email AS (
+    SELECT m.domain, 1 + CASE WHEN sp.copies THEN CARDINALITY(m.cc) + CARDINALITY(m.bcc) ELSE 0 END AS n
+    FROM sending_pool_emails AS sp
+        JOIN messages AS m ON m.id = sp.message_id
+        WHERE sp.id = @id
+)
+UPDATE domain_usage AS u
+    SET sent = u.sent + e.n
+    FROM email AS e, domains AS d
+    WHERE d.domain = e.domain AND u.domain = e.domain AND u.day = CURRENT_DATE
+    AND (d.daily_quota = 0 OR u.sent + e.n <= d.daily_quota)
+    AND (d.monthly_quota = 0 OR u.sent + e.n + (
+        SELECT COALESCE(SUM(p.sent), 0) FROM domain_usage AS p
+        WHERE p.domain = e.domain AND p.day >= date_trunc('month', CURRENT_DATE) AND p.day < CURRENT_DATE
+    ) <= d.monthly_quota);
+
+-- name: GetDomainUsage :one
+SELECT
+    COALESCE(SUM(sent) FILTER (WHERE day = CURRENT_DATE), 0)::integer AS daily,
+    COALESCE(SUM(sent), 0)::integer AS monthly
+FROM domain_usage
+    WHERE domain = @domain
+    AND day >= date_trunc('month', CURRENT_DATE)
+;
+
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (domain, name, prefix, key_hash, scopes)
+    VALUES (@domain, @name, @prefix, @key_hash, @scopes)
+    RETURNING *;
+
+-- name: FindAPIKey :one
+SELECT * FROM api_keys
+    WHERE domain = @domain
+    AND key_hash = @key_hash
+    AND revoked_at IS NULL
+    AND (expires_at IS NULL OR expires_at > NOW());
+
+-- name: TouchAPIKey :exec
+-- last_used_at is updated at most once a minute
+UPDATE api_keys
+    SET last_used_at = NOW()
+    WHERE id = @id
+    AND (last_used_at IS NULL OR last_used_at < NOW() - interval '1 minute');
+
+-- name: GetAPIKeys :many
+SELECT * FROM api_keys
+    WHERE domain = @domain
+    ORDER BY created_at DESC;
+
+-- name: RevokeAPIKey :one
+UPDATE api_keys
+    SET revoked_at = NOW()
+    WHERE domain = @domain
+    AND id = @id
+    AND revoked_at IS NULL
+    RETURNING *;
+
+-- name: RevokeAPIKeysByName :exec
+UPDATE api_keys
+    SET revoked_at = NOW()
+    WHERE domain = @domain
+    AND name = @name
+    AND revoked_at IS NULL;
+
+-- name: GetAPIKey :one
+SELECT * FROM api_keys
+    WHERE domain = @domain
+    AND id = @id;
+
+-- name: ExpireAPIKey :one
+-- keys already expiring before expires_at keep their expiration
+UPDATE api_keys
+    SET expires_at = @expires_at
+    WHERE domain = @domain
+    AND id = @id
+    AND revoked_at IS NULL
+    AND (expires_at IS NULL OR expires_at > @expires_at)
+    RETURNING *;
+
+-- name: CreateAPIKeyCall :exec
+INSERT INTO api_key_calls (api_key_id, domain, method, allowed)
+    VALUES (@api_key_id, @domain, @method, @allowed);
+
+-- name: GetAPIKeyCalls :many
+SELECT * FROM api_key_calls
+    WHERE domain = @domain
+    AND (@api_key_id::integer = 0 OR api_key_id = @api_key_id::integer)
+    AND called_at >= @start AND called_at < @stop
+    ORDER BY id DESC
+    LIMIT @max;
+
+-- name: PurgeAPIKeyCalls :execrows
+DELETE FROM api_key_calls
+    WHERE domain = @domain
+    AND called_at < @before;
+
+-- name: FindDeletedDomain :one
+SELECT * FROM domains
+    WHERE domain = @domain
+    AND deleted_at IS NOT NULL;
+
+-- name: DeleteDomain :one
+UPDATE domains
+    SET deleted_at = NOW(), purge_at = @purge_at
+    WHERE domain = @domain
+    AND deleted_at IS NULL
+    RETURNING *;
+
+-- name: RestoreDomain :one
+UPDATE domains
+    SET deleted_at = NULL, purge_at = NULL
+    WHERE domain = @domain
+    AND deleted_at IS NOT NULL
+    AND purge_at > NOW()
+    RETURNING *;
+
+-- name: CancelDomainPool :execrows
+-- emails being sent are canceled only with include_sending
+UPDATE sending_pool_emails AS sp
+    SET status = 'canceled'
+    FROM messages AS m
+    WHERE m.id = sp.message_id
+    AND m.domain = @domain
+    AND (sp.status IN ('initializing', 'scheduled')
+        OR (@include_sending::boolean AND sp.status = 'sending'));
+
+-- name: CancelMessagePool :many
+-- emails claimed by a dispatcher are locked until they are sending,
+-- then they are not canceled anymore. No emails cancels every recipient
+UPDATE sending_pool_emails AS sp
+    SET status = 'canceled'
+    FROM messages AS m
+    WHERE m.id = sp.message_id
+    AND m.domain = @domain
+    AND m.message_id = @message_id
+    AND (CARDINALITY(@emails::varchar[]) = 0 OR sp.email = ANY(@emails::varchar[]))
+    AND sp.status IN ('initializing', 'scheduled')
+    RETURNING sp.id, sp.email;
+
+-- name: PauseDomain :one
+UPDATE domains
+    SET paused_at = NOW(), paused_reason = @paused_reason
+    WHERE domain = @domain
+    AND deleted_at IS NULL
+    RETURNING *;
+
+-- name: ResumeDomain :one
+UPDATE domains
+    SET paused_at = NULL, paused_reason = ''
+    WHERE domain = @domain
+    AND deleted_at IS NULL
+    RETURNING *;
+
+-- name: GetDomainsToPurge :many
+SELECT * FROM domains
+    WHERE deleted_at IS NOT NULL
+    AND purge_at <= NOW();
+
+-- name: PurgeDomain :one
+-- deletes the domain and the rest of its data, its messages must
+-- be purged first. Statements in WITH run even when not counted
+WITH purged AS (
+    SELECT pd.domain FROM domains AS pd
+        WHERE pd.domain = @domain
+        AND pd.deleted_at IS NOT NULL
+        AND pd.purge_at <= NOW()
+), deleted_templates AS (
+    DELETE FROM templates AS t USING purged AS p
+        WHERE t.domain = p.domain
+        RETURNING t.id
+), deleted_stats AS (
+    DELETE FROM stats AS s USING purged AS p
+        WHERE s.domain = p.domain
+        RETURNING s.id
+), deleted_smtp_responses AS (
+    DELETE FROM smtp_responses AS r USING purged AS p
+        WHERE r.domain = p.domain
+        RETURNING r.id
+), deleted_suppressions AS (
+    DELETE FROM suppressions AS s USING purged AS p
+        WHERE s.domain = p.domain
+        RETURNING s.id
+), deleted_webhook_deliveries AS (
+    DELETE FROM webhook_deliveries AS wd USING webhooks AS w, purged AS p
+        WHERE wd.webhook_id = w.id AND w.domain = p.domain
+        RETURNING wd.id
+), deleted_webhooks AS (
+    DELETE FROM webhooks AS w USING purged AS p
+        WHERE w.domain = p.domain
+        RETURNING w.id
+), deleted_dkim_keys AS (
+    DELETE FROM dkim_keys AS k USING purged AS p
+        WHERE k.domain = p.domain
+        RETURNING k.id
+), deleted_records AS (
+    DELETE FROM domain_records AS r USING purged AS p
+        WHERE r.domain = p.domain
+        RETURNING r.id
+), deleted_usage AS (
+    DELETE FROM domain_usage AS u USING purged AS p
+        WHERE u.domain = p.domain
+        RETURNING u.id
+), deleted_api_keys AS (
+    DELETE FROM api_keys AS k USING purged AS p
+        WHERE k.domain = p.domain
+        RETURNING k.id
+), deleted_api_key_calls AS (
+    DELETE FROM api_key_calls AS c USING purged AS p
+        WHERE c.domain = p.domain
+        RETURNING c.id
+), deleted_dead_letters AS (
+    DELETE FROM dead_letters AS dl USING purged AS p
+        WHERE dl.domain = p.domain
+        RETURNING dl.id
+), deleted_dmarc_records AS (
+    DELETE FROM dmarc_records AS dr USING dmarc_reports AS r, purged AS p
+        WHERE dr.report_id = r.id AND r.domain = p.domain
+        RETURNING dr.id
+), deleted_dmarc_reports AS (
+    DELETE FROM dmarc_reports AS r USING purged AS p
+        WHERE r.domain = p.domain
+        RETURNING r.id
+), deleted_domains AS (
+    DELETE FROM domains AS d USING purged AS p
+        WHERE d.domain = p.domain
+        RETURNING d.id
+)
+SELECT
+    (SELECT COUNT(*) FROM deleted_domains) AS domains,
+    (SELECT COUNT(*) FROM deleted_templates) AS templates,
+    (SELECT COUNT(*) FROM deleted_stats) AS stats,
+    (SELECT COUNT(*) FROM deleted_smtp_responses) AS smtp_responses,
+    (SELECT COUNT(*) FROM deleted_suppressions) AS suppressions,
+    (SELECT COUNT(*) FROM deleted_webhooks) AS webhooks,
+    (SELECT COUNT(*) FROM deleted_webhook_deliveries) AS webhook_deliveries,
+    (SELECT COUNT(*) FROM deleted_dkim_keys) AS dkim_keys,
+    (SELECT COUNT(*) FROM deleted_api_keys) AS api_keys,
+    (SELECT COUNT(*) FROM deleted_api_key_calls) AS api_key_calls,
+    (SELECT COUNT(*) FROM deleted_dead_letters) AS dead_letters,
+    (SELECT COUNT(*) FROM deleted_dmarc_reports) AS dmarc_reports;
+
+-- name: CreateAuditLog :one
+INSERT INTO audit_logs (actor, method, domain, request, before_value, after_value)
+    VALUES (@actor, @method, @domain, @request, @before_value, @after_value)
+    RETURNING *;
+
+-- name: GetAuditLogs :many
+SELECT * FROM audit_logs
+WHERE (@domain::varchar = '' OR domain = @domain::varchar)
+    AND (@actor::varchar = '' OR actor = @actor::varchar)
+    AND (@method::varchar = '' OR method = @method::varchar)
+    AND created_at >= @start_time AND created_at < @end_time
+    AND (@cursor::integer = 0 OR id < @cursor::integer)
+ORDER BY id DESC
+LIMIT @max;
+
+-- name: GetMaintenance :one
+SELECT * FROM maintenance;
+
+-- name: SetMaintenance :one
+INSERT INTO maintenance (id, halted, reason, changed_at)
+    VALUES (true, @halted, @reason, NOW())
+    ON CONFLICT (id) DO UPDATE
+    SET halted = @halted, reason = @reason, changed_at = NOW()
+    RETURNING *;
+
+-- name: AddWarmupUsage :execrows
+-- deliveries over the max of the day are not counted
+INSERT INTO warmup_usage AS w (ip, provider, day, count)
+    SELECT @ip::varchar, @provider::varchar, @day::date, @count::integer
+    WHERE @count::integer <= @max::integer
+    ON CONFLICT (ip, provider, day) DO UPDATE
+        SET count = w.count + EXCLUDED.count
+        WHERE w.count + EXCLUDED.count <= @max::integer;
+
+-- name: GetFiringAlerts :many
+SELECT * FROM firing_alerts;
+
+-- name: FireAlert :execrows
+-- alerts already firing are not inserted again
+INSERT INTO firing_alerts (rule, domain, value, threshold, message, fired_at)
+    VALUES (@rule, @domain, @value, @threshold, @message, @fired_at)
+    ON CONFLICT (rule, domain) DO NOTHING;
+
+-- name: ResolveAlert :execrows
+DELETE FROM firing_alerts
+    WHERE rule = @rule AND domain = @domain;